@@ -14,6 +14,7 @@ import (
 
 	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
 	"github.com/therealutkarshpriyadarshi/log/internal/parser"
 	"github.com/therealutkarshpriyadarshi/log/internal/pool"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
@@ -29,6 +30,14 @@ var (
 	reportInterval = flag.Int("interval", 5, "Report interval in seconds")
 )
 
+var (
+	outputType   = flag.String("output", "null", "Output behind the buffer (null, file, kafka)")
+	outputBatch  = flag.Int("output-batch", 500, "Events per output batch")
+	outputPath   = flag.String("output-path", "loadtest-output.ndjson", "File the file output appends to")
+	kafkaBrokers = flag.String("kafka-brokers", "localhost:9092", "Comma-separated Kafka brokers for the kafka output")
+	kafkaTopic   = flag.String("kafka-topic", "loadtest", "Kafka topic for the kafka output")
+)
+
 // Stats tracks load test statistics
 type Stats struct {
 	eventsGenerated uint64
@@ -36,6 +45,9 @@ type Stats struct {
 	eventsBuffered  uint64
 	parseErrors     uint64
 	bufferErrors    uint64
+	eventsSent      uint64
+	sendFailures    uint64
+	sendLatency     latencyRecorder
 	startTime       time.Time
 }
 
@@ -46,6 +58,8 @@ func (s *Stats) Report() {
 	buffered := atomic.LoadUint64(&s.eventsBuffered)
 	parseErrors := atomic.LoadUint64(&s.parseErrors)
 	bufferErrors := atomic.LoadUint64(&s.bufferErrors)
+	sent := atomic.LoadUint64(&s.eventsSent)
+	sendFailures := atomic.LoadUint64(&s.sendFailures)
 
 	fmt.Printf("\n=== Load Test Statistics ===\n")
 	fmt.Printf("Duration: %.2f seconds\n", elapsed)
@@ -55,6 +69,9 @@ func (s *Stats) Report() {
 	fmt.Printf("Parse Errors: %d\n", parseErrors)
 	fmt.Printf("Buffer Errors: %d\n", bufferErrors)
 	fmt.Printf("Success Rate: %.2f%%\n", float64(parsed)/float64(generated)*100)
+	fmt.Printf("Events Sent: %d (%.0f/sec)\n", sent, float64(sent)/elapsed)
+	fmt.Printf("Send Failures: %d\n", sendFailures)
+	fmt.Printf("Send Latency: p50=%v p99=%v\n", s.sendLatency.percentile(50), s.sendLatency.percentile(99))
 	fmt.Printf("============================\n\n")
 }
 
@@ -72,15 +89,16 @@ func main() {
 	fmt.Printf("Workers: %d\n", *workers)
 	fmt.Printf("Buffer Size: %d\n", *bufferSize)
 	fmt.Printf("Parser Type: %s\n", *parserType)
-	fmt.Printf("Object Pooling: %t\n\n", *usePooling)
+	fmt.Printf("Object Pooling: %t\n", *usePooling)
+	fmt.Printf("Output: %s\n\n", *outputType)
 
-	if err := run(logger); err != nil {
+	if _, err := run(logger); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(logger *logging.Logger) error {
+func run(logger *logging.Logger) (*Stats, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -106,12 +124,12 @@ func run(logger *logging.Logger) error {
 			LevelField: "level",
 		}
 	default:
-		return fmt.Errorf("unsupported parser type: %s", *parserType)
+		return nil, fmt.Errorf("unsupported parser type: %s", *parserType)
 	}
 
 	p, err := parser.New(parserCfg)
 	if err != nil {
-		return fmt.Errorf("failed to create parser: %w", err)
+		return nil, fmt.Errorf("failed to create parser: %w", err)
 	}
 
 	// Create ring buffer
@@ -122,7 +140,13 @@ func run(logger *logging.Logger) error {
 
 	rb, err := buffer.NewRingBuffer(bufferCfg)
 	if err != nil {
-		return fmt.Errorf("failed to create buffer: %w", err)
+		return nil, fmt.Errorf("failed to create buffer: %w", err)
+	}
+
+	// Create the output the buffer drains into
+	out, err := newOutput(*outputType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output: %w", err)
 	}
 
 	// Initialize stats
@@ -145,6 +169,14 @@ func run(logger *logging.Logger) error {
 		}
 	}()
 
+	// Drain the buffer into the output
+	drainStop := make(chan struct{})
+	drainDone := make(chan struct{})
+	go func() {
+		defer close(drainDone)
+		drain(rb, out, *outputBatch, stats, drainStop)
+	}()
+
 	// Start workers
 	var wg sync.WaitGroup
 	eventsPerWorker := *targetRate / *workers
@@ -166,14 +198,25 @@ func run(logger *logging.Logger) error {
 		logger.Info().Msg("Received shutdown signal")
 	}
 
-	// Stop workers
+	// Stop workers, then send what is left in the buffer
 	cancel()
 	wg.Wait()
+	close(drainStop)
+	<-drainDone
+
+	if flusher, ok := out.(output.Flusher); ok {
+		if err := flusher.Flush(context.Background()); err != nil {
+			logger.Error().Err(err).Msg("Failed to flush output")
+		}
+	}
+	if err := out.Close(); err != nil {
+		logger.Error().Err(err).Msg("Failed to close output")
+	}
 
 	// Final report
 	stats.Report()
 
-	return nil
+	return stats, nil
 }
 
 func runWorker(ctx context.Context, workerID int, p parser.Parser, rb *buffer.RingBuffer, stats *Stats, sleepDuration time.Duration) {
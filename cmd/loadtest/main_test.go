@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestRun_NullOutput(t *testing.T) {
+	*duration = 1
+	*targetRate = 2000
+	*workers = 2
+	*bufferSize = 4096
+	*outputType = "null"
+
+	stats, err := run(logging.New(logging.Config{Level: "error", Format: "json"}))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if stats.eventsParsed == 0 {
+		t.Fatal("expected events to be parsed")
+	}
+	if stats.eventsSent == 0 || stats.eventsSent > stats.eventsBuffered {
+		t.Errorf("expected up to %d buffered events to be sent, got %d", stats.eventsBuffered, stats.eventsSent)
+	}
+	if stats.sendFailures != 0 {
+		t.Errorf("expected no send failures, got %d", stats.sendFailures)
+	}
+	if stats.sendLatency.percentile(99) < stats.sendLatency.percentile(50) {
+		t.Error("expected p99 latency to be at least p50")
+	}
+}
+
+func TestLatencyRecorder_Percentile(t *testing.T) {
+	var recorder latencyRecorder
+	if recorder.percentile(50) != 0 {
+		t.Error("expected zero latency with no samples")
+	}
+
+	for i := 100; i >= 1; i-- {
+		recorder.record(time.Duration(i) * time.Millisecond)
+	}
+	if got := recorder.percentile(50); got != 50*time.Millisecond {
+		t.Errorf("p50 = %v, want 50ms", got)
+	}
+	if got := recorder.percentile(99); got != 99*time.Millisecond {
+		t.Errorf("p99 = %v, want 99ms", got)
+	}
+}
+
+func TestNewOutput_File(t *testing.T) {
+	*outputPath = filepath.Join(t.TempDir(), "loadtest.ndjson")
+
+	out, err := newOutput("file")
+	if err != nil {
+		t.Fatalf("newOutput() error = %v", err)
+	}
+	events := []*types.LogEvent{{Message: "first"}, {Message: "second"}}
+	if err := out.SendBatch(context.Background(), events); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(*outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected 2 lines, got %q", data)
+	}
+}
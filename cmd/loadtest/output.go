@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// newOutput creates the output the load test drains the buffer into
+func newOutput(outputType string) (output.Output, error) {
+	switch outputType {
+	case "null":
+		return &nullOutput{}, nil
+	case "file":
+		return output.New("file", map[string]interface{}{
+			"name": "loadtest",
+			"path": *outputPath,
+		})
+	case "kafka":
+		return output.New("kafka", map[string]interface{}{
+			"name":    "loadtest",
+			"brokers": strings.Split(*kafkaBrokers, ","),
+			"topic":   *kafkaTopic,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported output type: %s", outputType)
+	}
+}
+
+// nullOutput discards events, measuring the pipeline without a destination
+type nullOutput struct {
+	sent int64
+}
+
+func (o *nullOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	atomic.AddInt64(&o.sent, 1)
+	return nil
+}
+
+func (o *nullOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	atomic.AddInt64(&o.sent, int64(len(events)))
+	return nil
+}
+
+func (o *nullOutput) Close() error { return nil }
+
+func (o *nullOutput) Name() string { return "null" }
+
+func (o *nullOutput) Metrics() *output.OutputMetrics {
+	return &output.OutputMetrics{EventsSent: atomic.LoadInt64(&o.sent)}
+}
+
+func (o *nullOutput) HealthCheck(ctx context.Context) error { return nil }
+
+// latencyRecorder keeps send latencies for percentile reporting
+type latencyRecorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (r *latencyRecorder) record(latency time.Duration) {
+	r.mu.Lock()
+	r.latencies = append(r.latencies, latency)
+	r.mu.Unlock()
+}
+
+// percentile returns the latency below which p percent of sends completed
+func (r *latencyRecorder) percentile(p float64) time.Duration {
+	r.mu.Lock()
+	sorted := make([]time.Duration, len(r.latencies))
+	copy(sorted, r.latencies)
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(float64(len(sorted))*p/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// drain sends buffered events to out in batches until stopCh is closed and
+// the buffer is empty
+func drain(rb *buffer.RingBuffer, out output.Output, batchSize int, stats *Stats, stopCh <-chan struct{}) {
+	batch := make([]*types.LogEvent, 0, batchSize)
+
+	send := func() {
+		start := time.Now()
+		err := out.SendBatch(context.Background(), batch)
+		stats.sendLatency.record(time.Since(start))
+
+		if err != nil {
+			atomic.AddUint64(&stats.sendFailures, uint64(len(batch)))
+		} else {
+			atomic.AddUint64(&stats.eventsSent, uint64(len(batch)))
+		}
+		batch = make([]*types.LogEvent, 0, batchSize)
+	}
+
+	for {
+		if event, ok := rb.TryDequeue(); ok {
+			// The buffer can hand out a slot before its event is stored
+			if event == nil {
+				continue
+			}
+			batch = append(batch, event)
+			if len(batch) >= batchSize {
+				send()
+			}
+			continue
+		}
+
+		// Send partial batches as soon as the buffer runs dry
+		if len(batch) > 0 {
+			send()
+			continue
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+)
+
+// debugState is the runtime state served on /debug/state
+type debugState struct {
+	Buffer          buffer.BufferMetrics             `json:"buffer"`
+	Router          *output.OutputMetrics            `json:"router,omitempty"`
+	Outputs         map[string]*output.OutputMetrics `json:"outputs"`
+	CircuitBreakers map[string]reliability.Metrics   `json:"circuit_breakers"`
+	WAL             *wal.WALMetrics                  `json:"wal"`
+	Config          map[string]interface{}           `json:"config"`
+}
+
+// debugHandler serves the pipeline's live metrics and the running
+// configuration, with its secrets masked. Outputs and circuit breakers are
+// empty for stdout and file outputs, and the WAL is null when disabled.
+func debugHandler(pipe *pipeline, running func() *config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cfg, err := running().Redacted()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		state := debugState{
+			Buffer:          pipe.buffer.Metrics(),
+			Outputs:         map[string]*output.OutputMetrics{},
+			CircuitBreakers: map[string]reliability.Metrics{},
+			Config:          cfg,
+		}
+		if pipe.router != nil {
+			state.Router = pipe.router.Metrics()
+			state.Outputs = pipe.router.OutputMetrics()
+			state.CircuitBreakers = pipe.router.CircuitBreakers().AllMetrics()
+		}
+		if pipe.wal != nil {
+			metrics := pipe.wal.Metrics()
+			state.WAL = &metrics
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	})
+}
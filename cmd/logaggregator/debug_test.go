@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestDebugHandler(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+
+	cfg := config.DefaultConfig()
+	cfg.Inputs.HTTP = []config.HTTPInputConfig{{
+		Name:    "api",
+		Address: "127.0.0.1:0",
+		APIKeys: []string{"input-key-1", "input-key-2"},
+	}}
+	cfg.Output = config.OutputConfig{
+		Type: "http",
+		HTTP: &config.HTTPOutputConfig{
+			URL:            "http://127.0.0.1:1/ingest",
+			Headers:        map[string]string{"X-API-Key": "header-secret"},
+			BearerToken:    "bearer-secret",
+			CircuitBreaker: &config.OutputCircuitBreakerConfig{FailureThreshold: 3},
+		},
+	}
+	cfg.WAL = &config.WALConfig{Enabled: true, Dir: t.TempDir()}
+
+	pipe, err := newPipeline(cfg, nil, logger)
+	if err != nil {
+		t.Fatalf("newPipeline() error = %v", err)
+	}
+	defer func() {
+		pipe.cancel()
+		pipe.wal.Close()
+		pipe.router.Close()
+	}()
+	pipe.wal.Write(&types.LogEvent{Message: "logged"})
+
+	rec := httptest.NewRecorder()
+	debugHandler(pipe, func() *config.Config { return cfg }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	body := rec.Body.String()
+	for _, secret := range []string{"input-key-1", "header-secret", "bearer-secret"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("secret %q was not masked: %s", secret, body)
+		}
+	}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, section := range []string{"buffer", "router", "outputs", "circuit_breakers", "wal", "config"} {
+		if state[section] == nil {
+			t.Errorf("missing section %s in %s", section, body)
+		}
+	}
+
+	if buf := state["buffer"].(map[string]interface{}); buf["capacity"] == nil || buf["utilization"] == nil {
+		t.Errorf("unexpected buffer section %v", buf)
+	}
+	if wal := state["wal"].(map[string]interface{}); wal["entries_written"] != float64(1) {
+		t.Errorf("unexpected wal section %v", wal)
+	}
+	if _, ok := state["outputs"].(map[string]interface{})["http"]; !ok {
+		t.Errorf("expected the http output's metrics, got %v", state["outputs"])
+	}
+	breaker, ok := state["circuit_breakers"].(map[string]interface{})["http"].(map[string]interface{})
+	if !ok || breaker["state"] != "closed" {
+		t.Errorf("expected the http output's closed breaker, got %v", state["circuit_breakers"])
+	}
+
+	output := state["config"].(map[string]interface{})["output"].(map[string]interface{})["http"].(map[string]interface{})
+	if output["bearer_token"] != config.RedactedValue || output["url"] != "http://127.0.0.1:1/ingest" {
+		t.Errorf("unexpected output config %v", output)
+	}
+	if output["headers"].(map[string]interface{})["X-API-Key"] != config.RedactedValue {
+		t.Errorf("expected the api key header to be masked, got %v", output["headers"])
+	}
+	input := state["config"].(map[string]interface{})["inputs"].(map[string]interface{})["http"].([]interface{})[0].(map[string]interface{})
+	if keys := input["api_keys"].([]interface{}); len(keys) != 2 || keys[0] != config.RedactedValue {
+		t.Errorf("expected the input api keys to be masked, got %v", keys)
+	}
+}
@@ -1,24 +1,37 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/therealutkarshpriyadarshi/log/internal/checkpoint"
 	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
 	"github.com/therealutkarshpriyadarshi/log/internal/input"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
 	"github.com/therealutkarshpriyadarshi/log/internal/parser"
+	"github.com/therealutkarshpriyadarshi/log/internal/performance"
+	"github.com/therealutkarshpriyadarshi/log/internal/profiling"
+	"github.com/therealutkarshpriyadarshi/log/internal/server"
+	"github.com/therealutkarshpriyadarshi/log/internal/shutdown"
 	"github.com/therealutkarshpriyadarshi/log/internal/tailer"
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
 	configFile = flag.String("config", "config.yaml", "Path to configuration file")
+	strict     = flag.Bool("strict", true, "Reject configuration keys that don't match a setting")
 	version    = "0.2.0"
 )
 
@@ -33,32 +46,139 @@ func main() {
 
 func run() error {
 	// Load configuration
-	cfg, err := config.Load(*configFile)
+	loadOptions := config.LoadOptions{Strict: *strict}
+	cfg, err := config.LoadWithOptions(loadOptions, *configFile)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	// Initialize logger
-	logger := logging.New(logging.Config{
+	logCfg := logging.Config{
 		Level:  cfg.Logging.Level,
 		Format: cfg.Logging.Format,
-	})
+	}
+	if cfg.Logging.Sampling != nil {
+		logCfg.Sampling = logging.SamplingConfig{
+			Burst:    cfg.Logging.Sampling.Burst,
+			Interval: cfg.Logging.Sampling.Interval,
+		}
+	}
+	logger := logging.New(logCfg)
 	logging.SetGlobal(logger)
 
 	logger.Info().Str("version", version).Msg("Starting log aggregator")
 
+	// Apply performance tuning before any input starts
+	var perf performance.Settings
+	if cfg.Performance != nil {
+		perf = performance.Apply(*cfg.Performance)
+		logger.Info().
+			Int("gomaxprocs", perf.GOMAXPROCS).
+			Int("gc_percent", perf.GCPercent).
+			Bool("pooling", perf.EnablePooling).
+			Msg("Performance settings applied")
+	}
+
+	// Start metrics server if enabled
+	var metricsServer *http.Server
+	if cfg.Metrics != nil && cfg.Metrics.Enabled {
+		metricsServer, err = metrics.Serve(*cfg.Metrics, metrics.GetGlobalCollector())
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		logger.Info().Str("address", metricsServer.Addr).Msg("Metrics server started")
+	}
+
+	// Start profiling if enabled
+	var profiler *profiling.Profiler
+	if cfg.Profiling != nil && cfg.Profiling.Enabled {
+		profiler, err = profiling.Start(*cfg.Profiling, logger)
+		if err != nil {
+			return fmt.Errorf("failed to start profiling: %w", err)
+		}
+		logger.Info().Str("address", profiler.Addr()).Msg("Profiling server started")
+	}
+
+	// Initialize tracing; without a tracing config spans are no-ops
+	var tracingCfg tracing.Config
+	if cfg.Tracing != nil {
+		tracingCfg = tracing.Config{
+			Enabled:      cfg.Tracing.Enabled,
+			Endpoint:     cfg.Tracing.Endpoint,
+			SampleRate:   cfg.Tracing.SampleRate,
+			EnableStdout: cfg.Tracing.EnableStdout,
+		}
+	}
+	tracingProvider, err := tracing.NewProvider(context.Background(), tracingCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	if tracingCfg.Enabled {
+		logger.Info().Float64("sample_rate", tracingCfg.SampleRate).Msg("Tracing enabled")
+	}
+
+	// Open the dead letter queue for events outputs fail to deliver
+	var deadLetter *dlq.Queue
+	if cfg.DeadLetter != nil && cfg.DeadLetter.Enabled {
+		deadLetter, err = dlq.NewQueue(dlq.QueueConfig{
+			Dir:           cfg.DeadLetter.Dir,
+			MaxSize:       cfg.DeadLetter.MaxSize,
+			MaxAge:        cfg.DeadLetter.MaxAge,
+			FlushInterval: cfg.DeadLetter.FlushInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open dead letter queue: %w", err)
+		}
+		logger.Info().Str("dir", cfg.DeadLetter.Dir).Int64("size_bytes", deadLetter.Size()).Msg("Dead letter queue opened")
+	}
+
+	// Build the delivery pipeline for the configured output
+	var deadLetterWriter output.DeadLetterWriter
+	if deadLetter != nil {
+		deadLetterWriter = deadLetter
+	}
+	pipe, err := newPipeline(cfg, deadLetterWriter, logger)
+	if err != nil {
+		return err
+	}
+	logger.Info().Str("type", cfg.Output.Type).Bool("wal", pipe.wal != nil).Msg("Output pipeline started")
+
+	// Create the stages every input runs around its own transforms
+	shared, err := newSharedStages(cfg)
+	if err != nil {
+		return err
+	}
+	if shared.dedup != nil {
+		logger.Info().Strs("fields", cfg.Dedup.Fields).Msg("Deduplication enabled")
+	}
+
+	// Apply configuration changes on SIGHUP
+	reload := newReloader(*configFile, loadOptions, cfg, shared, pipe.router, logger)
+	stopReload := reload.listen()
+	defer stopReload()
+
 	var wg sync.WaitGroup
 	var inputs []input.Input
+	sizeLimit := input.SizeLimit{MaxBytes: cfg.Inputs.MaxEventBytes, Policy: cfg.Inputs.OversizePolicy}
 
 	// Process file inputs
-	for _, fileInput := range cfg.Inputs.Files {
+	var fileWg sync.WaitGroup
+	var fileStopsMu sync.Mutex
+	var fileStops []func()
+	for i, fileInput := range cfg.Inputs.Files {
 		fileInputCopy := fileInput
-		wg.Add(1)
+		fileWg.Add(1)
 		go func() {
-			defer wg.Done()
-			if err := processFileInput(fileInputCopy, logger); err != nil {
+			defer fileWg.Done()
+			stop, proc, err := processFileInput(fileInputCopy, sizeLimit, shared, perf, pipe, logger)
+			if err != nil {
 				logger.Error().Err(err).Msg("Failed to process file input")
+				return
 			}
+			reload.addInput(config.FileInputPath(i), proc, nil)
+			fileStopsMu.Lock()
+			fileStops = append(fileStops, stop)
+			fileStopsMu.Unlock()
 		}()
 	}
 
@@ -72,13 +192,16 @@ func run() error {
 			TLSCert:    syslogInput.TLSCert,
 			TLSKey:     syslogInput.TLSKey,
 			RateLimit:  syslogInput.RateLimit,
-			BufferSize: syslogInput.BufferSize,
+			BufferSize: perf.BufferSize(syslogInput.BufferSize),
+
+			MaxMessageBytes: syslogInput.MaxMessageBytes,
 		}
 
 		inp, err := input.NewSyslogInput(syslogInput.Name, syslogConfig, logger)
 		if err != nil {
 			return fmt.Errorf("failed to create syslog input '%s': %w", syslogInput.Name, err)
 		}
+		inp.SetSizeLimit(sizeLimit)
 
 		if err := inp.Start(); err != nil {
 			return fmt.Errorf("failed to start syslog input '%s': %w", syslogInput.Name, err)
@@ -87,11 +210,13 @@ func run() error {
 		inputs = append(inputs, inp)
 
 		// Process events from this input
+		proc := newProcessor(inputStages(shared, syslogInput.Parser, syslogInput.Transforms, logger))
+		reload.addInput(config.InputPath("syslog", syslogInput.Name), proc, inp)
 		wg.Add(1)
-		go func(i input.Input, parserCfg *config.ParserConfig, transforms []config.TransformConfig) {
+		go func(i input.Input) {
 			defer wg.Done()
-			processInputEvents(i, parserCfg, transforms, logger)
-		}(inp, syslogInput.Parser, syslogInput.Transforms)
+			processInputEvents(i, proc, pipe, logger)
+		}(inp)
 
 		logger.Info().Str("name", syslogInput.Name).Str("type", "syslog").Msg("Input started")
 	}
@@ -108,15 +233,19 @@ func run() error {
 			TLSEnabled:   httpInput.TLSEnabled,
 			TLSCert:      httpInput.TLSCert,
 			TLSKey:       httpInput.TLSKey,
-			BufferSize:   httpInput.BufferSize,
+			BufferSize:   perf.BufferSize(httpInput.BufferSize),
 			ReadTimeout:  httpInput.ReadTimeout,
 			WriteTimeout: httpInput.WriteTimeout,
 		}
+		if httpInput.Validation != nil {
+			httpConfig.SchemaPath = httpInput.Validation.SchemaPath
+		}
 
 		inp, err := input.NewHTTPInput(httpInput.Name, httpConfig, logger)
 		if err != nil {
 			return fmt.Errorf("failed to create HTTP input '%s': %w", httpInput.Name, err)
 		}
+		inp.SetSizeLimit(sizeLimit)
 
 		if err := inp.Start(); err != nil {
 			return fmt.Errorf("failed to start HTTP input '%s': %w", httpInput.Name, err)
@@ -125,11 +254,13 @@ func run() error {
 		inputs = append(inputs, inp)
 
 		// Process events from this input
+		proc := newProcessor(inputStages(shared, httpInput.Parser, httpInput.Transforms, logger))
+		reload.addInput(config.InputPath("http", httpInput.Name), proc, inp)
 		wg.Add(1)
-		go func(i input.Input, parserCfg *config.ParserConfig, transforms []config.TransformConfig) {
+		go func(i input.Input) {
 			defer wg.Done()
-			processInputEvents(i, parserCfg, transforms, logger)
-		}(inp, httpInput.Parser, httpInput.Transforms)
+			processInputEvents(i, proc, pipe, logger)
+		}(inp)
 
 		logger.Info().Str("name", httpInput.Name).Str("type", "http").Msg("Input started")
 	}
@@ -146,13 +277,29 @@ func run() error {
 			IncludePrevious:  k8sInput.IncludePrevious,
 			TailLines:        k8sInput.TailLines,
 			EnrichMetadata:   k8sInput.EnrichMetadata,
-			BufferSize:       k8sInput.BufferSize,
+			BufferSize:       perf.BufferSize(k8sInput.BufferSize),
+			ParseTimestamps:  k8sInput.ParseTimestamps == nil || *k8sInput.ParseTimestamps,
+		}
+
+		// Persist per-container offsets so restarts don't re-ingest logs
+		if k8sInput.CheckpointPath != "" || (k8sInput.Checkpoint != nil && k8sInput.Checkpoint.Type == checkpoint.StoreRedis) {
+			ckptMgr, err := newCheckpointManager(k8sInput.CheckpointPath, k8sInput.CheckpointInterval, k8sInput.Checkpoint, logger)
+			if err != nil {
+				return fmt.Errorf("failed to create checkpoint manager for Kubernetes input '%s': %w", k8sInput.Name, err)
+			}
+			if err := ckptMgr.Load(); err != nil {
+				logger.Warn().Err(err).Str("name", k8sInput.Name).Msg("Failed to load checkpoints, starting fresh")
+			}
+			ckptMgr.Start()
+			defer ckptMgr.Stop()
+			k8sConfig.Checkpoints = ckptMgr
 		}
 
 		inp, err := input.NewKubernetesInput(k8sInput.Name, k8sConfig, logger)
 		if err != nil {
 			return fmt.Errorf("failed to create Kubernetes input '%s': %w", k8sInput.Name, err)
 		}
+		inp.SetSizeLimit(sizeLimit)
 
 		if err := inp.Start(); err != nil {
 			return fmt.Errorf("failed to start Kubernetes input '%s': %w", k8sInput.Name, err)
@@ -161,43 +308,240 @@ func run() error {
 		inputs = append(inputs, inp)
 
 		// Process events from this input
+		proc := newProcessor(inputStages(shared, k8sInput.Parser, k8sInput.Transforms, logger))
+		reload.addInput(config.InputPath("kubernetes", k8sInput.Name), proc, inp)
 		wg.Add(1)
-		go func(i input.Input, parserCfg *config.ParserConfig, transforms []config.TransformConfig) {
+		go func(i input.Input) {
 			defer wg.Done()
-			processInputEvents(i, parserCfg, transforms, logger)
-		}(inp, k8sInput.Parser, k8sInput.Transforms)
+			processInputEvents(i, proc, pipe, logger)
+		}(inp)
 
 		logger.Info().Str("name", k8sInput.Name).Str("type", "kubernetes").Msg("Input started")
 	}
 
-	// Wait for shutdown signal
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	// Process gRPC inputs
+	for _, grpcInput := range cfg.Inputs.GRPC {
+		grpcConfig := &input.GRPCConfig{
+			Address:        grpcInput.Address,
+			APIKeys:        grpcInput.APIKeys,
+			RateLimit:      grpcInput.RateLimit,
+			MaxRecvMsgSize: grpcInput.MaxRecvMsgSize,
+			TLSEnabled:     grpcInput.TLSEnabled,
+			TLSCert:        grpcInput.TLSCert,
+			TLSKey:         grpcInput.TLSKey,
+			BufferSize:     perf.BufferSize(grpcInput.BufferSize),
+		}
 
-	logger.Info().Msg("Shutdown signal received")
+		inp, err := input.NewGRPCInput(grpcInput.Name, grpcConfig, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create gRPC input '%s': %w", grpcInput.Name, err)
+		}
+		inp.SetSizeLimit(sizeLimit)
 
-	// Stop all inputs
-	for _, inp := range inputs {
-		if err := inp.Stop(); err != nil {
-			logger.Error().Err(err).Str("name", inp.Name()).Msg("Failed to stop input")
+		if err := inp.Start(); err != nil {
+			return fmt.Errorf("failed to start gRPC input '%s': %w", grpcInput.Name, err)
+		}
+
+		inputs = append(inputs, inp)
+
+		// Process events from this input
+		proc := newProcessor(inputStages(shared, grpcInput.Parser, grpcInput.Transforms, logger))
+		reload.addInput(config.InputPath("grpc", grpcInput.Name), proc, inp)
+		wg.Add(1)
+		go func(i input.Input) {
+			defer wg.Done()
+			processInputEvents(i, proc, pipe, logger)
+		}(inp)
+
+		logger.Info().Str("name", grpcInput.Name).Str("type", "grpc").Msg("Input started")
+	}
+
+	// Process OTLP inputs
+	for _, otlpInput := range cfg.Inputs.OTLP {
+		otlpConfig := &input.HTTPConfig{
+			Address:      otlpInput.Address,
+			Path:         otlpInput.Path,
+			APIKeys:      otlpInput.APIKeys,
+			RateLimit:    otlpInput.RateLimit,
+			MaxBodySize:  otlpInput.MaxBodySize,
+			TLSEnabled:   otlpInput.TLSEnabled,
+			TLSCert:      otlpInput.TLSCert,
+			TLSKey:       otlpInput.TLSKey,
+			BufferSize:   perf.BufferSize(otlpInput.BufferSize),
+			ReadTimeout:  otlpInput.ReadTimeout,
+			WriteTimeout: otlpInput.WriteTimeout,
+		}
+
+		inp, err := input.NewOTLPInput(otlpInput.Name, otlpConfig, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create OTLP input '%s': %w", otlpInput.Name, err)
+		}
+		inp.SetSizeLimit(sizeLimit)
+
+		if err := inp.Start(); err != nil {
+			return fmt.Errorf("failed to start OTLP input '%s': %w", otlpInput.Name, err)
+		}
+
+		inputs = append(inputs, inp)
+
+		// Process events from this input
+		proc := newProcessor(inputStages(shared, otlpInput.Parser, otlpInput.Transforms, logger))
+		reload.addInput(config.InputPath("otlp", otlpInput.Name), proc, inp)
+		wg.Add(1)
+		go func(i input.Input) {
+			defer wg.Done()
+			processInputEvents(i, proc, pipe, logger)
+		}(inp)
+
+		logger.Info().Str("name", otlpInput.Name).Str("type", "otlp").Msg("Input started")
+	}
+
+	// Start health server if enabled, reporting the health of every input
+	// and output
+	var healthServer *server.Server
+	if cfg.Health != nil && cfg.Health.Enabled {
+		checker := health.NewChecker(cfg.Health.Timeout)
+		registerComponentChecks(checker, inputs, pipe.router)
+		registerPressureChecks(checker, cfg.Health, pipe, deadLetter)
+
+		serverCfg := server.Config{
+			HealthAddress: cfg.Health.Address,
+			LivenessPath:  cfg.Health.LivenessPath,
+			ReadinessPath: cfg.Health.ReadinessPath,
+			HealthChecker: checker,
+			Logger:        logger,
+		}
+		if cfg.Health.Debug {
+			serverCfg.DebugHandler = debugHandler(pipe, reload.running)
+		}
+		healthServer = server.New(serverCfg)
+		if err := healthServer.Start(); err != nil {
+			return fmt.Errorf("failed to start health server: %w", err)
 		}
 	}
 
-	// Wait for all goroutines to finish
-	wg.Wait()
+	// Shut down in order: stop the inputs, then drain the pipeline into the
+	// outputs, then release the remaining components
+	shutdownCfg := shutdown.Config{Logger: logger}
+	if cfg.Shutdown != nil {
+		shutdownCfg.Timeout = cfg.Shutdown.Timeout
+		shutdownCfg.StageTimeout = cfg.Shutdown.StageTimeout
+	}
+	shutdownMgr := shutdown.New(shutdownCfg)
+
+	shutdownMgr.RegisterStage("inputs", func(ctx context.Context) (int, error) {
+		before := pipe.enqueuedCount()
+
+		// File inputs may still be starting
+		fileWg.Wait()
+		fileStopsMu.Lock()
+		for _, stop := range fileStops {
+			stop()
+		}
+		fileStopsMu.Unlock()
+
+		for _, inp := range inputs {
+			if err := inp.Stop(); err != nil {
+				logger.Error().Err(err).Str("name", inp.Name()).Msg("Failed to stop input")
+			}
+		}
+
+		// Wait for the events already received to be processed
+		wg.Wait()
+		return int(pipe.enqueuedCount() - before), nil
+	})
+	pipe.registerShutdown(shutdownMgr)
+
+	if healthServer != nil {
+		shutdownMgr.RegisterFunc("health server", healthServer.Stop)
+	}
+	if metricsServer != nil {
+		shutdownMgr.RegisterFunc("metrics server", metricsServer.Shutdown)
+	}
+	if deadLetter != nil {
+		shutdownMgr.RegisterFunc("dead letter queue", func(context.Context) error {
+			return deadLetter.Close()
+		})
+	}
+	// Stopping the profiler writes the CPU and heap profiles
+	if profiler != nil {
+		shutdownMgr.RegisterFunc("profiler", func(context.Context) error {
+			return profiler.Stop()
+		})
+	}
+	shutdownMgr.RegisterFunc("tracing", tracingProvider.Shutdown)
+
+	// Wait for shutdown signal
+	shutdownMgr.WaitForSignal()
 
 	return nil
 }
 
-func processFileInput(fileInput config.FileInputConfig, logger *logging.Logger) error {
+// registerComponentChecks registers a check for each input and each output
+// of the router, so readiness reports an unreachable destination: degraded
+// while another output is reachable, unhealthy otherwise
+func registerComponentChecks(checker *health.Checker, inputs []input.Input, router *output.Router) {
+	input.RegisterHealthChecks(checker, inputs)
+	output.RegisterHealthChecks(checker, router.GetOutputs())
+}
+
+// registerPressureChecks registers the checks that make readiness reflect
+// pipeline stress: a buffer sustained above its high-water mark is degraded,
+// and a dead letter queue growing faster than it drains is unhealthy
+func registerPressureChecks(checker *health.Checker, cfg *config.HealthConfig, pipe *pipeline, deadLetter *dlq.Queue) {
+	bufferMonitor := health.NewUtilizationMonitor(pipe.buffer.Utilization, health.UtilizationConfig{
+		HighWaterMark: cfg.BufferHighWaterMark,
+		Sustain:       cfg.BufferSustain,
+	})
+	checker.Register("buffer", bufferMonitor.Check())
+
+	if deadLetter != nil {
+		dlqMonitor := health.NewGrowthMonitor(deadLetter.Size, health.GrowthConfig{
+			Window:  cfg.DLQGrowthWindow,
+			MaxRate: cfg.DLQMaxGrowthRate,
+		})
+		checker.Register("dead_letter_queue", dlqMonitor.Check())
+	}
+}
+
+// newCheckpointManager creates a checkpoint manager on the store selected by
+// cfg, the files in dir unless another store is configured. Checkpoints
+// recovered from a backup are logged.
+func newCheckpointManager(dir string, interval time.Duration, cfg *config.CheckpointConfig, logger *logging.Logger) (*checkpoint.Manager, error) {
+	storeConfig := checkpoint.StoreConfig{
+		Dir: dir,
+		OnRecover: func(key string, err error) {
+			logger.Warn().Err(err).Str("key", key).Str("dir", dir).Msg("Recovered previous checkpoint")
+		},
+	}
+	if cfg != nil {
+		storeConfig.Type = cfg.Type
+		storeConfig.Address = cfg.Address
+		storeConfig.Password = cfg.Password
+		storeConfig.DB = cfg.DB
+		storeConfig.Key = cfg.Key
+	}
+
+	store, err := checkpoint.NewStore(storeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint.NewManagerWithStore(store, interval), nil
+}
+
+// processFileInput starts tailing a file input. It returns the processor
+// running the input's parser and transforms, and a function that stops the
+// tailer once its events have been processed.
+func processFileInput(fileInput config.FileInputConfig, sizeLimit input.SizeLimit, shared *sharedStages, perf performance.Settings, pipe *pipeline, logger *logging.Logger) (func(), *processor, error) {
 	// Create checkpoint manager
-	ckptMgr, err := checkpoint.NewManager(
+	ckptMgr, err := newCheckpointManager(
 		fileInput.CheckpointPath,
 		fileInput.CheckpointInterval,
+		fileInput.Checkpoint,
+		logger,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create checkpoint manager: %w", err)
+		return nil, nil, fmt.Errorf("failed to create checkpoint manager: %w", err)
 	}
 
 	// Load existing checkpoints
@@ -206,86 +550,93 @@ func processFileInput(fileInput config.FileInputConfig, logger *logging.Logger)
 	}
 
 	// Start checkpoint manager
+	ckptMgr.SetResetToEnd(fileInput.ResetToEnd)
 	ckptMgr.Start()
 
+	// Serve the checkpoint admin endpoint if configured
+	var adminServer *http.Server
+	if fileInput.AdminAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/checkpoints", ckptMgr.Handler())
+		adminServer = &http.Server{
+			Addr:         fileInput.AdminAddress,
+			Handler:      mux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error().Err(err).Msg("Checkpoint admin server error")
+			}
+		}()
+		logger.Info().Str("address", fileInput.AdminAddress).Msg("Checkpoint admin endpoint started")
+	}
+
 	// Create tailer
 	t, err := tailer.New(fileInput.Paths, ckptMgr, logger)
 	if err != nil {
-		return fmt.Errorf("failed to create tailer: %w", err)
+		return nil, nil, fmt.Errorf("failed to create tailer: %w", err)
 	}
-
-	// Create parser if configured
-	var logParser parser.Parser
-	if fileInput.Parser != nil {
-		parserCfg := &parser.ParserConfig{
-			Type:         parser.ParserType(fileInput.Parser.Type),
-			Pattern:      fileInput.Parser.Pattern,
-			GrokPattern:  fileInput.Parser.GrokPattern,
-			TimeFormat:   fileInput.Parser.TimeFormat,
-			TimeField:    fileInput.Parser.TimeField,
-			LevelField:   fileInput.Parser.LevelField,
-			MessageField: fileInput.Parser.MessageField,
-			CustomFields: fileInput.Parser.CustomFields,
-		}
-
-		if fileInput.Parser.Multiline != nil {
-			parserCfg.Multiline = &parser.MultilineConfig{
-				Pattern:  fileInput.Parser.Multiline.Pattern,
-				Negate:   fileInput.Parser.Multiline.Negate,
-				Match:    fileInput.Parser.Multiline.Match,
-				MaxLines: fileInput.Parser.Multiline.MaxLines,
-				Timeout:  fileInput.Parser.Multiline.Timeout,
-			}
-		}
-
-		logParser, err = parser.New(parserCfg)
+	if err := t.SetExcludePatterns(fileInput.ExcludePatterns); err != nil {
+		return nil, nil, fmt.Errorf("failed to configure tailer: %w", err)
+	}
+	t.SetBufferSize(perf.ChannelBufferSize)
+	t.SetMaxConcurrentReads(perf.MaxConcurrentReads)
+	t.SetSizeLimit(sizeLimit)
+	t.SetMaxLineBytes(fileInput.MaxLineBytes)
+
+	// Lines are joined by the assembler before they reach the parser
+	var assembler *parser.MultilineAssembler
+	if fileInput.Parser != nil && fileInput.Parser.Multiline != nil {
+		assembler, err = parser.NewMultilineAssembler(multilineConfig(fileInput.Parser.Multiline))
 		if err != nil {
-			return fmt.Errorf("failed to create parser: %w", err)
+			return nil, nil, fmt.Errorf("failed to create multiline assembler: %w", err)
 		}
-		logger.Info().Str("parser", logParser.Name()).Msg("Parser initialized")
+		logger.Info().Msg("Multiline assembler initialized")
 	}
 
-	// Create transform pipeline if configured
-	var transformPipeline *parser.TransformPipeline
-	if len(fileInput.Transforms) > 0 {
-		transformConfigs := make([]parser.TransformConfig, len(fileInput.Transforms))
-		for i, tc := range fileInput.Transforms {
-			transformConfigs[i] = parser.TransformConfig{
-				Type:          tc.Type,
-				Fields:        tc.Fields,
-				IncludeFields: tc.IncludeFields,
-				ExcludeFields: tc.ExcludeFields,
-				Rename:        tc.Rename,
-				Add:           tc.Add,
-				Patterns:      tc.Patterns,
-				FieldSplit:    tc.FieldSplit,
-				ValueSplit:    tc.ValueSplit,
-				Prefix:        tc.Prefix,
-			}
-		}
-
-		transformPipeline, err = parser.NewTransformPipeline(transformConfigs)
-		if err != nil {
-			return fmt.Errorf("failed to create transform pipeline: %w", err)
-		}
-		logger.Info().Int("transforms", len(transformConfigs)).Msg("Transform pipeline initialized")
+	// Create parser and transform pipeline if configured
+	initial, err := newStages(shared, fileParserConfig(fileInput.Parser), fileInput.Transforms)
+	if err != nil {
+		return nil, nil, err
+	}
+	if initial.parser != nil {
+		logger.Info().Str("parser", initial.parser.Name()).Msg("Parser initialized")
+	}
+	if initial.transforms != nil {
+		logger.Info().Int("transforms", initial.transforms.Len()).Msg("Transform pipeline initialized")
 	}
+	proc := newProcessor(initial)
 
 	// Start tailing
 	if err := t.Start(); err != nil {
-		return fmt.Errorf("failed to start tailer: %w", err)
+		return nil, nil, fmt.Errorf("failed to start tailer: %w", err)
 	}
 
-	// Process events
+	events := t.Events()
+	if assembler != nil {
+		events = assembler.Run(context.Background(), events)
+	}
+
+	// Process events; parse failures are logged sampled, as a malformed
+	// file can fail on every line
+	parseLogger := logger.RateLimited()
+	done := make(chan struct{})
 	go func() {
-		for event := range t.Events() {
+		defer close(done)
+		for event := range events {
+			st := proc.load()
+
 			// If parser is configured, parse the log line
-			if logParser != nil {
-				parsedEvent, err := logParser.Parse(event.Message, event.Source)
+			if st.parser != nil {
+				parsedEvent, err := parseEvent(st.parser, event)
 				if err != nil {
-					logger.Warn().Err(err).Str("line", event.Message).Msg("Failed to parse log line")
+					parseLogger.Warn().Err(err).Str("line", event.Message).Msg("Failed to parse log line")
 					// Output raw line if parsing fails
-					fmt.Println(event.Message)
+					if event = st.applyShared(event); event == nil {
+						continue
+					}
+					writeEvent(pipe, event)
 					continue
 				}
 
@@ -293,121 +644,106 @@ func processFileInput(fileInput config.FileInputConfig, logger *logging.Logger)
 				parsedEvent.Raw = event.Message
 
 				// Apply transformations if configured
-				if transformPipeline != nil {
-					parsedEvent, err = transformPipeline.Transform(parsedEvent)
+				if st.transforms != nil {
+					parsedEvent, err = transformEvent(st.transforms, parsedEvent)
+					if errors.Is(err, parser.ErrDropEvent) {
+						continue
+					}
 					if err != nil {
 						logger.Warn().Err(err).Msg("Failed to transform event")
+						if parsedEvent == nil {
+							continue
+						}
 					}
 				}
 
 				// Output parsed event as JSON
-				output, err := json.Marshal(parsedEvent)
-				if err != nil {
-					logger.Warn().Err(err).Msg("Failed to marshal event")
-					fmt.Println(event.Message)
-				} else {
-					fmt.Println(string(output))
-				}
+				writeEvent(pipe, parsedEvent)
 			} else {
 				// No parser configured, output raw line
-				fmt.Print(event.Message)
+				if event = st.applyShared(event); event == nil {
+					continue
+				}
+				writeEvent(pipe, event)
 			}
 		}
 	}()
 
-	// Set up cleanup on shutdown
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
-
+	// Stop the tailer and wait for its remaining events before saving the
+	// final checkpoints
+	stop := func() {
 		logger.Info().Msg("Stopping tailer")
+		if adminServer != nil {
+			adminServer.Close()
+		}
 		t.Stop()
+		<-done
 		ckptMgr.Stop()
-	}()
+	}
 
-	return nil
+	return stop, proc, nil
 }
 
-func processInputEvents(inp input.Input, parserCfg *config.ParserConfig, transforms []config.TransformConfig, logger *logging.Logger) {
-	// Create parser if configured
-	var logParser parser.Parser
-	var err error
-
-	if parserCfg != nil {
-		pCfg := &parser.ParserConfig{
-			Type:         parser.ParserType(parserCfg.Type),
-			Pattern:      parserCfg.Pattern,
-			GrokPattern:  parserCfg.GrokPattern,
-			TimeFormat:   parserCfg.TimeFormat,
-			TimeField:    parserCfg.TimeField,
-			LevelField:   parserCfg.LevelField,
-			MessageField: parserCfg.MessageField,
-			CustomFields: parserCfg.CustomFields,
-		}
-
-		if parserCfg.Multiline != nil {
-			pCfg.Multiline = &parser.MultilineConfig{
-				Pattern:  parserCfg.Multiline.Pattern,
-				Negate:   parserCfg.Multiline.Negate,
-				Match:    parserCfg.Multiline.Match,
-				MaxLines: parserCfg.Multiline.MaxLines,
-				Timeout:  parserCfg.Multiline.Timeout,
-			}
-		}
+// inputStages creates an input's initial processing stages, with the shared
+// stages around the input's transforms. A parser or transform pipeline that
+// fails to build is logged and skipped.
+func inputStages(shared *sharedStages, parserCfg *config.ParserConfig, transforms []config.TransformConfig, logger *logging.Logger) *stages {
+	s := &stages{}
 
-		logParser, err = parser.New(pCfg)
+	// Create parser if configured
+	if pCfg := parserConfig(parserCfg); pCfg != nil {
+		logParser, err := parser.New(pCfg)
 		if err != nil {
 			logger.Error().Err(err).Msg("Failed to create parser")
 		} else {
+			s.parser = logParser
 			logger.Info().Str("parser", logParser.Name()).Msg("Parser initialized for input")
 		}
 	}
 
-	// Create transform pipeline if configured
-	var transformPipeline *parser.TransformPipeline
-	if len(transforms) > 0 {
-		transformConfigs := make([]parser.TransformConfig, len(transforms))
-		for i, tc := range transforms {
-			transformConfigs[i] = parser.TransformConfig{
-				Type:          tc.Type,
-				Fields:        tc.Fields,
-				IncludeFields: tc.IncludeFields,
-				ExcludeFields: tc.ExcludeFields,
-				Rename:        tc.Rename,
-				Add:           tc.Add,
-				Patterns:      tc.Patterns,
-				FieldSplit:    tc.FieldSplit,
-				ValueSplit:    tc.ValueSplit,
-				Prefix:        tc.Prefix,
-			}
-		}
+	// Create the shared stages for events that skip the transforms
+	bypass, err := shared.pipeline(nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create shared stages")
+	}
+	s.bypass = bypass
 
-		transformPipeline, err = parser.NewTransformPipeline(transformConfigs)
-		if err != nil {
-			logger.Error().Err(err).Msg("Failed to create transform pipeline")
-		} else {
-			logger.Info().Int("transforms", len(transformConfigs)).Msg("Transform pipeline initialized for input")
-		}
+	// Create transform pipeline if configured
+	transformPipeline, err := shared.pipeline(transforms)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create transform pipeline")
+	} else if transformPipeline != nil {
+		s.transforms = transformPipeline
+		logger.Info().Int("transforms", transformPipeline.Len()).Msg("Transform pipeline initialized for input")
 	}
 
-	// Process events
+	return s
+}
+
+// processInputEvents processes an input's events with the processor's
+// current stages
+func processInputEvents(inp input.Input, proc *processor, pipe *pipeline, logger *logging.Logger) {
+	// Process events; parse failures are logged sampled
+	parseLogger := logger.RateLimited()
 	for event := range inp.Events() {
+		st := proc.load()
+
 		// If parser is configured, parse the log line
-		if logParser != nil {
-			parsedEvent, err := logParser.Parse(event.Message, event.Source)
+		if st.parser != nil {
+			parsedEvent, err := parseEvent(st.parser, event)
 			if err != nil {
-				logger.Warn().Err(err).Str("line", event.Message).Msg("Failed to parse log line")
+				parseLogger.Warn().Err(err).Str("line", event.Message).Msg("Failed to parse log line")
 				// Output as-is with existing fields
-				output, _ := json.Marshal(event)
-				fmt.Println(string(output))
+				if enriched := st.applyShared(event); enriched != nil {
+					writeEvent(pipe, enriched)
+				}
 				continue
 			}
 
 			// Merge existing fields from event (e.g., Kubernetes metadata)
 			if event.Fields != nil {
 				if parsedEvent.Fields == nil {
-					parsedEvent.Fields = make(map[string]interface{})
+					parsedEvent.Fields = make(map[string]string)
 				}
 				for k, v := range event.Fields {
 					parsedEvent.Fields[k] = v
@@ -418,29 +754,73 @@ func processInputEvents(inp input.Input, parserCfg *config.ParserConfig, transfo
 			parsedEvent.Raw = event.Message
 
 			// Apply transformations if configured
-			if transformPipeline != nil {
-				parsedEvent, err = transformPipeline.Transform(parsedEvent)
+			if st.transforms != nil {
+				parsedEvent, err = transformEvent(st.transforms, parsedEvent)
+				if errors.Is(err, parser.ErrDropEvent) {
+					continue
+				}
 				if err != nil {
 					logger.Warn().Err(err).Msg("Failed to transform event")
+					if parsedEvent == nil {
+						continue
+					}
 				}
 			}
 
 			// Output parsed event as JSON
-			output, err := json.Marshal(parsedEvent)
-			if err != nil {
-				logger.Warn().Err(err).Msg("Failed to marshal event")
-				fmt.Println(event.Message)
-			} else {
-				fmt.Println(string(output))
-			}
+			writeEvent(pipe, parsedEvent)
 		} else {
-			// No parser configured, output with fields
-			output, err := json.Marshal(event)
-			if err != nil {
-				fmt.Println(event.Message)
-			} else {
-				fmt.Println(string(output))
+			// Apply transformations if configured
+			if st.transforms != nil {
+				transformed, err := transformEvent(st.transforms, event)
+				if errors.Is(err, parser.ErrDropEvent) {
+					continue
+				}
+				if err != nil {
+					logger.Warn().Err(err).Msg("Failed to transform event")
+				}
+				if transformed != nil {
+					event = transformed
+				}
 			}
+
+			// No parser configured, output with fields
+			writeEvent(pipe, event)
 		}
 	}
 }
+
+// parseEvent parses the event's message in a parser.parse span that is part
+// of the event's trace
+func parseEvent(logParser parser.Parser, event *types.LogEvent) (*types.LogEvent, error) {
+	_, span := tracing.TraceParser(tracing.EventContext(event), tracing.Tracer(), logParser.Name())
+	parsed, err := logParser.Parse(event.Message, event.Source)
+	tracing.EndSpan(span, err)
+	if parsed != nil {
+		parsed.Context = event.Context
+	}
+	return parsed, err
+}
+
+// transformEvent applies the transform pipeline in a transform.apply span.
+// Dropped events are marked on the span rather than recorded as errors.
+func transformEvent(pipeline *parser.TransformPipeline, event *types.LogEvent) (*types.LogEvent, error) {
+	_, span := tracing.TraceTransform(tracing.EventContext(event), tracing.Tracer(), pipeline.Len())
+	transformed, err := pipeline.Transform(event)
+	if errors.Is(err, parser.ErrDropEvent) {
+		span.SetAttributes(attribute.Bool("event.dropped", true))
+		span.End()
+		return nil, err
+	}
+	tracing.EndSpan(span, err)
+	if transformed != nil && transformed.Context == nil {
+		transformed.Context = event.Context
+	}
+	return transformed, err
+}
+
+// writeEvent normalizes an event and hands it to the pipeline
+func writeEvent(pipe *pipeline, event *types.LogEvent) {
+	event.Normalize()
+	pipe.write(event)
+}
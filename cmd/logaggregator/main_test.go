@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
+	"github.com/therealutkarshpriyadarshi/log/internal/input"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// channelInput emits the events written to its channel
+type channelInput struct {
+	events chan *types.LogEvent
+}
+
+func (c *channelInput) Name() string                   { return "channel" }
+func (c *channelInput) Type() string                   { return "channel" }
+func (c *channelInput) Start() error                   { return nil }
+func (c *channelInput) Stop() error                    { return nil }
+func (c *channelInput) Events() <-chan *types.LogEvent { return c.events }
+func (c *channelInput) Health() input.Health           { return input.Health{} }
+
+// capturingOutput records the events sent to it
+type capturingOutput struct {
+	bufferingOutput
+}
+
+func (c *capturingOutput) Send(_ context.Context, event *types.LogEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, event)
+	return nil
+}
+
+func (c *capturingOutput) received() []*types.LogEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*types.LogEvent(nil), c.sent...)
+}
+
+var captureOutput = &capturingOutput{}
+
+func init() {
+	output.Register("test-capturing", func(map[string]interface{}) (output.Output, error) {
+		return captureOutput, nil
+	})
+	output.Register("test-health", func(settings map[string]interface{}) (output.Output, error) {
+		name, _ := settings["name"].(string)
+		return output.NewMemoryOutput(name), nil
+	})
+}
+
+// startCapturePipeline starts a pipeline delivering to captureOutput
+func startCapturePipeline(t *testing.T, logger *logging.Logger) *pipeline {
+	t.Helper()
+
+	captureOutput.mu.Lock()
+	captureOutput.sent = nil
+	captureOutput.mu.Unlock()
+
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{{Type: "test-capturing"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 64})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	pipe := startPipeline(rb, nil, router, wal.CoordinatorConfig{}, logger)
+	t.Cleanup(pipe.cancel)
+	return pipe
+}
+
+// waitForEvents returns the captured events once there are n of them
+func waitForEvents(t *testing.T, n int) []*types.LogEvent {
+	t.Helper()
+
+	var events []*types.LogEvent
+	deadline := time.Now().Add(5 * time.Second)
+	for len(events) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		events = captureOutput.received()
+	}
+	if len(events) != n {
+		t.Fatalf("expected %d events, got %d", n, len(events))
+	}
+	return events
+}
+
+func TestProcessInputEventsEnrichment(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	pipe := startCapturePipeline(t, logger)
+
+	enrichment := &config.EnrichmentConfig{
+		Fields: map[string]string{"env": "production", "namespace": "default"},
+		PID:    true,
+	}
+	parserCfg := &config.ParserConfig{Type: "json"}
+	transforms := []config.TransformConfig{{Type: "add", Add: map[string]string{"team": "payments"}}}
+
+	inp := &channelInput{events: make(chan *types.LogEvent, 3)}
+	inp.events <- &types.LogEvent{Message: `{"message":"parsed"}`, Fields: map[string]string{"namespace": "payments"}}
+	inp.events <- &types.LogEvent{Message: "not json", Fields: map[string]string{"namespace": "payments"}}
+	inp.events <- &types.LogEvent{Message: `{"message":"shadowed","env":"staging"}`}
+	close(inp.events)
+
+	processInputEvents(inp, newProcessor(inputStages(&sharedStages{enrichment: enrichment}, parserCfg, transforms, logger)), pipe, logger)
+
+	events := waitForEvents(t, 3)
+	for i, event := range events {
+		if event.Fields["pid"] == "" {
+			t.Errorf("event %d: expected the pid field, got %v", i, event.Fields)
+		}
+	}
+
+	// Input and parsed fields are not overwritten by the enrichment
+	if events[0].Fields["namespace"] != "payments" || events[0].Fields["env"] != "production" || events[0].Fields["team"] != "payments" {
+		t.Errorf("parsed event: unexpected fields %v", events[0].Fields)
+	}
+	if events[1].Fields["namespace"] != "payments" || events[1].Fields["env"] != "production" {
+		t.Errorf("unparsed event: unexpected fields %v", events[1].Fields)
+	}
+	if events[2].Fields["env"] != "staging" || events[2].Fields["namespace"] != "default" {
+		t.Errorf("shadowed event: unexpected fields %v", events[2].Fields)
+	}
+}
+
+func TestProcessInputEventsDedup(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	pipe := startCapturePipeline(t, logger)
+
+	shared, err := newSharedStages(&config.Config{Dedup: &config.DedupConfig{Enabled: true, Fields: []string{"message"}}})
+	if err != nil {
+		t.Fatalf("newSharedStages() error = %v", err)
+	}
+	parserCfg := &config.ParserConfig{Type: "json"}
+
+	// Both inputs share the deduplication, with or without parsing
+	for _, messages := range [][]string{
+		{`{"message":"one"}`, `{"message":"one"}`, "raw"},
+		{`{"message":"one"}`, "raw", `{"message":"two"}`},
+	} {
+		inp := &channelInput{events: make(chan *types.LogEvent, len(messages))}
+		for _, message := range messages {
+			inp.events <- &types.LogEvent{Message: message}
+		}
+		close(inp.events)
+		processInputEvents(inp, newProcessor(inputStages(shared, parserCfg, nil, logger)), pipe, logger)
+	}
+
+	events := waitForEvents(t, 3)
+	for i, want := range []string{"one", "raw", "two"} {
+		if events[i].Message != want {
+			t.Errorf("event %d = %q, want %q", i, events[i].Message, want)
+		}
+	}
+	if stats := shared.dedup.Stats(); stats.Duplicates != 3 {
+		t.Errorf("expected 3 duplicates dropped, got %d", stats.Duplicates)
+	}
+}
+
+func TestInputStagesWithoutSharedStages(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+
+	st := inputStages(&sharedStages{}, nil, nil, logger)
+	if st.transforms != nil || st.bypass != nil {
+		t.Errorf("expected no transforms without shared stages or transforms")
+	}
+
+	if event := st.applyShared(&types.LogEvent{Message: "plain"}); event.Fields != nil {
+		t.Errorf("expected no fields, got %v", event.Fields)
+	}
+}
+
+func TestRegisterComponentChecks(t *testing.T) {
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{
+			{Type: "test-health", Name: "elasticsearch"},
+			{Type: "test-health", Name: "s3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	defer router.Close()
+
+	checker := health.NewChecker(time.Second)
+	registerComponentChecks(checker, nil, router)
+
+	ctx := context.Background()
+	if status := checker.OverallStatus(ctx); status != health.StatusHealthy {
+		t.Fatalf("expected healthy with every output reachable, got %s", status)
+	}
+
+	// An unreachable output degrades readiness while another is reachable
+	outputs := router.GetOutputs()
+	outputs[0].Close()
+	if status := checker.OverallStatus(ctx); status != health.StatusDegraded {
+		t.Errorf("expected degraded with one output down, got %s", status)
+	}
+	if component, ok := checker.CheckComponent(ctx, "output:elasticsearch"); !ok || component.Status != health.StatusDegraded {
+		t.Errorf("expected the elasticsearch output degraded, got %+v", component)
+	}
+
+	outputs[1].Close()
+	if status := checker.OverallStatus(ctx); status != health.StatusUnhealthy {
+		t.Errorf("expected unhealthy with every output down, got %s", status)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/shutdown"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/internal/wiring"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// pipeline is the single path from the inputs to the outputs. Events are
+// recorded in the WAL when it is enabled, queued in the ring buffer and sent
+// to the output router, stdout included, by a single consumer.
+// With both a WAL and a router, a WAL coordinator commits the delivered
+// events and replays the uncommitted ones on startup. With the block
+// backpressure strategy a full buffer blocks the inputs' processing
+// goroutines, so inputs stop reading, or reject requests, while the outputs
+// lag. Events whose timestamp is skewed from the wall clock are flagged,
+// clamped or dead-lettered before they are buffered when the skew check is
+// enabled. With a memory pressure threshold, the output batchers are flushed
+// while the heap is over it. In ordered mode the router sends to one output
+// after another and the outputs flush one batch at a time, so each output
+// receives events in the order they were buffered. Metrics configured for
+// extraction are recorded from each event as it enters the pipeline.
+type pipeline struct {
+	buffer      *buffer.RingBuffer
+	wal         *wal.WAL
+	router      *output.Router
+	coordinator *wal.Coordinator   // nil without a WAL
+	skew        *skewChecker       // nil when disabled
+	pressure    *pressureRelief    // nil when disabled
+	extractor   *metrics.Extractor // nil without extraction rules
+	logger      *logging.Logger
+	extractLog  *logging.Logger // rate limited, for extraction failures
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	enqueued atomic.Int64
+}
+
+// newPipeline builds the delivery pipeline for the configured output
+func newPipeline(cfg *config.Config, deadLetter output.DeadLetterWriter, logger *logging.Logger) (*pipeline, error) {
+	routerCfg, err := wiring.RouterConfig(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	extractor, err := newExtractor(cfg.Metrics, metrics.GetGlobalCollector())
+	if err != nil {
+		return nil, err
+	}
+	routerCfg.Ordered = cfg.Ordered
+
+	router, err := output.NewRouter(*routerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outputs: %w", err)
+	}
+	if deadLetter != nil {
+		router.SetDeadLetter(deadLetter)
+	}
+	undelivered := logger.RateLimited()
+	router.OnAllFailed(func(events []*types.LogEvent, err error) {
+		undelivered.Error().Err(err).Int("events", len(events)).Bool("dead_lettered", deadLetter != nil).Msg("Every output failed to send events")
+	})
+
+	var bufferCfg buffer.RingBufferConfig
+	if cfg.Buffer != nil {
+		bufferCfg = buffer.RingBufferConfig{
+			Size:                 cfg.Buffer.Size,
+			BackpressureStrategy: buffer.BackpressureStrategy(cfg.Buffer.BackpressureStrategy),
+			SampleRate:           cfg.Buffer.SampleRate,
+			BlockTimeout:         cfg.Buffer.BlockTimeout,
+		}
+	}
+	rb, err := buffer.NewRingBuffer(bufferCfg)
+	if err != nil {
+		router.Close()
+		return nil, fmt.Errorf("failed to create buffer: %w", err)
+	}
+
+	var w *wal.WAL
+	var commit wal.CoordinatorConfig
+	if cfg.WAL != nil && cfg.WAL.Enabled {
+		commit.CommitInterval = cfg.WAL.CommitInterval
+		w, err = wal.NewWAL(wal.WALConfig{
+			Dir:              cfg.WAL.Dir,
+			SegmentSize:      cfg.WAL.SegmentSize,
+			MaxSegments:      cfg.WAL.MaxSegments,
+			SyncInterval:     cfg.WAL.SyncInterval,
+			CompactionPolicy: wal.CompactionPolicy(cfg.WAL.CompactionPolicy),
+			SegmentMaxAge:    cfg.WAL.SegmentMaxAge,
+			MmapReads:        cfg.WAL.MmapReads,
+			GroupCommit:      cfg.WAL.GroupCommit,
+		})
+		if err != nil {
+			router.Close()
+			return nil, fmt.Errorf("failed to open WAL: %w", err)
+		}
+	}
+
+	p := startPipeline(rb, w, router, commit, logger)
+	p.skew = newSkewChecker(cfg.TimestampSkew, deadLetter)
+	p.extractor = extractor
+	if p.pressure = newPressureRelief(cfg.Performance, p, logger); p.pressure != nil {
+		p.pressure.start(*cfg.Performance.MemoryPressure)
+	}
+	return p, nil
+}
+
+// newExtractor creates the extractor of the configured metric extraction
+// rules, registering its metrics on the collector's registry, or returns
+// nil when extraction is disabled
+func newExtractor(cfg *config.MetricsConfig, collector *metrics.Collector) (*metrics.Extractor, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	rules := metrics.RulesFromConfig(cfg.Extraction)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	extractor, err := collector.NewExtractor(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric extraction: %w", err)
+	}
+	return extractor, nil
+}
+
+// startPipeline starts the consumer sending buffered events to the router.
+// With a WAL, the uncommitted WAL entries are replayed first.
+func startPipeline(rb *buffer.RingBuffer, w *wal.WAL, router *output.Router, commit wal.CoordinatorConfig, logger *logging.Logger) *pipeline {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &pipeline{
+		buffer: rb,
+		wal:    w,
+		router: router,
+		logger: logger,
+		cancel: cancel,
+		done:   make(chan struct{}),
+
+		extractLog: logger.RateLimited(),
+	}
+	if w != nil {
+		p.coordinator = wal.NewCoordinator(w, rb, router, commit, logger)
+	}
+	go p.run(ctx)
+	return p
+}
+
+// write hands an event to the pipeline, blocking while the buffer is full
+// under the block backpressure strategy
+func (p *pipeline) write(event *types.LogEvent) {
+	if p.skew != nil {
+		if event = p.skew.check(event); event == nil {
+			return
+		}
+	}
+
+	if p.extractor != nil {
+		if err := p.extractor.ExtractEvent(event); err != nil {
+			p.extractLog.Warn().Err(err).Msg("Failed to extract metrics from event")
+		}
+	}
+
+	if p.coordinator != nil {
+		if err := p.coordinator.Enqueue(context.Background(), event); err != nil {
+			p.logger.Warn().Err(err).Msg("Failed to buffer event")
+			return
+		}
+		p.enqueued.Add(1)
+		return
+	}
+
+	if p.wal != nil {
+		if _, err := p.wal.Write(event); err != nil {
+			p.logger.Error().Err(err).Msg("Failed to write event to WAL")
+		}
+	}
+
+	if err := p.buffer.Enqueue(context.Background(), event); err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to buffer event")
+		return
+	}
+	p.enqueued.Add(1)
+}
+
+// run sends buffered events to the router until the pipeline is stopped
+func (p *pipeline) run(ctx context.Context) {
+	defer close(p.done)
+
+	if p.coordinator != nil {
+		replayed, err := p.coordinator.Replay(ctx)
+		if err != nil {
+			p.logger.Error().Err(err).Int("replayed", replayed).Msg("Failed to replay WAL")
+		} else if replayed > 0 {
+			p.logger.Info().Int("events", replayed).Msg("Replayed uncommitted WAL entries")
+		}
+		p.coordinator.Run(ctx)
+		return
+	}
+
+	for {
+		event, err := p.buffer.Dequeue(ctx)
+		if err != nil {
+			return
+		}
+		p.deliver(event)
+	}
+}
+
+// deliver sends an event to the outputs; failed events go to the dead
+// letter queue through the router
+func (p *pipeline) deliver(event *types.LogEvent) {
+	if err := p.router.Send(context.Background(), event); err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to send event")
+	}
+}
+
+// registerShutdown registers the pipeline's ordered shutdown stages, which
+// run after the inputs have been stopped
+func (p *pipeline) registerShutdown(manager *shutdown.Manager) {
+	manager.RegisterStage("buffer", p.drain)
+	manager.RegisterStage("batchers", p.flush)
+	if p.wal != nil {
+		manager.RegisterStage("wal", p.closeWAL)
+	}
+	manager.RegisterStage("outputs", p.closeOutputs)
+}
+
+// drain stops the consumer and sends the events left in the buffer
+func (p *pipeline) drain(ctx context.Context) (int, error) {
+	if p.pressure != nil {
+		p.pressure.stop()
+	}
+	p.cancel()
+	<-p.done
+
+	if p.coordinator != nil {
+		drained, err := p.coordinator.Drain(ctx)
+		if closeErr := p.buffer.Close(); err == nil {
+			err = closeErr
+		}
+		return drained, err
+	}
+
+	drained := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return drained, fmt.Errorf("%d events left in buffer: %w", p.buffer.Size(), err)
+		}
+		event, ok := p.buffer.TryDequeue()
+		if !ok {
+			break
+		}
+		p.deliver(event)
+		drained++
+	}
+
+	return drained, p.buffer.Close()
+}
+
+// flush flushes the output batchers, returning how many events they sent.
+// With a WAL coordinator, the flushed events are committed.
+func (p *pipeline) flush(ctx context.Context) (int, error) {
+	before := p.router.Metrics().EventsSent
+	var err error
+	if p.coordinator != nil {
+		err = p.coordinator.Commit(ctx)
+	} else {
+		err = p.router.Flush(ctx)
+	}
+	return int(p.router.Metrics().EventsSent - before), err
+}
+
+// closeWAL syncs and closes the WAL, returning how many events it recorded
+func (p *pipeline) closeWAL(ctx context.Context) (int, error) {
+	if err := p.wal.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	written := int(p.wal.Metrics().EntriesWritten)
+	return written, p.wal.Close()
+}
+
+// closeOutputs closes the outputs
+func (p *pipeline) closeOutputs(ctx context.Context) (int, error) {
+	return 0, p.router.Close()
+}
+
+// enqueuedCount returns how many events have been buffered
+func (p *pipeline) enqueuedCount() int64 {
+	return p.enqueued.Load()
+}
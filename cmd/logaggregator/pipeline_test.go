@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/shutdown"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// bufferingOutput holds events until it is flushed, like a batching output
+type bufferingOutput struct {
+	mu      sync.Mutex
+	pending []*types.LogEvent
+	sent    []*types.LogEvent
+	closed  bool
+	events  []string // lifecycle calls in order
+}
+
+func (b *bufferingOutput) Send(_ context.Context, event *types.LogEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errors.New("output closed")
+	}
+	b.pending = append(b.pending, event)
+	return nil
+}
+
+func (b *bufferingOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	for _, event := range events {
+		if err := b.Send(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bufferingOutput) Flush(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, b.pending...)
+	b.pending = nil
+	b.events = append(b.events, "flush")
+	return nil
+}
+
+func (b *bufferingOutput) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.events = append(b.events, "close")
+	return nil
+}
+
+func (b *bufferingOutput) Name() string { return "buffering" }
+
+func (b *bufferingOutput) Metrics() *output.OutputMetrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &output.OutputMetrics{EventsSent: int64(len(b.sent))}
+}
+
+func (b *bufferingOutput) HealthCheck(context.Context) error { return nil }
+
+var testOutput = &bufferingOutput{}
+
+func init() {
+	output.Register("test-buffering", func(map[string]interface{}) (output.Output, error) {
+		return testOutput, nil
+	})
+}
+
+func TestPipelineShutdownDeliversEvents(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{{Type: "test-buffering"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 1024})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	w, err := wal.NewWAL(wal.WALConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+
+	pipe := startPipeline(rb, w, router, wal.CoordinatorConfig{}, logger)
+
+	const total = 200
+	manager := shutdown.New(shutdown.Config{Logger: logger, Timeout: 5 * time.Second})
+	manager.RegisterStage("inputs", func(context.Context) (int, error) {
+		// Events still arriving while the inputs stop are delivered too
+		for i := 0; i < total; i++ {
+			pipe.write(&types.LogEvent{Message: fmt.Sprintf("event-%d", i)})
+		}
+		return total, nil
+	})
+	pipe.registerShutdown(manager)
+
+	manager.Shutdown()
+	<-manager.Done()
+
+	testOutput.mu.Lock()
+	defer testOutput.mu.Unlock()
+
+	if len(testOutput.sent) != total {
+		t.Errorf("expected %d events delivered before exit, got %d (pending %d)", total, len(testOutput.sent), len(testOutput.pending))
+	}
+	for i, event := range testOutput.sent {
+		if want := fmt.Sprintf("event-%d", i); event.Message != want {
+			t.Errorf("event %d = %q, want %q", i, event.Message, want)
+			break
+		}
+	}
+	if len(testOutput.events) != 2 || testOutput.events[0] != "flush" || testOutput.events[1] != "close" {
+		t.Errorf("expected the output to be flushed and then closed, got %v", testOutput.events)
+	}
+
+	if !rb.Empty() {
+		t.Errorf("expected an empty buffer, got %d events", rb.Size())
+	}
+	if got := w.Metrics().EntriesWritten; got != total {
+		t.Errorf("expected %d WAL entries, got %d", total, got)
+	}
+	if _, err := w.Write(&types.LogEvent{}); !errors.Is(err, wal.ErrWALClosed) {
+		t.Errorf("expected the WAL to be closed, got %v", err)
+	}
+}
+
+// slowOutput blocks every send until it is released
+type slowOutput struct {
+	bufferingOutput
+	release chan struct{}
+}
+
+func (s *slowOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	<-s.release
+	return s.bufferingOutput.Send(ctx, event)
+}
+
+var slow = &slowOutput{release: make(chan struct{})}
+
+func init() {
+	output.Register("test-slow", func(map[string]interface{}) (output.Output, error) {
+		return slow, nil
+	})
+}
+
+func TestPipelineBackpressureBlocksInputs(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{{Type: "test-slow"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{
+		Size:                 4,
+		BackpressureStrategy: buffer.BackpressureBlock,
+		BlockTimeout:         time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	pipe := startPipeline(rb, nil, router, wal.CoordinatorConfig{}, logger)
+	defer pipe.cancel()
+
+	// The input hands events over one at a time, like an input whose events
+	// channel is full
+	const total = 20
+	inp := &channelInput{events: make(chan *types.LogEvent)}
+	go processInputEvents(inp, newProcessor(&stages{}), pipe, logger)
+
+	var sent atomic.Int64
+	produced := make(chan struct{})
+	go func() {
+		defer close(produced)
+		for i := 0; i < total; i++ {
+			inp.events <- &types.LogEvent{Message: fmt.Sprintf("event-%d", i)}
+			sent.Add(1)
+		}
+		close(inp.events)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rb.Size() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rb.Size() != 4 {
+		t.Fatalf("expected the slow output to fill the buffer, size %d", rb.Size())
+	}
+
+	// One event is held by the output, four by the buffer and one by the
+	// blocked processing goroutine; the input cannot hand over any more
+	time.Sleep(100 * time.Millisecond)
+	if n := sent.Load(); n >= total || n > 6 {
+		t.Fatalf("expected the input to block once the buffer was full, it sent %d events", n)
+	}
+
+	close(slow.release)
+	select {
+	case <-produced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the input to resume once the output caught up")
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for slow.Metrics().EventsSent < total && time.Now().Before(deadline) {
+		slow.Flush(context.Background())
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := slow.Metrics().EventsSent; got != total {
+		t.Errorf("expected %d events delivered, got %d", total, got)
+	}
+}
+
+func TestPipelineExtractsMetrics(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	pipe := startCapturePipeline(t, logger)
+
+	collector := metrics.NewCollector()
+	extractor, err := newExtractor(&config.MetricsConfig{
+		Extraction: &config.MetricsExtractionConfig{
+			Enabled: true,
+			Rules: []config.MetricExtractionRule{{
+				Name:        "errors_total",
+				Type:        "counter",
+				Field:       "level",
+				Pattern:     "^error$",
+				LabelFields: map[string]string{"service": "service"},
+				Help:        "Error events",
+			}},
+		},
+	}, collector)
+	if err != nil {
+		t.Fatalf("newExtractor() error = %v", err)
+	}
+	pipe.extractor = extractor
+
+	for _, event := range []*types.LogEvent{
+		{Level: "error", Fields: map[string]string{"service": "api"}},
+		{Level: "info", Fields: map[string]string{"service": "api"}},
+		{Level: "error", Fields: map[string]string{"service": "api"}},
+		{Level: "error", Fields: map[string]string{"service": "worker"}},
+	} {
+		pipe.write(event)
+	}
+	waitForEvents(t, 4)
+
+	expected := `
+# HELP logaggregator_extracted_errors_total Error events
+# TYPE logaggregator_extracted_errors_total counter
+logaggregator_extracted_errors_total{service="api"} 2
+logaggregator_extracted_errors_total{service="worker"} 1
+`
+	if err := testutil.GatherAndCompare(collector.Registry(), strings.NewReader(expected), "logaggregator_extracted_errors_total"); err != nil {
+		t.Error(err)
+	}
+
+	// Without extraction rules there is no extractor
+	if extractor, err := newExtractor(&config.MetricsConfig{Extraction: &config.MetricsExtractionConfig{}}, collector); err != nil || extractor != nil {
+		t.Errorf("expected no extractor when extraction is disabled, got %v, %v", extractor, err)
+	}
+}
+
+func TestPipelineExtractedMetricCardinality(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	pipe := startCapturePipeline(t, logger)
+
+	collector := metrics.NewCollector()
+	extractor, err := newExtractor(&config.MetricsConfig{
+		Extraction: &config.MetricsExtractionConfig{
+			Enabled: true,
+			Rules: []config.MetricExtractionRule{{
+				Name:           "requests_total",
+				Type:           "counter",
+				Field:          "request_id",
+				Pattern:        "^req-",
+				LabelFields:    map[string]string{"request_id": "request_id"},
+				Help:           "Requests",
+				MaxCardinality: 2,
+			}},
+		},
+	}, collector)
+	if err != nil {
+		t.Fatalf("newExtractor() error = %v", err)
+	}
+	pipe.extractor = extractor
+
+	for i := 0; i < 5; i++ {
+		pipe.write(&types.LogEvent{Fields: map[string]string{"request_id": fmt.Sprintf("req-%d", i)}})
+	}
+	waitForEvents(t, 5)
+
+	expected := `
+# HELP logaggregator_extracted_requests_total Requests
+# TYPE logaggregator_extracted_requests_total counter
+logaggregator_extracted_requests_total{request_id="__overflow__"} 3
+logaggregator_extracted_requests_total{request_id="req-0"} 1
+logaggregator_extracted_requests_total{request_id="req-1"} 1
+# HELP logaggregator_extracted_overflow_total Observations of extracted metrics recorded in the overflow series, over their rule's maximum cardinality
+# TYPE logaggregator_extracted_overflow_total counter
+logaggregator_extracted_overflow_total{rule="requests_total"} 3
+`
+	if err := testutil.GatherAndCompare(collector.Registry(), strings.NewReader(expected),
+		"logaggregator_extracted_requests_total", "logaggregator_extracted_overflow_total"); err != nil {
+		t.Error(err)
+	}
+}
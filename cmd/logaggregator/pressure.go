@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/performance"
+)
+
+// pressureFlushTimeout bounds a flush forced by memory pressure
+const pressureFlushTimeout = 30 * time.Second
+
+// pressureRelief flushes the output batchers while the memory watchdog
+// reports the heap over its threshold, so outputs do not hold large batches.
+// With dropFor set, the ring buffer drops its oldest events instead of
+// applying its configured strategy until dropFor after the last report.
+type pressureRelief struct {
+	pipe    *pipeline
+	dropFor time.Duration
+	logger  *logging.Logger
+	stopRun context.CancelFunc
+
+	mu       sync.Mutex
+	restore  *time.Timer // set while the buffer drops events
+	previous buffer.BackpressureStrategy
+}
+
+// newPressureRelief creates the relief for the configured memory pressure
+// watchdog, or returns nil when it is disabled
+func newPressureRelief(cfg *config.PerformanceConfig, pipe *pipeline, logger *logging.Logger) *pressureRelief {
+	if cfg == nil || cfg.MemoryPressure == nil || !cfg.MemoryPressure.Enabled {
+		return nil
+	}
+	return &pressureRelief{
+		pipe:    pipe,
+		dropFor: cfg.MemoryPressure.DropFor,
+		logger:  logger,
+	}
+}
+
+// start runs a watchdog checking the heap against the configured threshold
+func (r *pressureRelief) start(cfg config.MemoryPressureConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stopRun = cancel
+	go performance.NewMemoryWatchdog(cfg, r.relieve).Run(ctx)
+}
+
+// relieve flushes the output batchers and, with dropFor set, switches the
+// buffer to the drop strategy
+func (r *pressureRelief) relieve(ctx context.Context, heapAlloc uint64) {
+	ctx, cancel := context.WithTimeout(ctx, pressureFlushTimeout)
+	defer cancel()
+
+	flushed, err := r.pipe.flush(ctx)
+	metrics.GetGlobalCollector().SystemMemoryPressureFlushes.Inc()
+
+	event := r.logger.Warn().Err(err).Uint64("heap_alloc", heapAlloc).Int("flushed", flushed)
+	if r.dropFor > 0 {
+		r.dropEvents()
+		event = event.Dur("drop_for", r.dropFor)
+	}
+	event.Msg("Memory pressure, flushed output batchers")
+}
+
+// dropEvents switches the buffer to the drop strategy, or extends the time
+// it drops events, until dropFor from now
+func (r *pressureRelief) dropEvents() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.restore != nil {
+		r.restore.Reset(r.dropFor)
+		return
+	}
+	r.previous = r.pipe.buffer.SetBackpressureStrategy(buffer.BackpressureDrop)
+	r.restore = time.AfterFunc(r.dropFor, r.restoreStrategy)
+}
+
+// restoreStrategy switches the buffer back to its configured strategy
+func (r *pressureRelief) restoreStrategy() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.restore == nil {
+		return
+	}
+	r.restore.Stop()
+	r.restore = nil
+	r.pipe.buffer.SetBackpressureStrategy(r.previous)
+	r.logger.Info().Str("strategy", string(r.previous)).Msg("Memory pressure over, buffer strategy restored")
+}
+
+// stop stops the watchdog and restores the buffer's strategy
+func (r *pressureRelief) stop() {
+	if r.stopRun != nil {
+		r.stopRun()
+	}
+	r.restoreStrategy()
+}
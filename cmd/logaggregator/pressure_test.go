@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func init() {
+	output.Register("test-pressure", func(map[string]interface{}) (output.Output, error) {
+		return &bufferingOutput{}, nil
+	})
+}
+
+func TestPressureReliefFlushesBatches(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{{Type: "test-pressure"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	pressureOutput := router.GetOutputs()[0].(*bufferingOutput)
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 16, BackpressureStrategy: buffer.BackpressureBlock})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	pipe := startPipeline(rb, nil, router, wal.CoordinatorConfig{}, logger)
+	defer pipe.cancel()
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		pipe.write(&types.LogEvent{Message: fmt.Sprintf("event-%d", i)})
+	}
+	pending := func() int {
+		pressureOutput.mu.Lock()
+		defer pressureOutput.mu.Unlock()
+		return len(pressureOutput.pending)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for pending() < total && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := pending(); got != total {
+		t.Fatalf("expected %d events pending in the output batch, got %d", total, got)
+	}
+
+	flushes := metrics.GetGlobalCollector().SystemMemoryPressureFlushes
+	before := testutil.ToFloat64(flushes)
+
+	relief := &pressureRelief{pipe: pipe, dropFor: 50 * time.Millisecond, logger: logger}
+	relief.relieve(context.Background(), 1<<30)
+
+	if got := pending(); got != 0 {
+		t.Errorf("expected the pending batch flushed, %d events left", got)
+	}
+	if got := pressureOutput.Metrics().EventsSent; got != total {
+		t.Errorf("expected %d events sent, got %d", total, got)
+	}
+	if got := testutil.ToFloat64(flushes) - before; got != 1 {
+		t.Errorf("expected 1 memory pressure flush counted, got %v", got)
+	}
+
+	// The buffer drops events until drop_for after the last report
+	if got := rb.BackpressureStrategy(); got != buffer.BackpressureDrop {
+		t.Errorf("expected the drop strategy under pressure, got %q", got)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for rb.BackpressureStrategy() != buffer.BackpressureBlock && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := rb.BackpressureStrategy(); got != buffer.BackpressureBlock {
+		t.Errorf("expected the block strategy restored after drop_for, got %q", got)
+	}
+
+	// Stopping restores the strategy of a buffer still dropping events
+	relief.dropFor = time.Hour
+	relief.relieve(context.Background(), 1<<30)
+	relief.stop()
+	if got := rb.BackpressureStrategy(); got != buffer.BackpressureBlock {
+		t.Errorf("expected the block strategy restored on stop, got %q", got)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/input"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/parser"
+	"github.com/therealutkarshpriyadarshi/log/internal/wiring"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// stages are the parser and transform pipeline applied to an input's events.
+// Either is nil when not configured. The transforms begin with the shared
+// enrichment and end with the shared deduplication, which bypass holds
+// alone for events that skip the transforms, such as lines that fail to
+// parse.
+type stages struct {
+	parser     parser.Parser
+	transforms *parser.TransformPipeline
+	bypass     *parser.TransformPipeline
+}
+
+// sharedStages are the stages run for every input: the enrichment ahead of
+// the input's transforms and the deduplication after them. The deduplication
+// is a single instance, so an event is dropped when any input emitted it
+// within the TTL.
+type sharedStages struct {
+	enrichment *config.EnrichmentConfig
+	dedup      *parser.DedupTransformer // nil when disabled
+}
+
+// newSharedStages creates the stages shared by all inputs
+func newSharedStages(cfg *config.Config) (*sharedStages, error) {
+	shared := &sharedStages{enrichment: cfg.Enrichment}
+
+	if cfg.Dedup != nil && cfg.Dedup.Enabled {
+		dedup, err := parser.NewDedupTransformer(&parser.TransformConfig{
+			Type:       "dedup",
+			Fields:     cfg.Dedup.Fields,
+			TTL:        cfg.Dedup.TTL,
+			MaxEntries: cfg.Dedup.MaxEntries,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dedup: %w", err)
+		}
+		shared.dedup = dedup
+	}
+
+	return shared, nil
+}
+
+// pipeline returns the transforms surrounded by the shared stages, or nil
+// when there is nothing to run
+func (sh *sharedStages) pipeline(transforms []config.TransformConfig) (*parser.TransformPipeline, error) {
+	configs := wiring.PipelineConfigs(sh.enrichment, transforms)
+	if len(configs) == 0 && sh.dedup == nil {
+		return nil, nil
+	}
+
+	pipeline, err := parser.NewTransformPipeline(configs)
+	if err != nil {
+		return nil, err
+	}
+	if sh.dedup != nil {
+		pipeline.Append(sh.dedup)
+	}
+	return pipeline, nil
+}
+
+// processor holds an input's processing stages, which a configuration reload
+// replaces while events are being processed
+type processor struct {
+	current atomic.Pointer[stages]
+}
+
+// newProcessor creates a processor running the given stages
+func newProcessor(s *stages) *processor {
+	p := &processor{}
+	p.store(s)
+	return p
+}
+
+// load returns the stages for the next event
+func (p *processor) load() *stages {
+	return p.current.Load()
+}
+
+// store replaces the stages
+func (p *processor) store(s *stages) {
+	p.current.Store(s)
+}
+
+// newStages creates the parser and transform pipeline of an input, with the
+// shared stages around the input's transforms
+func newStages(shared *sharedStages, parserCfg *parser.ParserConfig, transforms []config.TransformConfig) (*stages, error) {
+	s := &stages{}
+
+	if parserCfg != nil {
+		logParser, err := parser.New(parserCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser: %w", err)
+		}
+		s.parser = logParser
+	}
+
+	bypass, err := shared.pipeline(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shared stages: %w", err)
+	}
+	s.bypass = bypass
+
+	pipeline, err := shared.pipeline(transforms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transform pipeline: %w", err)
+	}
+	s.transforms = pipeline
+
+	return s, nil
+}
+
+// applyShared runs the shared stages on an event that skips the transforms.
+// It returns nil when the event is dropped as a duplicate.
+func (s *stages) applyShared(event *types.LogEvent) *types.LogEvent {
+	if s.bypass == nil {
+		return event
+	}
+	transformed, err := transformEvent(s.bypass, event)
+	if errors.Is(err, parser.ErrDropEvent) {
+		return nil
+	}
+	if transformed == nil {
+		return event
+	}
+	return transformed
+}
+
+// parserConfig converts an input's parser configuration
+func parserConfig(cfg *config.ParserConfig) *parser.ParserConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	return &parser.ParserConfig{
+		Type:         parser.ParserType(cfg.Type),
+		Pattern:      cfg.Pattern,
+		GrokPattern:  cfg.GrokPattern,
+		TimeFormat:   cfg.TimeFormat,
+		TimeField:    cfg.TimeField,
+		LevelField:   cfg.LevelField,
+		MessageField: cfg.MessageField,
+		CustomFields: cfg.CustomFields,
+		Multiline:    multilineConfig(cfg.Multiline),
+
+		StrictFields:     cfg.StrictFields,
+		AllowedFields:    cfg.AllowedFields,
+		NestedDepthLimit: cfg.NestedDepthLimit,
+	}
+}
+
+// fileParserConfig converts a file input's parser configuration. Lines are
+// joined by the multiline assembler before they reach the parser, so the
+// result has no multiline settings; it is nil when no parser is needed.
+func fileParserConfig(cfg *config.ParserConfig) *parser.ParserConfig {
+	parserCfg := parserConfig(cfg)
+	if parserCfg == nil || parserCfg.Multiline == nil {
+		return parserCfg
+	}
+
+	parserCfg.Multiline = nil
+	if parserCfg.Type == parser.ParserTypeMultiline {
+		// Parse assembled events with the regex pattern, if any
+		parserCfg.Type = parser.ParserTypeRegex
+		if parserCfg.Pattern == "" {
+			return nil
+		}
+	}
+	return parserCfg
+}
+
+// multilineConfig converts multiline settings
+func multilineConfig(cfg *config.MultilineConfig) *parser.MultilineConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	return &parser.MultilineConfig{
+		Pattern:  cfg.Pattern,
+		Negate:   cfg.Negate,
+		Match:    cfg.Match,
+		MaxLines: cfg.MaxLines,
+		Timeout:  cfg.Timeout,
+	}
+}
+
+// inputSettings are the reloadable settings of an input
+type inputSettings struct {
+	parser     *parser.ParserConfig
+	transforms []config.TransformConfig
+	rateLimit  int
+}
+
+// reloadableInputs returns the reloadable settings of every input, keyed by
+// the input's diff path
+func reloadableInputs(cfg *config.Config) map[string]inputSettings {
+	settings := make(map[string]inputSettings)
+
+	for i, in := range cfg.Inputs.Files {
+		settings[config.FileInputPath(i)] = inputSettings{parser: fileParserConfig(in.Parser), transforms: in.Transforms}
+	}
+	for _, in := range cfg.Inputs.Syslog {
+		settings[config.InputPath("syslog", in.Name)] = inputSettings{parserConfig(in.Parser), in.Transforms, in.RateLimit}
+	}
+	for _, in := range cfg.Inputs.HTTP {
+		settings[config.InputPath("http", in.Name)] = inputSettings{parserConfig(in.Parser), in.Transforms, in.RateLimit}
+	}
+	for _, in := range cfg.Inputs.Kubernetes {
+		settings[config.InputPath("kubernetes", in.Name)] = inputSettings{parser: parserConfig(in.Parser), transforms: in.Transforms}
+	}
+	for _, in := range cfg.Inputs.GRPC {
+		settings[config.InputPath("grpc", in.Name)] = inputSettings{parserConfig(in.Parser), in.Transforms, in.RateLimit}
+	}
+	for _, in := range cfg.Inputs.OTLP {
+		settings[config.InputPath("otlp", in.Name)] = inputSettings{parserConfig(in.Parser), in.Transforms, in.RateLimit}
+	}
+
+	return settings
+}
+
+// outputBatch is the batch configuration of an output
+type outputBatch struct {
+	path          string // diff path of the output's settings
+	name          string // output name in the router
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// outputBatches returns the batch configuration of the router's outputs
+func outputBatches(cfg config.OutputConfig) []outputBatch {
+	var batches []outputBatch
+
+	switch cfg.Type {
+	case "kafka", "elasticsearch", "s3", "gcs", "http", "loki", "stdout":
+		if b, ok := batchConfig(cfg.Type, cfg.Kafka, cfg.Elasticsearch, cfg.S3, cfg.GCS, cfg.HTTP, cfg.Loki, cfg.Stdout); ok {
+			b.path, b.name = config.OutputPath(cfg.Type, ""), cfg.Type
+			batches = append(batches, b)
+		}
+	case "multi":
+		if cfg.Multi == nil {
+			return nil
+		}
+		for _, def := range cfg.Multi.Outputs {
+			if b, ok := batchConfig(def.Type, def.Kafka, def.Elasticsearch, def.S3, def.GCS, def.HTTP, def.Loki, def.Stdout); ok {
+				b.path, b.name = config.OutputPath(def.Type, def.Name), def.Name
+				batches = append(batches, b)
+			}
+		}
+	}
+
+	return batches
+}
+
+// batchConfig returns the batch settings of a typed output configuration
+func batchConfig(outputType string, kafka *config.KafkaOutputConfig, es *config.ElasticsearchOutputConfig, s3 *config.S3OutputConfig, gcs *config.GCSOutputConfig, httpCfg *config.HTTPOutputConfig, loki *config.LokiOutputConfig, stdout *config.StdoutOutputConfig) (outputBatch, bool) {
+	switch {
+	case outputType == "kafka" && kafka != nil:
+		return outputBatch{batchSize: kafka.BatchSize, flushInterval: kafka.FlushInterval}, true
+	case outputType == "elasticsearch" && es != nil:
+		return outputBatch{batchSize: es.BatchSize, flushInterval: es.FlushInterval}, true
+	case outputType == "s3" && s3 != nil:
+		return outputBatch{batchSize: s3.BatchSize, flushInterval: s3.FlushInterval}, true
+	case outputType == "gcs" && gcs != nil:
+		return outputBatch{batchSize: gcs.BatchSize, flushInterval: gcs.FlushInterval}, true
+	case outputType == "http" && httpCfg != nil:
+		return outputBatch{batchSize: httpCfg.BatchSize, flushInterval: httpCfg.FlushInterval}, true
+	case outputType == "loki" && loki != nil:
+		return outputBatch{batchSize: loki.BatchSize, flushInterval: loki.FlushInterval}, true
+	case outputType == "stdout" && stdout != nil:
+		return outputBatch{batchSize: stdout.BatchSize, flushInterval: stdout.FlushInterval}, true
+	default:
+		return outputBatch{}, false
+	}
+}
+
+// reloader re-reads the configuration file and applies the changes that are
+// safe to make while running: the log level, enrichment, input parsers,
+// transforms and rate limits, and output batch settings. Other changes are
+// logged as requiring a restart.
+type reloader struct {
+	path    string
+	options config.LoadOptions
+	router  *output.Router
+	logger  *logging.Logger
+
+	mu         sync.Mutex
+	current    *config.Config
+	shared     *sharedStages
+	processors map[string]*processor
+	limiters   map[string]input.RateLimitUpdater
+}
+
+// newReloader creates a reloader for the configuration at path. router is
+// nil when events are not delivered through the output pipeline. Reloaded
+// inputs keep using the shared deduplication.
+func newReloader(path string, options config.LoadOptions, current *config.Config, shared *sharedStages, router *output.Router, logger *logging.Logger) *reloader {
+	return &reloader{
+		path:       path,
+		options:    options,
+		router:     router,
+		logger:     logger,
+		current:    current,
+		shared:     shared,
+		processors: make(map[string]*processor),
+		limiters:   make(map[string]input.RateLimitUpdater),
+	}
+}
+
+// addInput registers an input's processor and, for inputs that support it,
+// its rate limit under the input's diff path
+func (r *reloader) addInput(path string, proc *processor, inp input.Input) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.processors[path] = proc
+	if limiter, ok := inp.(input.RateLimitUpdater); ok {
+		r.limiters[path] = limiter
+	}
+}
+
+// running returns the configuration currently applied
+func (r *reloader) running() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// listen reloads the configuration on every SIGHUP until the returned
+// function is called
+func (r *reloader) listen() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			r.logger.Info().Str("path", r.path).Msg("Received SIGHUP, reloading configuration")
+			r.reload()
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+// reload loads the configuration file and applies its reloadable changes.
+// An invalid configuration is rejected and the running one is kept.
+func (r *reloader) reload() error {
+	cfg, err := config.LoadWithOptions(r.options, r.path)
+	if err != nil {
+		r.logger.Error().Err(err).Str("path", r.path).Msg("Configuration reload rejected, keeping the running configuration")
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	diff := r.current.Diff(cfg)
+	if diff.Empty() {
+		r.logger.Info().Msg("Configuration unchanged")
+		return nil
+	}
+
+	// Build every change before applying any, so a failure leaves the
+	// running configuration untouched
+	changes, err := r.prepare(cfg, diff)
+	if err != nil {
+		r.logger.Error().Err(err).Str("path", r.path).Msg("Configuration reload rejected, keeping the running configuration")
+		return err
+	}
+	for _, apply := range changes {
+		apply()
+	}
+
+	for _, setting := range diff.RestartRequired {
+		r.logger.Warn().Str("setting", setting).Msg("Configuration change requires a restart to take effect")
+	}
+
+	r.current = cfg
+	r.logger.Info().
+		Strs("applied", diff.Reloadable).
+		Int("restart_required", len(diff.RestartRequired)).
+		Msg("Configuration reloaded")
+
+	return nil
+}
+
+// prepare builds the functions applying the reloadable changes in diff
+func (r *reloader) prepare(cfg *config.Config, diff config.ConfigDiff) ([]func(), error) {
+	var changes []func()
+
+	if diff.Contains("logging.level") {
+		level := cfg.Logging.Level
+		changes = append(changes, func() { logging.SetLevel(level) })
+	}
+
+	shared := &sharedStages{enrichment: cfg.Enrichment, dedup: r.shared.dedup}
+	if diff.Contains("enrichment") {
+		changes = append(changes, func() { r.shared = shared })
+	}
+
+	settings := reloadableInputs(cfg)
+	for path, proc := range r.processors {
+		if !diff.Contains("enrichment") && !diff.Contains(path+".parser") && !diff.Contains(path+".transforms") {
+			continue
+		}
+		s, err := newStages(shared, settings[path].parser, settings[path].transforms)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		changes = append(changes, func() { proc.store(s) })
+	}
+
+	for path, limiter := range r.limiters {
+		if !diff.Contains(path + ".rate_limit") {
+			continue
+		}
+		perSecond := settings[path].rateLimit
+		changes = append(changes, func() { limiter.SetRateLimit(perSecond) })
+	}
+
+	if r.router != nil {
+		for _, b := range outputBatches(cfg.Output) {
+			if !diff.Contains(b.path+".batch_size") && !diff.Contains(b.path+".flush_interval") {
+				continue
+			}
+			changes = append(changes, func() {
+				if !r.router.SetBatchConfig(b.name, b.batchSize, b.flushInterval) {
+					r.logger.Warn().Str("output", b.name).Msg("Output does not batch events, batch settings require a restart to take effect")
+				}
+			})
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+)
+
+// reloadTestConfig renders a configuration with one file input
+func reloadTestConfig(level, inputSettings string) string {
+	return `
+inputs:
+  files:
+    - paths:
+        - /var/log/app.log
+      checkpoint_path: /tmp/checkpoints
+` + inputSettings + `
+logging:
+  level: ` + level + `
+  format: json
+
+output:
+  type: stdout
+`
+}
+
+// newTestReloader loads the configuration at path and registers the file
+// input's processor
+func newTestReloader(t *testing.T, path string) (*reloader, *processor) {
+	t.Helper()
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	logging.SetLevel(cfg.Logging.Level)
+	t.Cleanup(func() { logging.SetLevel("info") })
+
+	logger := logging.New(logging.Config{Level: cfg.Logging.Level, Format: "json"})
+	r := newReloader(path, config.LoadOptions{Strict: true}, cfg, &sharedStages{enrichment: cfg.Enrichment}, nil, logger)
+	proc := newProcessor(&stages{})
+	r.addInput(config.FileInputPath(0), proc, nil)
+
+	return r, proc
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestReloadAppliesChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadTestConfig("info", ""))
+	r, proc := newTestReloader(t, path)
+
+	writeConfig(t, path, reloadTestConfig("debug", `      parser:
+        type: json
+      transforms:
+        - type: add
+          add:
+            env: production
+`))
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+
+	if level := logging.Level(); level != "debug" {
+		t.Errorf("expected log level debug, got %s", level)
+	}
+	s := proc.load()
+	if s.parser == nil || s.transforms == nil {
+		t.Errorf("expected the parser and transforms to be replaced, got %+v", s)
+	}
+	if r.current.Logging.Level != "debug" {
+		t.Errorf("expected the reloaded config to become current, got level %s", r.current.Logging.Level)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "malformed yaml",
+			content: "inputs: [",
+		},
+		{
+			name:    "unknown key",
+			content: reloadTestConfig("debug", "      checkpoint_intervall: 5s\n"),
+		},
+		{
+			name:    "no inputs",
+			content: "logging:\n  level: debug\noutput:\n  type: stdout\n",
+		},
+		{
+			name: "invalid parser",
+			content: reloadTestConfig("debug", `      parser:
+        type: regex
+        pattern: "("
+`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfig(t, path, reloadTestConfig("info", ""))
+			r, proc := newTestReloader(t, path)
+			running := r.current
+			initial := proc.load()
+
+			writeConfig(t, path, tt.content)
+			if err := r.reload(); err == nil {
+				t.Fatal("expected reload to fail")
+			}
+
+			if r.current != running {
+				t.Error("expected the running config to be kept")
+			}
+			if level := logging.Level(); level != "info" {
+				t.Errorf("expected log level to stay info, got %s", level)
+			}
+			if proc.load() != initial {
+				t.Error("expected the processing stages to be kept")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// defaultMaxTimestampSkew is the skew allowed when max_skew is not set
+const defaultMaxTimestampSkew = time.Hour
+
+// Timestamp skew policies
+const (
+	skewPolicyFlag       = "flag"
+	skewPolicyClamp      = "clamp"
+	skewPolicyDeadLetter = "dead_letter"
+)
+
+// skewChecker flags events whose timestamp is further than maxSkew from the
+// wall clock with a timestamp_skew field, the event's offset from it. With
+// the clamp policy their timestamp is set to now, the original kept as
+// original_timestamp; with the dead_letter policy they are written to the
+// dead letter queue instead of being delivered.
+type skewChecker struct {
+	maxSkew    time.Duration
+	policy     string
+	deadLetter output.DeadLetterWriter
+	now        func() time.Time
+}
+
+// newSkewChecker creates the configured checker, or returns nil when it is
+// disabled
+func newSkewChecker(cfg *config.TimestampSkewConfig, deadLetter output.DeadLetterWriter) *skewChecker {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	s := &skewChecker{
+		maxSkew:    cfg.MaxSkew,
+		policy:     cfg.Policy,
+		deadLetter: deadLetter,
+		now:        time.Now,
+	}
+	if s.maxSkew == 0 {
+		s.maxSkew = defaultMaxTimestampSkew
+	}
+	if s.policy == "" {
+		s.policy = skewPolicyFlag
+	}
+	return s
+}
+
+// check returns the event to deliver, or nil when it was dead-lettered
+func (s *skewChecker) check(event *types.LogEvent) *types.LogEvent {
+	now := s.now()
+	skew := event.Timestamp.Sub(now)
+	direction := "future"
+	if skew < 0 {
+		direction = "past"
+	}
+	if skew.Abs() <= s.maxSkew {
+		return event
+	}
+	metrics.GetGlobalCollector().TimestampSkewedEvents.WithLabelValues(direction, s.policy).Inc()
+
+	if event.Fields == nil {
+		event.Fields = make(map[string]string)
+	}
+	event.Fields["timestamp_skew"] = skew.Round(time.Second).String()
+
+	switch s.policy {
+	case skewPolicyClamp:
+		event.Fields["original_timestamp"] = event.Timestamp.Format(time.RFC3339Nano)
+		event.Timestamp = now.UTC()
+	case skewPolicyDeadLetter:
+		if s.deadLetter == nil {
+			return event
+		}
+		reason := fmt.Errorf("timestamp %s is %s in the %s, beyond the allowed skew of %s",
+			event.Timestamp.Format(time.RFC3339), skew.Abs().Round(time.Second), direction, s.maxSkew)
+		_ = s.deadLetter.Write(event, reason, map[string]string{"stage": "timestamp_skew"})
+		return nil
+	}
+	return event
+}
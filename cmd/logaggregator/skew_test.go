@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordingDeadLetter keeps the events written to it with their reasons
+type recordingDeadLetter struct {
+	mu       sync.Mutex
+	events   []*types.LogEvent
+	reasons  []error
+	metadata []map[string]string
+}
+
+func (r *recordingDeadLetter) Write(event *types.LogEvent, reason error, metadata map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	r.reasons = append(r.reasons, reason)
+	r.metadata = append(r.metadata, metadata)
+	return nil
+}
+
+// startSkewPipeline starts a capture pipeline with the skew check of cfg,
+// whose clock is fixed at now
+func startSkewPipeline(t *testing.T, cfg *config.TimestampSkewConfig, deadLetter *recordingDeadLetter, now time.Time) *pipeline {
+	t.Helper()
+
+	pipe := startCapturePipeline(t, logging.New(logging.Config{Level: "error", Format: "json"}))
+	pipe.skew = newSkewChecker(cfg, deadLetter)
+	pipe.skew.now = func() time.Time { return now }
+	return pipe
+}
+
+func TestTimestampSkewClampsFutureEvents(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	future := now.Add(48 * time.Hour)
+	skewed := metrics.GetGlobalCollector().TimestampSkewedEvents.WithLabelValues("future", "clamp")
+	before := testutil.ToFloat64(skewed)
+
+	pipe := startSkewPipeline(t, &config.TimestampSkewConfig{Enabled: true, MaxSkew: time.Hour, Policy: "clamp"}, nil, now)
+	writeEvent(pipe, &types.LogEvent{Message: "future", Timestamp: future})
+	writeEvent(pipe, &types.LogEvent{Message: "recent", Timestamp: now.Add(-30 * time.Minute)})
+
+	events := waitForEvents(t, 2)
+	clamped := events[0]
+	if !clamped.Timestamp.Equal(now) {
+		t.Errorf("expected the timestamp clamped to %s, got %s", now, clamped.Timestamp)
+	}
+	if got := clamped.Fields["original_timestamp"]; got != future.Format(time.RFC3339Nano) {
+		t.Errorf("original_timestamp = %q, want %q", got, future.Format(time.RFC3339Nano))
+	}
+	if got := clamped.Fields["timestamp_skew"]; got != "48h0m0s" {
+		t.Errorf("timestamp_skew = %q, want 48h0m0s", got)
+	}
+
+	// Events within the skew are left alone
+	if recent := events[1]; recent.Fields["timestamp_skew"] != "" || !recent.Timestamp.Equal(now.Add(-30*time.Minute)) {
+		t.Errorf("expected the recent event unchanged, got %s with fields %v", recent.Timestamp, recent.Fields)
+	}
+
+	if got := testutil.ToFloat64(skewed) - before; got != 1 {
+		t.Errorf("expected 1 skewed event counted, got %v", got)
+	}
+}
+
+func TestTimestampSkewDeadLettersPastEvents(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	past := now.Add(-30 * 24 * time.Hour)
+	skewed := metrics.GetGlobalCollector().TimestampSkewedEvents.WithLabelValues("past", "dead_letter")
+	before := testutil.ToFloat64(skewed)
+
+	deadLetter := &recordingDeadLetter{}
+	pipe := startSkewPipeline(t, &config.TimestampSkewConfig{Enabled: true, Policy: "dead_letter"}, deadLetter, now)
+	writeEvent(pipe, &types.LogEvent{Message: "stale", Timestamp: past})
+	writeEvent(pipe, &types.LogEvent{Message: "current", Timestamp: now})
+
+	events := waitForEvents(t, 1)
+	if events[0].Message != "current" {
+		t.Errorf("expected only the current event delivered, got %q", events[0].Message)
+	}
+
+	deadLetter.mu.Lock()
+	defer deadLetter.mu.Unlock()
+	if len(deadLetter.events) != 1 || deadLetter.events[0].Message != "stale" {
+		t.Fatalf("expected the stale event dead-lettered, got %d events", len(deadLetter.events))
+	}
+	if !deadLetter.events[0].Timestamp.Equal(past) || deadLetter.events[0].Fields["timestamp_skew"] != "-720h0m0s" {
+		t.Errorf("expected the original timestamp flagged, got %s with fields %v", deadLetter.events[0].Timestamp, deadLetter.events[0].Fields)
+	}
+	if reason := deadLetter.reasons[0].Error(); !strings.Contains(reason, "in the past") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	if stage := deadLetter.metadata[0]["stage"]; stage != "timestamp_skew" {
+		t.Errorf("stage metadata = %q, want timestamp_skew", stage)
+	}
+
+	if got := testutil.ToFloat64(skewed) - before; got != 1 {
+		t.Errorf("expected 1 skewed event counted, got %v", got)
+	}
+}
+
+func TestNewSkewCheckerDefaults(t *testing.T) {
+	if s := newSkewChecker(&config.TimestampSkewConfig{}, nil); s != nil {
+		t.Error("expected no checker when disabled")
+	}
+
+	s := newSkewChecker(&config.TimestampSkewConfig{Enabled: true}, nil)
+	if s.maxSkew != defaultMaxTimestampSkew || s.policy != skewPolicyFlag {
+		t.Errorf("expected the default skew and policy, got %s and %q", s.maxSkew, s.policy)
+	}
+
+	// The flag policy keeps skewed events
+	event := &types.LogEvent{Timestamp: time.Now().Add(2 * time.Hour)}
+	if got := s.check(event); got != event || got.Fields["timestamp_skew"] == "" {
+		t.Errorf("expected the event flagged and kept, got fields %v", event.Fields)
+	}
+}
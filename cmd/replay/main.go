@@ -0,0 +1,202 @@
+// Command replay re-sends historical events from a WAL directory or from
+// the NDJSON objects the S3 output wrote, through the configured transforms
+// and outputs, e.g. after a downstream outage was fixed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/parser"
+	"github.com/therealutkarshpriyadarshi/log/internal/wiring"
+)
+
+var (
+	configFile = flag.String("config", "config.yaml", "Path to configuration file")
+	strict     = flag.Bool("strict", true, "Reject configuration keys that don't match a setting")
+	inputPath  = flag.String("input", "", "Input whose transforms are applied after the enrichment, e.g. inputs.http.api; none when empty")
+	from       = flag.String("from", "", "Replay events at or after this RFC 3339 time")
+	to         = flag.String("to", "", "Replay events before this RFC 3339 time")
+	rateLimit  = flag.Int("rate", 0, "Maximum events sent per second, 0 for unlimited")
+	progress   = flag.Duration("progress", 10*time.Second, "Progress report interval, 0 to disable")
+)
+
+var (
+	walDir     = flag.String("wal", "", "WAL directory to replay")
+	s3Bucket   = flag.String("s3-bucket", "", "S3 bucket to replay")
+	s3Prefix   = flag.String("s3-prefix", "", "Key prefix of the S3 objects to replay")
+	s3Region   = flag.String("s3-region", "us-east-1", "AWS region of the S3 bucket")
+	s3Endpoint = flag.String("s3-endpoint", "", "Endpoint of an S3-compatible service")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if (*walDir == "") == (*s3Bucket == "") {
+		return fmt.Errorf("exactly one of -wal and -s3-bucket is required")
+	}
+	fromTime, err := parseTime("from", *from)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTime("to", *to)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadWithOptions(config.LoadOptions{Strict: *strict}, *configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logger := logging.New(logging.Config{Level: cfg.Logging.Level, Format: cfg.Logging.Format})
+
+	transforms, err := transformPipeline(cfg, *inputPath)
+	if err != nil {
+		return err
+	}
+
+	routerCfg, err := wiring.RouterConfig(cfg.Output)
+	if err != nil {
+		return err
+	}
+	router, err := output.NewRouter(*routerCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create outputs: %w", err)
+	}
+	defer router.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	src, err := openSource(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	logger.Info().
+		Str("wal", *walDir).
+		Str("s3_bucket", *s3Bucket).
+		Str("s3_prefix", *s3Prefix).
+		Str("output", cfg.Output.Type).
+		Msg("Starting replay")
+
+	r := newReplayer(router, transforms, fromTime, toTime, *rateLimit, logger)
+	if err := r.run(ctx, src, *progress); err != nil {
+		return err
+	}
+	if failed := r.stats.failed.Load(); failed > 0 {
+		return fmt.Errorf("%d events failed to replay", failed)
+	}
+	return nil
+}
+
+// openSource opens the WAL or S3 source selected by the flags
+func openSource(ctx context.Context) (source, error) {
+	if *walDir != "" {
+		return openWALSource(*walDir)
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(*s3Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if *s3Endpoint != "" {
+			o.BaseEndpoint = aws.String(*s3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return newS3Source(client, *s3Bucket, *s3Prefix), nil
+}
+
+// transformPipeline returns the enrichment followed by the transforms of the
+// input at path, or nil when there is nothing to run. The deduplication is
+// left out, as replayed events are duplicates by design.
+func transformPipeline(cfg *config.Config, path string) (*parser.TransformPipeline, error) {
+	var transforms []config.TransformConfig
+	if path != "" {
+		var found bool
+		transforms, found = inputTransforms(cfg, path)
+		if !found {
+			return nil, fmt.Errorf("no input at %q", path)
+		}
+	}
+
+	configs := wiring.PipelineConfigs(cfg.Enrichment, transforms)
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	pipeline, err := parser.NewTransformPipeline(configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transform pipeline: %w", err)
+	}
+	return pipeline, nil
+}
+
+// inputTransforms returns the transforms of the input at a diff path, e.g.
+// inputs.files[0] or inputs.http.api
+func inputTransforms(cfg *config.Config, path string) ([]config.TransformConfig, bool) {
+	for i, in := range cfg.Inputs.Files {
+		if config.FileInputPath(i) == path {
+			return in.Transforms, true
+		}
+	}
+	for _, in := range cfg.Inputs.Syslog {
+		if config.InputPath("syslog", in.Name) == path {
+			return in.Transforms, true
+		}
+	}
+	for _, in := range cfg.Inputs.HTTP {
+		if config.InputPath("http", in.Name) == path {
+			return in.Transforms, true
+		}
+	}
+	for _, in := range cfg.Inputs.Kubernetes {
+		if config.InputPath("kubernetes", in.Name) == path {
+			return in.Transforms, true
+		}
+	}
+	for _, in := range cfg.Inputs.GRPC {
+		if config.InputPath("grpc", in.Name) == path {
+			return in.Transforms, true
+		}
+	}
+	for _, in := range cfg.Inputs.OTLP {
+		if config.InputPath("otlp", in.Name) == path {
+			return in.Transforms, true
+		}
+	}
+	return nil, false
+}
+
+// parseTime parses an optional RFC 3339 flag value
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -%s time %q: %w", name, value, err)
+	}
+	return t, nil
+}
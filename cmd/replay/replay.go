@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/parser"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// sink receives replayed events; *output.Router implements it
+type sink interface {
+	Send(ctx context.Context, event *types.LogEvent) error
+	Flush(ctx context.Context) error
+}
+
+// replayer feeds the events of a source within a time range through the
+// transforms into a sink, at most rate events per second
+type replayer struct {
+	sink       sink
+	transforms *parser.TransformPipeline // nil without transforms
+	limiter    *rate.Limiter             // nil when unlimited
+	from, to   time.Time                 // zero for an open range
+	logger     *logging.Logger
+	failures   *logging.Logger // rate limited, for per-event failures
+
+	stats replayStats
+}
+
+// replayStats counts the events of a replay
+type replayStats struct {
+	read    atomic.Int64
+	skipped atomic.Int64 // outside the time range
+	dropped atomic.Int64 // dropped by a transform
+	sent    atomic.Int64
+	failed  atomic.Int64
+}
+
+// newReplayer creates a replayer. Events are spaced evenly at perSecond, so
+// the sink never sees a burst; 0 disables the rate limit.
+func newReplayer(s sink, transforms *parser.TransformPipeline, from, to time.Time, perSecond int, logger *logging.Logger) *replayer {
+	r := &replayer{
+		sink:       s,
+		transforms: transforms,
+		from:       from,
+		to:         to,
+		logger:     logger,
+		failures:   logger.RateLimited(),
+	}
+	if perSecond > 0 {
+		r.limiter = rate.NewLimiter(rate.Limit(perSecond), 1)
+	}
+	return r
+}
+
+// run replays every event of src, reporting progress every interval, and
+// flushes the sink. Events that fail to transform or send are counted and
+// logged without stopping the replay; an error is returned when the source
+// cannot be read or ctx is done.
+func (r *replayer) run(ctx context.Context, src source, interval time.Duration) error {
+	start := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	if interval > 0 {
+		go r.reportProgress(done, interval, start)
+	}
+
+	err := r.replay(ctx, src)
+	if flushErr := r.sink.Flush(context.WithoutCancel(ctx)); flushErr != nil {
+		r.logger.Error().Err(flushErr).Msg("Failed to flush outputs")
+		err = errors.Join(err, fmt.Errorf("failed to flush outputs: %w", flushErr))
+	}
+
+	r.report("Replay finished", start)
+	return err
+}
+
+// replay sends the events of src until it is exhausted
+func (r *replayer) replay(ctx context.Context, src source) error {
+	for {
+		event, err := src.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.stats.read.Add(1)
+
+		if !r.inRange(event.Timestamp) {
+			r.stats.skipped.Add(1)
+			continue
+		}
+
+		if r.transforms != nil {
+			event, err = r.transforms.Transform(event)
+			if errors.Is(err, parser.ErrDropEvent) {
+				r.stats.dropped.Add(1)
+				continue
+			}
+			if err != nil {
+				r.stats.failed.Add(1)
+				r.failures.Warn().Err(err).Msg("Failed to transform event")
+				continue
+			}
+		}
+
+		if r.limiter != nil {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		if err := r.sink.Send(ctx, event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.stats.failed.Add(1)
+			r.failures.Warn().Err(err).Time("timestamp", event.Timestamp).Msg("Failed to send event")
+			continue
+		}
+		r.stats.sent.Add(1)
+	}
+}
+
+// inRange reports whether a timestamp is within [from, to)
+func (r *replayer) inRange(ts time.Time) bool {
+	if !r.from.IsZero() && ts.Before(r.from) {
+		return false
+	}
+	if !r.to.IsZero() && !ts.Before(r.to) {
+		return false
+	}
+	return true
+}
+
+// reportProgress logs the counts every interval until done is closed
+func (r *replayer) reportProgress(done <-chan struct{}, interval time.Duration, start time.Time) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			r.report("Replay progress", start)
+		}
+	}
+}
+
+// report logs the counts and send rate since start
+func (r *replayer) report(msg string, start time.Time) {
+	elapsed := time.Since(start)
+	sent := r.stats.sent.Load()
+	r.logger.Info().
+		Int64("read", r.stats.read.Load()).
+		Int64("sent", sent).
+		Int64("skipped", r.stats.skipped.Load()).
+		Int64("dropped", r.stats.dropped.Load()).
+		Int64("failed", r.stats.failed.Load()).
+		Dur("elapsed", elapsed).
+		Float64("events_per_sec", float64(sent)/elapsed.Seconds()).
+		Msg(msg)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordingSink keeps the events it was sent and how often it was flushed
+type recordingSink struct {
+	mu      sync.Mutex
+	events  []*types.LogEvent
+	flushes int
+}
+
+func (s *recordingSink) Send(_ context.Context, event *types.LogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) Flush(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+// writeWAL writes n events a minute apart from start to a new WAL in dir
+func writeWAL(t *testing.T, dir string, start time.Time, n int) {
+	t.Helper()
+
+	w, err := wal.NewWAL(wal.WALConfig{Dir: dir, SegmentSize: 1024})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	for i := 0; i < n; i++ {
+		event := &types.LogEvent{
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			Message:   fmt.Sprintf("event-%d", i),
+			Fields:    map[string]string{"i": fmt.Sprint(i)},
+		}
+		if _, err := w.Write(event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestReplayWAL(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	start := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	const total = 40
+
+	dir := t.TempDir()
+	writeWAL(t, dir, start, total)
+
+	t.Run("all events", func(t *testing.T) {
+		src, err := openWALSource(dir)
+		if err != nil {
+			t.Fatalf("openWALSource() error = %v", err)
+		}
+		defer src.Close()
+
+		sink := &recordingSink{}
+		r := newReplayer(sink, nil, time.Time{}, time.Time{}, 0, logger)
+		if err := r.run(context.Background(), src, time.Millisecond); err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+
+		if len(sink.events) != total {
+			t.Fatalf("expected %d events, got %d", total, len(sink.events))
+		}
+		for i, event := range sink.events {
+			if want := fmt.Sprintf("event-%d", i); event.Message != want || event.Fields["i"] != fmt.Sprint(i) {
+				t.Errorf("event %d = %s %v, want %s", i, event.Message, event.Fields, want)
+			}
+		}
+		if sink.flushes != 1 {
+			t.Errorf("expected the sink flushed once, got %d", sink.flushes)
+		}
+		if r.stats.read.Load() != total || r.stats.sent.Load() != total || r.stats.failed.Load() != 0 {
+			t.Errorf("unexpected stats: read %d, sent %d, failed %d", r.stats.read.Load(), r.stats.sent.Load(), r.stats.failed.Load())
+		}
+	})
+
+	t.Run("time range, transforms and rate", func(t *testing.T) {
+		src, err := openWALSource(dir)
+		if err != nil {
+			t.Fatalf("openWALSource() error = %v", err)
+		}
+		defer src.Close()
+
+		cfg := config.DefaultConfig()
+		cfg.Enrichment = &config.EnrichmentConfig{Fields: map[string]string{"replayed": "true"}}
+		cfg.Inputs.HTTP = []config.HTTPInputConfig{{
+			Name:       "api",
+			Transforms: []config.TransformConfig{{Type: "drop_if", Field: "i", Operator: "eq", Value: "12"}},
+		}}
+		transforms, err := transformPipeline(cfg, "inputs.http.api")
+		if err != nil {
+			t.Fatalf("transformPipeline() error = %v", err)
+		}
+		if _, err := transformPipeline(cfg, "inputs.http.missing"); err == nil {
+			t.Error("expected an error for an unknown input")
+		}
+
+		sink := &recordingSink{}
+		r := newReplayer(sink, transforms, start.Add(10*time.Minute), start.Add(20*time.Minute), 100, logger)
+		began := time.Now()
+		if err := r.run(context.Background(), src, 0); err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+
+		if len(sink.events) != 9 {
+			t.Fatalf("expected events 10 to 19 but 12, got %d", len(sink.events))
+		}
+		for _, event := range sink.events {
+			if event.Fields["replayed"] != "true" || event.Message == "event-12" {
+				t.Errorf("unexpected event %s %v", event.Message, event.Fields)
+			}
+		}
+		if r.stats.skipped.Load() != total-10 || r.stats.dropped.Load() != 1 {
+			t.Errorf("expected %d skipped and 1 dropped, got %d and %d", total-10, r.stats.skipped.Load(), r.stats.dropped.Load())
+		}
+		// 9 events at 100 per second are spaced over at least 80ms
+		if elapsed := time.Since(began); elapsed < 70*time.Millisecond {
+			t.Errorf("expected the rate limit to space the events, took %s", elapsed)
+		}
+	})
+}
+
+// fakeS3Client serves objects from memory
+type fakeS3Client struct {
+	objects map[string][]byte
+	keys    []string
+}
+
+func (c *fakeS3Client) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for _, key := range c.keys {
+		if bytes.HasPrefix([]byte(key), []byte(aws.ToString(params.Prefix))) {
+			out.Contents = append(out.Contents, s3types.Object{Key: aws.String(key)})
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeS3Client) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(c.objects[aws.ToString(params.Key)]))}, nil
+}
+
+func TestS3Source(t *testing.T) {
+	gzip, err := output.GetCompressor(output.CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := gzip.Compress([]byte(`{"message":"a","fields":{"tenant":"acme"}}` + "\n" + `{"message":"b"}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeS3Client{
+		keys: []string{"logs/2024/1.json.gz", "logs/2024/2.json", "other/3.json"},
+		objects: map[string][]byte{
+			"logs/2024/1.json.gz": compressed,
+			"logs/2024/2.json":    []byte("\n" + `{"message":"c"}` + "\n"),
+			"other/3.json":        []byte(`{"message":"d"}`),
+		},
+	}
+
+	src := newS3Source(client, "bucket", "logs/")
+	var messages []string
+	for {
+		event, err := src.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		messages = append(messages, event.Message)
+		if event.Message == "a" && event.Fields["tenant"] != "acme" {
+			t.Errorf("expected the event's fields decoded, got %v", event.Fields)
+		}
+	}
+	if fmt.Sprint(messages) != "[a b c]" {
+		t.Errorf("expected events a, b and c, got %v", messages)
+	}
+
+	client.objects["logs/2024/2.json"] = []byte("not json\n")
+	src = newS3Source(client, "bucket", "logs/2024/2")
+	if _, err := src.Next(context.Background()); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/wal"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// source yields the events to replay in order
+type source interface {
+	// Next returns the next event, or io.EOF once every event was read
+	Next(ctx context.Context) (*types.LogEvent, error)
+
+	// Close releases the source
+	Close() error
+}
+
+// walSource reads every entry left in a WAL directory, committed or not.
+// Segments the aggregator truncated after committing them are gone.
+type walSource struct {
+	wal     *wal.WAL
+	batch   int
+	next    uint64
+	entries []*wal.WALEntry
+}
+
+// openWALSource opens the WAL in dir for replay. The aggregator writing to
+// it should be stopped, or the directory copied, first.
+func openWALSource(dir string) (*walSource, error) {
+	w, err := wal.NewWAL(wal.WALConfig{Dir: dir, MmapReads: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	return &walSource{wal: w, batch: 500}, nil
+}
+
+func (s *walSource) Next(ctx context.Context) (*types.LogEvent, error) {
+	if len(s.entries) == 0 {
+		if s.next >= s.wal.NextOffset() {
+			return nil, io.EOF
+		}
+		entries, err := s.wal.Read(s.next, s.batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL at offset %d: %w", s.next, err)
+		}
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		s.entries = entries
+	}
+
+	entry := s.entries[0]
+	s.entries = s.entries[1:]
+	s.next = entry.Offset + 1
+	return entry.Event, nil
+}
+
+func (s *walSource) Close() error {
+	return s.wal.Close()
+}
+
+// s3Client is the subset of the S3 client used by s3Source
+type s3Client interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// s3Source reads NDJSON objects under a prefix in key order, decompressing
+// them by their extension as the S3 output names them
+type s3Source struct {
+	client s3Client
+	bucket string
+	pages  *s3.ListObjectsV2Paginator
+	keys   []string
+	events []*types.LogEvent
+}
+
+// newS3Source creates a source reading the objects under prefix in bucket
+func newS3Source(client s3Client, bucket, prefix string) *s3Source {
+	return &s3Source{
+		client: client,
+		bucket: bucket,
+		pages: s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		}),
+	}
+}
+
+func (s *s3Source) Next(ctx context.Context) (*types.LogEvent, error) {
+	for len(s.events) == 0 {
+		for len(s.keys) == 0 {
+			if !s.pages.HasMorePages() {
+				return nil, io.EOF
+			}
+			page, err := s.pages.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list objects in %s: %w", s.bucket, err)
+			}
+			for _, object := range page.Contents {
+				s.keys = append(s.keys, aws.ToString(object.Key))
+			}
+		}
+
+		key := s.keys[0]
+		s.keys = s.keys[1:]
+		events, err := s.readObject(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		s.events = events
+	}
+
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+// readObject downloads an object and decodes its events
+func (s *s3Source) readObject(ctx context.Context, key string) ([]*types.LogEvent, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	compressor, err := compressorFor(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err = compressor.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+
+	return decodeNDJSON(key, data)
+}
+
+func (s *s3Source) Close() error {
+	return nil
+}
+
+// compressorFor returns the compressor matching an object key's extension
+func compressorFor(key string) (output.Compressor, error) {
+	for _, compression := range []output.CompressionType{output.CompressionGzip, output.CompressionSnappy, output.CompressionLZ4, output.CompressionZstd} {
+		compressor, err := output.GetCompressor(compression)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(key, compressor.Extension()) {
+			return compressor, nil
+		}
+	}
+	return output.GetCompressor(output.CompressionNone)
+}
+
+// decodeNDJSON decodes one event per non-empty line
+func decodeNDJSON(name string, data []byte) ([]*types.LogEvent, error) {
+	var events []*types.LogEvent
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		event := &types.LogEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid event: %w", name, line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return events, nil
+}
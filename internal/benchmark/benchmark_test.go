@@ -114,11 +114,11 @@ func BenchmarkRingBufferEnqueue(b *testing.B) {
 	}
 
 	event := &types.LogEvent{
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now(),
 		Level:     "info",
 		Message:   "Test message",
 		Source:    "test.log",
-		Fields:    make(map[string]interface{}),
+		Fields:    make(map[string]string),
 	}
 
 	ctx := context.Background()
@@ -160,7 +160,7 @@ func BenchmarkRingBufferEnqueueDequeue(b *testing.B) {
 	// Enqueue
 	for i := 0; i < b.N; i++ {
 		event := pool.GetEvent()
-		event.Timestamp = time.Now().Format(time.RFC3339)
+		event.Timestamp = time.Now()
 		event.Level = "info"
 		event.Message = fmt.Sprintf("Test message %d", i)
 		event.Source = "test.log"
@@ -174,27 +174,32 @@ func BenchmarkRingBufferEnqueueDequeue(b *testing.B) {
 // BenchmarkWorkerPool benchmarks worker pool processing
 func BenchmarkWorkerPool(b *testing.B) {
 	cfg := worker.PoolConfig{
-		WorkerCount: 4,
-		JobTimeout:  5 * time.Second,
+		NumWorkers: 4,
+		JobTimeout: 5 * time.Second,
 	}
 
-	pool := worker.NewPool(cfg)
-	pool.Start()
-	defer pool.Stop()
+	wp, err := worker.NewWorkerPool(cfg, func(ctx context.Context, event *types.LogEvent) error {
+		// Simulate some work
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	wp.Start()
+	defer wp.Stop()
+
+	event := &types.LogEvent{
+		Timestamp: time.Now(),
+		Message:   "Test message",
+		Source:    "test.log",
+	}
+	ctx := context.Background()
 
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		job := worker.Job{
-			ID: fmt.Sprintf("job-%d", i),
-			Execute: func(ctx context.Context) error {
-				// Simulate some work
-				return nil
-			},
-		}
-
-		_ = pool.Submit(job)
+		_ = wp.Submit(ctx, event)
 	}
 
 	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "jobs/sec")
@@ -206,11 +211,11 @@ func BenchmarkObjectPooling(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			event := &types.LogEvent{
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: time.Now(),
 				Level:     "info",
 				Message:   "Test message",
 				Source:    "test.log",
-				Fields:    make(map[string]interface{}),
+				Fields:    make(map[string]string),
 			}
 			_ = event
 		}
@@ -220,7 +225,7 @@ func BenchmarkObjectPooling(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			event := pool.GetEvent()
-			event.Timestamp = time.Now().Format(time.RFC3339)
+			event.Timestamp = time.Now()
 			event.Level = "info"
 			event.Message = "Test message"
 			event.Source = "test.log"
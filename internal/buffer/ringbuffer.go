@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -35,15 +36,25 @@ type RingBufferConfig struct {
 	BlockTimeout         time.Duration
 }
 
+// slot holds one event. Its sequence number publishes the event: the slot is
+// free for the write at position pos when seq == pos, and holds that write's
+// event once seq == pos+1, so a reader never sees a slot before its event is
+// stored.
+type slot struct {
+	seq   atomic.Uint64
+	event *types.LogEvent
+}
+
 // RingBuffer is a lock-free circular buffer for log events
 type RingBuffer struct {
-	buffer   []*types.LogEvent
+	buffer   []slot
 	size     uint64
 	mask     uint64
 	writePos uint64
 	readPos  uint64
 
-	config RingBufferConfig
+	config   RingBufferConfig
+	strategy atomic.Value // BackpressureStrategy, see SetBackpressureStrategy
 
 	// Metrics
 	enqueued uint64
@@ -80,24 +91,36 @@ func NewRingBuffer(config RingBufferConfig) (*RingBuffer, error) {
 	}
 
 	rb := &RingBuffer{
-		buffer:   make([]*types.LogEvent, size),
+		buffer:   make([]slot, size),
 		size:     size,
 		mask:     size - 1,
 		config:   config,
 		notEmpty: make(chan struct{}, 1),
 		notFull:  make(chan struct{}, 1),
 	}
+	for i := range rb.buffer {
+		rb.buffer[i].seq.Store(uint64(i))
+	}
+	rb.strategy.Store(config.BackpressureStrategy)
 
 	return rb, nil
 }
 
-// Enqueue adds an event to the buffer
+// Enqueue adds an event to the buffer, tracing it as part of the event's trace
 func (rb *RingBuffer) Enqueue(ctx context.Context, event *types.LogEvent) error {
+	_, span := tracing.TraceBuffer(tracing.EventContext(event), tracing.Tracer(), "enqueue")
+	err := rb.enqueue(ctx, event)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// enqueue adds an event using the configured backpressure strategy
+func (rb *RingBuffer) enqueue(ctx context.Context, event *types.LogEvent) error {
 	if atomic.LoadUint32(&rb.closed) == 1 {
 		return ErrBufferClosed
 	}
 
-	switch rb.config.BackpressureStrategy {
+	switch rb.BackpressureStrategy() {
 	case BackpressureBlock:
 		return rb.enqueueBlocking(ctx, event)
 	case BackpressureDrop:
@@ -109,102 +132,117 @@ func (rb *RingBuffer) Enqueue(ctx context.Context, event *types.LogEvent) error
 	}
 }
 
-// enqueueBlocking blocks when buffer is full
-func (rb *RingBuffer) enqueueBlocking(ctx context.Context, event *types.LogEvent) error {
-	for {
-		writePos := atomic.LoadUint64(&rb.writePos)
-		readPos := atomic.LoadUint64(&rb.readPos)
-
-		// Check if buffer is full
-		if writePos-readPos >= rb.size {
-			// Buffer is full, wait
-			select {
-			case <-rb.notFull:
-				continue
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(rb.config.BlockTimeout):
-				return ErrBufferFull
-			}
-		}
+// BackpressureStrategy returns the strategy applied when the buffer is full
+func (rb *RingBuffer) BackpressureStrategy() BackpressureStrategy {
+	return rb.strategy.Load().(BackpressureStrategy)
+}
 
-		// Try to claim a slot
-		if atomic.CompareAndSwapUint64(&rb.writePos, writePos, writePos+1) {
-			rb.buffer[writePos&rb.mask] = event
-			atomic.AddUint64(&rb.enqueued, 1)
+// SetBackpressureStrategy changes the strategy applied when the buffer is
+// full, returning the previous one. Producers already blocked keep waiting
+// for space.
+func (rb *RingBuffer) SetBackpressureStrategy(strategy BackpressureStrategy) BackpressureStrategy {
+	return rb.strategy.Swap(strategy).(BackpressureStrategy)
+}
 
-			// Signal that buffer is not empty
-			select {
-			case rb.notEmpty <- struct{}{}:
-			default:
-			}
+// enqueueBlocking blocks when buffer is full
+func (rb *RingBuffer) enqueueBlocking(ctx context.Context, event *types.LogEvent) error {
+	timeout := time.NewTimer(rb.config.BlockTimeout)
+	defer timeout.Stop()
 
-			return nil
+	for !rb.tryEnqueue(event) {
+		// Buffer is full, wait
+		select {
+		case <-rb.notFull:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout.C:
+			return ErrBufferFull
 		}
 	}
+	return nil
 }
 
 // enqueueDrop drops oldest event when buffer is full
 func (rb *RingBuffer) enqueueDrop(event *types.LogEvent) error {
-	for {
-		writePos := atomic.LoadUint64(&rb.writePos)
-		readPos := atomic.LoadUint64(&rb.readPos)
-
-		// Check if buffer is full
-		if writePos-readPos >= rb.size {
-			// Drop the oldest event by advancing read position
-			atomic.CompareAndSwapUint64(&rb.readPos, readPos, readPos+1)
+	for !rb.tryEnqueue(event) {
+		if _, ok := rb.take(); ok {
 			atomic.AddUint64(&rb.dropped, 1)
 		}
+	}
+	return nil
+}
 
-		// Try to claim a slot
-		if atomic.CompareAndSwapUint64(&rb.writePos, writePos, writePos+1) {
-			rb.buffer[writePos&rb.mask] = event
-			atomic.AddUint64(&rb.enqueued, 1)
-
-			// Signal that buffer is not empty
-			select {
-			case rb.notEmpty <- struct{}{}:
-			default:
-			}
+// enqueueSample samples events when buffer is full, keeping 1 out of N by
+// dropping the oldest event for it
+func (rb *RingBuffer) enqueueSample(event *types.LogEvent) error {
+	if rb.tryEnqueue(event) {
+		return nil
+	}
 
-			return nil
-		}
+	sampled := atomic.AddUint64(&rb.sampled, 1)
+	if sampled%uint64(rb.config.SampleRate) != 0 {
+		atomic.AddUint64(&rb.dropped, 1)
+		return nil // Drop this event
 	}
+	return rb.enqueueDrop(event)
 }
 
-// enqueueSample samples events when buffer is full
-func (rb *RingBuffer) enqueueSample(event *types.LogEvent) error {
+// tryEnqueue stores an event in the next slot, returning false when the
+// buffer is full
+func (rb *RingBuffer) tryEnqueue(event *types.LogEvent) bool {
 	for {
-		writePos := atomic.LoadUint64(&rb.writePos)
-		readPos := atomic.LoadUint64(&rb.readPos)
-
-		// Check if buffer is full
-		if writePos-readPos >= rb.size {
-			// Sample: only keep 1 out of N events
-			sampled := atomic.AddUint64(&rb.sampled, 1)
-			if sampled%uint64(rb.config.SampleRate) != 0 {
-				atomic.AddUint64(&rb.dropped, 1)
-				return nil // Drop this event
+		pos := atomic.LoadUint64(&rb.writePos)
+		s := &rb.buffer[pos&rb.mask]
+
+		switch diff := int64(s.seq.Load() - pos); {
+		case diff == 0:
+			// Try to claim the slot
+			if atomic.CompareAndSwapUint64(&rb.writePos, pos, pos+1) {
+				s.event = event
+				s.seq.Store(pos + 1)
+				atomic.AddUint64(&rb.enqueued, 1)
+				signal(rb.notEmpty)
+				return true
 			}
+		case diff < 0:
+			// The slot still holds an event from the previous lap
+			return false
 		}
+	}
+}
 
-		// Try to claim a slot
-		if atomic.CompareAndSwapUint64(&rb.writePos, writePos, writePos+1) {
-			rb.buffer[writePos&rb.mask] = event
-			atomic.AddUint64(&rb.enqueued, 1)
-
-			// Signal that buffer is not empty
-			select {
-			case rb.notEmpty <- struct{}{}:
-			default:
+// take removes the oldest published event, returning false when there is
+// none
+func (rb *RingBuffer) take() (*types.LogEvent, bool) {
+	for {
+		pos := atomic.LoadUint64(&rb.readPos)
+		s := &rb.buffer[pos&rb.mask]
+
+		switch diff := int64(s.seq.Load() - (pos + 1)); {
+		case diff == 0:
+			// Try to claim the event
+			if atomic.CompareAndSwapUint64(&rb.readPos, pos, pos+1) {
+				event := s.event
+				s.event = nil // Clear reference for GC
+				s.seq.Store(pos + rb.size)
+				signal(rb.notFull)
+				return event, true
 			}
-
-			return nil
+		case diff < 0:
+			// Empty, or the next event is not stored yet
+			return nil, false
 		}
 	}
 }
 
+// signal wakes a goroutine waiting on ch, if any
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
 // Dequeue removes and returns an event from the buffer
 func (rb *RingBuffer) Dequeue(ctx context.Context) (*types.LogEvent, error) {
 	for {
@@ -212,67 +250,27 @@ func (rb *RingBuffer) Dequeue(ctx context.Context) (*types.LogEvent, error) {
 			return nil, ErrBufferClosed
 		}
 
-		readPos := atomic.LoadUint64(&rb.readPos)
-		writePos := atomic.LoadUint64(&rb.writePos)
-
-		// Check if buffer is empty
-		if readPos >= writePos {
-			// Buffer is empty, wait
-			select {
-			case <-rb.notEmpty:
-				continue
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			}
-		}
-
-		// Try to claim an event
-		if atomic.CompareAndSwapUint64(&rb.readPos, readPos, readPos+1) {
-			event := rb.buffer[readPos&rb.mask]
-			rb.buffer[readPos&rb.mask] = nil // Clear reference for GC
+		if event, ok := rb.take(); ok {
 			atomic.AddUint64(&rb.dequeued, 1)
-
-			// Signal that buffer is not full
-			select {
-			case rb.notFull <- struct{}{}:
-			default:
-			}
-
 			return event, nil
 		}
+
+		// Buffer is empty, wait
+		select {
+		case <-rb.notEmpty:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
 // TryDequeue attempts to dequeue without blocking
 func (rb *RingBuffer) TryDequeue() (*types.LogEvent, bool) {
-	if atomic.LoadUint32(&rb.closed) == 1 && rb.Empty() {
-		return nil, false
-	}
-
-	readPos := atomic.LoadUint64(&rb.readPos)
-	writePos := atomic.LoadUint64(&rb.writePos)
-
-	// Check if buffer is empty
-	if readPos >= writePos {
-		return nil, false
-	}
-
-	// Try to claim an event
-	if atomic.CompareAndSwapUint64(&rb.readPos, readPos, readPos+1) {
-		event := rb.buffer[readPos&rb.mask]
-		rb.buffer[readPos&rb.mask] = nil
+	event, ok := rb.take()
+	if ok {
 		atomic.AddUint64(&rb.dequeued, 1)
-
-		// Signal that buffer is not full
-		select {
-		case rb.notFull <- struct{}{}:
-		default:
-		}
-
-		return event, true
 	}
-
-	return nil, false
+	return event, ok
 }
 
 // Empty checks if buffer is empty
@@ -338,12 +336,12 @@ func (rb *RingBuffer) Close() error {
 
 // BufferMetrics holds buffer statistics
 type BufferMetrics struct {
-	Enqueued    uint64
-	Dequeued    uint64
-	Dropped     uint64
-	CurrentSize int
-	Capacity    int
-	Utilization float64
+	Enqueued    uint64  `json:"enqueued"`
+	Dequeued    uint64  `json:"dequeued"`
+	Dropped     uint64  `json:"dropped"`
+	CurrentSize int     `json:"current_size"`
+	Capacity    int     `json:"capacity"`
+	Utilization float64 `json:"utilization"`
 }
 
 // nextPowerOfTwo returns the next power of 2 greater than or equal to n
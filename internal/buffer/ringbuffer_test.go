@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -147,6 +148,44 @@ func TestRingBuffer_DropBackpressure(t *testing.T) {
 	}
 }
 
+func TestRingBuffer_SetBackpressureStrategy(t *testing.T) {
+	rb, err := NewRingBuffer(RingBufferConfig{
+		Size:         4,
+		BlockTimeout: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	defer rb.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if err := rb.Enqueue(ctx, &types.LogEvent{Message: "old"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	// Switching to drop makes room by dropping the oldest event
+	if previous := rb.SetBackpressureStrategy(BackpressureDrop); previous != BackpressureBlock {
+		t.Errorf("Expected previous strategy block, got %q", previous)
+	}
+	if err := rb.Enqueue(ctx, &types.LogEvent{Message: "new"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if dropped := rb.Metrics().Dropped; dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", dropped)
+	}
+
+	// Switching back blocks producers again
+	rb.SetBackpressureStrategy(BackpressureBlock)
+	if err := rb.Enqueue(ctx, &types.LogEvent{Message: "blocked"}); err != ErrBufferFull {
+		t.Errorf("Expected ErrBufferFull, got %v", err)
+	}
+	if got := rb.BackpressureStrategy(); got != BackpressureBlock {
+		t.Errorf("Expected strategy block, got %q", got)
+	}
+}
+
 func TestRingBuffer_SampleBackpressure(t *testing.T) {
 	rb, err := NewRingBuffer(RingBufferConfig{
 		Size:                 4,
@@ -252,6 +291,50 @@ func TestRingBuffer_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestRingBuffer_BlockingProducersSingleConsumer(t *testing.T) {
+	// A small buffer keeps producers wrapping around slots the consumer is
+	// still reading
+	rb, err := NewRingBuffer(RingBufferConfig{Size: 4, BlockTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	defer rb.Close()
+
+	ctx := context.Background()
+	const producers, perProducer = 8, 500
+
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < perProducer; j++ {
+				event := &types.LogEvent{Message: fmt.Sprintf("%d-%d", id, j)}
+				if err := rb.Enqueue(ctx, event); err != nil {
+					t.Errorf("Producer %d: Enqueue() error = %v", id, err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	seen := make(map[string]bool, producers*perProducer)
+	for len(seen) < producers*perProducer {
+		event, err := rb.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if event == nil {
+			t.Fatal("Dequeue() returned an event before it was stored")
+		}
+		if seen[event.Message] {
+			t.Fatalf("event %s dequeued twice", event.Message)
+		}
+		seen[event.Message] = true
+	}
+	wg.Wait()
+}
+
 func TestRingBuffer_Metrics(t *testing.T) {
 	rb, err := NewRingBuffer(RingBufferConfig{Size: 10})
 	if err != nil {
@@ -1,10 +1,11 @@
 package checkpoint
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,29 +14,55 @@ import (
 
 // Manager manages checkpoint persistence
 type Manager struct {
-	mu            sync.RWMutex
-	checkpointDir string
-	positions     map[string]*types.FilePosition
-	interval      time.Duration
-	stopCh        chan struct{}
-	saveCh        chan struct{}
+	mu         sync.RWMutex
+	store      Store
+	positions  map[string]*types.FilePosition
+	timestamps map[string]time.Time
+	interval   time.Duration
+	stopCh     chan struct{}
+	saveCh     chan struct{}
+	resetToEnd bool
+	resetHooks []func(path string)
+
+	// hasTimestamps is set once timestamps were recorded or loaded, so
+	// file-only managers keep a single checkpoint document
+	hasTimestamps bool
 }
 
-// NewManager creates a new checkpoint manager
+// Keys of the documents a manager stores
+const (
+	positionsKey  = "positions"
+	timestampsKey = "timestamps"
+)
+
+// Checkpoint is the persisted resume position of a file
+type Checkpoint struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+	Inode  uint64 `json:"inode"`
+}
+
+// NewManager creates a new checkpoint manager persisting to files in
+// checkpointDir
 func NewManager(checkpointDir string, interval time.Duration) (*Manager, error) {
-	if err := os.MkdirAll(checkpointDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	store, err := NewFileStore(checkpointDir)
+	if err != nil {
+		return nil, err
 	}
+	return NewManagerWithStore(store, interval), nil
+}
 
-	m := &Manager{
-		checkpointDir: checkpointDir,
-		positions:     make(map[string]*types.FilePosition),
-		interval:      interval,
-		stopCh:        make(chan struct{}),
-		saveCh:        make(chan struct{}, 1),
+// NewManagerWithStore creates a new checkpoint manager persisting to store.
+// The manager owns the store and closes it when stopped.
+func NewManagerWithStore(store Store, interval time.Duration) *Manager {
+	return &Manager{
+		store:      store,
+		positions:  make(map[string]*types.FilePosition),
+		timestamps: make(map[string]time.Time),
+		interval:   interval,
+		stopCh:     make(chan struct{}),
+		saveCh:     make(chan struct{}, 1),
 	}
-
-	return m, nil
 }
 
 // Start starts the periodic checkpoint saving
@@ -47,6 +74,7 @@ func (m *Manager) Start() {
 func (m *Manager) Stop() {
 	close(m.stopCh)
 	m.Save() // Final save before stopping
+	m.store.Close()
 }
 
 // UpdatePosition updates the position for a file
@@ -76,52 +104,199 @@ func (m *Manager) GetPosition(path string) (*types.FilePosition, bool) {
 	return pos, ok
 }
 
-// Load loads checkpoints from disk
+// DeletePosition removes the position recorded for a file
+func (m *Manager) DeletePosition(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.positions, path)
+
+	// Trigger save
+	select {
+	case m.saveCh <- struct{}{}:
+	default:
+	}
+}
+
+// Get returns the checkpoint for a file
+func (m *Manager) Get(path string) (Checkpoint, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pos, ok := m.positions[path]
+	if !ok {
+		return Checkpoint{}, false
+	}
+	return Checkpoint{Path: path, Offset: pos.Offset, Inode: pos.Inode}, true
+}
+
+// List returns all file checkpoints sorted by path
+func (m *Manager) List() []Checkpoint {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	checkpoints := make([]Checkpoint, 0, len(m.positions))
+	for path, pos := range m.positions {
+		checkpoints = append(checkpoints, Checkpoint{Path: path, Offset: pos.Offset, Inode: pos.Inode})
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Path < checkpoints[j].Path
+	})
+	return checkpoints
+}
+
+// SetResetToEnd controls where a reset file resumes: from the end instead
+// of re-reading it from the beginning
+func (m *Manager) SetResetToEnd(toEnd bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resetToEnd = toEnd
+}
+
+// ResetsToEnd reports whether reset files resume from the end
+func (m *Manager) ResetsToEnd() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.resetToEnd
+}
+
+// OnReset registers a function called with the path of each reset file
+func (m *Manager) OnReset(fn func(path string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resetHooks = append(m.resetHooks, fn)
+}
+
+// Reset discards the persisted offset of a file so it is read again from
+// the beginning, or from the end if SetResetToEnd was enabled
+func (m *Manager) Reset(path string) error {
+	m.mu.Lock()
+	if m.resetToEnd {
+		delete(m.positions, path)
+	} else if pos, ok := m.positions[path]; ok {
+		// Keep the inode so the same file resumes from the start
+		m.positions[path] = &types.FilePosition{Path: path, Offset: 0, Inode: pos.Inode}
+	}
+	hooks := append([]func(string){}, m.resetHooks...)
+	m.mu.Unlock()
+
+	if err := m.Save(); err != nil {
+		return fmt.Errorf("failed to persist checkpoint reset: %w", err)
+	}
+
+	for _, hook := range hooks {
+		hook(path)
+	}
+	return nil
+}
+
+// UpdateTimestamp records the last-seen timestamp for a stream key,
+// ignoring timestamps older than the one already recorded
+func (m *Manager) UpdateTimestamp(key string, ts time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if last, ok := m.timestamps[key]; ok && !ts.After(last) {
+		return
+	}
+	m.timestamps[key] = ts
+	m.hasTimestamps = true
+
+	// Trigger save
+	select {
+	case m.saveCh <- struct{}{}:
+	default:
+	}
+}
+
+// GetTimestamp retrieves the last-seen timestamp for a stream key
+func (m *Manager) GetTimestamp(key string) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ts, ok := m.timestamps[key]
+	return ts, ok
+}
+
+// DeleteTimestamp removes the timestamp recorded for a stream key
+func (m *Manager) DeleteTimestamp(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.timestamps, key)
+}
+
+// Load loads checkpoints from the store
 func (m *Manager) Load() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	checkpointFile := filepath.Join(m.checkpointDir, "positions.json")
-	data, err := os.ReadFile(checkpointFile)
+	var positions map[string]*types.FilePosition
+	found, err := m.read(positionsKey, &positions)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No checkpoint file yet
-		}
-		return fmt.Errorf("failed to read checkpoint file: %w", err)
+		return err
+	}
+	if found {
+		m.positions = positions
 	}
 
-	var positions map[string]*types.FilePosition
-	if err := json.Unmarshal(data, &positions); err != nil {
-		return fmt.Errorf("failed to unmarshal checkpoint data: %w", err)
+	var timestamps map[string]time.Time
+	found, err = m.read(timestampsKey, &timestamps)
+	if err != nil {
+		return err
+	}
+	if found {
+		m.hasTimestamps = true
+		if timestamps != nil {
+			m.timestamps = timestamps
+		}
 	}
 
-	m.positions = positions
 	return nil
 }
 
-// Save saves checkpoints to disk
+// read decodes a checkpoint document, reporting false if it does not exist
+func (m *Manager) read(key string, v interface{}) (bool, error) {
+	data, found, err := m.store.Load(context.Background(), key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to unmarshal checkpoint data: %w", err)
+	}
+
+	return true, nil
+}
+
+// Save saves checkpoints to the store
 func (m *Manager) Save() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	checkpointFile := filepath.Join(m.checkpointDir, "positions.json")
-
-	data, err := json.MarshalIndent(m.positions, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal checkpoint data: %w", err)
+	if err := m.write(positionsKey, m.positions); err != nil {
+		return err
 	}
 
-	// Write to temporary file first, then rename for atomicity
-	tmpFile := checkpointFile + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	// Only stream-based inputs record timestamps; skip the document until
+	// one has been recorded so file-only managers keep a single document
+	if !m.hasTimestamps {
+		return nil
 	}
+	return m.write(timestampsKey, m.timestamps)
+}
 
-	if err := os.Rename(tmpFile, checkpointFile); err != nil {
-		return fmt.Errorf("failed to rename checkpoint file: %w", err)
+// write encodes v into a checkpoint document
+func (m *Manager) write(key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint data: %w", err)
 	}
 
-	return nil
+	return m.store.Save(context.Background(), key, data)
 }
 
 // saveLoop periodically saves checkpoints
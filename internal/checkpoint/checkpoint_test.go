@@ -131,3 +131,180 @@ func TestCheckpointPeriodic(t *testing.T) {
 		t.Errorf("Expected offset 9999, got %d", pos.Offset)
 	}
 }
+
+func TestCheckpointTimestamps(t *testing.T) {
+	checkpointDir := filepath.Join(t.TempDir(), "checkpoints")
+
+	mgr1, err := NewManager(checkpointDir, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	mgr1.UpdateTimestamp("default/app/web", ts)
+	mgr1.UpdateTimestamp("default/app/web", ts.Add(-time.Second)) // Older, ignored
+
+	if got, _ := mgr1.GetTimestamp("default/app/web"); !got.Equal(ts) {
+		t.Errorf("expected timestamp %v, got %v", ts, got)
+	}
+
+	if err := mgr1.Save(); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	mgr1.Stop()
+
+	mgr2, err := NewManager(checkpointDir, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create second checkpoint manager: %v", err)
+	}
+	defer mgr2.Stop()
+
+	if err := mgr2.Load(); err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+
+	got, ok := mgr2.GetTimestamp("default/app/web")
+	if !ok || !got.Equal(ts) {
+		t.Errorf("expected loaded timestamp %v, got %v (found=%v)", ts, got, ok)
+	}
+
+	mgr2.DeleteTimestamp("default/app/web")
+	if _, ok := mgr2.GetTimestamp("default/app/web"); ok {
+		t.Error("expected timestamp to be deleted")
+	}
+}
+
+func TestCheckpointGetAndList(t *testing.T) {
+	mgr, err := NewManager(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+
+	if _, ok := mgr.Get("/var/log/missing.log"); ok {
+		t.Error("Expected no checkpoint for an unknown file")
+	}
+	if got := mgr.List(); len(got) != 0 {
+		t.Errorf("Expected empty list, got %v", got)
+	}
+
+	mgr.UpdatePosition("/var/log/b.log", 20, 2)
+	mgr.UpdatePosition("/var/log/a.log", 10, 1)
+
+	cp, ok := mgr.Get("/var/log/a.log")
+	if !ok || cp != (Checkpoint{Path: "/var/log/a.log", Offset: 10, Inode: 1}) {
+		t.Errorf("Get() = %+v, %v", cp, ok)
+	}
+
+	list := mgr.List()
+	if len(list) != 2 || list[0].Path != "/var/log/a.log" || list[1].Path != "/var/log/b.log" {
+		t.Errorf("List() = %+v, want both checkpoints sorted by path", list)
+	}
+}
+
+func TestCheckpointReset(t *testing.T) {
+	t.Run("from start", func(t *testing.T) {
+		dir := t.TempDir()
+		mgr, err := NewManager(dir, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to create checkpoint manager: %v", err)
+		}
+
+		var resets []string
+		mgr.OnReset(func(path string) { resets = append(resets, path) })
+
+		mgr.UpdatePosition("/var/log/app.log", 1234, 42)
+		if err := mgr.Reset("/var/log/app.log"); err != nil {
+			t.Fatalf("Reset() error = %v", err)
+		}
+
+		cp, ok := mgr.Get("/var/log/app.log")
+		if !ok || cp.Offset != 0 || cp.Inode != 42 {
+			t.Errorf("Expected offset 0 with inode kept, got %+v, %v", cp, ok)
+		}
+		if len(resets) != 1 || resets[0] != "/var/log/app.log" {
+			t.Errorf("Expected reset hook to be called once, got %v", resets)
+		}
+
+		// The reset must already be persisted
+		reloaded, _ := NewManager(dir, time.Second)
+		if err := reloaded.Load(); err != nil {
+			t.Fatalf("Failed to load checkpoints: %v", err)
+		}
+		if cp, _ := reloaded.Get("/var/log/app.log"); cp.Offset != 0 {
+			t.Errorf("Expected persisted offset 0, got %d", cp.Offset)
+		}
+	})
+
+	t.Run("to end", func(t *testing.T) {
+		mgr, err := NewManager(t.TempDir(), time.Second)
+		if err != nil {
+			t.Fatalf("Failed to create checkpoint manager: %v", err)
+		}
+		mgr.SetResetToEnd(true)
+
+		mgr.UpdatePosition("/var/log/app.log", 1234, 42)
+		if err := mgr.Reset("/var/log/app.log"); err != nil {
+			t.Fatalf("Reset() error = %v", err)
+		}
+
+		if _, ok := mgr.Get("/var/log/app.log"); ok {
+			t.Error("Expected checkpoint to be removed")
+		}
+	})
+}
+
+func TestCheckpointCorruptRecovery(t *testing.T) {
+	checkpointDir := filepath.Join(t.TempDir(), "checkpoints")
+	checkpointFile := filepath.Join(checkpointDir, "positions.json")
+
+	mgr1, err := NewManager(checkpointDir, 1*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	mgr1.UpdatePosition("/var/log/app.log", 1000, 123)
+	if err := mgr1.Save(); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	mgr1.UpdatePosition("/var/log/app.log", 2000, 123)
+	if err := mgr1.Save(); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty", content: nil},
+		{name: "truncated", content: data[:len(data)/2]},
+		{name: "truncated header", content: data[:10]},
+		{name: "flipped byte", content: append(append([]byte{}, data[:len(data)-3]...), 'X', data[len(data)-2], data[len(data)-1])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(checkpointFile, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			mgr2, err := NewManager(checkpointDir, 1*time.Second)
+			if err != nil {
+				t.Fatalf("Failed to create second manager: %v", err)
+			}
+			defer mgr2.Stop()
+
+			// The previous good checkpoint is loaded instead of failing
+			if err := mgr2.Load(); err != nil {
+				t.Fatalf("Failed to load checkpoint: %v", err)
+			}
+			pos, ok := mgr2.GetPosition("/var/log/app.log")
+			if !ok || pos.Offset != 1000 {
+				t.Fatalf("expected the previous offset 1000, got %+v, %v", pos, ok)
+			}
+		})
+	}
+}
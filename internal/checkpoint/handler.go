@@ -0,0 +1,54 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns an admin HTTP handler for inspecting and resetting
+// checkpoints. GET lists all checkpoints, or returns one with ?path=.
+// POST or DELETE with ?path= resets that file's checkpoint.
+func (m *Manager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+
+		switch r.Method {
+		case http.MethodGet:
+			if path == "" {
+				writeJSON(w, http.StatusOK, m.List())
+				return
+			}
+
+			cp, ok := m.Get(path)
+			if !ok {
+				http.Error(w, "checkpoint not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, cp)
+		case http.MethodPost, http.MethodDelete:
+			if path == "" {
+				http.Error(w, "path query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			if err := m.Reset(path); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{
+				"status": "reset",
+				"path":   path,
+			})
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,61 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	mgr, err := NewManager(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	mgr.UpdatePosition("/var/log/app.log", 100, 7)
+	handler := mgr.Handler()
+
+	t.Run("List", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checkpoints", nil))
+
+		var list []Checkpoint
+		if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if w.Code != http.StatusOK || len(list) != 1 || list[0].Offset != 100 {
+			t.Errorf("unexpected list response %d: %+v", w.Code, list)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checkpoints?path=/var/log/other.log", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkpoints?path=/var/log/app.log", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if cp, _ := mgr.Get("/var/log/app.log"); cp.Offset != 0 {
+			t.Errorf("expected offset 0 after reset, got %d", cp.Offset)
+		}
+	})
+
+	t.Run("ResetWithoutPath", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/checkpoints", nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store persists the checkpoint documents of a manager under names such as
+// "positions". Implementations must replace a document atomically, so a
+// crash or a concurrent reader never sees a partial save.
+type Store interface {
+	// Save atomically replaces the document stored under key
+	Save(ctx context.Context, key string, data []byte) error
+
+	// Load returns the document stored under key, reporting false if there
+	// is none
+	Load(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Delete removes the document stored under key, if any
+	Delete(ctx context.Context, key string) error
+
+	// List returns the keys of the stored documents, sorted
+	List(ctx context.Context) ([]string, error)
+
+	// Close releases the store
+	Close() error
+}
+
+// Store types
+const (
+	StoreFile  = "file"
+	StoreRedis = "redis"
+)
+
+// StoreConfig selects and configures a checkpoint store
+type StoreConfig struct {
+	// Type is file (the default) or redis
+	Type string
+
+	// Dir is the directory of the file store
+	Dir string
+
+	// Address, Password and DB locate the Redis server. Key is the hash
+	// holding the documents; replicas sharing a key share their positions.
+	Address  string
+	Password string
+	DB       int
+	Key      string
+
+	// Timeout bounds each Redis operation
+	Timeout time.Duration
+
+	// OnRecover is called by the file store when it loads the backup of a
+	// corrupt document, with the document's key and the corruption error
+	OnRecover func(key string, err error)
+}
+
+// DefaultRedisKey is the Redis hash holding checkpoints when none is set
+const DefaultRedisKey = "logaggregator:checkpoints"
+
+// NewStore creates the store selected by cfg
+func NewStore(cfg StoreConfig) (Store, error) {
+	switch cfg.Type {
+	case "", StoreFile:
+		store, err := NewFileStore(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store.OnRecover(cfg.OnRecover)
+		return store, nil
+	case StoreRedis:
+		return NewRedisStore(cfg)
+	default:
+		return nil, fmt.Errorf("unknown checkpoint store type: %s", cfg.Type)
+	}
+}
+
+// FileStore keeps each document in a JSON file of a directory. Files start
+// with a checksum header, and the previous version of each is kept as a
+// backup that Load falls back to if a crash left the file corrupt.
+type FileStore struct {
+	mu        sync.Mutex // Serializes saves sharing a temporary file
+	dir       string
+	onRecover func(key string, err error)
+}
+
+const (
+	fileSuffix   = ".json"
+	backupSuffix = ".bak"
+
+	// checksumHeader starts each file, followed by the CRC-32 of the
+	// document and a newline
+	checksumHeader = "# logaggregator checkpoint crc32="
+)
+
+// ErrCorruptCheckpoint is returned when a checkpoint file fails its checksum
+var ErrCorruptCheckpoint = errors.New("checkpoint file is corrupt")
+
+// NewFileStore creates a file store in dir, creating the directory
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the document to a synced temporary file, then renames it over
+// the previous one for atomicity. The previous file becomes the backup
+// unless it is corrupt, keeping the last good backup.
+func (s *FileStore) Save(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpointFile := s.path(key)
+
+	tmpFile := checkpointFile + ".tmp"
+	if err := writeSynced(tmpFile, encodeChecksummed(data)); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	if _, found, err := readChecksummed(checkpointFile); err == nil && found {
+		if err := os.Rename(checkpointFile, checkpointFile+backupSuffix); err != nil {
+			return fmt.Errorf("failed to back up checkpoint file: %w", err)
+		}
+	}
+	if err := os.Rename(tmpFile, checkpointFile); err != nil {
+		return fmt.Errorf("failed to rename checkpoint file: %w", err)
+	}
+
+	// Persist the renames too
+	return syncDir(s.dir)
+}
+
+// Load returns the document, or the backup if the file is missing or
+// corrupt. A corrupt file without a good backup is an error.
+func (s *FileStore) Load(_ context.Context, key string) ([]byte, bool, error) {
+	checkpointFile := s.path(key)
+
+	data, found, err := readChecksummed(checkpointFile)
+	if err == nil && found {
+		return data, true, nil
+	}
+	if err != nil && !errors.Is(err, ErrCorruptCheckpoint) {
+		return nil, false, err
+	}
+
+	backup, backupFound, backupErr := readChecksummed(checkpointFile + backupSuffix)
+	if backupErr == nil && backupFound {
+		if err != nil && s.onRecover != nil {
+			s.onRecover(key, err)
+		}
+		return backup, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return nil, false, nil // No checkpoint file yet
+}
+
+// OnRecover sets a function called with the key and the corruption error
+// of each corrupt document replaced by its backup on Load
+func (s *FileStore) OnRecover(fn func(key string, err error)) {
+	s.onRecover = fn
+}
+
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	checkpointFile := s.path(key)
+	for _, file := range []string{checkpointFile, checkpointFile + backupSuffix} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove checkpoint file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) List(context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoint directory: %w", err)
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), fileSuffix) {
+			keys = append(keys, strings.TrimSuffix(entry.Name(), fileSuffix))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// path returns the file of a document
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key+fileSuffix)
+}
+
+// encodeChecksummed prefixes data with its checksum header
+func encodeChecksummed(data []byte) []byte {
+	header := fmt.Sprintf("%s%08x\n", checksumHeader, crc32.ChecksumIEEE(data))
+	return append([]byte(header), data...)
+}
+
+// readChecksummed reads a checkpoint file and verifies its checksum,
+// reporting false if it does not exist. Files written before checksums
+// were added have no header and are returned as they are.
+func readChecksummed(file string) ([]byte, bool, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	// An empty file or a truncated header is what a crash mid-write leaves
+	if len(raw) == 0 || (len(raw) < len(checksumHeader) && strings.HasPrefix(checksumHeader, string(raw))) {
+		return nil, false, fmt.Errorf("%w: %s is truncated", ErrCorruptCheckpoint, file)
+	}
+	if !bytes.HasPrefix(raw, []byte(checksumHeader)) {
+		return raw, true, nil
+	}
+
+	header, data, ok := bytes.Cut(raw[len(checksumHeader):], []byte("\n"))
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s has a truncated header", ErrCorruptCheckpoint, file)
+	}
+	sum, err := strconv.ParseUint(string(header), 16, 32)
+	if err != nil || uint32(sum) != crc32.ChecksumIEEE(data) {
+		return nil, false, fmt.Errorf("%w: %s fails its checksum", ErrCorruptCheckpoint, file)
+	}
+	return data, true, nil
+}
+
+// writeSynced writes a file and flushes it to disk
+func writeSynced(file string, data []byte) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes the entries of a directory to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync checkpoint directory: %w", err)
+	}
+	return nil
+}
+
+// RedisStore keeps the documents as the fields of a Redis hash, so
+// replicas of an aggregator can share and resume each other's positions.
+// Each save is a single HSET, which Redis applies atomically.
+type RedisStore struct {
+	client  *redis.Client
+	key     string
+	timeout time.Duration
+}
+
+// NewRedisStore connects to the Redis server of cfg
+func NewRedisStore(cfg StoreConfig) (*RedisStore, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("redis checkpoint store requires an address")
+	}
+	if cfg.Key == "" {
+		cfg.Key = DefaultRedisKey
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	s := &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Address,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		key:     cfg.Key,
+		timeout: cfg.Timeout,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.Address, err)
+	}
+
+	return s, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.client.HSet(ctx, s.key, key, data).Err(); err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Load(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	data, err := s.client.HGet(ctx, s.key, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load checkpoint %s: %w", key, err)
+	}
+	return data, true, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.client.HDel(ctx, s.key, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete checkpoint %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	keys, err := s.client.HKeys(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
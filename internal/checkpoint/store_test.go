@@ -0,0 +1,231 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testStores returns a file store and a Redis store backed by miniredis
+func testStores() map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"file": func(t *testing.T) Store {
+			store, err := NewStore(StoreConfig{Type: StoreFile, Dir: filepath.Join(t.TempDir(), "checkpoints")})
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			return store
+		},
+		"redis": func(t *testing.T) Store {
+			server := miniredis.RunT(t)
+			store, err := NewStore(StoreConfig{Type: StoreRedis, Address: server.Addr()})
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			return store
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	for name, newStore := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			if _, found, err := store.Load(ctx, "positions"); err != nil || found {
+				t.Fatalf("Load() of a missing key = %v, %v, want not found", found, err)
+			}
+
+			for _, key := range []string{"timestamps", "positions"} {
+				if err := store.Save(ctx, key, []byte(`{"key":"`+key+`"}`)); err != nil {
+					t.Fatalf("Save(%s) error = %v", key, err)
+				}
+			}
+			// A save replaces the previous document
+			if err := store.Save(ctx, "positions", []byte(`{"offset":2}`)); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			data, found, err := store.Load(ctx, "positions")
+			if err != nil || !found || string(data) != `{"offset":2}` {
+				t.Fatalf("Load() = %q, %v, %v", data, found, err)
+			}
+
+			keys, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if strings.Join(keys, ",") != "positions,timestamps" {
+				t.Errorf("expected keys positions and timestamps, got %v", keys)
+			}
+
+			if err := store.Delete(ctx, "timestamps"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := store.Delete(ctx, "timestamps"); err != nil {
+				t.Errorf("expected deleting a missing key to succeed, got %v", err)
+			}
+			if keys, _ := store.List(ctx); strings.Join(keys, ",") != "positions" {
+				t.Errorf("expected only positions left, got %v", keys)
+			}
+		})
+	}
+}
+
+func TestFileStore_AtomicSave(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	if err := store.Save(context.Background(), "positions", []byte("{}")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Only the renamed document remains, and stray files are not keys
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "positions.json" {
+		t.Errorf("expected only positions.json, got %v", entries)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "positions.json.tmp"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := store.List(context.Background()); len(keys) != 1 {
+		t.Errorf("expected the temporary file ignored, got %v", keys)
+	}
+}
+
+func TestFileStore_Checksum(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	file := filepath.Join(dir, "positions.json")
+
+	// A corrupt file without a backup is reported, not read as empty
+	if err := store.Save(ctx, "positions", []byte(`{"offset":1}`)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(file, []byte(checksumHeader+"00000000\n{\"offset\":1}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Load(ctx, "positions"); !errors.Is(err, ErrCorruptCheckpoint) {
+		t.Errorf("expected ErrCorruptCheckpoint, got %v", err)
+	}
+
+	// Files written before checksums were added still load
+	if err := os.WriteFile(file, []byte(`{"offset":3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, found, err := store.Load(ctx, "positions"); err != nil || !found || string(data) != `{"offset":3}` {
+		t.Errorf("Load() of a legacy file = %q, %v, %v", data, found, err)
+	}
+
+	// A corrupt file with a good backup loads the backup and reports it
+	var recovered []string
+	store.OnRecover(func(key string, err error) {
+		if errors.Is(err, ErrCorruptCheckpoint) {
+			recovered = append(recovered, key)
+		}
+	})
+	if err := store.Save(ctx, "positions", []byte(`{"offset":5}`)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(file, []byte(checksumHeader+"00000000\n{\"offset\":6}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, found, err := store.Load(ctx, "positions"); err != nil || !found || string(data) != `{"offset":3}` {
+		t.Errorf("Load() of a corrupt file = %q, %v, %v", data, found, err)
+	}
+	if len(recovered) != 1 || recovered[0] != "positions" {
+		t.Errorf("expected the recovery of positions reported, got %v", recovered)
+	}
+
+	// A crash between the renames leaves only the backup
+	if err := store.Save(ctx, "positions", []byte(`{"offset":4}`)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.Rename(file, file+backupSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if data, found, err := store.Load(ctx, "positions"); err != nil || !found || string(data) != `{"offset":4}` {
+		t.Errorf("Load() without the file = %q, %v, %v", data, found, err)
+	}
+
+	if err := store.Delete(ctx, "positions"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, found, err := store.Load(ctx, "positions"); err != nil || found {
+		t.Errorf("expected the backup deleted too, got %v, %v", found, err)
+	}
+}
+
+func TestNewStore_Errors(t *testing.T) {
+	if _, err := NewStore(StoreConfig{Type: "etcd"}); err == nil {
+		t.Error("expected an error for an unknown store type")
+	}
+	if _, err := NewStore(StoreConfig{Type: StoreRedis}); err == nil {
+		t.Error("expected an error for a redis store without an address")
+	}
+
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+	if _, err := NewStore(StoreConfig{Type: StoreRedis, Address: addr, Timeout: time.Second}); err == nil {
+		t.Error("expected an error for an unreachable redis server")
+	}
+}
+
+func TestManager_RedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	newManager := func() *Manager {
+		store, err := NewRedisStore(StoreConfig{Address: server.Addr(), Key: "replicas"})
+		if err != nil {
+			t.Fatalf("NewRedisStore() error = %v", err)
+		}
+		return NewManagerWithStore(store, time.Second)
+	}
+
+	mgr1 := newManager()
+	defer mgr1.Stop()
+	mgr1.UpdatePosition("/var/log/app.log", 1000, 123)
+	if err := mgr1.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if fields, _ := server.HKeys("replicas"); strings.Join(fields, ",") != "positions" {
+		t.Errorf("expected only positions stored without timestamps, got %v", fields)
+	}
+	mgr1.UpdateTimestamp("pod/app", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	if err := mgr1.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Another replica sharing the key resumes from the saved positions
+	mgr2 := newManager()
+	defer mgr2.Stop()
+	if err := mgr2.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	pos, ok := mgr2.GetPosition("/var/log/app.log")
+	if !ok || pos.Offset != 1000 || pos.Inode != 123 {
+		t.Errorf("expected the shared position, got %+v, %v", pos, ok)
+	}
+	if ts, ok := mgr2.GetTimestamp("pod/app"); !ok || ts.Hour() != 10 {
+		t.Errorf("expected the shared timestamp, got %v, %v", ts, ok)
+	}
+}
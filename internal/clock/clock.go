@@ -0,0 +1,241 @@
+// Package clock is the time source of components with time-based behavior.
+// They use Real in production; tests drive a Fake forward instead of
+// sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers and tickers firing on it
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a time.Timer of a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a time.Ticker of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real is the wall clock of the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// Fake is a clock whose time only moves when Advance or Set is called.
+// Timers and tickers due by then fire in order, each sending the time it
+// was due on its channel, which like the time package's holds one value.
+// It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	added   chan struct{} // closed when a timer or ticker is started
+}
+
+// waiter is a pending timer or ticker of a Fake
+type waiter struct {
+	clock  *Fake
+	when   time.Time
+	period time.Duration // zero for timers
+	ch     chan time.Time
+}
+
+// NewFake creates a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, added: make(chan struct{})}
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel receiving the fake time once d has passed
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer creates a timer firing once d has passed
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &waiter{clock: f, ch: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.start(w, d)
+	return fakeTimer{w}
+}
+
+// NewTicker creates a ticker firing every d. Like time.NewTicker, it panics
+// if d is not positive.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake.NewTicker")
+	}
+	w := &waiter{clock: f, period: d, ch: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.start(w, d)
+	return fakeTicker{w}
+}
+
+// Advance moves the time forward by d, firing the timers and tickers due
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the time to t, firing the timers and tickers due by then
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = t
+	f.fire()
+}
+
+// Waiters returns the number of timers and tickers not yet fired or stopped
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, for
+// tests to advance the time only once a goroutine has started its timer
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		pending, added := len(f.waiters), f.added
+		f.mu.Unlock()
+
+		if pending >= n {
+			return
+		}
+		<-added
+	}
+}
+
+// start schedules w to fire once d has passed (must be called with the
+// lock held). A timer already due fires at once.
+func (f *Fake) start(w *waiter, d time.Duration) {
+	f.remove(w)
+	w.when = f.now.Add(d)
+	f.waiters = append(f.waiters, w)
+	close(f.added)
+	f.added = make(chan struct{})
+	f.fire()
+}
+
+// fire sends the due time on the channel of every waiter due (must be
+// called with the lock held), earliest first. Tickers are rescheduled past
+// the current time, dropping the ticks a slow receiver missed.
+func (f *Fake) fire() {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].when.Before(f.waiters[j].when)
+	})
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.when.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+
+		select {
+		case w.ch <- w.when:
+		default:
+		}
+		if w.period > 0 {
+			for !w.when.After(f.now) {
+				w.when = w.when.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+// remove unschedules w (must be called with the lock held), reporting
+// whether it was pending
+func (f *Fake) remove(w *waiter) bool {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct{ *waiter }
+
+func (t fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t.waiter)
+}
+
+func (t fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := t.clock.remove(t.waiter)
+	t.clock.start(t.waiter, d)
+	return pending
+}
+
+type fakeTicker struct{ *waiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.remove(t.waiter)
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.waiter.period = d
+	t.clock.start(t.waiter, d)
+}
@@ -0,0 +1,150 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// received returns the value waiting on ch, if any
+func received(ch <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-ch:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFake_Advance(t *testing.T) {
+	fake := NewFake(epoch)
+
+	fake.Advance(time.Minute)
+	if got := fake.Now(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("Now() = %v, want %v", got, epoch.Add(time.Minute))
+	}
+	if got := fake.Since(epoch); got != time.Minute {
+		t.Errorf("Since() = %v, want %v", got, time.Minute)
+	}
+
+	fake.Set(epoch)
+	if got := fake.Now(); !got.Equal(epoch) {
+		t.Errorf("Now() after Set = %v, want %v", got, epoch)
+	}
+}
+
+func TestFake_Timer(t *testing.T) {
+	fake := NewFake(epoch)
+	timer := fake.NewTimer(time.Second)
+
+	fake.Advance(999 * time.Millisecond)
+	if _, ok := received(timer.C()); ok {
+		t.Fatal("timer fired before it was due")
+	}
+
+	fake.Advance(time.Millisecond)
+	got, ok := received(timer.C())
+	if !ok {
+		t.Fatal("timer did not fire when due")
+	}
+	if !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("timer sent %v, want %v", got, epoch.Add(time.Second))
+	}
+	if fake.Waiters() != 0 {
+		t.Errorf("expected the fired timer removed, %d waiters", fake.Waiters())
+	}
+
+	// Reset rearms a fired timer from the current time
+	if timer.Reset(time.Second) {
+		t.Error("Reset() of a fired timer reported it pending")
+	}
+	if !timer.Stop() {
+		t.Error("Stop() of a pending timer reported it fired")
+	}
+	fake.Advance(time.Hour)
+	if _, ok := received(timer.C()); ok {
+		t.Error("stopped timer fired")
+	}
+
+	// A timer already due fires at once
+	if _, ok := received(fake.After(0)); !ok {
+		t.Error("After(0) did not fire at once")
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	fake := NewFake(epoch)
+	ticker := fake.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		fake.Advance(10 * time.Millisecond)
+		got, ok := received(ticker.C())
+		if !ok {
+			t.Fatalf("tick %d missing", i)
+		}
+		if want := epoch.Add(time.Duration(i) * 10 * time.Millisecond); !got.Equal(want) {
+			t.Errorf("tick %d sent %v, want %v", i, got, want)
+		}
+	}
+
+	// Ticks missed by a slow receiver are dropped
+	fake.Advance(100 * time.Millisecond)
+	if _, ok := received(ticker.C()); !ok {
+		t.Fatal("expected a tick after advancing several periods")
+	}
+	if _, ok := received(ticker.C()); ok {
+		t.Error("expected missed ticks dropped")
+	}
+
+	ticker.Reset(time.Second)
+	fake.Advance(10 * time.Millisecond)
+	if _, ok := received(ticker.C()); ok {
+		t.Error("ticker fired at its old period after Reset")
+	}
+	fake.Advance(990 * time.Millisecond)
+	if _, ok := received(ticker.C()); !ok {
+		t.Error("ticker did not fire at its new period")
+	}
+
+	ticker.Stop()
+	fake.Advance(time.Hour)
+	if _, ok := received(ticker.C()); ok {
+		t.Error("stopped ticker fired")
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	fake := NewFake(epoch)
+	fired := make(chan time.Time)
+
+	go func() {
+		fired <- <-fake.After(time.Second)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+
+	select {
+	case got := <-fired:
+		if !got.Equal(epoch.Add(time.Second)) {
+			t.Errorf("timer sent %v, want %v", got, epoch.Add(time.Second))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer started by the goroutine did not fire")
+	}
+}
+
+func TestReal(t *testing.T) {
+	start := Real.Now()
+	timer := Real.NewTimer(time.Millisecond)
+	<-timer.C()
+	if Real.Since(start) < time.Millisecond {
+		t.Error("timer fired before it was due")
+	}
+
+	ticker := Real.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+}
@@ -2,10 +2,8 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"strings"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
 // Config represents the main configuration
@@ -13,8 +11,12 @@ type Config struct {
 	Inputs       InputsConfig       `yaml:"inputs"`
 	Logging      LoggingConfig      `yaml:"logging"`
 	Output       OutputConfig       `yaml:"output"`
+	Ordered      bool               `yaml:"ordered,omitempty"` // deliver in buffer order, one send and batch flush at a time
 	Parser       *ParserConfig      `yaml:"parser,omitempty"`
 	Transforms   []TransformConfig  `yaml:"transforms,omitempty"`
+	Enrichment   *EnrichmentConfig  `yaml:"enrichment,omitempty"`
+	Dedup        *DedupConfig       `yaml:"dedup,omitempty"`
+	TimestampSkew *TimestampSkewConfig `yaml:"timestamp_skew,omitempty"`
 	Buffer       *BufferConfig      `yaml:"buffer,omitempty"`
 	WAL          *WALConfig         `yaml:"wal,omitempty"`
 	WorkerPool   *WorkerPoolConfig  `yaml:"worker_pool,omitempty"`
@@ -25,6 +27,7 @@ type Config struct {
 	Tracing      *TracingConfig     `yaml:"tracing,omitempty"`
 	Profiling    *ProfilingConfig   `yaml:"profiling,omitempty"`
 	Performance  *PerformanceConfig `yaml:"performance,omitempty"`
+	Shutdown     *ShutdownConfig    `yaml:"shutdown,omitempty"`
 }
 
 // InputsConfig defines input sources
@@ -33,17 +36,57 @@ type InputsConfig struct {
 	Syslog     []SyslogInputConfig     `yaml:"syslog,omitempty"`
 	HTTP       []HTTPInputConfig       `yaml:"http,omitempty"`
 	Kubernetes []KubernetesInputConfig `yaml:"kubernetes,omitempty"`
+	GRPC       []GRPCInputConfig       `yaml:"grpc,omitempty"`
+	OTLP       []OTLPInputConfig       `yaml:"otlp,omitempty"`
+
+	// Events of every input larger than MaxEventBytes are truncated (and
+	// marked truncated: true) or dropped, per OversizePolicy
+	MaxEventBytes  int    `yaml:"max_event_bytes,omitempty"`
+	OversizePolicy string `yaml:"oversize_policy,omitempty"` // truncate (default), drop
 }
 
 // FileInputConfig defines file input configuration
 type FileInputConfig struct {
 	Paths              []string          `yaml:"paths"`
+	ExcludePatterns    []string          `yaml:"exclude_patterns,omitempty"`
 	CheckpointPath     string            `yaml:"checkpoint_path"`
 	CheckpointInterval time.Duration     `yaml:"checkpoint_interval"`
+	Checkpoint         *CheckpointConfig `yaml:"checkpoint,omitempty"`    // Where checkpoints are stored
+	ResetToEnd         bool              `yaml:"reset_to_end,omitempty"`  // Resume reset files from the end
+	MaxLineBytes       int               `yaml:"max_line_bytes,omitempty"` // Lines are cut as read beyond this (max_event_bytes by default)
+	AdminAddress       string            `yaml:"admin_address,omitempty"` // Address of the checkpoint admin endpoint
 	Parser             *ParserConfig     `yaml:"parser,omitempty"`
 	Transforms         []TransformConfig `yaml:"transforms,omitempty"`
 }
 
+// CheckpointConfig selects the store holding an input's checkpoints. The
+// file store writes to the input's checkpoint_path; the redis store keeps
+// them in a hash shared by every replica using the same key.
+type CheckpointConfig struct {
+	Type     string `yaml:"type"` // file (default) or redis
+	Address  string `yaml:"address,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	DB       int    `yaml:"db,omitempty"`
+	Key      string `yaml:"key,omitempty"` // Redis hash, defaults to logaggregator:checkpoints
+}
+
+// validate checks the store type and its required settings
+func (c *CheckpointConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Type {
+	case "", "file":
+	case "redis":
+		if c.Address == "" {
+			return fmt.Errorf("redis checkpoint store has no address configured")
+		}
+	default:
+		return fmt.Errorf("unknown checkpoint store type: %s", c.Type)
+	}
+	return nil
+}
+
 // ParserConfig holds parser configuration
 type ParserConfig struct {
 	Type         string            `yaml:"type"`
@@ -55,6 +98,12 @@ type ParserConfig struct {
 	MessageField string            `yaml:"message_field,omitempty"`
 	Multiline    *MultilineConfig  `yaml:"multiline,omitempty"`
 	CustomFields map[string]string `yaml:"custom_fields,omitempty"`
+
+	// JSON field handling: strict mode keeps only the allowed fields, and
+	// documents nested deeper than the limit are rejected
+	StrictFields     bool     `yaml:"strict_fields,omitempty"`
+	AllowedFields    []string `yaml:"allowed_fields,omitempty"`
+	NestedDepthLimit int      `yaml:"nested_depth_limit,omitempty"`
 }
 
 // MultilineConfig holds configuration for multi-line log handling
@@ -68,27 +117,82 @@ type MultilineConfig struct {
 
 // TransformConfig holds transformation configuration
 type TransformConfig struct {
-	Type          string            `yaml:"type"`
-	Fields        []string          `yaml:"fields,omitempty"`
-	IncludeFields []string          `yaml:"include_fields,omitempty"`
-	ExcludeFields []string          `yaml:"exclude_fields,omitempty"`
-	Rename        map[string]string `yaml:"rename,omitempty"`
-	Add           map[string]string `yaml:"add,omitempty"`
-	Patterns      []string          `yaml:"patterns,omitempty"`
-	FieldSplit    string            `yaml:"field_split,omitempty"`
-	ValueSplit    string            `yaml:"value_split,omitempty"`
-	Prefix        string            `yaml:"prefix,omitempty"`
+	Type           string             `yaml:"type"`
+	Fields         []string           `yaml:"fields,omitempty"`
+	IncludeFields  []string           `yaml:"include_fields,omitempty"`
+	ExcludeFields  []string           `yaml:"exclude_fields,omitempty"`
+	Rename         map[string]string  `yaml:"rename,omitempty"`
+	Add            map[string]string  `yaml:"add,omitempty"`
+	Patterns       []string           `yaml:"patterns,omitempty"`
+	FieldSplit     string             `yaml:"field_split,omitempty"`
+	ValueSplit     string             `yaml:"value_split,omitempty"`
+	Prefix         string             `yaml:"prefix,omitempty"`
+	Mask           string             `yaml:"mask,omitempty"`
+	Hash           bool               `yaml:"hash,omitempty"`
+	RedactMessage  bool               `yaml:"redact_message,omitempty"`
+	Separator      string             `yaml:"separator,omitempty"`
+	MaxDepth       int                `yaml:"max_depth,omitempty"`
+	KeepOriginal   bool               `yaml:"keep_original,omitempty"`
+	Rate           float64            `yaml:"rate,omitempty"`
+	KeepOneIn      int                `yaml:"keep_one_in,omitempty"`
+	KeyField       string             `yaml:"key_field,omitempty"`
+	LevelOverrides map[string]float64 `yaml:"level_overrides,omitempty"`
+	Field          string             `yaml:"field,omitempty"`
+	Operator       string             `yaml:"operator,omitempty"`
+	Value          string             `yaml:"value,omitempty"`
+	Hostname       bool               `yaml:"hostname,omitempty"`
+	PID            bool               `yaml:"pid,omitempty"`
+	TTL            time.Duration      `yaml:"ttl,omitempty"`
+	MaxEntries     int                `yaml:"max_entries,omitempty"`
+}
+
+// EnrichmentConfig holds fields added to every event from every input,
+// before the input's own transforms. Fields an event already has are kept.
+type EnrichmentConfig struct {
+	Fields   map[string]string `yaml:"fields,omitempty"`
+	Hostname bool              `yaml:"hostname,omitempty"` // add the host name as "hostname"
+	PID      bool              `yaml:"pid,omitempty"`      // add the process ID as "pid"
+}
+
+// DedupConfig holds the deduplication applied to the events of all inputs
+// after their transforms. Events with the same content hash seen within the
+// TTL are dropped.
+type DedupConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Fields     []string      `yaml:"fields,omitempty"`      // fields to hash, the whole event if empty
+	TTL        time.Duration `yaml:"ttl,omitempty"`         // default 5m
+	MaxEntries int           `yaml:"max_entries,omitempty"` // default 100000
+}
+
+// TimestampSkewConfig flags events whose timestamp is further than MaxSkew
+// from the wall clock, as sent by sources with wrong clocks, with a
+// timestamp_skew field. Flagged events are kept, clamped to the current time
+// (keeping the original as original_timestamp) or written to the dead letter
+// queue, per Policy.
+type TimestampSkewConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	MaxSkew time.Duration `yaml:"max_skew,omitempty"` // default 1h
+	Policy  string        `yaml:"policy,omitempty"`   // flag (default), clamp, dead_letter
 }
 
 // LoggingConfig defines logging configuration
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"` // json or console
+
+	// Sampling of repeated messages; all messages are logged when unset
+	Sampling *LogSamplingConfig `yaml:"sampling,omitempty"`
+}
+
+// LogSamplingConfig limits how often the same message is logged
+type LogSamplingConfig struct {
+	Burst    int           `yaml:"burst"`              // messages with the same text logged per interval
+	Interval time.Duration `yaml:"interval,omitempty"` // 1s by default
 }
 
 // OutputConfig defines output configuration
 type OutputConfig struct {
-	Type string `yaml:"type"` // stdout, file, kafka, elasticsearch, s3, multi
+	Type string `yaml:"type"` // stdout, file, console, kafka, elasticsearch, s3, gcs, http, loki, gelf, multi
 	Path string `yaml:"path,omitempty"`
 
 	// Kafka output configuration
@@ -100,6 +204,24 @@ type OutputConfig struct {
 	// S3 output configuration
 	S3 *S3OutputConfig `yaml:"s3,omitempty"`
 
+	// Google Cloud Storage output configuration
+	GCS *GCSOutputConfig `yaml:"gcs,omitempty"`
+
+	// HTTP output configuration
+	HTTP *HTTPOutputConfig `yaml:"http,omitempty"`
+
+	// Loki output configuration
+	Loki *LokiOutputConfig `yaml:"loki,omitempty"`
+
+	// Console output configuration
+	Console *ConsoleOutputConfig `yaml:"console,omitempty"`
+
+	// Stdout output configuration
+	Stdout *StdoutOutputConfig `yaml:"stdout,omitempty"`
+
+	// GELF (Graylog) output configuration
+	GELF *GELFOutputConfig `yaml:"gelf,omitempty"`
+
 	// Multi-output configuration
 	Multi *MultiOutputConfig `yaml:"multi,omitempty"`
 }
@@ -112,6 +234,8 @@ type KafkaOutputConfig struct {
 	PartitionKey      string        `yaml:"partition_key,omitempty"`
 	PartitionStrategy string        `yaml:"partition_strategy,omitempty"`
 	RequiredAcks      int16         `yaml:"required_acks,omitempty"`
+	IdempotentWrites  bool          `yaml:"idempotent_writes,omitempty"`
+	OrderedByKey      bool          `yaml:"ordered_by_key,omitempty"` // Per-key ordering at lower throughput
 	CompressionCodec  string        `yaml:"compression_codec,omitempty"`
 	MaxMessageBytes   int           `yaml:"max_message_bytes,omitempty"`
 	BatchSize         int           `yaml:"batch_size,omitempty"`
@@ -122,6 +246,17 @@ type KafkaOutputConfig struct {
 	SASLUsername      string        `yaml:"sasl_username,omitempty"`
 	SASLPassword      string        `yaml:"sasl_password,omitempty"`
 	EnableTLS         bool          `yaml:"enable_tls,omitempty"`
+
+	// Serialization of message values (json, msgpack, avro)
+	Serialization *SerializationConfig `yaml:"serialization,omitempty"`
+
+	// Message headers: event fields to promote and fixed values
+	Headers       []string          `yaml:"headers,omitempty"`
+	StaticHeaders map[string]string `yaml:"static_headers,omitempty"`
+
+	TLSClientConfig `yaml:",inline"`
+
+	BatchOutputConfig `yaml:",inline"`
 }
 
 // ElasticsearchOutputConfig holds Elasticsearch-specific configuration
@@ -140,6 +275,17 @@ type ElasticsearchOutputConfig struct {
 	FlushInterval       time.Duration `yaml:"flush_interval,omitempty"`
 	BulkWorkers         int           `yaml:"bulk_workers,omitempty"`
 	MaxRetries          int           `yaml:"max_retries,omitempty"`
+	UseDataStream       bool          `yaml:"use_data_stream,omitempty"`
+	IDField             string        `yaml:"id_field,omitempty"`
+	IDTemplate          string        `yaml:"id_template,omitempty"`
+	EnableTLS           bool          `yaml:"enable_tls,omitempty"`
+
+	TLSClientConfig `yaml:",inline"`
+
+	BatchOutputConfig `yaml:",inline"`
+
+	// Field types checked before indexing, to avoid mapping conflicts
+	FieldContract *FieldContractConfig `yaml:"field_contract,omitempty"`
 }
 
 // S3OutputConfig holds S3-specific configuration
@@ -148,15 +294,199 @@ type S3OutputConfig struct {
 	Region               string        `yaml:"region"`
 	Prefix               string        `yaml:"prefix,omitempty"`
 	KeyTemplate          string        `yaml:"key_template,omitempty"`
+	SplitByKey           bool          `yaml:"split_by_key,omitempty"`
+	UploadConcurrency    int           `yaml:"upload_concurrency,omitempty"`
 	StorageClass         string        `yaml:"storage_class,omitempty"`
 	ServerSideEncryption string        `yaml:"server_side_encryption,omitempty"`
 	ACL                  string        `yaml:"acl,omitempty"`
-	Compression          string        `yaml:"compression,omitempty"`
+	Compression          string        `yaml:"compression,omitempty"` // none, gzip, snappy, lz4, zstd
 	BatchSize            int           `yaml:"batch_size,omitempty"`
 	BatchTimeout         time.Duration `yaml:"batch_timeout,omitempty"`
 	FlushInterval        time.Duration `yaml:"flush_interval,omitempty"`
 	Endpoint             string        `yaml:"endpoint,omitempty"`
 	UsePathStyle         bool          `yaml:"use_path_style,omitempty"`
+
+	// Serialization of uploaded events (json, msgpack, avro)
+	Serialization *SerializationConfig `yaml:"serialization,omitempty"`
+
+	BatchOutputConfig `yaml:",inline"`
+}
+
+// GCSOutputConfig holds Google Cloud Storage output configuration
+type GCSOutputConfig struct {
+	Bucket            string        `yaml:"bucket"`
+	Prefix            string        `yaml:"prefix,omitempty"`
+	KeyTemplate       string        `yaml:"key_template,omitempty"`
+	SplitByKey        bool          `yaml:"split_by_key,omitempty"`
+	UploadConcurrency int           `yaml:"upload_concurrency,omitempty"`
+	StorageClass      string        `yaml:"storage_class,omitempty"`    // STANDARD, NEARLINE, COLDLINE, ARCHIVE
+	CredentialsFile   string        `yaml:"credentials_file,omitempty"` // service account JSON key; GOOGLE_APPLICATION_CREDENTIALS by default
+	CredentialsJSON   string        `yaml:"credentials_json,omitempty"`
+	Compression       string        `yaml:"compression,omitempty"` // none, gzip, snappy, lz4, zstd
+	BatchSize         int           `yaml:"batch_size,omitempty"`
+	BatchTimeout      time.Duration `yaml:"batch_timeout,omitempty"`
+	FlushInterval     time.Duration `yaml:"flush_interval,omitempty"`
+	Endpoint          string        `yaml:"endpoint,omitempty"` // GCS emulator
+
+	// Serialization of uploaded events (json, msgpack, avro)
+	Serialization *SerializationConfig `yaml:"serialization,omitempty"`
+
+	BatchOutputConfig `yaml:",inline"`
+}
+
+// HTTPOutputConfig holds HTTP/webhook output configuration
+type HTTPOutputConfig struct {
+	URL            string            `yaml:"url"`
+	Method         string            `yaml:"method,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	BearerToken    string            `yaml:"bearer_token,omitempty"`
+	Username       string            `yaml:"username,omitempty"`
+	Password       string            `yaml:"password,omitempty"`
+	BodyTemplate   string            `yaml:"body_template,omitempty"` // text/template rendering each event
+	ContentType    string            `yaml:"content_type,omitempty"`
+	HealthCheckURL string            `yaml:"health_check_url,omitempty"`
+	Compression    string            `yaml:"compression,omitempty"` // none, gzip, snappy, lz4, zstd
+	Timeout        time.Duration     `yaml:"timeout,omitempty"`
+	MaxRetries     int               `yaml:"max_retries,omitempty"`
+	RetryBackoff   time.Duration     `yaml:"retry_backoff,omitempty"`
+	BatchSize      int               `yaml:"batch_size,omitempty"`
+	FlushInterval  time.Duration     `yaml:"flush_interval,omitempty"`
+
+	// Circuit breaker opened by consecutive failed sends
+	CircuitBreaker *OutputCircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+
+	// Serialization of request bodies without a template (json, msgpack, avro)
+	Serialization *SerializationConfig `yaml:"serialization,omitempty"`
+
+	TLSClientConfig `yaml:",inline"`
+
+	BatchOutputConfig `yaml:",inline"`
+}
+
+// LokiOutputConfig holds Grafana Loki output configuration
+type LokiOutputConfig struct {
+	URL           string            `yaml:"url"`
+	TenantID      string            `yaml:"tenant_id,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	LabelFields   []string          `yaml:"label_fields,omitempty"` // Event fields that become stream labels
+	Encoding      string            `yaml:"encoding,omitempty"`     // protobuf, json
+	LineFormat    string            `yaml:"line_format,omitempty"`  // json, message
+	Headers       map[string]string `yaml:"headers,omitempty"`
+	BearerToken   string            `yaml:"bearer_token,omitempty"`
+	Username      string            `yaml:"username,omitempty"`
+	Password      string            `yaml:"password,omitempty"`
+	Timeout       time.Duration     `yaml:"timeout,omitempty"`
+	MaxRetries    int               `yaml:"max_retries,omitempty"`
+	RetryBackoff  time.Duration     `yaml:"retry_backoff,omitempty"`
+	BatchSize     int               `yaml:"batch_size,omitempty"`
+	FlushInterval time.Duration     `yaml:"flush_interval,omitempty"`
+
+	// Circuit breaker opened by consecutive failed pushes
+	CircuitBreaker *OutputCircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+
+	TLSClientConfig `yaml:",inline"`
+
+	BatchOutputConfig `yaml:",inline"`
+}
+
+// ConsoleOutputConfig holds human-readable console output configuration
+type ConsoleOutputConfig struct {
+	NoColor    bool     `yaml:"no_color,omitempty"`
+	Fields     []string `yaml:"fields,omitempty"` // Fields shown after the message, in order
+	TimeFormat string   `yaml:"time_format,omitempty"`
+
+	RateLimitConfig `yaml:",inline"`
+}
+
+// StdoutOutputConfig holds stdout output configuration. Events are written
+// as JSON lines; a batch size above one writes each batch at once.
+type StdoutOutputConfig struct {
+	BatchSize     int           `yaml:"batch_size,omitempty"`
+	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
+}
+
+// GELFOutputConfig holds GELF (Graylog) output configuration
+type GELFOutputConfig struct {
+	Address     string        `yaml:"address"`
+	Transport   string        `yaml:"transport,omitempty"`   // udp, tcp
+	Host        string        `yaml:"host,omitempty"`        // host of events without one; the host name by default
+	Compression string        `yaml:"compression,omitempty"` // none, gzip, zlib (udp only)
+	ChunkSize   int           `yaml:"chunk_size,omitempty"`  // largest UDP datagram; 1420 by default
+	Timeout     time.Duration `yaml:"timeout,omitempty"`
+
+	// Renames of the GELF message fields
+	Schema *SchemaConfig `yaml:"schema,omitempty"`
+
+	RateLimitConfig `yaml:",inline"`
+}
+
+// TLSClientConfig holds an output's TLS client settings; certificates and
+// keys are PEM file paths
+type TLSClientConfig struct {
+	TLSCACert             string `yaml:"tls_ca_cert,omitempty"`
+	TLSClientCert         string `yaml:"tls_client_cert,omitempty"`
+	TLSClientKey          string `yaml:"tls_client_key,omitempty"`
+	TLSInsecureSkipVerify bool   `yaml:"tls_insecure_skip_verify,omitempty"`
+}
+
+// BatchOutputConfig holds the settings shared by the outputs that batch
+// events for a remote destination
+type BatchOutputConfig struct {
+	// Adaptive batch sizing bounds
+	AdaptiveBatch *AdaptiveBatchConfig `yaml:"adaptive_batch,omitempty"`
+
+	// Field names of encoded events: a target schema (ecs, gelf) and renames
+	Schema *SchemaConfig `yaml:"schema,omitempty"`
+
+	// Batches sent at once; further sends wait for one to finish
+	MaxConcurrentBatches int `yaml:"max_concurrent_batches,omitempty"`
+
+	RateLimitConfig `yaml:",inline"`
+}
+
+// RateLimitConfig caps an output's throughput; over the limits sends wait
+// or fail, per the policy
+type RateLimitConfig struct {
+	MaxEventsPerSec float64 `yaml:"max_events_per_sec,omitempty"`
+	MaxBytesPerSec  int     `yaml:"max_bytes_per_sec,omitempty"`
+	RateLimitPolicy string  `yaml:"rate_limit_policy,omitempty"` // block, reject
+}
+
+// OutputCircuitBreakerConfig configures an output's circuit breaker
+type OutputCircuitBreakerConfig struct {
+	FailureThreshold uint32        `yaml:"failure_threshold"` // 0 disables the breaker
+	Timeout          time.Duration `yaml:"timeout,omitempty"`
+}
+
+// AdaptiveBatchConfig grows an output's batch size while sends are fast
+// and shrinks it when latency or failures rise
+type AdaptiveBatchConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MinBatchSize  int           `yaml:"min_batch_size,omitempty"`
+	MaxBatchSize  int           `yaml:"max_batch_size,omitempty"` // 10x batch_size by default
+	TargetLatency time.Duration `yaml:"target_latency,omitempty"` // 100ms by default
+	MaxErrorRate  float64       `yaml:"max_error_rate,omitempty"` // 0.05 by default
+}
+
+// SerializationConfig selects how an output encodes events
+type SerializationConfig struct {
+	Format     string `yaml:"format"`                // json, msgpack, avro
+	AvroSchema string `yaml:"avro_schema,omitempty"` // Avro record schema (JSON); a LogEvent schema by default
+}
+
+// SchemaConfig renames the canonical event fields (timestamp, message,
+// level, source, fields, raw) when an output encodes events
+type SchemaConfig struct {
+	Name   string            `yaml:"name,omitempty"`   // ecs, gelf
+	Rename map[string]string `yaml:"rename,omitempty"` // canonical field -> output name, overriding the schema
+}
+
+// FieldContractConfig declares the Elasticsearch types of event fields.
+// Fields that cannot be coerced, or whose type conflicts with the rest of
+// the batch, are renamed after their type or quarantined.
+type FieldContractConfig struct {
+	Types      map[string]string `yaml:"types,omitempty"`       // field -> keyword, long, double, boolean, object
+	OnConflict string            `yaml:"on_conflict,omitempty"` // suffix (default), quarantine
 }
 
 // MultiOutputConfig holds configuration for multiple outputs
@@ -164,15 +494,27 @@ type MultiOutputConfig struct {
 	Outputs         []OutputDefinition `yaml:"outputs"`
 	FailureStrategy string             `yaml:"failure_strategy,omitempty"`
 	Parallel        bool               `yaml:"parallel,omitempty"`
+
+	// Retries of sends failing with transient errors, on top of the
+	// outputs' own retries
+	MaxRetries   int           `yaml:"max_retries,omitempty"`
+	RetryBackoff time.Duration `yaml:"retry_backoff,omitempty"`
 }
 
 // OutputDefinition defines a single output in multi-output mode
 type OutputDefinition struct {
 	Name          string                      `yaml:"name"`
 	Type          string                      `yaml:"type"`
+	Path          string                      `yaml:"path,omitempty"` // file outputs
 	Kafka         *KafkaOutputConfig         `yaml:"kafka,omitempty"`
 	Elasticsearch *ElasticsearchOutputConfig `yaml:"elasticsearch,omitempty"`
 	S3            *S3OutputConfig            `yaml:"s3,omitempty"`
+	GCS           *GCSOutputConfig           `yaml:"gcs,omitempty"`
+	HTTP          *HTTPOutputConfig          `yaml:"http,omitempty"`
+	Loki          *LokiOutputConfig          `yaml:"loki,omitempty"`
+	Console       *ConsoleOutputConfig       `yaml:"console,omitempty"`
+	Stdout        *StdoutOutputConfig        `yaml:"stdout,omitempty"`
+	GELF          *GELFOutputConfig          `yaml:"gelf,omitempty"`
 }
 
 // BufferConfig holds buffer configuration
@@ -192,14 +534,45 @@ type WALConfig struct {
 	MaxSegments      int           `yaml:"max_segments,omitempty"`
 	SyncInterval     time.Duration `yaml:"sync_interval,omitempty"`
 	CompactionPolicy string        `yaml:"compaction_policy,omitempty"`
+	SegmentMaxAge    time.Duration `yaml:"segment_max_age,omitempty"`
+
+	// CommitInterval is how often the events delivered to the outputs are
+	// committed, so only later ones are replayed after a restart
+	CommitInterval time.Duration `yaml:"commit_interval,omitempty"`
+
+	// MmapReads reads sealed segments through a memory mapping, which
+	// speeds up replaying large WALs
+	MmapReads bool `yaml:"mmap_reads,omitempty"`
+
+	// GroupCommit writes entries through a single writer goroutine that
+	// syncs them in groups, so each write is durable once it returns
+	GroupCommit bool `yaml:"group_commit,omitempty"`
 }
 
 // WorkerPoolConfig holds worker pool configuration
 type WorkerPoolConfig struct {
-	NumWorkers     int           `yaml:"num_workers"`
-	QueueSize      int           `yaml:"queue_size,omitempty"`
-	JobTimeout     time.Duration `yaml:"job_timeout,omitempty"`
-	EnableStealing bool          `yaml:"enable_stealing,omitempty"`
+	NumWorkers      int           `yaml:"num_workers"`
+	QueueSize       int           `yaml:"queue_size,omitempty"`
+	JobTimeout      time.Duration `yaml:"job_timeout,omitempty"`
+	EnableStealing  bool          `yaml:"enable_stealing,omitempty"`
+	MaxRetries      int           `yaml:"max_retries,omitempty"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff,omitempty"`
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff,omitempty"`
+
+	// AutoScale scales the workers with the queue depth
+	AutoScale *AutoScaleConfig `yaml:"auto_scale,omitempty"`
+}
+
+// AutoScaleConfig scales a worker pool between MinWorkers and MaxWorkers to
+// keep its queue depth under TargetQueueDepth, sampling every Interval and
+// scaling at most once per Cooldown
+type AutoScaleConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	MinWorkers       int           `yaml:"min_workers,omitempty"`
+	MaxWorkers       int           `yaml:"max_workers,omitempty"`
+	TargetQueueDepth int           `yaml:"target_queue_depth,omitempty"`
+	Interval         time.Duration `yaml:"interval,omitempty"`
+	Cooldown         time.Duration `yaml:"cooldown,omitempty"`
 }
 
 // ReliabilityConfig holds retry and circuit breaker configuration
@@ -220,6 +593,7 @@ type RetryConfig struct {
 // CircuitBreakerConfig holds circuit breaker configuration
 type CircuitBreakerConfig struct {
 	MaxRequests        uint32        `yaml:"max_requests,omitempty"`
+	HalfOpenMaxProbes  uint32        `yaml:"half_open_max_probes,omitempty"`
 	Interval           time.Duration `yaml:"interval,omitempty"`
 	Timeout            time.Duration `yaml:"timeout,omitempty"`
 	FailureThreshold   uint32        `yaml:"failure_threshold,omitempty"`
@@ -234,6 +608,12 @@ type DeadLetterConfig struct {
 	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
 }
 
+// ShutdownConfig holds graceful shutdown configuration
+type ShutdownConfig struct {
+	Timeout      time.Duration `yaml:"timeout,omitempty"`       // overall shutdown deadline
+	StageTimeout time.Duration `yaml:"stage_timeout,omitempty"` // deadline for each shutdown stage
+}
+
 // MetricsConfig holds metrics configuration
 type MetricsConfig struct {
 	Enabled    bool                      `yaml:"enabled"`
@@ -258,6 +638,10 @@ type MetricExtractionRule struct {
 	LabelFields map[string]string `yaml:"label_fields,omitempty"`
 	Help        string            `yaml:"help"`
 	Buckets     []float64         `yaml:"buckets,omitempty"`
+
+	// Distinct label_fields combinations kept; further ones are recorded
+	// with the value __overflow__. Zero is unlimited.
+	MaxCardinality int `yaml:"max_cardinality,omitempty"`
 }
 
 // HealthConfig holds health check configuration
@@ -267,6 +651,17 @@ type HealthConfig struct {
 	LivenessPath string        `yaml:"liveness_path,omitempty"`
 	ReadinessPath string       `yaml:"readiness_path,omitempty"`
 	Timeout      time.Duration `yaml:"timeout,omitempty"`
+
+	// Debug serves /debug/state on the health address: the live buffer,
+	// output, circuit breaker and WAL metrics and the redacted configuration
+	Debug bool `yaml:"debug,omitempty"`
+
+	// Readiness degrades while the buffer stays above its high-water mark
+	// and fails while the dead letter queue grows faster than it drains
+	BufferHighWaterMark float64       `yaml:"buffer_high_water_mark,omitempty"` // percent, 80 by default
+	BufferSustain       time.Duration `yaml:"buffer_sustain,omitempty"`         // 30s by default
+	DLQGrowthWindow     time.Duration `yaml:"dlq_growth_window,omitempty"`      // 1m by default
+	DLQMaxGrowthRate    float64       `yaml:"dlq_max_growth_rate,omitempty"`    // bytes per second tolerated
 }
 
 // TracingConfig holds tracing configuration
@@ -286,6 +681,9 @@ type ProfilingConfig struct {
 	BlockProfile       bool   `yaml:"block_profile"`
 	MutexProfile       bool   `yaml:"mutex_profile"`
 	GoroutineThreshold int    `yaml:"goroutine_threshold"`
+
+	// GoroutineCheckInterval is how often the goroutine count is checked
+	GoroutineCheckInterval time.Duration `yaml:"goroutine_check_interval,omitempty"`
 }
 
 // PerformanceConfig holds performance tuning configuration
@@ -295,6 +693,21 @@ type PerformanceConfig struct {
 	GCPercent          int  `yaml:"gc_percent"`
 	ChannelBufferSize  int  `yaml:"channel_buffer_size"`
 	MaxConcurrentReads int  `yaml:"max_concurrent_reads"`
+
+	// MemoryPressure flushes the output batchers while the heap is large
+	MemoryPressure *MemoryPressureConfig `yaml:"memory_pressure,omitempty"`
+}
+
+// MemoryPressureConfig configures a watchdog that checks the heap in use
+// (runtime.MemStats.HeapAlloc) every CheckInterval and, over HeapThreshold
+// bytes, flushes every output batcher instead of holding batches. With
+// DropFor set the ring buffer also drops its oldest events rather than
+// blocking for that long after the last check over the threshold.
+type MemoryPressureConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	HeapThreshold uint64        `yaml:"heap_threshold"`           // bytes
+	CheckInterval time.Duration `yaml:"check_interval,omitempty"` // default 5s
+	DropFor       time.Duration `yaml:"drop_for,omitempty"`       // 0 keeps the buffer's strategy
 }
 
 // Default values
@@ -305,30 +718,10 @@ const (
 	DefaultLogFormat          = "json"
 )
 
-// Load loads configuration from a YAML file with environment variable overrides
+// Load loads configuration from a YAML file with environment variable
+// overrides, merging any files it includes (see LoadLayered)
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	// Expand environment variables in the YAML content
-	expandedData := []byte(os.ExpandEnv(string(data)))
-
-	var cfg Config
-	if err := yaml.Unmarshal(expandedData, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	// Apply defaults
-	cfg.applyDefaults()
-
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
-
-	return &cfg, nil
+	return LoadLayered(path)
 }
 
 // applyDefaults sets default values for unspecified configuration
@@ -351,21 +744,47 @@ func (c *Config) applyDefaults() {
 			c.Inputs.Files[i].CheckpointInterval = DefaultCheckpointInterval
 		}
 	}
+
+	for i := range c.Inputs.Kubernetes {
+		if c.Inputs.Kubernetes[i].ParseTimestamps == nil {
+			parse := true
+			c.Inputs.Kubernetes[i].ParseTimestamps = &parse
+		}
+		// Kubernetes offsets are only persisted when a checkpoint path or a
+		// redis checkpoint store is set
+		k8sInput := &c.Inputs.Kubernetes[i]
+		persisted := k8sInput.CheckpointPath != "" || (k8sInput.Checkpoint != nil && k8sInput.Checkpoint.Type == "redis")
+		if persisted && k8sInput.CheckpointInterval == 0 {
+			k8sInput.CheckpointInterval = DefaultCheckpointInterval
+		}
+	}
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// Check that at least one input is configured
-	totalInputs := len(c.Inputs.Files) + len(c.Inputs.Syslog) + len(c.Inputs.HTTP) + len(c.Inputs.Kubernetes)
+	totalInputs := len(c.Inputs.Files) + len(c.Inputs.Syslog) + len(c.Inputs.HTTP) + len(c.Inputs.Kubernetes) + len(c.Inputs.GRPC) + len(c.Inputs.OTLP)
 	if totalInputs == 0 {
 		return fmt.Errorf("at least one input must be configured")
 	}
 
+	if c.Inputs.MaxEventBytes < 0 {
+		return fmt.Errorf("inputs max_event_bytes must not be negative")
+	}
+	switch c.Inputs.OversizePolicy {
+	case "", "truncate", "drop":
+	default:
+		return fmt.Errorf("unsupported inputs oversize_policy %q: must be truncate or drop", c.Inputs.OversizePolicy)
+	}
+
 	// Validate file inputs
 	for i, fileInput := range c.Inputs.Files {
 		if len(fileInput.Paths) == 0 {
 			return fmt.Errorf("file input %d has no paths configured", i)
 		}
+		if err := fileInput.Checkpoint.validate(); err != nil {
+			return fmt.Errorf("file input %d: %w", i, err)
+		}
 	}
 
 	// Validate syslog inputs
@@ -386,6 +805,9 @@ func (c *Config) Validate() error {
 		if httpInput.Address == "" {
 			return fmt.Errorf("HTTP input %d has no address configured", i)
 		}
+		if httpInput.Validation != nil && httpInput.Validation.SchemaPath == "" {
+			return fmt.Errorf("HTTP input %d has validation enabled but no schema_path configured", i)
+		}
 	}
 
 	// Validate Kubernetes inputs
@@ -393,6 +815,38 @@ func (c *Config) Validate() error {
 		if k8sInput.Name == "" {
 			return fmt.Errorf("Kubernetes input %d has no name configured", i)
 		}
+		if err := k8sInput.Checkpoint.validate(); err != nil {
+			return fmt.Errorf("Kubernetes input %d: %w", i, err)
+		}
+	}
+
+	// Validate gRPC inputs
+	for i, grpcInput := range c.Inputs.GRPC {
+		if grpcInput.Name == "" {
+			return fmt.Errorf("gRPC input %d has no name configured", i)
+		}
+		if grpcInput.Address == "" {
+			return fmt.Errorf("gRPC input %d has no address configured", i)
+		}
+		if grpcInput.TLSEnabled && (grpcInput.TLSCert == "" || grpcInput.TLSKey == "") {
+			return fmt.Errorf("gRPC input %d has TLS enabled without a certificate and key", i)
+		}
+	}
+
+	// Validate OTLP inputs
+	for i, otlpInput := range c.Inputs.OTLP {
+		if otlpInput.Name == "" {
+			return fmt.Errorf("OTLP input %d has no name configured", i)
+		}
+		if otlpInput.Address == "" {
+			return fmt.Errorf("OTLP input %d has no address configured", i)
+		}
+		if otlpInput.Path != "" && !strings.HasPrefix(otlpInput.Path, "/") {
+			return fmt.Errorf("OTLP input %d path must start with '/': %s", i, otlpInput.Path)
+		}
+		if otlpInput.TLSEnabled && (otlpInput.TLSCert == "" || otlpInput.TLSKey == "") {
+			return fmt.Errorf("OTLP input %d has TLS enabled without a certificate and key", i)
+		}
 	}
 
 	validLogLevels := map[string]bool{
@@ -409,6 +863,47 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log format: %s", c.Logging.Format)
 	}
 
+	if skew := c.TimestampSkew; skew != nil && skew.Enabled {
+		if skew.MaxSkew < 0 {
+			return fmt.Errorf("timestamp_skew max_skew must not be negative")
+		}
+		switch skew.Policy {
+		case "", "flag", "clamp":
+		case "dead_letter":
+			if c.DeadLetter == nil || !c.DeadLetter.Enabled {
+				return fmt.Errorf("timestamp_skew policy dead_letter requires the dead letter queue to be enabled")
+			}
+		default:
+			return fmt.Errorf("unsupported timestamp_skew policy %q: must be flag, clamp or dead_letter", skew.Policy)
+		}
+	}
+
+	if c.Performance != nil && c.Performance.MemoryPressure != nil && c.Performance.MemoryPressure.Enabled {
+		pressure := c.Performance.MemoryPressure
+		if pressure.HeapThreshold == 0 {
+			return fmt.Errorf("performance memory_pressure requires a heap_threshold")
+		}
+		if pressure.CheckInterval < 0 || pressure.DropFor < 0 {
+			return fmt.Errorf("performance memory_pressure check_interval and drop_for must not be negative")
+		}
+	}
+
+	if c.Ordered {
+		if c.Output.Multi != nil && c.Output.Multi.Parallel {
+			return fmt.Errorf("ordered delivery cannot send to outputs in parallel: unset output.multi.parallel")
+		}
+		if wp := c.WorkerPool; wp != nil && (wp.NumWorkers > 1 || (wp.AutoScale != nil && wp.AutoScale.Enabled && wp.AutoScale.MaxWorkers > 1)) {
+			return fmt.Errorf("ordered delivery requires a single worker: set worker_pool num_workers and auto_scale max_workers to 1")
+		}
+	}
+
+	if c.WorkerPool != nil && c.WorkerPool.AutoScale != nil && c.WorkerPool.AutoScale.Enabled {
+		as := c.WorkerPool.AutoScale
+		if as.MaxWorkers > 0 && as.MinWorkers > as.MaxWorkers {
+			return fmt.Errorf("worker pool auto_scale min_workers %d is above max_workers %d", as.MinWorkers, as.MaxWorkers)
+		}
+	}
+
 	return nil
 }
 
@@ -434,6 +929,10 @@ type SyslogInputConfig struct {
 	BufferSize int           `yaml:"buffer_size,omitempty"`
 	Parser     *ParserConfig `yaml:"parser,omitempty"`
 	Transforms []TransformConfig `yaml:"transforms,omitempty"`
+
+	// Largest message read, the UDP datagram buffer and the longest TCP
+	// line (max_event_bytes, else 64KB, by default)
+	MaxMessageBytes int `yaml:"max_message_bytes,omitempty"`
 }
 
 // HTTPInputConfig defines HTTP input configuration
@@ -453,23 +952,66 @@ type HTTPInputConfig struct {
 	WriteTimeout time.Duration     `yaml:"write_timeout,omitempty"`
 	Parser       *ParserConfig     `yaml:"parser,omitempty"`
 	Transforms   []TransformConfig `yaml:"transforms,omitempty"`
+	Validation   *ValidationConfig `yaml:"validation,omitempty"`
+}
+
+// ValidationConfig defines JSON Schema validation of incoming events
+type ValidationConfig struct {
+	SchemaPath string `yaml:"schema_path"`
+}
+
+// GRPCInputConfig defines gRPC input configuration
+type GRPCInputConfig struct {
+	Name           string            `yaml:"name"`
+	Address        string            `yaml:"address"`
+	APIKeys        []string          `yaml:"api_keys,omitempty"`
+	RateLimit      int               `yaml:"rate_limit,omitempty"`
+	MaxRecvMsgSize int               `yaml:"max_recv_msg_size,omitempty"`
+	TLSEnabled     bool              `yaml:"tls_enabled,omitempty"`
+	TLSCert        string            `yaml:"tls_cert,omitempty"`
+	TLSKey         string            `yaml:"tls_key,omitempty"`
+	BufferSize     int               `yaml:"buffer_size,omitempty"`
+	Parser         *ParserConfig     `yaml:"parser,omitempty"`
+	Transforms     []TransformConfig `yaml:"transforms,omitempty"`
+}
+
+// OTLPInputConfig defines OTLP/HTTP logs input configuration
+type OTLPInputConfig struct {
+	Name         string            `yaml:"name"`
+	Address      string            `yaml:"address"`
+	Path         string            `yaml:"path,omitempty"` // Defaults to /v1/logs
+	APIKeys      []string          `yaml:"api_keys,omitempty"`
+	RateLimit    int               `yaml:"rate_limit,omitempty"`
+	MaxBodySize  int64             `yaml:"max_body_size,omitempty"`
+	TLSEnabled   bool              `yaml:"tls_enabled,omitempty"`
+	TLSCert      string            `yaml:"tls_cert,omitempty"`
+	TLSKey       string            `yaml:"tls_key,omitempty"`
+	BufferSize   int               `yaml:"buffer_size,omitempty"`
+	ReadTimeout  time.Duration     `yaml:"read_timeout,omitempty"`
+	WriteTimeout time.Duration     `yaml:"write_timeout,omitempty"`
+	Parser       *ParserConfig     `yaml:"parser,omitempty"`
+	Transforms   []TransformConfig `yaml:"transforms,omitempty"`
 }
 
 // KubernetesInputConfig defines Kubernetes input configuration
 type KubernetesInputConfig struct {
-	Name             string            `yaml:"name"`
-	Kubeconfig       string            `yaml:"kubeconfig,omitempty"`
-	Namespace        string            `yaml:"namespace,omitempty"`
-	LabelSelector    string            `yaml:"label_selector,omitempty"`
-	FieldSelector    string            `yaml:"field_selector,omitempty"`
-	ContainerPattern string            `yaml:"container_pattern,omitempty"`
-	Follow           bool              `yaml:"follow"`
-	IncludePrevious  bool              `yaml:"include_previous,omitempty"`
-	TailLines        int64             `yaml:"tail_lines,omitempty"`
-	EnrichMetadata   bool              `yaml:"enrich_metadata"`
-	BufferSize       int               `yaml:"buffer_size,omitempty"`
-	Parser           *ParserConfig     `yaml:"parser,omitempty"`
-	Transforms       []TransformConfig `yaml:"transforms,omitempty"`
+	Name               string            `yaml:"name"`
+	Kubeconfig         string            `yaml:"kubeconfig,omitempty"`
+	Namespace          string            `yaml:"namespace,omitempty"`
+	LabelSelector      string            `yaml:"label_selector,omitempty"`
+	FieldSelector      string            `yaml:"field_selector,omitempty"`
+	ContainerPattern   string            `yaml:"container_pattern,omitempty"`
+	Follow             bool              `yaml:"follow"`
+	IncludePrevious    bool              `yaml:"include_previous,omitempty"`
+	TailLines          int64             `yaml:"tail_lines,omitempty"`
+	EnrichMetadata     bool              `yaml:"enrich_metadata"`
+	BufferSize         int               `yaml:"buffer_size,omitempty"`
+	ParseTimestamps    *bool             `yaml:"parse_timestamps,omitempty"` // Defaults to true
+	CheckpointPath     string            `yaml:"checkpoint_path,omitempty"`
+	CheckpointInterval time.Duration     `yaml:"checkpoint_interval,omitempty"`
+	Checkpoint         *CheckpointConfig `yaml:"checkpoint,omitempty"`
+	Parser             *ParserConfig     `yaml:"parser,omitempty"`
+	Transforms         []TransformConfig `yaml:"transforms,omitempty"`
 }
 
 // DefaultConfig returns a default configuration
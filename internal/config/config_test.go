@@ -145,6 +145,82 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid OTLP input",
+			config: &Config{
+				Inputs: InputsConfig{
+					OTLP: []OTLPInputConfig{{Name: "otlp", Address: ":4318"}},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Output:  OutputConfig{Type: "stdout"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OTLP input without address",
+			config: &Config{
+				Inputs: InputsConfig{
+					OTLP: []OTLPInputConfig{{Name: "otlp"}},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Output:  OutputConfig{Type: "stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OTLP input with relative path",
+			config: &Config{
+				Inputs: InputsConfig{
+					OTLP: []OTLPInputConfig{{Name: "otlp", Address: ":4318", Path: "v1/logs"}},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Output:  OutputConfig{Type: "stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "HTTP input validation without schema path",
+			config: &Config{
+				Inputs: InputsConfig{
+					HTTP: []HTTPInputConfig{{Name: "api", Address: ":8080", Validation: &ValidationConfig{}}},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Output:  OutputConfig{Type: "stdout"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ordered with a single worker",
+			config: &Config{
+				Inputs:     InputsConfig{Files: []FileInputConfig{{Paths: []string{"/var/log/app.log"}}}},
+				Logging:    LoggingConfig{Level: "info", Format: "json"},
+				Output:     OutputConfig{Type: "stdout"},
+				Ordered:    true,
+				WorkerPool: &WorkerPoolConfig{NumWorkers: 1},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ordered with several workers",
+			config: &Config{
+				Inputs:     InputsConfig{Files: []FileInputConfig{{Paths: []string{"/var/log/app.log"}}}},
+				Logging:    LoggingConfig{Level: "info", Format: "json"},
+				Output:     OutputConfig{Type: "stdout"},
+				Ordered:    true,
+				WorkerPool: &WorkerPoolConfig{NumWorkers: 4},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ordered with parallel outputs",
+			config: &Config{
+				Inputs:  InputsConfig{Files: []FileInputConfig{{Paths: []string{"/var/log/app.log"}}}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Output:  OutputConfig{Type: "multi", Multi: &MultiOutputConfig{Outputs: []OutputDefinition{{Name: "out", Type: "stdout"}}, Parallel: true}},
+				Ordered: true,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
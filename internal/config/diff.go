@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ConfigDiff lists the settings that differ between two configurations
+type ConfigDiff struct {
+	// Reloadable settings can be applied to the running process
+	Reloadable []string
+
+	// RestartRequired settings only take effect after a restart
+	RestartRequired []string
+}
+
+// Empty reports whether the configurations are equivalent
+func (d ConfigDiff) Empty() bool {
+	return len(d.Reloadable) == 0 && len(d.RestartRequired) == 0
+}
+
+// Contains reports whether the setting at path is a reloadable change
+func (d ConfigDiff) Contains(path string) bool {
+	for _, p := range d.Reloadable {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadableField is a struct field that can change without a restart
+type reloadableField struct {
+	field string // Go field name
+	key   string // YAML key used in the diff path
+}
+
+var (
+	// inputReloadableFields are the input settings applied in place
+	inputReloadableFields = []reloadableField{
+		{"Parser", "parser"},
+		{"Transforms", "transforms"},
+		{"RateLimit", "rate_limit"},
+	}
+
+	// batchReloadableFields are the output settings applied in place
+	batchReloadableFields = []reloadableField{
+		{"BatchSize", "batch_size"},
+		{"BatchTimeout", "batch_timeout"},
+		{"FlushInterval", "flush_interval"},
+	}
+)
+
+// FileInputPath returns the diff path of the i-th file input
+func FileInputPath(i int) string {
+	return fmt.Sprintf("inputs.files[%d]", i)
+}
+
+// InputPath returns the diff path of a named input of the given kind
+// (syslog, http, kubernetes, grpc or otlp)
+func InputPath(kind, name string) string {
+	return fmt.Sprintf("inputs.%s.%s", kind, name)
+}
+
+// OutputPath returns the diff path of an output's settings. name is empty
+// for a single output and the output name in multi-output mode.
+func OutputPath(outputType, name string) string {
+	if name == "" {
+		return "output." + outputType
+	}
+	return fmt.Sprintf("output.multi.%s.%s", name, outputType)
+}
+
+// Diff compares the running configuration c with other. Log levels,
+// enrichment, parsers, transforms, rate limits and output batch settings are
+// reloadable; any other change, such as a bind address, requires a restart.
+func (c *Config) Diff(other *Config) ConfigDiff {
+	var d ConfigDiff
+
+	if c.Logging.Level != other.Logging.Level {
+		d.Reloadable = append(d.Reloadable, "logging.level")
+	}
+	if c.Logging.Format != other.Logging.Format {
+		d.RestartRequired = append(d.RestartRequired, "logging.format")
+	}
+	if !reflect.DeepEqual(c.Logging.Sampling, other.Logging.Sampling) {
+		d.RestartRequired = append(d.RestartRequired, "logging.sampling")
+	}
+
+	if !reflect.DeepEqual(c.Enrichment, other.Enrichment) {
+		d.Reloadable = append(d.Reloadable, "enrichment")
+	}
+
+	d.diffInputs(c.Inputs, other.Inputs)
+	d.diffOutput(c.Output, other.Output)
+
+	sections := []struct {
+		path     string
+		old, new interface{}
+	}{
+		{"parser", c.Parser, other.Parser},
+		{"transforms", c.Transforms, other.Transforms},
+		{"dedup", c.Dedup, other.Dedup},
+		{"ordered", c.Ordered, other.Ordered},
+		{"timestamp_skew", c.TimestampSkew, other.TimestampSkew},
+		{"buffer", c.Buffer, other.Buffer},
+		{"wal", c.WAL, other.WAL},
+		{"worker_pool", c.WorkerPool, other.WorkerPool},
+		{"reliability", c.Reliability, other.Reliability},
+		{"dead_letter", c.DeadLetter, other.DeadLetter},
+		{"metrics", c.Metrics, other.Metrics},
+		{"health", c.Health, other.Health},
+		{"tracing", c.Tracing, other.Tracing},
+		{"profiling", c.Profiling, other.Profiling},
+		{"performance", c.Performance, other.Performance},
+		{"shutdown", c.Shutdown, other.Shutdown},
+	}
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.old, section.new) {
+			d.RestartRequired = append(d.RestartRequired, section.path)
+		}
+	}
+
+	return d
+}
+
+// diffInputs compares the inputs. File inputs are matched by position and
+// the other inputs by name; adding or removing an input requires a restart.
+func (d *ConfigDiff) diffInputs(old, new InputsConfig) {
+	if len(old.Files) != len(new.Files) {
+		d.RestartRequired = append(d.RestartRequired, "inputs.files")
+	}
+	for i := 0; i < len(old.Files) && i < len(new.Files); i++ {
+		fields := inputReloadableFields
+		if !reflect.DeepEqual(multiline(old.Files[i].Parser), multiline(new.Files[i].Parser)) {
+			// The multiline assembler runs ahead of the parser and is only
+			// built at startup
+			fields = fields[1:]
+		}
+		d.diffFields(FileInputPath(i), old.Files[i], new.Files[i], fields)
+	}
+
+	d.diffNamed("syslog", old.Syslog, new.Syslog)
+	d.diffNamed("http", old.HTTP, new.HTTP)
+	d.diffNamed("kubernetes", old.Kubernetes, new.Kubernetes)
+	d.diffNamed("grpc", old.GRPC, new.GRPC)
+	d.diffNamed("otlp", old.OTLP, new.OTLP)
+}
+
+// diffNamed compares two slices of named input configurations
+func (d *ConfigDiff) diffNamed(kind string, old, new interface{}) {
+	oldByName := inputsByName(old)
+	newByName := inputsByName(new)
+
+	for _, name := range inputNames(old) {
+		if newInput, ok := newByName[name]; ok {
+			d.diffFields(InputPath(kind, name), oldByName[name], newInput, inputReloadableFields)
+		} else {
+			d.RestartRequired = append(d.RestartRequired, InputPath(kind, name))
+		}
+	}
+	for _, name := range inputNames(new) {
+		if _, ok := oldByName[name]; !ok {
+			d.RestartRequired = append(d.RestartRequired, InputPath(kind, name))
+		}
+	}
+}
+
+// diffOutput compares the output configurations
+func (d *ConfigDiff) diffOutput(old, new OutputConfig) {
+	if old.Type != new.Type || old.Path != new.Path {
+		d.RestartRequired = append(d.RestartRequired, "output")
+		return
+	}
+
+	d.diffOutputSettings("", old.Kafka, new.Kafka, old.Elasticsearch, new.Elasticsearch, old.S3, new.S3, old.GCS, new.GCS, old.HTTP, new.HTTP, old.Loki, new.Loki, old.Console, new.Console, old.Stdout, new.Stdout, old.GELF, new.GELF)
+
+	oldMulti, newMulti := old.Multi, new.Multi
+	if (oldMulti == nil) != (newMulti == nil) {
+		d.RestartRequired = append(d.RestartRequired, "output.multi")
+		return
+	}
+	if oldMulti == nil {
+		return
+	}
+	if oldMulti.FailureStrategy != newMulti.FailureStrategy || oldMulti.Parallel != newMulti.Parallel ||
+		oldMulti.MaxRetries != newMulti.MaxRetries || oldMulti.RetryBackoff != newMulti.RetryBackoff ||
+		len(oldMulti.Outputs) != len(newMulti.Outputs) {
+		d.RestartRequired = append(d.RestartRequired, "output.multi")
+		return
+	}
+	for i, oldDef := range oldMulti.Outputs {
+		newDef := newMulti.Outputs[i]
+		if oldDef.Name != newDef.Name || oldDef.Type != newDef.Type || oldDef.Path != newDef.Path {
+			d.RestartRequired = append(d.RestartRequired, "output.multi")
+			return
+		}
+		d.diffOutputSettings(oldDef.Name, oldDef.Kafka, newDef.Kafka, oldDef.Elasticsearch, newDef.Elasticsearch, oldDef.S3, newDef.S3, oldDef.GCS, newDef.GCS, oldDef.HTTP, newDef.HTTP, oldDef.Loki, newDef.Loki, oldDef.Console, newDef.Console, oldDef.Stdout, newDef.Stdout, oldDef.GELF, newDef.GELF)
+	}
+}
+
+// diffOutputSettings compares the typed settings of an output
+func (d *ConfigDiff) diffOutputSettings(name string, oldKafka, newKafka *KafkaOutputConfig, oldES, newES *ElasticsearchOutputConfig, oldS3, newS3 *S3OutputConfig, oldGCS, newGCS *GCSOutputConfig, oldHTTP, newHTTP *HTTPOutputConfig, oldLoki, newLoki *LokiOutputConfig, oldConsole, newConsole *ConsoleOutputConfig, oldStdout, newStdout *StdoutOutputConfig, oldGELF, newGELF *GELFOutputConfig) {
+	settings := []struct {
+		outputType string
+		old, new   interface{}
+	}{
+		{"kafka", oldKafka, newKafka},
+		{"elasticsearch", oldES, newES},
+		{"s3", oldS3, newS3},
+		{"gcs", oldGCS, newGCS},
+		{"http", oldHTTP, newHTTP},
+		{"loki", oldLoki, newLoki},
+		{"console", oldConsole, newConsole},
+		{"stdout", oldStdout, newStdout},
+		{"gelf", oldGELF, newGELF},
+	}
+
+	for _, s := range settings {
+		oldValue, newValue := reflect.ValueOf(s.old), reflect.ValueOf(s.new)
+		path := OutputPath(s.outputType, name)
+		switch {
+		case oldValue.IsNil() && newValue.IsNil():
+		case oldValue.IsNil() != newValue.IsNil():
+			d.RestartRequired = append(d.RestartRequired, path)
+		default:
+			d.diffFields(path, oldValue.Elem().Interface(), newValue.Elem().Interface(), batchReloadableFields)
+		}
+	}
+}
+
+// diffFields compares two structs of the same type. Differences in the
+// reloadable fields are recorded per field; any other difference requires a
+// restart.
+func (d *ConfigDiff) diffFields(path string, old, new interface{}, reloadable []reloadableField) {
+	oldCopy := reflect.New(reflect.TypeOf(old)).Elem()
+	oldCopy.Set(reflect.ValueOf(old))
+	newCopy := reflect.New(reflect.TypeOf(new)).Elem()
+	newCopy.Set(reflect.ValueOf(new))
+
+	for _, f := range reloadable {
+		oldField, newField := oldCopy.FieldByName(f.field), newCopy.FieldByName(f.field)
+		if !oldField.IsValid() {
+			continue
+		}
+		if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			d.Reloadable = append(d.Reloadable, path+"."+f.key)
+		}
+		oldField.Set(reflect.Zero(oldField.Type()))
+		newField.Set(reflect.Zero(newField.Type()))
+	}
+
+	if !reflect.DeepEqual(oldCopy.Interface(), newCopy.Interface()) {
+		d.RestartRequired = append(d.RestartRequired, path)
+	}
+}
+
+// inputsByName indexes a slice of input configurations by their Name field
+func inputsByName(inputs interface{}) map[string]interface{} {
+	v := reflect.ValueOf(inputs)
+	byName := make(map[string]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		byName[v.Index(i).FieldByName("Name").String()] = v.Index(i).Interface()
+	}
+	return byName
+}
+
+// inputNames returns the names of a slice of input configurations in order
+func inputNames(inputs interface{}) []string {
+	v := reflect.ValueOf(inputs)
+	names := make([]string, v.Len())
+	for i := range names {
+		names[i] = v.Index(i).FieldByName("Name").String()
+	}
+	return names
+}
+
+// multiline returns the multiline settings of a parser configuration
+func multiline(p *ParserConfig) *MultilineConfig {
+	if p == nil {
+		return nil
+	}
+	return p.Multiline
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// diffTestConfig returns a configuration with one input of each reloadable
+// kind and a kafka output
+func diffTestConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Inputs.HTTP = []HTTPInputConfig{{
+		Name:      "api",
+		Address:   "0.0.0.0:8080",
+		RateLimit: 100,
+		Parser:    &ParserConfig{Type: "json"},
+	}}
+	cfg.Output = OutputConfig{
+		Type: "kafka",
+		Kafka: &KafkaOutputConfig{
+			Brokers:       []string{"localhost:9092"},
+			Topic:         "logs",
+			BatchSize:     100,
+			FlushInterval: time.Second,
+		},
+	}
+	return cfg
+}
+
+func TestConfigDiff(t *testing.T) {
+	tests := []struct {
+		name            string
+		modify          func(cfg *Config)
+		reloadable      []string
+		restartRequired []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:       "log level",
+			modify:     func(cfg *Config) { cfg.Logging.Level = "debug" },
+			reloadable: []string{"logging.level"},
+		},
+		{
+			name:            "log sampling",
+			modify:          func(cfg *Config) { cfg.Logging.Sampling = &LogSamplingConfig{Burst: 10} },
+			restartRequired: []string{"logging.sampling"},
+		},
+		{
+			name:       "enrichment",
+			modify:     func(cfg *Config) { cfg.Enrichment = &EnrichmentConfig{Fields: map[string]string{"env": "production"}} },
+			reloadable: []string{"enrichment"},
+		},
+		{
+			name: "file input transforms",
+			modify: func(cfg *Config) {
+				cfg.Inputs.Files[0].Transforms = []TransformConfig{{Type: "add", Add: map[string]string{"env": "production"}}}
+			},
+			reloadable: []string{"inputs.files[0].transforms"},
+		},
+		{
+			name: "http parser and rate limit",
+			modify: func(cfg *Config) {
+				cfg.Inputs.HTTP[0].Parser = &ParserConfig{Type: "logfmt"}
+				cfg.Inputs.HTTP[0].RateLimit = 50
+			},
+			reloadable: []string{"inputs.http.api.parser", "inputs.http.api.rate_limit"},
+		},
+		{
+			name: "output batch settings",
+			modify: func(cfg *Config) {
+				cfg.Output.Kafka.BatchSize = 500
+				cfg.Output.Kafka.FlushInterval = 5 * time.Second
+			},
+			reloadable: []string{"output.kafka.batch_size", "output.kafka.flush_interval"},
+		},
+		{
+			name:            "bind address",
+			modify:          func(cfg *Config) { cfg.Inputs.HTTP[0].Address = "0.0.0.0:9090" },
+			restartRequired: []string{"inputs.http.api"},
+		},
+		{
+			name: "multiline settings",
+			modify: func(cfg *Config) {
+				cfg.Inputs.Files[0].Parser = &ParserConfig{Type: "regex", Pattern: "(?P<message>.*)", Multiline: &MultilineConfig{Pattern: "^\\s"}}
+			},
+			restartRequired: []string{"inputs.files[0]"},
+		},
+		{
+			name: "added input",
+			modify: func(cfg *Config) {
+				cfg.Inputs.HTTP = append(cfg.Inputs.HTTP, HTTPInputConfig{Name: "web", Address: "0.0.0.0:8081"})
+			},
+			restartRequired: []string{"inputs.http.web"},
+		},
+		{
+			name:            "output topic",
+			modify:          func(cfg *Config) { cfg.Output.Kafka.Topic = "events" },
+			restartRequired: []string{"output.kafka"},
+		},
+		{
+			name:            "metrics server",
+			modify:          func(cfg *Config) { cfg.Metrics = &MetricsConfig{Enabled: true, Address: ":9091"} },
+			restartRequired: []string{"metrics"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := diffTestConfig()
+			updated := diffTestConfig()
+			tt.modify(updated)
+
+			diff := old.Diff(updated)
+			if !reflect.DeepEqual(diff.Reloadable, tt.reloadable) {
+				t.Errorf("Reloadable = %v, want %v", diff.Reloadable, tt.reloadable)
+			}
+			if !reflect.DeepEqual(diff.RestartRequired, tt.restartRequired) {
+				t.Errorf("RestartRequired = %v, want %v", diff.RestartRequired, tt.restartRequired)
+			}
+			if diff.Empty() != (tt.reloadable == nil && tt.restartRequired == nil) {
+				t.Errorf("Empty() = %v", diff.Empty())
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// includesKey lists files merged beneath the file that names them. Paths
+	// are relative to the including file.
+	includesKey = "includes"
+
+	// appendSuffix marks a list key whose items are appended to the list
+	// from earlier files instead of replacing it, e.g. "transforms+:"
+	appendSuffix = "+"
+)
+
+// LoadOptions control how configuration files are decoded
+type LoadOptions struct {
+	// Strict rejects keys that don't match a configuration field, so
+	// misspelled settings aren't silently ignored
+	Strict bool
+}
+
+// LoadLayered loads configuration from YAML files merged in order. Later
+// files override scalar values from earlier ones and maps are merged key by
+// key. Lists replace the earlier list unless their key ends in "+", which
+// appends the items instead. Each file may name files to merge beneath it
+// with an includes list. The merged configuration is validated once and
+// unknown keys are rejected.
+func LoadLayered(paths ...string) (*Config, error) {
+	return LoadWithOptions(LoadOptions{Strict: true}, paths...)
+}
+
+// LoadWithOptions loads and merges configuration files like LoadLayered
+func LoadWithOptions(opts LoadOptions, paths ...string) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files given")
+	}
+
+	merged := make(map[string]interface{})
+	for _, path := range paths {
+		layer, err := loadLayer(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeLayer(merged, layer); err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", path, err)
+		}
+	}
+
+	// Report unknown keys by their path in the configuration; the decoder's
+	// errors refer to lines of the merged document
+	if opts.Strict {
+		if unknown := unknownFields(merged, reflect.TypeOf(Config{}), ""); len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown config fields: %s", strings.Join(unknown, ", "))
+		}
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged config: %w", err)
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(opts.Strict)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Apply defaults
+	cfg.applyDefaults()
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// loadLayer reads a YAML file with environment variable expansion and
+// merges it on top of its includes. stack holds the files being included,
+// to detect cycles.
+func loadLayer(path string, stack []string) (map[string]interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path %s: %w", path, err)
+	}
+	for _, including := range stack {
+		if including == absPath {
+			return nil, fmt.Errorf("config include cycle: %s", strings.Join(append(stack, absPath), " -> "))
+		}
+	}
+	stack = append(stack, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Expand environment variables in the YAML content
+	expandedData := []byte(os.ExpandEnv(string(data)))
+
+	layer := make(map[string]interface{})
+	if err := yaml.Unmarshal(expandedData, &layer); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	includes, err := includePaths(layer, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(layer, includesKey)
+	if len(includes) == 0 {
+		return layer, nil
+	}
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		included, err := loadLayer(include, stack)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeLayer(merged, included); err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", include, err)
+		}
+	}
+	if err := mergeLayer(merged, layer); err != nil {
+		return nil, fmt.Errorf("failed to merge %s: %w", path, err)
+	}
+
+	return merged, nil
+}
+
+// includePaths returns the files listed under includes, resolved against
+// the including file's directory
+func includePaths(layer map[string]interface{}, dir string) ([]string, error) {
+	value, ok := layer[includesKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of file paths", includesKey)
+	}
+
+	paths := make([]string, 0, len(list))
+	for _, item := range list {
+		path, ok := item.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("%s must be a list of file paths", includesKey)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// mergeLayer merges overlay into base following the LoadLayered merge policy
+func mergeLayer(base, overlay map[string]interface{}) error {
+	// Appends apply after the overlay's own values for the same key
+	var appends []string
+
+	for key, value := range overlay {
+		if strings.HasSuffix(key, appendSuffix) && key != appendSuffix {
+			appends = append(appends, key)
+			continue
+		}
+
+		overlayMap, ok := value.(map[string]interface{})
+		if !ok {
+			base[key] = value
+			continue
+		}
+
+		// Maps are merged into a fresh map when the base has none, so
+		// append markers nested in them are resolved too
+		baseMap, ok := base[key].(map[string]interface{})
+		if !ok {
+			baseMap = make(map[string]interface{})
+		}
+		if err := mergeLayer(baseMap, overlayMap); err != nil {
+			return fmt.Errorf("%s.%w", key, err)
+		}
+		base[key] = baseMap
+	}
+
+	for _, key := range appends {
+		name := strings.TrimSuffix(key, appendSuffix)
+		items, ok := overlay[key].([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: append marker requires a list", name)
+		}
+		existing, _ := base[name].([]interface{})
+		base[name] = append(append([]interface{}{}, existing...), items...)
+	}
+
+	return nil
+}
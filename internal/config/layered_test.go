@@ -0,0 +1,211 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const layeredBase = `
+inputs:
+  files:
+    - paths:
+        - /var/log/app.log
+      checkpoint_path: /tmp/checkpoints
+      transforms:
+        - type: add
+          add:
+            env: base
+
+logging:
+  level: info
+  format: json
+
+output:
+  type: kafka
+  kafka:
+    brokers:
+      - localhost:9092
+    topic: logs
+    batch_size: 100
+`
+
+// writeLayer writes a config file into dir
+func writeLayer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadLayered(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "scalar override",
+			overlay: `
+logging:
+  level: debug
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Logging.Level != "debug" {
+					t.Errorf("expected level debug, got %s", cfg.Logging.Level)
+				}
+				if cfg.Logging.Format != "json" {
+					t.Errorf("expected format from the base, got %s", cfg.Logging.Format)
+				}
+			},
+		},
+		{
+			name: "map merge",
+			overlay: `
+output:
+  kafka:
+    topic: prod-logs
+    compression_codec: zstd
+`,
+			check: func(t *testing.T, cfg *Config) {
+				kafka := cfg.Output.Kafka
+				if cfg.Output.Type != "kafka" || kafka == nil {
+					t.Fatalf("expected the kafka output from the base, got %+v", cfg.Output)
+				}
+				if kafka.Topic != "prod-logs" || kafka.CompressionCodec != "zstd" {
+					t.Errorf("expected overlay values, got topic %q compression %q", kafka.Topic, kafka.CompressionCodec)
+				}
+				if len(kafka.Brokers) != 1 || kafka.BatchSize != 100 {
+					t.Errorf("expected base values to be kept, got brokers %v batch_size %d", kafka.Brokers, kafka.BatchSize)
+				}
+			},
+		},
+		{
+			name: "array replace",
+			overlay: `
+output:
+  kafka:
+    brokers:
+      - kafka1:9092
+      - kafka2:9092
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if got := strings.Join(cfg.Output.Kafka.Brokers, ","); got != "kafka1:9092,kafka2:9092" {
+					t.Errorf("expected the overlay brokers to replace the base, got %s", got)
+				}
+			},
+		},
+		{
+			name: "array append marker",
+			overlay: `
+output:
+  kafka:
+    brokers+:
+      - kafka2:9092
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if got := strings.Join(cfg.Output.Kafka.Brokers, ","); got != "localhost:9092,kafka2:9092" {
+					t.Errorf("expected the overlay brokers to be appended, got %s", got)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			base := writeLayer(t, dir, "base.yaml", layeredBase)
+			overlay := writeLayer(t, dir, "prod.yaml", tt.overlay)
+
+			cfg, err := LoadLayered(base, overlay)
+			if err != nil {
+				t.Fatalf("LoadLayered() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeLayer(t, dir, "base.yaml", layeredBase)
+	if err := os.Mkdir(filepath.Join(dir, "env"), 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	path := writeLayer(t, filepath.Join(dir, "env"), "prod.yaml", `
+includes:
+  - ../base.yaml
+logging:
+  level: warn
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Logging.Level != "warn" {
+		t.Errorf("expected the including file to override its includes, got %s", cfg.Logging.Level)
+	}
+	if len(cfg.Inputs.Files) != 1 || cfg.Output.Kafka == nil {
+		t.Errorf("expected the included base config, got %+v", cfg)
+	}
+}
+
+func TestLoadLayered_Errors(t *testing.T) {
+	dir := t.TempDir()
+	base := writeLayer(t, dir, "base.yaml", layeredBase)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		load  []string
+	}{
+		{
+			name:  "include cycle",
+			files: map[string]string{"a.yaml": "includes: [b.yaml]\n", "b.yaml": "includes: [a.yaml]\n"},
+			load:  []string{"a.yaml"},
+		},
+		{
+			name:  "append to a scalar",
+			files: map[string]string{"bad.yaml": "logging:\n  level+: debug\n"},
+			load:  []string{"base.yaml", "bad.yaml"},
+		},
+		{
+			name:  "invalid merged config",
+			files: map[string]string{"bad.yaml": "logging:\n  level: verbose\n"},
+			load:  []string{"base.yaml", "bad.yaml"},
+		},
+		{
+			name:  "missing include",
+			files: map[string]string{"bad.yaml": "includes: [missing.yaml]\n"},
+			load:  []string{"bad.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, content := range tt.files {
+				writeLayer(t, dir, name, content)
+			}
+			paths := make([]string, len(tt.load))
+			for i, name := range tt.load {
+				paths[i] = filepath.Join(dir, name)
+			}
+
+			if _, err := LoadLayered(paths...); err == nil {
+				t.Error("expected LoadLayered() to fail")
+			}
+		})
+	}
+
+	if _, err := LoadLayered(); err == nil {
+		t.Error("expected error without config files")
+	}
+	if _, err := LoadLayered(base); err != nil {
+		t.Errorf("LoadLayered() error = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue replaces secrets in a redacted configuration
+const RedactedValue = "******"
+
+// secretKeys are the substrings of setting names holding secrets, such as
+// password, sasl_password, api_key(s) and bearer_token. Header names such as
+// Authorization and X-API-Key match too.
+var secretKeys = []string{"password", "api_key", "token", "secret", "authorization"}
+
+// Redacted returns the configuration as a tree of its YAML settings, with the
+// values of secret settings masked
+func (c *Config) Redacted() (map[string]interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	redact(tree)
+	return tree, nil
+}
+
+// redact masks the secret settings of a YAML tree in place
+func redact(node interface{}) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSecretKey(key) {
+				v[key] = mask(value)
+				continue
+			}
+			redact(value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+// mask replaces a secret value, keeping the number of list entries
+func mask(value interface{}) interface{} {
+	if list, ok := value.([]interface{}); ok {
+		masked := make([]interface{}, len(list))
+		for i := range list {
+			masked[i] = RedactedValue
+		}
+		return masked
+	}
+	return RedactedValue
+}
+
+// isSecretKey reports whether a setting name holds a secret
+func isSecretKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
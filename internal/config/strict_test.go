@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const strictTestConfig = `
+inputs:
+  files:
+    - paths:
+        - /var/log/app.log
+      checkpoint_path: /tmp/checkpoints
+      parser:
+        type: json
+        custom_fields:
+          team: payments
+
+buffer:
+  size: 1024
+  backpressure_strategy: drop
+
+output:
+  type: kafka
+  kafka:
+    brokers:
+      - localhost:9092
+    topic: logs
+    batch_timeout: 100ms
+    max_events_per_sec: 1000
+`
+
+func TestLoadStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr []string
+	}{
+		{
+			name:   "valid config",
+			config: strictTestConfig,
+		},
+		{
+			name:    "misspelled key",
+			config:  strings.Replace(strictTestConfig, "backpressure_strategy", "backpresure_strategy", 1),
+			wantErr: []string{"buffer.backpresure_strategy"},
+		},
+		{
+			name: "unknown keys in lists and maps",
+			config: strictTestConfig + `
+  multi:
+    outputs:
+      - name: archive
+        type: s3
+        s3:
+          bucket: logs
+          regoin: us-east-1
+logging:
+  levle: debug
+`,
+			wantErr: []string{"output.multi.outputs[0].s3.regoin", "logging.levle"},
+		},
+		{
+			name:    "unknown key next to inline keys",
+			config:  strings.Replace(strictTestConfig, "max_events_per_sec", "max_event_per_sec", 1),
+			wantErr: []string{"output.kafka.max_event_per_sec"},
+		},
+		{
+			name:    "unknown key in a list item",
+			config:  strings.Replace(strictTestConfig, "checkpoint_path", "checkpoint_pth", 1),
+			wantErr: []string{"inputs.files[0].checkpoint_pth"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLayer(t, t.TempDir(), "config.yaml", tt.config)
+
+			cfg, err := Load(path)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if cfg.Buffer == nil || cfg.Buffer.BackpressureStrategy != "drop" {
+					t.Errorf("expected the buffer settings to load, got %+v", cfg.Buffer)
+				}
+				if cfg.Output.Kafka.MaxEventsPerSec != 1000 {
+					t.Errorf("expected the inline rate limit to load, got %+v", cfg.Output.Kafka.RateLimitConfig)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected Load() to reject unknown fields")
+			}
+			for _, field := range tt.wantErr {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("expected error to name %s, got %v", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_NotStrict(t *testing.T) {
+	config := strings.Replace(strictTestConfig, "backpressure_strategy", "backpresure_strategy", 1)
+	path := writeLayer(t, t.TempDir(), "config.yaml", config)
+
+	cfg, err := LoadWithOptions(LoadOptions{Strict: false}, path)
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+	if cfg.Buffer == nil || cfg.Buffer.Size != 1024 {
+		t.Errorf("expected the known buffer settings to load, got %+v", cfg.Buffer)
+	}
+}
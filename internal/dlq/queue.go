@@ -0,0 +1,476 @@
+package dlq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+const (
+	segmentPrefix = "deadletter-"
+	segmentSuffix = ".jsonl"
+)
+
+// QueueConfig holds configuration for the file-backed Queue
+type QueueConfig struct {
+	Dir           string
+	MaxSize       int64         // Maximum total size of the queue files in bytes
+	MaxAge        time.Duration // Files last written before this are removed
+	FlushInterval time.Duration
+	SegmentSize   int64 // Size in bytes at which the current file is rotated
+}
+
+// Queue appends events that outputs failed to deliver to rotating files
+// under Dir. Unlike DeadLetterQueue it keeps no entries in memory, so its
+// size is bounded by MaxSize on disk rather than by an event count.
+type Queue struct {
+	config QueueConfig
+
+	mu        sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	fileSize  int64
+	lastWrite time.Time  // When the current file was last written
+	segments  []*segment // Rotated files, oldest first
+	nextSeq   uint64
+	closed    bool
+	closeCh   chan struct{}
+	wg        sync.WaitGroup
+
+	written uint64
+	now     func() time.Time
+}
+
+// segment is a rotated queue file
+type segment struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// NewQueue creates a file-backed dead letter queue, picking up files left by
+// a previous run so they can be replayed
+func NewQueue(config QueueConfig) (*Queue, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("DLQ directory is required")
+	}
+
+	if config.MaxSize == 0 {
+		config.MaxSize = 100 * 1024 * 1024 // Default 100MB
+	}
+
+	if config.MaxAge == 0 {
+		config.MaxAge = 24 * time.Hour // Default max age
+	}
+
+	if config.FlushInterval == 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+
+	if config.SegmentSize == 0 {
+		config.SegmentSize = config.MaxSize / 10
+	}
+
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create DLQ directory: %w", err)
+	}
+
+	q := &Queue{
+		config:  config,
+		closeCh: make(chan struct{}),
+		now:     time.Now,
+	}
+
+	if err := q.loadSegments(); err != nil {
+		return nil, fmt.Errorf("failed to load DLQ files: %w", err)
+	}
+
+	if err := q.openSegment(); err != nil {
+		return nil, err
+	}
+	q.enforceRetention()
+
+	q.wg.Add(1)
+	go q.flushLoop()
+
+	return q, nil
+}
+
+// Write appends a failed event with the reason it could not be delivered
+func (q *Queue) Write(event *types.LogEvent, reason error, metadata map[string]string) error {
+	entry := &DLQEntry{
+		Event:     event,
+		Timestamp: q.now(),
+		Metadata:  metadata,
+	}
+	if reason != nil {
+		entry.Error = reason.Error()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrDLQClosed
+	}
+
+	if q.fileSize > 0 && q.fileSize+int64(len(data)) > q.config.SegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := q.writer.Write(data)
+	q.fileSize += int64(n)
+	q.lastWrite = entry.Timestamp
+	if err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+
+	atomic.AddUint64(&q.written, 1)
+	metrics.GetGlobalCollector().DLQEventsWritten.Inc()
+	q.enforceRetention()
+
+	return nil
+}
+
+// Replay re-sends every queued event through send, oldest first. Files are
+// removed once all of their events were sent; if send fails, the unsent
+// events are kept for the next replay and the error is returned. Events
+// written while replaying are kept for the next replay as well.
+func (q *Queue) Replay(ctx context.Context, send func(context.Context, *types.LogEvent) error) (int, error) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return 0, ErrDLQClosed
+	}
+	if q.fileSize > 0 {
+		if err := q.rotate(); err != nil {
+			q.mu.Unlock()
+			return 0, err
+		}
+	}
+	segments := make([]*segment, len(q.segments))
+	copy(segments, q.segments)
+	q.mu.Unlock()
+
+	replayed := 0
+	for _, seg := range segments {
+		entries, err := readSegment(seg.path)
+		if os.IsNotExist(err) {
+			continue // Removed by retention in the meantime
+		}
+		if err != nil {
+			return replayed, err
+		}
+
+		for i, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return replayed, q.keepUnsent(seg, entries[i:], err)
+			}
+			if err := send(ctx, entry.Event); err != nil {
+				return replayed, q.keepUnsent(seg, entries[i:], fmt.Errorf("failed to replay event: %w", err))
+			}
+			replayed++
+		}
+
+		q.removeSegment(seg)
+	}
+
+	return replayed, nil
+}
+
+// Flush writes buffered entries to disk
+func (q *Queue) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrDLQClosed
+	}
+	return q.flushLocked()
+}
+
+// Close flushes buffered entries and closes the current file
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrDLQClosed
+	}
+	q.closed = true
+	close(q.closeCh)
+
+	err := q.flushLocked()
+	if closeErr := q.file.Close(); err == nil {
+		err = closeErr
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	return err
+}
+
+// Size returns the total size of the queue files in bytes
+func (q *Queue) Size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.sizeLocked()
+}
+
+// Written returns the number of events written since the queue was created
+func (q *Queue) Written() uint64 {
+	return atomic.LoadUint64(&q.written)
+}
+
+// flushLoop periodically flushes buffered entries and applies retention
+func (q *Queue) flushLoop() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.mu.Lock()
+			if !q.closed {
+				_ = q.flushLocked()
+				q.enforceRetention()
+			}
+			q.mu.Unlock()
+		case <-q.closeCh:
+			return
+		}
+	}
+}
+
+// flushLocked flushes the writer and syncs the current file (must be called
+// with lock held)
+func (q *Queue) flushLocked() error {
+	if err := q.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush DLQ file: %w", err)
+	}
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync DLQ file: %w", err)
+	}
+	return nil
+}
+
+// rotate closes the current file and starts a new one (must be called with
+// lock held)
+func (q *Queue) rotate() error {
+	if err := q.flushLocked(); err != nil {
+		return err
+	}
+	if err := q.file.Close(); err != nil {
+		return fmt.Errorf("failed to close DLQ file: %w", err)
+	}
+
+	q.segments = append(q.segments, &segment{
+		path:    q.file.Name(),
+		size:    q.fileSize,
+		modTime: q.lastWrite,
+	})
+
+	if err := q.openSegment(); err != nil {
+		return err
+	}
+	q.enforceRetention()
+	return nil
+}
+
+// openSegment creates the next queue file (must be called with lock held)
+func (q *Queue) openSegment() error {
+	name := fmt.Sprintf("%s%020d%s", segmentPrefix, q.nextSeq, segmentSuffix)
+	q.nextSeq++
+
+	file, err := os.OpenFile(filepath.Join(q.config.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create DLQ file: %w", err)
+	}
+
+	q.file = file
+	q.writer = bufio.NewWriter(file)
+	q.fileSize = 0
+	return nil
+}
+
+// enforceRetention removes rotated files older than MaxAge and then the
+// oldest files until the queue fits in MaxSize (must be called with lock
+// held)
+func (q *Queue) enforceRetention() {
+	cutoff := q.now().Add(-q.config.MaxAge)
+	total := q.sizeLocked()
+
+	kept := q.segments[:0]
+	for _, seg := range q.segments {
+		if seg.modTime.Before(cutoff) || total > q.config.MaxSize {
+			if err := os.Remove(seg.path); err == nil || os.IsNotExist(err) {
+				total -= seg.size
+				continue
+			}
+		}
+		kept = append(kept, seg)
+	}
+	q.segments = kept
+
+	metrics.GetGlobalCollector().DLQSize.Set(float64(total))
+}
+
+// sizeLocked returns the total size of the queue files (must be called with
+// lock held)
+func (q *Queue) sizeLocked() int64 {
+	total := q.fileSize
+	for _, seg := range q.segments {
+		total += seg.size
+	}
+	return total
+}
+
+// keepUnsent rewrites a segment with the entries that were not replayed and
+// returns cause
+func (q *Queue) keepUnsent(seg *segment, entries []*DLQEntry, cause error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tmp := seg.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("%w (failed to keep unsent events: %v)", cause, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("%w (failed to keep unsent events: %v)", cause, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("%w (failed to keep unsent events: %v)", cause, err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, seg.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%w (failed to keep unsent events: %v)", cause, err)
+	}
+
+	if info, err := os.Stat(seg.path); err == nil {
+		seg.size = info.Size()
+	}
+	metrics.GetGlobalCollector().DLQSize.Set(float64(q.sizeLocked()))
+
+	return cause
+}
+
+// removeSegment deletes a fully replayed segment
+func (q *Queue) removeSegment(seg *segment) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	os.Remove(seg.path)
+	for i, s := range q.segments {
+		if s == seg {
+			q.segments = append(q.segments[:i], q.segments[i+1:]...)
+			break
+		}
+	}
+	metrics.GetGlobalCollector().DLQSize.Set(float64(q.sizeLocked()))
+}
+
+// loadSegments picks up queue files from a previous run, oldest first
+func (q *Queue) loadSegments() error {
+	entries, err := os.ReadDir(q.config.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		seq, ok := segmentSeq(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			os.Remove(filepath.Join(q.config.Dir, entry.Name()))
+			continue
+		}
+
+		q.segments = append(q.segments, &segment{
+			path:    filepath.Join(q.config.Dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool {
+		return q.segments[i].path < q.segments[j].path
+	})
+	return nil
+}
+
+// segmentSeq parses the sequence number from a queue file name
+func segmentSeq(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+	return seq, err == nil
+}
+
+// readSegment decodes all entries of a queue file
+func readSegment(path string) ([]*DLQEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []*DLQEntry
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var entry DLQEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				break // Last entry was cut short by a crash
+			}
+			return nil, fmt.Errorf("failed to decode entry in %s: %w", path, err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,219 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// queueFiles returns the queue files in dir
+func queueFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"+segmentSuffix))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	return files
+}
+
+func TestQueue_Write(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := NewQueue(QueueConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	defer q.Close()
+
+	event := &types.LogEvent{Message: "failed", Source: "app"}
+	if err := q.Write(event, errors.New("connection refused"), map[string]string{"output": "kafka"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	files := queueFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 queue file, got %d", len(files))
+	}
+
+	entries, err := readSegment(files[0])
+	if err != nil {
+		t.Fatalf("readSegment() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Event.Message != "failed" || entry.Error != "connection refused" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Metadata["output"] != "kafka" || entry.Timestamp.IsZero() {
+		t.Errorf("expected metadata and timestamp, got %+v", entry)
+	}
+	if q.Written() != 1 || q.Size() == 0 {
+		t.Errorf("Written() = %d, Size() = %d", q.Written(), q.Size())
+	}
+}
+
+func TestQueue_RotationAndMaxSize(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := NewQueue(QueueConfig{Dir: dir, MaxSize: 1000, SegmentSize: 200})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	defer q.Close()
+
+	for i := 0; i < 50; i++ {
+		event := &types.LogEvent{Message: fmt.Sprintf("event-%02d", i)}
+		if err := q.Write(event, errors.New("timeout"), nil); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if files := queueFiles(t, dir); len(files) < 2 {
+		t.Errorf("expected the queue to rotate files, got %d", len(files))
+	}
+	if size := q.Size(); size > 1000 {
+		t.Errorf("expected size within MaxSize, got %d", size)
+	}
+
+	// The oldest events were removed to stay within MaxSize
+	var messages []string
+	if _, err := q.Replay(context.Background(), func(_ context.Context, event *types.LogEvent) error {
+		messages = append(messages, event.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(messages) == 0 || messages[0] == "event-00" || messages[len(messages)-1] != "event-49" {
+		t.Errorf("expected the newest events to be kept, got %v", messages)
+	}
+}
+
+func TestQueue_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := NewQueue(QueueConfig{Dir: dir, MaxAge: time.Hour, SegmentSize: 1})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	defer q.Close()
+
+	now := time.Now()
+	q.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if err := q.Write(&types.LogEvent{Message: "old"}, errors.New("timeout"), nil); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := q.Write(&types.LogEvent{Message: "new"}, errors.New("timeout"), nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var messages []string
+	if _, err := q.Replay(context.Background(), func(_ context.Context, event *types.LogEvent) error {
+		messages = append(messages, event.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if strings.Join(messages, ",") != "new" {
+		t.Errorf("expected only the new event to be kept, got %v", messages)
+	}
+}
+
+func TestQueue_Replay(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := NewQueue(QueueConfig{Dir: dir, SegmentSize: 150})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := q.Write(&types.LogEvent{Message: fmt.Sprintf("event-%d", i)}, errors.New("timeout"), nil); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	// A failing send keeps the unsent events
+	sendErr := errors.New("still down")
+	var sent []string
+	replayed, err := q.Replay(context.Background(), func(_ context.Context, event *types.LogEvent) error {
+		if event.Message == "event-2" {
+			return sendErr
+		}
+		sent = append(sent, event.Message)
+		return nil
+	})
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("expected send error, got %v", err)
+	}
+	if replayed != 2 {
+		t.Errorf("expected 2 events replayed before the failure, got %d", replayed)
+	}
+
+	// The remaining events survive a restart and are replayed in order
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	q, err = NewQueue(QueueConfig{Dir: dir, SegmentSize: 150})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	defer q.Close()
+
+	replayed, err = q.Replay(context.Background(), func(_ context.Context, event *types.LogEvent) error {
+		sent = append(sent, event.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if replayed != 3 {
+		t.Errorf("expected 3 events replayed, got %d", replayed)
+	}
+	if got := strings.Join(sent, ","); got != "event-0,event-1,event-2,event-3,event-4" {
+		t.Errorf("unexpected replay order: %s", got)
+	}
+
+	if q.Size() != 0 {
+		t.Errorf("expected an empty queue after replay, got %d bytes", q.Size())
+	}
+	for _, file := range queueFiles(t, dir) {
+		if info, err := os.Stat(file); err == nil && info.Size() > 0 {
+			t.Errorf("expected replayed file %s to be removed", file)
+		}
+	}
+}
+
+func TestQueue_Closed(t *testing.T) {
+	q, err := NewQueue(QueueConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := q.Write(&types.LogEvent{}, errors.New("x"), nil); !errors.Is(err, ErrDLQClosed) {
+		t.Errorf("expected ErrDLQClosed, got %v", err)
+	}
+	if _, err := q.Replay(context.Background(), nil); !errors.Is(err, ErrDLQClosed) {
+		t.Errorf("expected ErrDLQClosed, got %v", err)
+	}
+}
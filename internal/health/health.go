@@ -89,9 +89,8 @@ func (c *Checker) Check(ctx context.Context) map[string]ComponentHealth {
 
 			c.mu.Lock()
 			c.lastStatus[n] = result
-			c.mu.Unlock()
-
 			results[n] = result
+			c.mu.Unlock()
 		}(name, check)
 	}
 
@@ -0,0 +1,164 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UtilizationConfig configures a utilization monitor
+type UtilizationConfig struct {
+	// HighWaterMark is the utilization percentage (0-100) above which the
+	// component is under pressure. Defaults to 80.
+	HighWaterMark float64
+
+	// Sustain is how long utilization must stay above the high-water mark
+	// before the component is degraded, so bursts are tolerated. Defaults
+	// to 30s.
+	Sustain time.Duration
+}
+
+// UtilizationMonitor reports a component, such as the ring buffer, as
+// degraded while its utilization stays above a high-water mark
+type UtilizationMonitor struct {
+	utilization func() float64
+	config      UtilizationConfig
+	now         func() time.Time
+
+	mu         sync.Mutex
+	aboveSince time.Time // zero while below the high-water mark
+}
+
+// NewUtilizationMonitor creates a monitor of the utilization percentage
+// returned by utilization
+func NewUtilizationMonitor(utilization func() float64, config UtilizationConfig) *UtilizationMonitor {
+	if config.HighWaterMark <= 0 {
+		config.HighWaterMark = 80
+	}
+	if config.Sustain <= 0 {
+		config.Sustain = 30 * time.Second
+	}
+
+	return &UtilizationMonitor{
+		utilization: utilization,
+		config:      config,
+		now:         time.Now,
+	}
+}
+
+// Check returns the monitor's health check
+func (m *UtilizationMonitor) Check() HealthCheck {
+	return CheckWithMetadata(m.status)
+}
+
+func (m *UtilizationMonitor) status() (Status, string, map[string]interface{}) {
+	utilization := m.utilization()
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metadata := map[string]interface{}{
+		"utilization":     utilization,
+		"high_water_mark": m.config.HighWaterMark,
+	}
+
+	if utilization <= m.config.HighWaterMark {
+		m.aboveSince = time.Time{}
+		return StatusHealthy, fmt.Sprintf("Utilization %.1f%%", utilization), metadata
+	}
+
+	if m.aboveSince.IsZero() {
+		m.aboveSince = now
+	}
+	above := now.Sub(m.aboveSince)
+	metadata["above_for"] = above.String()
+
+	message := fmt.Sprintf("Utilization %.1f%% above %.1f%% for %s", utilization, m.config.HighWaterMark, above)
+	if above < m.config.Sustain {
+		return StatusHealthy, message, metadata
+	}
+	return StatusDegraded, message, metadata
+}
+
+// GrowthConfig configures a growth monitor
+type GrowthConfig struct {
+	// Window is the period the growth rate is measured over. Defaults to
+	// 1m.
+	Window time.Duration
+
+	// MaxRate is the growth in units per second tolerated over the window.
+	// Zero makes any net growth unhealthy.
+	MaxRate float64
+}
+
+// sizeSample is a size observed by a growth monitor
+type sizeSample struct {
+	at   time.Time
+	size int64
+}
+
+// GrowthMonitor reports a queue, such as the dead letter queue, as
+// unhealthy while it grows faster than it drains. Sizes are sampled on
+// each check, so the rate is measured once checks span a whole window.
+type GrowthMonitor struct {
+	size   func() int64
+	config GrowthConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	samples []sizeSample
+}
+
+// NewGrowthMonitor creates a monitor of the size returned by size
+func NewGrowthMonitor(size func() int64, config GrowthConfig) *GrowthMonitor {
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+
+	return &GrowthMonitor{
+		size:   size,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Check returns the monitor's health check
+func (m *GrowthMonitor) Check() HealthCheck {
+	return CheckWithMetadata(m.status)
+}
+
+func (m *GrowthMonitor) status() (Status, string, map[string]interface{}) {
+	size := m.size()
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.samples = append(m.samples, sizeSample{at: now, size: size})
+
+	// Keep the newest sample at least a window old as the baseline
+	start := now.Add(-m.config.Window)
+	for len(m.samples) > 1 && !m.samples[1].at.After(start) {
+		m.samples = m.samples[1:]
+	}
+
+	baseline := m.samples[0]
+	elapsed := now.Sub(baseline.at)
+	metadata := map[string]interface{}{
+		"size":     size,
+		"max_rate": m.config.MaxRate,
+	}
+
+	if elapsed < m.config.Window {
+		return StatusHealthy, fmt.Sprintf("Size %d, measuring growth", size), metadata
+	}
+
+	rate := float64(size-baseline.size) / elapsed.Seconds()
+	metadata["rate"] = rate
+
+	if rate > m.config.MaxRate {
+		return StatusUnhealthy, fmt.Sprintf("Size %d growing %.1f/s over %s", size, rate, elapsed), metadata
+	}
+	return StatusHealthy, fmt.Sprintf("Size %d changing %.1f/s over %s", size, rate, elapsed), metadata
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for monitors
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestUtilizationMonitor(t *testing.T) {
+	clock := newFakeClock()
+	utilization := 50.0
+	monitor := NewUtilizationMonitor(func() float64 { return utilization }, UtilizationConfig{
+		HighWaterMark: 80,
+		Sustain:       30 * time.Second,
+	})
+	monitor.now = clock.Now
+
+	c := NewChecker(time.Second)
+	c.Register("buffer", monitor.Check())
+
+	steps := []struct {
+		name        string
+		utilization float64
+		advance     time.Duration
+		want        Status
+	}{
+		{"below the mark", 50, 0, StatusHealthy},
+		{"burst above the mark", 95, 0, StatusHealthy},
+		{"above but not sustained", 95, 20 * time.Second, StatusHealthy},
+		{"sustained above the mark", 90, 15 * time.Second, StatusDegraded},
+		{"still above", 85, time.Minute, StatusDegraded},
+		{"back below", 40, time.Second, StatusHealthy},
+		{"above again restarts the period", 95, time.Second, StatusHealthy},
+	}
+
+	for _, step := range steps {
+		utilization = step.utilization
+		clock.Advance(step.advance)
+
+		if got := c.OverallStatus(context.Background()); got != step.want {
+			t.Errorf("%s: status = %s, want %s", step.name, got, step.want)
+		}
+		result := c.GetLastStatus()["buffer"]
+		if result.Metadata["utilization"] != step.utilization {
+			t.Errorf("%s: metadata utilization = %v, want %v", step.name, result.Metadata["utilization"], step.utilization)
+		}
+	}
+}
+
+func TestGrowthMonitor(t *testing.T) {
+	clock := newFakeClock()
+	var size int64
+	monitor := NewGrowthMonitor(func() int64 { return size }, GrowthConfig{Window: time.Minute, MaxRate: 10})
+	monitor.now = clock.Now
+	check := monitor.Check()
+
+	steps := []struct {
+		name    string
+		size    int64
+		advance time.Duration
+		want    Status
+		rate    interface{}
+	}{
+		{"first sample", 0, 0, StatusHealthy, nil},
+		{"growing within the window", 5000, 30 * time.Second, StatusHealthy, nil},
+		{"growing over the window", 6000, 30 * time.Second, StatusUnhealthy, 100.0},
+		{"growth within the tolerated rate", 6300, time.Minute, StatusHealthy, 5.0},
+		{"draining", 300, time.Minute, StatusHealthy, -100.0},
+		{"growing again", 9300, time.Minute, StatusUnhealthy, 150.0},
+	}
+
+	for _, step := range steps {
+		size = step.size
+		clock.Advance(step.advance)
+
+		result := check(context.Background())
+		if result.Status != step.want {
+			t.Errorf("%s: status = %s (%s), want %s", step.name, result.Status, result.Message, step.want)
+		}
+		if result.Metadata["rate"] != step.rate {
+			t.Errorf("%s: metadata rate = %v, want %v", step.name, result.Metadata["rate"], step.rate)
+		}
+		if result.Metadata["size"] != step.size {
+			t.Errorf("%s: metadata size = %v, want %d", step.name, result.Metadata["size"], step.size)
+		}
+	}
+}
+
+func TestReadinessReflectsPressure(t *testing.T) {
+	clock := newFakeClock()
+	var size int64
+	monitor := NewGrowthMonitor(func() int64 { return size }, GrowthConfig{Window: time.Minute})
+	monitor.now = clock.Now
+
+	c := NewChecker(time.Second)
+	c.Register("dlq", monitor.Check())
+	handler := c.ReadinessHandler()
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Errorf("expected ready before the growth is measured, got %d", code)
+	}
+	size = 100
+	clock.Advance(time.Minute)
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected a growing DLQ to fail readiness, got %d", code)
+	}
+}
@@ -0,0 +1,70 @@
+package input
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// errBodyTooLarge is returned when a decompressed body exceeds the size limit
+	errBodyTooLarge = errors.New("decompressed body exceeds maximum size")
+	// errUnsupportedEncoding is returned for an unknown Content-Encoding
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+// maxZstdWindow is the largest zstd window accepted, the size RFC 8878
+// recommends decoders support. The decoder allocates the window a frame
+// declares before the size limit applies, so it must be bounded.
+const maxZstdWindow = 8 << 20
+
+// decodeBody reads r, decompressing it according to a Content-Encoding
+// header value. The decompressed size is capped at maxSize so a small
+// compressed payload cannot expand without bound, and zstd frames may not
+// declare a window over maxZstdWindow.
+func decodeBody(r io.Reader, encoding string, maxSize int64) ([]byte, error) {
+	var decoded io.Reader
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.ReadAll(r)
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed gzip body: %w", err)
+		}
+		defer gz.Close()
+		decoded = gz
+	case "zstd":
+		zr, err := zstd.NewReader(r,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(maxZstdWindow),
+			zstd.WithDecoderMaxMemory(uint64(max(maxSize, 1))),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("malformed zstd body: %w", err)
+		}
+		defer zr.Close()
+		decoded = zr
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, encoding)
+	}
+
+	// Read one byte past the limit to detect oversized bodies
+	data, err := io.ReadAll(io.LimitReader(decoded, maxSize+1))
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return nil, errBodyTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed %s body: %w", encoding, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+
+	return data, nil
+}
@@ -0,0 +1,308 @@
+package input
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/logpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCConfig holds configuration for gRPC input
+type GRPCConfig struct {
+	// Address to bind to (e.g., "0.0.0.0:4317")
+	Address string
+	// API keys for authentication
+	APIKeys []string
+	// Rate limit per peer (batches per second)
+	RateLimit int
+	// Max received message size (bytes)
+	MaxRecvMsgSize int
+	// TLS configuration
+	TLSEnabled bool
+	TLSCert    string
+	TLSKey     string
+	// Buffer size for events channel
+	BufferSize int
+}
+
+// GRPCInput receives log batches over a bidirectional gRPC stream
+type GRPCInput struct {
+	*BaseInput
+	logpb.UnimplementedLogIngestServer
+	config   *GRPCConfig
+	logger   *logging.Logger
+	server   *grpc.Server
+	listener net.Listener
+	limiters *clientLimiters
+	stats    *grpcStats
+}
+
+// grpcStats tracks gRPC input statistics
+type grpcStats struct {
+	batchesTotal  uint64
+	eventsTotal   uint64
+	errorsTotal   uint64
+	authFailures  uint64
+	rateLimitHits uint64
+}
+
+// NewGRPCInput creates a new gRPC input
+func NewGRPCInput(name string, config *GRPCConfig, logger *logging.Logger) (*GRPCInput, error) {
+	if config.BufferSize == 0 {
+		config.BufferSize = 10000
+	}
+	if config.MaxRecvMsgSize == 0 {
+		config.MaxRecvMsgSize = 10 * 1024 * 1024 // 10MB default
+	}
+
+	input := &GRPCInput{
+		BaseInput: NewBaseInput(name, "grpc", config.BufferSize),
+		config:    config,
+		logger:    logger.WithComponent("input-grpc"),
+		limiters:  newClientLimiters(config.RateLimit, limiterIdleTTL),
+		stats:     &grpcStats{},
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(config.MaxRecvMsgSize),
+		grpc.StreamInterceptor(input.authInterceptor),
+	}
+
+	if config.TLSEnabled {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	input.server = grpc.NewServer(opts...)
+	logpb.RegisterLogIngestServer(input.server, input)
+
+	return input, nil
+}
+
+// Start starts the gRPC receiver
+func (g *GRPCInput) Start() error {
+	listener, err := net.Listen("tcp", g.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", g.config.Address, err)
+	}
+	g.listener = listener
+
+	g.logger.Info().
+		Str("address", listener.Addr().String()).
+		Bool("tls", g.config.TLSEnabled).
+		Msg("gRPC receiver starting")
+
+	// The sweeper runs even without a rate limit, which a reload can enable
+	go g.limiters.run(g.Context(), limiterSweepInterval)
+
+	go func() {
+		if err := g.server.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+			g.logger.Error().Err(err).Msg("gRPC server error")
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the gRPC receiver
+func (g *GRPCInput) Stop() error {
+	g.logger.Info().Msg("Stopping gRPC receiver")
+
+	// Cancel first so open streams return and GracefulStop can finish
+	g.Cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		g.server.Stop()
+	}
+
+	g.Close()
+
+	return nil
+}
+
+// Addr returns the address the receiver is listening on
+func (g *GRPCInput) Addr() string {
+	if g.listener == nil {
+		return g.config.Address
+	}
+	return g.listener.Addr().String()
+}
+
+// Health returns the health status
+func (g *GRPCInput) Health() Health {
+	details := make(map[string]interface{})
+	details["address"] = g.Addr()
+	details["batches_total"] = atomic.LoadUint64(&g.stats.batchesTotal)
+	details["events_total"] = atomic.LoadUint64(&g.stats.eventsTotal)
+	details["errors_total"] = atomic.LoadUint64(&g.stats.errorsTotal)
+	details["auth_failures"] = atomic.LoadUint64(&g.stats.authFailures)
+	details["rate_limit_hits"] = atomic.LoadUint64(&g.stats.rateLimitHits)
+
+	return Health{
+		Status:  HealthStatusHealthy,
+		Message: "gRPC receiver is running",
+		Details: details,
+	}
+}
+
+// Stream receives log batches from a client and acknowledges each one
+func (g *GRPCInput) Stream(stream logpb.LogIngest_StreamServer) error {
+	peerAddr := peerAddress(stream.Context())
+	batches, recvErr := g.receive(stream)
+
+	for {
+		var batch *logpb.LogBatch
+		select {
+		case b, ok := <-batches:
+			if !ok {
+				err := <-recvErr
+				if err == io.EOF || err == context.Canceled || status.Code(err) == codes.Canceled {
+					return nil
+				}
+				atomic.AddUint64(&g.stats.errorsTotal, 1)
+				return err
+			}
+			batch = b
+		case <-g.Context().Done():
+			return status.Error(codes.Unavailable, "input is shutting down")
+		}
+
+		atomic.AddUint64(&g.stats.batchesTotal, 1)
+		ack := &logpb.Ack{BatchId: batch.BatchId}
+
+		if g.limiters.enabled() && !g.limiters.get(peerAddr).Allow() {
+			atomic.AddUint64(&g.stats.rateLimitHits, 1)
+			g.logger.Warn().Str("peer", peerAddr).Msg("Rate limit exceeded")
+			ack.Error = "rate limit exceeded"
+		} else {
+			ack.Accepted = g.acceptBatch(batch, peerAddr)
+			if int(ack.Accepted) < len(batch.Events) {
+				ack.Error = "input is shutting down"
+			}
+		}
+
+		if err := stream.Send(ack); err != nil {
+			atomic.AddUint64(&g.stats.errorsTotal, 1)
+			return err
+		}
+	}
+}
+
+// receive reads batches from the stream in the background so Stream can
+// return on shutdown while a Recv is blocked. The batch channel is closed
+// once Recv fails, after the error has been buffered on the error channel.
+func (g *GRPCInput) receive(stream logpb.LogIngest_StreamServer) (<-chan *logpb.LogBatch, <-chan error) {
+	batches := make(chan *logpb.LogBatch)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(batches)
+		for {
+			batch, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case batches <- batch:
+			case <-stream.Context().Done():
+				errCh <- stream.Context().Err()
+				return
+			}
+		}
+	}()
+
+	return batches, errCh
+}
+
+// acceptBatch converts and forwards a batch, returning how many events were accepted
+func (g *GRPCInput) acceptBatch(batch *logpb.LogBatch, peerAddr string) uint32 {
+	var accepted uint32
+	now := time.Now()
+
+	for _, m := range batch.Events {
+		event := m.ToEvent(now)
+		if event.Source == "" {
+			event.Source = g.name
+		}
+
+		// Add metadata
+		event.Fields["peer"] = peerAddr
+		event.Fields["input_type"] = "grpc"
+
+		if !g.SendEvent(event) {
+			break
+		}
+		accepted++
+	}
+
+	atomic.AddUint64(&g.stats.eventsTotal, uint64(accepted))
+	return accepted
+}
+
+// authInterceptor checks API key authentication on every stream
+func (g *GRPCInput) authInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// If no API keys configured, allow all
+	if len(g.config.APIKeys) == 0 {
+		return handler(srv, ss)
+	}
+
+	// Check API key in metadata
+	var apiKey string
+	if md, ok := metadata.FromIncomingContext(ss.Context()); ok {
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			apiKey = values[0]
+		} else if values := md.Get("authorization"); len(values) > 0 {
+			apiKey = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+
+	// Validate API key
+	valid := false
+	for _, key := range g.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		atomic.AddUint64(&g.stats.authFailures, 1)
+		g.logger.Warn().Str("peer", peerAddress(ss.Context())).Msg("Authentication failed")
+		return status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	return handler(srv, ss)
+}
+
+// peerAddress returns the remote address of a gRPC call
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
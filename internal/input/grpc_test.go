@@ -0,0 +1,164 @@
+package input
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/logpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startGRPCInput starts a gRPC input on a random port and dials it
+func startGRPCInput(t *testing.T, config *GRPCConfig) (*GRPCInput, logpb.LogIngestClient) {
+	t.Helper()
+
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	config.Address = "127.0.0.1:0"
+	inp, err := NewGRPCInput("test-grpc", config, logger)
+	if err != nil {
+		t.Fatalf("failed to create gRPC input: %v", err)
+	}
+	if err := inp.Start(); err != nil {
+		t.Fatalf("failed to start gRPC input: %v", err)
+	}
+	t.Cleanup(func() { inp.Stop() })
+
+	conn, err := grpc.NewClient(inp.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial gRPC input: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return inp, logpb.NewLogIngestClient(conn)
+}
+
+func TestGRPCInput_RoundTrip(t *testing.T) {
+	inp, client := startGRPCInput(t, &GRPCConfig{BufferSize: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Stream(ctx)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	batch := &logpb.LogBatch{
+		BatchId: 7,
+		Events: []*logpb.LogEvent{
+			{TimestampUnixNano: ts.UnixNano(), Message: "first", Level: "info", Fields: map[string]string{"user": "alice"}},
+			{Message: "second", Source: "billing"},
+		},
+	}
+	if err := stream.Send(batch); err != nil {
+		t.Fatalf("failed to send batch: %v", err)
+	}
+
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive ack: %v", err)
+	}
+	if ack.BatchId != 7 || ack.Accepted != 2 || ack.Error != "" {
+		t.Errorf("unexpected ack: %+v", ack)
+	}
+
+	first := <-inp.Events()
+	if first.Message != "first" || !first.Timestamp.Equal(ts) || first.Level != "info" {
+		t.Errorf("unexpected first event: %+v", first)
+	}
+	if first.Source != "test-grpc" || first.Fields["user"] != "alice" || first.Fields["input_type"] != "grpc" {
+		t.Errorf("unexpected first event metadata: %+v", first)
+	}
+
+	second := <-inp.Events()
+	if second.Message != "second" || second.Source != "billing" || second.Timestamp.IsZero() {
+		t.Errorf("unexpected second event: %+v", second)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close stream: %v", err)
+	}
+}
+
+func TestGRPCInput_Auth(t *testing.T) {
+	_, client := startGRPCInput(t, &GRPCConfig{APIKeys: []string{"secret"}})
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		{"missing key", nil, codes.Unauthenticated},
+		{"wrong key", metadata.Pairs("x-api-key", "nope"), codes.Unauthenticated},
+		{"api key header", metadata.Pairs("x-api-key", "secret"), codes.OK},
+		{"bearer token", metadata.Pairs("authorization", "Bearer secret"), codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.md != nil {
+				ctx = metadata.NewOutgoingContext(ctx, tt.md)
+			}
+
+			stream, err := client.Stream(ctx)
+			if err != nil {
+				t.Fatalf("failed to open stream: %v", err)
+			}
+			if err := stream.Send(&logpb.LogBatch{BatchId: 1}); err != nil {
+				t.Fatalf("failed to send batch: %v", err)
+			}
+
+			_, err = stream.Recv()
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("expected code %v, got %v (%v)", tt.wantCode, got, err)
+			}
+		})
+	}
+}
+
+func TestGRPCInput_RateLimit(t *testing.T) {
+	_, client := startGRPCInput(t, &GRPCConfig{RateLimit: 1, BufferSize: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Stream(ctx)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+
+	// Burst is twice the rate, so the third batch is rejected
+	var limited bool
+	for i := uint64(1); i <= 3; i++ {
+		if err := stream.Send(&logpb.LogBatch{BatchId: i, Events: []*logpb.LogEvent{{Message: "m"}}}); err != nil {
+			t.Fatalf("failed to send batch: %v", err)
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive ack: %v", err)
+		}
+		if ack.Error == "rate limit exceeded" {
+			limited = true
+			if ack.Accepted != 0 {
+				t.Errorf("expected no events accepted when limited, got %d", ack.Accepted)
+			}
+		}
+	}
+
+	if !limited {
+		t.Error("expected a batch to be rate limited")
+	}
+}
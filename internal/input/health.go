@@ -0,0 +1,40 @@
+package input
+
+import (
+	"context"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
+)
+
+// HealthCheck adapts an input's Health method to a health.HealthCheck
+func HealthCheck(inp Input) health.HealthCheck {
+	return func(ctx context.Context) health.ComponentHealth {
+		h := inp.Health()
+		return health.ComponentHealth{
+			Status:   toHealthStatus(h.Status),
+			Message:  h.Message,
+			Metadata: h.Details,
+		}
+	}
+}
+
+// RegisterHealthChecks registers a health check for each input with the
+// checker, keyed as "input:<name>"
+func RegisterHealthChecks(checker *health.Checker, inputs []Input) {
+	for _, inp := range inputs {
+		checker.Register("input:"+inp.Name(), HealthCheck(inp))
+	}
+}
+
+// toHealthStatus translates an input health status to a checker status.
+// Unknown statuses are reported as unhealthy.
+func toHealthStatus(status HealthStatus) health.Status {
+	switch status {
+	case HealthStatusHealthy:
+		return health.StatusHealthy
+	case HealthStatusDegraded:
+		return health.StatusDegraded
+	default:
+		return health.StatusUnhealthy
+	}
+}
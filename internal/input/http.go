@@ -4,16 +4,16 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/parser"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
-	"golang.org/x/time/rate"
 )
 
 // HTTPConfig holds configuration for HTTP input
@@ -40,17 +40,19 @@ type HTTPConfig struct {
 	ReadTimeout time.Duration
 	// Write timeout
 	WriteTimeout time.Duration
+	// JSON Schema file events must conform to (optional)
+	SchemaPath string
 }
 
 // HTTPInput receives logs via HTTP API
 type HTTPInput struct {
 	*BaseInput
-	config   *HTTPConfig
-	logger   *logging.Logger
-	server   *http.Server
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	stats    *httpStats
+	config    *HTTPConfig
+	logger    *logging.Logger
+	server    *http.Server
+	limiters  *clientLimiters
+	validator *parser.SchemaValidator
+	stats     *httpStats
 }
 
 // httpStats tracks HTTP input statistics
@@ -60,10 +62,33 @@ type httpStats struct {
 	errorsTotal     uint64
 	authFailures    uint64
 	rateLimitHits   uint64
+	invalidEvents   uint64
 }
 
 // NewHTTPInput creates a new HTTP input
 func NewHTTPInput(name string, config *HTTPConfig, logger *logging.Logger) (*HTTPInput, error) {
+	input := newHTTPReceiver(name, "http", config, logger)
+
+	if config.SchemaPath != "" {
+		validator, err := parser.NewSchemaValidator(config.SchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load validation schema: %w", err)
+		}
+		input.validator = validator
+	}
+
+	// Setup HTTP server
+	mux := http.NewServeMux()
+	mux.HandleFunc(config.Path, input.handleSingleEvent)
+	mux.HandleFunc(config.BatchPath, input.handleBatchEvents)
+	input.setupServer(mux)
+
+	return input, nil
+}
+
+// newHTTPReceiver applies HTTP defaults and builds a receiver without routes,
+// so other HTTP-based inputs can share its auth, rate limit and body limits
+func newHTTPReceiver(name, inputType string, config *HTTPConfig, logger *logging.Logger) *HTTPInput {
 	if config.BufferSize == 0 {
 		config.BufferSize = 10000
 	}
@@ -83,29 +108,27 @@ func NewHTTPInput(name string, config *HTTPConfig, logger *logging.Logger) (*HTT
 		config.WriteTimeout = 30 * time.Second
 	}
 
-	input := &HTTPInput{
-		BaseInput: NewBaseInput(name, "http", config.BufferSize),
+	return &HTTPInput{
+		BaseInput: NewBaseInput(name, inputType, config.BufferSize),
 		config:    config,
-		logger:    logger.WithComponent("input-http"),
-		limiters:  make(map[string]*rate.Limiter),
+		logger:    logger.WithComponent("input-" + inputType),
+		limiters:  newClientLimiters(config.RateLimit, limiterIdleTTL),
 		stats:     &httpStats{},
 	}
+}
 
-	// Setup HTTP server
-	mux := http.NewServeMux()
-	mux.HandleFunc(config.Path, input.handleSingleEvent)
-	mux.HandleFunc(config.BatchPath, input.handleBatchEvents)
-	mux.HandleFunc("/health", input.handleHealth)
-	mux.HandleFunc("/metrics", input.handleMetrics)
-
-	input.server = &http.Server{
-		Addr:         config.Address,
-		Handler:      input.authMiddleware(input.rateLimitMiddleware(mux)),
-		ReadTimeout:  config.ReadTimeout,
-		WriteTimeout: config.WriteTimeout,
+// setupServer adds the health and metrics endpoints to mux and wraps it in
+// the auth and rate limit middleware
+func (h *HTTPInput) setupServer(mux *http.ServeMux) {
+	mux.HandleFunc("/health", h.handleHealth)
+	mux.HandleFunc("/metrics", h.handleMetrics)
+
+	h.server = &http.Server{
+		Addr:         h.config.Address,
+		Handler:      h.authMiddleware(h.rateLimitMiddleware(mux)),
+		ReadTimeout:  h.config.ReadTimeout,
+		WriteTimeout: h.config.WriteTimeout,
 	}
-
-	return input, nil
 }
 
 // Start starts the HTTP receiver
@@ -116,6 +139,9 @@ func (h *HTTPInput) Start() error {
 		Str("batch_path", h.config.BatchPath).
 		Msg("HTTP receiver starting")
 
+	// The sweeper runs even without a rate limit, which a reload can enable
+	go h.limiters.run(h.Context(), limiterSweepInterval)
+
 	go func() {
 		var err error
 		if h.config.TLSEnabled {
@@ -210,6 +236,11 @@ func (h *HTTPInput) authMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// SetRateLimit changes the requests allowed per second per client
+func (h *HTTPInput) SetRateLimit(perSecond int) {
+	h.limiters.setLimit(perSecond)
+}
+
 // rateLimitMiddleware applies rate limiting
 func (h *HTTPInput) rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -219,8 +250,8 @@ func (h *HTTPInput) rateLimitMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if h.config.RateLimit > 0 {
-			limiter := h.getRateLimiter(r.RemoteAddr)
+		if h.limiters.enabled() {
+			limiter := h.limiters.get(r.RemoteAddr)
 			if !limiter.Allow() {
 				atomic.AddUint64(&h.stats.rateLimitHits, 1)
 				h.logger.Warn().Str("remote_addr", r.RemoteAddr).Msg("Rate limit exceeded")
@@ -242,17 +273,19 @@ func (h *HTTPInput) handleSingleEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Limit request body size
-	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodySize)
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		atomic.AddUint64(&h.stats.errorsTotal, 1)
-		h.logger.Error().Err(err).Msg("Failed to read request body")
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	body, ok := h.readBody(w, r)
+	if !ok {
 		return
 	}
 
+	// Reject events that don't conform to the schema
+	if h.validator != nil {
+		if err := h.validator.ValidateJSON(body); err != nil {
+			h.rejectInvalid(w, err)
+			return
+		}
+	}
+
 	// Try to parse as JSON
 	var data map[string]interface{}
 	if err := json.Unmarshal(body, &data); err != nil {
@@ -267,17 +300,23 @@ func (h *HTTPInput) handleSingleEvent(w http.ResponseWriter, r *http.Request) {
 		Timestamp: time.Now(),
 		Message:   fmt.Sprintf("%v", data["message"]),
 		Source:    h.name,
-		Fields:    data,
+		Fields:    stringifyFields(data),
 		Raw:       string(body),
 	}
 
 	// Add metadata
+	eventID := uuid.NewString()
+	event.Fields["event_id"] = eventID
 	event.Fields["remote_addr"] = r.RemoteAddr
 	event.Fields["user_agent"] = r.UserAgent()
 	event.Fields["input_type"] = "http"
 
 	// Send event
-	if !h.SendEvent(event) {
+	if err := h.TrySendEvent(event); err != nil {
+		if errors.Is(err, ErrBufferFull) {
+			h.rejectBufferFull(w, 1)
+			return
+		}
 		atomic.AddUint64(&h.stats.errorsTotal, 1)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -287,7 +326,8 @@ func (h *HTTPInput) handleSingleEvent(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "accepted",
+		"status":   "accepted",
+		"event_id": eventID,
 	})
 }
 
@@ -300,14 +340,8 @@ func (h *HTTPInput) handleBatchEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Limit request body size
-	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodySize)
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		atomic.AddUint64(&h.stats.errorsTotal, 1)
-		h.logger.Error().Err(err).Msg("Failed to read request body")
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	body, ok := h.readBody(w, r)
+	if !ok {
 		return
 	}
 
@@ -320,37 +354,138 @@ func (h *HTTPInput) handleBatchEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Process each event
+	// Process each event, recording IDs in input order (nil when rejected)
 	accepted := 0
-	for _, data := range events {
+	bufferFull := 0
+	eventIDs := make([]*string, len(events))
+	var invalid []validationFailure
+	for i, data := range events {
+		// Skip events that don't conform to the schema
+		if h.validator != nil {
+			if err := h.validator.Validate(data); err != nil {
+				invalid = append(invalid, validationFailure{Index: i, Error: err.Error()})
+				continue
+			}
+		}
+
 		event := &types.LogEvent{
 			Timestamp: time.Now(),
 			Message:   fmt.Sprintf("%v", data["message"]),
 			Source:    h.name,
-			Fields:    data,
+			Fields:    stringifyFields(data),
 		}
 
 		// Add metadata
+		eventID := uuid.NewString()
+		event.Fields["event_id"] = eventID
 		event.Fields["remote_addr"] = r.RemoteAddr
 		event.Fields["user_agent"] = r.UserAgent()
 		event.Fields["input_type"] = "http"
-		event.Fields["batch"] = true
+		event.Fields["batch"] = "true"
 
-		if h.SendEvent(event) {
+		switch err := h.TrySendEvent(event); {
+		case err == nil:
+			eventIDs[i] = &eventID
 			accepted++
+		case errors.Is(err, ErrBufferFull):
+			bufferFull++
 		}
 	}
 
 	atomic.AddUint64(&h.stats.eventsTotal, uint64(accepted))
+	if len(invalid) > 0 {
+		h.recordInvalid(len(invalid))
+		h.logger.Warn().Int("rejected", len(invalid)).Str("error", invalid[0].Error).Msg("Batch events failed schema validation")
+	}
+
+	if bufferFull > 0 {
+		if accepted == 0 {
+			h.rejectBufferFull(w, bufferFull)
+			return
+		}
+		// Partially accepted: still ask the client to slow down
+		w.Header().Set("Retry-After", retryAfterSeconds)
+	}
+
+	response := map[string]interface{}{
+		"status":    "accepted",
+		"accepted":  accepted,
+		"total":     len(events),
+		"event_ids": eventIDs,
+	}
+	if len(invalid) > 0 {
+		response["rejected"] = len(invalid)
+		response["errors"] = invalid
+	}
 
 	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// validationFailure records why an event in a batch was rejected
+type validationFailure struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// rejectInvalid responds 422 with the reason an event failed validation
+func (h *HTTPInput) rejectInvalid(w http.ResponseWriter, err error) {
+	h.recordInvalid(1)
+	h.logger.Warn().Err(err).Msg("Event failed schema validation")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":   "accepted",
-		"accepted": accepted,
-		"total":    len(events),
+		"status": "rejected",
+		"error":  err.Error(),
 	})
 }
 
+// recordInvalid counts events rejected by schema validation
+func (h *HTTPInput) recordInvalid(rejected int) {
+	atomic.AddUint64(&h.stats.invalidEvents, uint64(rejected))
+	h.RecordDropped(DropReasonValidation, rejected)
+}
+
+// retryAfterSeconds is the Retry-After hint sent when the buffer is full
+const retryAfterSeconds = "1"
+
+// rejectBufferFull responds 503 with Retry-After so clients back off while
+// the events buffer drains. TrySendEvent already counted the dropped events.
+func (h *HTTPInput) rejectBufferFull(w http.ResponseWriter, dropped int) {
+	h.logger.Warn().Int("dropped", dropped).Msg("Event buffer full, rejecting request")
+
+	w.Header().Set("Retry-After", retryAfterSeconds)
+	http.Error(w, "Service Unavailable: event buffer full", http.StatusServiceUnavailable)
+}
+
+// readBody reads the request body, decompressing it according to
+// Content-Encoding. MaxBodySize applies to both the compressed and the
+// decompressed size. On failure it writes the error response and returns false.
+func (h *HTTPInput) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	// Limit request body size
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodySize)
+
+	body, err := decodeBody(r.Body, r.Header.Get("Content-Encoding"), h.config.MaxBodySize)
+	if err != nil {
+		atomic.AddUint64(&h.stats.errorsTotal, 1)
+		h.logger.Error().Err(err).Msg("Failed to read request body")
+
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr), errors.Is(err, errBodyTooLarge):
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errUnsupportedEncoding):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		}
+		return nil, false
+	}
+
+	return body, true
+}
+
 // handleHealth handles health check endpoint
 func (h *HTTPInput) handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := h.Health()
@@ -366,43 +501,9 @@ func (h *HTTPInput) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		"errors_total":      atomic.LoadUint64(&h.stats.errorsTotal),
 		"auth_failures":     atomic.LoadUint64(&h.stats.authFailures),
 		"rate_limit_hits":   atomic.LoadUint64(&h.stats.rateLimitHits),
+		"invalid_events":    atomic.LoadUint64(&h.stats.invalidEvents),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
-
-// getRateLimiter gets or creates a rate limiter for a client
-func (h *HTTPInput) getRateLimiter(remoteAddr string) *rate.Limiter {
-	h.mu.RLock()
-	limiter, exists := h.limiters[remoteAddr]
-	h.mu.RUnlock()
-
-	if !exists {
-		// Create new rate limiter: RateLimit requests per second
-		limiter = rate.NewLimiter(rate.Limit(h.config.RateLimit), h.config.RateLimit*2)
-		h.mu.Lock()
-		h.limiters[remoteAddr] = limiter
-		h.mu.Unlock()
-
-		// Clean up old limiters
-		go h.cleanupLimiter(remoteAddr)
-	}
-
-	return limiter
-}
-
-// cleanupLimiter removes inactive rate limiters
-func (h *HTTPInput) cleanupLimiter(remoteAddr string) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	select {
-	case <-ticker.C:
-		h.mu.Lock()
-		delete(h.limiters, remoteAddr)
-		h.mu.Unlock()
-	case <-h.Context().Done():
-		return
-	}
-}
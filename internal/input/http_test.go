@@ -2,13 +2,21 @@ package input
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 func TestHTTPInput(t *testing.T) {
@@ -263,3 +271,495 @@ func TestHTTPInput(t *testing.T) {
 		}
 	})
 }
+
+// gzipBody compresses data with gzip
+func gzipBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("failed to gzip body: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to gzip body: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestHTTPInput_CompressedBodies(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	t.Run("GzipSingleEvent", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+		body := gzipBody(t, []byte(`{"message":"compressed single"}`))
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		if event := <-input.Events(); event.Message != "compressed single" {
+			t.Errorf("expected message 'compressed single', got '%s'", event.Message)
+		}
+	})
+
+	t.Run("GzipBatchEvents", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+		body := gzipBody(t, []byte(`[{"message":"a"},{"message":"b"}]`))
+		req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		input.handleBatchEvents(w, req)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		for _, want := range []string{"a", "b"} {
+			if event := <-input.Events(); event.Message != want {
+				t.Errorf("expected message '%s', got '%s'", want, event.Message)
+			}
+		}
+	})
+
+	t.Run("ZstdSingleEvent", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+		enc, _ := zstd.NewWriter(nil)
+		body := enc.EncodeAll([]byte(`{"message":"zstd single"}`), nil)
+		enc.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "zstd")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		if event := <-input.Events(); event.Message != "zstd single" {
+			t.Errorf("expected message 'zstd single', got '%s'", event.Message)
+		}
+	})
+
+	t.Run("MalformedGzip", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte("not gzip")))
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("DecompressedSizeLimit", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10, MaxBodySize: 1024}, logger)
+
+		// Highly compressible payload well over the limit once inflated
+		body := gzipBody(t, bytes.Repeat([]byte("a"), 64*1024))
+		if len(body) >= 1024 {
+			t.Fatalf("expected compressed body under the limit, got %d bytes", len(body))
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+
+	t.Run("ZstdDecompressedSizeLimit", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10, MaxBodySize: 1024}, logger)
+
+		enc, _ := zstd.NewWriter(nil)
+		body := enc.EncodeAll(bytes.Repeat([]byte("a"), 64*1024), nil)
+		enc.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "zstd")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+
+	t.Run("ZstdOversizedWindow", func(t *testing.T) {
+		input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+		// A frame declaring a 512 MiB window around a one byte raw block
+		body := []byte{
+			0x28, 0xb5, 0x2f, 0xfd, // magic number
+			0x00,             // frame header descriptor: no content size
+			19 << 3,          // window descriptor: 1 << (10 + 19) bytes
+			0x09, 0x00, 0x00, // last block, raw, one byte
+			'a',
+		}
+		if _, err := decodeBody(bytes.NewReader(body), "zstd", 1024); !errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			t.Errorf("expected the window to be rejected, got %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "zstd")
+		w := httptest.NewRecorder()
+
+		input.handleSingleEvent(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestHTTPInput_RateLimiterBounded(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", RateLimit: 1000, BufferSize: 2000}, logger)
+
+	// Many short-lived connections from one IP, each on a new ephemeral port
+	for port := 10000; port < 11000; port++ {
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"m"}`)))
+		req.RemoteAddr = "10.0.0.1:" + strconv.Itoa(port)
+		w := httptest.NewRecorder()
+		input.server.Handler.ServeHTTP(w, req)
+	}
+
+	if n := input.limiters.len(); n != 1 {
+		t.Errorf("expected one limiter for a single client IP, got %d", n)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"m"}`)))
+	req.RemoteAddr = "10.0.0.2:10000"
+	input.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	if n := input.limiters.len(); n != 2 {
+		t.Errorf("expected a limiter per client IP, got %d", n)
+	}
+
+	// Idle limiters are evicted once they outlive the TTL
+	now := time.Now()
+	input.limiters.now = func() time.Time { return now.Add(limiterIdleTTL + time.Second) }
+	input.limiters.sweep()
+	if n := input.limiters.len(); n != 0 {
+		t.Errorf("expected idle limiters to be evicted, got %d", n)
+	}
+}
+
+func TestHTTPInput_SetRateLimit(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"m"}`)))
+		req.RemoteAddr = "10.0.0.1:10000"
+		w := httptest.NewRecorder()
+		input.server.Handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Without a rate limit every request is accepted
+	for i := 0; i < 5; i++ {
+		if code := send(); code == http.StatusTooManyRequests {
+			t.Fatalf("request %d rate limited without a limit", i)
+		}
+	}
+
+	// A limit of 1 per second allows a burst of 2
+	input.SetRateLimit(1)
+	var limited int
+	for i := 0; i < 5; i++ {
+		if send() == http.StatusTooManyRequests {
+			limited++
+		}
+	}
+	if limited != 3 {
+		t.Errorf("expected 3 rate limited requests, got %d", limited)
+	}
+
+	// Raising the limit relaxes throttling for the same client
+	input.SetRateLimit(1000)
+	time.Sleep(20 * time.Millisecond)
+	if code := send(); code == http.StatusTooManyRequests {
+		t.Error("expected requests to be accepted after raising the limit")
+	}
+
+	// Disabling the limit applies to clients that already have a limiter
+	input.SetRateLimit(0)
+	if code := send(); code == http.StatusTooManyRequests {
+		t.Error("expected requests to be accepted after disabling the limit")
+	}
+}
+
+func TestHTTPInput_EventIDs(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+	seen := make(map[string]bool)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"single"}`)))
+		w := httptest.NewRecorder()
+		input.handleSingleEvent(w, req)
+
+		var resp struct {
+			EventID string `json:"event_id"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.EventID == "" || seen[resp.EventID] {
+			t.Errorf("expected a new unique event ID, got %q", resp.EventID)
+		}
+		seen[resp.EventID] = true
+
+		if event := <-input.Events(); event.Fields["event_id"] != resp.EventID {
+			t.Errorf("expected event field event_id %q, got %q", resp.EventID, event.Fields["event_id"])
+		}
+	}
+
+	body, _ := json.Marshal([]map[string]interface{}{{"message": "a"}, {"message": "b"}, {"message": "c"}})
+	req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	input.handleBatchEvents(w, req)
+
+	var resp struct {
+		Accepted int       `json:"accepted"`
+		EventIDs []*string `json:"event_ids"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.EventIDs) != resp.Accepted || resp.Accepted != 3 {
+		t.Fatalf("expected 3 IDs for 3 accepted events, got %d IDs and %d accepted", len(resp.EventIDs), resp.Accepted)
+	}
+	for i, id := range resp.EventIDs {
+		if id == nil || seen[*id] {
+			t.Errorf("expected unique ID at index %d, got %v", i, id)
+			continue
+		}
+		seen[*id] = true
+
+		if event := <-input.Events(); event.Fields["event_id"] != *id {
+			t.Errorf("expected IDs aligned with input order at index %d", i)
+		}
+	}
+
+	// Rejected entries are reported as null
+	input.Cancel()
+	req = httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	input.handleBatchEvents(w, req)
+
+	resp.EventIDs = nil
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Accepted != 0 || len(resp.EventIDs) != 3 || resp.EventIDs[0] != nil {
+		t.Errorf("expected null IDs for rejected entries, got %d accepted and %v", resp.Accepted, resp.EventIDs)
+	}
+}
+
+func TestHTTPInput_BufferFull(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, _ := NewHTTPInput("test-http-full", &HTTPConfig{Address: "localhost:0", BufferSize: 1}, logger)
+	dropped := metrics.GetGlobalCollector().InputEventsDropped.WithLabelValues("test-http-full", "http", "buffer_full")
+	before := testutil.ToFloat64(dropped)
+
+	// First event fills the buffer
+	req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"first"}`)))
+	w := httptest.NewRecorder()
+	input.handleSingleEvent(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(`{"message":"second"}`)))
+	w = httptest.NewRecorder()
+	input.handleSingleEvent(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader([]byte(`[{"message":"a"},{"message":"b"}]`)))
+	w = httptest.NewRecorder()
+	input.handleBatchEvents(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After for batch, got %d", w.Code)
+	}
+
+	if got := testutil.ToFloat64(dropped) - before; got != 3 {
+		t.Errorf("expected 3 buffer_full drops, got %v", got)
+	}
+}
+
+func TestHTTPInput_NestedFields(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, _ := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", BufferSize: 10}, logger)
+
+	body := []byte(`{"message":"failed","error":{"code":500},"tags":["a","b"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	input.handleSingleEvent(w, req)
+
+	event := <-input.Events()
+	if event.Fields["error"] != `{"code":500}` {
+		t.Errorf("expected nested object kept as JSON, got %q", event.Fields["error"])
+	}
+	if event.Fields["tags"] != `["a","b"]` {
+		t.Errorf("expected array kept as JSON, got %q", event.Fields["tags"])
+	}
+}
+
+func TestHTTPInput_SchemaValidation(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	schemaPath := filepath.Join(t.TempDir(), "event.schema.json")
+	schema := `{
+  "type": "object",
+  "required": ["message", "level"],
+  "properties": {
+    "message": {"type": "string"},
+    "level": {"type": "string"},
+    "status": {"type": "integer"}
+  }
+}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	input, err := NewHTTPInput("test-http-schema", &HTTPConfig{Address: "localhost:0", BufferSize: 10, SchemaPath: schemaPath}, logger)
+	if err != nil {
+		t.Fatalf("NewHTTPInput() error = %v", err)
+	}
+	dropped := metrics.GetGlobalCollector().InputEventsDropped.WithLabelValues("test-http-schema", "http", "validation")
+	before := testutil.ToFloat64(dropped)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"conforming", `{"message":"ok","level":"info","status":200}`, http.StatusAccepted},
+		{"missing required field", `{"message":"no level"}`, http.StatusUnprocessableEntity},
+		{"type mismatch", `{"message":"bad status","level":"info","status":"ok"}`, http.StatusUnprocessableEntity},
+		{"not JSON", `plain text`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/log", bytes.NewReader([]byte(tt.body)))
+			w := httptest.NewRecorder()
+			input.handleSingleEvent(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusUnprocessableEntity {
+				var resp struct {
+					Status string `json:"status"`
+					Error  string `json:"error"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Status != "rejected" || resp.Error == "" {
+					t.Errorf("expected the validation error in the response, got %+v", resp)
+				}
+			}
+		})
+	}
+
+	if event := <-input.Events(); event.Message != "ok" {
+		t.Errorf("expected only the conforming event, got %q", event.Message)
+	}
+
+	// Batch mode skips invalid events and reports why
+	body := `[{"message":"a","level":"info"},{"message":"b"},{"message":"c","level":"warn","status":"x"}]`
+	req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	input.handleBatchEvents(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	var resp struct {
+		Accepted int       `json:"accepted"`
+		Rejected int       `json:"rejected"`
+		EventIDs []*string `json:"event_ids"`
+		Errors   []struct {
+			Index int    `json:"index"`
+			Error string `json:"error"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Accepted != 1 || resp.Rejected != 2 || len(resp.Errors) != 2 {
+		t.Fatalf("expected 1 accepted and 2 rejected, got %+v", resp)
+	}
+	if resp.Errors[0].Index != 1 || resp.Errors[1].Index != 2 || resp.Errors[0].Error == "" {
+		t.Errorf("unexpected validation errors: %+v", resp.Errors)
+	}
+	if resp.EventIDs[0] == nil || resp.EventIDs[1] != nil || resp.EventIDs[2] != nil {
+		t.Errorf("expected an event ID only for the valid event, got %v", resp.EventIDs)
+	}
+
+	if got := testutil.ToFloat64(dropped) - before; got != 5 {
+		t.Errorf("expected 5 validation drops, got %v", got)
+	}
+}
+
+func TestNewHTTPInput_InvalidSchema(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	_, err := NewHTTPInput("test-http", &HTTPConfig{Address: "localhost:0", SchemaPath: filepath.Join(t.TempDir(), "missing.json")}, logger)
+	if err == nil {
+		t.Error("expected error for a missing schema file")
+	}
+}
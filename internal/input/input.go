@@ -2,7 +2,12 @@ package input
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -27,6 +32,14 @@ type Input interface {
 	Health() Health
 }
 
+// RateLimitUpdater is implemented by inputs whose per-client rate limit can
+// change while they are running
+type RateLimitUpdater interface {
+	// SetRateLimit sets the events or requests allowed per second per client;
+	// zero disables rate limiting
+	SetRateLimit(perSecond int)
+}
+
 // Health represents the health status of an input
 type Health struct {
 	Status  HealthStatus `json:"status"`
@@ -43,6 +56,30 @@ const (
 	HealthStatusUnhealthy HealthStatus = "unhealthy"
 )
 
+var (
+	// ErrBufferFull is returned by TrySendEvent when the events channel is full
+	ErrBufferFull = errors.New("input buffer is full")
+	// ErrInputStopped is returned by TrySendEvent once the input is cancelled
+	ErrInputStopped = errors.New("input is stopped")
+)
+
+// Reasons an input dropped events, the reason label of the input's dropped
+// events metric
+const (
+	// DropReasonBufferFull means the events channel was full
+	DropReasonBufferFull = "buffer_full"
+
+	// DropReasonStopped means the input was stopped before the event was sent
+	DropReasonStopped = "stopped"
+
+	// DropReasonValidation means the event failed schema validation
+	DropReasonValidation = "validation"
+
+	// DropReasonOversize means the event exceeded the size limit under the
+	// drop policy
+	DropReasonOversize = "oversize"
+)
+
 // BaseInput provides common functionality for all inputs
 type BaseInput struct {
 	ctx      context.Context
@@ -50,9 +87,12 @@ type BaseInput struct {
 	eventCh  chan *types.LogEvent
 	name     string
 	inputType string
+	collector *metrics.Collector
+	limit     SizeLimit
 }
 
-// NewBaseInput creates a new BaseInput
+// NewBaseInput creates a new BaseInput recording its throughput in the
+// global metrics collector
 func NewBaseInput(name, inputType string, bufferSize int) *BaseInput {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &BaseInput{
@@ -61,7 +101,61 @@ func NewBaseInput(name, inputType string, bufferSize int) *BaseInput {
 		eventCh:   make(chan *types.LogEvent, bufferSize),
 		name:      name,
 		inputType: inputType,
+		collector: metrics.GetGlobalCollector(),
+	}
+}
+
+// SetCollector sets the collector recording the input's throughput. It
+// must be called before the input starts.
+func (b *BaseInput) SetCollector(collector *metrics.Collector) {
+	b.collector = collector
+}
+
+// SetSizeLimit sets the limit events are truncated or dropped over. It must
+// be called before the input starts.
+func (b *BaseInput) SetSizeLimit(limit SizeLimit) {
+	b.limit = limit
+}
+
+// MaxLineBytes returns the size lines are cut at by line-oriented readers:
+// the configured size, else the size limit, else DefaultMaxLineBytes
+func (b *BaseInput) MaxLineBytes(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	if b.limit.MaxBytes > 0 {
+		return b.limit.MaxBytes
+	}
+	return DefaultMaxLineBytes
+}
+
+// applyLimit enforces the size limit on an event, counting it as truncated
+// or dropped. It reports false if the event is dropped.
+func (b *BaseInput) applyLimit(event *types.LogEvent, cut bool) bool {
+	kept, truncated := b.limit.Apply(event, cut)
+	if !kept {
+		b.RecordDropped(DropReasonOversize, 1)
+		return false
+	}
+	if truncated {
+		b.collector.InputEventsTruncated.WithLabelValues(b.name, b.inputType).Inc()
+	}
+	return true
+}
+
+// recordReceived counts an event handed to the events channel
+func (b *BaseInput) recordReceived(event *types.LogEvent) {
+	size := len(event.Raw)
+	if size == 0 {
+		size = len(event.Message)
 	}
+	b.collector.InputEventsReceived.WithLabelValues(b.name, b.inputType).Inc()
+	b.collector.InputBytesReceived.WithLabelValues(b.name, b.inputType).Add(float64(size))
+}
+
+// RecordDropped counts events the input dropped for a reason
+func (b *BaseInput) RecordDropped(reason string, n int) {
+	b.collector.InputEventsDropped.WithLabelValues(b.name, b.inputType, reason).Add(float64(n))
 }
 
 // Name returns the name of the input
@@ -89,17 +183,87 @@ func (b *BaseInput) Cancel() {
 	b.cancel()
 }
 
-// SendEvent sends an event to the channel
+// SendEvent sends an event to the channel, starting the event's trace.
+// Events over the size limit are truncated or dropped, per its policy; a
+// dropped event still reports true, as the input is running.
 func (b *BaseInput) SendEvent(event *types.LogEvent) bool {
+	return b.SendLine(event, false)
+}
+
+// SendLine sends the event of a line read by a LineScanner like SendEvent.
+// cut reports that the scanner cut the line, which is then handled as over
+// the size limit.
+func (b *BaseInput) SendLine(event *types.LogEvent, cut bool) bool {
+	// Check cancellation first so a cancelled input never races a send
+	// against a closed channel
+	if b.ctx.Err() != nil {
+		b.RecordDropped(DropReasonStopped, 1)
+		return false
+	}
+
+	if !b.applyLimit(event, cut) {
+		return true
+	}
+
+	span := tracing.StartEvent(event, b.name, b.inputType)
 	select {
 	case b.eventCh <- event:
+		span.End()
+		b.recordReceived(event)
 		return true
 	case <-b.ctx.Done():
+		tracing.EndSpan(span, ErrInputStopped)
+		b.RecordDropped(DropReasonStopped, 1)
 		return false
 	}
 }
 
+// TrySendEvent sends an event without blocking. It returns ErrBufferFull
+// when the events channel is full so callers can apply backpressure; the
+// event is counted as dropped either way. Events over the size limit are
+// handled as by SendEvent.
+func (b *BaseInput) TrySendEvent(event *types.LogEvent) error {
+	if b.ctx.Err() != nil {
+		b.RecordDropped(DropReasonStopped, 1)
+		return ErrInputStopped
+	}
+
+	if !b.applyLimit(event, false) {
+		return nil
+	}
+
+	span := tracing.StartEvent(event, b.name, b.inputType)
+	select {
+	case b.eventCh <- event:
+		span.End()
+		b.recordReceived(event)
+		return nil
+	default:
+		tracing.EndSpan(span, ErrBufferFull)
+		b.RecordDropped(DropReasonBufferFull, 1)
+		return ErrBufferFull
+	}
+}
+
 // Close closes the event channel
 func (b *BaseInput) Close() {
 	close(b.eventCh)
 }
+
+// stringifyFields converts decoded JSON values into the flat string map
+// carried by LogEvent.Fields. Nested objects and arrays are kept as JSON so
+// the flatten transformer can expand them later.
+func stringifyFields(data map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			if encoded, err := json.Marshal(v); err == nil {
+				fields[k] = string(encoded)
+				continue
+			}
+		}
+		fields[k] = fmt.Sprintf("%v", v)
+	}
+	return fields
+}
@@ -1,9 +1,21 @@
 package input
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -68,6 +80,160 @@ func TestBaseInput(t *testing.T) {
 	})
 }
 
+func TestBaseInputMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	inp := &fakeInput{BaseInput: NewBaseInput("fake", "test", 1)}
+	inp.SetCollector(collector)
+
+	received := func() float64 {
+		return testutil.ToFloat64(collector.InputEventsReceived.WithLabelValues("fake", "test"))
+	}
+	bytesReceived := func() float64 {
+		return testutil.ToFloat64(collector.InputBytesReceived.WithLabelValues("fake", "test"))
+	}
+	dropped := func(reason string) float64 {
+		return testutil.ToFloat64(collector.InputEventsDropped.WithLabelValues("fake", "test", reason))
+	}
+
+	// Raw lines are measured over the parsed message
+	if !inp.SendEvent(&types.LogEvent{Message: "hello", Raw: `{"msg":"hello"}`}) {
+		t.Fatal("SendEvent() failed")
+	}
+	if received() != 1 || bytesReceived() != 15 {
+		t.Errorf("expected 1 event and 15 bytes received, got %v and %v", received(), bytesReceived())
+	}
+
+	// The buffer holds one event, so the next one is dropped
+	if err := inp.TrySendEvent(&types.LogEvent{Message: "full"}); err != ErrBufferFull {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+	<-inp.Events()
+	if err := inp.TrySendEvent(&types.LogEvent{Message: "world"}); err != nil {
+		t.Fatalf("TrySendEvent() error = %v", err)
+	}
+	if received() != 2 || bytesReceived() != 20 || dropped(DropReasonBufferFull) != 1 {
+		t.Errorf("expected 2 events, 20 bytes and 1 buffer_full drop, got %v, %v and %v",
+			received(), bytesReceived(), dropped(DropReasonBufferFull))
+	}
+
+	inp.Cancel()
+	if inp.SendEvent(&types.LogEvent{Message: "late"}) {
+		t.Fatal("expected SendEvent() to fail after cancel")
+	}
+	inp.RecordDropped(DropReasonValidation, 3)
+	if dropped(DropReasonStopped) != 1 || dropped(DropReasonValidation) != 3 || received() != 2 {
+		t.Errorf("expected 1 stopped and 3 validation drops, got %v and %v", dropped(DropReasonStopped), dropped(DropReasonValidation))
+	}
+}
+
+func TestBaseInputSizeLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		wantMessage string
+		wantDropped float64
+	}{
+		{name: "truncate", policy: OversizeTruncate, wantMessage: "hell"},
+		{name: "default truncates", wantMessage: "hell"},
+		{name: "drop", policy: OversizeDrop, wantDropped: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := metrics.NewCollector()
+			inp := &fakeInput{BaseInput: NewBaseInput("fake", "test", 10)}
+			inp.SetCollector(collector)
+			inp.SetSizeLimit(SizeLimit{MaxBytes: 5, Policy: tt.policy})
+
+			// Events within the limit, newline aside, are left alone
+			if !inp.SendEvent(&types.LogEvent{Message: "fits!\n"}) {
+				t.Fatal("SendEvent() failed")
+			}
+			if event := <-inp.Events(); event.Fields[TruncatedField] != "" {
+				t.Errorf("expected an event within the limit left alone, got %+v", event)
+			}
+
+			// The cut does not split the two-byte character at the limit
+			if !inp.SendEvent(&types.LogEvent{Message: "hellé wörld", Raw: "hellé wörld"}) {
+				t.Fatal("SendEvent() failed")
+			}
+			if err := inp.TrySendEvent(&types.LogEvent{Message: "hellé wörld"}); err != nil {
+				t.Fatalf("TrySendEvent() error = %v", err)
+			}
+
+			dropped := testutil.ToFloat64(collector.InputEventsDropped.WithLabelValues("fake", "test", DropReasonOversize))
+			if dropped != 2*tt.wantDropped {
+				t.Errorf("oversize drops = %v, want %v", dropped, 2*tt.wantDropped)
+			}
+			truncated := testutil.ToFloat64(collector.InputEventsTruncated.WithLabelValues("fake", "test"))
+			if wantTruncated := 2 - 2*tt.wantDropped; truncated != wantTruncated {
+				t.Errorf("truncated events = %v, want %v", truncated, wantTruncated)
+			}
+			if tt.wantDropped > 0 {
+				if len(inp.Events()) != 0 {
+					t.Errorf("expected the oversized events dropped, got %d", len(inp.Events()))
+				}
+				return
+			}
+			for i := 0; i < 2; i++ {
+				event := <-inp.Events()
+				if event.Message != tt.wantMessage || event.Fields[TruncatedField] != "true" {
+					t.Errorf("expected a truncated event, got %q with fields %v", event.Message, event.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestLineScanner(t *testing.T) {
+	long := strings.Repeat("x", DefaultMaxLineBytes+100)
+	input := "first\n" + long + "\nlast\n"
+
+	// bufio.Scanner gives up on the long line
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	for scanner.Scan() {
+	}
+	if !errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		t.Fatalf("expected bufio.ErrTooLong, got %v", scanner.Err())
+	}
+
+	lines := NewLineScanner(strings.NewReader(input), 0)
+	var got []string
+	var cut []bool
+	for lines.Scan() {
+		got = append(got, lines.Text())
+		cut = append(cut, lines.Cut())
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(got) != 3 || got[0] != "first" || got[1] != long[:DefaultMaxLineBytes] || got[2] != "last" {
+		t.Fatalf("expected the long line cut between the others, got %d lines", len(got))
+	}
+	if cut[0] || !cut[1] || cut[2] {
+		t.Errorf("expected only the long line reported cut, got %v", cut)
+	}
+
+	// The configured maximum applies, and a cut last line ends the input
+	lines = NewLineScanner(strings.NewReader("0123456789"), 4)
+	if !lines.Scan() || lines.Text() != "0123" || !lines.Cut() || lines.Scan() {
+		t.Errorf("expected one cut line 0123, got %q", lines.Text())
+	}
+
+	// The line after a cut line is returned without waiting for more input,
+	// as on a connection that stays open
+	r, w := io.Pipe()
+	defer w.Close()
+	go w.Write([]byte("0123456789\nnext\n"))
+	lines = NewLineScanner(r, 4)
+	if !lines.Scan() || !lines.Cut() {
+		t.Fatalf("expected the long line cut, got %q", lines.Text())
+	}
+	if !lines.Scan() || lines.Text() != "next" {
+		t.Errorf("expected the next line, got %q", lines.Text())
+	}
+}
+
 func TestHealthStatus(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -92,3 +258,61 @@ func TestHealthStatus(t *testing.T) {
 		})
 	}
 }
+
+// fakeInput is a minimal Input whose health can be changed by tests
+type fakeInput struct {
+	*BaseInput
+	mu     sync.Mutex
+	status HealthStatus
+}
+
+func (f *fakeInput) Start() error { return nil }
+func (f *fakeInput) Stop() error  { return nil }
+
+func (f *fakeInput) Health() Health {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Health{Status: f.status, Message: string(f.status)}
+}
+
+func (f *fakeInput) setStatus(status HealthStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func TestRegisterHealthChecks(t *testing.T) {
+	inp := &fakeInput{
+		BaseInput: NewBaseInput("fake", "test", 1),
+		status:    HealthStatusHealthy,
+	}
+
+	checker := health.NewChecker(time.Second)
+	RegisterHealthChecks(checker, []Input{inp})
+
+	ctx := context.Background()
+	if status := checker.OverallStatus(ctx); status != health.StatusHealthy {
+		t.Errorf("expected healthy, got %s", status)
+	}
+
+	inp.setStatus(HealthStatusDegraded)
+	result, ok := checker.CheckComponent(ctx, "input:fake")
+	if !ok {
+		t.Fatal("expected input:fake to be registered")
+	}
+	if result.Status != health.StatusDegraded {
+		t.Errorf("expected degraded, got %s", result.Status)
+	}
+
+	inp.setStatus(HealthStatusUnhealthy)
+	if status := checker.OverallStatus(ctx); status != health.StatusUnhealthy {
+		t.Errorf("expected unhealthy, got %s", status)
+	}
+
+	// Readiness follows the input going unhealthy
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
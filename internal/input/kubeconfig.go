@@ -0,0 +1,112 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serviceAccountTokenFile is the projected service account token mounted in pods
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// ErrNoKubeConfig is returned when no Kubernetes config source is usable
+var ErrNoKubeConfig = errors.New("no usable Kubernetes config found")
+
+// kubeConfigLoader resolves a rest config from the available sources
+type kubeConfigLoader struct {
+	inCluster func() (*rest.Config, error)
+	fromFile  func(path string) (*rest.Config, error)
+	getenv    func(key string) string
+	homeDir   func() (string, error)
+	exists    func(path string) bool
+}
+
+// defaultKubeConfigLoader returns a loader backed by client-go and the OS
+func defaultKubeConfigLoader() kubeConfigLoader {
+	return kubeConfigLoader{
+		inCluster: rest.InClusterConfig,
+		fromFile: func(path string) (*rest.Config, error) {
+			return clientcmd.BuildConfigFromFlags("", path)
+		},
+		getenv:  os.Getenv,
+		homeDir: os.UserHomeDir,
+		exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+	}
+}
+
+// load resolves a config. An explicit kubeconfig path always wins; otherwise
+// in-cluster config is tried first, then $KUBECONFIG, then ~/.kube/config.
+// It returns the config and a description of the source that was used.
+func (l kubeConfigLoader) load(explicit string) (*rest.Config, string, error) {
+	if explicit != "" {
+		cfg, err := l.fromFile(explicit)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load kubeconfig %s: %w", explicit, err)
+		}
+		return configureTokenRefresh(cfg, false), explicit, nil
+	}
+
+	var failures []string
+
+	cfg, err := l.inCluster()
+	if err == nil {
+		return configureTokenRefresh(cfg, true), "in-cluster", nil
+	}
+	failures = append(failures, fmt.Sprintf("in-cluster: %v", err))
+
+	if env := l.getenv("KUBECONFIG"); env != "" {
+		for _, path := range filepath.SplitList(env) {
+			if path == "" || !l.exists(path) {
+				continue
+			}
+			cfg, err := l.fromFile(path)
+			if err == nil {
+				return configureTokenRefresh(cfg, false), path, nil
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+		}
+	} else {
+		failures = append(failures, "$KUBECONFIG: not set")
+	}
+
+	if home, err := l.homeDir(); err == nil && home != "" {
+		path := filepath.Join(home, ".kube", "config")
+		if l.exists(path) {
+			cfg, err := l.fromFile(path)
+			if err == nil {
+				return configureTokenRefresh(cfg, false), path, nil
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: not found", path))
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w (%s)", ErrNoKubeConfig, strings.Join(failures, "; "))
+}
+
+// configureTokenRefresh makes sure long-running watches survive token
+// rotation. client-go re-reads BearerTokenFile periodically, so in-cluster
+// configs always point at the projected token rather than a static copy.
+// Exec and auth-provider credentials are refreshed by client-go itself.
+func configureTokenRefresh(cfg *rest.Config, inCluster bool) *rest.Config {
+	if cfg.ExecProvider != nil || cfg.AuthProvider != nil {
+		return cfg
+	}
+	if inCluster && cfg.BearerTokenFile == "" {
+		cfg.BearerTokenFile = serviceAccountTokenFile
+	}
+	if cfg.BearerTokenFile != "" {
+		// Drop the static token so the file is the single source of truth
+		cfg.BearerToken = ""
+	}
+	return cfg
+}
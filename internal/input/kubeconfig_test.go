@@ -0,0 +1,102 @@
+package input
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+// fakeKubeConfigLoader builds a loader where each source is either usable
+// (returns a config whose Host names the source) or missing
+func fakeKubeConfigLoader(inCluster bool, env string, files map[string]bool) kubeConfigLoader {
+	return kubeConfigLoader{
+		inCluster: func() (*rest.Config, error) {
+			if !inCluster {
+				return nil, rest.ErrNotInCluster
+			}
+			return &rest.Config{Host: "in-cluster", BearerToken: "static"}, nil
+		},
+		fromFile: func(path string) (*rest.Config, error) {
+			if !files[path] {
+				return nil, errors.New("invalid kubeconfig")
+			}
+			return &rest.Config{Host: path}, nil
+		},
+		getenv: func(key string) string {
+			if key == "KUBECONFIG" {
+				return env
+			}
+			return ""
+		},
+		homeDir: func() (string, error) { return "/home/test", nil },
+		exists: func(path string) bool {
+			_, ok := files[path]
+			return ok
+		},
+	}
+}
+
+func TestKubeConfigLoader_Precedence(t *testing.T) {
+	home := filepath.Join("/home/test", ".kube", "config")
+
+	tests := []struct {
+		name      string
+		explicit  string
+		inCluster bool
+		env       string
+		files     map[string]bool
+		wantHost  string
+	}{
+		{"explicit wins", "/etc/kube.yaml", true, "/env/config", map[string]bool{"/etc/kube.yaml": true, "/env/config": true}, "/etc/kube.yaml"},
+		{"in-cluster first", "", true, "/env/config", map[string]bool{"/env/config": true, home: true}, "in-cluster"},
+		{"then KUBECONFIG", "", false, "/env/config", map[string]bool{"/env/config": true, home: true}, "/env/config"},
+		{"KUBECONFIG list skips missing", "", false, "/missing" + string(filepath.ListSeparator) + "/env/config", map[string]bool{"/env/config": true}, "/env/config"},
+		{"then home config", "", false, "", map[string]bool{home: true}, home},
+		{"home when KUBECONFIG invalid", "", false, "/env/config", map[string]bool{"/env/config": false, home: true}, home},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, source, err := fakeKubeConfigLoader(tt.inCluster, tt.env, tt.files).load(tt.explicit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Host != tt.wantHost || source != tt.wantHost {
+				t.Errorf("expected source %s, got host %s source %s", tt.wantHost, cfg.Host, source)
+			}
+		})
+	}
+}
+
+func TestKubeConfigLoader_NoSource(t *testing.T) {
+	_, _, err := fakeKubeConfigLoader(false, "", nil).load("")
+	if !errors.Is(err, ErrNoKubeConfig) {
+		t.Fatalf("expected ErrNoKubeConfig, got %v", err)
+	}
+
+	// An explicit path that fails is reported directly rather than falling back
+	_, _, err = fakeKubeConfigLoader(true, "", nil).load("/bad")
+	if err == nil || errors.Is(err, ErrNoKubeConfig) {
+		t.Errorf("expected explicit kubeconfig error, got %v", err)
+	}
+}
+
+func TestConfigureTokenRefresh(t *testing.T) {
+	cfg, _, err := fakeKubeConfigLoader(true, "", nil).load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.BearerTokenFile != serviceAccountTokenFile {
+		t.Errorf("expected token file %s, got %q", serviceAccountTokenFile, cfg.BearerTokenFile)
+	}
+	if cfg.BearerToken != "" {
+		t.Error("expected static token to be dropped in favour of the token file")
+	}
+
+	exec := &rest.Config{BearerToken: "t", ExecProvider: nil, AuthProvider: nil}
+	if got := configureTokenRefresh(exec, false); got.BearerToken != "t" {
+		t.Error("expected static token to be kept without a token file")
+	}
+}
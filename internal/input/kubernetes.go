@@ -1,22 +1,21 @@
 package input
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/checkpoint"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 // KubernetesConfig holds configuration for Kubernetes input
@@ -41,28 +40,36 @@ type KubernetesConfig struct {
 	EnrichMetadata bool
 	// Buffer size for events channel
 	BufferSize int
+	// Parse the RFC3339 timestamp prefix into the event timestamp
+	ParseTimestamps bool
+	// Checkpoints persists per-container log offsets across restarts (nil to disable)
+	Checkpoints *checkpoint.Manager
 }
 
 // KubernetesInput collects logs from Kubernetes pods
 type KubernetesInput struct {
 	*BaseInput
-	config    *KubernetesConfig
-	logger    *logging.Logger
-	clientset *kubernetes.Clientset
-	watcher   watch.Interface
-	pods      map[string]*podInfo
-	mu        sync.RWMutex
-	wg        sync.WaitGroup
+	config      *KubernetesConfig
+	logger      *logging.Logger
+	clientset   *kubernetes.Clientset
+	watcher     watch.Interface
+	watcherDown atomic.Bool // Set when the watcher closed and could not be restarted
+	pods        map[string]*podInfo
+	offsets     map[string]time.Time // Last emitted log timestamp per container
+	offsetMu    sync.Mutex
+	mu          sync.RWMutex
+	wg          sync.WaitGroup
 }
 
 // podInfo tracks information about a pod
 type podInfo struct {
-	name       string
-	namespace  string
-	labels     map[string]string
+	name        string
+	namespace   string
+	labels      map[string]string
 	annotations map[string]string
-	containers []string
-	cancelFuncs []context.CancelFunc
+	containers  []string
+	cancelFuncs map[string]context.CancelFunc // Keyed by container name
+	active      map[string]bool               // Containers with a running log stream
 }
 
 // NewKubernetesInput creates a new Kubernetes input
@@ -76,20 +83,11 @@ func NewKubernetesInput(name string, config *KubernetesConfig, logger *logging.L
 	}
 
 	// Create Kubernetes client
-	var kubeConfig *rest.Config
-	var err error
-
-	if config.Kubeconfig != "" {
-		// Use kubeconfig file
-		kubeConfig, err = clientcmd.BuildConfigFromFlags("", config.Kubeconfig)
-	} else {
-		// Use in-cluster config
-		kubeConfig, err = rest.InClusterConfig()
-	}
-
+	kubeConfig, source, err := defaultKubeConfigLoader().load(config.Kubeconfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes config: %w", err)
 	}
+	logger.Debug().Str("source", source).Msg("Loaded Kubernetes config")
 
 	clientset, err := kubernetes.NewForConfig(kubeConfig)
 	if err != nil {
@@ -102,6 +100,7 @@ func NewKubernetesInput(name string, config *KubernetesConfig, logger *logging.L
 		logger:    logger.WithComponent("input-kubernetes"),
 		clientset: clientset,
 		pods:      make(map[string]*podInfo),
+		offsets:   make(map[string]time.Time),
 	}, nil
 }
 
@@ -165,6 +164,14 @@ func (k *KubernetesInput) Health() Health {
 	details["namespace"] = k.config.Namespace
 	details["pods_watching"] = podCount
 
+	if k.watcherDown.Load() {
+		return Health{
+			Status:  HealthStatusUnhealthy,
+			Message: "Pod watcher closed and could not be restarted",
+			Details: details,
+		}
+	}
+
 	return Health{
 		Status:  HealthStatusHealthy,
 		Message: "Kubernetes log collector is running",
@@ -229,9 +236,12 @@ func (k *KubernetesInput) watchLoop() {
 				k.logger.Info().Msg("Pod watcher closed, restarting...")
 				// Restart watcher
 				if err := k.startWatcher(); err != nil {
+					k.watcherDown.Store(true)
 					k.logger.Error().Err(err).Msg("Failed to restart watcher")
 					time.Sleep(5 * time.Second)
+					continue
 				}
+				k.watcherDown.Store(false)
 				continue
 			}
 
@@ -255,30 +265,32 @@ func (k *KubernetesInput) watchLoop() {
 	}
 }
 
-// handlePodAdded handles when a pod is added or becomes running
+// handlePodAdded handles when a pod is added or becomes running. For a pod
+// that is already tracked, containers whose log stream has ended (for example
+// after a container restart) are re-attached from their last-seen timestamp.
 func (k *KubernetesInput) handlePodAdded(pod *corev1.Pod) {
 	podKey := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
 
-	k.mu.RLock()
-	_, exists := k.pods[podKey]
-	k.mu.RUnlock()
-
-	if exists {
-		return // Already watching this pod
-	}
-
-	k.logger.Info().
-		Str("namespace", pod.Namespace).
-		Str("pod", pod.Name).
-		Msg("Starting to collect logs from pod")
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	info := &podInfo{
-		name:        pod.Name,
-		namespace:   pod.Namespace,
-		labels:      pod.Labels,
-		annotations: pod.Annotations,
-		containers:  make([]string, 0),
-		cancelFuncs: make([]context.CancelFunc, 0),
+	info, exists := k.pods[podKey]
+	if !exists {
+		k.logger.Info().
+			Str("namespace", pod.Namespace).
+			Str("pod", pod.Name).
+			Msg("Starting to collect logs from pod")
+
+		info = &podInfo{
+			name:        pod.Name,
+			namespace:   pod.Namespace,
+			labels:      pod.Labels,
+			annotations: pod.Annotations,
+			containers:  make([]string, 0),
+			cancelFuncs: make(map[string]context.CancelFunc),
+			active:      make(map[string]bool),
+		}
+		k.pods[podKey] = info
 	}
 
 	// Collect logs from all containers in the pod
@@ -290,18 +302,24 @@ func (k *KubernetesInput) handlePodAdded(pod *corev1.Pod) {
 			}
 		}
 
-		info.containers = append(info.containers, container.Name)
+		if info.active[container.Name] {
+			continue // Already tailing this container
+		}
+
+		if !exists {
+			info.containers = append(info.containers, container.Name)
+		}
+		info.active[container.Name] = true
 
+		if prev, ok := info.cancelFuncs[container.Name]; ok {
+			prev() // Release the context of the ended stream
+		}
 		ctx, cancel := context.WithCancel(k.Context())
-		info.cancelFuncs = append(info.cancelFuncs, cancel)
+		info.cancelFuncs[container.Name] = cancel
 
 		k.wg.Add(1)
 		go k.tailContainer(ctx, info, container.Name)
 	}
-
-	k.mu.Lock()
-	k.pods[podKey] = info
-	k.mu.Unlock()
 }
 
 // handlePodDeleted handles when a pod is deleted
@@ -314,6 +332,9 @@ func (k *KubernetesInput) handlePodDeleted(pod *corev1.Pod) {
 		for _, cancel := range info.cancelFuncs {
 			cancel()
 		}
+		for _, container := range info.containers {
+			k.forgetOffset(containerKey(info, container))
+		}
 		delete(k.pods, podKey)
 	}
 	k.mu.Unlock()
@@ -329,6 +350,11 @@ func (k *KubernetesInput) handlePodDeleted(pod *corev1.Pod) {
 // tailContainer tails logs from a container
 func (k *KubernetesInput) tailContainer(ctx context.Context, pod *podInfo, containerName string) {
 	defer k.wg.Done()
+	defer func() {
+		k.mu.Lock()
+		pod.active[containerName] = false
+		k.mu.Unlock()
+	}()
 
 	k.logger.Debug().
 		Str("namespace", pod.namespace).
@@ -342,7 +368,11 @@ func (k *KubernetesInput) tailContainer(ctx context.Context, pod *podInfo, conta
 		Timestamps: true,
 	}
 
-	if k.config.TailLines > 0 {
+	if since, ok := k.lastSeen(containerKey(pod, containerName)); ok {
+		// Resume from the last emitted line; SinceTime has second precision
+		// so readStream drops lines at or before the recorded timestamp
+		opts.SinceTime = &metav1.Time{Time: since}
+	} else if k.config.TailLines > 0 {
 		opts.TailLines = &k.config.TailLines
 	}
 
@@ -364,48 +394,155 @@ func (k *KubernetesInput) tailContainer(ctx context.Context, pod *podInfo, conta
 	}
 	defer stream.Close()
 
-	// Read logs line by line
-	scanner := bufio.NewScanner(stream)
+	if err := k.readStream(ctx, stream, pod, containerName); err != nil && err != io.EOF {
+		k.logger.Error().
+			Err(err).
+			Str("namespace", pod.namespace).
+			Str("pod", pod.name).
+			Str("container", containerName).
+			Msg("Error reading container logs")
+	}
+}
+
+// readStream emits events for each line of a timestamped container log
+// stream, skipping lines at or before the container's last-seen timestamp
+func (k *KubernetesInput) readStream(ctx context.Context, r io.Reader, pod *podInfo, containerName string) error {
+	key := containerKey(pod, containerName)
+	since, resumed := k.lastSeen(key)
+
+	// Read logs line by line, cutting lines over the size limit
+	maxBytes := k.MaxLineBytes(0)
+	scanner := NewLineScanner(r, maxBytes)
+	var cutLogger *logging.Logger
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		default:
 		}
 
 		line := scanner.Text()
+		ts, _, ok := splitTimestamp(line)
+		if ok && resumed && !ts.After(since) {
+			continue // Already emitted before re-attaching
+		}
+
+		if scanner.Cut() {
+			if cutLogger == nil {
+				cutLogger = k.logger.RateLimited()
+			}
+			cutLogger.Warn().
+				Str("namespace", pod.namespace).
+				Str("pod", pod.name).
+				Str("container", containerName).
+				Int("max_bytes", maxBytes).
+				Msg("Line over the maximum size cut")
+		}
+
 		event := k.createEvent(line, pod, containerName)
-		k.SendEvent(event)
+		if !k.SendLine(event, scanner.Cut()) {
+			return nil
+		}
+
+		if ok {
+			k.recordOffset(key, ts)
+		}
 	}
 
-	if err := scanner.Err(); err != nil && err != io.EOF {
-		k.logger.Error().
-			Err(err).
-			Str("namespace", pod.namespace).
-			Str("pod", pod.name).
-			Str("container", containerName).
-			Msg("Error reading container logs")
+	return scanner.Err()
+}
+
+// lastSeen returns the last emitted timestamp for a container, falling back
+// to the checkpoint manager so aggregator restarts resume where they left off
+func (k *KubernetesInput) lastSeen(key string) (time.Time, bool) {
+	k.offsetMu.Lock()
+	defer k.offsetMu.Unlock()
+
+	if ts, ok := k.offsets[key]; ok {
+		return ts, true
+	}
+	if k.config.Checkpoints != nil {
+		if ts, ok := k.config.Checkpoints.GetTimestamp(key); ok {
+			k.offsets[key] = ts
+			return ts, true
+		}
 	}
+	return time.Time{}, false
 }
 
-// createEvent creates a log event from a container log line
+// recordOffset records the timestamp of the last emitted line for a container
+func (k *KubernetesInput) recordOffset(key string, ts time.Time) {
+	k.offsetMu.Lock()
+	if last, ok := k.offsets[key]; !ok || ts.After(last) {
+		k.offsets[key] = ts
+	}
+	k.offsetMu.Unlock()
+
+	if k.config.Checkpoints != nil {
+		k.config.Checkpoints.UpdateTimestamp(key, ts)
+	}
+}
+
+// forgetOffset drops the recorded offset for a container of a deleted pod
+func (k *KubernetesInput) forgetOffset(key string) {
+	k.offsetMu.Lock()
+	delete(k.offsets, key)
+	k.offsetMu.Unlock()
+
+	if k.config.Checkpoints != nil {
+		k.config.Checkpoints.DeleteTimestamp(key)
+	}
+}
+
+// containerKey identifies a container's log stream as namespace/pod/container
+func containerKey(pod *podInfo, containerName string) string {
+	return pod.namespace + "/" + pod.name + "/" + containerName
+}
+
+// splitTimestamp splits the RFC3339 timestamp prefix added by the API server
+// when Timestamps is set from the rest of a log line
+func splitTimestamp(line string) (time.Time, string, bool) {
+	prefix, rest, found := strings.Cut(line, " ")
+	if !found {
+		prefix, rest = line, ""
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return ts, rest, true
+}
+
+// createEvent creates a log event from a container log line. When
+// ParseTimestamps is set, the API server's timestamp prefix becomes the
+// event timestamp and is stripped from the message.
 func (k *KubernetesInput) createEvent(line string, pod *podInfo, containerName string) *types.LogEvent {
 	event := &types.LogEvent{
 		Timestamp: time.Now(),
 		Message:   line,
 		Source:    k.name,
-		Fields:    make(map[string]interface{}),
+		Fields:    make(map[string]string),
 		Raw:       line,
 	}
 
+	if k.config.ParseTimestamps {
+		if ts, message, ok := splitTimestamp(line); ok {
+			event.Timestamp = ts
+			event.Message = message
+		}
+	}
+
 	// Add Kubernetes metadata if enabled
 	if k.config.EnrichMetadata {
-		event.Fields["kubernetes"] = map[string]interface{}{
-			"namespace":   pod.namespace,
-			"pod":         pod.name,
-			"container":   containerName,
-			"labels":      pod.labels,
-			"annotations": pod.annotations,
+		event.Fields["kubernetes.namespace"] = pod.namespace
+		event.Fields["kubernetes.pod"] = pod.name
+		event.Fields["kubernetes.container"] = containerName
+		for key, value := range pod.labels {
+			event.Fields["kubernetes.labels."+key] = value
+		}
+		for key, value := range pod.annotations {
+			event.Fields["kubernetes.annotations."+key] = value
 		}
 	} else {
 		// Add minimal metadata
@@ -0,0 +1,128 @@
+package input
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/checkpoint"
+)
+
+// newTestKubernetesInput builds a Kubernetes input without a clientset so
+// log streams can be fed directly through readStream
+func newTestKubernetesInput(ckpt *checkpoint.Manager) *KubernetesInput {
+	return &KubernetesInput{
+		BaseInput: NewBaseInput("k8s", "kubernetes", 100),
+		config:    &KubernetesConfig{ParseTimestamps: true, Checkpoints: ckpt},
+		pods:      make(map[string]*podInfo),
+		offsets:   make(map[string]time.Time),
+	}
+}
+
+// timestampedLog builds a log stream in the format returned with Timestamps set
+func timestampedLog(base time.Time, lines ...int) string {
+	var sb strings.Builder
+	for _, n := range lines {
+		ts := base.Add(time.Duration(n) * 100 * time.Millisecond)
+		sb.WriteString(ts.Format(time.RFC3339Nano))
+		sb.WriteString(" line-")
+		sb.WriteByte(byte('0' + n))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// drainMessages collects all buffered event messages
+func drainMessages(k *KubernetesInput) []string {
+	var messages []string
+	for {
+		select {
+		case event := <-k.Events():
+			messages = append(messages, event.Message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestKubernetesInput_ReAddDoesNotDuplicate(t *testing.T) {
+	ckpt, err := checkpoint.NewManager(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint manager: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pod := &podInfo{name: "web-0", namespace: "default"}
+	ctx := context.Background()
+
+	k := newTestKubernetesInput(ckpt)
+	if err := k.readStream(ctx, strings.NewReader(timestampedLog(base, 1, 2, 3)), pod, "app"); err != nil {
+		t.Fatalf("readStream failed: %v", err)
+	}
+
+	// Re-attach after a restart: SinceTime has second precision so the
+	// API server replays lines already emitted
+	if err := k.readStream(ctx, strings.NewReader(timestampedLog(base, 2, 3, 4)), pod, "app"); err != nil {
+		t.Fatalf("readStream failed: %v", err)
+	}
+
+	got := drainMessages(k)
+	want := []string{"line-1", "line-2", "line-3", "line-4"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// A fresh input sharing the checkpoint simulates an aggregator restart
+	restarted := newTestKubernetesInput(ckpt)
+	if err := restarted.readStream(ctx, strings.NewReader(timestampedLog(base, 3, 4, 5)), pod, "app"); err != nil {
+		t.Fatalf("readStream failed: %v", err)
+	}
+
+	got = drainMessages(restarted)
+	if len(got) != 1 || got[0] != "line-5" {
+		t.Errorf("expected only line-5 after restart, got %v", got)
+	}
+}
+
+func TestKubernetesInput_ParseTimestamps(t *testing.T) {
+	pod := &podInfo{name: "web-0", namespace: "default"}
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+	prefixed := ts.Format(time.RFC3339Nano) + " hello world"
+
+	tests := []struct {
+		name        string
+		parse       bool
+		line        string
+		wantMessage string
+		wantParsed  bool
+	}{
+		{"prefixed", true, prefixed, "hello world", true},
+		{"no prefix", true, "no timestamp here", "no timestamp here", false},
+		{"invalid prefix", true, "2024-13-45 not a time", "2024-13-45 not a time", false},
+		{"disabled", false, prefixed, prefixed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestKubernetesInput(nil)
+			k.config.ParseTimestamps = tt.parse
+
+			before := time.Now()
+			event := k.createEvent(tt.line, pod, "app")
+
+			if event.Message != tt.wantMessage {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, event.Message)
+			}
+			if event.Raw != tt.line {
+				t.Errorf("expected raw line to be kept, got %q", event.Raw)
+			}
+			if tt.wantParsed && !event.Timestamp.Equal(ts) {
+				t.Errorf("expected timestamp %v, got %v", ts, event.Timestamp)
+			}
+			if !tt.wantParsed && event.Timestamp.Before(before) {
+				t.Errorf("expected current time, got %v", event.Timestamp)
+			}
+		})
+	}
+}
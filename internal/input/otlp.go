@@ -0,0 +1,339 @@
+package input
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultOTLPPath is the standard OTLP/HTTP logs endpoint
+const DefaultOTLPPath = "/v1/logs"
+
+// OTLP/HTTP content types
+const (
+	otlpContentTypeProto = "application/x-protobuf"
+	otlpContentTypeJSON  = "application/json"
+)
+
+// OTLPInput receives OTLP/HTTP ExportLogsServiceRequest payloads. It shares
+// the HTTP input's server, auth, rate limiting and body size limit.
+type OTLPInput struct {
+	*HTTPInput
+}
+
+// NewOTLPInput creates a new OTLP/HTTP input. Path defaults to "/v1/logs".
+func NewOTLPInput(name string, config *HTTPConfig, logger *logging.Logger) (*OTLPInput, error) {
+	if config.Path == "" {
+		config.Path = DefaultOTLPPath
+	}
+
+	input := &OTLPInput{HTTPInput: newHTTPReceiver(name, "otlp", config, logger)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(config.Path, input.handleExport)
+	input.setupServer(mux)
+
+	return input, nil
+}
+
+// handleExport handles an OTLP logs export request
+func (o *OTLPInput) handleExport(w http.ResponseWriter, r *http.Request) {
+	atomic.AddUint64(&o.stats.requestsTotal, 1)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != otlpContentTypeProto && contentType != otlpContentTypeJSON {
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	data, ok := o.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := decodeExportRequest(data, contentType)
+	if err != nil {
+		atomic.AddUint64(&o.stats.errorsTotal, 1)
+		o.logger.Error().Err(err).Msg("Failed to decode OTLP request")
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	accepted := 0
+	events := o.convertRequest(req, r.RemoteAddr)
+	for _, event := range events {
+		if !o.SendEvent(event) {
+			break
+		}
+		accepted++
+	}
+	atomic.AddUint64(&o.stats.eventsTotal, uint64(accepted))
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if rejected := len(events) - accepted; rejected > 0 {
+		atomic.AddUint64(&o.stats.errorsTotal, 1)
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(rejected),
+			ErrorMessage:       "input is shutting down",
+		}
+	}
+
+	var out []byte
+	if contentType == otlpContentTypeJSON {
+		out, err = protojson.Marshal(resp)
+	} else {
+		out, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// decodeExportRequest decodes a protobuf or JSON ExportLogsServiceRequest
+func decodeExportRequest(data []byte, contentType string) (*collogspb.ExportLogsServiceRequest, error) {
+	req := &collogspb.ExportLogsServiceRequest{}
+
+	if contentType == otlpContentTypeProto {
+		if err := proto.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal protobuf request: %w", err)
+		}
+		return req, nil
+	}
+
+	// OTLP/JSON encodes trace and span IDs as hex rather than base64
+	data, err := hexIDsToBase64(data)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err := opts.Unmarshal(data, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON request: %w", err)
+	}
+	return req, nil
+}
+
+// hexIDsToBase64 rewrites the traceId and spanId of every log record from
+// the hex form used by OTLP/JSON to the base64 form protojson expects
+func hexIDsToBase64(data []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON request: %w", err)
+	}
+
+	for _, rl := range jsonList(doc, "resourceLogs", "resource_logs") {
+		for _, sl := range jsonList(rl, "scopeLogs", "scope_logs") {
+			for _, rec := range jsonList(sl, "logRecords", "log_records") {
+				for _, key := range []string{"traceId", "trace_id", "spanId", "span_id"} {
+					if id, ok := rec[key].(string); ok && id != "" {
+						raw, err := hex.DecodeString(id)
+						if err != nil {
+							return nil, fmt.Errorf("invalid %s %q: %w", key, id, err)
+						}
+						rec[key] = base64.StdEncoding.EncodeToString(raw)
+					}
+				}
+			}
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// jsonList returns the objects in the first array found under any of keys
+func jsonList(obj map[string]interface{}, keys ...string) []map[string]interface{} {
+	for _, key := range keys {
+		items, ok := obj[key].([]interface{})
+		if !ok {
+			continue
+		}
+		list := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				list = append(list, m)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// convertRequest flattens an export request into log events. Resource and
+// scope attributes are prefixed with "resource." and "scope." respectively.
+func (o *OTLPInput) convertRequest(req *collogspb.ExportLogsServiceRequest, remoteAddr string) []*types.LogEvent {
+	var events []*types.LogEvent
+	now := time.Now()
+
+	for _, rl := range req.GetResourceLogs() {
+		resourceFields := make(map[string]string)
+		for _, kv := range rl.GetResource().GetAttributes() {
+			resourceFields["resource."+kv.GetKey()] = anyValueString(kv.GetValue())
+		}
+
+		for _, sl := range rl.GetScopeLogs() {
+			scopeFields := make(map[string]string)
+			if scope := sl.GetScope(); scope != nil {
+				if scope.GetName() != "" {
+					scopeFields["scope.name"] = scope.GetName()
+				}
+				if scope.GetVersion() != "" {
+					scopeFields["scope.version"] = scope.GetVersion()
+				}
+				for _, kv := range scope.GetAttributes() {
+					scopeFields["scope."+kv.GetKey()] = anyValueString(kv.GetValue())
+				}
+			}
+
+			for _, rec := range sl.GetLogRecords() {
+				event := o.convertRecord(rec, now)
+				for k, v := range resourceFields {
+					event.Fields[k] = v
+				}
+				for k, v := range scopeFields {
+					event.Fields[k] = v
+				}
+
+				// Add metadata
+				event.Fields["remote_addr"] = remoteAddr
+				event.Fields["input_type"] = "otlp"
+
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events
+}
+
+// convertRecord converts a single OTLP log record into a log event
+func (o *OTLPInput) convertRecord(rec *logspb.LogRecord, now time.Time) *types.LogEvent {
+	event := &types.LogEvent{
+		Timestamp: now,
+		Message:   anyValueString(rec.GetBody()),
+		Level:     severityLevel(rec.GetSeverityNumber(), rec.GetSeverityText()),
+		Source:    o.name,
+		Fields:    make(map[string]string),
+	}
+
+	if ts := rec.GetTimeUnixNano(); ts != 0 {
+		event.Timestamp = time.Unix(0, int64(ts))
+	} else if ts := rec.GetObservedTimeUnixNano(); ts != 0 {
+		event.Timestamp = time.Unix(0, int64(ts))
+	}
+
+	for _, kv := range rec.GetAttributes() {
+		event.Fields[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	if text := rec.GetSeverityText(); text != "" {
+		event.Fields["severity_text"] = text
+	}
+	if id := rec.GetTraceId(); len(id) > 0 {
+		event.Fields["trace_id"] = hex.EncodeToString(id)
+	}
+	if id := rec.GetSpanId(); len(id) > 0 {
+		event.Fields["span_id"] = hex.EncodeToString(id)
+	}
+
+	return event
+}
+
+// severityLevel maps an OTLP severity number to a log level, falling back
+// to the severity text when the number is unspecified
+func severityLevel(number logspb.SeverityNumber, text string) string {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return "fatal"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return "error"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return "warn"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return "info"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG:
+		return "debug"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return "trace"
+	default:
+		return strings.ToLower(text)
+	}
+}
+
+// anyValueString renders an OTLP AnyValue as a string. Arrays and key-value
+// lists are rendered as JSON.
+func anyValueString(v *commonpb.AnyValue) string {
+	if v == nil {
+		return ""
+	}
+
+	switch val := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(val.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	default:
+		data, err := json.Marshal(anyValueJSON(v))
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// anyValueJSON converts an OTLP AnyValue into a JSON-encodable value
+func anyValueJSON(v *commonpb.AnyValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_ArrayValue:
+		items := make([]interface{}, 0, len(val.ArrayValue.GetValues()))
+		for _, item := range val.ArrayValue.GetValues() {
+			items = append(items, anyValueJSON(item))
+		}
+		return items
+	case *commonpb.AnyValue_KvlistValue:
+		obj := make(map[string]interface{}, len(val.KvlistValue.GetValues()))
+		for _, kv := range val.KvlistValue.GetValues() {
+			obj[kv.GetKey()] = anyValueJSON(kv.GetValue())
+		}
+		return obj
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	default:
+		return anyValueString(v)
+	}
+}
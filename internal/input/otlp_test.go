@@ -0,0 +1,196 @@
+package input
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestOTLPInput(t *testing.T, config *HTTPConfig) *OTLPInput {
+	t.Helper()
+
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	config.Address = "localhost:0"
+	inp, err := NewOTLPInput("test-otlp", config, logger)
+	if err != nil {
+		t.Fatalf("failed to create OTLP input: %v", err)
+	}
+	return inp
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func TestOTLPInput_Protobuf(t *testing.T) {
+	inp := newTestOTLPInput(t, &HTTPConfig{BufferSize: 10})
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{{Key: "service.name", Value: stringValue("checkout")}},
+			},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope: &commonpb.InstrumentationScope{Name: "app.logger", Version: "1.2.0"},
+				LogRecords: []*logspb.LogRecord{{
+					TimeUnixNano:   uint64(ts.UnixNano()),
+					SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN2,
+					Body:           stringValue("payment retried"),
+					Attributes: []*commonpb.KeyValue{
+						{Key: "attempt", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 2}}},
+					},
+					TraceId: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+				}},
+			}},
+		}},
+	}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	httpReq := httptest.NewRequest(http.MethodPost, DefaultOTLPPath, bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	inp.server.Handler.ServeHTTP(w, httpReq)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if err := proto.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.GetPartialSuccess() != nil {
+		t.Errorf("unexpected partial success: %v", resp.GetPartialSuccess())
+	}
+
+	event := <-inp.Events()
+	if event.Message != "payment retried" || event.Level != "warn" || !event.Timestamp.Equal(ts) {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	wantFields := map[string]string{
+		"resource.service.name": "checkout",
+		"scope.name":            "app.logger",
+		"scope.version":         "1.2.0",
+		"attempt":               "2",
+		"trace_id":              "0102030405060708090a0b0c0d0e0f10",
+		"input_type":            "otlp",
+	}
+	for k, want := range wantFields {
+		if got := event.Fields[k]; got != want {
+			t.Errorf("field %s: expected %q, got %q", k, want, got)
+		}
+	}
+}
+
+func TestOTLPInput_JSON(t *testing.T) {
+	inp := newTestOTLPInput(t, &HTTPConfig{BufferSize: 10})
+
+	body := `{
+		"resourceLogs": [{
+			"resource": {"attributes": [{"key": "host.name", "value": {"stringValue": "web-1"}}]},
+			"scopeLogs": [{
+				"scope": {"name": "json.scope"},
+				"logRecords": [
+					{
+						"timeUnixNano": "1704110400000000000",
+						"severityNumber": 17,
+						"severityText": "ERROR",
+						"body": {"stringValue": "disk full"},
+						"traceId": "5b8efff798038103d269b633813fc60c",
+						"spanId": "eee19b7ec3c1b174"
+					},
+					{
+						"severityText": "Info",
+						"body": {"kvlistValue": {"values": [{"key": "k", "value": {"boolValue": true}}]}}
+					}
+				]
+			}]
+		}]
+	}`
+
+	httpReq := httptest.NewRequest(http.MethodPost, DefaultOTLPPath, bytes.NewBufferString(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	inp.server.Handler.ServeHTTP(w, httpReq)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON response, got %q", ct)
+	}
+
+	first := <-inp.Events()
+	if first.Message != "disk full" || first.Level != "error" {
+		t.Errorf("unexpected first event: %+v", first)
+	}
+	if !first.Timestamp.Equal(time.Unix(0, 1704110400000000000)) {
+		t.Errorf("unexpected timestamp: %v", first.Timestamp)
+	}
+	if first.Fields["trace_id"] != "5b8efff798038103d269b633813fc60c" || first.Fields["span_id"] != "eee19b7ec3c1b174" {
+		t.Errorf("unexpected trace context: %v", first.Fields)
+	}
+	if first.Fields["resource.host.name"] != "web-1" || first.Fields["scope.name"] != "json.scope" {
+		t.Errorf("unexpected resource/scope fields: %v", first.Fields)
+	}
+
+	// Unspecified severity number falls back to the severity text
+	second := <-inp.Events()
+	if second.Level != "info" || second.Message != `{"k":true}` {
+		t.Errorf("unexpected second event: %+v", second)
+	}
+}
+
+func TestOTLPInput_Rejects(t *testing.T) {
+	inp := newTestOTLPInput(t, &HTTPConfig{APIKeys: []string{"secret"}})
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		apiKey      string
+		body        string
+		wantStatus  int
+	}{
+		{"missing api key", http.MethodPost, "application/json", "", "{}", http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "application/json", "secret", "", http.StatusMethodNotAllowed},
+		{"unsupported content type", http.MethodPost, "text/plain", "secret", "hi", http.StatusUnsupportedMediaType},
+		{"malformed JSON", http.MethodPost, "application/json", "secret", "{", http.StatusBadRequest},
+		{"empty request", http.MethodPost, "application/json", "secret", "{}", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, DefaultOTLPPath, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			inp.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
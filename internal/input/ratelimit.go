@@ -0,0 +1,132 @@
+package input
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiterIdleTTL is how long a client's limiter is kept without requests
+	limiterIdleTTL = 5 * time.Minute
+	// limiterSweepInterval is how often idle limiters are evicted
+	limiterSweepInterval = time.Minute
+)
+
+// clientLimiters holds one rate limiter per client IP and evicts limiters
+// that have been idle longer than a TTL with a single periodic sweep
+type clientLimiters struct {
+	limit   rate.Limit
+	burst   int
+	ttl     time.Duration
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+	now     func() time.Time
+}
+
+// clientLimiter is a rate limiter with its last use time
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiters creates limiters allowing perSecond requests per client
+// with a burst of twice that
+func newClientLimiters(perSecond int, ttl time.Duration) *clientLimiters {
+	return &clientLimiters{
+		limit:   rate.Limit(perSecond),
+		burst:   perSecond * 2,
+		ttl:     ttl,
+		clients: make(map[string]*clientLimiter),
+		now:     time.Now,
+	}
+}
+
+// get returns the limiter for the client at remoteAddr. The port is
+// stripped so every connection from one IP shares a limiter.
+func (c *clientLimiters) get(remoteAddr string) *rate.Limiter {
+	key := clientIP(remoteAddr)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.clients[key]
+	if !ok {
+		entry = &clientLimiter{limiter: rate.NewLimiter(c.limit, c.burst)}
+		c.clients[key] = entry
+	}
+	entry.lastSeen = c.now()
+
+	return entry.limiter
+}
+
+// setLimit changes the rate allowed per client, including clients that
+// already have a limiter
+func (c *clientLimiters) setLimit(perSecond int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limit = rate.Limit(perSecond)
+	c.burst = perSecond * 2
+	for _, entry := range c.clients {
+		entry.limiter.SetLimit(c.limit)
+		entry.limiter.SetBurst(c.burst)
+	}
+}
+
+// enabled reports whether requests are rate limited
+func (c *clientLimiters) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.limit > 0
+}
+
+// sweep evicts limiters idle for longer than the TTL
+func (c *clientLimiters) sweep() {
+	cutoff := c.now().Add(-c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.clients {
+		if entry.lastSeen.Before(cutoff) {
+			delete(c.clients, key)
+		}
+	}
+}
+
+// run sweeps idle limiters every interval until ctx is done
+func (c *clientLimiters) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// len returns the number of tracked clients
+func (c *clientLimiters) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.clients)
+}
+
+// clientIP strips the port from a host:port address
+func clientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
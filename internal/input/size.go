@@ -0,0 +1,163 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Policies for events over the size limit
+const (
+	// OversizeTruncate cuts the event to the limit and marks it truncated
+	OversizeTruncate = "truncate"
+
+	// OversizeDrop drops the event, counting it as dropped
+	OversizeDrop = "drop"
+)
+
+// TruncatedField is set to "true" on events cut to the size limit
+const TruncatedField = "truncated"
+
+// DefaultMaxLineBytes bounds the lines read by line-oriented inputs when no
+// size is configured, as bufio.Scanner does
+const DefaultMaxLineBytes = bufio.MaxScanTokenSize
+
+// SizeLimit bounds the size of an event's message and raw line, so a single
+// enormous line cannot exhaust memory or downstream limits
+type SizeLimit struct {
+	// MaxBytes is the largest message or raw line accepted; zero is
+	// unlimited
+	MaxBytes int
+
+	// Policy is truncate (the default) or drop
+	Policy string
+}
+
+// Validate checks the limit's policy
+func (l SizeLimit) Validate() error {
+	switch l.Policy {
+	case "", OversizeTruncate, OversizeDrop:
+		return nil
+	default:
+		return fmt.Errorf("unsupported oversize policy %q: must be truncate or drop", l.Policy)
+	}
+}
+
+// Apply enforces the limit on an event; a line's terminating newline does
+// not count towards it. cut reports that a reader already cut the event's
+// line, which is then treated as oversized too. It reports whether the
+// event is kept and whether it was truncated.
+func (l SizeLimit) Apply(event *types.LogEvent, cut bool) (kept, truncated bool) {
+	oversized := l.MaxBytes > 0 && (lineLen(event.Message) > l.MaxBytes || lineLen(event.Raw) > l.MaxBytes)
+	if !oversized && !cut {
+		return true, false
+	}
+	if l.Policy == OversizeDrop {
+		return false, false
+	}
+
+	if l.MaxBytes > 0 {
+		event.Message = truncateUTF8(event.Message, l.MaxBytes)
+		event.Raw = truncateUTF8(event.Raw, l.MaxBytes)
+	}
+	if event.Fields == nil {
+		event.Fields = make(map[string]string)
+	}
+	event.Fields[TruncatedField] = "true"
+	return true, true
+}
+
+// lineLen returns the size of a line without its terminating newline
+func lineLen(s string) int {
+	return len(strings.TrimSuffix(s, "\n"))
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// LineScanner reads newline-terminated lines like bufio.Scanner, but cuts
+// lines longer than its maximum instead of failing with "token too long".
+// The rest of a cut line is discarded.
+type LineScanner struct {
+	scanner  *bufio.Scanner
+	max      int
+	cut      bool // The current line was cut
+	skipping bool // Discarding the rest of a cut line
+}
+
+// NewLineScanner creates a scanner of lines up to max bytes, or
+// DefaultMaxLineBytes when max is not positive
+func NewLineScanner(r io.Reader, max int) *LineScanner {
+	if max <= 0 {
+		max = DefaultMaxLineBytes
+	}
+
+	s := &LineScanner{scanner: bufio.NewScanner(r), max: max}
+	s.scanner.Buffer(make([]byte, 0, min(max+1, 4096)), max+1)
+	s.scanner.Split(s.split)
+	return s
+}
+
+// Scan advances to the next line, reporting false at the end of the input
+// or on an error
+func (s *LineScanner) Scan() bool {
+	return s.scanner.Scan()
+}
+
+// Text returns the current line
+func (s *LineScanner) Text() string {
+	return s.scanner.Text()
+}
+
+// Cut reports whether the current line was longer than the maximum and cut
+func (s *LineScanner) Cut() bool {
+	return s.cut
+}
+
+// Err returns the first error other than the end of the input
+func (s *LineScanner) Err() error {
+	return s.scanner.Err()
+}
+
+func (s *LineScanner) split(data []byte, atEOF bool) (int, []byte, error) {
+	if s.skipping {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			// Go on to the next line now, as the scanner reads again
+			// before splitting when no token is returned
+			s.skipping = false
+			advance, token, err := s.split(data[i+1:], atEOF)
+			if advance == 0 && token == nil && err == nil {
+				return i + 1, nil, nil
+			}
+			return i + 1 + advance, token, err
+		}
+		return len(data), nil, nil
+	}
+
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance > 0 || token != nil || err != nil {
+		s.cut = false
+		return advance, token, err
+	}
+
+	// No newline within the maximum: hand over what fits
+	if len(data) > s.max {
+		s.cut = true
+		s.skipping = true
+		return s.max, data[:s.max], nil
+	}
+	return 0, nil, nil
+}
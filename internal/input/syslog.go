@@ -1,8 +1,6 @@
 package input
 
 import (
-	"bufio"
-	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
@@ -11,7 +9,6 @@ import (
 
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
-	"golang.org/x/time/rate"
 )
 
 // SyslogConfig holds configuration for syslog input
@@ -30,6 +27,9 @@ type SyslogConfig struct {
 	RateLimit int
 	// Buffer size for events channel
 	BufferSize int
+	// Largest message read: the UDP datagram buffer and the longest TCP
+	// line, beyond which lines are cut (the size limit, else 64KB)
+	MaxMessageBytes int
 }
 
 // SyslogInput receives syslog messages over TCP/UDP
@@ -39,8 +39,7 @@ type SyslogInput struct {
 	logger   *logging.Logger
 	tcpLn    net.Listener
 	udpConn  *net.UDPConn
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
+	limiters *clientLimiters
 	wg       sync.WaitGroup
 }
 
@@ -54,7 +53,7 @@ func NewSyslogInput(name string, config *SyslogConfig, logger *logging.Logger) (
 		BaseInput: NewBaseInput(name, "syslog", config.BufferSize),
 		config:    config,
 		logger:    logger.WithComponent("input-syslog"),
-		limiters:  make(map[string]*rate.Limiter),
+		limiters:  newClientLimiters(config.RateLimit, limiterIdleTTL),
 	}, nil
 }
 
@@ -79,6 +78,9 @@ func (s *SyslogInput) Start() error {
 		}
 	}
 
+	// Evict the limiters of clients that stopped sending
+	go s.limiters.run(s.Context(), limiterSweepInterval)
+
 	s.logger.Info().
 		Str("protocol", protocol).
 		Str("address", s.config.Address).
@@ -112,9 +114,7 @@ func (s *SyslogInput) Health() Health {
 	details["protocol"] = s.config.Protocol
 	details["address"] = s.config.Address
 
-	s.mu.RLock()
-	details["active_clients"] = len(s.limiters)
-	s.mu.RUnlock()
+	details["active_clients"] = s.limiters.len()
 
 	return Health{
 		Status:  HealthStatusHealthy,
@@ -188,10 +188,9 @@ func (s *SyslogInput) handleTCP(conn net.Conn) {
 	clientAddr := conn.RemoteAddr().String()
 	s.logger.Debug().Str("client", clientAddr).Msg("New TCP connection")
 
-	// Get or create rate limiter for this client
-	limiter := s.getRateLimiter(clientAddr)
-
-	scanner := bufio.NewScanner(conn)
+	maxBytes := s.MaxLineBytes(s.config.MaxMessageBytes)
+	scanner := NewLineScanner(conn, maxBytes)
+	cutLogger := s.logger.RateLimited()
 	for scanner.Scan() {
 		select {
 		case <-s.Context().Done():
@@ -200,15 +199,19 @@ func (s *SyslogInput) handleTCP(conn net.Conn) {
 		}
 
 		// Apply rate limiting
-		if limiter != nil && !limiter.Allow() {
+		if !s.allow(clientAddr) {
 			s.logger.Warn().Str("client", clientAddr).Msg("Rate limit exceeded")
 			continue
 		}
 
+		if scanner.Cut() {
+			cutLogger.Warn().Str("client", clientAddr).Int("max_bytes", maxBytes).Msg("Line over the maximum size cut")
+		}
+
 		line := scanner.Text()
 		event := s.parseMessage(line, clientAddr)
 		if event != nil {
-			s.SendEvent(event)
+			s.SendLine(event, scanner.Cut())
 		}
 	}
 
@@ -241,7 +244,10 @@ func (s *SyslogInput) startUDP() error {
 func (s *SyslogInput) receiveUDP() {
 	defer s.wg.Done()
 
-	buf := make([]byte, 65536) // Max UDP packet size
+	// The byte over the maximum tells datagrams that were cut
+	max := s.MaxLineBytes(s.config.MaxMessageBytes)
+	buf := make([]byte, max+1)
+	cutLogger := s.logger.RateLimited()
 
 	for {
 		select {
@@ -264,57 +270,38 @@ func (s *SyslogInput) receiveUDP() {
 		clientAddr := addr.String()
 
 		// Apply rate limiting
-		limiter := s.getRateLimiter(clientAddr)
-		if limiter != nil && !limiter.Allow() {
+		if !s.allow(clientAddr) {
 			s.logger.Warn().Str("client", clientAddr).Msg("Rate limit exceeded")
 			continue
 		}
 
-		message := string(buf[:n])
+		cut := n > max
+		if cut {
+			cutLogger.Warn().Str("client", clientAddr).Int("max_bytes", max).Msg("Datagram over the maximum size cut")
+		}
+		message := string(buf[:min(n, max)])
 		event := s.parseMessage(message, clientAddr)
 		if event != nil {
-			s.SendEvent(event)
+			s.SendLine(event, cut)
 		}
 	}
 }
 
-// getRateLimiter gets or creates a rate limiter for a client
-func (s *SyslogInput) getRateLimiter(clientAddr string) *rate.Limiter {
-	if s.config.RateLimit <= 0 {
-		return nil
-	}
-
-	s.mu.RLock()
-	limiter, exists := s.limiters[clientAddr]
-	s.mu.RUnlock()
-
-	if !exists {
-		// Create new rate limiter: RateLimit events per second, burst of 2x
-		limiter = rate.NewLimiter(rate.Limit(s.config.RateLimit), s.config.RateLimit*2)
-		s.mu.Lock()
-		s.limiters[clientAddr] = limiter
-		s.mu.Unlock()
-
-		// Clean up old limiters periodically
-		go s.cleanupLimiter(clientAddr)
-	}
-
-	return limiter
+// SetRateLimit changes the events allowed per second per client. The
+// limiters of connected clients are updated in place, so open connections
+// are throttled at the new rate without being dropped.
+func (s *SyslogInput) SetRateLimit(perSecond int) {
+	s.limiters.setLimit(perSecond)
 }
 
-// cleanupLimiter removes inactive rate limiters
-func (s *SyslogInput) cleanupLimiter(clientAddr string) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	select {
-	case <-ticker.C:
-		s.mu.Lock()
-		delete(s.limiters, clientAddr)
-		s.mu.Unlock()
-	case <-s.Context().Done():
-		return
+// allow reports whether the client at clientAddr may send another event.
+// Clients share a limiter per IP address, looked up for every event so
+// that it stays in use and picks up rate limit changes.
+func (s *SyslogInput) allow(clientAddr string) bool {
+	if !s.limiters.enabled() {
+		return true
 	}
+	return s.limiters.get(clientAddr).Allow()
 }
 
 // parseMessage parses a syslog message based on configured format
@@ -324,7 +311,7 @@ func (s *SyslogInput) parseMessage(message, source string) *types.LogEvent {
 		format = "3164" // Default to BSD format
 	}
 
-	var fields map[string]interface{}
+	var fields map[string]string
 	var err error
 
 	switch format {
@@ -334,13 +321,13 @@ func (s *SyslogInput) parseMessage(message, source string) *types.LogEvent {
 		fields, err = parseRFC5424(message)
 	default:
 		s.logger.Warn().Str("format", format).Msg("Unknown syslog format")
-		fields = make(map[string]interface{})
+		fields = make(map[string]string)
 	}
 
 	if err != nil {
 		s.logger.Debug().Err(err).Str("message", message).Msg("Failed to parse syslog message")
 		// Still create event with raw message
-		fields = make(map[string]interface{})
+		fields = make(map[string]string)
 	}
 
 	// Add metadata
@@ -358,8 +345,8 @@ func (s *SyslogInput) parseMessage(message, source string) *types.LogEvent {
 
 // parseRFC3164 parses BSD syslog format (RFC 3164)
 // Format: <PRI>TIMESTAMP HOSTNAME TAG: MESSAGE
-func parseRFC3164(message string) (map[string]interface{}, error) {
-	fields := make(map[string]interface{})
+func parseRFC3164(message string) (map[string]string, error) {
+	fields := make(map[string]string)
 
 	// Simple parsing - can be enhanced with proper syslog parser
 	// For now, just extract the basic structure
@@ -374,8 +361,8 @@ func parseRFC3164(message string) (map[string]interface{}, error) {
 
 // parseRFC5424 parses new syslog format (RFC 5424)
 // Format: <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
-func parseRFC5424(message string) (map[string]interface{}, error) {
-	fields := make(map[string]interface{})
+func parseRFC5424(message string) (map[string]string, error) {
+	fields := make(map[string]string)
 
 	// Simple parsing - can be enhanced with proper syslog parser
 	fields["format"] = "rfc5424"
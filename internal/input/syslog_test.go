@@ -2,10 +2,14 @@ package input
 
 import (
 	"net"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 func TestSyslogInput(t *testing.T) {
@@ -129,6 +133,62 @@ func TestSyslogInput(t *testing.T) {
 		}
 	})
 
+	t.Run("TCPLongLine", func(t *testing.T) {
+		config := &SyslogConfig{
+			Protocol:   "tcp",
+			Address:    "localhost:5144",
+			Format:     "3164",
+			BufferSize: 100,
+		}
+
+		input, err := NewSyslogInput("test-syslog", config, logger)
+		if err != nil {
+			t.Fatalf("failed to create syslog input: %v", err)
+		}
+		collector := metrics.NewCollector()
+		input.SetCollector(collector)
+
+		if err := input.Start(); err != nil {
+			t.Fatalf("failed to start syslog input: %v", err)
+		}
+		defer input.Stop()
+
+		// Give the server time to start
+		time.Sleep(100 * time.Millisecond)
+
+		conn, err := net.Dial("tcp", config.Address)
+		if err != nil {
+			t.Fatalf("failed to connect to syslog server: %v", err)
+		}
+		defer conn.Close()
+
+		// A line over bufio.Scanner's 64KB token limit must not end the
+		// connection
+		long := "<34>Oct 11 22:14:15 mymachine app: " + strings.Repeat("x", 100*1024) + "\n"
+		next := "<34>Oct 11 22:14:16 mymachine app: next\n"
+		if _, err := conn.Write([]byte(long + next)); err != nil {
+			t.Fatalf("failed to send syslog messages: %v", err)
+		}
+
+		for i, wantTruncated := range []string{"true", ""} {
+			select {
+			case event := <-input.Events():
+				if event.Fields[TruncatedField] != wantTruncated {
+					t.Errorf("event %d: truncated = %q, want %q", i, event.Fields[TruncatedField], wantTruncated)
+				}
+				if i == 1 && !strings.HasSuffix(event.Message, "next") {
+					t.Errorf("expected the line after the long one, got %q", event.Message)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timeout waiting for event %d", i)
+			}
+		}
+
+		if truncated := testutil.ToFloat64(collector.InputEventsTruncated.WithLabelValues("test-syslog", "syslog")); truncated != 1 {
+			t.Errorf("expected 1 truncated event, got %v", truncated)
+		}
+	})
+
 	t.Run("RateLimiting", func(t *testing.T) {
 		config := &SyslogConfig{
 			Protocol:   "udp",
@@ -240,3 +300,53 @@ func TestSyslogInput(t *testing.T) {
 		}
 	})
 }
+
+func TestSyslogInput_SetRateLimit(t *testing.T) {
+	logger := logging.New(logging.Config{
+		Level:  "info",
+		Format: "json",
+	})
+
+	input, err := NewSyslogInput("test-syslog", &SyslogConfig{Protocol: "udp", Address: "localhost:0", RateLimit: 100, BufferSize: 10}, logger)
+	if err != nil {
+		t.Fatalf("failed to create syslog input: %v", err)
+	}
+
+	const client = "10.0.0.1:5000"
+	allowed := func(n int) int {
+		var count int
+		for i := 0; i < n; i++ {
+			if input.allow(client) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := allowed(10); got != 10 {
+		t.Fatalf("expected 10 messages allowed at 100/s, got %d", got)
+	}
+	limiter := input.limiters.get(client)
+
+	// Lowering the limit to 1 per second throttles the client to a burst
+	// of 2, with its existing limiter
+	input.SetRateLimit(1)
+	if got := allowed(5); got != 2 {
+		t.Errorf("expected 2 of 5 messages allowed after lowering the limit, got %d", got)
+	}
+	if input.limiters.get(client) != limiter {
+		t.Error("expected the client's limiter updated in place")
+	}
+
+	// Raising it relaxes throttling for the same client
+	input.SetRateLimit(1000)
+	time.Sleep(20 * time.Millisecond)
+	if got := allowed(10); got != 10 {
+		t.Errorf("expected 10 messages allowed after raising the limit, got %d", got)
+	}
+
+	// Other connections from the same IP share the limiter
+	if input.limiters.get("10.0.0.1:5001") != limiter {
+		t.Error("expected clients to share a limiter per IP")
+	}
+}
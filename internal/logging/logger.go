@@ -3,15 +3,28 @@ package logging
 import (
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// Defaults of the sampling applied by RateLimited
+const (
+	DefaultSampleBurst    = 10
+	DefaultSampleInterval = time.Second
+)
+
+// maxSampledMessages bounds the messages a sampler tracks
+const maxSampledMessages = 1000
+
 // Logger wraps zerolog.Logger
 type Logger struct {
 	zerolog.Logger
+
+	sampling SamplingConfig
+	sampled  bool // whether the logger's messages are sampled
 }
 
 // Config holds logger configuration
@@ -19,27 +32,24 @@ type Config struct {
 	Level  string
 	Format string // "json" or "console"
 	Output io.Writer
+
+	// Sampling limits repeated messages; disabled when Burst is zero
+	Sampling SamplingConfig
+}
+
+// SamplingConfig limits how often the same message is logged
+type SamplingConfig struct {
+	// Burst is how many messages with the same level and text are logged
+	// per interval; the rest are dropped
+	Burst int
+
+	// Interval is the sampling window (DefaultSampleInterval by default)
+	Interval time.Duration
 }
 
 // New creates a new logger instance
 func New(cfg Config) *Logger {
-	var level zerolog.Level
-	switch cfg.Level {
-	case "debug":
-		level = zerolog.DebugLevel
-	case "info":
-		level = zerolog.InfoLevel
-	case "warn":
-		level = zerolog.WarnLevel
-	case "error":
-		level = zerolog.ErrorLevel
-	case "fatal":
-		level = zerolog.FatalLevel
-	default:
-		level = zerolog.InfoLevel
-	}
-
-	zerolog.SetGlobalLevel(level)
+	SetLevel(cfg.Level)
 
 	output := cfg.Output
 	if output == nil {
@@ -56,7 +66,40 @@ func New(cfg Config) *Logger {
 		logger = zerolog.New(output).With().Timestamp().Logger()
 	}
 
-	return &Logger{Logger: logger}
+	l := &Logger{Logger: logger, sampling: cfg.Sampling}
+	if cfg.Sampling.Burst > 0 {
+		l = l.sample(cfg.Sampling)
+	}
+	return l
+}
+
+// SetLevel sets the minimum level logged by every logger. Unknown levels
+// fall back to info.
+func SetLevel(level string) {
+	zerolog.SetGlobalLevel(parseLevel(level))
+}
+
+// Level returns the name of the minimum level logged
+func Level() string {
+	return zerolog.GlobalLevel().String()
+}
+
+// parseLevel converts a level name into a zerolog level
+func parseLevel(level string) zerolog.Level {
+	switch level {
+	case "debug":
+		return zerolog.DebugLevel
+	case "info":
+		return zerolog.InfoLevel
+	case "warn":
+		return zerolog.WarnLevel
+	case "error":
+		return zerolog.ErrorLevel
+	case "fatal":
+		return zerolog.FatalLevel
+	default:
+		return zerolog.InfoLevel
+	}
 }
 
 // SetGlobal sets the global logger
@@ -71,14 +114,124 @@ func Global() *Logger {
 
 // WithComponent creates a child logger with a component field
 func (l *Logger) WithComponent(component string) *Logger {
-	return &Logger{
-		Logger: l.Logger.With().Str("component", component).Logger(),
-	}
+	return l.child(l.Logger.With().Str("component", component).Logger())
 }
 
 // WithField adds a field to the logger
 func (l *Logger) WithField(key string, value interface{}) *Logger {
-	return &Logger{
-		Logger: l.Logger.With().Interface(key, value).Logger(),
+	return l.child(l.Logger.With().Interface(key, value).Logger())
+}
+
+// With creates a child logger adding fields to every message
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	return l.child(l.Logger.With().Fields(fields).Logger())
+}
+
+// RateLimited returns a logger for hot paths that logs at most a burst of
+// messages with the same level and text per interval, using the logger's
+// sampling settings or DefaultSampleBurst per DefaultSampleInterval. The
+// first message logged after some were dropped carries their number in a
+// suppressed field. Keep the returned logger: each call starts a new
+// sampler.
+func (l *Logger) RateLimited() *Logger {
+	if l.sampled {
+		return l
+	}
+
+	sampling := l.sampling
+	if sampling.Burst <= 0 {
+		sampling.Burst = DefaultSampleBurst
+	}
+	return l.sample(sampling)
+}
+
+// child wraps a logger derived from l, keeping its sampling
+func (l *Logger) child(logger zerolog.Logger) *Logger {
+	return &Logger{Logger: logger, sampling: l.sampling, sampled: l.sampled}
+}
+
+// sample returns l with its messages sampled
+func (l *Logger) sample(sampling SamplingConfig) *Logger {
+	if sampling.Interval <= 0 {
+		sampling.Interval = DefaultSampleInterval
+	}
+
+	sampler := &messageSampler{
+		burst:    sampling.Burst,
+		interval: sampling.Interval,
+		now:      time.Now,
+		windows:  make(map[sampleKey]*sampleWindow),
+	}
+	return &Logger{Logger: l.Logger.Hook(sampler), sampling: sampling, sampled: true}
+}
+
+// sampleKey identifies the messages sampled together
+type sampleKey struct {
+	level zerolog.Level
+	msg   string
+}
+
+// sampleWindow counts the messages logged in the current interval
+type sampleWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// messageSampler is a hook dropping messages past the burst of their
+// window
+type messageSampler struct {
+	burst    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	windows map[sampleKey]*sampleWindow
+}
+
+// Run discards the event when its message is over the burst
+func (s *messageSampler) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if !e.Enabled() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := sampleKey{level: level, msg: msg}
+	window, ok := s.windows[key]
+	if !ok || now.Sub(window.start) >= s.interval {
+		if !ok {
+			s.prune(now)
+			window = &sampleWindow{}
+			s.windows[key] = window
+		}
+		if window.suppressed > 0 {
+			e.Int("suppressed", window.suppressed)
+		}
+		*window = sampleWindow{start: now}
+	}
+
+	window.count++
+	if window.count > s.burst {
+		window.suppressed++
+		e.Discard()
+	}
+}
+
+// prune forgets expired windows when the sampler tracks too many messages,
+// and every window if they are all current
+func (s *messageSampler) prune(now time.Time) {
+	if len(s.windows) < maxSampledMessages {
+		return
+	}
+	for key, window := range s.windows {
+		if now.Sub(window.start) >= s.interval {
+			delete(s.windows, key)
+		}
+	}
+	if len(s.windows) >= maxSampledMessages {
+		s.windows = make(map[sampleKey]*sampleWindow)
 	}
 }
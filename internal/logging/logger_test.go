@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logLines decodes the JSON lines written to buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{
+		Level:    "info",
+		Format:   "json",
+		Output:   &buf,
+		Sampling: SamplingConfig{Burst: 3, Interval: time.Hour},
+	})
+
+	for i := 0; i < 10; i++ {
+		logger.Warn().Int("line", i).Msg("Failed to parse log line")
+		logger.Info().Msg("other message")
+	}
+	logger.Error().Msg("Failed to parse log line")
+
+	counts := make(map[string]int)
+	for _, entry := range logLines(t, &buf) {
+		counts[entry["level"].(string)+" "+entry["message"].(string)]++
+	}
+	want := map[string]int{
+		"warn Failed to parse log line":  3,
+		"info other message":             3,
+		"error Failed to parse log line": 1,
+	}
+	for key, n := range want {
+		if counts[key] != n {
+			t.Errorf("%q logged %d times, want %d", key, counts[key], n)
+		}
+	}
+}
+
+func TestMessageSampler_Window(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{Level: "info", Format: "json", Output: &buf})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampler := &messageSampler{
+		burst:    2,
+		interval: time.Second,
+		now:      func() time.Time { return now },
+		windows:  make(map[sampleKey]*sampleWindow),
+	}
+	sampled := logger.Hook(sampler)
+
+	for i := 0; i < 5; i++ {
+		sampled.Warn().Msg("hot path")
+	}
+	now = now.Add(time.Second)
+	sampled.Warn().Msg("hot path")
+
+	lines := logLines(t, &buf)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), buf.String())
+	}
+	if _, ok := lines[1]["suppressed"]; ok {
+		t.Errorf("unexpected suppressed count in the first window: %v", lines[1])
+	}
+	if lines[2]["suppressed"] != float64(3) {
+		t.Errorf("expected the next window to report 3 suppressed, got %v", lines[2])
+	}
+}
+
+func TestLogger_RateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{Level: "info", Format: "json", Output: &buf})
+
+	limited := logger.RateLimited()
+	if limited.RateLimited() != limited {
+		t.Error("expected a rate-limited logger to be returned as is")
+	}
+
+	for i := 0; i < DefaultSampleBurst+5; i++ {
+		limited.Warn().Msg("flood")
+		logger.Warn().Msg("unsampled")
+	}
+
+	counts := make(map[string]int)
+	for _, entry := range logLines(t, &buf) {
+		counts[entry["message"].(string)]++
+	}
+	if counts["flood"] != DefaultSampleBurst || counts["unsampled"] != DefaultSampleBurst+5 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestLogger_WithFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{Level: "info", Format: "json", Output: &buf})
+
+	child := logger.With(map[string]interface{}{"input": "syslog", "port": 514}).WithComponent("receiver")
+	child.RateLimited().Info().Msg("listening")
+	child.WithField("peer", "10.0.0.1").Info().Msg("connected")
+
+	lines := logLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	for _, entry := range lines {
+		if entry["input"] != "syslog" || entry["port"] != float64(514) || entry["component"] != "receiver" {
+			t.Errorf("expected the persistent fields, got %v", entry)
+		}
+	}
+	if lines[1]["peer"] != "10.0.0.1" {
+		t.Errorf("expected the added field, got %v", lines[1])
+	}
+}
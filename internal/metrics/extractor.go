@@ -1,12 +1,19 @@
 package metrics
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
 // MetricType represents the type of metric to extract
@@ -28,26 +35,82 @@ type ExtractionRule struct {
 	LabelFields map[string]string // Dynamic labels from fields (metric_label: field_name)
 	Help        string            // Metric description
 	Buckets     []float64         // Histogram buckets (optional)
+
+	// MaxCardinality caps the distinct combinations of LabelFields values;
+	// further combinations are recorded with OverflowLabelValue. Zero is
+	// unlimited.
+	MaxCardinality int
 }
 
+// OverflowLabelValue replaces the dynamic label values of observations
+// over a rule's MaxCardinality
+const OverflowLabelValue = "__overflow__"
+
 // Extractor extracts metrics from log events
 type Extractor struct {
-	mu      sync.RWMutex
-	rules   []ExtractionRule
-	metrics map[string]interface{} // Stores prometheus metrics
-	regex   map[string]*regexp.Regexp
+	mu       sync.RWMutex
+	rules    []ExtractionRule
+	metrics  map[string]prometheus.Collector // Stores prometheus metric vectors
+	labels   map[string][]string             // Label names per rule
+	regex    map[string]*regexp.Regexp
+	limits   map[string]*seriesLimit // Cardinality limits per rule
+	overflow *prometheus.CounterVec  // Observations collapsed into overflow, per rule
+	logger   *logging.Logger
+}
+
+// seriesLimit tracks the distinct dynamic label combinations of a rule
+type seriesLimit struct {
+	mu      sync.Mutex
+	max     int
+	series  map[string]struct{}
+	reached bool
+}
+
+// admit reports whether a label combination is within the limit, counting
+// it if new. reached is true the first time a combination is rejected.
+func (l *seriesLimit) admit(key string) (admitted, reached bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.series[key]; ok {
+		return true, false
+	}
+	if len(l.series) < l.max {
+		l.series[key] = struct{}{}
+		return true, false
+	}
+
+	reached = !l.reached
+	l.reached = true
+	return false, reached
 }
 
-// NewExtractor creates a new metrics extractor
-func NewExtractor(rules []ExtractionRule) (*Extractor, error) {
+// NewExtractor creates a new metrics extractor and registers the metrics
+// it creates with the given registerer (the default registerer if nil)
+func NewExtractor(rules []ExtractionRule, registerer prometheus.Registerer) (*Extractor, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
 	e := &Extractor{
 		rules:   rules,
-		metrics: make(map[string]interface{}),
+		metrics: make(map[string]prometheus.Collector),
+		labels:  make(map[string][]string),
 		regex:   make(map[string]*regexp.Regexp),
+		limits:  make(map[string]*seriesLimit),
+		logger:  logging.Global().WithComponent("metrics-extractor"),
 	}
 
 	// Compile regex patterns and create metrics
 	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("metric extraction rule has no name")
+		}
+
+		if _, exists := e.metrics[rule.Name]; exists {
+			return nil, fmt.Errorf("duplicate metric extraction rule: %s", rule.Name)
+		}
+
 		if rule.Pattern != "" {
 			re, err := regexp.Compile(rule.Pattern)
 			if err != nil {
@@ -57,63 +120,126 @@ func NewExtractor(rules []ExtractionRule) (*Extractor, error) {
 		}
 
 		// Create prometheus metric based on type
-		if err := e.createMetric(rule); err != nil {
+		metric, err := e.createMetric(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := registerer.Register(metric); err != nil {
+			return nil, fmt.Errorf("failed to register metric %s: %w", rule.Name, err)
+		}
+		e.metrics[rule.Name] = metric
+
+		if rule.MaxCardinality > 0 && len(rule.LabelFields) > 0 {
+			e.limits[rule.Name] = &seriesLimit{max: rule.MaxCardinality, series: make(map[string]struct{})}
+		}
+	}
+
+	if len(e.limits) > 0 {
+		overflow, err := registerOverflow(registerer)
+		if err != nil {
 			return nil, err
 		}
+		e.overflow = overflow
 	}
 
 	return e, nil
 }
 
-func (e *Extractor) createMetric(rule ExtractionRule) error {
-	// Determine label names
-	labelNames := make([]string, 0, len(rule.LabelFields))
+// registerOverflow registers the overflow counter, shared by the
+// extractors of a registry
+func registerOverflow(registerer prometheus.Registerer) (*prometheus.CounterVec, error) {
+	overflow := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "extracted_overflow_total",
+			Help:      "Observations of extracted metrics recorded in the overflow series, over their rule's maximum cardinality",
+		},
+		[]string{"rule"},
+	)
+
+	if err := registerer.Register(overflow); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to register overflow metric: %w", err)
+	}
+	return overflow, nil
+}
+
+// NewExtractor creates a metrics extractor whose metrics are registered on
+// the collector's registry
+func (c *Collector) NewExtractor(rules []ExtractionRule) (*Extractor, error) {
+	return NewExtractor(rules, c.registry)
+}
+
+// RulesFromConfig converts configured extraction rules into ExtractionRules
+func RulesFromConfig(cfg *config.MetricsExtractionConfig) []ExtractionRule {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	rules := make([]ExtractionRule, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		rules[i] = ExtractionRule{
+			Name:        r.Name,
+			Type:        MetricType(r.Type),
+			Field:       r.Field,
+			Pattern:     r.Pattern,
+			Labels:      r.Labels,
+			LabelFields: r.LabelFields,
+			Help:        r.Help,
+			Buckets:     r.Buckets,
+
+			MaxCardinality: r.MaxCardinality,
+		}
+	}
+	return rules
+}
+
+func (e *Extractor) createMetric(rule ExtractionRule) (prometheus.Collector, error) {
+	// Static and dynamic labels share one sorted label set so every
+	// observation carries the same label names
+	labelNames := make([]string, 0, len(rule.LabelFields)+len(rule.Labels))
 	for labelName := range rule.LabelFields {
 		labelNames = append(labelNames, labelName)
 	}
+	for labelName := range rule.Labels {
+		if _, dynamic := rule.LabelFields[labelName]; !dynamic {
+			labelNames = append(labelNames, labelName)
+		}
+	}
+	sort.Strings(labelNames)
+	e.labels[rule.Name] = labelNames
 
 	metricName := fmt.Sprintf("%s_extracted_%s", namespace, rule.Name)
 
+	help := rule.Help
+	if help == "" {
+		help = fmt.Sprintf("Metric extracted from log field %s", rule.Field)
+	}
+
 	switch rule.Type {
 	case MetricTypeCounter:
-		if len(labelNames) > 0 {
-			e.metrics[rule.Name] = prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Name: metricName,
-					Help: rule.Help,
-				},
-				labelNames,
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(*prometheus.CounterVec))
-		} else {
-			e.metrics[rule.Name] = prometheus.NewCounter(
-				prometheus.CounterOpts{
-					Name: metricName,
-					Help: rule.Help,
-				},
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(prometheus.Counter))
-		}
+		return prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: metricName,
+				Help: help,
+			},
+			labelNames,
+		), nil
 
 	case MetricTypeGauge:
-		if len(labelNames) > 0 {
-			e.metrics[rule.Name] = prometheus.NewGaugeVec(
-				prometheus.GaugeOpts{
-					Name: metricName,
-					Help: rule.Help,
-				},
-				labelNames,
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(*prometheus.GaugeVec))
-		} else {
-			e.metrics[rule.Name] = prometheus.NewGauge(
-				prometheus.GaugeOpts{
-					Name: metricName,
-					Help: rule.Help,
-				},
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(prometheus.Gauge))
-		}
+		return prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: metricName,
+				Help: help,
+			},
+			labelNames,
+		), nil
 
 	case MetricTypeHistogram:
 		buckets := rule.Buckets
@@ -121,32 +247,37 @@ func (e *Extractor) createMetric(rule ExtractionRule) error {
 			buckets = prometheus.DefBuckets
 		}
 
-		if len(labelNames) > 0 {
-			e.metrics[rule.Name] = prometheus.NewHistogramVec(
-				prometheus.HistogramOpts{
-					Name:    metricName,
-					Help:    rule.Help,
-					Buckets: buckets,
-				},
-				labelNames,
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(*prometheus.HistogramVec))
-		} else {
-			e.metrics[rule.Name] = prometheus.NewHistogram(
-				prometheus.HistogramOpts{
-					Name:    metricName,
-					Help:    rule.Help,
-					Buckets: buckets,
-				},
-			)
-			prometheus.MustRegister(e.metrics[rule.Name].(prometheus.Histogram))
-		}
+		return prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    metricName,
+				Help:    help,
+				Buckets: buckets,
+			},
+			labelNames,
+		), nil
 
 	default:
-		return fmt.Errorf("unsupported metric type: %s", rule.Type)
+		return nil, fmt.Errorf("unsupported metric type: %s", rule.Type)
 	}
+}
 
-	return nil
+// ExtractEvent evaluates all rules against a log event. The event's
+// message, level, and source are addressable as "message", "level", and
+// "source" unless a field of the same name is present.
+func (e *Extractor) ExtractEvent(event *types.LogEvent) error {
+	if event == nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(event.Fields)+3)
+	fields["message"] = event.Message
+	fields["level"] = event.Level
+	fields["source"] = event.Source
+	for k, v := range event.Fields {
+		fields[k] = v
+	}
+
+	return e.Extract(fields)
 }
 
 // Extract processes a log event and extracts metrics
@@ -159,6 +290,7 @@ func (e *Extractor) Extract(fields map[string]interface{}) error {
 		if err != nil {
 			continue // Skip if extraction fails
 		}
+		e.limitCardinality(rule, labels)
 
 		if err := e.recordMetric(rule, value, labels); err != nil {
 			return err
@@ -191,43 +323,89 @@ func (e *Extractor) extractValue(rule ExtractionRule, fields map[string]interfac
 	case int32:
 		value = float64(v)
 	case string:
-		// Try to parse as number
-		value, err = strconv.ParseFloat(v, 64)
+		value, err = e.parseString(rule, v)
 		if err != nil {
-			// If pattern is specified, try to extract using regex
-			if rule.Pattern != "" {
-				re := e.regex[rule.Name]
-				matches := re.FindStringSubmatch(v)
-				if len(matches) > 1 {
-					value, err = strconv.ParseFloat(matches[1], 64)
-					if err != nil {
-						return 0, nil, fmt.Errorf("failed to parse extracted value: %w", err)
-					}
-				} else {
-					return 0, nil, fmt.Errorf("pattern did not match")
-				}
-			} else {
-				return 0, nil, fmt.Errorf("failed to parse field as number: %w", err)
-			}
+			return 0, nil, err
 		}
 	default:
 		return 0, nil, fmt.Errorf("unsupported field type: %T", v)
 	}
 
-	// Extract labels
-	labels := prometheus.Labels{}
+	// Extract labels; missing label fields get an empty value
+	labels := make(prometheus.Labels, len(e.labels[rule.Name]))
+	for _, labelName := range e.labels[rule.Name] {
+		labels[labelName] = ""
+	}
+
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+
 	for labelName, fieldName := range rule.LabelFields {
 		if labelValue, exists := fields[fieldName]; exists {
 			labels[labelName] = fmt.Sprintf("%v", labelValue)
 		}
 	}
 
-	// Add static labels
-	for k, v := range rule.Labels {
-		labels[k] = v
+	return value, labels, nil
+}
+
+// limitCardinality collapses the dynamic labels of a combination over the
+// rule's maximum cardinality into OverflowLabelValue, warning once per rule
+func (e *Extractor) limitCardinality(rule ExtractionRule, labels prometheus.Labels) {
+	limit, ok := e.limits[rule.Name]
+	if !ok {
+		return
 	}
 
-	return value, labels, nil
+	values := make([]string, 0, len(labels))
+	for _, labelName := range e.labels[rule.Name] {
+		values = append(values, labels[labelName])
+	}
+	admitted, reached := limit.admit(strings.Join(values, "\xff"))
+	if admitted {
+		return
+	}
+
+	if reached {
+		e.logger.Warn().
+			Str("rule", rule.Name).
+			Int("max_cardinality", rule.MaxCardinality).
+			Msg("Extracted metric reached its maximum cardinality, recording further label values as " + OverflowLabelValue)
+	}
+	for labelName := range rule.LabelFields {
+		labels[labelName] = OverflowLabelValue
+	}
+	e.overflow.WithLabelValues(rule.Name).Inc()
+}
+
+// parseString derives a numeric value from a string field. With a pattern,
+// the first capture group is parsed; a pattern without capture groups
+// yields 1 when it matches, which suits counting occurrences.
+func (e *Extractor) parseString(rule ExtractionRule, v string) (float64, error) {
+	re, hasPattern := e.regex[rule.Name]
+	if !hasPattern {
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse field as number: %w", err)
+		}
+		return value, nil
+	}
+
+	matches := re.FindStringSubmatch(v)
+	if matches == nil {
+		return 0, fmt.Errorf("pattern did not match")
+	}
+
+	if len(matches) == 1 {
+		return 1, nil
+	}
+
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse extracted value: %w", err)
+	}
+	return value, nil
 }
 
 func (e *Extractor) recordMetric(rule ExtractionRule, value float64, labels prometheus.Labels) error {
@@ -236,29 +414,19 @@ func (e *Extractor) recordMetric(rule ExtractionRule, value float64, labels prom
 		return fmt.Errorf("metric %s not found", rule.Name)
 	}
 
-	hasLabels := len(labels) > 0
-
 	switch rule.Type {
 	case MetricTypeCounter:
-		if hasLabels {
-			metric.(*prometheus.CounterVec).With(labels).Add(value)
-		} else {
-			metric.(prometheus.Counter).Add(value)
+		// Counters cannot decrease
+		if value < 0 {
+			return nil
 		}
+		metric.(*prometheus.CounterVec).With(labels).Add(value)
 
 	case MetricTypeGauge:
-		if hasLabels {
-			metric.(*prometheus.GaugeVec).With(labels).Set(value)
-		} else {
-			metric.(prometheus.Gauge).Set(value)
-		}
+		metric.(*prometheus.GaugeVec).With(labels).Set(value)
 
 	case MetricTypeHistogram:
-		if hasLabels {
-			metric.(*prometheus.HistogramVec).With(labels).Observe(value)
-		} else {
-			metric.(prometheus.Histogram).Observe(value)
-		}
+		metric.(*prometheus.HistogramVec).With(labels).Observe(value)
 	}
 
 	return nil
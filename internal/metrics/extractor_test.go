@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestExtractor_Counter(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor([]ExtractionRule{
+		{
+			Name:        "errors",
+			Type:        MetricTypeCounter,
+			Field:       "message",
+			Pattern:     "(?i)error",
+			Labels:      map[string]string{"team": "core"},
+			LabelFields: map[string]string{"service": "service"},
+			Help:        "Error lines",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	events := []*types.LogEvent{
+		{Message: "ERROR connecting to db", Fields: map[string]string{"service": "api"}},
+		{Message: "request failed with error", Fields: map[string]string{"service": "api"}},
+		{Message: "all good", Fields: map[string]string{"service": "api"}},
+	}
+	for _, event := range events {
+		if err := e.ExtractEvent(event); err != nil {
+			t.Fatalf("ExtractEvent() error = %v", err)
+		}
+	}
+
+	vec := e.metrics["errors"].(*prometheus.CounterVec)
+	if got := testutil.ToFloat64(vec.With(prometheus.Labels{"service": "api", "team": "core"})); got != 2 {
+		t.Errorf("counter = %v, want 2", got)
+	}
+}
+
+func TestExtractor_Gauge(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor([]ExtractionRule{
+		{
+			Name:    "queue_depth",
+			Type:    MetricTypeGauge,
+			Field:   "status",
+			Pattern: `depth=(\d+)`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	for _, status := range []string{"depth=10", "depth=42"} {
+		event := &types.LogEvent{Fields: map[string]string{"status": status}}
+		if err := e.ExtractEvent(event); err != nil {
+			t.Fatalf("ExtractEvent() error = %v", err)
+		}
+	}
+
+	vec := e.metrics["queue_depth"].(*prometheus.GaugeVec)
+	if got := testutil.ToFloat64(vec.With(prometheus.Labels{})); got != 42 {
+		t.Errorf("gauge = %v, want 42", got)
+	}
+}
+
+func TestExtractor_Histogram(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor([]ExtractionRule{
+		{
+			Name:        "response_time",
+			Type:        MetricTypeHistogram,
+			Field:       "duration_ms",
+			LabelFields: map[string]string{"method": "method"},
+			Buckets:     []float64{10, 100, 1000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	for _, duration := range []string{"5", "50", "500"} {
+		event := &types.LogEvent{Fields: map[string]string{"duration_ms": duration, "method": "GET"}}
+		if err := e.ExtractEvent(event); err != nil {
+			t.Fatalf("ExtractEvent() error = %v", err)
+		}
+	}
+
+	vec := e.metrics["response_time"].(*prometheus.HistogramVec)
+	metric := &dto.Metric{}
+	if err := vec.With(prometheus.Labels{"method": "GET"}).(prometheus.Histogram).Write(metric); err != nil {
+		t.Fatalf("failed to write metric: %v", err)
+	}
+
+	if got := metric.GetHistogram().GetSampleCount(); got != 3 {
+		t.Errorf("sample count = %d, want 3", got)
+	}
+	if got := metric.GetHistogram().GetSampleSum(); got != 555 {
+		t.Errorf("sample sum = %v, want 555", got)
+	}
+}
+
+func TestExtractor_MissingField(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor([]ExtractionRule{
+		{
+			Name:        "latency",
+			Type:        MetricTypeHistogram,
+			Field:       "latency_ms",
+			LabelFields: map[string]string{"host": "hostname"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	event := &types.LogEvent{Message: "no numbers here", Fields: map[string]string{"other": "1"}}
+	if err := e.ExtractEvent(event); err != nil {
+		t.Errorf("ExtractEvent() error = %v, want nil", err)
+	}
+
+	if err := e.ExtractEvent(nil); err != nil {
+		t.Errorf("ExtractEvent(nil) error = %v, want nil", err)
+	}
+
+	if got := testutil.CollectAndCount(e.metrics["latency"]); got != 0 {
+		t.Errorf("collected %d series, want 0", got)
+	}
+}
+
+func TestExtractor_RegistersOnCollector(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor(RulesFromConfig(&config.MetricsExtractionConfig{
+		Enabled: true,
+		Rules: []config.MetricExtractionRule{
+			{Name: "bytes", Type: "counter", Field: "bytes", Help: "Bytes"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	if err := e.ExtractEvent(&types.LogEvent{Fields: map[string]string{"bytes": "128"}}); err != nil {
+		t.Fatalf("ExtractEvent() error = %v", err)
+	}
+
+	families, err := c.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	found := false
+	for _, family := range families {
+		if family.GetName() == "logaggregator_extracted_bytes" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("extracted metric not registered on collector registry")
+	}
+}
+
+func TestExtractor_InvalidRules(t *testing.T) {
+	c := NewCollector()
+
+	if _, err := c.NewExtractor([]ExtractionRule{{Name: "bad", Type: "summary", Field: "x"}}); err == nil {
+		t.Error("expected error for unsupported metric type")
+	}
+
+	if _, err := c.NewExtractor([]ExtractionRule{{Name: "bad_pattern", Type: MetricTypeCounter, Pattern: "("}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestExtractor_MaxCardinality(t *testing.T) {
+	c := NewCollector()
+	e, err := c.NewExtractor(RulesFromConfig(&config.MetricsExtractionConfig{
+		Enabled: true,
+		Rules: []config.MetricExtractionRule{
+			{
+				Name:           "requests",
+				Type:           "counter",
+				Field:          "count",
+				Labels:         map[string]string{"team": "core"},
+				LabelFields:    map[string]string{"request_id": "request_id"},
+				MaxCardinality: 3,
+			},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		event := &types.LogEvent{Fields: map[string]string{"count": "1", "request_id": fmt.Sprintf("req-%d", i)}}
+		if err := e.ExtractEvent(event); err != nil {
+			t.Fatalf("ExtractEvent() error = %v", err)
+		}
+	}
+	// A combination seen before the limit keeps its series
+	if err := e.ExtractEvent(&types.LogEvent{Fields: map[string]string{"count": "1", "request_id": "req-0"}}); err != nil {
+		t.Fatalf("ExtractEvent() error = %v", err)
+	}
+
+	vec := e.metrics["requests"].(*prometheus.CounterVec)
+	if series := testutil.CollectAndCount(vec); series != 4 {
+		t.Errorf("expected 3 series and the overflow one, got %d", series)
+	}
+	if got := testutil.ToFloat64(vec.With(prometheus.Labels{"request_id": "req-0", "team": "core"})); got != 2 {
+		t.Errorf("req-0 counter = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(vec.With(prometheus.Labels{"request_id": OverflowLabelValue, "team": "core"})); got != 7 {
+		t.Errorf("overflow counter = %v, want 7", got)
+	}
+	if got := testutil.ToFloat64(e.overflow.WithLabelValues("requests")); got != 7 {
+		t.Errorf("overflow observations = %v, want 7", got)
+	}
+
+	// Extractors of the same registry share the overflow counter
+	other, err := c.NewExtractor([]ExtractionRule{
+		{Name: "paths", Type: MetricTypeCounter, Field: "count", LabelFields: map[string]string{"path": "path"}, MaxCardinality: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor() error = %v", err)
+	}
+	if other.overflow != e.overflow {
+		t.Error("expected the overflow counter shared")
+	}
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// JSONPathSuffix is appended to the metrics path to serve the JSON snapshot
+const JSONPathSuffix = "/json"
+
+// Snapshot is the current value of every metric family of a registry, for
+// consumers that poll JSON rather than scrape Prometheus
+type Snapshot struct {
+	Timestamp time.Time                 `json:"timestamp"`
+	Metrics   map[string]FamilySnapshot `json:"metrics"`
+}
+
+// FamilySnapshot holds the series of a metric family
+type FamilySnapshot struct {
+	Type    string           `json:"type"`
+	Help    string           `json:"help,omitempty"`
+	Samples []SampleSnapshot `json:"samples"`
+}
+
+// SampleSnapshot is the value of one series. Counters, gauges and untyped
+// metrics have a value; histograms and summaries a count, a sum and their
+// buckets or quantiles, keyed by upper bound or quantile.
+type SampleSnapshot struct {
+	Labels    map[string]string  `json:"labels,omitempty"`
+	Value     *float64           `json:"value,omitempty"`
+	Count     *uint64            `json:"count,omitempty"`
+	Sum       *float64           `json:"sum,omitempty"`
+	Buckets   map[string]uint64  `json:"buckets,omitempty"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+}
+
+// TakeSnapshot gathers the current metric values of gatherer
+func TakeSnapshot(gatherer prometheus.Gatherer) (*Snapshot, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Timestamp: time.Now(),
+		Metrics:   make(map[string]FamilySnapshot, len(families)),
+	}
+	for _, family := range families {
+		samples := make([]SampleSnapshot, 0, len(family.GetMetric()))
+		for _, metric := range family.GetMetric() {
+			samples = append(samples, sampleSnapshot(family.GetType(), metric))
+		}
+		snapshot.Metrics[family.GetName()] = FamilySnapshot{
+			Type:    typeName(family.GetType()),
+			Help:    family.GetHelp(),
+			Samples: samples,
+		}
+	}
+	return snapshot, nil
+}
+
+// sampleSnapshot converts a gathered series. Non-finite values, which JSON
+// cannot represent, are left out.
+func sampleSnapshot(metricType dto.MetricType, metric *dto.Metric) SampleSnapshot {
+	var sample SampleSnapshot
+	if len(metric.GetLabel()) > 0 {
+		sample.Labels = make(map[string]string, len(metric.GetLabel()))
+		for _, label := range metric.GetLabel() {
+			sample.Labels[label.GetName()] = label.GetValue()
+		}
+	}
+
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		sample.Value = finite(metric.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		sample.Value = finite(metric.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		sample.Value = finite(metric.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		count := histogram.GetSampleCount()
+		sample.Count = &count
+		sample.Sum = finite(histogram.GetSampleSum())
+		sample.Buckets = make(map[string]uint64, len(histogram.GetBucket())+1)
+		for _, bucket := range histogram.GetBucket() {
+			sample.Buckets[formatFloat(bucket.GetUpperBound())] = bucket.GetCumulativeCount()
+		}
+		sample.Buckets["+Inf"] = count
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		count := summary.GetSampleCount()
+		sample.Count = &count
+		sample.Sum = finite(summary.GetSampleSum())
+		sample.Quantiles = make(map[string]float64, len(summary.GetQuantile()))
+		for _, quantile := range summary.GetQuantile() {
+			if value := finite(quantile.GetValue()); value != nil {
+				sample.Quantiles[formatFloat(quantile.GetQuantile())] = *value
+			}
+		}
+	}
+	return sample
+}
+
+// typeName returns the Prometheus exposition name of a metric type
+func typeName(metricType dto.MetricType) string {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return "counter"
+	case dto.MetricType_GAUGE:
+		return "gauge"
+	case dto.MetricType_HISTOGRAM:
+		return "histogram"
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		return "gaugehistogram"
+	case dto.MetricType_SUMMARY:
+		return "summary"
+	default:
+		return "untyped"
+	}
+}
+
+// finite returns a pointer to value, or nil if it is NaN or infinite
+func finite(value float64) *float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+	return &value
+}
+
+// formatFloat formats a bucket bound or quantile as a JSON object key
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// JSONHandler serves a JSON snapshot of gatherer's metrics
+func JSONHandler(gatherer prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := TakeSnapshot(gatherer)
+		if err != nil {
+			http.Error(w, "failed to gather metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(snapshot)
+	})
+}
@@ -18,6 +18,7 @@ type Collector struct {
 	InputEventsReceived   *prometheus.CounterVec
 	InputBytesReceived    *prometheus.CounterVec
 	InputEventsDropped    *prometheus.CounterVec
+	InputEventsTruncated  *prometheus.CounterVec
 	InputConnectionsTotal *prometheus.GaugeVec
 	InputRateLimited      *prometheus.CounterVec
 
@@ -25,6 +26,7 @@ type Collector struct {
 	ParserEventsProcessed *prometheus.CounterVec
 	ParserEventsFailed    *prometheus.CounterVec
 	ParserDuration        *prometheus.HistogramVec
+	ParserAutoDetected    *prometheus.CounterVec
 
 	// Buffer metrics
 	BufferSize        *prometheus.GaugeVec
@@ -39,11 +41,15 @@ type Collector struct {
 	WALCompactionCount *prometheus.CounterVec
 
 	// Output metrics
-	OutputEventsSent   *prometheus.CounterVec
-	OutputEventsFailed *prometheus.CounterVec
-	OutputBytesSent    *prometheus.CounterVec
-	OutputDuration     *prometheus.HistogramVec
-	OutputBatchSize    *prometheus.HistogramVec
+	OutputEventsSent        *prometheus.CounterVec
+	OutputEventsFailed      *prometheus.CounterVec
+	OutputBytesSent         *prometheus.CounterVec
+	OutputDuration          *prometheus.HistogramVec
+	OutputBatchSize         *prometheus.HistogramVec
+	OutputBatchFlushes      *prometheus.CounterVec
+	OutputAdaptiveSize      *prometheus.GaugeVec
+	OutputInflightBatches   *prometheus.GaugeVec
+	RouterEventsUndelivered prometheus.Counter
 
 	// Worker pool metrics
 	WorkerPoolSize    *prometheus.GaugeVec
@@ -52,14 +58,24 @@ type Collector struct {
 	WorkerJobDuration *prometheus.HistogramVec
 
 	// System metrics
-	SystemGoroutines *prometheus.Gauge
-	SystemMemAlloc   *prometheus.Gauge
-	SystemMemSys     *prometheus.Gauge
-	SystemGCPauses   *prometheus.Histogram
+	SystemGoroutines prometheus.Gauge
+	SystemMemAlloc   prometheus.Gauge
+	SystemMemSys     prometheus.Gauge
+	SystemGCPauses   prometheus.Histogram
+
+	// SystemMemoryPressureFlushes counts the output flushes forced by the
+	// memory watchdog
+	SystemMemoryPressureFlushes prometheus.Counter
+
+	// Dedup metrics
+	DedupEventsDropped prometheus.Counter
+
+	// Timestamp skew metrics
+	TimestampSkewedEvents *prometheus.CounterVec
 
 	// Dead letter queue metrics
-	DLQEventsWritten *prometheus.Counter
-	DLQSize          *prometheus.Gauge
+	DLQEventsWritten prometheus.Counter
+	DLQSize          prometheus.Gauge
 
 	// Circuit breaker metrics
 	CircuitBreakerState       *prometheus.GaugeVec
@@ -88,6 +104,8 @@ func NewCollector() *Collector {
 	c.initOutputMetrics()
 	c.initWorkerPoolMetrics()
 	c.initSystemMetrics()
+	c.initDedupMetrics()
+	c.initTimestampSkewMetrics()
 	c.initDLQMetrics()
 	c.initCircuitBreakerMetrics()
 	c.initHealthMetrics()
@@ -126,6 +144,16 @@ func (c *Collector) initInputMetrics() {
 		[]string{"input_name", "input_type", "reason"},
 	)
 
+	c.InputEventsTruncated = promauto.With(c.registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "input",
+			Name:      "events_truncated_total",
+			Help:      "Total number of events truncated to the size limit by input source",
+		},
+		[]string{"input_name", "input_type"},
+	)
+
 	c.InputConnectionsTotal = promauto.With(c.registry).NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
@@ -178,6 +206,16 @@ func (c *Collector) initParserMetrics() {
 		},
 		[]string{"parser_type"},
 	)
+
+	c.ParserAutoDetected = promauto.With(c.registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "parser",
+			Name:      "auto_detected_total",
+			Help:      "Total number of lines the auto parser resolved to each format",
+		},
+		[]string{"format"},
+	)
 }
 
 func (c *Collector) initBufferMetrics() {
@@ -317,6 +355,45 @@ func (c *Collector) initOutputMetrics() {
 		},
 		[]string{"output_name", "output_type"},
 	)
+
+	c.OutputBatchFlushes = promauto.With(c.registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "output",
+			Name:      "batch_flushes_total",
+			Help:      "Total number of batches flushed, by trigger (count, size, time, manual)",
+		},
+		[]string{"output_name", "output_type", "trigger"},
+	)
+
+	c.OutputAdaptiveSize = promauto.With(c.registry).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "output",
+			Name:      "adaptive_batch_size",
+			Help:      "Current batch size chosen by adaptive batching",
+		},
+		[]string{"output_name", "output_type"},
+	)
+
+	c.OutputInflightBatches = promauto.With(c.registry).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "output",
+			Name:      "inflight_batches",
+			Help:      "Number of batches currently being sent to output",
+		},
+		[]string{"output_name", "output_type"},
+	)
+
+	c.RouterEventsUndelivered = promauto.With(c.registry).NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "router",
+			Name:      "events_undelivered_total",
+			Help:      "Total number of events that every output failed to send",
+		},
+	)
 }
 
 func (c *Collector) initWorkerPoolMetrics() {
@@ -399,6 +476,38 @@ func (c *Collector) initSystemMetrics() {
 			Buckets:   prometheus.ExponentialBuckets(0.00001, 2, 15), // 10µs to ~300ms
 		},
 	)
+
+	c.SystemMemoryPressureFlushes = promauto.With(c.registry).NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "system",
+			Name:      "memory_pressure_flushes_total",
+			Help:      "Total number of output flushes forced by the heap exceeding the memory pressure threshold",
+		},
+	)
+}
+
+func (c *Collector) initDedupMetrics() {
+	c.DedupEventsDropped = promauto.With(c.registry).NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dedup",
+			Name:      "events_dropped_total",
+			Help:      "Total number of duplicate events dropped",
+		},
+	)
+}
+
+func (c *Collector) initTimestampSkewMetrics() {
+	c.TimestampSkewedEvents = promauto.With(c.registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "pipeline",
+			Name:      "timestamp_skewed_events_total",
+			Help:      "Total number of events whose timestamp deviates from the wall clock beyond the allowed skew, by direction (future, past) and policy",
+		},
+		[]string{"direction", "policy"},
+	)
 }
 
 func (c *Collector) initDLQMetrics() {
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+)
+
+// DefaultMetricsPath is the path metrics are served on when none is configured
+const DefaultMetricsPath = "/metrics"
+
+// Serve starts an HTTP server exposing the collector's registry at the
+// configured address and path, and as a JSON snapshot at the path followed
+// by JSONPathSuffix. The listener is bound before Serve returns,
+// so address errors are reported synchronously. The returned server should
+// be shut down by the caller.
+func Serve(cfg config.MetricsConfig, c *Collector) (*http.Server, error) {
+	if c == nil {
+		return nil, fmt.Errorf("metrics collector is required")
+	}
+
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("metrics address is required")
+	}
+
+	path := cfg.Path
+	if path == "" {
+		path = DefaultMetricsPath
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, promhttp.HandlerFor(
+		c.Registry(),
+		promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		},
+	))
+	mux.Handle(path+JSONPathSuffix, JSONHandler(c.Registry()))
+
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.Address, err)
+	}
+
+	server := &http.Server{
+		Addr:         listener.Addr().String(),
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	// Serve only returns once the server is shut down or the listener fails
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+)
+
+func TestServe(t *testing.T) {
+	c := NewCollector()
+	c.InputEventsReceived.WithLabelValues("test-input", "file").Add(3)
+
+	server, err := Serve(config.MetricsConfig{
+		Enabled: true,
+		Address: "127.0.0.1:0",
+		Path:    "/custom-metrics",
+	}, c)
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr + "/custom-metrics")
+	if err != nil {
+		t.Fatalf("failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+
+	if !strings.Contains(string(body), "logaggregator_input_events_received_total") {
+		t.Errorf("scrape output missing namespaced input metric:\n%s", body)
+	}
+}
+
+func TestServe_InvalidConfig(t *testing.T) {
+	if _, err := Serve(config.MetricsConfig{Address: "127.0.0.1:0"}, nil); err == nil {
+		t.Error("expected error for nil collector")
+	}
+
+	if _, err := Serve(config.MetricsConfig{}, NewCollector()); err == nil {
+		t.Error("expected error for empty address")
+	}
+}
+
+func TestServe_JSON(t *testing.T) {
+	c := NewCollector()
+	c.InputEventsReceived.WithLabelValues("test-input", "file").Add(3)
+	c.ParserDuration.WithLabelValues("json").Observe(0.00002)
+	c.ParserDuration.WithLabelValues("json").Observe(0.5)
+	c.BufferUtilization.WithLabelValues("ring").Set(0.25)
+
+	server, err := Serve(config.MetricsConfig{Enabled: true, Address: "127.0.0.1:0"}, c)
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr + DefaultMetricsPath + JSONPathSuffix)
+	if err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var snapshot Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	for _, name := range []string{
+		"logaggregator_input_events_received_total",
+		"logaggregator_parser_duration_seconds",
+		"logaggregator_buffer_utilization_ratio",
+	} {
+		if _, ok := snapshot.Metrics[name]; !ok {
+			t.Errorf("snapshot missing %s", name)
+		}
+	}
+
+	received := snapshot.Metrics["logaggregator_input_events_received_total"]
+	if received.Type != "counter" || len(received.Samples) != 1 {
+		t.Fatalf("unexpected input family %+v", received)
+	}
+	if sample := received.Samples[0]; *sample.Value != 3 || sample.Labels["input_name"] != "test-input" || sample.Labels["input_type"] != "file" {
+		t.Errorf("unexpected input sample %+v", sample)
+	}
+
+	if utilization := snapshot.Metrics["logaggregator_buffer_utilization_ratio"]; utilization.Type != "gauge" || *utilization.Samples[0].Value != 0.25 {
+		t.Errorf("unexpected buffer family %+v", utilization)
+	}
+
+	duration := snapshot.Metrics["logaggregator_parser_duration_seconds"]
+	if duration.Type != "histogram" || len(duration.Samples) != 1 {
+		t.Fatalf("unexpected parser family %+v", duration)
+	}
+	sample := duration.Samples[0]
+	if *sample.Count != 2 || *sample.Sum != 0.50002 || sample.Buckets["2e-05"] != 1 || sample.Buckets["+Inf"] != 2 {
+		t.Errorf("unexpected histogram sample count=%d sum=%v buckets=%v", *sample.Count, *sample.Sum, sample.Buckets)
+	}
+}
@@ -0,0 +1,108 @@
+package output
+
+import "time"
+
+// AdaptiveBatchConfig configures adaptive batch sizing. The batch size
+// starts at the configured batch size, grows while full batches are sent
+// quickly and shrinks when sends slow down or fail.
+type AdaptiveBatchConfig struct {
+	// Enabled turns adaptive sizing on
+	Enabled bool `yaml:"enabled"`
+
+	// MinBatchSize and MaxBatchSize bound the batch size
+	MinBatchSize int `yaml:"min_batch_size,omitempty"`
+	MaxBatchSize int `yaml:"max_batch_size,omitempty"`
+
+	// TargetLatency is the average send latency to stay under. Batches
+	// grow while the latency is below half of it and shrink above it.
+	TargetLatency time.Duration `yaml:"target_latency,omitempty"`
+
+	// MaxErrorRate is the share of failed events since the last flush
+	// above which batches shrink
+	MaxErrorRate float64 `yaml:"max_error_rate,omitempty"`
+}
+
+// withDefaults fills unset bounds, using initial as the starting batch size
+func (c AdaptiveBatchConfig) withDefaults(initial int) AdaptiveBatchConfig {
+	if c.MinBatchSize <= 0 {
+		c.MinBatchSize = 1
+	}
+	if c.MaxBatchSize <= 0 {
+		c.MaxBatchSize = 10 * initial
+	}
+	if c.MaxBatchSize < c.MinBatchSize {
+		c.MaxBatchSize = c.MinBatchSize
+	}
+	if c.TargetLatency <= 0 {
+		c.TargetLatency = 100 * time.Millisecond
+	}
+	if c.MaxErrorRate <= 0 {
+		c.MaxErrorRate = 0.05
+	}
+	return c
+}
+
+// clamp bounds size to the configured batch sizes
+func (c AdaptiveBatchConfig) clamp(size int) int {
+	if size < c.MinBatchSize {
+		return c.MinBatchSize
+	}
+	if size > c.MaxBatchSize {
+		return c.MaxBatchSize
+	}
+	return size
+}
+
+// adapt resizes the batch from the output's mean latency and the failures
+// since the last flush (must be called with lock held). The size
+// halves when sends are slow or failing and grows by a quarter after a full
+// batch is sent well within the target latency.
+func (b *Batcher) adapt(trigger FlushTrigger) {
+	adaptive := b.config.Adaptive
+	if !adaptive.Enabled || b.config.Metrics == nil {
+		return
+	}
+
+	metrics := b.config.Metrics()
+	sent := metrics.EventsSent - b.lastSent
+	failed := metrics.EventsFailed - b.lastFailed
+	b.lastSent, b.lastFailed = metrics.EventsSent, metrics.EventsFailed
+
+	// Outputs that don't track latency samples only report a mean
+	latency := metrics.AvgLatency
+	if count := metrics.LatencyCount - b.lastLatencyCount; count > 0 {
+		latency = (metrics.LatencySum - b.lastLatencySum) / time.Duration(count)
+	}
+	b.lastLatencyCount, b.lastLatencySum = metrics.LatencyCount, metrics.LatencySum
+
+	size := b.config.MaxBatchSize
+	switch {
+	case sent+failed > 0 && float64(failed)/float64(sent+failed) > adaptive.MaxErrorRate,
+		latency > adaptive.TargetLatency:
+		size /= 2
+	case trigger == FlushByCount && latency < adaptive.TargetLatency/2:
+		size += size/4 + 1
+	}
+
+	b.resize(adaptive.clamp(size))
+}
+
+// resize sets the batch size and reports it when sizing is adaptive (must
+// be called with lock held)
+func (b *Batcher) resize(size int) {
+	if size == b.config.MaxBatchSize {
+		return
+	}
+	b.config.MaxBatchSize = size
+	if b.config.Adaptive.Enabled && b.config.OnResize != nil {
+		b.config.OnResize(size)
+	}
+}
+
+// BatchSize returns the number of events that fills a batch, which changes
+// over time with adaptive sizing
+func (b *Batcher) BatchSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.MaxBatchSize
+}
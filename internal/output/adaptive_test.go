@@ -0,0 +1,171 @@
+package output
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// scriptedSink is a fake output whose send latency and failures are set by
+// the test. It tracks its metrics the way the outputs do.
+type scriptedSink struct {
+	mu        sync.Mutex
+	latency   time.Duration
+	fail      bool
+	batches   []int
+	metrics   OutputMetrics
+	latencies LatencyHistogram
+}
+
+func (s *scriptedSink) send(ctx context.Context, events []*types.LogEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, len(events))
+	if s.fail {
+		s.metrics.EventsFailed += int64(len(events))
+	} else {
+		s.metrics.EventsSent += int64(len(events))
+	}
+	s.latencies.Record(s.latency)
+	return nil
+}
+
+func (s *scriptedSink) Metrics() *OutputMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metricsCopy := s.metrics
+	s.latencies.fill(&metricsCopy)
+	return &metricsCopy
+}
+
+func (s *scriptedSink) set(latency time.Duration, fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+	s.fail = fail
+}
+
+// fill adds events until the batcher flushes n full batches
+func fill(t *testing.T, batcher *Batcher, sink *scriptedSink, n int) {
+	t.Helper()
+
+	sink.mu.Lock()
+	want := len(sink.batches) + n
+	sink.mu.Unlock()
+
+	for i := 0; i < 100000; i++ {
+		if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "event"}); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+		sink.mu.Lock()
+		done := len(sink.batches) >= want
+		sink.mu.Unlock()
+		if done {
+			return
+		}
+	}
+	t.Fatalf("expected %d flushes", n)
+}
+
+func TestBatcherAdaptiveSizing(t *testing.T) {
+	sink := &scriptedSink{}
+	var sizes []int
+
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  10,
+		MaxBatchBytes: 1 << 30,
+		FlushInterval: time.Hour,
+		Adaptive: AdaptiveBatchConfig{
+			Enabled:       true,
+			MinBatchSize:  4,
+			MaxBatchSize:  50,
+			TargetLatency: 100 * time.Millisecond,
+		},
+		Metrics:  sink.Metrics,
+		OnResize: func(size int) { sizes = append(sizes, size) },
+	}, sink.send)
+	defer batcher.Stop()
+
+	if len(sizes) != 1 || sizes[0] != 10 {
+		t.Fatalf("expected the starting size to be reported, got %v", sizes)
+	}
+
+	// Fast sends grow the batch up to the maximum
+	sink.set(5*time.Millisecond, false)
+	fill(t, batcher, sink, 3)
+	grown := batcher.BatchSize()
+	if grown <= 10 {
+		t.Errorf("expected the batch size to grow with low latency, got %d", grown)
+	}
+	fill(t, batcher, sink, 20)
+	if size := batcher.BatchSize(); size != 50 {
+		t.Errorf("expected the batch size to stop at the maximum, got %d", size)
+	}
+
+	// Slow sends shrink it down to the minimum
+	sink.set(500*time.Millisecond, false)
+	fill(t, batcher, sink, 1)
+	if size := batcher.BatchSize(); size >= 50 {
+		t.Errorf("expected the batch size to shrink with high latency, got %d", size)
+	}
+	fill(t, batcher, sink, 10)
+	if size := batcher.BatchSize(); size != 4 {
+		t.Errorf("expected the batch size to stop at the minimum, got %d", size)
+	}
+
+	// Once latency falls again the batch grows back
+	sink.set(time.Millisecond, false)
+	fill(t, batcher, sink, 10)
+	recovered := batcher.BatchSize()
+	if recovered <= 4 {
+		t.Errorf("expected the batch size to grow again, got %d", recovered)
+	}
+
+	// Failures shrink it even when sends are fast
+	sink.set(time.Millisecond, true)
+	fill(t, batcher, sink, 1)
+	if size := batcher.BatchSize(); size >= recovered {
+		t.Errorf("expected the batch size to shrink on failures, got %d (was %d)", size, recovered)
+	}
+
+	if sizes[len(sizes)-1] != batcher.BatchSize() {
+		t.Errorf("expected the last reported size %d to match %d", sizes[len(sizes)-1], batcher.BatchSize())
+	}
+}
+
+func TestBatcherAdaptiveSizing_PartialBatches(t *testing.T) {
+	sink := &scriptedSink{}
+	sink.set(time.Millisecond, false)
+
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  10,
+		MaxBatchBytes: 1 << 30,
+		FlushInterval: time.Hour,
+		Adaptive:      AdaptiveBatchConfig{Enabled: true},
+		Metrics:       sink.Metrics,
+	}, sink.send)
+	defer batcher.Stop()
+
+	// Batches that never fill up don't need to grow
+	for i := 0; i < 5; i++ {
+		if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "event"}); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+		if err := batcher.Flush(context.Background()); err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+	}
+	if size := batcher.BatchSize(); size != 10 {
+		t.Errorf("expected the batch size to stay at 10, got %d", size)
+	}
+
+	// Reloaded batch sizes are kept within the default bounds
+	batcher.SetLimits(500, 0, 0)
+	if size := batcher.BatchSize(); size != 100 {
+		t.Errorf("expected the batch size to be capped at 100, got %d", size)
+	}
+}
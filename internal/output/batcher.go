@@ -3,38 +3,123 @@ package output
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
+// FlushTrigger is the reason a batch was flushed
+type FlushTrigger string
+
+const (
+	// FlushByCount flushes a batch holding MaxBatchSize events
+	FlushByCount FlushTrigger = "count"
+	// FlushBySize flushes a batch that reached MaxBatchBytes
+	FlushBySize FlushTrigger = "size"
+	// FlushByTime flushes a batch FlushInterval after its first event
+	FlushByTime FlushTrigger = "time"
+	// FlushManual flushes a batch on Flush or Stop
+	FlushManual FlushTrigger = "manual"
+)
+
+const (
+	// flushTimeoutIntervals bounds a flush started by the batcher itself to
+	// this many flush intervals
+	flushTimeoutIntervals = 4
+
+	// minFlushTimeout is the shortest bound of a flush started by the
+	// batcher itself
+	minFlushTimeout = 5 * time.Second
+)
+
 // BatcherConfig configures the batching behavior
 type BatcherConfig struct {
 	MaxBatchSize  int
 	MaxBatchBytes int
 	FlushInterval time.Duration
+
+	// EventSize is the size an event counts towards MaxBatchBytes;
+	// defaults to estimateEventSize
+	EventSize func(event *types.LogEvent) int
+
+	// Ordered holds the batch lock while a batch is flushed, so batches
+	// are flushed one at a time in the order their events were added
+	Ordered bool
+
+	// OnFlush is called with the trigger of each non-empty flush
+	OnFlush func(trigger FlushTrigger)
+
+	// OnFlushError is called with the events and error of each failed
+	// flush the batcher started itself, on FlushInterval or Stop, whose
+	// error no caller would otherwise see. It is called with the batch
+	// lock held and must not call back into the batcher.
+	OnFlushError func(events []*types.LogEvent, err error)
+
+	// Adaptive sizes batches from the output's Metrics, starting at
+	// MaxBatchSize. OnResize is called with each new batch size.
+	Adaptive AdaptiveBatchConfig
+	Metrics  func() *OutputMetrics
+	OnResize func(size int)
+
+	// Clock times the flush interval; defaults to the wall clock
+	Clock clock.Clock
+}
+
+// BatcherStats counts flushes by trigger
+type BatcherStats struct {
+	FlushesByCount int64 `json:"flushes_by_count"`
+	FlushesBySize  int64 `json:"flushes_by_size"`
+	FlushesByTime  int64 `json:"flushes_by_time"`
+	FlushesManual  int64 `json:"flushes_manual"`
 }
 
 // Batcher accumulates events and flushes them in batches
 type Batcher struct {
-	config   BatcherConfig
-	events   []*types.LogEvent
-	size     int
-	mu       sync.Mutex
-	flushFn  func(ctx context.Context, events []*types.LogEvent) error
-	stopCh   chan struct{}
-	flushCh  chan struct{}
-	doneCh   chan struct{}
+	config  BatcherConfig
+	events  []*types.LogEvent
+	size    int
+	oldest  time.Time
+	mu      sync.Mutex
+	flushFn func(ctx context.Context, events []*types.LogEvent) error
+	stats   BatcherStats
+
+	// Output counters at the last adaptive sizing decision
+	lastSent         int64
+	lastFailed       int64
+	lastLatencyCount int64
+	lastLatencySum   time.Duration
+
+	stopCh  chan struct{}
+	flushCh chan struct{}
+	resetCh chan struct{}
+	doneCh  chan struct{}
 }
 
 // NewBatcher creates a new batcher
 func NewBatcher(config BatcherConfig, flushFn func(ctx context.Context, events []*types.LogEvent) error) *Batcher {
+	if config.Adaptive.Enabled {
+		config.Adaptive = config.Adaptive.withDefaults(config.MaxBatchSize)
+		config.MaxBatchSize = config.Adaptive.clamp(config.MaxBatchSize)
+		if config.OnResize != nil {
+			config.OnResize(config.MaxBatchSize)
+		}
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real
+	}
+	if config.EventSize == nil {
+		config.EventSize = estimateEventSize
+	}
+
 	b := &Batcher{
 		config:  config,
 		events:  make([]*types.LogEvent, 0, config.MaxBatchSize),
 		flushFn: flushFn,
 		stopCh:  make(chan struct{}),
 		flushCh: make(chan struct{}, 1),
+		resetCh: make(chan struct{}, 1),
 		doneCh:  make(chan struct{}),
 	}
 
@@ -44,17 +129,35 @@ func NewBatcher(config BatcherConfig, flushFn func(ctx context.Context, events [
 	return b
 }
 
-// Add adds an event to the batch
+// Add adds an event to the batch. The batch is flushed once it holds
+// MaxBatchSize events or MaxBatchBytes bytes, whichever comes first; an
+// event that would take the batch past MaxBatchBytes flushes the events
+// before it first. A MaxBatchBytes of zero disables the byte limit.
 func (b *Batcher) Add(ctx context.Context, event *types.LogEvent) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	eventSize := b.config.EventSize(event)
+	if len(b.events) > 0 && b.config.MaxBatchBytes > 0 && b.size+eventSize > b.config.MaxBatchBytes {
+		if err := b.flushLocked(ctx, FlushBySize); err != nil {
+			return err
+		}
+	}
+
+	// The flush interval counts from the first event of a batch
+	if len(b.events) == 0 {
+		b.oldest = b.config.Clock.Now()
+		b.rearm()
+	}
+
 	b.events = append(b.events, event)
-	b.size += len(event.Raw)
+	b.size += eventSize
 
-	// Flush if batch is full
-	if len(b.events) >= b.config.MaxBatchSize || b.size >= b.config.MaxBatchBytes {
-		return b.flushLocked(ctx)
+	switch {
+	case len(b.events) >= b.config.MaxBatchSize:
+		return b.flushLocked(ctx, FlushByCount)
+	case b.config.MaxBatchBytes > 0 && b.size >= b.config.MaxBatchBytes:
+		return b.flushLocked(ctx, FlushBySize)
 	}
 
 	return nil
@@ -64,14 +167,74 @@ func (b *Batcher) Add(ctx context.Context, event *types.LogEvent) error {
 func (b *Batcher) Flush(ctx context.Context) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	return b.flushLocked(ctx)
+	return b.flushLocked(ctx, FlushManual)
+}
+
+// estimateEventSize estimates the encoded size of an event from its raw
+// line, or from its message and fields when the raw line is not kept, as
+// for events received over HTTP, gRPC or OTLP
+func estimateEventSize(event *types.LogEvent) int {
+	if len(event.Raw) > 0 {
+		return len(event.Raw)
+	}
+
+	size := len(event.Message)
+	for key, value := range event.Fields {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// Stats returns the number of flushes by trigger
+func (b *Batcher) Stats() BatcherStats {
+	return BatcherStats{
+		FlushesByCount: atomic.LoadInt64(&b.stats.FlushesByCount),
+		FlushesBySize:  atomic.LoadInt64(&b.stats.FlushesBySize),
+		FlushesByTime:  atomic.LoadInt64(&b.stats.FlushesByTime),
+		FlushesManual:  atomic.LoadInt64(&b.stats.FlushesManual),
+	}
+}
+
+// SetLimits changes the batch limits while the batcher is running. Zero
+// values keep the current setting.
+func (b *Batcher) SetLimits(maxBatchSize, maxBatchBytes int, flushInterval time.Duration) {
+	b.mu.Lock()
+	if maxBatchSize > 0 {
+		if b.config.Adaptive.Enabled {
+			maxBatchSize = b.config.Adaptive.clamp(maxBatchSize)
+		}
+		b.resize(maxBatchSize)
+	}
+	if maxBatchBytes > 0 {
+		b.config.MaxBatchBytes = maxBatchBytes
+	}
+	if flushInterval > 0 && flushInterval != b.config.FlushInterval {
+		b.config.FlushInterval = flushInterval
+		b.rearm()
+	}
+	b.mu.Unlock()
+}
+
+// rearm asks the flush loop to reschedule the age-based flush
+func (b *Batcher) rearm() {
+	select {
+	case b.resetCh <- struct{}{}:
+	default:
+	}
 }
 
 // flushLocked flushes the current batch (must be called with lock held)
-func (b *Batcher) flushLocked(ctx context.Context) error {
+func (b *Batcher) flushLocked(ctx context.Context, trigger FlushTrigger) error {
 	if len(b.events) == 0 {
 		return nil
 	}
+	return b.sendLocked(ctx, trigger, b.takeLocked(trigger))
+}
+
+// takeLocked removes and returns the current batch (must be called with
+// lock held and a non-empty batch)
+func (b *Batcher) takeLocked(trigger FlushTrigger) []*types.LogEvent {
+	b.recordFlush(trigger)
 
 	// Copy events to flush
 	toFlush := make([]*types.LogEvent, len(b.events))
@@ -81,29 +244,138 @@ func (b *Batcher) flushLocked(ctx context.Context) error {
 	b.events = b.events[:0]
 	b.size = 0
 
-	// Flush without holding lock
-	b.mu.Unlock()
-	err := b.flushFn(ctx, toFlush)
-	b.mu.Lock()
+	return toFlush
+}
+
+// sendLocked flushes events taken from the batch (must be called with lock
+// held)
+func (b *Batcher) sendLocked(ctx context.Context, trigger FlushTrigger, toFlush []*types.LogEvent) error {
+	var err error
+	if b.config.Ordered {
+		// Holding the lock keeps the next batch from overtaking this one
+		err = b.flushFn(ctx, toFlush)
+	} else {
+		// Flush without holding lock
+		b.mu.Unlock()
+		err = b.flushFn(ctx, toFlush)
+		b.mu.Lock()
+	}
+	b.adapt(trigger)
 
 	return err
 }
 
-// flushLoop periodically flushes the batch
+// recordFlush counts a flush by its trigger
+func (b *Batcher) recordFlush(trigger FlushTrigger) {
+	switch trigger {
+	case FlushByCount:
+		atomic.AddInt64(&b.stats.FlushesByCount, 1)
+	case FlushBySize:
+		atomic.AddInt64(&b.stats.FlushesBySize, 1)
+	case FlushByTime:
+		atomic.AddInt64(&b.stats.FlushesByTime, 1)
+	case FlushManual:
+		atomic.AddInt64(&b.stats.FlushesManual, 1)
+	}
+
+	if b.config.OnFlush != nil {
+		b.config.OnFlush(trigger)
+	}
+}
+
+// flushExpired flushes the batch once its first event is FlushInterval
+// old. It returns how long until the batch expires, or zero when there is
+// nothing left to wait for.
+func (b *Batcher) flushExpired() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.events) == 0 {
+		return 0
+	}
+	if remaining := b.config.FlushInterval - b.config.Clock.Since(b.oldest); remaining > 0 {
+		return remaining
+	}
+	b.flushBoundedLocked(FlushByTime)
+	return 0
+}
+
+// flushBounded flushes the current batch under a bounded context
+func (b *Batcher) flushBounded(trigger FlushTrigger) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushBoundedLocked(trigger)
+}
+
+// flushBoundedLocked flushes the current batch (must be called with lock
+// held) with a timeout of flushTimeoutIntervals flush intervals, at least
+// minFlushTimeout, so a hung destination cannot stall the flush loop or
+// Stop indefinitely. A failed flush is reported to OnFlushError.
+func (b *Batcher) flushBoundedLocked(trigger FlushTrigger) {
+	if len(b.events) == 0 {
+		return
+	}
+
+	timeout := max(flushTimeoutIntervals*b.config.FlushInterval, minFlushTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	events := b.takeLocked(trigger)
+	if err := b.sendLocked(ctx, trigger, events); err != nil && b.config.OnFlushError != nil {
+		b.config.OnFlushError(events, err)
+	}
+}
+
+// flushErrorHook holds the function an output reports the events of failed
+// background flushes to
+type flushErrorHook struct {
+	mu sync.RWMutex
+	fn func(events []*types.LogEvent, err error)
+}
+
+// OnFlushError sets a function called with the events and error of each
+// batch the output failed to flush in the background
+func (h *flushErrorHook) OnFlushError(fn func(events []*types.LogEvent, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fn = fn
+}
+
+// flushFailed reports a failed background flush to the function set with
+// OnFlushError, if any
+func (h *flushErrorHook) flushFailed(events []*types.LogEvent, err error) {
+	h.mu.RLock()
+	fn := h.fn
+	h.mu.RUnlock()
+
+	if fn != nil {
+		fn(events, err)
+	}
+}
+
+// flushLoop flushes batches that reach the flush interval
 func (b *Batcher) flushLoop() {
-	ticker := time.NewTicker(b.config.FlushInterval)
-	defer ticker.Stop()
+	// Armed by rearm when a batch starts
+	timer := b.config.Clock.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
 	defer close(b.doneCh)
 
 	for {
 		select {
-		case <-ticker.C:
-			b.Flush(context.Background())
+		case <-timer.C():
+			if wait := b.flushExpired(); wait > 0 {
+				timer.Reset(wait)
+			}
+		case <-b.resetCh:
+			if wait := b.flushExpired(); wait > 0 {
+				timer.Reset(wait)
+			}
 		case <-b.flushCh:
-			b.Flush(context.Background())
+			b.flushBounded(FlushManual)
 		case <-b.stopCh:
 			// Final flush on shutdown
-			b.Flush(context.Background())
+			b.flushBounded(FlushManual)
 			return
 		}
 	}
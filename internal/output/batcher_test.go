@@ -2,11 +2,14 @@ package output
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -208,3 +211,362 @@ func TestBatcherSize(t *testing.T) {
 		t.Errorf("expected size 7, got %d", size)
 	}
 }
+
+func TestBatcherSetLimits(t *testing.T) {
+	var flushedCount int64
+
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		atomic.AddInt64(&flushedCount, int64(len(events)))
+		return nil
+	}
+
+	config := BatcherConfig{
+		MaxBatchSize:  100,
+		MaxBatchBytes: 10000,
+		FlushInterval: time.Hour,
+	}
+
+	batcher := NewBatcher(config, flushFn)
+	defer batcher.Stop()
+
+	// A smaller batch size flushes on the next full batch
+	batcher.SetLimits(2, 0, 0)
+	for i := 0; i < 2; i++ {
+		if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "test"}); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+	if count := atomic.LoadInt64(&flushedCount); count != 2 {
+		t.Errorf("expected 2 events flushed on size, got %d", count)
+	}
+
+	// A shorter flush interval resets the ticker
+	batcher.SetLimits(0, 0, 50*time.Millisecond)
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "test"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if count := atomic.LoadInt64(&flushedCount); count != 3 {
+		t.Errorf("expected 3 events flushed on interval, got %d", count)
+	}
+}
+
+func TestBatcherFlushTriggers(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]*types.LogEvent
+	var triggers []FlushTrigger
+
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, events)
+		return nil
+	}
+
+	config := BatcherConfig{
+		MaxBatchSize:  3,
+		MaxBatchBytes: 10,
+		FlushInterval: time.Hour,
+		OnFlush: func(trigger FlushTrigger) {
+			triggers = append(triggers, trigger)
+		},
+	}
+
+	batcher := NewBatcher(config, flushFn)
+	defer batcher.Stop()
+
+	add := func(raw string) {
+		t.Helper()
+		if err := batcher.Add(context.Background(), &types.LogEvent{Raw: raw}); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+
+	// Three small events reach the count limit
+	add("a")
+	add("b")
+	add("c")
+
+	// Two events reach the byte limit exactly
+	add("12345")
+	add("67890")
+
+	// An event that would take the batch past the byte limit flushes the
+	// batch before it
+	add("1234")
+	add("12345678")
+	batcher.Flush(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	wantSizes := []int{3, 2, 1, 1}
+	if len(batches) != len(wantSizes) {
+		t.Fatalf("expected %d batches, got %d", len(wantSizes), len(batches))
+	}
+	for i, batch := range batches {
+		if len(batch) != wantSizes[i] {
+			t.Errorf("batch %d: expected %d events, got %d", i, wantSizes[i], len(batch))
+		}
+		bytes := 0
+		for _, event := range batch {
+			bytes += len(event.Raw)
+		}
+		if bytes > config.MaxBatchBytes {
+			t.Errorf("batch %d: %d bytes exceeds the %d byte limit", i, bytes, config.MaxBatchBytes)
+		}
+	}
+
+	wantTriggers := []FlushTrigger{FlushByCount, FlushBySize, FlushBySize, FlushManual}
+	for i, trigger := range wantTriggers {
+		if i >= len(triggers) || triggers[i] != trigger {
+			t.Fatalf("expected triggers %v, got %v", wantTriggers, triggers)
+		}
+	}
+
+	stats := batcher.Stats()
+	if stats.FlushesByCount != 1 || stats.FlushesBySize != 2 || stats.FlushesByTime != 0 || stats.FlushesManual != 1 {
+		t.Errorf("unexpected flush stats: %+v", stats)
+	}
+}
+
+func TestBatcherFlushIntervalFromFirstEvent(t *testing.T) {
+	flushed := make(chan int, 1)
+
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		flushed <- len(events)
+		return nil
+	}
+
+	fake := clock.NewFake(time.Now())
+	config := BatcherConfig{
+		MaxBatchSize:  100,
+		MaxBatchBytes: 10000,
+		FlushInterval: 200 * time.Millisecond,
+		Clock:         fake,
+	}
+
+	batcher := NewBatcher(config, flushFn)
+	defer batcher.Stop()
+
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "first"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	// The flush loop arms its timer for the new batch
+	fake.BlockUntil(1)
+
+	fake.Advance(120 * time.Millisecond)
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "second"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+
+	// Past the interval since the first event but not since the second
+	fake.Advance(80 * time.Millisecond)
+
+	select {
+	case count := <-flushed:
+		if count != 2 {
+			t.Errorf("expected 2 events flushed by age, got %d", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the batch flushed by age")
+	}
+	if stats := batcher.Stats(); stats.FlushesByTime != 1 {
+		t.Errorf("expected 1 flush by time, got %+v", stats)
+	}
+
+	// The next batch starts a fresh interval
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "third"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	fake.BlockUntil(1)
+	fake.Advance(199 * time.Millisecond)
+	if size := batcher.Size(); size != 1 {
+		t.Errorf("expected the new event to wait for its own interval, got size %d", size)
+	}
+	fake.Advance(time.Millisecond)
+	select {
+	case count := <-flushed:
+		if count != 1 {
+			t.Errorf("expected 1 event flushed by age, got %d", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the new batch flushed by age")
+	}
+}
+
+func TestBatcherBoundedFlushContext(t *testing.T) {
+	deadlines := make(chan time.Duration, 2)
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadlines <- 0
+			return nil
+		}
+		deadlines <- time.Until(deadline)
+		return nil
+	}
+
+	interval := 2 * time.Second
+	batcher := NewBatcher(BatcherConfig{MaxBatchSize: 100, FlushInterval: interval}, flushFn)
+	batcher.SetLimits(0, 0, 20*time.Millisecond)
+	event := &types.LogEvent{Message: "test event", Raw: "test event"}
+
+	// A time-based flush is bounded by the flush interval...
+	if err := batcher.Add(context.Background(), event); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	select {
+	case remaining := <-deadlines:
+		if remaining <= 0 || remaining > minFlushTimeout {
+			t.Errorf("expected the time-based flush bounded by %s, got %s", minFlushTimeout, remaining)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the time-based flush")
+	}
+
+	// ...and so is the final flush on Stop
+	batcher.SetLimits(0, 0, interval)
+	if err := batcher.Add(context.Background(), event); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	batcher.Stop()
+	select {
+	case remaining := <-deadlines:
+		want := flushTimeoutIntervals * interval
+		if remaining <= minFlushTimeout || remaining > want {
+			t.Errorf("expected the final flush bounded by %s, got %s", want, remaining)
+		}
+	default:
+		t.Fatal("expected a final flush on Stop")
+	}
+}
+
+func TestBatcherFlushErrorReported(t *testing.T) {
+	flushErr := errors.New("destination unavailable")
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		return flushErr
+	}
+
+	reported := make(chan []*types.LogEvent, 2)
+	fake := clock.NewFake(time.Now())
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  2,
+		FlushInterval: 100 * time.Millisecond,
+		Clock:         fake,
+		OnFlushError: func(events []*types.LogEvent, err error) {
+			if !errors.Is(err, flushErr) {
+				t.Errorf("expected the flush error reported, got %v", err)
+			}
+			reported <- events
+		},
+	}, flushFn)
+
+	// A flush started by Add returns its error instead
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "first"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "second"}); !errors.Is(err, flushErr) {
+		t.Errorf("expected Add to return the flush error, got %v", err)
+	}
+
+	// A flush by time has no caller to return its error to
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "third"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	fake.BlockUntil(1)
+	fake.Advance(100 * time.Millisecond)
+	select {
+	case events := <-reported:
+		if len(events) != 1 || events[0].Raw != "third" {
+			t.Errorf("expected the expired batch reported, got %d events", len(events))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the failed flush by time reported")
+	}
+
+	// Nor has the final flush on Stop
+	if err := batcher.Add(context.Background(), &types.LogEvent{Raw: "fourth"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	batcher.Stop()
+	select {
+	case events := <-reported:
+		if len(events) != 1 || events[0].Raw != "fourth" {
+			t.Errorf("expected the final batch reported, got %d events", len(events))
+		}
+	default:
+		t.Fatal("expected the failed final flush reported")
+	}
+}
+
+func TestBatcherFlushOnSizeWithoutRaw(t *testing.T) {
+	flushed := make(chan int, 4)
+	flushFn := func(ctx context.Context, events []*types.LogEvent) error {
+		flushed <- len(events)
+		return nil
+	}
+
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  100,
+		MaxBatchBytes: 100,
+		FlushInterval: 10 * time.Second,
+	}, flushFn)
+	defer batcher.Stop()
+
+	// Events received over HTTP, gRPC or OTLP keep no raw line; each of
+	// these counts 40 bytes of message and 10 of fields
+	for i := 0; i < 4; i++ {
+		event := &types.LogEvent{
+			Message: strings.Repeat("m", 40),
+			Fields:  map[string]string{"service": "api"},
+		}
+		if err := batcher.Add(context.Background(), event); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case count := <-flushed:
+			if count != 2 {
+				t.Errorf("expected 2 events flushed by size, got %d", count)
+			}
+		default:
+			t.Fatalf("expected batch %d flushed by size", i+1)
+		}
+	}
+	if stats := batcher.Stats(); stats.FlushesBySize != 2 {
+		t.Errorf("expected 2 flushes by size, got %+v", stats)
+	}
+}
+
+func TestBatcherEventSize(t *testing.T) {
+	flushed := make(chan int, 1)
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  100,
+		MaxBatchBytes: 3000,
+		FlushInterval: 10 * time.Second,
+		EventSize:     func(*types.LogEvent) int { return 1000 },
+	}, func(ctx context.Context, events []*types.LogEvent) error {
+		flushed <- len(events)
+		return nil
+	})
+	defer batcher.Stop()
+
+	for i := 0; i < 3; i++ {
+		if err := batcher.Add(context.Background(), &types.LogEvent{Message: "short"}); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+
+	select {
+	case count := <-flushed:
+		if count != 3 {
+			t.Errorf("expected 3 events flushed by their configured size, got %d", count)
+		}
+	default:
+		t.Fatal("expected the batch flushed by its configured size")
+	}
+}
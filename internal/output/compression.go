@@ -5,27 +5,39 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
 // Compressor interface for compression implementations
 type Compressor interface {
 	Compress(data []byte) ([]byte, error)
 	Decompress(data []byte) ([]byte, error)
+
+	// Extension is the file name suffix for compressed objects, e.g. ".gz"
+	Extension() string
+
+	// ContentEncoding is the Content-Encoding of compressed objects, or
+	// empty when the data is not compressed
+	ContentEncoding() string
 }
 
 // GetCompressor returns a compressor for the specified type
 func GetCompressor(compressionType CompressionType) (Compressor, error) {
 	switch compressionType {
-	case CompressionNone:
+	case "", CompressionNone:
 		return &NoneCompressor{}, nil
 	case CompressionGzip:
 		return &GzipCompressor{}, nil
 	case CompressionSnappy:
 		return &SnappyCompressor{}, nil
 	case CompressionLZ4:
-		return nil, fmt.Errorf("lz4 compression not available in this build")
+		return &LZ4Compressor{}, nil
+	case CompressionZstd:
+		return &ZstdCompressor{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported compression type: %s", compressionType)
 	}
@@ -42,6 +54,9 @@ func (c *NoneCompressor) Decompress(data []byte) ([]byte, error) {
 	return data, nil
 }
 
+func (c *NoneCompressor) Extension() string       { return "" }
+func (c *NoneCompressor) ContentEncoding() string { return "" }
+
 // GzipCompressor uses gzip compression
 type GzipCompressor struct{}
 
@@ -75,6 +90,9 @@ func (c *GzipCompressor) Decompress(data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
+func (c *GzipCompressor) Extension() string       { return ".gz" }
+func (c *GzipCompressor) ContentEncoding() string { return "gzip" }
+
 // SnappyCompressor uses snappy compression
 type SnappyCompressor struct{}
 
@@ -90,5 +108,72 @@ func (c *SnappyCompressor) Decompress(data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
-// LZ4Compressor is disabled in this build
-// To enable, install: go get github.com/pierrec/lz4/v4
+func (c *SnappyCompressor) Extension() string       { return ".snappy" }
+func (c *SnappyCompressor) ContentEncoding() string { return "snappy" }
+
+// LZ4Compressor uses the LZ4 frame format
+type LZ4Compressor struct{}
+
+func (c *LZ4Compressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := lz4.NewWriter(&buf)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("lz4 write failed: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("lz4 close failed: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *LZ4Compressor) Decompress(data []byte) ([]byte, error) {
+	decompressed, err := io.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("lz4 read failed: %w", err)
+	}
+	return decompressed, nil
+}
+
+func (c *LZ4Compressor) Extension() string       { return ".lz4" }
+func (c *LZ4Compressor) ContentEncoding() string { return "lz4" }
+
+// zstdEncoder and zstdDecoder are shared by all zstd compressors; EncodeAll
+// and DecodeAll are safe for concurrent use
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// ZstdCompressor uses zstd compression
+type ZstdCompressor struct{}
+
+func (c *ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, fmt.Errorf("zstd encoder creation failed: %w", err)
+	}
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (c *ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	decoder, err := zstdDecoder()
+	if err != nil {
+		return nil, fmt.Errorf("zstd decoder creation failed: %w", err)
+	}
+
+	decompressed, err := decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("zstd decode failed: %w", err)
+	}
+	return decompressed, nil
+}
+
+func (c *ZstdCompressor) Extension() string       { return ".zst" }
+func (c *ZstdCompressor) ContentEncoding() string { return "zstd" }
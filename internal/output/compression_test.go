@@ -2,7 +2,12 @@ package output
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
 func TestNoneCompressor(t *testing.T) {
@@ -86,12 +91,14 @@ func TestCompressorRoundTrip(t *testing.T) {
 		"The quick brown fox jumps over the lazy dog.")
 
 	tests := []struct {
-		name           string
+		name            string
 		compressionType CompressionType
 	}{
 		{"none", CompressionNone},
 		{"gzip", CompressionGzip},
 		{"snappy", CompressionSnappy},
+		{"lz4", CompressionLZ4},
+		{"zstd", CompressionZstd},
 	}
 
 	for _, tt := range tests {
@@ -117,3 +124,86 @@ func TestCompressorRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestCompressorEncoding(t *testing.T) {
+	tests := []struct {
+		compressionType CompressionType
+		extension       string
+		contentEncoding string
+	}{
+		{CompressionNone, "", ""},
+		{CompressionGzip, ".gz", "gzip"},
+		{CompressionSnappy, ".snappy", "snappy"},
+		{CompressionLZ4, ".lz4", "lz4"},
+		{CompressionZstd, ".zst", "zstd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.compressionType), func(t *testing.T) {
+			compressor, err := GetCompressor(tt.compressionType)
+			if err != nil {
+				t.Fatalf("failed to get compressor: %v", err)
+			}
+			if got := compressor.Extension(); got != tt.extension {
+				t.Errorf("Extension() = %q, want %q", got, tt.extension)
+			}
+			if got := compressor.ContentEncoding(); got != tt.contentEncoding {
+				t.Errorf("ContentEncoding() = %q, want %q", got, tt.contentEncoding)
+			}
+
+			out := newTestS3Output(nil)
+			out.object.KeyTemplate = "{{.Year}}/events.ndjson"
+			out.compressor = compressor
+			key := out.generateKey(&types.LogEvent{Timestamp: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)})
+			if want := "logs/2024/events.ndjson" + tt.extension; key != want {
+				t.Errorf("generateKey() = %q, want %q", key, want)
+			}
+		})
+	}
+
+	if _, err := GetCompressor("brotli"); err == nil {
+		t.Error("expected error for an unsupported compression type")
+	}
+}
+
+// sampleNDJSONBatch returns a batch of n log events encoded as NDJSON
+func sampleNDJSONBatch(n int) []byte {
+	var buf bytes.Buffer
+	levels := []string{"info", "warn", "error", "debug"}
+	for i := 0; i < n; i++ {
+		data, _ := json.Marshal(&types.LogEvent{
+			Timestamp: time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC).Add(time.Duration(i) * time.Millisecond),
+			Message:   fmt.Sprintf("GET /api/v1/users/%d completed", i%97),
+			Level:     levels[i%len(levels)],
+			Source:    "/var/log/app/access.log",
+			Fields:    map[string]string{"status": "200", "duration_ms": fmt.Sprint(i % 250), "request_id": fmt.Sprintf("req-%08d", i)},
+		})
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkCompressors(b *testing.B) {
+	data := sampleNDJSONBatch(1000)
+
+	for _, compressionType := range []CompressionType{CompressionGzip, CompressionSnappy, CompressionLZ4, CompressionZstd} {
+		compressor, err := GetCompressor(compressionType)
+		if err != nil {
+			b.Fatalf("failed to get compressor: %v", err)
+		}
+
+		b.Run(string(compressionType), func(b *testing.B) {
+			var compressed []byte
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				compressed, err = compressor.Compress(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data))/float64(len(compressed)), "ratio")
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package output
+
+import (
+	"context"
+	"fmt"
+)
+
+// sendSlots bounds the batches an output sends at once to its
+// MaxConcurrentBatches. Sends beyond the limit, whether from SendBatch or a
+// batcher flush, wait for a slot. A nil sendSlots does not bound sends.
+type sendSlots chan struct{}
+
+// newSendSlots returns max send slots, or nil for no limit
+func newSendSlots(max int) sendSlots {
+	if max <= 0 {
+		return nil
+	}
+	return make(sendSlots, max)
+}
+
+// begin waits for a slot and records the batch in flight, returning the
+// function that ends the send. It fails with ctx's error if ctx is done
+// before a slot is free.
+func (s sendSlots) begin(ctx context.Context, i *instrumentation, name, outputType string) (func(), error) {
+	if s != nil {
+		select {
+		case s <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a send slot of output %s: %w", name, ctx.Err())
+		}
+	}
+
+	i.observeInflight(name, outputType, 1)
+	return func() {
+		i.observeInflight(name, outputType, -1)
+		if s != nil {
+			<-s
+		}
+	}, nil
+}
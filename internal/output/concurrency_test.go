@@ -0,0 +1,139 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+)
+
+// slowSink is an object upload that takes delay, tracking the uploads in
+// progress and the in-flight gauge seen by each
+type slowSink struct {
+	delay    time.Duration
+	gauge    func() float64
+	active   atomic.Int32
+	peak     atomic.Int32
+	mu       sync.Mutex
+	inflight []float64
+}
+
+func (s *slowSink) upload(ctx context.Context, _ string, _ []byte) error {
+	active := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if active <= peak || s.peak.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.inflight = append(s.inflight, s.gauge())
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestMaxConcurrentBatches(t *testing.T) {
+	collector := metrics.NewCollector()
+	gauge := collector.OutputInflightBatches.WithLabelValues("sink", "s3")
+	sink := &slowSink{delay: 20 * time.Millisecond, gauge: func() float64 { return testutil.ToFloat64(gauge) }}
+
+	base := DefaultBaseConfig()
+	base.Name = "sink"
+	base.BatchSize = 1
+	base.MaxConcurrentBatches = 2
+	w, err := newObjectWriter("s3", base, DefaultObjectConfig(), sink.upload)
+	if err != nil {
+		t.Fatalf("newObjectWriter() error = %v", err)
+	}
+	w.SetCollector(collector)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.SendBatch(context.Background(), messageBatch("event", 2)); err != nil {
+				t.Errorf("SendBatch() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := sink.peak.Load(); peak != 2 {
+		t.Errorf("expected at most 2 concurrent uploads, reaching the limit, got a peak of %d", peak)
+	}
+	for _, inflight := range sink.inflight {
+		if inflight < 1 || inflight > 2 {
+			t.Errorf("in-flight gauge = %v during an upload, want 1 or 2", inflight)
+		}
+	}
+	if inflight := testutil.ToFloat64(gauge); inflight != 0 {
+		t.Errorf("in-flight gauge = %v after the sends, want 0", inflight)
+	}
+	if m := w.Metrics(); m.EventsSent != 16 {
+		t.Errorf("expected 16 events sent, got %d", m.EventsSent)
+	}
+
+	// A send waiting for a slot gives up with its context
+	blocked := make(chan struct{})
+	w.upload = func(ctx context.Context, _ string, _ []byte) error {
+		<-blocked
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.SendBatch(context.Background(), messageBatch("busy", 1))
+		}()
+	}
+	for testutil.ToFloat64(gauge) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.SendBatch(ctx, messageBatch("waiting", 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline exceeded while the slots are taken, got %v", err)
+	}
+	close(blocked)
+	wg.Wait()
+}
+
+func TestSendSlotsUnbounded(t *testing.T) {
+	if slots := newSendSlots(0); slots != nil {
+		t.Errorf("expected no slots without a limit, got %d", cap(slots))
+	}
+
+	var i instrumentation
+	i.SetCollector(metrics.NewCollector())
+	var slots sendSlots
+	var dones []func()
+	for n := 0; n < 10; n++ {
+		done, err := slots.begin(context.Background(), &i, "out", "http")
+		if err != nil {
+			t.Fatalf("begin() error = %v", err)
+		}
+		dones = append(dones, done)
+	}
+	if inflight := testutil.ToFloat64(i.metricsCollector().OutputInflightBatches.WithLabelValues("out", "http")); inflight != 10 {
+		t.Errorf("in-flight gauge = %v, want 10", inflight)
+	}
+	for _, done := range dones {
+		done()
+	}
+}
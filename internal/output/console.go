@@ -0,0 +1,265 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// ANSI escape codes used by the console output
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+// ConsoleConfig contains console output configuration
+type ConsoleConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// NoColor disables colors. Colors are also disabled when the writer is
+	// not a terminal or the NO_COLOR environment variable is set.
+	NoColor bool `yaml:"no_color,omitempty"`
+
+	// Fields lists the fields shown after the message, in order. "source"
+	// refers to the event's source; other names are event fields. All
+	// fields are shown, source first and the rest sorted, when empty.
+	Fields []string `yaml:"fields,omitempty"`
+
+	// TimeFormat is the timestamp layout (RFC3339 with milliseconds by default)
+	TimeFormat string `yaml:"time_format,omitempty"`
+
+	// Writer receives the formatted lines (stdout by default)
+	Writer io.Writer `yaml:"-"`
+}
+
+func init() {
+	Register("console", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultConsoleConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewConsoleOutput(config)
+	})
+}
+
+// DefaultConsoleConfig returns default console output configuration
+func DefaultConsoleConfig() ConsoleConfig {
+	return ConsoleConfig{
+		BaseConfig: DefaultBaseConfig(),
+		TimeFormat: "2006-01-02T15:04:05.000Z07:00",
+	}
+}
+
+// ConsoleOutput prints events as human-readable lines: the timestamp, the
+// colored level, the message and then key=value fields
+type ConsoleOutput struct {
+	config  ConsoleConfig
+	writer  io.Writer
+	color   bool
+	metrics *OutputMetrics
+	latency LatencyHistogram
+	mu      sync.Mutex
+	closed  atomic.Bool
+}
+
+// NewConsoleOutput creates a new console output
+func NewConsoleOutput(config ConsoleConfig) (*ConsoleOutput, error) {
+	if config.Writer == nil {
+		config.Writer = os.Stdout
+	}
+	if config.TimeFormat == "" {
+		config.TimeFormat = DefaultConsoleConfig().TimeFormat
+	}
+
+	return &ConsoleOutput{
+		config:  config,
+		writer:  config.Writer,
+		color:   !config.NoColor && os.Getenv("NO_COLOR") == "" && isTerminal(config.Writer),
+		metrics: &OutputMetrics{},
+	}, nil
+}
+
+// isTerminal reports whether w is a character device such as a TTY. It is
+// a variable so tests can simulate a terminal.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Send prints a single event
+func (c *ConsoleOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	return c.SendBatch(ctx, []*types.LogEvent{event})
+}
+
+// SendBatch prints a batch of events
+func (c *ConsoleOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	if c.closed.Load() {
+		return fmt.Errorf("console output is closed")
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, event := range events {
+		c.format(&b, event)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	startTime := time.Now()
+	n, err := io.WriteString(c.writer, b.String())
+	latency := time.Since(startTime)
+
+	if err != nil {
+		c.metrics.EventsFailed += int64(len(events))
+		c.metrics.LastError = err.Error()
+		c.metrics.LastErrorTime = time.Now()
+		return fmt.Errorf("failed to write to console: %w", err)
+	}
+
+	c.metrics.EventsSent += int64(len(events))
+	c.metrics.BytesSent += int64(n)
+	c.metrics.LastSendTime = time.Now()
+	c.latency.Record(latency)
+	return nil
+}
+
+// format appends the line of an event to b
+func (c *ConsoleOutput) format(b *strings.Builder, event *types.LogEvent) {
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	c.colorize(b, colorGray, timestamp.Format(c.config.TimeFormat))
+	b.WriteByte(' ')
+
+	level := strings.ToUpper(types.NormalizeLevel(event.Level))
+	if level == "" {
+		level = "-"
+	}
+	c.colorize(b, levelColor(level), fmt.Sprintf("%-5s", level))
+	b.WriteByte(' ')
+
+	b.WriteString(strings.TrimRight(event.Message, "\r\n"))
+
+	for _, name := range c.fieldNames(event) {
+		value, ok := event.Fields[name]
+		if name == "source" {
+			value, ok = event.Source, event.Source != ""
+		}
+		if !ok {
+			continue
+		}
+		b.WriteByte(' ')
+		c.colorize(b, colorCyan, name+"=")
+		b.WriteString(quoteValue(value))
+	}
+	b.WriteByte('\n')
+}
+
+// fieldNames returns the names of the fields to show for an event
+func (c *ConsoleOutput) fieldNames(event *types.LogEvent) []string {
+	if len(c.config.Fields) > 0 {
+		return c.config.Fields
+	}
+
+	names := make([]string, 0, len(event.Fields)+1)
+	for name := range event.Fields {
+		if name != "source" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{"source"}, names...)
+}
+
+// colorize writes s to b, wrapped in color when colors are enabled
+func (c *ConsoleOutput) colorize(b *strings.Builder, color, s string) {
+	if !c.color || color == "" {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(color)
+	b.WriteString(s)
+	b.WriteString(colorReset)
+}
+
+// levelColor returns the color of a normalized, uppercased level
+func levelColor(level string) string {
+	switch level {
+	case "DEBUG":
+		return colorGray
+	case "INFO":
+		return colorGreen
+	case "WARN":
+		return colorYellow
+	case "ERROR":
+		return colorRed
+	case "FATAL":
+		return colorMagenta
+	default:
+		return ""
+	}
+}
+
+// quoteValue quotes a field value that is empty or contains spaces, quotes
+// or equals signs
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// Close closes the console output. The writer is left open.
+func (c *ConsoleOutput) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// HealthCheck always succeeds
+func (c *ConsoleOutput) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Name returns the output name
+func (c *ConsoleOutput) Name() string {
+	if c.config.Name != "" {
+		return c.config.Name
+	}
+	return "console"
+}
+
+// Metrics returns the current metrics
+func (c *ConsoleOutput) Metrics() *OutputMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Return a copy
+	metricsCopy := *c.metrics
+	c.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func consoleEvent() *types.LogEvent {
+	return &types.LogEvent{
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 5e6, time.UTC),
+		Message:   "request served\n",
+		Level:     "WARNING",
+		Source:    "api",
+		Fields:    map[string]string{"status": "503", "path": "/v1/logs", "user": "jane doe"},
+	}
+}
+
+func TestConsoleOutput_Format(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := NewConsoleOutput(ConsoleConfig{Writer: &buf})
+	if err != nil {
+		t.Fatalf("NewConsoleOutput() error = %v", err)
+	}
+
+	if err := out.Send(context.Background(), consoleEvent()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := `2024-03-01T12:00:00.005Z WARN  request served source=api path=/v1/logs status=503 user="jane doe"` + "\n"
+	if buf.String() != want {
+		t.Errorf("unexpected line:\n%q\nwant:\n%q", buf.String(), want)
+	}
+	if metrics := out.Metrics(); metrics.EventsSent != 1 || metrics.BytesSent != int64(len(want)) {
+		t.Errorf("expected 1 event and %d bytes sent, got %d and %d", len(want), metrics.EventsSent, metrics.BytesSent)
+	}
+}
+
+func TestConsoleOutput_FieldSelection(t *testing.T) {
+	var buf bytes.Buffer
+	out, _ := NewConsoleOutput(ConsoleConfig{
+		Writer:     &buf,
+		Fields:     []string{"status", "source", "missing"},
+		TimeFormat: time.Kitchen,
+	})
+
+	if err := out.Send(context.Background(), consoleEvent()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "12:00PM WARN  request served status=503 source=api\n"
+	if buf.String() != want {
+		t.Errorf("unexpected line:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
+
+func TestConsoleOutput_Color(t *testing.T) {
+	var buf bytes.Buffer
+	out, _ := NewConsoleOutput(ConsoleConfig{Writer: &buf, Fields: []string{"status"}})
+
+	// A buffer is not a terminal, so colors are off by default
+	if out.color {
+		t.Fatal("expected colors to be disabled for a non-terminal writer")
+	}
+
+	out.color = true
+	if err := out.SendBatch(context.Background(), []*types.LogEvent{
+		consoleEvent(),
+		{Timestamp: time.Unix(0, 0), Message: "boom", Level: "err"},
+	}); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], colorYellow+"WARN "+colorReset) {
+		t.Errorf("expected a yellow warn level, got %q", lines[0])
+	}
+	if !strings.Contains(lines[0], colorCyan+"status="+colorReset+"503") {
+		t.Errorf("expected a colored field key, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], colorRed+"ERROR"+colorReset) {
+		t.Errorf("expected a red error level, got %q", lines[1])
+	}
+}
+
+func TestConsoleOutput_NoColor(t *testing.T) {
+	terminal := isTerminal
+	isTerminal = func(io.Writer) bool { return true }
+	t.Cleanup(func() { isTerminal = terminal })
+
+	var buf bytes.Buffer
+	if out, _ := NewConsoleOutput(ConsoleConfig{Writer: &buf}); !out.color {
+		t.Error("expected colors on a terminal")
+	}
+	if out, _ := NewConsoleOutput(ConsoleConfig{Writer: &buf, NoColor: true}); out.color {
+		t.Error("expected NoColor to disable colors on a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	out, _ := NewConsoleOutput(ConsoleConfig{Writer: &buf})
+	if out.color {
+		t.Error("expected NO_COLOR to disable colors on a terminal")
+	}
+	out.Send(context.Background(), consoleEvent())
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no escape codes, got %q", buf.String())
+	}
+}
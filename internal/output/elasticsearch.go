@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -31,6 +35,22 @@ type ElasticsearchConfig struct {
 	// IndexTimestampField is the field to use for index timestamp
 	IndexTimestampField string `yaml:"index_timestamp_field,omitempty"`
 
+	// UseDataStream writes to Index as a data stream using create actions.
+	// Index rotation is disabled since the data stream handles rollover, and
+	// IndexTimestampField is mapped to @timestamp.
+	UseDataStream bool `yaml:"use_data_stream,omitempty"`
+
+	// IDField names the event field holding the document ID. Setting a
+	// deterministic ID makes redelivery idempotent: index actions overwrite
+	// the existing document, while create actions (data streams) reject the
+	// duplicate with a conflict, which is treated as already delivered.
+	IDField string `yaml:"id_field,omitempty"`
+
+	// IDTemplate builds the document ID from the event when IDField is unset
+	// or missing, e.g. "{{.Source}}-{{.Fields.offset}}" (see DocumentIDData).
+	// Events that produce an empty ID get an auto-generated one.
+	IDTemplate string `yaml:"id_template,omitempty"`
+
 	// Pipeline is the ingest pipeline to use
 	Pipeline string `yaml:"pipeline,omitempty"`
 
@@ -46,14 +66,32 @@ type ElasticsearchConfig struct {
 	// APIKey for authentication
 	APIKey string `yaml:"api_key,omitempty"`
 
-	// EnableTLS enables TLS for connections
+	// EnableTLS enables TLS for connections. Setting any TLS option below
+	// enables it as well.
 	EnableTLS bool `yaml:"enable_tls,omitempty"`
 
+	// TLS client settings
+	TLSClientConfig `yaml:",inline"`
+
 	// BulkWorkers is the number of concurrent bulk workers
 	BulkWorkers int `yaml:"bulk_workers,omitempty"`
 
 	// MaxRetries for failed requests
 	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// FieldContract coerces event fields to declared types and handles
+	// fields whose type conflicts within a batch before indexing
+	FieldContract FieldContractConfig `yaml:"field_contract,omitempty"`
+}
+
+func init() {
+	Register("elasticsearch", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultElasticsearchConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewElasticsearchOutput(config)
+	})
 }
 
 // DefaultElasticsearchConfig returns default Elasticsearch configuration
@@ -71,12 +109,29 @@ func DefaultElasticsearchConfig() ElasticsearchConfig {
 
 // ElasticsearchOutput sends events to Elasticsearch
 type ElasticsearchOutput struct {
-	config  ElasticsearchConfig
-	client  *elasticsearch.Client
-	batcher *Batcher
-	metrics *OutputMetrics
-	mu      sync.RWMutex
-	closed  atomic.Bool
+	config     ElasticsearchConfig
+	client     *elasticsearch.Client
+	idTemplate *template.Template
+	schema     *SchemaMapper
+	contract   *fieldContract
+	batcher    *Batcher
+	slots      sendSlots
+	metrics    *OutputMetrics
+	latency    LatencyHistogram
+	mu         sync.RWMutex
+	closed     atomic.Bool
+
+	instrumentation
+	flushErrorHook
+}
+
+// DocumentIDData is the data available to IDTemplate
+type DocumentIDData struct {
+	Message   string
+	Level     string
+	Source    string
+	Fields    map[string]string
+	Timestamp int64 // Unix nanoseconds
 }
 
 // NewElasticsearchOutput creates a new Elasticsearch output
@@ -89,13 +144,30 @@ func NewElasticsearchOutput(config ElasticsearchConfig) (*ElasticsearchOutput, e
 		return nil, fmt.Errorf("no index specified")
 	}
 
-	// Create Elasticsearch config
-	esConfig := elasticsearch.Config{
-		Addresses: config.Addresses,
-		CloudID:   config.CloudID,
-		Username:  config.Username,
-		Password:  config.Password,
-		APIKey:    config.APIKey,
+	if config.UseDataStream {
+		if err := validateDataStreamName(config.Index); err != nil {
+			return nil, err
+		}
+	}
+
+	idTemplate, err := parseIDTemplate(config.IDTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := newOptionalSchemaMapper(config.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	contract, err := newFieldContract(config.FieldContract)
+	if err != nil {
+		return nil, err
+	}
+
+	esConfig, err := newElasticsearchClientConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create client
@@ -116,9 +188,13 @@ func NewElasticsearchOutput(config ElasticsearchConfig) (*ElasticsearchOutput, e
 	}
 
 	output := &ElasticsearchOutput{
-		config:  config,
-		client:  client,
-		metrics: &OutputMetrics{},
+		config:     config,
+		client:     client,
+		idTemplate: idTemplate,
+		schema:     schema,
+		contract:   contract,
+		metrics:    &OutputMetrics{},
+		slots:      newSendSlots(config.MaxConcurrentBatches),
 	}
 
 	// Create batcher
@@ -127,12 +203,80 @@ func NewElasticsearchOutput(config ElasticsearchConfig) (*ElasticsearchOutput, e
 			MaxBatchSize:  config.BatchSize,
 			MaxBatchBytes: 10 * 1024 * 1024, // 10MB default bulk size
 			FlushInterval: config.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				output.observeFlush(output.Name(), "elasticsearch", trigger)
+			},
+			Adaptive: config.AdaptiveBatch,
+			Ordered:  config.Ordered,
+			Metrics:  output.Metrics,
+			OnResize: func(size int) {
+				output.observeBatchSize(output.Name(), "elasticsearch", size)
+			},
+			OnFlushError: output.flushFailed,
 		}, output.sendBatchInternal)
 	}
 
 	return output, nil
 }
 
+// newElasticsearchClientConfig builds the Elasticsearch client configuration,
+// using a transport with the output's TLS settings when TLS is configured
+func newElasticsearchClientConfig(config ElasticsearchConfig) (elasticsearch.Config, error) {
+	esConfig := elasticsearch.Config{
+		Addresses: config.Addresses,
+		CloudID:   config.CloudID,
+		Username:  config.Username,
+		Password:  config.Password,
+		APIKey:    config.APIKey,
+
+		// Retry the statuses classified as transient
+		RetryOnStatus: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		MaxRetries:    config.MaxRetries,
+	}
+
+	tlsConfig, err := config.buildTLSConfig(config.EnableTLS)
+	if err != nil {
+		return elasticsearch.Config{}, err
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		esConfig.Transport = transport
+	}
+
+	return esConfig, nil
+}
+
+// parseIDTemplate parses the document ID template, returning nil if unset
+func parseIDTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("id").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// validateDataStreamName checks that name is a valid, fixed data stream name
+func validateDataStreamName(name string) error {
+	if strings.Contains(name, "%{") {
+		return fmt.Errorf("data stream name %q cannot contain date patterns", name)
+	}
+	if name != strings.ToLower(name) {
+		return fmt.Errorf("data stream name %q must be lowercase", name)
+	}
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "_") || strings.HasPrefix(name, "+") || strings.HasPrefix(name, ".ds-") {
+		return fmt.Errorf("data stream name %q has an invalid prefix", name)
+	}
+	if strings.ContainsAny(name, "\\/*?\"<>| ,#:") {
+		return fmt.Errorf("data stream name %q contains invalid characters", name)
+	}
+	return nil
+}
+
 // Send sends a single event to Elasticsearch
 func (e *ElasticsearchOutput) Send(ctx context.Context, event *types.LogEvent) error {
 	if e.closed.Load() {
@@ -161,53 +305,58 @@ func (e *ElasticsearchOutput) sendSingle(ctx context.Context, event *types.LogEv
 	index := e.getIndexName(event)
 
 	// Serialize event
-	doc, err := json.Marshal(event)
+	doc, err := e.encodeDocument(event, e.newContractBatch())
 	if err != nil {
-		atomic.AddInt64(&e.metrics.EventsFailed, 1)
-		e.metrics.LastError = err.Error()
-		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to marshal event: %w", err)
+		e.recordEncodeFailure(err)
+		return classifyf(ErrSerialization, "failed to marshal event: %w", err)
 	}
 
 	startTime := time.Now()
 
 	// Index document
 	req := esapi.IndexRequest{
-		Index:   index,
-		Body:    bytes.NewReader(doc),
-		Refresh: "false",
+		Index:      index,
+		DocumentID: e.documentID(event),
+		Body:       bytes.NewReader(doc),
+		Refresh:    "false",
 	}
 
 	if e.config.Pipeline != "" {
 		req.Pipeline = e.config.Pipeline
 	}
 
+	// Data streams only accept create operations
+	if e.config.UseDataStream {
+		req.OpType = "create"
+	}
+
 	res, err := req.Do(ctx, e.client)
 	if err != nil {
 		atomic.AddInt64(&e.metrics.EventsFailed, 1)
 		e.metrics.LastError = err.Error()
 		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to index document: %w", err)
+		return classifyf(ErrTransient, "failed to index document: %w", err)
 	}
 	defer res.Body.Close()
 
 	latency := time.Since(startTime)
 
-	if res.IsError() {
+	if res.IsError() && !e.isDuplicate(req.DocumentID, res.StatusCode) {
 		atomic.AddInt64(&e.metrics.EventsFailed, 1)
 		e.metrics.LastError = res.Status()
 		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("elasticsearch returned error: %s", res.Status())
+		return statusError(res.StatusCode, fmt.Errorf("elasticsearch returned error: %s", res.Status()))
 	}
 
 	// Update metrics
 	atomic.AddInt64(&e.metrics.EventsSent, 1)
 	atomic.AddInt64(&e.metrics.BytesSent, int64(len(doc)))
 	e.metrics.LastSendTime = time.Now()
+	e.observeSend(e.Name(), "elasticsearch", int64(len(doc)), latency)
 
-	// Update average latency
+	// Record latency
 	e.mu.Lock()
-	e.metrics.AvgLatency = (e.metrics.AvgLatency + latency) / 2
+	e.latency.Record(latency)
 	e.mu.Unlock()
 
 	return nil
@@ -219,53 +368,30 @@ func (e *ElasticsearchOutput) sendBatchInternal(ctx context.Context, events []*t
 		return nil
 	}
 
+	done, err := e.slots.begin(ctx, &e.instrumentation, e.Name(), "elasticsearch")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	startTime := time.Now()
 
 	// Build bulk request body
-	var buf bytes.Buffer
-	var totalBytes int64
-
-	for _, event := range events {
-		index := e.getIndexName(event)
-
-		// Action metadata
-		meta := map[string]interface{}{
-			"index": map[string]interface{}{
-				"_index": index,
-			},
-		}
-		if e.config.Pipeline != "" {
-			meta["index"].(map[string]interface{})["pipeline"] = e.config.Pipeline
-		}
-
-		metaJSON, err := json.Marshal(meta)
-		if err != nil {
-			atomic.AddInt64(&e.metrics.EventsFailed, 1)
-			continue
-		}
-
-		// Document
-		docJSON, err := json.Marshal(event)
-		if err != nil {
-			atomic.AddInt64(&e.metrics.EventsFailed, 1)
-			continue
-		}
-
-		buf.Write(metaJSON)
-		buf.WriteByte('\n')
-		buf.Write(docJSON)
-		buf.WriteByte('\n')
-
-		totalBytes += int64(len(docJSON))
+	buf, totalBytes, skipped := e.buildBulkBody(events)
+	if skipped == len(events) {
+		pool.PutBatchBuffer(buf)
+		return classifyf(ErrSerialization, "failed to encode any of %d events", len(events))
 	}
 
-	// Send bulk request
+	// Send bulk request. The buffer goes back to the pool only once the
+	// request is known to be complete: after a failed or rejected request
+	// the transport may still be reading the body, so it is left to the GC.
 	res, err := e.client.Bulk(bytes.NewReader(buf.Bytes()), e.client.Bulk.WithContext(ctx))
 	if err != nil {
 		atomic.AddInt64(&e.metrics.EventsFailed, int64(len(events)))
 		e.metrics.LastError = err.Error()
 		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("bulk request failed: %w", err)
+		return classifyf(ErrTransient, "bulk request failed: %w", err)
 	}
 	defer res.Body.Close()
 
@@ -275,15 +401,16 @@ func (e *ElasticsearchOutput) sendBatchInternal(ctx context.Context, events []*t
 		atomic.AddInt64(&e.metrics.EventsFailed, int64(len(events)))
 		e.metrics.LastError = res.Status()
 		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("bulk request returned error: %s", res.Status())
+		return statusError(res.StatusCode, fmt.Errorf("bulk request returned error: %s", res.Status()))
 	}
 
 	// Parse bulk response
 	var bulkResp struct {
 		Errors bool `json:"errors"`
 		Items  []map[string]struct {
-			Status int    `json:"status"`
-			Error  string `json:"error"`
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
 		} `json:"items"`
 	}
 
@@ -291,24 +418,32 @@ func (e *ElasticsearchOutput) sendBatchInternal(ctx context.Context, events []*t
 		atomic.AddInt64(&e.metrics.EventsFailed, int64(len(events)))
 		e.metrics.LastError = err.Error()
 		e.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to parse bulk response: %w", err)
+		return classifyf(ErrTransient, "failed to parse bulk response: %w", err)
 	}
 
-	// Count successes and failures
+	// Elasticsearch answered after reading the whole request
+	pool.PutBatchBuffer(buf)
+
+	// Count successes and failures. The batch failed transiently only if
+	// every failed document may be indexed when retried.
 	var failedCount int64
+	var failedStatus int
 	if bulkResp.Errors {
 		for _, item := range bulkResp.Items {
 			for _, doc := range item {
-				if doc.Status >= 400 {
+				if doc.Status >= 400 && !e.isDuplicate(doc.ID, doc.Status) {
 					failedCount++
-					e.metrics.LastError = doc.Error
+					if failedStatus == 0 || statusClass(failedStatus) == ErrTransient {
+						failedStatus = doc.Status
+					}
+					e.metrics.LastError = string(doc.Error)
 					e.metrics.LastErrorTime = time.Now()
 				}
 			}
 		}
 	}
 
-	successCount := int64(len(events)) - failedCount
+	successCount := int64(len(events)-skipped) - failedCount
 
 	// Update metrics
 	atomic.AddInt64(&e.metrics.EventsSent, successCount)
@@ -316,26 +451,229 @@ func (e *ElasticsearchOutput) sendBatchInternal(ctx context.Context, events []*t
 	atomic.AddInt64(&e.metrics.BytesSent, totalBytes)
 	atomic.AddInt64(&e.metrics.BatchesSent, 1)
 	e.metrics.LastSendTime = time.Now()
+	e.observeBatch(e.Name(), "elasticsearch", len(events)-skipped, totalBytes, latency)
 
-	// Update average batch size and latency
+	// Update average batch size and record latency
 	e.mu.Lock()
 	if e.metrics.BatchesSent > 0 {
 		e.metrics.AvgBatchSize = float64(e.metrics.EventsSent) / float64(e.metrics.BatchesSent)
 	}
-	e.metrics.AvgLatency = (e.metrics.AvgLatency + latency) / 2
+	e.latency.Record(latency)
 	e.mu.Unlock()
 
 	if failedCount > 0 {
-		return fmt.Errorf("%d out of %d events failed to index", failedCount, len(events))
+		return statusError(failedStatus, fmt.Errorf("%d out of %d events failed to index", failedCount, len(events)))
 	}
 
 	return nil
 }
 
+// buildBulkBody builds the Bulk API request body for events and returns it
+// with the total size of the documents and the number of events skipped.
+// Events that cannot be encoded or are quarantined by the field contract
+// are counted as failed and skipped. The buffer comes from the batch buffer
+// pool.
+func (e *ElasticsearchOutput) buildBulkBody(events []*types.LogEvent) (*bytes.Buffer, int64, int) {
+	buf := pool.GetBatchBuffer()
+	var totalBytes int64
+	var skipped int
+	batch := e.newContractBatch()
+
+	// Data streams only accept create actions
+	action := "index"
+	if e.config.UseDataStream {
+		action = "create"
+	}
+
+	for _, event := range events {
+		index := e.getIndexName(event)
+
+		// Action metadata
+		params := map[string]interface{}{
+			"_index": index,
+		}
+		if e.config.Pipeline != "" {
+			params["pipeline"] = e.config.Pipeline
+		}
+		if id := e.documentID(event); id != "" {
+			params["_id"] = id
+		}
+
+		metaJSON, err := json.Marshal(map[string]interface{}{action: params})
+		if err != nil {
+			e.recordEncodeFailure(err)
+			skipped++
+			continue
+		}
+
+		// Document
+		docJSON, err := e.encodeDocument(event, batch)
+		if err != nil {
+			e.recordEncodeFailure(err)
+			skipped++
+			continue
+		}
+
+		buf.Write(metaJSON)
+		buf.WriteByte('\n')
+		buf.Write(docJSON)
+		buf.WriteByte('\n')
+
+		totalBytes += int64(len(docJSON))
+	}
+
+	return buf, totalBytes, skipped
+}
+
+// newContractBatch starts checking a batch against the field contract, or
+// returns nil without one
+func (e *ElasticsearchOutput) newContractBatch() *contractBatch {
+	if e.contract == nil {
+		return nil
+	}
+	return e.contract.newBatch()
+}
+
+// recordEncodeFailure counts an event that could not be encoded, recording
+// quarantined documents as mapping conflicts
+func (e *ElasticsearchOutput) recordEncodeFailure(err error) {
+	atomic.AddInt64(&e.metrics.EventsFailed, 1)
+	e.mu.Lock()
+	e.metrics.LastError = err.Error()
+	e.metrics.LastErrorTime = time.Now()
+	e.mu.Unlock()
+
+	if errors.Is(err, errMappingConflict) {
+		e.observeFailed(e.Name(), "elasticsearch", ReasonMappingConflict, 1)
+	}
+}
+
+// documentID returns the configured document ID for an event, or an empty
+// string to let Elasticsearch generate one
+func (e *ElasticsearchOutput) documentID(event *types.LogEvent) string {
+	if e.config.IDField != "" {
+		if id := event.Fields[e.config.IDField]; id != "" {
+			return id
+		}
+	}
+
+	if e.idTemplate == nil {
+		return ""
+	}
+
+	var buf strings.Builder
+	if err := e.idTemplate.Execute(&buf, DocumentIDData{
+		Message:   event.Message,
+		Level:     event.Level,
+		Source:    event.Source,
+		Fields:    event.Fields,
+		Timestamp: event.Timestamp.UnixNano(),
+	}); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// isDuplicate reports whether a failed write was a create conflict for a
+// document that already exists with the configured ID
+func (e *ElasticsearchOutput) isDuplicate(id string, status int) bool {
+	return e.config.UseDataStream && id != "" && status == http.StatusConflict
+}
+
+// encodeDocument serializes an event. For data streams the configured
+// timestamp field (the event timestamp or a parsed field) is written as
+// @timestamp. With a contract batch, the event fields are normalized to the
+// field contract.
+func (e *ElasticsearchOutput) encodeDocument(event *types.LogEvent, batch *contractBatch) ([]byte, error) {
+	var fields map[string]interface{}
+	if batch != nil && len(event.Fields) > 0 {
+		normalized, err := batch.normalize(event.Fields)
+		if err != nil {
+			return nil, err
+		}
+		fields = normalized
+	}
+
+	if e.schema == nil && !e.config.UseDataStream && fields == nil {
+		return json.Marshal(event)
+	}
+
+	var doc map[string]interface{}
+	if e.schema != nil {
+		doc = e.schema.Document(event)
+	} else {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	if fields != nil {
+		e.setFields(doc, event, fields)
+	}
+
+	if !e.config.UseDataStream {
+		return json.Marshal(doc)
+	}
+
+	// The schema may already name the timestamp @timestamp (ecs)
+	if _, ok := doc["@timestamp"]; ok && e.config.IndexTimestampField == "" {
+		return json.Marshal(doc)
+	}
+
+	field := e.config.IndexTimestampField
+	if field == "" {
+		field = "timestamp"
+	}
+
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var value interface{} = timestamp.Format(time.RFC3339Nano)
+	if field == "timestamp" {
+		delete(doc, "timestamp")
+	} else if fieldValue, ok := event.Fields[field]; ok {
+		value = fieldValue
+	}
+	doc["@timestamp"] = value
+
+	return json.Marshal(doc)
+}
+
+// setFields replaces the event fields of a document with normalized ones
+func (e *ElasticsearchOutput) setFields(doc map[string]interface{}, event *types.LogEvent, fields map[string]interface{}) {
+	name := "fields"
+	if e.schema != nil {
+		name = e.schema.names["fields"]
+	}
+	if name != "" {
+		doc[name] = fields
+		return
+	}
+
+	// Without a fields name, the schema spreads them prefixed with an
+	// underscore
+	for key := range event.Fields {
+		delete(doc, "_"+key)
+	}
+	for key, value := range fields {
+		doc["_"+key] = value
+	}
+}
+
 // getIndexName returns the index name for an event, with optional time-based rotation
 func (e *ElasticsearchOutput) getIndexName(event *types.LogEvent) string {
 	index := e.config.Index
 
+	// Data streams handle rollover themselves
+	if e.config.UseDataStream {
+		return index
+	}
+
 	// Apply index rotation
 	if e.config.IndexRotation != "none" && e.config.IndexRotation != "" {
 		timestamp := event.Timestamp
@@ -372,6 +710,23 @@ func (e *ElasticsearchOutput) getIndexName(event *types.LogEvent) string {
 	return index
 }
 
+// Flush sends any events buffered in the batcher
+func (e *ElasticsearchOutput) Flush(ctx context.Context) error {
+	if e.batcher == nil {
+		return nil
+	}
+	return e.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (e *ElasticsearchOutput) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if e.batcher == nil {
+		return false
+	}
+	e.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
 // Close closes the Elasticsearch output
 func (e *ElasticsearchOutput) Close() error {
 	if !e.closed.CompareAndSwap(false, true) {
@@ -388,6 +743,21 @@ func (e *ElasticsearchOutput) Close() error {
 	return nil
 }
 
+// HealthCheck verifies the Elasticsearch cluster is reachable
+func (e *ElasticsearchOutput) HealthCheck(ctx context.Context) error {
+	res, err := e.client.Info(e.client.Info.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to connect to Elasticsearch: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch returned error: %s", res.Status())
+	}
+
+	return nil
+}
+
 // Name returns the output name
 func (e *ElasticsearchOutput) Name() string {
 	if e.config.Name != "" {
@@ -403,5 +773,6 @@ func (e *ElasticsearchOutput) Metrics() *OutputMetrics {
 
 	// Return a copy
 	metricsCopy := *e.metrics
+	e.latency.fill(&metricsCopy)
 	return &metricsCopy
 }
@@ -0,0 +1,487 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordingTransport records requests and answers them as Elasticsearch would
+type recordingTransport struct {
+	requests []*http.Request
+	bodies   []string
+	status   int // response status for document writes, 200 if unset
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+
+	status := http.StatusOK
+	if r.status != 0 {
+		status = r.status
+	}
+
+	respBody := `{"result":"created"}`
+	if strings.HasSuffix(req.URL.Path, "/_bulk") {
+		respBody = `{"errors":false,"items":[]}`
+		if status != http.StatusOK {
+			respBody = fmt.Sprintf(`{"errors":true,"items":[{"create":{"_id":"id-1","status":%d,"error":{"type":"version_conflict_engine_exception"}}}]}`, status)
+			status = http.StatusOK
+		}
+	}
+
+	header := http.Header{}
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(respBody)),
+	}, nil
+}
+
+func newTestElasticsearchOutput(t *testing.T, config ElasticsearchConfig) (*ElasticsearchOutput, *recordingTransport) {
+	t.Helper()
+
+	transport := &recordingTransport{}
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{"http://localhost:9200"},
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return &ElasticsearchOutput{config: config, client: client, metrics: &OutputMetrics{}}, transport
+}
+
+// bulkLines decodes the NDJSON lines of a bulk body
+func bulkLines(t *testing.T, body string) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid bulk line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestElasticsearchOutput_BulkBody(t *testing.T) {
+	timestamp := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	events := []*types.LogEvent{
+		{Timestamp: timestamp, Message: "first"},
+		{Timestamp: timestamp, Message: "second", Fields: map[string]string{"ts": "2024-03-15T09:00:00Z"}},
+	}
+
+	tests := []struct {
+		name          string
+		config        ElasticsearchConfig
+		wantAction    string
+		wantIndex     string
+		wantTimestamp []interface{}
+	}{
+		{
+			name:          "index with rotation",
+			config:        ElasticsearchConfig{Index: "logs", IndexRotation: "daily", IndexTimestampField: "timestamp"},
+			wantAction:    "index",
+			wantIndex:     "logs-2024.03.15",
+			wantTimestamp: []interface{}{nil, nil},
+		},
+		{
+			name:          "data stream",
+			config:        ElasticsearchConfig{Index: "logs-app-default", IndexRotation: "daily", IndexTimestampField: "timestamp", UseDataStream: true},
+			wantAction:    "create",
+			wantIndex:     "logs-app-default",
+			wantTimestamp: []interface{}{"2024-03-15T10:30:00Z", "2024-03-15T10:30:00Z"},
+		},
+		{
+			name:          "data stream with timestamp field",
+			config:        ElasticsearchConfig{Index: "logs-app-default", IndexTimestampField: "ts", UseDataStream: true},
+			wantAction:    "create",
+			wantIndex:     "logs-app-default",
+			wantTimestamp: []interface{}{"2024-03-15T10:30:00Z", "2024-03-15T09:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &ElasticsearchOutput{config: tt.config, metrics: &OutputMetrics{}}
+
+			buf, _, _ := out.buildBulkBody(events)
+			lines := bulkLines(t, buf.String())
+			if len(lines) != 2*len(events) {
+				t.Fatalf("expected %d bulk lines, got %d", 2*len(events), len(lines))
+			}
+
+			for i := range events {
+				meta, doc := lines[2*i], lines[2*i+1]
+
+				action, ok := meta[tt.wantAction].(map[string]interface{})
+				if !ok || len(meta) != 1 {
+					t.Fatalf("expected a %s action, got %v", tt.wantAction, meta)
+				}
+				if action["_index"] != tt.wantIndex {
+					t.Errorf("_index = %v, want %s", action["_index"], tt.wantIndex)
+				}
+				if doc["@timestamp"] != tt.wantTimestamp[i] {
+					t.Errorf("@timestamp = %v, want %v", doc["@timestamp"], tt.wantTimestamp[i])
+				}
+				if tt.config.UseDataStream && tt.config.IndexTimestampField == "timestamp" {
+					if _, ok := doc["timestamp"]; ok {
+						t.Error("expected timestamp to be mapped to @timestamp")
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestElasticsearchOutput_DataStreamRequests(t *testing.T) {
+	config := ElasticsearchConfig{Index: "logs-app-default", IndexTimestampField: "timestamp", UseDataStream: true}
+	out, transport := newTestElasticsearchOutput(t, config)
+
+	event := &types.LogEvent{Timestamp: time.Now(), Message: "hello"}
+	if err := out.sendSingle(context.Background(), event); err != nil {
+		t.Fatalf("sendSingle() error = %v", err)
+	}
+	if err := out.sendBatchInternal(context.Background(), []*types.LogEvent{event}); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	if len(transport.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(transport.requests))
+	}
+
+	single := transport.requests[0]
+	if single.URL.Path != "/logs-app-default/_doc" || single.URL.Query().Get("op_type") != "create" {
+		t.Errorf("expected a create request to the data stream, got %s?%s", single.URL.Path, single.URL.RawQuery)
+	}
+	if !bytes.Contains([]byte(transport.bodies[0]), []byte(`"@timestamp"`)) {
+		t.Errorf("expected @timestamp in document, got %s", transport.bodies[0])
+	}
+
+	if lines := bulkLines(t, transport.bodies[1]); len(lines) != 2 || lines[0]["create"] == nil {
+		t.Errorf("expected a create action in the bulk body, got %s", transport.bodies[1])
+	}
+}
+
+func TestValidateDataStreamName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"logs-app-default", false},
+		{"logs-%{+YYYY.MM.dd}", true},
+		{"Logs-App", true},
+		{"_logs", true},
+		{".ds-logs", true},
+		{"logs*", true},
+		{"logs app", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDataStreamName(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("validateDataStreamName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewElasticsearchOutput_InvalidDataStream(t *testing.T) {
+	config := DefaultElasticsearchConfig()
+	config.Index = "logs-%{+YYYY.MM.dd}"
+	config.UseDataStream = true
+
+	if _, err := NewElasticsearchOutput(config); err == nil || !strings.Contains(err.Error(), "data stream") {
+		t.Errorf("expected data stream validation error, got %v", err)
+	}
+}
+
+func TestElasticsearchOutput_DocumentID(t *testing.T) {
+	timestamp := time.Unix(0, 1700000000000000000)
+	withID := &types.LogEvent{Timestamp: timestamp, Source: "app", Fields: map[string]string{"event_id": "abc", "offset": "42"}}
+	withoutID := &types.LogEvent{Timestamp: timestamp, Source: "app"}
+
+	tests := []struct {
+		name     string
+		idField  string
+		template string
+		event    *types.LogEvent
+		want     string
+	}{
+		{name: "no id configured", event: withID, want: ""},
+		{name: "id field", idField: "event_id", event: withID, want: "abc"},
+		{name: "missing id field", idField: "event_id", event: withoutID, want: ""},
+		{name: "template", template: "{{.Source}}-{{.Fields.offset}}", event: withID, want: "app-42"},
+		{name: "template timestamp", template: "{{.Source}}-{{.Timestamp}}", event: withoutID, want: "app-1700000000000000000"},
+		{name: "field before template", idField: "event_id", template: "{{.Source}}", event: withID, want: "abc"},
+		{name: "template fallback", idField: "event_id", template: "{{.Source}}", event: withoutID, want: "app"},
+		{name: "template with missing field", template: "{{.Fields.offset}}", event: withoutID, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idTemplate, err := parseIDTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseIDTemplate() error = %v", err)
+			}
+			out := &ElasticsearchOutput{
+				config:     ElasticsearchConfig{Index: "logs", IDField: tt.idField, IDTemplate: tt.template},
+				idTemplate: idTemplate,
+				metrics:    &OutputMetrics{},
+			}
+
+			if got := out.documentID(tt.event); got != tt.want {
+				t.Errorf("documentID() = %q, want %q", got, tt.want)
+			}
+
+			buf, _, _ := out.buildBulkBody([]*types.LogEvent{tt.event})
+			meta := bulkLines(t, buf.String())[0]["index"].(map[string]interface{})
+			id, ok := meta["_id"]
+			if tt.want == "" {
+				if ok {
+					t.Errorf("expected an auto-generated ID, got _id %v", id)
+				}
+			} else if id != tt.want {
+				t.Errorf("bulk _id = %v, want %s", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIDTemplate_Invalid(t *testing.T) {
+	if _, err := parseIDTemplate("{{.Source"); err == nil {
+		t.Error("expected error for invalid template")
+	}
+
+	config := DefaultElasticsearchConfig()
+	config.IDTemplate = "{{.Source"
+	if _, err := NewElasticsearchOutput(config); err == nil || !strings.Contains(err.Error(), "id_template") {
+		t.Errorf("expected id_template error, got %v", err)
+	}
+}
+
+func TestElasticsearchOutput_SendWithDocumentID(t *testing.T) {
+	out, transport := newTestElasticsearchOutput(t, ElasticsearchConfig{Index: "logs", IDField: "event_id"})
+
+	event := &types.LogEvent{Message: "hello", Fields: map[string]string{"event_id": "id-1"}}
+	if err := out.sendSingle(context.Background(), event); err != nil {
+		t.Fatalf("sendSingle() error = %v", err)
+	}
+
+	req := transport.requests[0]
+	if req.Method != http.MethodPut || req.URL.Path != "/logs/_doc/id-1" {
+		t.Errorf("expected PUT /logs/_doc/id-1, got %s %s", req.Method, req.URL.Path)
+	}
+}
+
+func TestElasticsearchOutput_DuplicateCreate(t *testing.T) {
+	config := ElasticsearchConfig{Index: "logs-app-default", UseDataStream: true, IDField: "event_id"}
+	out, transport := newTestElasticsearchOutput(t, config)
+	transport.status = http.StatusConflict
+
+	event := &types.LogEvent{Message: "hello", Fields: map[string]string{"event_id": "id-1"}}
+	if err := out.sendSingle(context.Background(), event); err != nil {
+		t.Errorf("expected a duplicate create to succeed, got %v", err)
+	}
+	if err := out.sendBatchInternal(context.Background(), []*types.LogEvent{event}); err != nil {
+		t.Errorf("expected a duplicate bulk create to succeed, got %v", err)
+	}
+	if failed := out.Metrics().EventsFailed; failed != 0 {
+		t.Errorf("expected no failed events, got %d", failed)
+	}
+
+	// Without a document ID a conflict is a real failure
+	out.config.IDField = ""
+	if err := out.sendSingle(context.Background(), &types.LogEvent{Message: "hello"}); err == nil {
+		t.Error("expected a conflict without document ID to fail")
+	}
+}
+
+// discardTransport answers bulk requests with success without recording them
+type discardTransport struct{}
+
+func (discardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+	}
+
+	header := http.Header{}
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"errors":false,"items":[]}`)),
+	}, nil
+}
+
+func BenchmarkElasticsearchOutput_SendBatch(b *testing.B) {
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{"http://localhost:9200"},
+		Transport: discardTransport{},
+	})
+	if err != nil {
+		b.Fatalf("failed to create client: %v", err)
+	}
+
+	for _, pooled := range []bool{false, true} {
+		name := "unpooled"
+		if pooled {
+			name = "pooled"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			pool.SetEnabled(pooled)
+			defer pool.SetEnabled(true)
+
+			out := &ElasticsearchOutput{config: ElasticsearchConfig{Index: "logs"}, client: client, metrics: &OutputMetrics{}}
+			events := testBatch(500)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := out.sendBatchInternal(context.Background(), events); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestElasticsearchOutput_ErrorClasses(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrAuth},
+		{http.StatusServiceUnavailable, ErrTransient},
+		{http.StatusTooManyRequests, ErrTransient},
+		{http.StatusBadRequest, ErrPermanent},
+	}
+
+	for _, tt := range tests {
+		out, transport := newTestElasticsearchOutput(t, ElasticsearchConfig{Index: "logs"})
+		transport.status = tt.status
+		event := &types.LogEvent{Message: "hello"}
+
+		// Single documents fail with the response status, bulk requests
+		// with the status of their failed items
+		if err := out.sendSingle(context.Background(), event); !errors.Is(err, tt.want) {
+			t.Errorf("status %d: expected %v from sendSingle, got %v", tt.status, tt.want, err)
+		}
+		if err := out.sendBatchInternal(context.Background(), []*types.LogEvent{event}); !errors.Is(err, tt.want) {
+			t.Errorf("status %d: expected %v from sendBatchInternal, got %v", tt.status, tt.want, err)
+		}
+	}
+}
+
+func TestElasticsearchOutput_FieldContract(t *testing.T) {
+	events := []*types.LogEvent{
+		{Message: "first", Fields: map[string]string{"user": "alice", "status": "200"}},
+		{Message: "second", Fields: map[string]string{"user": `{"name":"bob"}`, "status": "ok"}},
+		{Message: "third", Fields: map[string]string{"user.id": "7", "status": "404"}},
+	}
+
+	tests := []struct {
+		name       string
+		onConflict string
+		wantFields []map[string]interface{}
+	}{
+		{
+			name:       "suffix",
+			onConflict: ConflictSuffix,
+			wantFields: []map[string]interface{}{
+				{"user": "alice", "status": float64(200)},
+				{"user_object": map[string]interface{}{"name": "bob"}, "status_keyword": "ok"},
+				{"user_object.id": "7", "status": float64(404)},
+			},
+		},
+		{
+			name:       "quarantine",
+			onConflict: ConflictQuarantine,
+			wantFields: []map[string]interface{}{
+				{"user": "alice", "status": float64(200)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := metrics.NewCollector()
+			out, transport := newTestElasticsearchOutput(t, ElasticsearchConfig{Index: "logs"})
+			out.SetCollector(collector)
+			contract, err := newFieldContract(FieldContractConfig{
+				Types:      map[string]string{"status": FieldLong},
+				OnConflict: tt.onConflict,
+			})
+			if err != nil {
+				t.Fatalf("newFieldContract() error = %v", err)
+			}
+			out.contract = contract
+
+			// The conflicting documents do not fail the batch
+			if err := out.sendBatchInternal(context.Background(), events); err != nil {
+				t.Fatalf("sendBatchInternal() error = %v", err)
+			}
+
+			lines := bulkLines(t, transport.bodies[0])
+			if len(lines) != 2*len(tt.wantFields) {
+				t.Fatalf("expected %d documents, got %d lines", len(tt.wantFields), len(lines))
+			}
+			for i, want := range tt.wantFields {
+				got, _ := json.Marshal(lines[2*i+1]["fields"])
+				if wantJSON, _ := json.Marshal(want); string(got) != string(wantJSON) {
+					t.Errorf("document %d fields = %s, want %s", i, got, wantJSON)
+				}
+			}
+
+			quarantined := int64(len(events) - len(tt.wantFields))
+			if m := out.Metrics(); m.EventsSent != int64(len(tt.wantFields)) || m.EventsFailed != quarantined {
+				t.Errorf("expected %d sent and %d failed, got %d and %d", len(tt.wantFields), quarantined, m.EventsSent, m.EventsFailed)
+			}
+			if quarantined > 0 {
+				failed := findMetric(t, collector, "logaggregator_output_events_failed_total", "elasticsearch", "elasticsearch")
+				if failed.GetCounter().GetValue() != float64(quarantined) {
+					t.Errorf("mapping conflicts = %v, want %d", failed.GetCounter().GetValue(), quarantined)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFieldContract_Invalid(t *testing.T) {
+	if contract, err := newFieldContract(FieldContractConfig{}); contract != nil || err != nil {
+		t.Errorf("expected no contract when disabled, got %v, %v", contract, err)
+	}
+	if _, err := newFieldContract(FieldContractConfig{Types: map[string]string{"user": "text"}}); err == nil {
+		t.Error("expected an error for an unsupported type")
+	}
+	if _, err := newFieldContract(FieldContractConfig{OnConflict: "drop"}); err == nil {
+		t.Error("expected an error for an unsupported on_conflict")
+	}
+}
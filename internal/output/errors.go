@@ -0,0 +1,118 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error classes returned by outputs. Errors returned by Send and SendBatch
+// wrap one of them, so callers can decide with errors.Is whether a failed
+// send is worth retrying.
+var (
+	// ErrSerialization means an event could not be encoded; resending it
+	// fails the same way
+	ErrSerialization = errors.New("serialization error")
+
+	// ErrTransient means the destination was unreachable, overloaded or
+	// timed out; the send may succeed when retried
+	ErrTransient = errors.New("transient error")
+
+	// ErrAuth means the destination rejected the output's credentials
+	ErrAuth = errors.New("authentication error")
+
+	// ErrPermanent means the destination rejected the request itself
+	ErrPermanent = errors.New("permanent error")
+)
+
+// Error is an output error with its class and, for HTTP-based
+// destinations, the response status
+type Error struct {
+	// Class is ErrSerialization, ErrTransient, ErrAuth or ErrPermanent
+	Class error
+
+	// StatusCode is the HTTP status of the response, 0 if there was none
+	StatusCode int
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the class and the underlying error, so errors.Is matches
+// both
+func (e *Error) Unwrap() []error {
+	return []error{e.Class, e.Err}
+}
+
+// classify wraps err with an error class. Errors that already have a class
+// and context cancellations are returned unchanged.
+func classify(class error, err error) error {
+	if err == nil || ErrorClass(err) != nil {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &Error{Class: class, Err: err}
+}
+
+// classifyf formats an error and wraps it with an error class
+func classifyf(class error, format string, args ...interface{}) error {
+	return classify(class, fmt.Errorf(format, args...))
+}
+
+// statusError wraps err with the class of an HTTP response status
+func statusError(statusCode int, err error) error {
+	return &Error{Class: statusClass(statusCode), StatusCode: statusCode, Err: err}
+}
+
+// statusClass maps an unsuccessful HTTP status to an error class: 401 and
+// 403 are auth failures, 408, 429 and 5xx transient, other statuses
+// permanent
+func statusClass(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests || statusCode >= 500:
+		return ErrTransient
+	default:
+		return ErrPermanent
+	}
+}
+
+// ErrorClass returns the class of an output error, or nil if it has none
+func ErrorClass(err error) error {
+	for _, class := range []error{ErrSerialization, ErrTransient, ErrAuth, ErrPermanent} {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+	return nil
+}
+
+// IsRetryable reports whether a failed send may succeed when retried
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+// errorClassName returns the name of the class of err as recorded in dead
+// letter metadata
+func errorClassName(err error) string {
+	switch ErrorClass(err) {
+	case ErrSerialization:
+		return "serialization"
+	case ErrTransient:
+		return "transient"
+	case ErrAuth:
+		return "auth"
+	case ErrPermanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
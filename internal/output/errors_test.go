@@ -0,0 +1,69 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]error{
+		http.StatusBadRequest:            ErrPermanent,
+		http.StatusUnauthorized:          ErrAuth,
+		http.StatusForbidden:             ErrAuth,
+		http.StatusNotFound:              ErrPermanent,
+		http.StatusRequestTimeout:        ErrTransient,
+		http.StatusConflict:              ErrPermanent,
+		http.StatusRequestEntityTooLarge: ErrPermanent,
+		http.StatusTooManyRequests:       ErrTransient,
+		http.StatusInternalServerError:   ErrTransient,
+		http.StatusServiceUnavailable:    ErrTransient,
+	}
+	for status, want := range tests {
+		if got := statusClass(status); got != want {
+			t.Errorf("statusClass(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("es: %w", classifyf(ErrTransient, "bulk request failed: %w", cause))
+
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, cause) {
+		t.Errorf("expected the error to match its class and cause, got %v", err)
+	}
+	if errors.Is(err, ErrPermanent) || errors.Is(err, ErrAuth) {
+		t.Errorf("expected the error to match only its class")
+	}
+	if err.Error() != "es: bulk request failed: connection refused" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	var outputErr *Error
+	if !errors.As(statusError(http.StatusUnauthorized, cause), &outputErr) || outputErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the status code to be recorded, got %+v", outputErr)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	// An error keeps the class it was given first
+	err := classify(ErrPermanent, classify(ErrAuth, errors.New("forbidden")))
+	if ErrorClass(err) != ErrAuth {
+		t.Errorf("expected the auth class to be kept, got %v", ErrorClass(err))
+	}
+
+	// Cancellations are not output failures
+	if err := classify(ErrTransient, context.Canceled); ErrorClass(err) != nil {
+		t.Errorf("expected a cancellation to stay unclassified, got %v", err)
+	}
+
+	if classify(ErrTransient, nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+	if errorClassName(err) != "auth" || errorClassName(errors.New("plain")) != "unknown" {
+		t.Error("expected class names auth and unknown")
+	}
+}
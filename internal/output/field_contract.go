@@ -0,0 +1,192 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field types of a field contract, named after the Elasticsearch types
+// they are indexed as
+const (
+	FieldKeyword = "keyword"
+	FieldLong    = "long"
+	FieldDouble  = "double"
+	FieldBoolean = "boolean"
+	FieldObject  = "object"
+)
+
+// What a field contract does with a document whose field conflicts
+const (
+	ConflictSuffix     = "suffix"
+	ConflictQuarantine = "quarantine"
+)
+
+// ReasonMappingConflict is the failure reason of quarantined documents
+const ReasonMappingConflict = "mapping_conflict"
+
+// errMappingConflict is returned for documents quarantined by a contract
+var errMappingConflict = errors.New("mapping conflict")
+
+// FieldContractConfig declares the types event fields are indexed as, so a
+// field that is a string in one document and an object in another does
+// not fail the bulk request it is part of.
+//
+// Declared fields are coerced to their type. Other fields keep their
+// string values, except for JSON objects, which are indexed as objects. A
+// field that cannot be coerced, or whose type differs from the one it had
+// earlier in the batch, is a conflict.
+type FieldContractConfig struct {
+	// Types maps field names to keyword, long, double, boolean or object.
+	// Dotted names are object paths, as in Elasticsearch.
+	Types map[string]string `yaml:"types,omitempty"`
+
+	// OnConflict is suffix (the default), which renames the conflicting
+	// field after its type (user_object), or quarantine, which drops the
+	// document and counts it as failed with reason mapping_conflict
+	OnConflict string `yaml:"on_conflict,omitempty"`
+}
+
+// Enabled returns whether documents are checked against the contract
+func (c FieldContractConfig) Enabled() bool {
+	return len(c.Types) > 0 || c.OnConflict != ""
+}
+
+// fieldContract checks the fields of documents against a contract
+type fieldContract struct {
+	types      map[string]string
+	quarantine bool
+}
+
+// newFieldContract validates a contract configuration, returning nil when
+// it is not enabled
+func newFieldContract(config FieldContractConfig) (*fieldContract, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	for field, fieldType := range config.Types {
+		switch fieldType {
+		case FieldKeyword, FieldLong, FieldDouble, FieldBoolean, FieldObject:
+		default:
+			return nil, fmt.Errorf("field_contract: unsupported type %q for field %s", fieldType, field)
+		}
+	}
+
+	switch config.OnConflict {
+	case "", ConflictSuffix, ConflictQuarantine:
+	default:
+		return nil, fmt.Errorf("field_contract: unsupported on_conflict %q: must be suffix or quarantine", config.OnConflict)
+	}
+
+	return &fieldContract{types: config.Types, quarantine: config.OnConflict == ConflictQuarantine}, nil
+}
+
+// newBatch starts tracking the field types of a batch of documents
+func (c *fieldContract) newBatch() *contractBatch {
+	return &contractBatch{contract: c, types: make(map[string]string)}
+}
+
+// contractBatch remembers the type each field path first had in a batch,
+// since Elasticsearch maps a new field after the first document with it
+type contractBatch struct {
+	contract *fieldContract
+	types    map[string]string
+}
+
+// normalize returns the fields of a document coerced to the contract, with
+// conflicting fields renamed. With quarantine, a conflict returns
+// errMappingConflict instead.
+func (b *contractBatch) normalize(fields map[string]string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	// A field precedes the paths below it, which conflict with it
+	sort.Strings(keys)
+
+	normalized := make(map[string]interface{}, len(fields))
+	seen := make(map[string]string)
+	for _, key := range keys {
+		value, fieldType := b.coerce(key, fields[key])
+
+		// Every parent of a dotted path is an object
+		segments := strings.Split(key, ".")
+		for i := range segments {
+			path := strings.Join(segments[:i+1], ".")
+			pathType := FieldObject
+			if i == len(segments)-1 {
+				pathType = fieldType
+			}
+
+			if expected := b.expected(path, seen); expected != "" && expected != pathType {
+				if b.contract.quarantine {
+					return nil, fmt.Errorf("%w: field %s is %s, not %s", errMappingConflict, path, pathType, expected)
+				}
+				segments[i] += "_" + pathType
+				key = strings.Join(segments, ".")
+				break
+			}
+		}
+
+		for i := range segments {
+			path := strings.Join(segments[:i+1], ".")
+			if i == len(segments)-1 {
+				seen[path] = fieldType
+			} else {
+				seen[path] = FieldObject
+			}
+		}
+		normalized[key] = value
+	}
+
+	// Only documents that are indexed settle the types of their fields
+	for path, fieldType := range seen {
+		b.types[path] = fieldType
+	}
+	return normalized, nil
+}
+
+// expected returns the type a field path must have: its declared type, or
+// the type it had earlier in the document or batch
+func (b *contractBatch) expected(path string, seen map[string]string) string {
+	if fieldType, ok := b.contract.types[path]; ok {
+		return fieldType
+	}
+	if fieldType, ok := seen[path]; ok {
+		return fieldType
+	}
+	return b.types[path]
+}
+
+// coerce converts a field value to its declared type, or infers the type
+// of undeclared and unconvertible values
+func (b *contractBatch) coerce(key, value string) (interface{}, string) {
+	switch b.contract.types[key] {
+	case FieldKeyword:
+		return value, FieldKeyword
+	case FieldLong:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n, FieldLong
+		}
+	case FieldDouble:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f, FieldDouble
+		}
+	case FieldBoolean:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v, FieldBoolean
+		}
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &object); err == nil {
+			return object, FieldObject
+		}
+	}
+	return value, FieldKeyword
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileConfig contains file output configuration
+type FileConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// Path is the file events are appended to; it is created if missing
+	Path string `yaml:"path"`
+}
+
+func init() {
+	Register("file", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultFileConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewFileOutput(config)
+	})
+}
+
+// DefaultFileConfig returns default file output configuration, batched like
+// the stdout output
+func DefaultFileConfig() FileConfig {
+	return FileConfig{BaseConfig: DefaultStdoutConfig().BaseConfig}
+}
+
+// FileOutput appends events to a file, one JSON document per line by
+// default. It writes and batches events like the stdout output.
+type FileOutput struct {
+	*StdoutOutput
+	file      *os.File
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFileOutput creates a new file output, opening its file for appending
+func NewFileOutput(config FileConfig) (*FileOutput, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("no path specified")
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	out, err := newWriterOutput("file", StdoutConfig{BaseConfig: config.BaseConfig, Writer: file})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileOutput{StdoutOutput: out, file: file}, nil
+}
+
+// Close writes the buffered events and closes the file
+func (f *FileOutput) Close() error {
+	f.closeOnce.Do(func() {
+		f.closeErr = errors.Join(f.StdoutOutput.Close(), f.file.Close())
+	})
+	return f.closeErr
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, []byte("{\"message\":\"existing\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := New("file", map[string]interface{}{
+		"path":           path,
+		"batch_size":     3,
+		"flush_interval": time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if out.Name() != "file" {
+		t.Errorf("Name() = %q, want file", out.Name())
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := out.Send(context.Background(), &types.LogEvent{Message: fmt.Sprintf("event-%d", i)}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are appended after the existing content, the last one written
+	// on Close
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{"existing", "event-0", "event-1", "event-2", "event-3"}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %q", len(want), len(lines), data)
+	}
+	for i, line := range lines {
+		var event types.LogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		if event.Message != want[i] {
+			t.Errorf("line %d message = %q, want %q", i, event.Message, want[i])
+		}
+	}
+
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "late"}); err == nil {
+		t.Error("expected Send() to fail after Close()")
+	}
+}
+
+func TestNewFileOutput_Errors(t *testing.T) {
+	if _, err := NewFileOutput(DefaultFileConfig()); err == nil {
+		t.Error("expected an error without a path")
+	}
+
+	config := DefaultFileConfig()
+	config.Path = filepath.Join(t.TempDir(), "missing", "events.ndjson")
+	if _, err := NewFileOutput(config); err == nil {
+		t.Error("expected an error for a path in a missing directory")
+	}
+}
@@ -0,0 +1,249 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+// gcsScope is the OAuth scope of object uploads and bucket lookups
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsDefaultEndpoint is the Cloud Storage JSON API endpoint
+const gcsDefaultEndpoint = "https://storage.googleapis.com"
+
+// GCSConfig contains Google Cloud Storage-specific configuration
+type GCSConfig struct {
+	BaseConfig   `yaml:",inline"`
+	ObjectConfig `yaml:",inline"`
+
+	// Bucket is the GCS bucket name
+	Bucket string `yaml:"bucket"`
+
+	// StorageClass is the GCS storage class (STANDARD, NEARLINE, COLDLINE,
+	// ARCHIVE); the bucket's default class if not set
+	StorageClass string `yaml:"storage_class,omitempty"`
+
+	// CredentialsFile is the path of a service account JSON key. Defaults
+	// to GOOGLE_APPLICATION_CREDENTIALS. Only service account keys are
+	// supported: user credentials from gcloud, workload identity
+	// federation and the metadata server of GCE and GKE are not.
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+
+	// CredentialsJSON is a service account JSON key, instead of a file
+	CredentialsJSON string `yaml:"credentials_json,omitempty"`
+
+	// Endpoint for GCS emulators (e.g., fake-gcs-server), which may be
+	// used without credentials
+	Endpoint string `yaml:"endpoint,omitempty"`
+}
+
+func init() {
+	Register("gcs", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultGCSConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewGCSOutput(config)
+	})
+}
+
+// DefaultGCSConfig returns default GCS configuration
+func DefaultGCSConfig() GCSConfig {
+	return GCSConfig{
+		BaseConfig:   DefaultBaseConfig(),
+		ObjectConfig: DefaultObjectConfig(),
+	}
+}
+
+// serviceAccountKey is the part of a service account JSON key used to
+// sign token requests
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// GCSOutput sends events to Google Cloud Storage through its JSON API,
+// authorized with tokens it signs for a service account key rather than
+// Application Default Credentials
+type GCSOutput struct {
+	*objectWriter
+	config   GCSConfig
+	client   *http.Client
+	endpoint string
+}
+
+// NewGCSOutput creates a new GCS output
+func NewGCSOutput(gcsConfig GCSConfig) (*GCSOutput, error) {
+	if gcsConfig.Bucket == "" {
+		return nil, fmt.Errorf("no bucket specified")
+	}
+
+	client, err := gcsClient(gcsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := gcsDefaultEndpoint
+	if gcsConfig.Endpoint != "" {
+		endpoint = strings.TrimSuffix(gcsConfig.Endpoint, "/")
+	}
+
+	output := &GCSOutput{
+		config:   gcsConfig,
+		client:   client,
+		endpoint: endpoint,
+	}
+	output.objectWriter, err = newObjectWriter("gcs", gcsConfig.BaseConfig, gcsConfig.ObjectConfig, output.uploadObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// gcsClient returns an HTTP client authorized with the configured service
+// account, or an unauthorized one for an emulator without credentials.
+// Other credential types are rejected rather than looked up the way
+// Application Default Credentials would.
+func gcsClient(config GCSConfig) (*http.Client, error) {
+	credentials := []byte(config.CredentialsJSON)
+	if len(credentials) == 0 {
+		path := config.CredentialsFile
+		if path == "" {
+			path = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+		}
+		if path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read GCS credentials: %w", err)
+			}
+			credentials = data
+		}
+	}
+
+	if len(credentials) == 0 {
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("no GCS credentials: set credentials_file, credentials_json or GOOGLE_APPLICATION_CREDENTIALS")
+		}
+		return &http.Client{Timeout: config.Timeout}, nil
+	}
+
+	var key serviceAccountKey
+	if err := json.Unmarshal(credentials, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse GCS credentials: %w", err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported GCS credentials type %q: only service_account keys are supported", key.Type)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	jwtConfig := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		Scopes:       []string{gcsScope},
+		TokenURL:     key.TokenURI,
+	}
+
+	// Token requests use a client with the same timeout as uploads
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: config.Timeout})
+	return &http.Client{
+		Transport: &oauth2.Transport{Source: jwtConfig.TokenSource(ctx)},
+		Timeout:   config.Timeout,
+	}, nil
+}
+
+// uploadObject uploads data to GCS as a multipart upload, whose metadata
+// part sets the object's name, content type and encoding, and storage class
+func (g *GCSOutput) uploadObject(ctx context.Context, key string, data []byte) error {
+	metadata := map[string]string{
+		"name":        key,
+		"contentType": g.object.ContentType,
+	}
+	if g.config.StorageClass != "" {
+		metadata["storageClass"] = g.config.StorageClass
+	}
+	if encoding := g.compressor.ContentEncoding(); encoding != "" {
+		metadata["contentEncoding"] = encoding
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return classifyf(ErrSerialization, "failed to encode object metadata: %w", err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", metadataJSON},
+		{g.object.ContentType, data},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return classifyf(ErrSerialization, "failed to encode upload: %w", err)
+		}
+		w.Write(part.data)
+	}
+	parts.Close()
+
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=multipart", g.endpoint, url.PathEscape(g.config.Bucket))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &body)
+	if err != nil {
+		return classifyf(ErrPermanent, "failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+parts.Boundary())
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return classifyf(ErrTransient, "failed to upload to GCS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return statusError(resp.StatusCode, fmt.Errorf("failed to upload to GCS: %w", &httpStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(message))}))
+}
+
+// HealthCheck verifies the GCS bucket is reachable
+func (g *GCSOutput) HealthCheck(ctx context.Context) error {
+	bucketURL := fmt.Sprintf("%s/storage/v1/b/%s", g.endpoint, url.PathEscape(g.config.Bucket))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bucketURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach GCS bucket %s: %w", g.config.Bucket, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to reach GCS bucket %s: %w", g.config.Bucket, &httpStatusError{StatusCode: resp.StatusCode})
+	}
+	return nil
+}
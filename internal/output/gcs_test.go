@@ -0,0 +1,263 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// fakeGCS serves the JSON API endpoints used by GCSOutput, keeping the
+// metadata and media of each multipart upload
+type fakeGCS struct {
+	mu       sync.Mutex
+	metadata []map[string]string
+	media    [][]byte
+	auth     []string
+	status   int
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/logs":
+		w.Write([]byte(`{"name":"logs"}`))
+		return
+	case r.Method != http.MethodPost || r.URL.Path != "/upload/storage/v1/b/logs/o" || r.URL.Query().Get("uploadType") != "multipart":
+		http.NotFound(w, r)
+		return
+	}
+	if f.status != 0 {
+		http.Error(w, "unavailable", f.status)
+		return
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		http.Error(w, "not a multipart upload", http.StatusBadRequest)
+		return
+	}
+	parts := multipart.NewReader(r.Body, params["boundary"])
+
+	var metadata map[string]string
+	part, err := parts.NextPart()
+	if err != nil || json.NewDecoder(part).Decode(&metadata) != nil {
+		http.Error(w, "bad metadata", http.StatusBadRequest)
+		return
+	}
+	part, err = parts.NextPart()
+	if err != nil {
+		http.Error(w, "no media", http.StatusBadRequest)
+		return
+	}
+	media, _ := io.ReadAll(part)
+
+	f.metadata = append(f.metadata, metadata)
+	f.media = append(f.media, media)
+	w.Write([]byte(`{}`))
+}
+
+func newTestGCSOutput(t *testing.T, server *httptest.Server, configure func(*GCSConfig)) *GCSOutput {
+	t.Helper()
+
+	config := DefaultGCSConfig()
+	config.Bucket = "logs"
+	config.Endpoint = server.URL
+	config.BatchSize = 1
+	if configure != nil {
+		configure(&config)
+	}
+	out, err := NewGCSOutput(config)
+	if err != nil {
+		t.Fatalf("NewGCSOutput() error = %v", err)
+	}
+	t.Cleanup(func() { out.Close() })
+	return out
+}
+
+func TestGCSOutput_Upload(t *testing.T) {
+	gcs := &fakeGCS{}
+	server := httptest.NewServer(gcs)
+	defer server.Close()
+
+	out := newTestGCSOutput(t, server, func(c *GCSConfig) {
+		c.StorageClass = "NEARLINE"
+		c.Compression = CompressionGzip
+	})
+
+	ts := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	if err := out.Send(context.Background(), &types.LogEvent{Timestamp: ts, Message: "hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(gcs.metadata) != 1 {
+		t.Fatalf("expected 1 upload, got %d", len(gcs.metadata))
+	}
+	want := map[string]string{
+		"name":            "logs/2024/03/15/10/1710496800.json.gz",
+		"contentType":     "application/json",
+		"contentEncoding": "gzip",
+		"storageClass":    "NEARLINE",
+	}
+	for key, value := range want {
+		if gcs.metadata[0][key] != value {
+			t.Errorf("metadata %s = %q, want %q", key, gcs.metadata[0][key], value)
+		}
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(gcs.media[0]))
+	if err != nil {
+		t.Fatalf("expected gzip media: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if !strings.Contains(string(body), `"message":"hello"`) {
+		t.Errorf("unexpected object body %q", body)
+	}
+	if m := out.Metrics(); m.EventsSent != 1 || m.BytesSent != int64(len(gcs.media[0])) {
+		t.Errorf("expected 1 event of %d bytes sent, got %d of %d", len(gcs.media[0]), m.EventsSent, m.BytesSent)
+	}
+
+	if err := out.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck() error = %v", err)
+	}
+}
+
+func TestGCSOutput_UploadErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		class  error
+	}{
+		{http.StatusServiceUnavailable, ErrTransient},
+		{http.StatusForbidden, ErrAuth},
+		{http.StatusBadRequest, ErrPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(&fakeGCS{status: tt.status})
+			defer server.Close()
+			out := newTestGCSOutput(t, server, nil)
+
+			err := out.SendBatch(context.Background(), messageBatch("event", 2))
+			if !errors.Is(err, tt.class) {
+				t.Errorf("expected %v, got %v", tt.class, err)
+			}
+			if m := out.Metrics(); m.EventsFailed != 2 {
+				t.Errorf("expected 2 failed events, got %d", m.EventsFailed)
+			}
+		})
+	}
+}
+
+func TestGCSOutput_KeyGeneration(t *testing.T) {
+	server := httptest.NewServer(&fakeGCS{})
+	defer server.Close()
+
+	out := newTestGCSOutput(t, server, func(c *GCSConfig) {
+		c.Prefix = "archive/"
+		c.KeyTemplate = "{{.Fields.service}}/{{.Year}}/{{.Month}}/{{.Day}}/{{.Timestamp}}.ndjson"
+		c.Compression = CompressionZstd
+	})
+
+	ts := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"interpolated", map[string]string{"service": "api"}, "archive/api/2024/03/15/1710496800.ndjson.zst"},
+		{"sanitized", map[string]string{"service": "web/v2"}, "archive/web_v2/2024/03/15/1710496800.ndjson.zst"},
+		{"missing", nil, "archive/unknown/2024/03/15/1710496800.ndjson.zst"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := out.generateKey(&types.LogEvent{Timestamp: ts, Fields: tt.fields}); got != tt.want {
+				t.Errorf("generateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := DefaultGCSConfig()
+	config.Bucket = "logs"
+	config.Endpoint = server.URL
+	config.KeyTemplate = "{{.Date}}/events.json"
+	if _, err := NewGCSOutput(config); err == nil {
+		t.Error("expected an error for an unknown key template placeholder")
+	}
+}
+
+func TestGCSOutput_ServiceAccount(t *testing.T) {
+	gcs := &fakeGCS{}
+	var tokenRequests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token-1","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.Handle("/", gcs)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "logs@project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":      server.URL + "/token",
+	})
+
+	out := newTestGCSOutput(t, server, func(c *GCSConfig) {
+		c.CredentialsJSON = string(credentials)
+	})
+	for i := 0; i < 2; i++ {
+		if err := out.Send(context.Background(), &types.LogEvent{Message: "hello"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	if tokenRequests != 1 {
+		t.Errorf("expected the token reused, got %d token requests", tokenRequests)
+	}
+	for _, auth := range gcs.auth {
+		if auth != "Bearer token-1" {
+			t.Errorf("expected requests authorized with the token, got %q", auth)
+		}
+	}
+
+	// Credentials are required outside an emulator
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	if _, err := NewGCSOutput(GCSConfig{Bucket: "logs"}); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	if _, err := NewGCSOutput(GCSConfig{Bucket: "logs", CredentialsJSON: `{"type":"authorized_user"}`}); err == nil {
+		t.Error("expected an error for credentials other than a service account")
+	}
+}
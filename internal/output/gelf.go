@@ -0,0 +1,398 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// GELF transports
+const (
+	GELFTransportUDP = "udp"
+	GELFTransportTCP = "tcp"
+)
+
+// CompressionZlib is the zlib compression GELF accepts over UDP
+const CompressionZlib CompressionType = "zlib"
+
+// GELF UDP chunking limits
+const (
+	// DefaultGELFChunkSize keeps chunks within a typical WAN MTU
+	DefaultGELFChunkSize = 1420
+
+	// gelfMaxChunks is the most chunks a message may be split into
+	gelfMaxChunks = 128
+
+	// gelfChunkHeader is the size of a chunk header: the magic bytes, an
+	// 8 byte message ID, the sequence number and the sequence count
+	gelfChunkHeader = 12
+)
+
+// gelfChunkMagic starts every chunk of a chunked GELF message
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// GELFConfig contains GELF (Graylog) output configuration
+type GELFConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// Address is the host:port of the GELF input
+	Address string `yaml:"address"`
+
+	// Transport is udp (the default) or tcp. UDP messages larger than the
+	// chunk size are chunked; TCP messages are null-delimited.
+	Transport string `yaml:"transport,omitempty"`
+
+	// Host is the host of messages whose events have none (the machine's
+	// host name by default)
+	Host string `yaml:"host,omitempty"`
+
+	// ChunkSize is the largest UDP datagram sent, chunk header included
+	ChunkSize int `yaml:"chunk_size,omitempty"`
+}
+
+func init() {
+	Register("gelf", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultGELFConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewGELFOutput(config)
+	})
+}
+
+// DefaultGELFConfig returns default GELF output configuration
+func DefaultGELFConfig() GELFConfig {
+	base := DefaultBaseConfig()
+	base.Timeout = 5 * time.Second
+
+	return GELFConfig{
+		BaseConfig: base,
+		Address:    "localhost:12201",
+		Transport:  GELFTransportUDP,
+		ChunkSize:  DefaultGELFChunkSize,
+	}
+}
+
+// GELFOutput sends events to Graylog as GELF 1.1 messages. Events are
+// mapped with the gelf schema: the message is the short_message, the raw
+// line the full_message, the level a syslog severity and the event fields
+// additional fields prefixed with an underscore. The event source is sent
+// as _source, and the host is the configured one.
+type GELFOutput struct {
+	config GELFConfig
+	mapper *SchemaMapper
+	host   string
+
+	connMu sync.Mutex
+	conn   net.Conn
+
+	metrics *OutputMetrics
+	latency LatencyHistogram
+	mu      sync.Mutex
+	closed  atomic.Bool
+
+	instrumentation
+}
+
+// NewGELFOutput creates a new GELF output
+func NewGELFOutput(config GELFConfig) (*GELFOutput, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("no address specified")
+	}
+
+	switch config.Transport {
+	case "":
+		config.Transport = GELFTransportUDP
+	case GELFTransportUDP, GELFTransportTCP:
+	default:
+		return nil, fmt.Errorf("unsupported gelf transport: %s", config.Transport)
+	}
+
+	switch config.Compression {
+	case "", CompressionNone:
+	case CompressionGzip, CompressionZlib:
+		if config.Transport == GELFTransportTCP {
+			return nil, fmt.Errorf("gelf over tcp does not support compression")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported gelf compression: %s", config.Compression)
+	}
+
+	if config.ChunkSize == 0 {
+		config.ChunkSize = DefaultGELFChunkSize
+	}
+	if config.ChunkSize <= gelfChunkHeader {
+		return nil, fmt.Errorf("gelf chunk_size must be larger than %d: %d", gelfChunkHeader, config.ChunkSize)
+	}
+
+	if config.Schema.Name != "" && config.Schema.Name != SchemaGELF {
+		return nil, fmt.Errorf("gelf output cannot use the %s schema", config.Schema.Name)
+	}
+	rename := map[string]string{"source": "_source"}
+	for field, name := range config.Schema.Rename {
+		rename[field] = name
+	}
+	mapper, err := NewSchemaMapper(SchemaConfig{Name: SchemaGELF, Rename: rename})
+	if err != nil {
+		return nil, err
+	}
+
+	host := config.Host
+	if host == "" {
+		if host, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to resolve hostname: %w", err)
+		}
+	}
+
+	return &GELFOutput{
+		config:  config,
+		mapper:  mapper,
+		host:    host,
+		metrics: &OutputMetrics{},
+	}, nil
+}
+
+// message builds the GELF message of an event. GELF requires a host and a
+// non-empty short_message.
+func (g *GELFOutput) message(event *types.LogEvent) map[string]interface{} {
+	msg := g.mapper.Document(event)
+	if host, _ := msg["host"].(string); host == "" {
+		msg["host"] = g.host
+	}
+	if short, ok := msg["short_message"].(string); ok && short == "" {
+		msg["short_message"] = "-"
+	}
+	return msg
+}
+
+// encode serializes and, over UDP, compresses the GELF message of an event
+func (g *GELFOutput) encode(event *types.LogEvent) ([]byte, error) {
+	data, err := json.Marshal(g.message(event))
+	if err != nil {
+		return nil, classifyf(ErrSerialization, "failed to serialize event: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch g.config.Compression {
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, classifyf(ErrSerialization, "failed to compress message: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, classifyf(ErrSerialization, "failed to compress message: %w", err)
+		}
+	case CompressionZlib:
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, classifyf(ErrSerialization, "failed to compress message: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, classifyf(ErrSerialization, "failed to compress message: %w", err)
+		}
+	default:
+		return data, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// chunks splits a UDP message into datagrams of at most the chunk size
+func (g *GELFOutput) chunks(data []byte) ([][]byte, error) {
+	if len(data) <= g.config.ChunkSize {
+		return [][]byte{data}, nil
+	}
+
+	payload := g.config.ChunkSize - gelfChunkHeader
+	count := (len(data) + payload - 1) / payload
+	if count > gelfMaxChunks {
+		return nil, classifyf(ErrSerialization, "gelf message of %d bytes needs %d chunks, more than %d", len(data), count, gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payload
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunk := make([]byte, 0, gelfChunkHeader+end-i*payload)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*payload:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// Send sends a single event
+func (g *GELFOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	return g.SendBatch(ctx, []*types.LogEvent{event})
+}
+
+// SendBatch sends a batch of events, one message each. Over TCP the batch
+// is written at once.
+func (g *GELFOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	if g.closed.Load() {
+		return fmt.Errorf("gelf output is closed")
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	var packets [][]byte
+	for _, event := range events {
+		data, err := g.encode(event)
+		if err != nil {
+			g.recordFailure(len(events), err)
+			return err
+		}
+
+		if g.config.Transport == GELFTransportTCP {
+			packets = append(packets, append(data, 0))
+			continue
+		}
+		chunks, err := g.chunks(data)
+		if err != nil {
+			g.recordFailure(len(events), err)
+			return err
+		}
+		packets = append(packets, chunks...)
+	}
+	if g.config.Transport == GELFTransportTCP {
+		packets = [][]byte{bytes.Join(packets, nil)}
+	}
+
+	startTime := time.Now()
+	n, err := g.write(ctx, packets)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		g.recordFailure(len(events), err)
+		return err
+	}
+
+	g.observeBatch(g.Name(), "gelf", len(events), int64(n), latency)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.metrics.EventsSent += int64(len(events))
+	g.metrics.BytesSent += int64(n)
+	g.metrics.BatchesSent++
+	g.metrics.AvgBatchSize = float64(g.metrics.EventsSent) / float64(g.metrics.BatchesSent)
+	g.metrics.LastSendTime = time.Now()
+	g.latency.Record(latency)
+	return nil
+}
+
+// write sends packets over the connection, dialing it when needed. The
+// connection is dropped on failure and redialed by the next send.
+func (g *GELFOutput) write(ctx context.Context, packets [][]byte) (int, error) {
+	g.connMu.Lock()
+	defer g.connMu.Unlock()
+
+	if g.conn == nil {
+		dialer := net.Dialer{Timeout: g.config.Timeout}
+		conn, err := dialer.DialContext(ctx, g.config.Transport, g.config.Address)
+		if err != nil {
+			return 0, classifyf(ErrTransient, "failed to connect to %s: %w", g.config.Address, err)
+		}
+		g.conn = conn
+	}
+
+	if g.config.Timeout > 0 {
+		g.conn.SetWriteDeadline(time.Now().Add(g.config.Timeout))
+	}
+
+	written := 0
+	for _, packet := range packets {
+		n, err := g.conn.Write(packet)
+		written += n
+		if err != nil {
+			g.conn.Close()
+			g.conn = nil
+			return written, classifyf(ErrTransient, "failed to send to %s: %w", g.config.Address, err)
+		}
+	}
+	return written, nil
+}
+
+// recordFailure counts failed events and records the error
+func (g *GELFOutput) recordFailure(events int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.metrics.EventsFailed += int64(events)
+	g.metrics.LastError = err.Error()
+	g.metrics.LastErrorTime = time.Now()
+}
+
+// Close closes the connection
+func (g *GELFOutput) Close() error {
+	if !g.closed.CompareAndSwap(false, true) {
+		return nil // Already closed
+	}
+
+	g.connMu.Lock()
+	defer g.connMu.Unlock()
+
+	if g.conn == nil {
+		return nil
+	}
+	err := g.conn.Close()
+	g.conn = nil
+	return err
+}
+
+// HealthCheck dials the GELF input over TCP, or resolves its address for
+// UDP, which has no handshake
+func (g *GELFOutput) HealthCheck(ctx context.Context) error {
+	if g.config.Transport == GELFTransportUDP {
+		if _, err := net.ResolveUDPAddr("udp", g.config.Address); err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", g.config.Address, err)
+		}
+		return nil
+	}
+
+	dialer := net.Dialer{Timeout: g.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", g.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", g.config.Address, err)
+	}
+	return conn.Close()
+}
+
+// Name returns the output name
+func (g *GELFOutput) Name() string {
+	if g.config.Name != "" {
+		return g.config.Name
+	}
+	return "gelf"
+}
+
+// Metrics returns the current metrics
+func (g *GELFOutput) Metrics() *OutputMetrics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Return a copy
+	metricsCopy := *g.metrics
+	g.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
@@ -0,0 +1,293 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func newTestGELFOutput(t *testing.T, configure func(*GELFConfig)) *GELFOutput {
+	t.Helper()
+
+	config := DefaultGELFConfig()
+	config.Host = "aggregator-1"
+	if configure != nil {
+		configure(&config)
+	}
+
+	out, err := NewGELFOutput(config)
+	if err != nil {
+		t.Fatalf("NewGELFOutput() error = %v", err)
+	}
+	t.Cleanup(func() { out.Close() })
+	return out
+}
+
+func TestGELFOutput_Message(t *testing.T) {
+	out := newTestGELFOutput(t, nil)
+
+	msg := out.message(&types.LogEvent{
+		Timestamp: time.Date(2024, 3, 15, 10, 30, 0, 500000000, time.UTC),
+		Message:   "disk almost full",
+		Level:     "WARNING",
+		Source:    "/var/log/app.log",
+		Fields:    map[string]string{"mount": "/data"},
+		Raw:       "WARNING disk almost full",
+	})
+
+	want := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "aggregator-1",
+		"short_message": "disk almost full",
+		"full_message":  "WARNING disk almost full",
+		"timestamp":     1710498600.5,
+		"level":         4,
+		"_source":       "/var/log/app.log",
+		"_mount":        "/data",
+	}
+	for key, value := range want {
+		if msg[key] != value {
+			t.Errorf("%s = %v, want %v", key, msg[key], value)
+		}
+	}
+	if len(msg) != len(want) {
+		t.Errorf("unexpected message %v", msg)
+	}
+
+	// GELF requires a short_message
+	msg = out.message(&types.LogEvent{})
+	if msg["short_message"] != "-" || msg["host"] != "aggregator-1" {
+		t.Errorf("unexpected message for an empty event %v", msg)
+	}
+}
+
+func TestGELFOutput_LevelMapping(t *testing.T) {
+	out := newTestGELFOutput(t, nil)
+
+	tests := map[string]int{
+		"debug":    7,
+		"trace":    7,
+		"info":     6,
+		"notice":   6,
+		"warn":     4,
+		"error":    3,
+		"critical": 2,
+		"fatal":    2,
+		"verbose":  6,
+	}
+	for level, want := range tests {
+		if got := out.message(&types.LogEvent{Message: "m", Level: level})["level"]; got != want {
+			t.Errorf("level %s = %v, want %d", level, got, want)
+		}
+	}
+
+	if _, ok := out.message(&types.LogEvent{Message: "m"})["level"]; ok {
+		t.Error("expected no level for an event without one")
+	}
+}
+
+// listenGELFUDP listens for GELF datagrams on a free local port
+func listenGELFUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestGELFOutput_UDPChunking(t *testing.T) {
+	listener := listenGELFUDP(t)
+	out := newTestGELFOutput(t, func(c *GELFConfig) {
+		c.Address = listener.LocalAddr().String()
+		c.ChunkSize = 100
+	})
+
+	message := strings.Repeat("x", 500)
+	if err := out.Send(context.Background(), &types.LogEvent{Message: message}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var (
+		id      []byte
+		count   int
+		payload = map[int][]byte{}
+	)
+	buf := make([]byte, 1024)
+	for count == 0 || len(payload) < count {
+		n, err := listener.Read(buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		chunk := buf[:n]
+		if n > 100 || !bytes.HasPrefix(chunk, gelfChunkMagic) {
+			t.Fatalf("unexpected chunk of %d bytes: %q", n, chunk)
+		}
+		if id == nil {
+			id = append([]byte(nil), chunk[2:10]...)
+		} else if !bytes.Equal(id, chunk[2:10]) {
+			t.Fatalf("chunks have different message ids")
+		}
+		count = int(chunk[11])
+		payload[int(chunk[10])] = append([]byte(nil), chunk[12:]...)
+	}
+
+	var data []byte
+	for i := 0; i < count; i++ {
+		data = append(data, payload[i]...)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("invalid reassembled message %s: %v", data, err)
+	}
+	if msg["short_message"] != message {
+		t.Errorf("unexpected reassembled message %v", msg)
+	}
+}
+
+func TestGELFOutput_UDPGzip(t *testing.T) {
+	listener := listenGELFUDP(t)
+	out := newTestGELFOutput(t, func(c *GELFConfig) {
+		c.Address = listener.LocalAddr().String()
+		c.Compression = CompressionGzip
+	})
+
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "compressed"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	if err != nil {
+		t.Fatalf("expected a gzip datagram: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	if !bytes.Contains(data, []byte(`"short_message":"compressed"`)) {
+		t.Errorf("unexpected message %s", data)
+	}
+}
+
+func TestGELFOutput_UDPTooManyChunks(t *testing.T) {
+	out := newTestGELFOutput(t, func(c *GELFConfig) {
+		c.ChunkSize = gelfChunkHeader + 1
+	})
+
+	err := out.Send(context.Background(), &types.LogEvent{Message: strings.Repeat("x", 200)})
+	if ErrorClass(err) != ErrSerialization {
+		t.Errorf("expected a serialization error, got %v", err)
+	}
+	if metrics := out.Metrics(); metrics.EventsFailed != 1 {
+		t.Errorf("expected 1 failed event, got %d", metrics.EventsFailed)
+	}
+}
+
+func TestGELFOutput_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	// Each connection's null-delimited messages; the health check's
+	// connection has none
+	received := make(chan []string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var messages []string
+			reader := bufio.NewReader(conn)
+			for len(messages) < 2 {
+				msg, err := reader.ReadString(0)
+				if err != nil {
+					break
+				}
+				messages = append(messages, strings.TrimSuffix(msg, "\x00"))
+			}
+			conn.Close()
+			if len(messages) > 0 {
+				received <- messages
+			}
+		}
+	}()
+
+	out := newTestGELFOutput(t, func(c *GELFConfig) {
+		c.Address = listener.Addr().String()
+		c.Transport = GELFTransportTCP
+	})
+	if err := out.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck() error = %v", err)
+	}
+
+	events := []*types.LogEvent{{Message: "first"}, {Message: "second"}}
+	if err := out.SendBatch(context.Background(), events); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	select {
+	case messages := <-received:
+		if len(messages) != 2 {
+			t.Fatalf("expected 2 null-delimited messages, got %q", messages)
+		}
+		for i, want := range []string{"first", "second"} {
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(messages[i]), &msg); err != nil {
+				t.Fatalf("invalid message %q: %v", messages[i], err)
+			}
+			if msg["short_message"] != want {
+				t.Errorf("message %d = %v, want %s", i, msg["short_message"], want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for messages")
+	}
+
+	if metrics := out.Metrics(); metrics.EventsSent != 2 || metrics.BatchesSent != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestNewGELFOutput_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*GELFConfig)
+	}{
+		{"no address", func(c *GELFConfig) { c.Address = "" }},
+		{"transport", func(c *GELFConfig) { c.Transport = "http" }},
+		{"compression", func(c *GELFConfig) { c.Compression = CompressionZstd }},
+		{"tcp compression", func(c *GELFConfig) {
+			c.Transport = GELFTransportTCP
+			c.Compression = CompressionGzip
+		}},
+		{"chunk size", func(c *GELFConfig) { c.ChunkSize = gelfChunkHeader }},
+		{"schema", func(c *GELFConfig) { c.Schema.Name = SchemaECS }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultGELFConfig()
+			tt.configure(&config)
+			if _, err := NewGELFOutput(config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"context"
+	"sync"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
+)
+
+// healthGroup tracks the last known reachability of a set of outputs so a
+// failing output can be reported as degraded while others are still up
+type healthGroup struct {
+	mu sync.RWMutex
+	up map[string]bool
+}
+
+// HealthCheck adapts an output's HealthCheck method to a health.HealthCheck.
+// A failing output is always reported as unhealthy.
+func HealthCheck(out Output) health.HealthCheck {
+	return newHealthGroup([]Output{out}).check(out)
+}
+
+// RegisterHealthChecks registers a health check for each output with the
+// checker, keyed as "output:<name>". A failing output is reported as
+// degraded while at least one other output is still reachable.
+func RegisterHealthChecks(checker *health.Checker, outputs []Output) {
+	group := newHealthGroup(outputs)
+	for _, out := range outputs {
+		checker.Register("output:"+out.Name(), group.check(out))
+	}
+}
+
+// newHealthGroup creates a group with every output assumed reachable
+func newHealthGroup(outputs []Output) *healthGroup {
+	g := &healthGroup{up: make(map[string]bool, len(outputs))}
+	for _, out := range outputs {
+		g.up[out.Name()] = true
+	}
+	return g
+}
+
+// check returns the health check for a single output in the group
+func (g *healthGroup) check(out Output) health.HealthCheck {
+	name := out.Name()
+	return func(ctx context.Context) health.ComponentHealth {
+		err := out.HealthCheck(ctx)
+
+		g.mu.Lock()
+		g.up[name] = err == nil
+		othersUp := false
+		for other, up := range g.up {
+			if other != name && up {
+				othersUp = true
+				break
+			}
+		}
+		g.mu.Unlock()
+
+		if err == nil {
+			return health.ComponentHealth{
+				Status:  health.StatusHealthy,
+				Message: "reachable",
+			}
+		}
+
+		status := health.StatusUnhealthy
+		if othersUp {
+			status = health.StatusDegraded
+		}
+		return health.ComponentHealth{
+			Status:  status,
+			Message: err.Error(),
+		}
+	}
+}
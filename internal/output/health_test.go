@@ -0,0 +1,152 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/therealutkarshpriyadarshi/log/internal/health"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+var errStubUnreachable = errors.New("unreachable")
+
+// errorTransport fails every request
+type errorTransport struct{}
+
+func (errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errStubUnreachable
+}
+
+// stubKafkaClient fails to resolve the controller
+type stubKafkaClient struct {
+	sarama.Client
+}
+
+func (stubKafkaClient) Controller() (*sarama.Broker, error) { return nil, errStubUnreachable }
+func (stubKafkaClient) Closed() bool                        { return false }
+
+// stubS3Client fails HeadBucket
+type stubS3Client struct {
+	s3API
+}
+
+func (stubS3Client) HeadBucket(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return nil, errStubUnreachable
+}
+
+// stubOutput is an output with a configurable health check result
+type stubOutput struct {
+	name string
+	err  error
+}
+
+func (s *stubOutput) Send(context.Context, *types.LogEvent) error        { return nil }
+func (s *stubOutput) SendBatch(context.Context, []*types.LogEvent) error { return nil }
+func (s *stubOutput) Close() error                                       { return nil }
+func (s *stubOutput) Name() string                                       { return s.name }
+func (s *stubOutput) Metrics() *OutputMetrics                            { return &OutputMetrics{} }
+func (s *stubOutput) HealthCheck(context.Context) error                  { return s.err }
+
+func init() {
+	Register("stub", func(cfg map[string]interface{}) (Output, error) {
+		out := &stubOutput{name: fmt.Sprintf("%v", cfg["name"])}
+		if cfg["unreachable"] == true {
+			out.err = errStubUnreachable
+		}
+		return out, nil
+	})
+}
+
+func TestElasticsearchOutput_HealthCheck(t *testing.T) {
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:  []string{"http://localhost:9200"},
+		Transport:  errorTransport{},
+		MaxRetries: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	out := &ElasticsearchOutput{client: client, metrics: &OutputMetrics{}}
+	if err := out.HealthCheck(context.Background()); !errors.Is(err, errStubUnreachable) {
+		t.Errorf("expected unreachable error, got %v", err)
+	}
+}
+
+func TestKafkaOutput_HealthCheck(t *testing.T) {
+	out := &KafkaOutput{client: stubKafkaClient{}, metrics: &OutputMetrics{}}
+	if err := out.HealthCheck(context.Background()); !errors.Is(err, errStubUnreachable) {
+		t.Errorf("expected unreachable error, got %v", err)
+	}
+
+	closed := &KafkaOutput{metrics: &OutputMetrics{}}
+	if err := closed.HealthCheck(context.Background()); err == nil {
+		t.Error("expected error for output without client")
+	}
+}
+
+func TestS3Output_HealthCheck(t *testing.T) {
+	out := &S3Output{
+		config: S3Config{Bucket: "logs"},
+		client: stubS3Client{},
+	}
+	if err := out.HealthCheck(context.Background()); !errors.Is(err, errStubUnreachable) {
+		t.Errorf("expected unreachable error, got %v", err)
+	}
+}
+
+func TestRegisterHealthChecks(t *testing.T) {
+	down := &stubOutput{name: "es", err: errStubUnreachable}
+	up := &stubOutput{name: "kafka"}
+
+	checker := health.NewChecker(0)
+	RegisterHealthChecks(checker, []Output{down, up})
+
+	ctx := context.Background()
+	results := checker.Check(ctx)
+	if got := results["output:es"].Status; got != health.StatusDegraded {
+		t.Errorf("expected failing output to be degraded, got %s", got)
+	}
+	if got := results["output:kafka"].Status; got != health.StatusHealthy {
+		t.Errorf("expected healthy output, got %s", got)
+	}
+	if got := checker.OverallStatus(ctx); got != health.StatusDegraded {
+		t.Errorf("expected overall degraded, got %s", got)
+	}
+
+	// With every output down, readiness must report unhealthy
+	up.err = errStubUnreachable
+	checker.Check(ctx)
+	results = checker.Check(ctx)
+	for name, result := range results {
+		if result.Status != health.StatusUnhealthy {
+			t.Errorf("expected %s to be unhealthy, got %s", name, result.Status)
+		}
+	}
+}
+
+func TestRouter_HealthCheck(t *testing.T) {
+	router, err := NewRouter(RouterConfig{Outputs: []OutputConfig{
+		{Type: "stub", Name: "a", Config: map[string]interface{}{"unreachable": true}},
+		{Type: "stub", Name: "b"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+
+	if err := router.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected router to be healthy with one output up, got %v", err)
+	}
+
+	router.AddOutput(&stubOutput{name: "c", err: errStubUnreachable})
+	router.outputs[1].(*stubOutput).err = errStubUnreachable
+	if err := router.HealthCheck(context.Background()); err == nil {
+		t.Error("expected error when all outputs are down")
+	}
+}
@@ -0,0 +1,631 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// maxRetryAfter caps how long a Retry-After header can delay a retry
+const maxRetryAfter = time.Minute
+
+// HTTPConfig contains HTTP output configuration
+type HTTPConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// URL is the endpoint events are sent to
+	URL string `yaml:"url"`
+
+	// Method is the HTTP method (POST by default)
+	Method string `yaml:"method,omitempty"`
+
+	// BodyTemplate renders each event with text/template instead of the
+	// serializer, e.g. `{"text": {{json .Message}}}`. The template receives
+	// the *types.LogEvent; the json function encodes a value as JSON.
+	BodyTemplate string `yaml:"body_template,omitempty"`
+
+	// ContentType overrides the request content type. Single events default
+	// to the serializer's and batches of JSON events to application/x-ndjson.
+	ContentType string `yaml:"content_type,omitempty"`
+
+	// HealthCheckURL is requested with GET by HealthCheck. Without it the
+	// output is healthy unless its circuit breaker is open.
+	HealthCheckURL string `yaml:"health_check_url,omitempty"`
+
+	// Headers, authentication, circuit breaker and TLS settings
+	HTTPClientConfig `yaml:",inline"`
+}
+
+// HTTPClientConfig contains the request settings shared by HTTP-based
+// outputs
+type HTTPClientConfig struct {
+	// Headers are added to every request
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// BearerToken is sent in the Authorization header
+	BearerToken string `yaml:"bearer_token,omitempty"`
+
+	// Username and Password enable basic authentication
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// CircuitBreaker stops sending while the endpoint keeps failing
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker,omitempty"`
+
+	// TLS client settings
+	TLSClientConfig `yaml:",inline"`
+}
+
+// BreakerConfig configures the circuit breaker of an HTTP-based output
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed sends, after
+	// retries, that opens the breaker. Zero disables the breaker.
+	FailureThreshold uint32 `yaml:"failure_threshold"`
+
+	// Timeout is how long the breaker stays open before a trial send
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+func init() {
+	Register("http", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultHTTPConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewHTTPOutput(config)
+	})
+}
+
+// DefaultHTTPConfig returns default HTTP output configuration
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		BaseConfig:       DefaultBaseConfig(),
+		Method:           http.MethodPost,
+		HTTPClientConfig: DefaultHTTPClientConfig(),
+	}
+}
+
+// DefaultHTTPClientConfig returns the default request settings, with a
+// circuit breaker opening after five failed sends
+func DefaultHTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		CircuitBreaker: BreakerConfig{
+			FailureThreshold: 5,
+			Timeout:          30 * time.Second,
+		},
+	}
+}
+
+// HTTPOutput sends events to an HTTP endpoint, one event per request or
+// batches of newline-delimited events
+type HTTPOutput struct {
+	config     HTTPConfig
+	sender     *httpSender
+	template   *template.Template
+	serializer Serializer
+	compressor Compressor
+	batcher    *Batcher
+	slots      sendSlots
+	metrics    *OutputMetrics
+	latency    LatencyHistogram
+	mu         sync.RWMutex
+	closed     atomic.Bool
+
+	instrumentation
+	flushErrorHook
+}
+
+// NewHTTPOutput creates a new HTTP output
+func NewHTTPOutput(config HTTPConfig) (*HTTPOutput, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("no url specified")
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+
+	tmpl, err := parseBodyTemplate(config.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer, err := NewSerializer(config.BaseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := GetCompressor(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := newHTTPSender(config.Name, config.Method, config.BaseConfig, config.HTTPClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &HTTPOutput{
+		config:     config,
+		sender:     sender,
+		template:   tmpl,
+		serializer: serializer,
+		compressor: compressor,
+		metrics:    &OutputMetrics{},
+		slots:      newSendSlots(config.MaxConcurrentBatches),
+	}
+
+	// Create batcher
+	if config.BatchSize > 1 {
+		output.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  config.BatchSize,
+			MaxBatchBytes: 10 * 1024 * 1024, // 10MB
+			FlushInterval: config.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				output.observeFlush(output.Name(), "http", trigger)
+			},
+			Adaptive: config.AdaptiveBatch,
+			Ordered:  config.Ordered,
+			Metrics:  output.Metrics,
+			OnResize: func(size int) {
+				output.observeBatchSize(output.Name(), "http", size)
+			},
+			OnFlushError: output.flushFailed,
+		}, output.sendBatchInternal)
+	}
+
+	return output, nil
+}
+
+// parseBodyTemplate parses the body template, returning nil if unset
+func parseBodyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("body").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body_template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Send sends a single event
+func (h *HTTPOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	if h.closed.Load() {
+		return fmt.Errorf("http output is closed")
+	}
+
+	// Use batcher if configured
+	if h.batcher != nil {
+		return h.batcher.Add(ctx, event)
+	}
+
+	return h.sendSingle(ctx, event)
+}
+
+// SendBatch sends a batch of events in a single request
+func (h *HTTPOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	if h.closed.Load() {
+		return fmt.Errorf("http output is closed")
+	}
+
+	return h.sendBatchInternal(ctx, events)
+}
+
+// sendSingle sends an event as the body of its own request
+func (h *HTTPOutput) sendSingle(ctx context.Context, event *types.LogEvent) error {
+	data, err := h.encode(event)
+	if err != nil {
+		h.recordFailure(1, err)
+		return err
+	}
+
+	contentType := h.config.ContentType
+	if contentType == "" {
+		contentType = h.serializer.ContentType()
+	}
+
+	startTime := time.Now()
+	size, err := h.post(ctx, contentType, data)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		h.recordFailure(1, err)
+		return err
+	}
+
+	// Update metrics
+	atomic.AddInt64(&h.metrics.EventsSent, 1)
+	atomic.AddInt64(&h.metrics.BytesSent, int64(size))
+	h.observeSend(h.Name(), "http", int64(size), latency)
+
+	h.mu.Lock()
+	h.metrics.LastSendTime = time.Now()
+	h.latency.Record(latency)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// sendBatchInternal sends a batch of events in a single request, one
+// event per line for JSON and templated bodies
+func (h *HTTPOutput) sendBatchInternal(ctx context.Context, events []*types.LogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	done, err := h.slots.begin(ctx, &h.instrumentation, h.Name(), "http")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	startTime := time.Now()
+
+	separator := recordSeparator(h.serializer)
+	if h.template != nil {
+		separator = []byte{'\n'}
+	}
+
+	var buf bytes.Buffer
+	var encoded int
+	for _, event := range events {
+		data, err := h.encode(event)
+		if err != nil {
+			h.recordFailure(1, err)
+			continue
+		}
+		buf.Write(data)
+		buf.Write(separator)
+		encoded++
+	}
+	if encoded == 0 {
+		return classifyf(ErrSerialization, "failed to encode any of %d events", len(events))
+	}
+
+	contentType := h.config.ContentType
+	if contentType == "" {
+		contentType = h.serializer.ContentType()
+		if len(separator) > 0 {
+			contentType = "application/x-ndjson"
+		}
+	}
+
+	size, err := h.post(ctx, contentType, buf.Bytes())
+	latency := time.Since(startTime)
+
+	if err != nil {
+		h.recordFailure(encoded, err)
+		return err
+	}
+
+	// Update metrics
+	atomic.AddInt64(&h.metrics.EventsSent, int64(encoded))
+	atomic.AddInt64(&h.metrics.BytesSent, int64(size))
+	atomic.AddInt64(&h.metrics.BatchesSent, 1)
+	h.observeBatch(h.Name(), "http", encoded, int64(size), latency)
+
+	// Update average batch size and record latency
+	h.mu.Lock()
+	h.metrics.LastSendTime = time.Now()
+	if h.metrics.BatchesSent > 0 {
+		h.metrics.AvgBatchSize = float64(h.metrics.EventsSent) / float64(h.metrics.BatchesSent)
+	}
+	h.latency.Record(latency)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// encode renders an event with the body template or the serializer
+func (h *HTTPOutput) encode(event *types.LogEvent) ([]byte, error) {
+	if h.template == nil {
+		data, err := h.serializer.Serialize(event)
+		if err != nil {
+			return nil, classifyf(ErrSerialization, "failed to serialize event: %w", err)
+		}
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := h.template.Execute(&buf, event); err != nil {
+		return nil, classifyf(ErrSerialization, "failed to render body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post compresses and sends a request body, returning the bytes sent
+func (h *HTTPOutput) post(ctx context.Context, contentType string, data []byte) (int, error) {
+	compressed, err := h.compressor.Compress(data)
+	if err != nil {
+		return 0, classifyf(ErrSerialization, "failed to compress data: %w", err)
+	}
+
+	if err := h.sender.send(ctx, h.config.URL, contentType, h.compressor.ContentEncoding(), compressed); err != nil {
+		return 0, err
+	}
+	return len(compressed), nil
+}
+
+// recordFailure counts failed events and records the error
+func (h *HTTPOutput) recordFailure(events int, err error) {
+	atomic.AddInt64(&h.metrics.EventsFailed, int64(events))
+
+	h.mu.Lock()
+	h.metrics.LastError = err.Error()
+	h.metrics.LastErrorTime = time.Now()
+	h.mu.Unlock()
+}
+
+// Flush sends any events buffered in the batcher
+func (h *HTTPOutput) Flush(ctx context.Context) error {
+	if h.batcher == nil {
+		return nil
+	}
+	return h.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (h *HTTPOutput) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if h.batcher == nil {
+		return false
+	}
+	h.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
+// Close closes the HTTP output
+func (h *HTTPOutput) Close() error {
+	if !h.closed.CompareAndSwap(false, true) {
+		return nil // Already closed
+	}
+
+	// Stop batcher first
+	if h.batcher != nil {
+		if err := h.batcher.Stop(); err != nil {
+			return err
+		}
+	}
+
+	h.sender.client.CloseIdleConnections()
+	return nil
+}
+
+// HealthCheck requests the health check URL, or reports whether the
+// circuit breaker is open when none is configured
+func (h *HTTPOutput) HealthCheck(ctx context.Context) error {
+	return h.sender.healthCheck(ctx, h.config.HealthCheckURL)
+}
+
+// CircuitBreaker returns the output's circuit breaker, or nil when disabled
+func (h *HTTPOutput) CircuitBreaker() *reliability.CircuitBreaker {
+	return h.sender.breaker
+}
+
+// Name returns the output name
+func (h *HTTPOutput) Name() string {
+	if h.config.Name != "" {
+		return h.config.Name
+	}
+	return "http"
+}
+
+// Metrics returns the current metrics
+func (h *HTTPOutput) Metrics() *OutputMetrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// Return a copy
+	metricsCopy := *h.metrics
+	metricsCopy.RetryCount = h.sender.retries.Load()
+	h.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
+
+// httpStatusError is an unsuccessful HTTP response
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("endpoint returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("endpoint returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// httpSender sends request bodies with an output's authentication and
+// headers, retrying failures with backoff behind a circuit breaker. It is
+// shared by the HTTP-based outputs.
+type httpSender struct {
+	client      *http.Client
+	method      string
+	headers     map[string]string
+	bearerToken string
+	username    string
+	password    string
+	retrier     *reliability.Retrier
+	breaker     *reliability.CircuitBreaker // nil when disabled
+	retries     atomic.Int64
+}
+
+// newHTTPSender creates a sender using the retry settings and timeout of
+// base and the request settings of client
+func newHTTPSender(name, method string, base BaseConfig, client HTTPClientConfig) (*httpSender, error) {
+	tlsConfig, err := client.buildTLSConfig(false)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	s := &httpSender{
+		client:      &http.Client{Transport: transport, Timeout: base.Timeout},
+		method:      method,
+		headers:     client.Headers,
+		bearerToken: client.BearerToken,
+		username:    client.Username,
+		password:    client.Password,
+		retrier: reliability.NewRetrier(reliability.RetryConfig{
+			MaxRetries:     base.MaxRetries,
+			InitialBackoff: base.RetryBackoff,
+			Jitter:         true,
+		}),
+	}
+
+	if client.CircuitBreaker.FailureThreshold > 0 {
+		threshold := client.CircuitBreaker.FailureThreshold
+		s.breaker = reliability.NewCircuitBreaker(reliability.CircuitBreakerConfig{
+			Name:    name,
+			Timeout: client.CircuitBreaker.Timeout,
+			ReadyToTrip: func(counts reliability.Counts) bool {
+				return counts.ConsecutiveFailures >= threshold
+			},
+			// Rejected requests mean the endpoint is up
+			IsSuccessful: func(err error) bool {
+				var statusErr *httpStatusError
+				return err == nil || errors.As(err, &statusErr) && !IsRetryable(err)
+			},
+		})
+	}
+
+	return s, nil
+}
+
+// send sends a request body to url, retrying transient failures. Errors
+// that are still failing after the retries, or rejected by the circuit
+// breaker, are ErrTransient.
+func (s *httpSender) send(ctx context.Context, url, contentType, contentEncoding string, body []byte) error {
+	attempts := 0
+	attempt := func() error {
+		if attempts > 0 {
+			s.retries.Add(1)
+		}
+		attempts++
+		return s.do(ctx, url, contentType, contentEncoding, body)
+	}
+
+	retried := func() error {
+		return s.retrier.Do(ctx, attempt)
+	}
+	if s.breaker == nil {
+		return classify(ErrTransient, retried())
+	}
+	return classify(ErrTransient, s.breaker.Execute(ctx, retried))
+}
+
+// do sends a single request
+func (s *httpSender) do(ctx context.Context, url, contentType, contentEncoding string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, s.method, url, bytes.NewReader(body))
+	if err != nil {
+		return reliability.Permanent(classifyf(ErrPermanent, "failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	s.authorize(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return classifyf(ErrTransient, "failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	statusErr := statusError(resp.StatusCode, &httpStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(message))})
+	if !IsRetryable(statusErr) {
+		return reliability.Permanent(statusErr)
+	}
+
+	// Honor the server's requested delay before the retry backoff
+	if delay := retryAfter(resp.Header.Get("Retry-After")); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+	return statusErr
+}
+
+// authorize adds the configured headers and credentials to a request
+func (s *httpSender) authorize(req *http.Request) {
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	if s.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+	} else if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+}
+
+// healthCheck requests url with GET, or reports whether the circuit
+// breaker is open when url is empty
+func (s *httpSender) healthCheck(ctx context.Context, url string) error {
+	if url == "" {
+		if s.breaker != nil && s.breaker.State() == reliability.StateOpen {
+			return reliability.ErrCircuitOpen
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	s.authorize(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &httpStatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date, capped at maxRetryAfter
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		delay = time.Until(t)
+	}
+	return min(max(delay, 0), maxRetryAfter)
+}
@@ -0,0 +1,279 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordedRequest is a request received by a test endpoint
+type recordedRequest struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// testEndpoint records requests and answers with scripted status codes,
+// then 200 once the script runs out
+type testEndpoint struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+	statuses []int
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	e.requests = append(e.requests, recordedRequest{method: r.Method, header: r.Header.Clone(), body: string(body)})
+	status := http.StatusOK
+	if len(e.statuses) > 0 {
+		status, e.statuses = e.statuses[0], e.statuses[1:]
+	}
+	e.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (e *testEndpoint) recorded() []recordedRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]recordedRequest(nil), e.requests...)
+}
+
+func newTestHTTPOutput(t *testing.T, endpoint *testEndpoint, configure func(*HTTPConfig)) *HTTPOutput {
+	t.Helper()
+
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	config := DefaultHTTPConfig()
+	config.URL = server.URL
+	config.BatchSize = 1
+	config.RetryBackoff = time.Millisecond
+	if configure != nil {
+		configure(&config)
+	}
+
+	out, err := NewHTTPOutput(config)
+	if err != nil {
+		t.Fatalf("NewHTTPOutput() error = %v", err)
+	}
+	t.Cleanup(func() { out.Close() })
+	return out
+}
+
+func TestNewHTTPOutput_Validation(t *testing.T) {
+	if _, err := NewHTTPOutput(DefaultHTTPConfig()); err == nil {
+		t.Error("expected an error without a url")
+	}
+
+	config := DefaultHTTPConfig()
+	config.URL = "http://localhost"
+	config.BodyTemplate = "{{.Message"
+	if _, err := NewHTTPOutput(config); err == nil {
+		t.Error("expected an error for an invalid body template")
+	}
+}
+
+func TestHTTPOutput_Send(t *testing.T) {
+	endpoint := &testEndpoint{}
+	out := newTestHTTPOutput(t, endpoint, func(c *HTTPConfig) {
+		c.Headers = map[string]string{"X-Scope-OrgID": "tenant-1"}
+		c.BearerToken = "secret"
+	})
+
+	event := &types.LogEvent{Message: "hello", Level: "info", Source: "app"}
+	if err := out.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	requests := endpoint.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	req := requests[0]
+	if req.method != http.MethodPost {
+		t.Errorf("expected POST, got %s", req.method)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("expected bearer auth, got %q", got)
+	}
+	if got := req.header.Get("X-Scope-OrgID"); got != "tenant-1" {
+		t.Errorf("expected the configured header, got %q", got)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected application/json, got %q", got)
+	}
+	if !strings.Contains(req.body, `"message":"hello"`) || strings.Contains(req.body, "\n") {
+		t.Errorf("expected a single JSON event, got %q", req.body)
+	}
+
+	metrics := out.Metrics()
+	if metrics.EventsSent != 1 || metrics.BytesSent != int64(len(req.body)) {
+		t.Errorf("expected 1 event and %d bytes sent, got %d and %d", len(req.body), metrics.EventsSent, metrics.BytesSent)
+	}
+}
+
+func TestHTTPOutput_SendBatchTemplate(t *testing.T) {
+	endpoint := &testEndpoint{}
+	out := newTestHTTPOutput(t, endpoint, func(c *HTTPConfig) {
+		c.BodyTemplate = `{"text":{{json .Message}},"level":"{{.Level}}","host":"{{.Fields.host}}"}`
+		c.Username = "user"
+		c.Password = "pass"
+	})
+
+	events := []*types.LogEvent{
+		{Message: `say "hi"`, Level: "info", Fields: map[string]string{"host": "a"}},
+		{Message: "bye", Level: "warn"},
+	}
+	if err := out.SendBatch(context.Background(), events); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	requests := endpoint.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	want := `{"text":"say \"hi\"","level":"info","host":"a"}` + "\n" +
+		`{"text":"bye","level":"warn","host":""}` + "\n"
+	if requests[0].body != want {
+		t.Errorf("unexpected body:\n%s\nwant:\n%s", requests[0].body, want)
+	}
+	if got := requests[0].header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %q", got)
+	}
+
+	req := &http.Request{Header: requests[0].header}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("expected basic auth user:pass, got %q:%q", user, pass)
+	}
+
+	metrics := out.Metrics()
+	if metrics.BatchesSent != 1 || metrics.EventsSent != 2 {
+		t.Errorf("expected 1 batch of 2 events, got %d batches and %d events", metrics.BatchesSent, metrics.EventsSent)
+	}
+}
+
+func TestHTTPOutput_RetriesServerErrors(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	out := newTestHTTPOutput(t, endpoint, nil)
+
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "retry me"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	requests := endpoint.recorded()
+	if len(requests) != 3 {
+		t.Fatalf("expected 2 retries, got %d requests", len(requests))
+	}
+	for _, req := range requests {
+		if req.body != requests[0].body {
+			t.Errorf("expected retries to resend the same body, got %q", req.body)
+		}
+	}
+	if metrics := out.Metrics(); metrics.RetryCount != 2 || metrics.EventsSent != 1 {
+		t.Errorf("expected 2 retries and 1 event sent, got %d and %d", metrics.RetryCount, metrics.EventsSent)
+	}
+}
+
+func TestHTTPOutput_RetryLimit(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{500, 500, 500, 500, 500}}
+	out := newTestHTTPOutput(t, endpoint, func(c *HTTPConfig) {
+		c.MaxRetries = 2
+	})
+
+	err := out.Send(context.Background(), &types.LogEvent{Message: "lost"})
+	if !errors.Is(err, reliability.ErrMaxRetriesExceeded) || !errors.Is(err, ErrTransient) {
+		t.Fatalf("expected retries to be exhausted with a transient error, got %v", err)
+	}
+	if len(endpoint.recorded()) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(endpoint.recorded()))
+	}
+	if metrics := out.Metrics(); metrics.EventsFailed != 1 || metrics.LastError == "" {
+		t.Errorf("expected 1 failed event with its error, got %d and %q", metrics.EventsFailed, metrics.LastError)
+	}
+}
+
+func TestHTTPOutput_ClientErrorsNotRetried(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusBadRequest}}
+	out := newTestHTTPOutput(t, endpoint, nil)
+
+	err := out.Send(context.Background(), &types.LogEvent{Message: "bad"})
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a 400 error, got %v", err)
+	}
+	if !errors.Is(err, ErrPermanent) || IsRetryable(err) {
+		t.Errorf("expected a permanent error, got %v", err)
+	}
+	if len(endpoint.recorded()) != 1 {
+		t.Errorf("expected no retries, got %d requests", len(endpoint.recorded()))
+	}
+}
+
+func TestHTTPOutput_CircuitBreaker(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{500, 500, 500, 500}}
+	out := newTestHTTPOutput(t, endpoint, func(c *HTTPConfig) {
+		c.MaxRetries = 1
+		c.CircuitBreaker = BreakerConfig{FailureThreshold: 2, Timeout: time.Hour}
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := out.Send(context.Background(), &types.LogEvent{Message: "fail"}); err == nil {
+			t.Fatal("expected the send to fail")
+		}
+	}
+	if err := out.HealthCheck(context.Background()); !errors.Is(err, reliability.ErrCircuitOpen) {
+		t.Errorf("expected the health check to report the open breaker, got %v", err)
+	}
+
+	// The open breaker rejects sends without reaching the endpoint
+	err := out.Send(context.Background(), &types.LogEvent{Message: "rejected"})
+	if !errors.Is(err, reliability.ErrCircuitOpen) {
+		t.Errorf("expected the breaker to be open, got %v", err)
+	}
+	if len(endpoint.recorded()) != 4 {
+		t.Errorf("expected 4 requests before the breaker opened, got %d", len(endpoint.recorded()))
+	}
+}
+
+func TestHTTPOutput_HealthCheckURL(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusServiceUnavailable}}
+	out := newTestHTTPOutput(t, endpoint, nil)
+	out.config.HealthCheckURL = out.config.URL + "/ready"
+
+	if err := out.HealthCheck(context.Background()); err == nil {
+		t.Error("expected an unhealthy endpoint to fail the health check")
+	}
+	if err := out.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected a healthy endpoint, got %v", err)
+	}
+	if req := endpoint.recorded()[0]; req.method != http.MethodGet {
+		t.Errorf("expected a GET health check, got %s", req.method)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter("2"); got != 2*time.Second {
+		t.Errorf("expected 2s, got %v", got)
+	}
+	if got := retryAfter("3600"); got != maxRetryAfter {
+		t.Errorf("expected the delay to be capped, got %v", got)
+	}
+	if got := retryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)); got != 0 {
+		t.Errorf("expected a past date to give no delay, got %v", got)
+	}
+	if got := retryAfter("soon"); got != 0 {
+		t.Errorf("expected an invalid value to give no delay, got %v", got)
+	}
+}
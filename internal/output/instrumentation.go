@@ -0,0 +1,73 @@
+package output
+
+import (
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+)
+
+// Instrumented is implemented by outputs that record Prometheus metrics
+type Instrumented interface {
+	// SetCollector sets the collector the output records metrics to
+	SetCollector(collector *metrics.Collector)
+}
+
+// instrumentation records an output's sends to the Prometheus collector,
+// defaulting to the global collector
+type instrumentation struct {
+	collectorMu sync.RWMutex
+	collector   *metrics.Collector
+}
+
+// SetCollector sets the collector the output records metrics to
+func (i *instrumentation) SetCollector(collector *metrics.Collector) {
+	i.collectorMu.Lock()
+	defer i.collectorMu.Unlock()
+	i.collector = collector
+}
+
+// metricsCollector returns the configured or global collector
+func (i *instrumentation) metricsCollector() *metrics.Collector {
+	i.collectorMu.RLock()
+	collector := i.collector
+	i.collectorMu.RUnlock()
+
+	if collector == nil {
+		return metrics.GetGlobalCollector()
+	}
+	return collector
+}
+
+// observeSend records the duration and bytes of a single send
+func (i *instrumentation) observeSend(name, outputType string, bytes int64, duration time.Duration) {
+	collector := i.metricsCollector()
+	collector.OutputDuration.WithLabelValues(name, outputType).Observe(duration.Seconds())
+	collector.OutputBytesSent.WithLabelValues(name, outputType).Add(float64(bytes))
+}
+
+// observeBatch records the size, duration and bytes of a batch send
+func (i *instrumentation) observeBatch(name, outputType string, size int, bytes int64, duration time.Duration) {
+	i.observeSend(name, outputType, bytes, duration)
+	i.metricsCollector().OutputBatchSize.WithLabelValues(name, outputType).Observe(float64(size))
+}
+
+// observeFlush records a batcher flush and its trigger
+func (i *instrumentation) observeFlush(name, outputType string, trigger FlushTrigger) {
+	i.metricsCollector().OutputBatchFlushes.WithLabelValues(name, outputType, string(trigger)).Inc()
+}
+
+// observeBatchSize records the batch size chosen by adaptive batching
+func (i *instrumentation) observeBatchSize(name, outputType string, size int) {
+	i.metricsCollector().OutputAdaptiveSize.WithLabelValues(name, outputType).Set(float64(size))
+}
+
+// observeFailed records events that failed for a reason
+func (i *instrumentation) observeFailed(name, outputType, reason string, events int) {
+	i.metricsCollector().OutputEventsFailed.WithLabelValues(name, outputType, reason).Add(float64(events))
+}
+
+// observeInflight adds delta to the output's batches in flight
+func (i *instrumentation) observeInflight(name, outputType string, delta float64) {
+	i.metricsCollector().OutputInflightBatches.WithLabelValues(name, outputType).Add(delta)
+}
@@ -0,0 +1,176 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// findMetric returns the metric of family name with the given output labels
+func findMetric(t *testing.T, collector *metrics.Collector, name, outputName, outputType string) *dto.Metric {
+	t.Helper()
+
+	families, err := collector.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["output_name"] == outputName && labels["output_type"] == outputType {
+				return metric
+			}
+		}
+	}
+
+	t.Fatalf("metric %s{output_name=%q,output_type=%q} not found", name, outputName, outputType)
+	return nil
+}
+
+func testBatch(n int) []*types.LogEvent {
+	events := make([]*types.LogEvent, n)
+	for i := range events {
+		events[i] = &types.LogEvent{Message: "hello", Raw: "hello world"}
+	}
+	return events
+}
+
+func TestKafkaOutput_BatchMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	config := DefaultKafkaConfig()
+	config.Name = "kafka-logs"
+	config.Topic = "logs"
+	out, producer := newTestKafkaOutput(t, config, &JSONSerializer{})
+	for i := 0; i < 3; i++ {
+		producer.ExpectInputAndSucceed()
+	}
+	out.SetCollector(collector)
+
+	if err := out.sendBatchInternal(context.Background(), testBatch(3)); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	batchSize := findMetric(t, collector, "logaggregator_output_batch_size", "kafka-logs", "kafka").GetHistogram()
+	if batchSize.GetSampleCount() != 1 || batchSize.GetSampleSum() != 3 {
+		t.Errorf("batch size histogram count = %d, sum = %v, want 1 and 3", batchSize.GetSampleCount(), batchSize.GetSampleSum())
+	}
+
+	duration := findMetric(t, collector, "logaggregator_output_duration_seconds", "kafka-logs", "kafka").GetHistogram()
+	if duration.GetSampleCount() != 1 {
+		t.Errorf("expected 1 duration observation, got %d", duration.GetSampleCount())
+	}
+
+	bytesSent := findMetric(t, collector, "logaggregator_output_bytes_sent_total", "kafka-logs", "kafka").GetCounter()
+	if bytesSent.GetValue() != 3*float64(len("hello world")) {
+		t.Errorf("bytes sent = %v, want %d", bytesSent.GetValue(), 3*len("hello world"))
+	}
+}
+
+func TestElasticsearchOutput_BatchMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	out, _ := newTestElasticsearchOutput(t, ElasticsearchConfig{Index: "logs"})
+	out.SetCollector(collector)
+
+	if err := out.sendBatchInternal(context.Background(), testBatch(2)); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+	if err := out.sendBatchInternal(context.Background(), testBatch(4)); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	batchSize := findMetric(t, collector, "logaggregator_output_batch_size", "elasticsearch", "elasticsearch").GetHistogram()
+	if batchSize.GetSampleCount() != 2 || batchSize.GetSampleSum() != 6 {
+		t.Errorf("batch size histogram count = %d, sum = %v, want 2 and 6", batchSize.GetSampleCount(), batchSize.GetSampleSum())
+	}
+
+	duration := findMetric(t, collector, "logaggregator_output_duration_seconds", "elasticsearch", "elasticsearch").GetHistogram()
+	if duration.GetSampleCount() != 2 {
+		t.Errorf("expected 2 duration observations, got %d", duration.GetSampleCount())
+	}
+
+	if bytesSent := findMetric(t, collector, "logaggregator_output_bytes_sent_total", "elasticsearch", "elasticsearch").GetCounter(); bytesSent.GetValue() == 0 {
+		t.Error("expected bytes sent to be recorded")
+	}
+}
+
+func TestInstrumentation_ObserveFlush(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	var i instrumentation
+	i.SetCollector(collector)
+
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  2,
+		MaxBatchBytes: 10000,
+		FlushInterval: time.Hour,
+		OnFlush: func(trigger FlushTrigger) {
+			i.observeFlush("kafka-logs", "kafka", trigger)
+		},
+	}, func(ctx context.Context, events []*types.LogEvent) error { return nil })
+	defer batcher.Stop()
+
+	for _, event := range testBatch(4) {
+		if err := batcher.Add(context.Background(), event); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	flushes := findMetric(t, collector, "logaggregator_output_batch_flushes_total", "kafka-logs", "kafka")
+	if flushes.GetCounter().GetValue() != 2 {
+		t.Errorf("expected 2 flushes, got %v", flushes.GetCounter().GetValue())
+	}
+	for _, label := range flushes.GetLabel() {
+		if label.GetName() == "trigger" && label.GetValue() != string(FlushByCount) {
+			t.Errorf("expected trigger %s, got %s", FlushByCount, label.GetValue())
+		}
+	}
+}
+
+func TestInstrumentation_ObserveBatchSize(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	var i instrumentation
+	i.SetCollector(collector)
+
+	batcher := NewBatcher(BatcherConfig{
+		MaxBatchSize:  8,
+		FlushInterval: time.Hour,
+		Adaptive:      AdaptiveBatchConfig{Enabled: true, MaxBatchSize: 20},
+		Metrics:       func() *OutputMetrics { return &OutputMetrics{AvgLatency: time.Second} },
+		OnResize: func(size int) {
+			i.observeBatchSize("es-logs", "elasticsearch", size)
+		},
+	}, func(ctx context.Context, events []*types.LogEvent) error { return nil })
+	defer batcher.Stop()
+
+	size := findMetric(t, collector, "logaggregator_output_adaptive_batch_size", "es-logs", "elasticsearch")
+	if size.GetGauge().GetValue() != 8 {
+		t.Errorf("expected batch size 8, got %v", size.GetGauge().GetValue())
+	}
+
+	// A slow output halves the batch size
+	for _, event := range testBatch(8) {
+		if err := batcher.Add(context.Background(), event); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	size = findMetric(t, collector, "logaggregator_output_adaptive_batch_size", "es-logs", "elasticsearch")
+	if size.GetGauge().GetValue() != 4 {
+		t.Errorf("expected batch size 4, got %v", size.GetGauge().GetValue())
+	}
+}
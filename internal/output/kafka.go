@@ -2,8 +2,9 @@ package output
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,12 +41,31 @@ type KafkaConfig struct {
 	// MaxMessageBytes is the maximum size of a single message
 	MaxMessageBytes int `yaml:"max_message_bytes,omitempty"`
 
+	// Headers lists event fields promoted to message headers, e.g. level,
+	// source or input_type. Missing or empty fields are skipped.
+	Headers []string `yaml:"headers,omitempty"`
+
+	// StaticHeaders are added to every message
+	StaticHeaders map[string]string `yaml:"static_headers,omitempty"`
+
 	// IdempotentWrites enables idempotent producer for exactly-once semantics
 	IdempotentWrites bool `yaml:"idempotent_writes,omitempty"`
 
-	// EnableTLS enables TLS for connections
+	// OrderedByKey guarantees that messages with the same partition key are
+	// written in the order they were sent, including across retries. Each
+	// broker connection is limited to one in-flight request and every
+	// write waits for all in-sync replicas, which lowers throughput,
+	// especially with high broker latency. Requires IdempotentWrites, a
+	// PartitionKey and the hash partition strategy.
+	OrderedByKey bool `yaml:"ordered_by_key,omitempty"`
+
+	// EnableTLS enables TLS for connections. Setting any TLS option below
+	// enables it as well.
 	EnableTLS bool `yaml:"enable_tls,omitempty"`
 
+	// TLS client settings
+	TLSClientConfig `yaml:",inline"`
+
 	// SASL configuration
 	SASLEnabled   bool   `yaml:"sasl_enabled,omitempty"`
 	SASLMechanism string `yaml:"sasl_mechanism,omitempty"` // PLAIN, SCRAM-SHA-256, SCRAM-SHA-512
@@ -59,6 +79,16 @@ type KafkaConfig struct {
 	Version string `yaml:"version,omitempty"`
 }
 
+func init() {
+	Register("kafka", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultKafkaConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewKafkaOutput(config)
+	})
+}
+
 // DefaultKafkaConfig returns default Kafka configuration
 func DefaultKafkaConfig() KafkaConfig {
 	return KafkaConfig{
@@ -77,12 +107,20 @@ func DefaultKafkaConfig() KafkaConfig {
 
 // KafkaOutput sends events to Kafka
 type KafkaOutput struct {
-	config   KafkaConfig
-	producer sarama.SyncProducer
-	batcher  *Batcher
-	metrics  *OutputMetrics
-	mu       sync.RWMutex
-	closed   atomic.Bool
+	config     KafkaConfig
+	client     sarama.Client
+	producer   sarama.AsyncProducer
+	dispatched chan struct{} // closed once the producer's results are drained
+	batcher    *Batcher
+	serializer Serializer
+	slots      sendSlots
+	metrics    *OutputMetrics
+	latency    LatencyHistogram
+	mu         sync.RWMutex
+	closed     atomic.Bool
+
+	instrumentation
+	flushErrorHook
 }
 
 // NewKafkaOutput creates a new Kafka output
@@ -95,7 +133,66 @@ func NewKafkaOutput(config KafkaConfig) (*KafkaOutput, error) {
 		return nil, fmt.Errorf("no topic specified")
 	}
 
-	// Create Sarama config
+	serializer, err := NewSerializer(config.BaseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	saramaConfig, err := newSaramaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create client and producer
+	client, err := sarama.NewClient(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+
+	producer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	output := &KafkaOutput{
+		config:     config,
+		client:     client,
+		producer:   producer,
+		serializer: serializer,
+		metrics:    &OutputMetrics{},
+		slots:      newSendSlots(config.MaxConcurrentBatches),
+	}
+	output.startDispatch()
+
+	// Create batcher if batch size > 1
+	if config.BatchSize > 1 {
+		output.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  config.BatchSize,
+			MaxBatchBytes: config.MaxMessageBytes, // keep batches within the broker's limit
+			FlushInterval: config.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				output.observeFlush(output.Name(), "kafka", trigger)
+			},
+			Adaptive: config.AdaptiveBatch,
+			Ordered:  config.Ordered,
+			Metrics:  output.Metrics,
+			OnResize: func(size int) {
+				output.observeBatchSize(output.Name(), "kafka", size)
+			},
+			OnFlushError: output.flushFailed,
+		}, output.sendBatchInternal)
+	}
+
+	return output, nil
+}
+
+// newSaramaConfig builds the Sarama client configuration for a Kafka output
+func newSaramaConfig(config KafkaConfig) (*sarama.Config, error) {
+	if err := validateOrdering(config); err != nil {
+		return nil, err
+	}
+
 	saramaConfig := sarama.NewConfig()
 	saramaConfig.Producer.Return.Successes = true
 	saramaConfig.Producer.Return.Errors = true
@@ -129,6 +226,15 @@ func NewKafkaOutput(config KafkaConfig) (*KafkaOutput, error) {
 		saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
 	}
 
+	// A single in-flight request per broker keeps retried batches from
+	// overtaking later ones; idempotence keeps retries from duplicating
+	if config.OrderedByKey || config.Ordered {
+		saramaConfig.Net.MaxOpenRequests = 1
+	}
+	if config.OrderedByKey {
+		saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	}
+
 	// Set max message bytes
 	if config.MaxMessageBytes > 0 {
 		saramaConfig.Producer.MaxMessageBytes = config.MaxMessageBytes
@@ -152,40 +258,43 @@ func NewKafkaOutput(config KafkaConfig) (*KafkaOutput, error) {
 		switch config.SASLMechanism {
 		case "SCRAM-SHA-256":
 			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMSHA256Client
 		case "SCRAM-SHA-512":
 			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMSHA512Client
 		default:
 			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
 		}
 	}
 
 	// Enable TLS if configured
-	if config.EnableTLS {
+	tlsConfig, err := config.buildTLSConfig(config.EnableTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
 		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
 	}
 
-	// Create producer
-	producer, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
-	}
+	return saramaConfig, nil
+}
 
-	output := &KafkaOutput{
-		config:   config,
-		producer: producer,
-		metrics:  &OutputMetrics{},
+// validateOrdering checks the settings OrderedByKey depends on
+func validateOrdering(config KafkaConfig) error {
+	if !config.OrderedByKey {
+		return nil
 	}
-
-	// Create batcher if batch size > 1
-	if config.BatchSize > 1 {
-		output.batcher = NewBatcher(BatcherConfig{
-			MaxBatchSize:  config.BatchSize,
-			MaxBatchBytes: config.MaxMessageBytes * config.BatchSize,
-			FlushInterval: config.FlushInterval,
-		}, output.sendBatchInternal)
+	if !config.IdempotentWrites {
+		return fmt.Errorf("ordered_by_key requires idempotent_writes")
 	}
-
-	return output, nil
+	if config.PartitionKey == "" {
+		return fmt.Errorf("ordered_by_key requires a partition_key")
+	}
+	if config.PartitionStrategy != "" && config.PartitionStrategy != "hash" {
+		return fmt.Errorf("ordered_by_key requires the hash partition strategy, got %s", config.PartitionStrategy)
+	}
+	return nil
 }
 
 // Send sends a single event to Kafka
@@ -222,29 +331,30 @@ func (k *KafkaOutput) sendSingle(ctx context.Context, event *types.LogEvent) err
 	}
 
 	startTime := time.Now()
-	partition, offset, err := k.producer.SendMessage(msg)
+	errs, err := k.produce(ctx, []*sarama.ProducerMessage{msg})
+	if err == nil {
+		err = errs[0]
+	}
 	latency := time.Since(startTime)
 
 	if err != nil {
 		atomic.AddInt64(&k.metrics.EventsFailed, 1)
 		k.metrics.LastError = err.Error()
 		k.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to send message to Kafka: %w", err)
+		return classifyKafkaError(fmt.Errorf("failed to send message to Kafka: %w", err))
 	}
 
 	// Update metrics
 	atomic.AddInt64(&k.metrics.EventsSent, 1)
 	atomic.AddInt64(&k.metrics.BytesSent, int64(len(event.Raw)))
+	k.observeSend(k.Name(), "kafka", int64(len(event.Raw)), latency)
 	k.metrics.LastSendTime = time.Now()
 
-	// Update average latency (simple moving average)
+	// Record latency
 	k.mu.Lock()
-	k.metrics.AvgLatency = (k.metrics.AvgLatency + latency) / 2
+	k.latency.Record(latency)
 	k.mu.Unlock()
 
-	_ = partition // Can be used for logging
-	_ = offset    // Can be used for logging
-
 	return nil
 }
 
@@ -254,6 +364,12 @@ func (k *KafkaOutput) sendBatchInternal(ctx context.Context, events []*types.Log
 		return nil
 	}
 
+	done, err := k.slots.begin(ctx, &k.instrumentation, k.Name(), "kafka")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	startTime := time.Now()
 	var totalBytes int64
 
@@ -269,17 +385,23 @@ func (k *KafkaOutput) sendBatchInternal(ctx context.Context, events []*types.Log
 		totalBytes += int64(len(event.Raw))
 	}
 
-	// Send messages
-	// Note: SyncProducer doesn't have a native batch API, so we send individually
-	// In production, you might want to use AsyncProducer for better batching
+	// Send messages, all in flight at once. The batch failed transiently
+	// only if every failed message may be sent when retried.
+	results, err := k.produce(ctx, messages)
+	if err != nil {
+		k.metrics.LastError = err.Error()
+		k.metrics.LastErrorTime = time.Now()
+		return fmt.Errorf("failed to send batch to Kafka: %w", err)
+	}
+
 	var failedCount int64
-	for _, msg := range messages {
-		if msg == nil {
-			continue
-		}
-		_, _, err := k.producer.SendMessage(msg)
+	var failedClass error
+	for _, err := range results {
 		if err != nil {
 			failedCount++
+			if failedClass == nil || failedClass == ErrTransient {
+				failedClass = ErrorClass(classifyKafkaError(err))
+			}
 			k.metrics.LastError = err.Error()
 			k.metrics.LastErrorTime = time.Now()
 		}
@@ -294,22 +416,97 @@ func (k *KafkaOutput) sendBatchInternal(ctx context.Context, events []*types.Log
 	atomic.AddInt64(&k.metrics.BytesSent, totalBytes)
 	atomic.AddInt64(&k.metrics.BatchesSent, 1)
 	k.metrics.LastSendTime = time.Now()
+	k.observeBatch(k.Name(), "kafka", len(events), totalBytes, latency)
 
-	// Update average batch size and latency
+	// Update average batch size and record latency
 	k.mu.Lock()
 	if k.metrics.BatchesSent > 0 {
 		k.metrics.AvgBatchSize = float64(k.metrics.EventsSent) / float64(k.metrics.BatchesSent)
 	}
-	k.metrics.AvgLatency = (k.metrics.AvgLatency + latency) / 2
+	k.latency.Record(latency)
 	k.mu.Unlock()
 
 	if failedCount > 0 {
-		return fmt.Errorf("%d out of %d events failed to send", failedCount, len(events))
+		return classifyf(failedClass, "%d out of %d events failed to send", failedCount, len(events))
 	}
 
 	return nil
 }
 
+// produce enqueues messages on the producer and waits for each to be
+// acknowledged, returning their results in order; nil messages are skipped.
+// It gives up with ctx's error once ctx is done, as a hung broker would
+// otherwise block the send indefinitely. Messages already enqueued may
+// still be delivered.
+func (k *KafkaOutput) produce(ctx context.Context, messages []*sarama.ProducerMessage) ([]error, error) {
+	results := make([]chan error, len(messages))
+	for i, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		results[i] = make(chan error, 1)
+		msg.Metadata = results[i]
+
+		select {
+		case k.producer.Input() <- msg:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	errs := make([]error, len(messages))
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		select {
+		case errs[i] = <-result:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return errs, nil
+}
+
+// startDispatch starts routing the producer's results to the sends waiting
+// for them
+func (k *KafkaOutput) startDispatch() {
+	k.dispatched = make(chan struct{})
+	go k.dispatch()
+}
+
+// dispatch delivers each acknowledgement or error to the result channel of
+// its message until the producer is closed
+func (k *KafkaOutput) dispatch() {
+	defer close(k.dispatched)
+
+	successes, errs := k.producer.Successes(), k.producer.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			deliver(msg, nil)
+		case produceErr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			deliver(produceErr.Msg, produceErr.Err)
+		}
+	}
+}
+
+// deliver sends a message's result to the send waiting for it. The result
+// channel is buffered, so a send that gave up does not block the dispatch.
+func deliver(msg *sarama.ProducerMessage, err error) {
+	if result, ok := msg.Metadata.(chan error); ok {
+		result <- err
+	}
+}
+
 // buildMessage creates a Kafka producer message from a log event
 func (k *KafkaOutput) buildMessage(event *types.LogEvent) (*sarama.ProducerMessage, error) {
 	// Determine topic
@@ -320,15 +517,16 @@ func (k *KafkaOutput) buildMessage(event *types.LogEvent) (*sarama.ProducerMessa
 		}
 	}
 
-	// Serialize event to JSON
-	value, err := json.Marshal(event)
+	// Serialize event with the configured format
+	value, err := k.serializer.Serialize(event)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal event: %w", err)
+		return nil, classifyf(ErrSerialization, "failed to serialize event: %w", err)
 	}
 
 	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.ByteEncoder(value),
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(value),
+		Headers: k.buildHeaders(event),
 	}
 
 	// Set partition key if configured
@@ -341,6 +539,102 @@ func (k *KafkaOutput) buildMessage(event *types.LogEvent) (*sarama.ProducerMessa
 	return msg, nil
 }
 
+// classifyKafkaError wraps a producer error with its class. Authorization
+// failures are ErrAuth, messages the brokers or client reject as invalid
+// are ErrPermanent and all other errors are ErrTransient.
+func classifyKafkaError(err error) error {
+	var configErr sarama.ConfigurationError
+	if errors.As(err, &configErr) {
+		return classify(ErrPermanent, err)
+	}
+
+	var kafkaErr sarama.KError
+	if errors.As(err, &kafkaErr) {
+		switch kafkaErr {
+		case sarama.ErrSASLAuthenticationFailed, sarama.ErrTopicAuthorizationFailed, sarama.ErrClusterAuthorizationFailed:
+			return classify(ErrAuth, err)
+		case sarama.ErrInvalidMessage, sarama.ErrMessageSizeTooLarge, sarama.ErrInvalidTopic, sarama.ErrMessageSetSizeTooLarge:
+			return classify(ErrPermanent, err)
+		}
+	}
+
+	return classify(ErrTransient, err)
+}
+
+// buildHeaders returns the message headers for an event: the content type,
+// the static headers and the configured event fields
+func (k *KafkaOutput) buildHeaders(event *types.LogEvent) []sarama.RecordHeader {
+	headers := make([]sarama.RecordHeader, 0, 1+len(k.config.StaticHeaders)+len(k.config.Headers))
+	headers = append(headers, sarama.RecordHeader{
+		Key:   []byte("content-type"),
+		Value: []byte(k.serializer.ContentType()),
+	})
+
+	// Sort static headers so messages are built deterministically
+	keys := make([]string, 0, len(k.config.StaticHeaders))
+	for key := range k.config.StaticHeaders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(k.config.StaticHeaders[key]),
+		})
+	}
+
+	for _, field := range k.config.Headers {
+		if value := headerValue(event, field); value != "" {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(field),
+				Value: []byte(value),
+			})
+		}
+	}
+
+	return headers
+}
+
+// headerValue returns the string value of an event field, preferring the
+// event's own fields and falling back to its parsed fields
+func headerValue(event *types.LogEvent, field string) string {
+	var value string
+	switch field {
+	case "level":
+		value = event.Level
+	case "source":
+		value = event.Source
+	case "message":
+		value = event.Message
+	case "timestamp":
+		if !event.Timestamp.IsZero() {
+			value = event.Timestamp.Format(time.RFC3339Nano)
+		}
+	}
+
+	if value == "" {
+		value = event.Fields[field]
+	}
+	return value
+}
+
+// Flush sends any events buffered in the batcher
+func (k *KafkaOutput) Flush(ctx context.Context) error {
+	if k.batcher == nil {
+		return nil
+	}
+	return k.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (k *KafkaOutput) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if k.batcher == nil {
+		return false
+	}
+	k.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
 // Close closes the Kafka output
 func (k *KafkaOutput) Close() error {
 	if !k.closed.CompareAndSwap(false, true) {
@@ -354,9 +648,37 @@ func (k *KafkaOutput) Close() error {
 		}
 	}
 
-	// Close producer
+	// Close producer, waiting for the in-flight messages' results
 	if k.producer != nil {
-		return k.producer.Close()
+		k.producer.AsyncClose()
+		<-k.dispatched
+	}
+
+	// Close client
+	if k.client != nil && !k.client.Closed() {
+		return k.client.Close()
+	}
+
+	return nil
+}
+
+// HealthCheck verifies a Kafka broker is reachable
+func (k *KafkaOutput) HealthCheck(ctx context.Context) error {
+	if k.client == nil || k.client.Closed() {
+		return fmt.Errorf("kafka client is closed")
+	}
+
+	broker, err := k.client.Controller()
+	if err != nil {
+		return fmt.Errorf("failed to reach Kafka controller: %w", err)
+	}
+
+	connected, err := broker.Connected()
+	if err != nil {
+		return fmt.Errorf("failed to connect to broker %s: %w", broker.Addr(), err)
+	}
+	if !connected {
+		return fmt.Errorf("broker %s is not connected", broker.Addr())
 	}
 
 	return nil
@@ -377,5 +699,6 @@ func (k *KafkaOutput) Metrics() *OutputMetrics {
 
 	// Return a copy
 	metricsCopy := *k.metrics
+	k.latency.fill(&metricsCopy)
 	return &metricsCopy
 }
@@ -0,0 +1,393 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+	"github.com/xdg-go/scram"
+)
+
+func TestNewSaramaConfig_SASL(t *testing.T) {
+	tests := []struct {
+		mechanism     string
+		wantMechanism sarama.SASLMechanism
+		wantGenerator bool
+	}{
+		{"SCRAM-SHA-256", sarama.SASLTypeSCRAMSHA256, true},
+		{"SCRAM-SHA-512", sarama.SASLTypeSCRAMSHA512, true},
+		{"PLAIN", sarama.SASLTypePlaintext, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mechanism, func(t *testing.T) {
+			config := DefaultKafkaConfig()
+			config.Brokers = []string{"localhost:9092"}
+			config.Topic = "logs"
+			config.SASLEnabled = true
+			config.SASLMechanism = tt.mechanism
+			config.SASLUsername = "user"
+			config.SASLPassword = "secret"
+
+			saramaConfig, err := newSaramaConfig(config)
+			if err != nil {
+				t.Fatalf("newSaramaConfig() error = %v", err)
+			}
+
+			if saramaConfig.Net.SASL.Mechanism != tt.wantMechanism {
+				t.Errorf("Mechanism = %s, want %s", saramaConfig.Net.SASL.Mechanism, tt.wantMechanism)
+			}
+			if got := saramaConfig.Net.SASL.SCRAMClientGeneratorFunc != nil; got != tt.wantGenerator {
+				t.Errorf("SCRAMClientGeneratorFunc set = %v, want %v", got, tt.wantGenerator)
+			}
+			if err := saramaConfig.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSCRAMClient_Conversation(t *testing.T) {
+	tests := []struct {
+		name      string
+		newClient func() sarama.SCRAMClient
+		hash      scram.HashGeneratorFcn
+	}{
+		{"SHA-256", newSCRAMSHA256Client, scram.SHA256},
+		{"SHA-512", newSCRAMSHA512Client, scram.SHA512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kf := scram.KeyFactors{Salt: "saltsaltsalt", Iters: 4096}
+			hashClient, err := tt.hash.NewClient("user", "secret", "")
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			creds := hashClient.GetStoredCredentials(kf)
+
+			server, err := tt.hash.NewServer(func(string) (scram.StoredCredentials, error) {
+				return creds, nil
+			})
+			if err != nil {
+				t.Fatalf("NewServer() error = %v", err)
+			}
+			serverConv := server.NewConversation()
+
+			client := tt.newClient()
+			if err := client.Begin("user", "secret", ""); err != nil {
+				t.Fatalf("Begin() error = %v", err)
+			}
+
+			// The client speaks first with an empty challenge
+			challenge := ""
+			for !client.Done() {
+				response, err := client.Step(challenge)
+				if err != nil {
+					t.Fatalf("client Step() error = %v", err)
+				}
+				if serverConv.Done() {
+					break
+				}
+				challenge, err = serverConv.Step(response)
+				if err != nil {
+					t.Fatalf("server Step() error = %v", err)
+				}
+			}
+
+			if !serverConv.Valid() {
+				t.Error("expected the server to authenticate the client")
+			}
+		})
+	}
+}
+
+// newTestKafkaOutput creates a Kafka output producing to a mock producer,
+// closed with the test
+func newTestKafkaOutput(t *testing.T, config KafkaConfig, serializer Serializer) (*KafkaOutput, *mocks.AsyncProducer) {
+	t.Helper()
+
+	mockConfig := mocks.NewTestConfig()
+	mockConfig.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, mockConfig)
+
+	out := &KafkaOutput{config: config, producer: producer, serializer: serializer, metrics: &OutputMetrics{}}
+	out.startDispatch()
+	t.Cleanup(func() { out.Close() })
+	return out, producer
+}
+
+func TestKafkaOutput_Headers(t *testing.T) {
+	config := DefaultKafkaConfig()
+	config.Topic = "logs"
+	config.Headers = []string{"level", "source", "input_type", "missing"}
+	config.StaticHeaders = map[string]string{"env": "prod", "cluster": "eu-1"}
+
+	out, producer := newTestKafkaOutput(t, config, &JSONSerializer{})
+
+	var produced *sarama.ProducerMessage
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		produced = msg
+		return nil
+	})
+
+	event := &types.LogEvent{
+		Message: "hello",
+		Level:   "error",
+		Source:  "/var/log/app.log",
+		Fields:  map[string]string{"input_type": "file"},
+	}
+	if err := out.sendSingle(context.Background(), event); err != nil {
+		t.Fatalf("sendSingle() error = %v", err)
+	}
+	if produced == nil {
+		t.Fatal("expected a produced message")
+	}
+
+	value, err := produced.Value.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode value: %v", err)
+	}
+	var decoded types.LogEvent
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if decoded.Message != "hello" {
+		t.Errorf("decoded message = %q, want hello", decoded.Message)
+	}
+
+	headers := make(map[string]string, len(produced.Headers))
+	var keys []string
+	for _, header := range produced.Headers {
+		headers[string(header.Key)] = string(header.Value)
+		keys = append(keys, string(header.Key))
+	}
+
+	expected := map[string]string{
+		"content-type": "application/json",
+		"env":          "prod",
+		"cluster":      "eu-1",
+		"level":        "error",
+		"source":       "/var/log/app.log",
+		"input_type":   "file",
+	}
+	if len(headers) != len(expected) {
+		t.Errorf("expected %d headers, got %v", len(expected), keys)
+	}
+	for key, want := range expected {
+		if got := headers[key]; got != want {
+			t.Errorf("header %s = %q, want %q", key, got, want)
+		}
+	}
+
+	// Static headers are sorted after the content type
+	if strings.Join(keys[:3], ",") != "content-type,cluster,env" {
+		t.Errorf("unexpected header order: %v", keys)
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	timestamp := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	event := &types.LogEvent{Timestamp: timestamp, Message: "hello", Fields: map[string]string{"level": "warn"}}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"timestamp", "2024-03-15T10:30:00Z"},
+		{"message", "hello"},
+		{"level", "warn"},
+		{"unknown", ""},
+	}
+	for _, tt := range tests {
+		if got := headerValue(event, tt.field); got != tt.want {
+			t.Errorf("headerValue(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	if got := headerValue(&types.LogEvent{}, "timestamp"); got != "" {
+		t.Errorf("expected no header for a zero timestamp, got %q", got)
+	}
+}
+
+func TestKafkaOutput_SerializationContentType(t *testing.T) {
+	tests := []struct {
+		format      SerializationFormat
+		contentType string
+	}{
+		{SerializationJSON, "application/json"},
+		{SerializationMsgpack, "application/msgpack"},
+		{SerializationAvro, "application/avro"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			serializer, err := GetSerializer(SerializationConfig{Format: tt.format})
+			if err != nil {
+				t.Fatalf("GetSerializer() error = %v", err)
+			}
+
+			out, producer := newTestKafkaOutput(t, DefaultKafkaConfig(), serializer)
+
+			var produced *sarama.ProducerMessage
+			producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+				produced = msg
+				return nil
+			})
+
+			event := &types.LogEvent{Timestamp: time.Now(), Message: "hello"}
+			if err := out.sendSingle(context.Background(), event); err != nil {
+				t.Fatalf("sendSingle() error = %v", err)
+			}
+
+			if got := string(produced.Headers[0].Key); got != "content-type" {
+				t.Fatalf("expected the content type header first, got %s", got)
+			}
+			if got := string(produced.Headers[0].Value); got != tt.contentType {
+				t.Errorf("content-type = %q, want %q", got, tt.contentType)
+			}
+
+			value, err := produced.Value.Encode()
+			if err != nil {
+				t.Fatalf("failed to encode value: %v", err)
+			}
+			want, _ := serializer.Serialize(event)
+			if string(value) != string(want) {
+				t.Error("expected the message value to be encoded by the serializer")
+			}
+		})
+	}
+}
+
+func TestNewSaramaConfig_OrderedByKey(t *testing.T) {
+	config := DefaultKafkaConfig()
+	config.OrderedByKey = true
+	config.IdempotentWrites = true
+	config.PartitionKey = "request_id"
+
+	saramaConfig, err := newSaramaConfig(config)
+	if err != nil {
+		t.Fatalf("newSaramaConfig() error = %v", err)
+	}
+	if saramaConfig.Net.MaxOpenRequests != 1 {
+		t.Errorf("MaxOpenRequests = %d, want 1", saramaConfig.Net.MaxOpenRequests)
+	}
+	if !saramaConfig.Producer.Idempotent || saramaConfig.Producer.RequiredAcks != sarama.WaitForAll {
+		t.Errorf("expected an idempotent producer waiting for all replicas")
+	}
+	if err := saramaConfig.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestNewSaramaConfig_OrderedByKeyValidation(t *testing.T) {
+	tests := map[string]func(*KafkaConfig){
+		"without idempotence":   func(c *KafkaConfig) { c.IdempotentWrites = false },
+		"without partition key": func(c *KafkaConfig) { c.PartitionKey = "" },
+		"random partitioning":   func(c *KafkaConfig) { c.PartitionStrategy = "random" },
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultKafkaConfig()
+			config.OrderedByKey = true
+			config.IdempotentWrites = true
+			config.PartitionKey = "request_id"
+			modify(&config)
+
+			if _, err := newSaramaConfig(config); err == nil {
+				t.Error("expected a validation error")
+			}
+		})
+	}
+}
+
+// hungProducer accepts messages but never acknowledges them, like a
+// producer whose broker stopped responding
+type hungProducer struct {
+	sarama.AsyncProducer
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newHungProducer() *hungProducer {
+	return &hungProducer{
+		input:     make(chan *sarama.ProducerMessage, 1),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+}
+
+func (p *hungProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *hungProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *hungProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func (p *hungProducer) AsyncClose() {
+	close(p.successes)
+	close(p.errors)
+}
+
+func TestKafkaOutput_SendHonorsContext(t *testing.T) {
+	newOutput := func() *KafkaOutput {
+		out := &KafkaOutput{config: DefaultKafkaConfig(), producer: newHungProducer(), serializer: &JSONSerializer{}, metrics: &OutputMetrics{}}
+		out.startDispatch()
+		t.Cleanup(func() { out.Close() })
+		return out
+	}
+
+	t.Run("cancelled mid-send", func(t *testing.T) {
+		out := newOutput()
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := out.Send(ctx, &types.LogEvent{Message: "hello"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the send to return promptly, took %s", elapsed)
+		}
+		if ErrorClass(err) != nil {
+			t.Errorf("expected a cancelled send left unclassified, got %v", ErrorClass(err))
+		}
+		if failed := out.Metrics().EventsFailed; failed != 1 {
+			t.Errorf("expected 1 failed event, got %d", failed)
+		}
+	})
+
+	t.Run("batch deadline", func(t *testing.T) {
+		out := newOutput()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// The second message blocks on the full input channel
+		err := out.SendBatch(ctx, testBatch(2))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestKafkaOutput_SendBatchResults(t *testing.T) {
+	out, producer := newTestKafkaOutput(t, DefaultKafkaConfig(), &JSONSerializer{})
+	producer.ExpectInputAndSucceed()
+	producer.ExpectInputAndFail(sarama.ErrMessageSizeTooLarge)
+	producer.ExpectInputAndSucceed()
+
+	err := out.SendBatch(context.Background(), testBatch(3))
+	if !errors.Is(err, ErrPermanent) {
+		t.Fatalf("expected a permanent batch error, got %v", err)
+	}
+	metrics := out.Metrics()
+	if metrics.EventsSent != 2 || metrics.EventsFailed != 1 {
+		t.Errorf("expected 2 sent and 1 failed, got %d and %d", metrics.EventsSent, metrics.EventsFailed)
+	}
+}
@@ -0,0 +1,118 @@
+package output
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// latencySubBucketBits sets the histogram precision: each power of two is
+// split into 2^latencySubBucketBits buckets, bounding the relative error of
+// a percentile to about 3%.
+const latencySubBucketBits = 5
+
+const (
+	latencySubBuckets = 1 << latencySubBucketBits
+	latencyBuckets    = (64 - latencySubBucketBits) * latencySubBuckets
+)
+
+// LatencyHistogram is a streaming latency summary with log-linear buckets,
+// in the style of an HDR histogram. It keeps an exact mean, minimum and
+// maximum and approximates percentiles. It is not safe for concurrent use.
+type LatencyHistogram struct {
+	counts [latencyBuckets]uint64
+	count  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// Record adds a latency sample
+func (h *LatencyHistogram) Record(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+
+	h.counts[latencyBucket(uint64(latency))]++
+	if h.count == 0 || latency < h.min {
+		h.min = latency
+	}
+	if latency > h.max {
+		h.max = latency
+	}
+	h.count++
+	h.sum += latency
+}
+
+// Count returns the number of recorded samples
+func (h *LatencyHistogram) Count() int64 {
+	return h.count
+}
+
+// Mean returns the mean of all recorded samples
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Percentile returns the latency below which p percent of the samples fall
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen < rank {
+			continue
+		}
+
+		// Report the middle of the bucket, within the observed range
+		value := time.Duration(latencyBucketMidpoint(i))
+		if value < h.min {
+			return h.min
+		}
+		if value > h.max {
+			return h.max
+		}
+		return value
+	}
+	return h.max
+}
+
+// fill sets the latency fields of metrics from the histogram
+func (h *LatencyHistogram) fill(metrics *OutputMetrics) {
+	metrics.AvgLatency = h.Mean()
+	metrics.P50Latency = h.Percentile(50)
+	metrics.P90Latency = h.Percentile(90)
+	metrics.P99Latency = h.Percentile(99)
+	metrics.LatencyCount = h.count
+	metrics.LatencySum = h.sum
+}
+
+// latencyBucket returns the bucket index of a latency in nanoseconds
+func latencyBucket(value uint64) int {
+	if value < latencySubBuckets {
+		return int(value)
+	}
+	shift := bits.Len64(value) - latencySubBucketBits - 1
+	return (shift+1)*latencySubBuckets + int(value>>shift) - latencySubBuckets
+}
+
+// latencyBucketMidpoint returns the middle value of a bucket
+func latencyBucketMidpoint(index int) uint64 {
+	if index < latencySubBuckets {
+		return uint64(index)
+	}
+	shift := index/latencySubBuckets - 1
+	lower := uint64(latencySubBuckets+index%latencySubBuckets) << shift
+	return lower + (uint64(1)<<shift)/2
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Empty(t *testing.T) {
+	var h LatencyHistogram
+
+	if h.Count() != 0 || h.Mean() != 0 || h.Percentile(99) != 0 {
+		t.Errorf("expected an empty histogram to report zeros, got count=%d mean=%v p99=%v",
+			h.Count(), h.Mean(), h.Percentile(99))
+	}
+}
+
+func TestLatencyHistogram_Uniform(t *testing.T) {
+	var h LatencyHistogram
+
+	// 1ms..1000ms, each once
+	var sum time.Duration
+	for i := 1; i <= 1000; i++ {
+		latency := time.Duration(i) * time.Millisecond
+		h.Record(latency)
+		sum += latency
+	}
+
+	if h.Count() != 1000 {
+		t.Errorf("expected 1000 samples, got %d", h.Count())
+	}
+	if mean := h.Mean(); mean != sum/1000 {
+		t.Errorf("expected exact mean %v, got %v", sum/1000, mean)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		assertWithin(t, tt.p, h.Percentile(tt.p), tt.want, 0.03)
+	}
+}
+
+func TestLatencyHistogram_LongTail(t *testing.T) {
+	var h LatencyHistogram
+	rng := rand.New(rand.NewSource(1))
+
+	// Mostly fast sends with a slow tail, compared against exact percentiles
+	samples := make([]time.Duration, 0, 100000)
+	for i := 0; i < cap(samples); i++ {
+		latency := time.Duration(rng.ExpFloat64() * float64(5*time.Millisecond))
+		if rng.Intn(100) == 0 {
+			latency += 2 * time.Second
+		}
+		samples = append(samples, latency)
+		h.Record(latency)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		want := samples[int(p/100*float64(len(samples)))-1]
+		assertWithin(t, p, h.Percentile(p), want, 0.03)
+	}
+
+	// A moving average of the last samples would hide the tail entirely
+	if h.Percentile(99.9) < time.Second {
+		t.Errorf("expected p99.9 to include the slow tail, got %v", h.Percentile(99.9))
+	}
+}
+
+func TestLatencyHistogram_Fill(t *testing.T) {
+	var h LatencyHistogram
+	for i := 0; i < 10; i++ {
+		h.Record(10 * time.Millisecond)
+	}
+	h.Record(time.Second)
+	h.Record(-time.Millisecond)
+
+	var metrics OutputMetrics
+	h.fill(&metrics)
+
+	if metrics.LatencyCount != 12 {
+		t.Errorf("expected 12 samples, got %d", metrics.LatencyCount)
+	}
+	if metrics.LatencySum != 1100*time.Millisecond {
+		t.Errorf("expected sum 1.1s, got %v", metrics.LatencySum)
+	}
+	if metrics.AvgLatency != metrics.LatencySum/12 {
+		t.Errorf("expected AvgLatency to be the true mean, got %v", metrics.AvgLatency)
+	}
+	assertWithin(t, 50, metrics.P50Latency, 10*time.Millisecond, 0.03)
+	assertWithin(t, 90, metrics.P90Latency, 10*time.Millisecond, 0.03)
+	assertWithin(t, 99, metrics.P99Latency, time.Second, 0.03)
+}
+
+func assertWithin(t *testing.T, p float64, got, want time.Duration, tolerance float64) {
+	t.Helper()
+	if diff := float64(got - want); diff > tolerance*float64(want) || -diff > tolerance*float64(want) {
+		t.Errorf("p%v: expected %v within %.0f%%, got %v", p, want, tolerance*100, got)
+	}
+}
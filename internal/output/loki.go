@@ -0,0 +1,536 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// lokiPushPath is the Loki push API endpoint
+const lokiPushPath = "/loki/api/v1/push"
+
+// Loki push request encodings
+const (
+	LokiEncodingProtobuf = "protobuf"
+	LokiEncodingJSON     = "json"
+)
+
+// LokiConfig contains Grafana Loki output configuration
+type LokiConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// URL is the Loki base URL; the push path is appended unless present
+	URL string `yaml:"url"`
+
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki
+	TenantID string `yaml:"tenant_id,omitempty"`
+
+	// Labels are static labels added to every stream
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// LabelFields are the event fields that become stream labels. "level"
+	// and "source" refer to the event's level and source; other names are
+	// looked up in its fields. Names are sanitized to valid label names and
+	// empty values are left out.
+	LabelFields []string `yaml:"label_fields,omitempty"`
+
+	// Encoding is protobuf (snappy-compressed, the default) or json
+	Encoding string `yaml:"encoding,omitempty"`
+
+	// LineFormat is json to push the serialized event or message to push
+	// only its message
+	LineFormat string `yaml:"line_format,omitempty"`
+
+	// Headers, authentication, circuit breaker and TLS settings
+	HTTPClientConfig `yaml:",inline"`
+}
+
+func init() {
+	Register("loki", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultLokiConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewLokiOutput(config)
+	})
+}
+
+// DefaultLokiConfig returns default Loki output configuration
+func DefaultLokiConfig() LokiConfig {
+	return LokiConfig{
+		BaseConfig:       DefaultBaseConfig(),
+		URL:              "http://localhost:3100",
+		Labels:           map[string]string{"job": "logaggregator"},
+		LabelFields:      []string{"level"},
+		Encoding:         LokiEncodingProtobuf,
+		LineFormat:       "json",
+		HTTPClientConfig: DefaultHTTPClientConfig(),
+	}
+}
+
+// LokiOutput pushes events to Grafana Loki, grouping each batch into
+// streams by label set
+type LokiOutput struct {
+	config  LokiConfig
+	url     string
+	sender  *httpSender
+	schema  *SchemaMapper
+	batcher *Batcher
+	slots   sendSlots
+	metrics *OutputMetrics
+	latency LatencyHistogram
+	mu      sync.RWMutex
+	closed  atomic.Bool
+
+	instrumentation
+	flushErrorHook
+}
+
+// NewLokiOutput creates a new Loki output
+func NewLokiOutput(config LokiConfig) (*LokiOutput, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("no url specified")
+	}
+
+	switch config.Encoding {
+	case "":
+		config.Encoding = LokiEncodingProtobuf
+	case LokiEncodingProtobuf, LokiEncodingJSON:
+	default:
+		return nil, fmt.Errorf("unsupported loki encoding: %s", config.Encoding)
+	}
+
+	switch config.LineFormat {
+	case "":
+		config.LineFormat = "json"
+	case "json", "message":
+	default:
+		return nil, fmt.Errorf("unsupported loki line format: %s", config.LineFormat)
+	}
+
+	if config.TenantID != "" {
+		headers := make(map[string]string, len(config.Headers)+1)
+		for key, value := range config.Headers {
+			headers[key] = value
+		}
+		headers["X-Scope-OrgID"] = config.TenantID
+		config.Headers = headers
+	}
+
+	schema, err := newOptionalSchemaMapper(config.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := newHTTPSender(config.Name, http.MethodPost, config.BaseConfig, config.HTTPClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &LokiOutput{
+		config:  config,
+		url:     lokiPushURL(config.URL),
+		sender:  sender,
+		schema:  schema,
+		metrics: &OutputMetrics{},
+		slots:   newSendSlots(config.MaxConcurrentBatches),
+	}
+
+	// Create batcher
+	if config.BatchSize > 1 {
+		output.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  config.BatchSize,
+			MaxBatchBytes: 4 * 1024 * 1024, // Loki's default gRPC message limit
+			FlushInterval: config.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				output.observeFlush(output.Name(), "loki", trigger)
+			},
+			Adaptive: config.AdaptiveBatch,
+			Ordered:  config.Ordered,
+			Metrics:  output.Metrics,
+			OnResize: func(size int) {
+				output.observeBatchSize(output.Name(), "loki", size)
+			},
+			OnFlushError: output.flushFailed,
+		}, output.sendBatchInternal)
+	}
+
+	return output, nil
+}
+
+// lokiPushURL appends the push path to a Loki base URL
+func lokiPushURL(base string) string {
+	base = strings.TrimSuffix(base, "/")
+	if strings.HasSuffix(base, lokiPushPath) {
+		return base
+	}
+	return base + lokiPushPath
+}
+
+// Send pushes a single event
+func (l *LokiOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	if l.closed.Load() {
+		return fmt.Errorf("loki output is closed")
+	}
+
+	// Use batcher if configured
+	if l.batcher != nil {
+		return l.batcher.Add(ctx, event)
+	}
+
+	return l.sendBatchInternal(ctx, []*types.LogEvent{event})
+}
+
+// SendBatch pushes a batch of events in a single request
+func (l *LokiOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	if l.closed.Load() {
+		return fmt.Errorf("loki output is closed")
+	}
+
+	return l.sendBatchInternal(ctx, events)
+}
+
+// sendBatchInternal groups events into streams and pushes them
+func (l *LokiOutput) sendBatchInternal(ctx context.Context, events []*types.LogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	done, err := l.slots.begin(ctx, &l.instrumentation, l.Name(), "loki")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	startTime := time.Now()
+
+	streams, err := l.streams(events)
+	if err != nil {
+		l.recordFailure(len(events), err)
+		return err
+	}
+
+	var body []byte
+	var contentType string
+	if l.config.Encoding == LokiEncodingJSON {
+		body, err = encodeLokiJSON(streams)
+		contentType = "application/json"
+	} else {
+		body = snappy.Encode(nil, encodeLokiProtobuf(streams))
+		contentType = "application/x-protobuf"
+	}
+	if err != nil {
+		l.recordFailure(len(events), err)
+		return err
+	}
+
+	err = l.sender.send(ctx, l.url, contentType, "", body)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		l.recordFailure(len(events), err)
+		return err
+	}
+
+	// Update metrics
+	atomic.AddInt64(&l.metrics.EventsSent, int64(len(events)))
+	atomic.AddInt64(&l.metrics.BytesSent, int64(len(body)))
+	atomic.AddInt64(&l.metrics.BatchesSent, 1)
+	l.observeBatch(l.Name(), "loki", len(events), int64(len(body)), latency)
+
+	// Update average batch size and record latency
+	l.mu.Lock()
+	l.metrics.LastSendTime = time.Now()
+	if l.metrics.BatchesSent > 0 {
+		l.metrics.AvgBatchSize = float64(l.metrics.EventsSent) / float64(l.metrics.BatchesSent)
+	}
+	l.latency.Record(latency)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// lokiStream is a set of entries sharing a label set
+type lokiStream struct {
+	labels  map[string]string
+	key     string // labels in Loki's {name="value", ...} syntax
+	entries []lokiEntry
+}
+
+// lokiEntry is a single log line
+type lokiEntry struct {
+	timestamp time.Time
+	line      string
+}
+
+// streams groups events by label set, in order of first appearance, with
+// each stream's entries sorted by timestamp
+func (l *LokiOutput) streams(events []*types.LogEvent) ([]*lokiStream, error) {
+	var streams []*lokiStream
+	byKey := make(map[string]*lokiStream)
+
+	now := time.Now()
+	for _, event := range events {
+		line, err := l.line(event)
+		if err != nil {
+			return nil, err
+		}
+
+		labels := l.labels(event)
+		key := lokiLabelString(labels)
+		stream, ok := byKey[key]
+		if !ok {
+			stream = &lokiStream{labels: labels, key: key}
+			byKey[key] = stream
+			streams = append(streams, stream)
+		}
+
+		timestamp := event.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		stream.entries = append(stream.entries, lokiEntry{timestamp: timestamp, line: line})
+	}
+
+	for _, stream := range streams {
+		sort.SliceStable(stream.entries, func(i, j int) bool {
+			return stream.entries[i].timestamp.Before(stream.entries[j].timestamp)
+		})
+	}
+
+	return streams, nil
+}
+
+// labels returns the stream labels of an event
+func (l *LokiOutput) labels(event *types.LogEvent) map[string]string {
+	labels := make(map[string]string, len(l.config.Labels)+len(l.config.LabelFields))
+	for name, value := range l.config.Labels {
+		if value != "" {
+			labels[sanitizeLabelName(name)] = value
+		}
+	}
+
+	for _, field := range l.config.LabelFields {
+		var value string
+		switch field {
+		case "level":
+			value = event.Level
+		case "source":
+			value = event.Source
+		default:
+			value = event.Fields[field]
+		}
+		if value != "" {
+			labels[sanitizeLabelName(field)] = value
+		}
+	}
+
+	return labels
+}
+
+// line renders the log line of an event
+func (l *LokiOutput) line(event *types.LogEvent) (string, error) {
+	if l.config.LineFormat == "message" {
+		return event.Message, nil
+	}
+
+	var value interface{} = event
+	if l.schema != nil {
+		value = l.schema.Document(event)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", classifyf(ErrSerialization, "failed to serialize event: %w", err)
+	}
+	return string(data), nil
+}
+
+// sanitizeLabelName maps a field name to a valid Loki label name by
+// replacing invalid characters with underscores
+func sanitizeLabelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// lokiLabelString renders labels sorted by name in Loki's selector syntax
+func lokiLabelString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// encodeLokiJSON encodes streams as a JSON push request, with timestamps
+// as Unix nanosecond strings
+func encodeLokiJSON(streams []*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, len(streams))}
+
+	for i, stream := range streams {
+		values := make([][2]string, len(stream.entries))
+		for j, entry := range stream.entries {
+			values[j] = [2]string{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line}
+		}
+		request.Streams[i] = jsonStream{Stream: stream.labels, Values: values}
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, classifyf(ErrSerialization, "failed to encode push request: %w", err)
+	}
+	return data, nil
+}
+
+// encodeLokiProtobuf encodes streams as a logproto.PushRequest:
+//
+//	PushRequest  { repeated Stream streams = 1; }
+//	Stream       { string labels = 1; repeated Entry entries = 2; }
+//	Entry        { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeLokiProtobuf(streams []*lokiStream) []byte {
+	var request []byte
+	for _, stream := range streams {
+		var msg []byte
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, stream.key)
+
+		for _, entry := range stream.entries {
+			var timestamp []byte
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Unix()))
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Nanosecond()))
+
+			var e []byte
+			e = protowire.AppendTag(e, 1, protowire.BytesType)
+			e = protowire.AppendBytes(e, timestamp)
+			e = protowire.AppendTag(e, 2, protowire.BytesType)
+			e = protowire.AppendString(e, entry.line)
+
+			msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+			msg = protowire.AppendBytes(msg, e)
+		}
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, msg)
+	}
+	return request
+}
+
+// recordFailure counts failed events and records the error
+func (l *LokiOutput) recordFailure(events int, err error) {
+	atomic.AddInt64(&l.metrics.EventsFailed, int64(events))
+
+	l.mu.Lock()
+	l.metrics.LastError = err.Error()
+	l.metrics.LastErrorTime = time.Now()
+	l.mu.Unlock()
+}
+
+// Flush sends any events buffered in the batcher
+func (l *LokiOutput) Flush(ctx context.Context) error {
+	if l.batcher == nil {
+		return nil
+	}
+	return l.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (l *LokiOutput) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if l.batcher == nil {
+		return false
+	}
+	l.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
+// Close closes the Loki output
+func (l *LokiOutput) Close() error {
+	if !l.closed.CompareAndSwap(false, true) {
+		return nil // Already closed
+	}
+
+	// Stop batcher first
+	if l.batcher != nil {
+		if err := l.batcher.Stop(); err != nil {
+			return err
+		}
+	}
+
+	l.sender.client.CloseIdleConnections()
+	return nil
+}
+
+// CircuitBreaker returns the output's circuit breaker, or nil when disabled
+func (l *LokiOutput) CircuitBreaker() *reliability.CircuitBreaker {
+	return l.sender.breaker
+}
+
+// HealthCheck requests Loki's readiness endpoint
+func (l *LokiOutput) HealthCheck(ctx context.Context) error {
+	ready := strings.TrimSuffix(l.url, lokiPushPath) + "/ready"
+	return l.sender.healthCheck(ctx, ready)
+}
+
+// Name returns the output name
+func (l *LokiOutput) Name() string {
+	if l.config.Name != "" {
+		return l.config.Name
+	}
+	return "loki"
+}
+
+// Metrics returns the current metrics
+func (l *LokiOutput) Metrics() *OutputMetrics {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Return a copy
+	metricsCopy := *l.metrics
+	metricsCopy.RetryCount = l.sender.retries.Load()
+	l.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
@@ -0,0 +1,261 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// lokiPush is a decoded JSON push request
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func newTestLokiOutput(t *testing.T, endpoint *testEndpoint, configure func(*LokiConfig)) (*LokiOutput, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	config := DefaultLokiConfig()
+	config.URL = server.URL
+	config.BatchSize = 1
+	config.RetryBackoff = time.Millisecond
+	config.LabelFields = []string{"level", "app"}
+	if configure != nil {
+		configure(&config)
+	}
+
+	out, err := NewLokiOutput(config)
+	if err != nil {
+		t.Fatalf("NewLokiOutput() error = %v", err)
+	}
+	t.Cleanup(func() { out.Close() })
+	return out, server
+}
+
+func lokiEvents() []*types.LogEvent {
+	base := time.Unix(1700000000, 0)
+	return []*types.LogEvent{
+		{Timestamp: base.Add(2), Message: "api error", Level: "error", Fields: map[string]string{"app": "api"}},
+		{Timestamp: base.Add(1), Message: "api info", Level: "info", Fields: map[string]string{"app": "api"}},
+		{Timestamp: base.Add(3), Message: "web info", Level: "info", Fields: map[string]string{"app": "web"}},
+		{Timestamp: base, Message: "api info first", Level: "info", Fields: map[string]string{"app": "api"}},
+	}
+}
+
+func TestLokiOutput_StreamsJSON(t *testing.T) {
+	endpoint := &testEndpoint{}
+	out, _ := newTestLokiOutput(t, endpoint, func(c *LokiConfig) {
+		c.Encoding = LokiEncodingJSON
+		c.LineFormat = "message"
+		c.TenantID = "team-a"
+	})
+
+	if err := out.SendBatch(context.Background(), lokiEvents()); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	requests := endpoint.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if got := requests[0].header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected application/json, got %q", got)
+	}
+	if got := requests[0].header.Get("X-Scope-OrgID"); got != "team-a" {
+		t.Errorf("expected the tenant header, got %q", got)
+	}
+
+	var push lokiPush
+	if err := json.Unmarshal([]byte(requests[0].body), &push); err != nil {
+		t.Fatalf("failed to decode push request: %v", err)
+	}
+	if len(push.Streams) != 3 {
+		t.Fatalf("expected 3 streams, got %d: %s", len(push.Streams), requests[0].body)
+	}
+
+	// Streams appear in order, entries sorted by time
+	want := []struct {
+		app, level string
+		lines      []string
+	}{
+		{"api", "error", []string{"api error"}},
+		{"api", "info", []string{"api info first", "api info"}},
+		{"web", "info", []string{"web info"}},
+	}
+	for i, w := range want {
+		stream := push.Streams[i]
+		if stream.Stream["app"] != w.app || stream.Stream["level"] != w.level || stream.Stream["job"] != "logaggregator" {
+			t.Errorf("stream %d: unexpected labels %v", i, stream.Stream)
+		}
+		if len(stream.Values) != len(w.lines) {
+			t.Fatalf("stream %d: expected %d entries, got %d", i, len(w.lines), len(stream.Values))
+		}
+		for j, line := range w.lines {
+			if stream.Values[j][1] != line {
+				t.Errorf("stream %d entry %d: expected %q, got %q", i, j, line, stream.Values[j][1])
+			}
+		}
+	}
+	if ts := push.Streams[1].Values[0][0]; ts != "1700000000000000000" {
+		t.Errorf("expected a nanosecond timestamp string, got %s", ts)
+	}
+}
+
+func TestLokiOutput_StreamsProtobuf(t *testing.T) {
+	endpoint := &testEndpoint{}
+	out, _ := newTestLokiOutput(t, endpoint, nil)
+
+	if err := out.SendBatch(context.Background(), lokiEvents()); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	req := endpoint.recorded()[0]
+	if got := req.header.Get("Content-Type"); got != "application/x-protobuf" {
+		t.Errorf("expected application/x-protobuf, got %q", got)
+	}
+
+	data, err := snappy.Decode(nil, []byte(req.body))
+	if err != nil {
+		t.Fatalf("expected a snappy body: %v", err)
+	}
+
+	labels, entries := decodeLokiProtobuf(t, data)
+	want := []string{
+		`{app="api", job="logaggregator", level="error"}`,
+		`{app="api", job="logaggregator", level="info"}`,
+		`{app="web", job="logaggregator", level="info"}`,
+	}
+	if len(labels) != len(want) {
+		t.Fatalf("expected %d streams, got %v", len(want), labels)
+	}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("stream %d: expected labels %s, got %s", i, want[i], labels[i])
+		}
+	}
+	if entries[1] != 2 {
+		t.Errorf("expected 2 entries in the api info stream, got %d", entries[1])
+	}
+}
+
+// decodeLokiProtobuf returns the label string and entry count of each stream
+func decodeLokiProtobuf(t *testing.T, data []byte) ([]string, []int) {
+	t.Helper()
+
+	var labels []string
+	var entries []int
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			t.Fatalf("unexpected push request field %d", num)
+		}
+		data = data[n:]
+		stream, n := protowire.ConsumeBytes(data)
+		data = data[n:]
+
+		count := 0
+		for len(stream) > 0 {
+			num, _, n := protowire.ConsumeTag(stream)
+			stream = stream[n:]
+			value, n := protowire.ConsumeBytes(stream)
+			stream = stream[n:]
+			switch num {
+			case 1:
+				labels = append(labels, string(value))
+			case 2:
+				count++
+			}
+		}
+		entries = append(entries, count)
+	}
+	return labels, entries
+}
+
+func TestLokiOutput_RetriesTooManyRequests(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests}}
+	out, _ := newTestLokiOutput(t, endpoint, nil)
+
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "throttled"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(endpoint.recorded()) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(endpoint.recorded()))
+	}
+	if metrics := out.Metrics(); metrics.RetryCount != 2 || metrics.EventsSent != 1 {
+		t.Errorf("expected 2 retries and 1 event sent, got %d and %d", metrics.RetryCount, metrics.EventsSent)
+	}
+}
+
+func TestLokiOutput_Labels(t *testing.T) {
+	out := &LokiOutput{config: LokiConfig{
+		Labels:      map[string]string{"env": "prod", "empty": ""},
+		LabelFields: []string{"source", "k8s.namespace", "1st", "missing"},
+	}}
+
+	labels := out.labels(&types.LogEvent{
+		Source: "/var/log/app.log",
+		Fields: map[string]string{"k8s.namespace": "default", "1st": "x"},
+	})
+	want := `{_st="x", env="prod", k8s_namespace="default", source="/var/log/app.log"}`
+	if got := lokiLabelString(labels); got != want {
+		t.Errorf("expected labels %s, got %s", want, got)
+	}
+}
+
+func TestLokiOutput_LineSchema(t *testing.T) {
+	mapper, err := NewSchemaMapper(SchemaConfig{Name: SchemaECS})
+	if err != nil {
+		t.Fatalf("NewSchemaMapper() error = %v", err)
+	}
+	out := &LokiOutput{config: LokiConfig{LineFormat: "json"}, schema: mapper}
+
+	line, err := out.line(&types.LogEvent{Timestamp: time.Unix(1700000000, 0).UTC(), Message: "ready", Level: "info"})
+	if err != nil {
+		t.Fatalf("line() error = %v", err)
+	}
+	want := `{"@timestamp":"2023-11-14T22:13:20Z","log.file.path":"","log.level":"info","message":"ready"}`
+	if line != want {
+		t.Errorf("expected line %s, got %s", want, line)
+	}
+}
+
+func TestLokiPushURL(t *testing.T) {
+	tests := map[string]string{
+		"http://loki:3100":                   "http://loki:3100/loki/api/v1/push",
+		"http://loki:3100/":                  "http://loki:3100/loki/api/v1/push",
+		"http://loki:3100/loki/api/v1/push":  "http://loki:3100/loki/api/v1/push",
+		"https://gateway/tenant/loki/api/v1": "https://gateway/tenant/loki/api/v1/loki/api/v1/push",
+	}
+	for base, want := range tests {
+		if got := lokiPushURL(base); got != want {
+			t.Errorf("lokiPushURL(%s) = %s, want %s", base, got, want)
+		}
+	}
+}
+
+func TestNewLokiOutput_Validation(t *testing.T) {
+	config := DefaultLokiConfig()
+	config.Encoding = "xml"
+	if _, err := NewLokiOutput(config); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+
+	config = DefaultLokiConfig()
+	config.URL = ""
+	if _, err := NewLokiOutput(config); err == nil {
+		t.Error("expected an error without a url")
+	}
+}
@@ -0,0 +1,198 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// MemoryOutput keeps the events sent to it in memory, for testing routing,
+// retries, circuit breaking and backpressure. Calls can be scripted to fail
+// or to take time. It is safe for concurrent use.
+type MemoryOutput struct {
+	name string
+
+	mu          sync.Mutex
+	events      []*types.LogEvent
+	batches     [][]*types.LogEvent
+	calls       int
+	inFlight    int
+	maxInFlight int
+	script      []error
+	err         error
+	latency     time.Duration
+	closed      bool
+	metrics     OutputMetrics
+}
+
+// NewMemoryOutput creates an empty memory output
+func NewMemoryOutput(name string) *MemoryOutput {
+	return &MemoryOutput{name: name}
+}
+
+// FailNext scripts the results of the next calls, one per call; a nil
+// error lets its call succeed. Calls past the script use SetError.
+func (m *MemoryOutput) FailNext(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, errs...)
+}
+
+// SetError fails every unscripted call with err, or none when err is nil
+func (m *MemoryOutput) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// SetLatency makes each call take d, or until its context is done
+func (m *MemoryOutput) SetLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = d
+}
+
+// Send records an event as a batch of one
+func (m *MemoryOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	return m.SendBatch(ctx, []*types.LogEvent{event})
+}
+
+// SendBatch records a batch unless the call is scripted to fail. Failed
+// batches are not recorded.
+func (m *MemoryOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return classifyf(ErrPermanent, "output %s is closed", m.name)
+	}
+	m.calls++
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	err := m.err
+	if len(m.script) > 0 {
+		err, m.script = m.script[0], m.script[1:]
+	}
+	latency := m.latency
+	m.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+	if err != nil {
+		m.metrics.EventsFailed += int64(len(events))
+		m.metrics.LastError = err.Error()
+		m.metrics.LastErrorTime = time.Now()
+		return err
+	}
+
+	m.events = append(m.events, events...)
+	m.batches = append(m.batches, append([]*types.LogEvent(nil), events...))
+	m.metrics.EventsSent += int64(len(events))
+	m.metrics.BatchesSent++
+	m.metrics.LastSendTime = time.Now()
+	return nil
+}
+
+// Events returns the events recorded so far, in the order received
+func (m *MemoryOutput) Events() []*types.LogEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*types.LogEvent(nil), m.events...)
+}
+
+// Messages returns the messages of the recorded events
+func (m *MemoryOutput) Messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages := make([]string, len(m.events))
+	for i, event := range m.events {
+		messages[i] = event.Message
+	}
+	return messages
+}
+
+// Batches returns the recorded batches; Send records batches of one
+func (m *MemoryOutput) Batches() [][]*types.LogEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]*types.LogEvent(nil), m.batches...)
+}
+
+// Calls returns the number of Send and SendBatch calls, failed ones included
+func (m *MemoryOutput) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// MaxInFlight returns the most calls that were in progress at once
+func (m *MemoryOutput) MaxInFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.maxInFlight
+}
+
+// Closed reports whether Close was called
+func (m *MemoryOutput) Closed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+// Reset forgets the recorded events, calls and script, keeping the error
+// and latency settings
+func (m *MemoryOutput) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = nil
+	m.batches = nil
+	m.calls = 0
+	m.maxInFlight = m.inFlight
+	m.script = nil
+	m.metrics = OutputMetrics{}
+}
+
+// Close marks the output closed; later sends fail with ErrPermanent
+func (m *MemoryOutput) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// Name returns the output name
+func (m *MemoryOutput) Name() string {
+	return m.name
+}
+
+// Metrics returns the events and batches sent and failed
+func (m *MemoryOutput) Metrics() *OutputMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metricsCopy := m.metrics
+	if metricsCopy.BatchesSent > 0 {
+		metricsCopy.AvgBatchSize = float64(metricsCopy.EventsSent) / float64(metricsCopy.BatchesSent)
+	}
+	return &metricsCopy
+}
+
+// HealthCheck fails once the output is closed
+func (m *MemoryOutput) HealthCheck(context.Context) error {
+	if m.Closed() {
+		return fmt.Errorf("output %s is closed", m.name)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestMemoryOutput(t *testing.T) {
+	out := NewMemoryOutput("memory")
+	ctx := context.Background()
+
+	failure := errors.New("scripted")
+	out.FailNext(failure, nil)
+	if err := out.Send(ctx, &types.LogEvent{Message: "first"}); !errors.Is(err, failure) {
+		t.Errorf("expected the scripted failure, got %v", err)
+	}
+	if err := out.SendBatch(ctx, []*types.LogEvent{{Message: "a"}, {Message: "b"}}); err != nil {
+		t.Errorf("SendBatch() error = %v", err)
+	}
+	if err := out.Send(ctx, &types.LogEvent{Message: "c"}); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+
+	if out.Calls() != 3 || len(out.Batches()) != 2 {
+		t.Errorf("expected 3 calls and 2 batches, got %d and %d", out.Calls(), len(out.Batches()))
+	}
+	if messages := out.Messages(); len(messages) != 3 || messages[0] != "a" || messages[2] != "c" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+	if m := out.Metrics(); m.EventsSent != 3 || m.EventsFailed != 1 || m.BatchesSent != 2 || m.LastError != "scripted" {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+
+	// Unscripted calls use the set error
+	out.SetError(failure)
+	if err := out.Send(ctx, &types.LogEvent{}); !errors.Is(err, failure) {
+		t.Errorf("expected the set error, got %v", err)
+	}
+
+	out.Reset()
+	if out.Calls() != 0 || len(out.Events()) != 0 {
+		t.Errorf("expected nothing recorded after Reset, got %d calls", out.Calls())
+	}
+
+	out.Close()
+	if err := out.Send(ctx, &types.LogEvent{}); !errors.Is(err, ErrPermanent) {
+		t.Errorf("expected ErrPermanent once closed, got %v", err)
+	}
+	if out.HealthCheck(ctx) == nil {
+		t.Error("expected a closed output to be unhealthy")
+	}
+}
+
+func TestMemoryOutput_Latency(t *testing.T) {
+	out := NewMemoryOutput("slow")
+	out.SetLatency(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out.Send(context.Background(), &types.LogEvent{})
+		}()
+	}
+	wg.Wait()
+	if out.MaxInFlight() < 2 || len(out.Events()) != 4 {
+		t.Errorf("expected concurrent calls recording 4 events, got %d in flight and %d events", out.MaxInFlight(), len(out.Events()))
+	}
+
+	// A call gives up with its context
+	out.SetLatency(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := out.Send(ctx, &types.LogEvent{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline exceeded, got %v", err)
+	}
+}
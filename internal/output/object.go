@@ -0,0 +1,551 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
+	logtypes "github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// ObjectConfig contains the configuration shared by object storage outputs
+type ObjectConfig struct {
+	// Prefix is the key prefix for objects
+	Prefix string `yaml:"prefix,omitempty"`
+
+	// KeyTemplate is the template for object keys (supports time patterns
+	// and event fields, e.g. {{.Fields.service}})
+	KeyTemplate string `yaml:"key_template,omitempty"`
+
+	// UploadConcurrency is the number of concurrent uploads
+	UploadConcurrency int `yaml:"upload_concurrency,omitempty"`
+
+	// SplitByKey splits each batch into one object per resolved key, so
+	// events land under the hour and fields of their own key rather than
+	// those of the batch's first event
+	SplitByKey bool `yaml:"split_by_key,omitempty"`
+
+	// ContentType for uploaded objects; defaults to the serializer's
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// DefaultObjectConfig returns the default object key and upload settings
+func DefaultObjectConfig() ObjectConfig {
+	return ObjectConfig{
+		Prefix:            "logs/",
+		KeyTemplate:       "{{.Year}}/{{.Month}}/{{.Day}}/{{.Hour}}/{{.Timestamp}}.json",
+		UploadConcurrency: 5,
+	}
+}
+
+// objectUploader uploads data as the object named key
+type objectUploader func(ctx context.Context, key string, data []byte) error
+
+// objectWriter batches events into objects named by a key template and
+// uploads them with its uploader. It implements the sending, batching and
+// metrics of the object storage outputs, which only differ in how objects
+// are uploaded.
+type objectWriter struct {
+	outputType string
+	base       BaseConfig
+	object     ObjectConfig
+	upload     objectUploader
+	batcher    *Batcher
+	slots      sendSlots
+	metrics    *OutputMetrics
+	latency    LatencyHistogram
+	compressor Compressor
+	serializer Serializer
+	mu         sync.RWMutex
+	closed     atomic.Bool
+
+	instrumentation
+	flushErrorHook
+}
+
+// newObjectWriter creates a writer of objects uploaded with upload
+func newObjectWriter(outputType string, base BaseConfig, object ObjectConfig, upload objectUploader) (*objectWriter, error) {
+	if err := validateKeyTemplate(object.KeyTemplate); err != nil {
+		return nil, err
+	}
+
+	// Get compressor
+	compressor, err := GetCompressor(base.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer, err := NewSerializer(base)
+	if err != nil {
+		return nil, err
+	}
+	if object.ContentType == "" {
+		object.ContentType = serializer.ContentType()
+	}
+
+	w := &objectWriter{
+		outputType: outputType,
+		base:       base,
+		object:     object,
+		upload:     upload,
+		metrics:    &OutputMetrics{},
+		slots:      newSendSlots(base.MaxConcurrentBatches),
+		compressor: compressor,
+		serializer: serializer,
+	}
+
+	// Create batcher
+	if base.BatchSize > 1 {
+		w.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  base.BatchSize,
+			MaxBatchBytes: 100 * 1024 * 1024, // 100MB
+			FlushInterval: base.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				w.observeFlush(w.Name(), outputType, trigger)
+			},
+			Adaptive: base.AdaptiveBatch,
+			Ordered:  base.Ordered,
+			Metrics:  w.Metrics,
+			OnResize: func(size int) {
+				w.observeBatchSize(w.Name(), outputType, size)
+			},
+			OnFlushError: w.flushFailed,
+		}, w.sendBatchInternal)
+	}
+
+	return w, nil
+}
+
+// Send sends a single event
+func (w *objectWriter) Send(ctx context.Context, event *logtypes.LogEvent) error {
+	if w.closed.Load() {
+		return fmt.Errorf("%s output is closed", w.outputType)
+	}
+
+	// Use batcher if configured
+	if w.batcher != nil {
+		return w.batcher.Add(ctx, event)
+	}
+
+	return w.sendSingle(ctx, event)
+}
+
+// SendBatch sends a batch of events
+func (w *objectWriter) SendBatch(ctx context.Context, events []*logtypes.LogEvent) error {
+	if w.closed.Load() {
+		return fmt.Errorf("%s output is closed", w.outputType)
+	}
+
+	return w.sendBatchInternal(ctx, events)
+}
+
+// sendSingle sends a single event as a separate object
+func (w *objectWriter) sendSingle(ctx context.Context, event *logtypes.LogEvent) error {
+	if err := checkKeyFields(event, keyFields(w.object.KeyTemplate)); err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, 1)
+		return err
+	}
+	key := w.generateKey(event)
+
+	// Serialize event
+	data, err := w.serializer.Serialize(event)
+	if err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, 1)
+		w.metrics.LastError = err.Error()
+		w.metrics.LastErrorTime = time.Now()
+		return classifyf(ErrSerialization, "failed to serialize event: %w", err)
+	}
+
+	// Compress if needed
+	data, err = w.compressor.Compress(data)
+	if err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, 1)
+		w.metrics.LastError = err.Error()
+		w.metrics.LastErrorTime = time.Now()
+		return classifyf(ErrSerialization, "failed to compress data: %w", err)
+	}
+
+	// Upload the object
+	startTime := time.Now()
+	err = w.upload(ctx, key, data)
+	latency := time.Since(startTime)
+
+	if err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, 1)
+		w.metrics.LastError = err.Error()
+		w.metrics.LastErrorTime = time.Now()
+		return err
+	}
+
+	// Update metrics
+	atomic.AddInt64(&w.metrics.EventsSent, 1)
+	atomic.AddInt64(&w.metrics.BytesSent, int64(len(data)))
+	w.metrics.LastSendTime = time.Now()
+
+	// Record latency
+	w.mu.Lock()
+	w.latency.Record(latency)
+	w.mu.Unlock()
+
+	return nil
+}
+
+// sendBatchInternal sends a batch of events as one object per partition:
+// the event field values the key template interpolates or, with
+// SplitByKey, the whole resolved key. Partitions are uploaded in parallel,
+// up to UploadConcurrency at a time, and the batch fails if any upload
+// fails, so a retried batch uploads the other partitions again.
+func (w *objectWriter) sendBatchInternal(ctx context.Context, events []*logtypes.LogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	done, err := w.slots.begin(ctx, &w.instrumentation, w.Name(), w.outputType)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	fields := keyFields(w.object.KeyTemplate)
+	if err := checkKeyFields(events[0], fields); err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, int64(len(events)))
+		return err
+	}
+
+	var partitions [][]*logtypes.LogEvent
+	if w.object.SplitByKey {
+		partitions = w.partitionByKey(events)
+	} else {
+		partitions = partitionEvents(events, fields)
+	}
+
+	startTime := time.Now()
+
+	var (
+		wg      sync.WaitGroup
+		sent    atomic.Int64
+		size    atomic.Int64
+		errsMu  sync.Mutex
+		errs    []error
+		uploads = make(chan struct{}, max(w.object.UploadConcurrency, 1))
+	)
+	for _, partition := range partitions {
+		wg.Add(1)
+		uploads <- struct{}{}
+		go func() {
+			defer func() {
+				<-uploads
+				wg.Done()
+			}()
+
+			n, err := w.uploadBatch(ctx, partition)
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+				return
+			}
+			sent.Add(int64(len(partition)))
+			size.Add(int64(n))
+		}()
+	}
+	wg.Wait()
+	latency := time.Since(startTime)
+
+	// Update metrics for the batch as a whole
+	atomic.AddInt64(&w.metrics.EventsSent, sent.Load())
+	atomic.AddInt64(&w.metrics.BytesSent, size.Load())
+	if err := errors.Join(errs...); err != nil {
+		w.mu.Lock()
+		w.metrics.LastError = err.Error()
+		w.metrics.LastErrorTime = time.Now()
+		w.mu.Unlock()
+		return err
+	}
+	batches := atomic.AddInt64(&w.metrics.BatchesSent, 1)
+	w.observeBatch(w.Name(), w.outputType, len(events), size.Load(), latency)
+
+	// Update average batch size and record latency. Batches may be sent
+	// concurrently, up to MaxConcurrentBatches.
+	w.mu.Lock()
+	w.metrics.LastSendTime = time.Now()
+	w.metrics.AvgBatchSize = float64(atomic.LoadInt64(&w.metrics.EventsSent)) / float64(batches)
+	w.latency.Record(latency)
+	w.mu.Unlock()
+
+	return nil
+}
+
+// uploadBatch sends events as a single object, keyed by the first event,
+// and returns the size of the upload. Events that fail to serialize are
+// skipped and counted as failed, as is the whole partition if the upload
+// fails.
+func (w *objectWriter) uploadBatch(ctx context.Context, events []*logtypes.LogEvent) (int, error) {
+	key := w.generateKey(events[0])
+
+	// Serialize events back to back; JSON events are newline-delimited
+	buf := pool.GetBatchBuffer()
+	separator := recordSeparator(w.serializer)
+	for _, event := range events {
+		data, err := w.serializer.Serialize(event)
+		if err != nil {
+			atomic.AddInt64(&w.metrics.EventsFailed, 1)
+			continue
+		}
+		buf.Write(data)
+		buf.Write(separator)
+	}
+
+	data := buf.Bytes()
+
+	// Compress if needed
+	compressed, err := w.compressor.Compress(data)
+	if err != nil {
+		pool.PutBatchBuffer(buf)
+		atomic.AddInt64(&w.metrics.EventsFailed, int64(len(events)))
+		return 0, classifyf(ErrSerialization, "failed to compress data: %w", err)
+	}
+
+	// Upload the object. Without compression the upload reads the pooled
+	// buffer itself, so the buffer is only reused after a successful
+	// upload; after a failure the transport may still be reading it.
+	if err := w.upload(ctx, key, compressed); err != nil {
+		atomic.AddInt64(&w.metrics.EventsFailed, int64(len(events)))
+		return 0, err
+	}
+	n := len(compressed)
+	pool.PutBatchBuffer(buf)
+
+	return n, nil
+}
+
+// generateKey generates an object key from a template and an event's
+// timestamp and fields
+func (w *objectWriter) generateKey(event *logtypes.LogEvent) string {
+	template := w.object.KeyTemplate
+	if template == "" {
+		template = "{{.Timestamp}}.json"
+	}
+	key := expandKeyTemplate(template, event)
+
+	// Add prefix
+	if w.object.Prefix != "" {
+		key = w.object.Prefix + key
+	}
+
+	// Add compression extension
+	key += w.compressor.Extension()
+
+	return key
+}
+
+// partitionByKey groups events by their resolved object key, keeping the
+// order of events within a partition and of partitions by first event. The
+// {{.Timestamp}} and {{.UnixNano}} placeholders name an object rather than
+// partition it, so they take the value of the partition's first event.
+func (w *objectWriter) partitionByKey(events []*logtypes.LogEvent) [][]*logtypes.LogEvent {
+	template := strings.NewReplacer("{{.Timestamp}}", "", "{{.UnixNano}}", "").Replace(w.object.KeyTemplate)
+
+	var partitions [][]*logtypes.LogEvent
+	index := make(map[string]int)
+	for _, event := range events {
+		key := expandKeyTemplate(template, event)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(partitions)
+			index[key] = i
+			partitions = append(partitions, nil)
+		}
+		partitions[i] = append(partitions[i], event)
+	}
+	return partitions
+}
+
+// expandKeyTemplate replaces the placeholders of a key template with an
+// event's timestamp, or the current time if it has none, and sanitized
+// field values
+func expandKeyTemplate(template string, event *logtypes.LogEvent) string {
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	// Replace template variables
+	replacements := map[string]string{
+		"{{.Year}}":      fmt.Sprintf("%04d", timestamp.Year()),
+		"{{.Month}}":     fmt.Sprintf("%02d", timestamp.Month()),
+		"{{.Day}}":       fmt.Sprintf("%02d", timestamp.Day()),
+		"{{.Hour}}":      fmt.Sprintf("%02d", timestamp.Hour()),
+		"{{.Minute}}":    fmt.Sprintf("%02d", timestamp.Minute()),
+		"{{.Second}}":    fmt.Sprintf("%02d", timestamp.Second()),
+		"{{.Timestamp}}": fmt.Sprintf("%d", timestamp.Unix()),
+		"{{.UnixNano}}":  fmt.Sprintf("%d", timestamp.UnixNano()),
+	}
+
+	key := template
+	for placeholder, value := range replacements {
+		key = strings.ReplaceAll(key, placeholder, value)
+	}
+	return keyFieldPattern.ReplaceAllStringFunc(key, func(placeholder string) string {
+		name := keyFieldPattern.FindStringSubmatch(placeholder)[1]
+		return sanitizeKeySegment(event.Fields[name])
+	})
+}
+
+// keyFieldPattern matches the event field placeholders of a key template
+var keyFieldPattern = regexp.MustCompile(`\{\{\.Fields\.([A-Za-z0-9_.-]+)\}\}`)
+
+// keyTimePlaceholders are the time placeholders of a key template
+var keyTimePlaceholders = []string{
+	"{{.Year}}", "{{.Month}}", "{{.Day}}", "{{.Hour}}", "{{.Minute}}", "{{.Second}}", "{{.Timestamp}}", "{{.UnixNano}}",
+}
+
+// validateKeyTemplate checks that a key template only uses known
+// placeholders
+func validateKeyTemplate(template string) error {
+	rest := keyFieldPattern.ReplaceAllString(template, "")
+	for _, placeholder := range keyTimePlaceholders {
+		rest = strings.ReplaceAll(rest, placeholder, "")
+	}
+	if start := strings.Index(rest, "{{"); start >= 0 {
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return fmt.Errorf("unterminated placeholder in key template %q", template)
+		}
+		return fmt.Errorf("unknown placeholder %s in key template %q", rest[start:start+end+2], template)
+	}
+	return nil
+}
+
+// keyFields returns the event fields a key template interpolates, in order
+// of first use
+func keyFields(template string) []string {
+	var fields []string
+	for _, match := range keyFieldPattern.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(fields, match[1]) {
+			fields = append(fields, match[1])
+		}
+	}
+	return fields
+}
+
+// checkKeyFields returns an error if event lacks one of the key template's
+// fields. Batches are only checked on their first event; later events
+// missing a field are keyed with "unknown".
+func checkKeyFields(event *logtypes.LogEvent, fields []string) error {
+	for _, name := range fields {
+		if _, ok := event.Fields[name]; !ok {
+			return classifyf(ErrSerialization, "event has no field %q for the object key template", name)
+		}
+	}
+	return nil
+}
+
+// partitionEvents groups events by the sanitized values of fields, keeping
+// the order of events within a partition and of partitions by first event
+func partitionEvents(events []*logtypes.LogEvent, fields []string) [][]*logtypes.LogEvent {
+	if len(fields) == 0 {
+		return [][]*logtypes.LogEvent{events}
+	}
+
+	var partitions [][]*logtypes.LogEvent
+	index := make(map[string]int)
+	values := make([]string, len(fields))
+	for _, event := range events {
+		for i, name := range fields {
+			values[i] = sanitizeKeySegment(event.Fields[name])
+		}
+		// Sanitized values contain no "/", so it separates them unambiguously
+		partition := strings.Join(values, "/")
+
+		i, ok := index[partition]
+		if !ok {
+			i = len(partitions)
+			index[partition] = i
+			partitions = append(partitions, nil)
+		}
+		partitions[i] = append(partitions[i], event)
+	}
+	return partitions
+}
+
+// sanitizeKeySegment maps a field value to a single object key segment.
+// Letters, digits, "-", "_" and "." are kept and any other character,
+// including "/", becomes "_"; empty values and the "." and ".." segments
+// become "unknown".
+func sanitizeKeySegment(value string) string {
+	if value == "" || value == "." || value == ".." {
+		return "unknown"
+	}
+
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// Flush sends any events buffered in the batcher
+func (w *objectWriter) Flush(ctx context.Context) error {
+	if w.batcher == nil {
+		return nil
+	}
+	return w.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (w *objectWriter) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if w.batcher == nil {
+		return false
+	}
+	w.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
+// Close stops the batcher, sending any buffered events
+func (w *objectWriter) Close() error {
+	if !w.closed.CompareAndSwap(false, true) {
+		return nil // Already closed
+	}
+
+	// Stop batcher first
+	if w.batcher != nil {
+		if err := w.batcher.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Name returns the output name
+func (w *objectWriter) Name() string {
+	if w.base.Name != "" {
+		return w.base.Name
+	}
+	return w.outputType
+}
+
+// Metrics returns the current metrics
+func (w *objectWriter) Metrics() *OutputMetrics {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	// Return a copy
+	metricsCopy := *w.metrics
+	w.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -23,6 +24,38 @@ type Output interface {
 
 	// Metrics returns the current metrics for this output
 	Metrics() *OutputMetrics
+
+	// HealthCheck verifies that the output destination is reachable
+	HealthCheck(ctx context.Context) error
+}
+
+// Flusher is implemented by outputs that buffer events before sending them
+type Flusher interface {
+	// Flush sends any buffered events
+	Flush(ctx context.Context) error
+}
+
+// BatchConfigurer is implemented by outputs whose batch settings can change
+// while they are running
+type BatchConfigurer interface {
+	// SetBatchConfig updates the batch size and flush interval, reporting
+	// whether the output batches events. Zero values keep the current setting.
+	SetBatchConfig(batchSize int, flushInterval time.Duration) bool
+}
+
+// FlushErrorReporter is implemented by outputs that flush batches in the
+// background, where no caller sees a failed flush
+type FlushErrorReporter interface {
+	// OnFlushError sets a function called with the events and error of each
+	// batch the output failed to flush in the background
+	OnFlushError(fn func(events []*types.LogEvent, err error))
+}
+
+// BreakerOutput is implemented by outputs that guard their sends with a
+// circuit breaker
+type BreakerOutput interface {
+	// CircuitBreaker returns the output's breaker, or nil when it is disabled
+	CircuitBreaker() *reliability.CircuitBreaker
 }
 
 // OutputMetrics tracks performance and health metrics for an output
@@ -37,6 +70,11 @@ type OutputMetrics struct {
 	LastErrorTime   time.Time     `json:"last_error_time,omitempty"`
 	AvgBatchSize    float64       `json:"avg_batch_size"`
 	AvgLatency      time.Duration `json:"avg_latency"`
+	P50Latency      time.Duration `json:"p50_latency"`
+	P90Latency      time.Duration `json:"p90_latency"`
+	P99Latency      time.Duration `json:"p99_latency"`
+	LatencyCount    int64         `json:"latency_count"`
+	LatencySum      time.Duration `json:"latency_sum"`
 }
 
 // CompressionType defines the compression algorithm to use
@@ -47,6 +85,7 @@ const (
 	CompressionGzip   CompressionType = "gzip"
 	CompressionSnappy CompressionType = "snappy"
 	CompressionLZ4    CompressionType = "lz4"
+	CompressionZstd   CompressionType = "zstd"
 )
 
 // BaseConfig contains common configuration for all outputs
@@ -63,9 +102,19 @@ type BaseConfig struct {
 	// BatchTimeout is the maximum time to wait before sending a partial batch
 	BatchTimeout time.Duration `yaml:"batch_timeout,omitempty"`
 
+	// AdaptiveBatch adjusts the batch size to the output's latency
+	AdaptiveBatch AdaptiveBatchConfig `yaml:"adaptive_batch,omitempty"`
+
 	// Compression specifies the compression algorithm
 	Compression CompressionType `yaml:"compression,omitempty"`
 
+	// Serialization selects how events are encoded (json, msgpack, avro)
+	Serialization SerializationConfig `yaml:"serialization,omitempty"`
+
+	// Schema renames the event fields to a target schema (ecs, gelf, or a
+	// custom rename map) when events are encoded
+	Schema SchemaConfig `yaml:"schema,omitempty"`
+
 	// FlushInterval is how often to flush buffered events
 	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
 
@@ -77,6 +126,18 @@ type BaseConfig struct {
 
 	// Timeout is the timeout for send operations
 	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// MaxConcurrentBatches bounds the batches sent to the destination at
+	// once; further sends and flushes wait for one to finish. 0 for no limit.
+	MaxConcurrentBatches int `yaml:"max_concurrent_batches,omitempty"`
+
+	// Ordered flushes one batch at a time, in the order its events were
+	// sent, holding further sends until the flush returns. Set by an
+	// ordered router.
+	Ordered bool `yaml:"ordered,omitempty"`
+
+	// Rate limits applied by the router (MaxEventsPerSec, MaxBytesPerSec)
+	RateLimitConfig `yaml:",inline"`
 }
 
 // DefaultBaseConfig returns a base config with sensible defaults
@@ -34,7 +34,8 @@ func TestCompressionTypes(t *testing.T) {
 		{"none", CompressionNone, false},
 		{"gzip", CompressionGzip, false},
 		{"snappy", CompressionSnappy, false},
-		{"lz4", CompressionLZ4, true}, // Not available in this build
+		{"lz4", CompressionLZ4, false},
+		{"zstd", CompressionZstd, false},
 		{"invalid", CompressionType("invalid"), true},
 	}
 
@@ -0,0 +1,180 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// RateLimitPolicy defines what happens to a send that exceeds a rate limit
+type RateLimitPolicy string
+
+const (
+	// RateLimitBlock waits until the send fits in the limits
+	RateLimitBlock RateLimitPolicy = "block"
+
+	// RateLimitReject fails the send with ErrTransient
+	RateLimitReject RateLimitPolicy = "reject"
+)
+
+// RateLimitConfig caps the throughput to an output's destination. Each
+// limit allows a burst of one second's worth.
+type RateLimitConfig struct {
+	// MaxEventsPerSec caps the events sent per second, 0 for no limit
+	MaxEventsPerSec float64 `yaml:"max_events_per_sec,omitempty"`
+
+	// MaxBytesPerSec caps the event bytes sent per second, 0 for no limit
+	MaxBytesPerSec int `yaml:"max_bytes_per_sec,omitempty"`
+
+	// RateLimitPolicy is block (default) or reject
+	RateLimitPolicy RateLimitPolicy `yaml:"rate_limit_policy,omitempty"`
+}
+
+// Enabled reports whether any limit is set
+func (c RateLimitConfig) Enabled() bool {
+	return c.MaxEventsPerSec > 0 || c.MaxBytesPerSec > 0
+}
+
+// RateLimiter wraps an output and limits the events and bytes sent to it
+// per second. Event sizes are the sizes of their raw lines.
+type RateLimiter struct {
+	Output
+
+	events *rate.Limiter // nil when events are not limited
+	bytes  *rate.Limiter // nil when bytes are not limited
+	reject bool
+}
+
+// NewRateLimiter wraps out with the limits of config
+func NewRateLimiter(out Output, config RateLimitConfig) (*RateLimiter, error) {
+	switch config.RateLimitPolicy {
+	case "", RateLimitBlock, RateLimitReject:
+	default:
+		return nil, fmt.Errorf("unsupported rate limit policy: %s", config.RateLimitPolicy)
+	}
+
+	r := &RateLimiter{Output: out, reject: config.RateLimitPolicy == RateLimitReject}
+	if config.MaxEventsPerSec > 0 {
+		r.events = rate.NewLimiter(rate.Limit(config.MaxEventsPerSec), int(math.Ceil(config.MaxEventsPerSec)))
+	}
+	if config.MaxBytesPerSec > 0 {
+		r.bytes = rate.NewLimiter(rate.Limit(config.MaxBytesPerSec), config.MaxBytesPerSec)
+	}
+	return r, nil
+}
+
+// Send sends an event once it fits in the limits
+func (r *RateLimiter) Send(ctx context.Context, event *types.LogEvent) error {
+	if err := r.acquire(ctx, 1, eventSize(event)); err != nil {
+		return err
+	}
+	return r.Output.Send(ctx, event)
+}
+
+// SendBatch sends a batch once it fits in the limits
+func (r *RateLimiter) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	var size int
+	for _, event := range events {
+		size += eventSize(event)
+	}
+
+	if err := r.acquire(ctx, len(events), size); err != nil {
+		return err
+	}
+	return r.Output.SendBatch(ctx, events)
+}
+
+// acquire takes events and bytes from the limiters, waiting for them or,
+// with the reject policy, failing if they are not available now
+func (r *RateLimiter) acquire(ctx context.Context, events, bytes int) error {
+	if r.reject {
+		now := time.Now()
+		if !available(r.events, events, now) || !available(r.bytes, bytes, now) {
+			return classifyf(ErrTransient, "rate limit of output %s exceeded", r.Name())
+		}
+		take(r.events, events, now)
+		take(r.bytes, bytes, now)
+		return nil
+	}
+
+	if err := wait(ctx, r.events, events); err != nil {
+		return err
+	}
+	return wait(ctx, r.bytes, bytes)
+}
+
+// available reports whether l has n tokens at now, or a full bucket when n
+// exceeds the burst
+func available(l *rate.Limiter, n int, now time.Time) bool {
+	return l == nil || l.TokensAt(now) >= float64(min(n, l.Burst()))
+}
+
+// take removes n tokens from l at now, going into debt beyond its tokens
+func take(l *rate.Limiter, n int, now time.Time) {
+	if l == nil {
+		return
+	}
+	for n > 0 {
+		chunk := min(n, l.Burst())
+		l.ReserveN(now, chunk)
+		n -= chunk
+	}
+}
+
+// wait blocks until l has n tokens, taking them in chunks of the burst
+func wait(ctx context.Context, l *rate.Limiter, n int) error {
+	if l == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := min(n, l.Burst())
+		if err := l.WaitN(ctx, chunk); err != nil {
+			return fmt.Errorf("rate limit wait aborted: %w", err)
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// eventSize returns the size of an event's raw line, or of its message
+// when the raw line is not kept
+func eventSize(event *types.LogEvent) int {
+	if len(event.Raw) > 0 {
+		return len(event.Raw)
+	}
+	return len(event.Message)
+}
+
+// Flush flushes the wrapped output if it buffers events
+func (r *RateLimiter) Flush(ctx context.Context) error {
+	if flusher, ok := r.Output.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// SetBatchConfig updates the batch settings of the wrapped output
+func (r *RateLimiter) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if configurer, ok := r.Output.(BatchConfigurer); ok {
+		return configurer.SetBatchConfig(batchSize, flushInterval)
+	}
+	return false
+}
+
+// OnFlushError sets the flush failure function of the wrapped output if it
+// flushes batches in the background
+func (r *RateLimiter) OnFlushError(fn func(events []*types.LogEvent, err error)) {
+	if reporter, ok := r.Output.(FlushErrorReporter); ok {
+		reporter.OnFlushError(fn)
+	}
+}
+
+// Unwrap returns the wrapped output
+func (r *RateLimiter) Unwrap() Output {
+	return r.Output
+}
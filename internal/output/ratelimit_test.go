@@ -0,0 +1,124 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// countingOutput counts the events sent to it
+type countingOutput struct {
+	stubOutput
+	events atomic.Int64
+}
+
+func (c *countingOutput) Send(context.Context, *types.LogEvent) error {
+	c.events.Add(1)
+	return nil
+}
+
+func (c *countingOutput) SendBatch(_ context.Context, events []*types.LogEvent) error {
+	c.events.Add(int64(len(events)))
+	return nil
+}
+
+func TestRateLimiter_BoundsEventRate(t *testing.T) {
+	out := &countingOutput{}
+	limiter, err := NewRateLimiter(out, RateLimitConfig{MaxEventsPerSec: 1000})
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+
+	// The first second's worth is a burst, the remaining 300 events take
+	// at least 300ms
+	start := time.Now()
+	for i := 0; i < 13; i++ {
+		if err := limiter.SendBatch(context.Background(), testBatch(100)); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	if out.events.Load() != 1300 {
+		t.Fatalf("expected 1300 events, got %d", out.events.Load())
+	}
+	if elapsed < 250*time.Millisecond {
+		t.Errorf("expected sends beyond the burst to be throttled, took %v", elapsed)
+	}
+	if rate := float64(300) / elapsed.Seconds(); rate > 1100 {
+		t.Errorf("expected at most 1000 events/sec beyond the burst, got %.0f", rate)
+	}
+}
+
+func TestRateLimiter_BoundsByteRate(t *testing.T) {
+	out := &countingOutput{}
+	limiter, err := NewRateLimiter(out, RateLimitConfig{MaxBytesPerSec: 10000})
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+
+	event := &types.LogEvent{Raw: strings.Repeat("x", 100)}
+	start := time.Now()
+	for i := 0; i < 130; i++ {
+		if err := limiter.Send(context.Background(), event); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected 13000 bytes at 10000 bytes/sec to be throttled, took %v", elapsed)
+	}
+}
+
+func TestRateLimiter_Reject(t *testing.T) {
+	out := &countingOutput{}
+	limiter, err := NewRateLimiter(out, RateLimitConfig{MaxEventsPerSec: 10, RateLimitPolicy: RateLimitReject})
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error = %v", err)
+	}
+
+	// A batch larger than the burst is sent when the bucket is full
+	if err := limiter.SendBatch(context.Background(), testBatch(15)); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	err = limiter.Send(context.Background(), &types.LogEvent{Message: "over"})
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("expected a transient error over the limit, got %v", err)
+	}
+	if out.events.Load() != 15 {
+		t.Errorf("expected 15 events sent, got %d", out.events.Load())
+	}
+}
+
+func TestRateLimiter_InvalidPolicy(t *testing.T) {
+	if _, err := NewRateLimiter(&countingOutput{}, RateLimitConfig{MaxEventsPerSec: 1, RateLimitPolicy: "drop"}); err == nil {
+		t.Error("expected an error for an unsupported policy")
+	}
+}
+
+func TestNewRouter_RateLimitedOutputs(t *testing.T) {
+	router, err := NewRouter(RouterConfig{Outputs: []OutputConfig{
+		{Type: "stub", Name: "limited", Config: map[string]interface{}{"max_events_per_sec": 50}},
+		{Type: "stub", Name: "unlimited"},
+	}})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	outputs := router.GetOutputs()
+	if _, ok := outputs[0].(*RateLimiter); !ok {
+		t.Errorf("expected the limited output to be wrapped, got %T", outputs[0])
+	}
+	if _, ok := outputs[1].(*RateLimiter); ok {
+		t.Error("expected the unlimited output not to be wrapped")
+	}
+	if outputs[0].Name() != "limited" {
+		t.Errorf("expected the wrapper to keep the output name, got %s", outputs[0].Name())
+	}
+}
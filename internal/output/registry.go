@@ -0,0 +1,82 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Factory creates an output from its generic configuration map
+type Factory func(cfg map[string]interface{}) (Output, error)
+
+var (
+	registryMu sync.RWMutex
+	factories  = make(map[string]Factory)
+)
+
+// Register makes an output type available to New. Outputs usually register
+// themselves from init. Register panics if the type is registered twice or
+// the factory is nil.
+func Register(typeName string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("output: Register factory is nil for " + typeName)
+	}
+	if _, dup := factories[typeName]; dup {
+		panic("output: Register called twice for " + typeName)
+	}
+	factories[typeName] = factory
+}
+
+// New creates an output of a registered type
+func New(typeName string, cfg map[string]interface{}) (Output, error) {
+	registryMu.RLock()
+	factory, ok := factories[typeName]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown output type: %s", typeName)
+	}
+
+	out, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s output: %w", typeName, err)
+	}
+	return out, nil
+}
+
+// Types returns the registered output types
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(factories))
+	for typeName := range factories {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// DecodeConfig decodes a generic configuration map into a concrete config
+// struct using its yaml tags. Fields missing from the map keep the values
+// already set in out, so callers can pass a struct holding the defaults.
+func DecodeConfig(cfg map[string]interface{}, out interface{}) error {
+	if len(cfg) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode output config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode output config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// fakeConfig is the configuration decoded for fakeOutput
+type fakeConfig struct {
+	BaseConfig `yaml:",inline"`
+	Endpoint   string `yaml:"endpoint"`
+	FailCreate bool   `yaml:"fail_create,omitempty"`
+}
+
+// fakeOutput records the configuration it was created with
+type fakeOutput struct {
+	config fakeConfig
+}
+
+func (f *fakeOutput) Send(context.Context, *types.LogEvent) error        { return nil }
+func (f *fakeOutput) SendBatch(context.Context, []*types.LogEvent) error { return nil }
+func (f *fakeOutput) Close() error                                       { return nil }
+func (f *fakeOutput) Name() string                                       { return f.config.Name }
+func (f *fakeOutput) Metrics() *OutputMetrics                            { return &OutputMetrics{} }
+func (f *fakeOutput) HealthCheck(context.Context) error                  { return nil }
+
+func init() {
+	Register("fake", func(cfg map[string]interface{}) (Output, error) {
+		config := fakeConfig{BaseConfig: DefaultBaseConfig()}
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		if config.FailCreate {
+			return nil, errors.New("create failed")
+		}
+		return &fakeOutput{config: config}, nil
+	})
+}
+
+func TestNew_RegisteredOutput(t *testing.T) {
+	out, err := New("fake", map[string]interface{}{
+		"name":          "fake-1",
+		"endpoint":      "http://localhost:1234",
+		"batch_size":    50,
+		"batch_timeout": "2s",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	fake := out.(*fakeOutput)
+	if fake.config.Name != "fake-1" || fake.config.Endpoint != "http://localhost:1234" {
+		t.Errorf("unexpected decoded config: %+v", fake.config)
+	}
+	if fake.config.BatchSize != 50 || fake.config.BatchTimeout != 2*time.Second {
+		t.Errorf("expected inline base config to be decoded, got %+v", fake.config.BaseConfig)
+	}
+	if fake.config.MaxRetries != DefaultBaseConfig().MaxRetries {
+		t.Errorf("expected defaults to be kept for missing keys, got %d retries", fake.config.MaxRetries)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New("missing", nil); err == nil || !strings.Contains(err.Error(), "unknown output type") {
+		t.Errorf("expected unknown type error, got %v", err)
+	}
+
+	if _, err := New("fake", map[string]interface{}{"fail_create": true}); err == nil {
+		t.Error("expected factory error to be returned")
+	}
+
+	if _, err := New("fake", map[string]interface{}{"batch_size": "many"}); err == nil {
+		t.Error("expected decode error for a mistyped field")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	Register("fake", func(map[string]interface{}) (Output, error) { return nil, nil })
+}
+
+func TestTypes_BuiltinOutputs(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, typeName := range Types() {
+		registered[typeName] = true
+	}
+
+	for _, typeName := range []string{"kafka", "elasticsearch", "s3", "gcs", "http", "loki", "console", "stdout", "gelf", "fake"} {
+		if !registered[typeName] {
+			t.Errorf("expected %s output to be registered", typeName)
+		}
+	}
+}
+
+func TestNewRouter_BuildsOutputs(t *testing.T) {
+	router, err := NewRouter(RouterConfig{Outputs: []OutputConfig{
+		{Type: "fake", Name: "primary", Config: map[string]interface{}{"endpoint": "a"}},
+		{Type: "fake", Config: map[string]interface{}{"name": "explicit", "endpoint": "b"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	if len(router.outputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %d", len(router.outputs))
+	}
+	if got := router.outputs[0].Name(); got != "primary" {
+		t.Errorf("expected wrapper name to be applied, got %q", got)
+	}
+	if got := router.outputs[1].Name(); got != "explicit" {
+		t.Errorf("expected config name to be kept, got %q", got)
+	}
+
+	if _, err := NewRouter(RouterConfig{Outputs: []OutputConfig{{Type: "missing"}}}); err == nil {
+		t.Error("expected error for an unregistered output type")
+	}
+}
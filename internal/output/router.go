@@ -2,11 +2,15 @@ package output
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -20,6 +24,20 @@ type RouterConfig struct {
 
 	// Parallel enables parallel sending to all outputs
 	Parallel bool `yaml:"parallel,omitempty"`
+
+	// MaxRetries is how many times a send failing with ErrTransient is
+	// retried before its events are dead-lettered. Batches are resent
+	// whole. Outputs retry on their own, so this defaults to 0.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// RetryBackoff is the initial backoff between retries
+	RetryBackoff time.Duration `yaml:"retry_backoff,omitempty"`
+
+	// Ordered sends one event or batch at a time, to one output after
+	// another, and makes each output flush one batch at a time, so every
+	// output receives events in the order they were sent. Parallel is
+	// ignored.
+	Ordered bool `yaml:"ordered,omitempty"`
 }
 
 // OutputConfig wraps an output with its specific configuration
@@ -38,13 +56,23 @@ func DefaultRouterConfig() RouterConfig {
 	}
 }
 
+// DeadLetterWriter receives events that an output failed to deliver
+type DeadLetterWriter interface {
+	Write(event *types.LogEvent, reason error, metadata map[string]string) error
+}
+
 // Router routes events to multiple outputs
 type Router struct {
-	config  RouterConfig
-	outputs []Output
-	metrics *RouterMetrics
-	mu      sync.RWMutex
-	closed  atomic.Bool
+	config      RouterConfig
+	outputs     []Output
+	outputTypes []string
+	deadLetter  DeadLetterWriter
+	allFailed   func(events []*types.LogEvent, err error)
+	retrier     *reliability.Retrier // nil when sends are not retried
+	metrics     *RouterMetrics
+	mu          sync.RWMutex
+	sendMu      sync.Mutex // serializes sends when ordered
+	closed      atomic.Bool
 }
 
 // RouterMetrics tracks aggregate metrics across all outputs
@@ -52,10 +80,12 @@ type RouterMetrics struct {
 	TotalEventsSent   int64           `json:"total_events_sent"`
 	TotalEventsFailed int64           `json:"total_events_failed"`
 	TotalBytesSent    int64           `json:"total_bytes_sent"`
+	TotalUndelivered  int64           `json:"total_undelivered"`
 	OutputMetrics     []*OutputMetrics `json:"output_metrics"`
 }
 
-// NewRouter creates a new multi-output router
+// NewRouter creates a new multi-output router, constructing each configured
+// output through the output registry
 func NewRouter(config RouterConfig) (*Router, error) {
 	if len(config.Outputs) == 0 {
 		return nil, fmt.Errorf("no outputs configured")
@@ -69,16 +99,221 @@ func NewRouter(config RouterConfig) (*Router, error) {
 		},
 	}
 
+	if config.MaxRetries > 0 {
+		router.retrier = reliability.NewRetrier(reliability.RetryConfig{
+			MaxRetries:     config.MaxRetries,
+			InitialBackoff: config.RetryBackoff,
+			Jitter:         true,
+		})
+	}
+
+	for _, oc := range config.Outputs {
+		out, err := newRoutedOutput(oc, config.Ordered)
+		if err != nil {
+			router.Close()
+			return nil, err
+		}
+		router.addOutput(out, oc.Type)
+	}
+
 	return router, nil
 }
 
+// newRoutedOutput creates a configured output, wrapped in a RateLimiter when
+// it has rate limits. Outputs of an ordered router flush in order.
+func newRoutedOutput(oc OutputConfig, ordered bool) (Output, error) {
+	settings := oc.settings()
+	if ordered {
+		settings["ordered"] = true
+	}
+
+	var limits RateLimitConfig
+	if err := DecodeConfig(settings, &limits); err != nil {
+		return nil, fmt.Errorf("failed to create %s output: %w", oc.Type, err)
+	}
+
+	out, err := New(oc.Type, settings)
+	if err != nil || !limits.Enabled() {
+		return out, err
+	}
+
+	limited, err := NewRateLimiter(out, limits)
+	if err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to create %s output: %w", oc.Type, err)
+	}
+	return limited, nil
+}
+
+// settings returns the output's config map with the wrapper name applied
+func (oc OutputConfig) settings() map[string]interface{} {
+	settings := make(map[string]interface{}, len(oc.Config)+1)
+	for k, v := range oc.Config {
+		settings[k] = v
+	}
+	if _, ok := settings["name"]; !ok && oc.Name != "" {
+		settings["name"] = oc.Name
+	}
+	return settings
+}
+
 // AddOutput adds an output to the router
 func (r *Router) AddOutput(output Output) {
+	r.addOutput(output, "custom")
+}
+
+// addOutput adds an output along with the type reported in its spans
+func (r *Router) addOutput(output Output, outputType string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	r.outputs = append(r.outputs, output)
+	r.outputTypes = append(r.outputTypes, outputType)
 	r.metrics.OutputMetrics = append(r.metrics.OutputMetrics, output.Metrics())
+
+	// Batches flushed in the background fail where no send sees the error
+	if reporter, ok := output.(FlushErrorReporter); ok {
+		reporter.OnFlushError(func(events []*types.LogEvent, err error) {
+			r.flushFailed(output, outputType, events, err)
+		})
+	}
+}
+
+// SetDeadLetter sets where events are written when an output fails to send
+// them. Transient failures are written once the router's retries are
+// exhausted, all other failures right away.
+func (r *Router) SetDeadLetter(w DeadLetterWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deadLetter = w
+}
+
+// OnAllFailed sets a function called with the events no output took, along
+// with the outputs' errors, whatever the failure strategy. Their events are
+// dead-lettered as well.
+func (r *Router) OnAllFailed(fn func(events []*types.LogEvent, err error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.allFailed = fn
+}
+
+// outputFailure is a failed send to one output
+type outputFailure struct {
+	out        Output
+	outputType string
+	err        error
+}
+
+// handleFailures dead-letters the events of each failed send. When no output
+// took the events, the dead letters are marked all_outputs_failed, the
+// events are counted as undelivered and the OnAllFailed function is called.
+func (r *Router) handleFailures(events []*types.LogEvent, failures []outputFailure, delivered bool) {
+	if len(failures) == 0 {
+		return
+	}
+
+	allFailed := !delivered
+	for _, failure := range failures {
+		r.writeDeadLetter(failure.out, failure.outputType, events, failure.err, allFailed)
+	}
+	if !allFailed {
+		return
+	}
+
+	atomic.AddInt64(&r.metrics.TotalUndelivered, int64(len(events)))
+	metrics.GetGlobalCollector().RouterEventsUndelivered.Add(float64(len(events)))
+
+	r.mu.RLock()
+	fn := r.allFailed
+	r.mu.RUnlock()
+	if fn != nil {
+		errs := make([]error, len(failures))
+		for i, failure := range failures {
+			errs[i] = fmt.Errorf("%s: %w", failure.out.Name(), failure.err)
+		}
+		fn(events, errors.Join(errs...))
+	}
+}
+
+// flushFailed handles a batch an output failed to flush in the background.
+// The router cannot tell whether its other outputs took the batch's events
+// when they were sent, so they count as undelivered only when it has no
+// other outputs.
+func (r *Router) flushFailed(out Output, outputType string, events []*types.LogEvent, err error) {
+	outputs, _ := r.snapshot()
+	failures := []outputFailure{{out: out, outputType: outputType, err: err}}
+	r.handleFailures(events, failures, len(outputs) > 1)
+}
+
+// writeDeadLetter hands events an output failed to send to the dead letter
+// writer, if one is set
+func (r *Router) writeDeadLetter(out Output, outputType string, events []*types.LogEvent, reason error, allFailed bool) {
+	r.mu.RLock()
+	w := r.deadLetter
+	r.mu.RUnlock()
+
+	if w == nil {
+		return
+	}
+
+	metadata := map[string]string{
+		"output":      out.Name(),
+		"output_type": outputType,
+		"error_class": errorClassName(reason),
+	}
+	if allFailed {
+		metadata["all_outputs_failed"] = "true"
+	}
+	for _, event := range events {
+		_ = w.Write(event, reason, metadata)
+	}
+}
+
+// snapshot returns the current outputs and their types
+func (r *Router) snapshot() ([]Output, []string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outputs, r.outputTypes
+}
+
+// retry calls send, retrying it while it fails with ErrTransient if the
+// router retries sends
+func (r *Router) retry(ctx context.Context, send func() error) error {
+	if r.retrier == nil {
+		return send()
+	}
+
+	err := r.retrier.Do(ctx, func() error {
+		err := send()
+		if err != nil && !IsRetryable(err) {
+			return reliability.Permanent(err)
+		}
+		return err
+	})
+	// Exhausted retries are reported without the class of the last error
+	if errors.Is(err, reliability.ErrMaxRetriesExceeded) || errors.Is(err, reliability.ErrRetryAborted) {
+		return classify(ErrTransient, err)
+	}
+	return err
+}
+
+// sendTo sends an event to one output inside an output.send span that is
+// part of the event's trace
+func (r *Router) sendTo(ctx context.Context, out Output, outputType string, event *types.LogEvent) error {
+	ctx, span := tracing.TraceOutput(tracing.WithEventSpan(ctx, event), tracing.Tracer(), out.Name(), outputType, 1)
+	err := r.retry(ctx, func() error { return out.Send(ctx, event) })
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// sendBatchTo sends a batch to one output inside an output.send span
+func (r *Router) sendBatchTo(ctx context.Context, out Output, outputType string, events []*types.LogEvent) error {
+	ctx, span := tracing.TraceOutput(ctx, tracing.Tracer(), out.Name(), outputType, len(events))
+	err := r.retry(ctx, func() error { return out.SendBatch(ctx, events) })
+	tracing.EndSpan(span, err)
+	return err
 }
 
 // Send sends an event to all configured outputs
@@ -91,6 +326,12 @@ func (r *Router) Send(ctx context.Context, event *types.LogEvent) error {
 		return fmt.Errorf("no outputs available")
 	}
 
+	if r.config.Ordered {
+		r.sendMu.Lock()
+		defer r.sendMu.Unlock()
+		return r.sendSequential(ctx, event)
+	}
+
 	if r.config.Parallel {
 		return r.sendParallel(ctx, event)
 	}
@@ -108,6 +349,12 @@ func (r *Router) SendBatch(ctx context.Context, events []*types.LogEvent) error
 		return fmt.Errorf("no outputs available")
 	}
 
+	if r.config.Ordered {
+		r.sendMu.Lock()
+		defer r.sendMu.Unlock()
+		return r.sendBatchSequential(ctx, events)
+	}
+
 	if r.config.Parallel {
 		return r.sendBatchParallel(ctx, events)
 	}
@@ -117,30 +364,30 @@ func (r *Router) SendBatch(ctx context.Context, events []*types.LogEvent) error
 
 // sendParallel sends an event to all outputs in parallel
 func (r *Router) sendParallel(ctx context.Context, event *types.LogEvent) error {
-	r.mu.RLock()
-	outputs := r.outputs
-	r.mu.RUnlock()
+	outputs, outputTypes := r.snapshot()
 
 	var wg sync.WaitGroup
-	errors := make(chan error, len(outputs))
+	failed := make(chan outputFailure, len(outputs))
 
-	for _, output := range outputs {
+	for i, output := range outputs {
 		wg.Add(1)
-		go func(out Output) {
+		go func(out Output, outputType string) {
 			defer wg.Done()
-			if err := out.Send(ctx, event); err != nil {
-				errors <- fmt.Errorf("%s: %w", out.Name(), err)
+			if err := r.sendTo(ctx, out, outputType, event); err != nil {
+				failed <- outputFailure{out: out, outputType: outputType, err: err}
 			}
-		}(output)
+		}(output, outputTypes[i])
 	}
 
 	wg.Wait()
-	close(errors)
+	close(failed)
 
 	// Collect errors
+	var failures []outputFailure
 	var errs []error
-	for err := range errors {
-		errs = append(errs, err)
+	for failure := range failed {
+		failures = append(failures, failure)
+		errs = append(errs, fmt.Errorf("%s: %w", failure.out.Name(), failure.err))
 		atomic.AddInt64(&r.metrics.TotalEventsFailed, 1)
 	}
 
@@ -148,6 +395,7 @@ func (r *Router) sendParallel(ctx context.Context, event *types.LogEvent) error
 	successCount := int64(len(outputs)) - int64(len(errs))
 	atomic.AddInt64(&r.metrics.TotalEventsSent, successCount)
 	atomic.AddInt64(&r.metrics.TotalBytesSent, int64(len(event.Raw))*successCount)
+	r.handleFailures([]*types.LogEvent{event}, failures, successCount > 0)
 
 	if len(errs) > 0 {
 		if r.config.FailureStrategy == "stop" {
@@ -161,60 +409,59 @@ func (r *Router) sendParallel(ctx context.Context, event *types.LogEvent) error
 
 // sendSequential sends an event to all outputs sequentially
 func (r *Router) sendSequential(ctx context.Context, event *types.LogEvent) error {
-	r.mu.RLock()
-	outputs := r.outputs
-	r.mu.RUnlock()
+	outputs, outputTypes := r.snapshot()
+	events := []*types.LogEvent{event}
 
-	var errs []error
+	var failures []outputFailure
+	delivered := false
 
-	for _, output := range outputs {
-		if err := output.Send(ctx, event); err != nil {
-			errs = append(errs, fmt.Errorf("%s: %w", output.Name(), err))
+	for i, output := range outputs {
+		if err := r.sendTo(ctx, output, outputTypes[i], event); err != nil {
+			failures = append(failures, outputFailure{out: output, outputType: outputTypes[i], err: err})
 			atomic.AddInt64(&r.metrics.TotalEventsFailed, 1)
 
 			if r.config.FailureStrategy == "stop" {
+				r.handleFailures(events, failures, delivered)
 				return fmt.Errorf("failed to send to output %s: %w", output.Name(), err)
 			}
 		} else {
+			delivered = true
 			atomic.AddInt64(&r.metrics.TotalEventsSent, 1)
 			atomic.AddInt64(&r.metrics.TotalBytesSent, int64(len(event.Raw)))
 		}
 	}
 
-	if len(errs) > 0 && r.config.FailureStrategy == "continue" {
-		// Log errors but don't fail
-		return nil
-	}
-
+	// Continue strategy - failures are dead-lettered but don't fail
+	r.handleFailures(events, failures, delivered)
 	return nil
 }
 
 // sendBatchParallel sends a batch to all outputs in parallel
 func (r *Router) sendBatchParallel(ctx context.Context, events []*types.LogEvent) error {
-	r.mu.RLock()
-	outputs := r.outputs
-	r.mu.RUnlock()
+	outputs, outputTypes := r.snapshot()
 
 	var wg sync.WaitGroup
-	errors := make(chan error, len(outputs))
+	failed := make(chan outputFailure, len(outputs))
 
-	for _, output := range outputs {
+	for i, output := range outputs {
 		wg.Add(1)
-		go func(out Output) {
+		go func(out Output, outputType string) {
 			defer wg.Done()
-			if err := out.SendBatch(ctx, events); err != nil {
-				errors <- fmt.Errorf("%s: %w", out.Name(), err)
+			if err := r.sendBatchTo(ctx, out, outputType, events); err != nil {
+				failed <- outputFailure{out: out, outputType: outputType, err: err}
 			}
-		}(output)
+		}(output, outputTypes[i])
 	}
 
 	wg.Wait()
-	close(errors)
+	close(failed)
 
 	// Collect errors
+	var failures []outputFailure
 	var errs []error
-	for err := range errors {
-		errs = append(errs, err)
+	for failure := range failed {
+		failures = append(failures, failure)
+		errs = append(errs, fmt.Errorf("%s: %w", failure.out.Name(), failure.err))
 		atomic.AddInt64(&r.metrics.TotalEventsFailed, int64(len(events)))
 	}
 
@@ -226,6 +473,7 @@ func (r *Router) sendBatchParallel(ctx context.Context, events []*types.LogEvent
 	}
 	atomic.AddInt64(&r.metrics.TotalEventsSent, int64(len(events))*successCount)
 	atomic.AddInt64(&r.metrics.TotalBytesSent, totalBytes*successCount)
+	r.handleFailures(events, failures, successCount > 0)
 
 	if len(errs) > 0 {
 		if r.config.FailureStrategy == "stop" {
@@ -238,37 +486,72 @@ func (r *Router) sendBatchParallel(ctx context.Context, events []*types.LogEvent
 
 // sendBatchSequential sends a batch to all outputs sequentially
 func (r *Router) sendBatchSequential(ctx context.Context, events []*types.LogEvent) error {
-	r.mu.RLock()
-	outputs := r.outputs
-	r.mu.RUnlock()
+	outputs, outputTypes := r.snapshot()
 
-	var errs []error
+	var failures []outputFailure
+	delivered := false
 	var totalBytes int64
 	for _, event := range events {
 		totalBytes += int64(len(event.Raw))
 	}
 
-	for _, output := range outputs {
-		if err := output.SendBatch(ctx, events); err != nil {
-			errs = append(errs, fmt.Errorf("%s: %w", output.Name(), err))
+	for i, output := range outputs {
+		if err := r.sendBatchTo(ctx, output, outputTypes[i], events); err != nil {
+			failures = append(failures, outputFailure{out: output, outputType: outputTypes[i], err: err})
 			atomic.AddInt64(&r.metrics.TotalEventsFailed, int64(len(events)))
 
 			if r.config.FailureStrategy == "stop" {
+				r.handleFailures(events, failures, delivered)
 				return fmt.Errorf("failed to send to output %s: %w", output.Name(), err)
 			}
 		} else {
+			delivered = true
 			atomic.AddInt64(&r.metrics.TotalEventsSent, int64(len(events)))
 			atomic.AddInt64(&r.metrics.TotalBytesSent, totalBytes)
 		}
 	}
 
-	if len(errs) > 0 && r.config.FailureStrategy == "continue" {
-		return nil
+	r.handleFailures(events, failures, delivered)
+	return nil
+}
+
+// Flush flushes every output that buffers events
+func (r *Router) Flush(ctx context.Context) error {
+	outputs, _ := r.snapshot()
+
+	var errs []error
+	for _, output := range outputs {
+		if flusher, ok := output.(Flusher); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", output.Name(), err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to flush %d outputs: %v", len(errs), errs)
 	}
 
 	return nil
 }
 
+// SetBatchConfig updates the batch settings of the named output, reporting
+// whether it batches events
+func (r *Router) SetBatchConfig(name string, batchSize int, flushInterval time.Duration) bool {
+	outputs, _ := r.snapshot()
+
+	for _, output := range outputs {
+		if output.Name() != name {
+			continue
+		}
+		if configurer, ok := output.(BatchConfigurer); ok {
+			return configurer.SetBatchConfig(batchSize, flushInterval)
+		}
+	}
+
+	return false
+}
+
 // Close closes all outputs
 func (r *Router) Close() error {
 	if !r.closed.CompareAndSwap(false, true) {
@@ -293,6 +576,30 @@ func (r *Router) Close() error {
 	return nil
 }
 
+// HealthCheck checks all outputs and fails only when none are reachable
+func (r *Router) HealthCheck(ctx context.Context) error {
+	r.mu.RLock()
+	outputs := r.outputs
+	r.mu.RUnlock()
+
+	if len(outputs) == 0 {
+		return fmt.Errorf("no outputs available")
+	}
+
+	var errs []error
+	for _, output := range outputs {
+		if err := output.HealthCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", output.Name(), err))
+		}
+	}
+
+	if len(errs) == len(outputs) {
+		return fmt.Errorf("all %d outputs unreachable: %v", len(errs), errs)
+	}
+
+	return nil
+}
+
 // Name returns the router name
 func (r *Router) Name() string {
 	return "router"
@@ -304,8 +611,8 @@ func (r *Router) Metrics() *OutputMetrics {
 	defer r.mu.RUnlock()
 
 	// Aggregate metrics from all outputs
-	var totalSent, totalFailed, totalBytes, totalBatches int64
-	var totalLatency time.Duration
+	var totalSent, totalFailed, totalBytes, totalBatches, latencyCount int64
+	var latencySum, p50, p90, p99 time.Duration
 	var totalBatchSize float64
 	var lastSendTime, lastErrorTime time.Time
 	var lastError string
@@ -316,9 +623,15 @@ func (r *Router) Metrics() *OutputMetrics {
 		totalFailed += metrics.EventsFailed
 		totalBytes += metrics.BytesSent
 		totalBatches += metrics.BatchesSent
-		totalLatency += metrics.AvgLatency
+		latencyCount += metrics.LatencyCount
+		latencySum += metrics.LatencySum
 		totalBatchSize += metrics.AvgBatchSize
 
+		// Percentiles cannot be merged exactly, so report the slowest output
+		p50 = max(p50, metrics.P50Latency)
+		p90 = max(p90, metrics.P90Latency)
+		p99 = max(p99, metrics.P99Latency)
+
 		if metrics.LastSendTime.After(lastSendTime) {
 			lastSendTime = metrics.LastSendTime
 		}
@@ -329,8 +642,8 @@ func (r *Router) Metrics() *OutputMetrics {
 	}
 
 	avgLatency := time.Duration(0)
-	if len(r.outputs) > 0 {
-		avgLatency = totalLatency / time.Duration(len(r.outputs))
+	if latencyCount > 0 {
+		avgLatency = latencySum / time.Duration(latencyCount)
 	}
 
 	avgBatchSize := 0.0
@@ -348,7 +661,39 @@ func (r *Router) Metrics() *OutputMetrics {
 		LastErrorTime: lastErrorTime,
 		AvgBatchSize:  avgBatchSize,
 		AvgLatency:    avgLatency,
+		P50Latency:    p50,
+		P90Latency:    p90,
+		P99Latency:    p99,
+		LatencyCount:  latencyCount,
+		LatencySum:    latencySum,
+	}
+}
+
+// OutputMetrics returns the metrics of each output, keyed by output name
+func (r *Router) OutputMetrics() map[string]*OutputMetrics {
+	outputs, _ := r.snapshot()
+
+	metrics := make(map[string]*OutputMetrics, len(outputs))
+	for _, output := range outputs {
+		metrics[output.Name()] = output.Metrics()
+	}
+	return metrics
+}
+
+// CircuitBreakers returns the circuit breakers of the outputs that have one,
+// keyed by output name
+func (r *Router) CircuitBreakers() *reliability.MultiCircuitBreaker {
+	outputs, _ := r.snapshot()
+
+	breakers := reliability.NewMultiCircuitBreaker()
+	for _, output := range outputs {
+		if bo, ok := output.(BreakerOutput); ok {
+			if cb := bo.CircuitBreaker(); cb != nil {
+				breakers.Add(output.Name(), cb)
+			}
+		}
 	}
+	return breakers
 }
 
 // GetOutputs returns all configured outputs
@@ -0,0 +1,530 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// failingOutput fails every send with err
+type failingOutput struct {
+	stubOutput
+	sendErr error
+}
+
+func (f *failingOutput) Send(context.Context, *types.LogEvent) error        { return f.sendErr }
+func (f *failingOutput) SendBatch(context.Context, []*types.LogEvent) error { return f.sendErr }
+
+// deadLetterRecord is one event handed to a recordingDeadLetter
+type deadLetterRecord struct {
+	event    *types.LogEvent
+	reason   error
+	metadata map[string]string
+}
+
+// recordingDeadLetter records the events written to it
+type recordingDeadLetter struct {
+	mu      sync.Mutex
+	records []deadLetterRecord
+}
+
+func (r *recordingDeadLetter) Write(event *types.LogEvent, reason error, metadata map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, deadLetterRecord{event: event, reason: reason, metadata: metadata})
+	return nil
+}
+
+func TestRouter_DeadLetter(t *testing.T) {
+	sendErr := errors.New("retries exhausted")
+
+	for _, parallel := range []bool{true, false} {
+		router, err := NewRouter(RouterConfig{
+			Outputs:         []OutputConfig{{Type: "stub", Name: "healthy"}},
+			FailureStrategy: "continue",
+			Parallel:        parallel,
+		})
+		if err != nil {
+			t.Fatalf("NewRouter() error = %v", err)
+		}
+		router.AddOutput(&failingOutput{stubOutput: stubOutput{name: "broken"}, sendErr: sendErr})
+
+		deadLetter := &recordingDeadLetter{}
+		router.SetDeadLetter(deadLetter)
+
+		event := &types.LogEvent{Message: "single"}
+		if err := router.Send(context.Background(), event); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		batch := []*types.LogEvent{{Message: "a"}, {Message: "b"}}
+		if err := router.SendBatch(context.Background(), batch); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+
+		if len(deadLetter.records) != 3 {
+			t.Fatalf("parallel=%v: expected 3 dead letters, got %d", parallel, len(deadLetter.records))
+		}
+		first := deadLetter.records[0]
+		if first.event != event || !errors.Is(first.reason, sendErr) {
+			t.Errorf("parallel=%v: unexpected dead letter %+v", parallel, first)
+		}
+		if first.metadata["output"] != "broken" || first.metadata["output_type"] != "custom" {
+			t.Errorf("parallel=%v: unexpected metadata %v", parallel, first.metadata)
+		}
+	}
+}
+
+// newRetryingRouter returns a router that retries transient failures, with
+// an Elasticsearch output answering every request with status
+func newRetryingRouter(t *testing.T, status int) (*Router, *recordingTransport, *recordingDeadLetter) {
+	t.Helper()
+
+	router, err := NewRouter(RouterConfig{
+		Outputs:      []OutputConfig{{Type: "stub", Name: "healthy"}},
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	// Disable the client's own retries to count the router's
+	transport := &recordingTransport{status: status}
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:    []string{"http://localhost:9200"},
+		Transport:    transport,
+		DisableRetry: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	router.AddOutput(&ElasticsearchOutput{config: ElasticsearchConfig{Index: "logs"}, client: client, metrics: &OutputMetrics{}})
+
+	deadLetter := &recordingDeadLetter{}
+	router.SetDeadLetter(deadLetter)
+	return router, transport, deadLetter
+}
+
+func TestRouter_AuthErrorsNotRetried(t *testing.T) {
+	router, transport, deadLetter := newRetryingRouter(t, http.StatusUnauthorized)
+
+	if err := router.Send(context.Background(), &types.LogEvent{Message: "denied"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(transport.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(transport.requests))
+	}
+	if len(deadLetter.records) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(deadLetter.records))
+	}
+	record := deadLetter.records[0]
+	if !errors.Is(record.reason, ErrAuth) || IsRetryable(record.reason) {
+		t.Errorf("expected an auth error, got %v", record.reason)
+	}
+	if record.metadata["error_class"] != "auth" {
+		t.Errorf("expected the auth class in the metadata, got %v", record.metadata)
+	}
+}
+
+func TestRouter_TransientErrorsRetried(t *testing.T) {
+	router, transport, deadLetter := newRetryingRouter(t, http.StatusServiceUnavailable)
+
+	if err := router.Send(context.Background(), &types.LogEvent{Message: "unavailable"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(transport.requests) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(transport.requests))
+	}
+	if len(deadLetter.records) != 1 {
+		t.Fatalf("expected 1 dead letter after the retries, got %d", len(deadLetter.records))
+	}
+	record := deadLetter.records[0]
+	if !errors.Is(record.reason, ErrTransient) || !errors.Is(record.reason, reliability.ErrMaxRetriesExceeded) {
+		t.Errorf("expected exhausted transient retries, got %v", record.reason)
+	}
+	if record.metadata["error_class"] != "transient" {
+		t.Errorf("expected the transient class in the metadata, got %v", record.metadata)
+	}
+}
+
+func TestRouter_RetrySucceeds(t *testing.T) {
+	router, err := NewRouter(RouterConfig{
+		Outputs:      []OutputConfig{{Type: "stub", Name: "healthy"}},
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	flaky := &flakyOutput{stubOutput: stubOutput{name: "flaky"}, failures: 2}
+	router.AddOutput(flaky)
+	deadLetter := &recordingDeadLetter{}
+	router.SetDeadLetter(deadLetter)
+
+	if err := router.SendBatch(context.Background(), []*types.LogEvent{{Message: "a"}}); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+	if flaky.calls != 3 || len(deadLetter.records) != 0 {
+		t.Errorf("expected 3 calls and no dead letters, got %d and %d", flaky.calls, len(deadLetter.records))
+	}
+}
+
+// flakyOutput fails its first sends with a transient error
+type flakyOutput struct {
+	stubOutput
+	failures int
+	calls    int
+}
+
+func (f *flakyOutput) SendBatch(context.Context, []*types.LogEvent) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return classifyf(ErrTransient, "attempt %d timed out", f.calls)
+	}
+	return nil
+}
+
+func init() {
+	Register("test-memory", func(cfg map[string]interface{}) (Output, error) {
+		return NewMemoryOutput(fmt.Sprintf("%v", cfg["name"])), nil
+	})
+}
+
+// newMemoryRouter creates a router of memory outputs with the given names
+func newMemoryRouter(t *testing.T, config RouterConfig, names ...string) (*Router, []*MemoryOutput) {
+	t.Helper()
+
+	for _, name := range names {
+		config.Outputs = append(config.Outputs, OutputConfig{Type: "test-memory", Name: name})
+	}
+	router, err := NewRouter(config)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	t.Cleanup(func() { router.Close() })
+
+	var outputs []*MemoryOutput
+	for _, out := range router.GetOutputs() {
+		outputs = append(outputs, out.(*MemoryOutput))
+	}
+	return router, outputs
+}
+
+func TestRouter_FailureStrategy(t *testing.T) {
+	tests := []struct {
+		strategy  string
+		parallel  bool
+		wantErr   bool
+		delivered bool // whether the outputs after the failing one get the events
+	}{
+		{"stop", false, true, false},
+		{"continue", false, false, true},
+		{"stop", true, true, true},
+		{"continue", true, false, true},
+	}
+
+	for _, tt := range tests {
+		name := tt.strategy + "/sequential"
+		if tt.parallel {
+			name = tt.strategy + "/parallel"
+		}
+		t.Run(name, func(t *testing.T) {
+			router, outputs := newMemoryRouter(t, RouterConfig{FailureStrategy: tt.strategy, Parallel: tt.parallel}, "broken", "first", "second")
+			broken, healthy := outputs[0], outputs[1:]
+			broken.SetError(classifyf(ErrPermanent, "rejected"))
+			deadLetter := &recordingDeadLetter{}
+			router.SetDeadLetter(deadLetter)
+
+			sendErr := router.Send(context.Background(), &types.LogEvent{Message: "single"})
+			batchErr := router.SendBatch(context.Background(), []*types.LogEvent{{Message: "a"}, {Message: "b"}})
+			if (sendErr != nil) != tt.wantErr || (batchErr != nil) != tt.wantErr {
+				t.Errorf("expected errors %v, got %v and %v", tt.wantErr, sendErr, batchErr)
+			}
+
+			if broken.Calls() != 2 || len(broken.Events()) != 0 {
+				t.Errorf("expected 2 failed calls to the broken output, got %d calls and %d events", broken.Calls(), len(broken.Events()))
+			}
+			for _, out := range healthy {
+				want := 0
+				if tt.delivered {
+					want = 3
+				}
+				if got := len(out.Events()); got != want {
+					t.Errorf("output %s: expected %d events, got %d", out.Name(), want, got)
+				}
+			}
+			if len(deadLetter.records) != 3 {
+				t.Errorf("expected the broken output's 3 events dead-lettered, got %d", len(deadLetter.records))
+			}
+			for _, record := range deadLetter.records {
+				if _, ok := record.metadata["all_outputs_failed"]; ok != !tt.delivered {
+					t.Errorf("expected all_outputs_failed only when no output took the events, got %v", record.metadata)
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_ParallelSends(t *testing.T) {
+	const latency = 50 * time.Millisecond
+
+	for _, parallel := range []bool{true, false} {
+		router, outputs := newMemoryRouter(t, RouterConfig{FailureStrategy: "continue", Parallel: parallel}, "slow-0", "slow-1", "slow-2")
+		for _, out := range outputs {
+			out.SetLatency(latency)
+		}
+
+		start := time.Now()
+		if err := router.SendBatch(context.Background(), []*types.LogEvent{{Message: "a"}}); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+		elapsed := time.Since(start)
+
+		// Sequential sends wait for each output in turn; parallel sends
+		// wait for the slowest only
+		if parallel && elapsed >= 2*latency {
+			t.Errorf("parallel send took %s, expected about %s", elapsed, latency)
+		}
+		if !parallel && elapsed < 3*latency {
+			t.Errorf("sequential send took %s, expected at least %s", elapsed, 3*latency)
+		}
+		for _, out := range outputs {
+			if len(out.Batches()) != 1 {
+				t.Errorf("parallel=%v: expected output %s to receive the batch, got %d batches", parallel, out.Name(), len(out.Batches()))
+			}
+		}
+	}
+}
+
+func TestRouter_RetriesScriptedFailures(t *testing.T) {
+	router, outputs := newMemoryRouter(t, RouterConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}, "flaky")
+	out := outputs[0]
+	out.FailNext(classifyf(ErrTransient, "timeout"), classifyf(ErrTransient, "timeout"))
+
+	if err := router.Send(context.Background(), &types.LogEvent{Message: "retried"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if out.Calls() != 3 {
+		t.Errorf("expected 2 retries, got %d calls", out.Calls())
+	}
+	if messages := out.Messages(); len(messages) != 1 || messages[0] != "retried" {
+		t.Errorf("expected the event delivered once, got %v", messages)
+	}
+}
+
+func TestRouter_AllOutputsFailed(t *testing.T) {
+	undelivered := metrics.GetGlobalCollector().RouterEventsUndelivered
+
+	for _, strategy := range []string{"continue", "stop"} {
+		for _, parallel := range []bool{true, false} {
+			router, outputs := newMemoryRouter(t, RouterConfig{FailureStrategy: strategy, Parallel: parallel}, "first", "second")
+			for _, out := range outputs {
+				out.SetError(classifyf(ErrPermanent, "rejected by %s", out.Name()))
+			}
+			deadLetter := &recordingDeadLetter{}
+			router.SetDeadLetter(deadLetter)
+			var hookEvents []*types.LogEvent
+			var hookErr error
+			router.OnAllFailed(func(events []*types.LogEvent, err error) {
+				hookEvents = append(hookEvents, events...)
+				hookErr = err
+			})
+
+			before := testutil.ToFloat64(undelivered)
+			router.Send(context.Background(), &types.LogEvent{Message: "single"})
+			router.SendBatch(context.Background(), []*types.LogEvent{{Message: "a"}, {Message: "b"}})
+
+			if got := testutil.ToFloat64(undelivered) - before; got != 3 {
+				t.Errorf("%s/parallel=%v: expected 3 undelivered events counted, got %v", strategy, parallel, got)
+			}
+			if got := router.metrics.TotalUndelivered; got != 3 {
+				t.Errorf("%s/parallel=%v: expected 3 undelivered events in the router metrics, got %d", strategy, parallel, got)
+			}
+			if len(hookEvents) != 3 || !errors.Is(hookErr, ErrPermanent) {
+				t.Errorf("%s/parallel=%v: expected the hook called with 3 events and the outputs' errors, got %d and %v", strategy, parallel, len(hookEvents), hookErr)
+			}
+
+			// Each output that was tried dead-letters the events; the stop
+			// strategy does not try the second output of a sequential router
+			want := 6
+			if strategy == "stop" && !parallel {
+				want = 3
+			}
+			if len(deadLetter.records) != want {
+				t.Fatalf("%s/parallel=%v: expected %d dead letters, got %d", strategy, parallel, want, len(deadLetter.records))
+			}
+			for _, record := range deadLetter.records {
+				if record.metadata["all_outputs_failed"] != "true" {
+					t.Errorf("%s/parallel=%v: expected the dead letter marked all_outputs_failed, got %v", strategy, parallel, record.metadata)
+				}
+			}
+		}
+	}
+}
+
+// batchingMemoryOutput batches events for a memory output like the network
+// outputs do, each flush taking a random time, as requests to a destination
+// would
+type batchingMemoryOutput struct {
+	*MemoryOutput
+	batcher *Batcher
+
+	flushErrorHook
+}
+
+func (b *batchingMemoryOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	return b.batcher.Add(ctx, event)
+}
+
+func (b *batchingMemoryOutput) Flush(ctx context.Context) error {
+	return b.batcher.Flush(ctx)
+}
+
+func (b *batchingMemoryOutput) Close() error {
+	b.batcher.Stop()
+	return b.MemoryOutput.Close()
+}
+
+func init() {
+	Register("test-batching", func(cfg map[string]interface{}) (Output, error) {
+		var config BaseConfig
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		out := &batchingMemoryOutput{MemoryOutput: NewMemoryOutput(config.Name)}
+		out.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  config.BatchSize,
+			FlushInterval: config.FlushInterval,
+			Ordered:       config.Ordered,
+			OnFlushError:  out.flushFailed,
+		}, func(ctx context.Context, events []*types.LogEvent) error {
+			time.Sleep(time.Duration(rand.Int64N(int64(time.Millisecond))))
+			return out.MemoryOutput.SendBatch(ctx, events)
+		})
+		return out, nil
+	})
+}
+
+func TestRouter_BackgroundFlushDeadLettered(t *testing.T) {
+	router, err := NewRouter(RouterConfig{
+		Outputs: []OutputConfig{
+			{Type: "test-batching", Name: "batched", Config: map[string]interface{}{"batch_size": 100, "flush_interval": "10ms"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	defer router.Close()
+	batched := router.GetOutputs()[0].(*batchingMemoryOutput)
+	batched.SetError(classifyf(ErrPermanent, "rejected"))
+	deadLetter := &recordingDeadLetter{}
+	router.SetDeadLetter(deadLetter)
+	hookEvents := make(chan int, 1)
+	router.OnAllFailed(func(events []*types.LogEvent, err error) {
+		hookEvents <- len(events)
+	})
+	undelivered := metrics.GetGlobalCollector().RouterEventsUndelivered
+	before := testutil.ToFloat64(undelivered)
+
+	// The send only adds the events to the batch, which fails once it is
+	// flushed on its interval
+	if err := router.SendBatch(context.Background(), []*types.LogEvent{{Message: "a"}, {Message: "b"}}); err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deadLetter.mu.Lock()
+		records := append([]deadLetterRecord(nil), deadLetter.records...)
+		deadLetter.mu.Unlock()
+		if len(records) == 2 {
+			for _, record := range records {
+				if record.metadata["output"] != "batched" || !errors.Is(record.reason, ErrPermanent) {
+					t.Errorf("expected the dead letter from the batched output, got %v: %v", record.metadata, record.reason)
+				}
+				if record.metadata["all_outputs_failed"] != "true" {
+					t.Errorf("expected the dead letter marked all_outputs_failed, got %v", record.metadata)
+				}
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the failed flush's 2 events dead-lettered, got %d", len(records))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case count := <-hookEvents:
+		if count != 2 {
+			t.Errorf("expected the hook called with 2 events, got %d", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the all-failed hook called")
+	}
+	if got := testutil.ToFloat64(undelivered) - before; got != 2 {
+		t.Errorf("expected 2 undelivered events counted, got %v", got)
+	}
+	if got := atomic.LoadInt64(&router.metrics.TotalUndelivered); got != 2 {
+		t.Errorf("expected 2 undelivered events in the router metrics, got %d", got)
+	}
+}
+
+func TestRouter_Ordered(t *testing.T) {
+	// Batches fill while the previous one, flushed on its interval, is
+	// still being sent, which would let the later batch overtake it
+	router, err := NewRouter(RouterConfig{
+		Outputs: []OutputConfig{
+			{Type: "test-batching", Name: "batched", Config: map[string]interface{}{"batch_size": 7, "flush_interval": "100us"}},
+			{Type: "test-memory", Name: "memory"},
+		},
+		Parallel: true,
+		Ordered:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	defer router.Close()
+	batched := router.GetOutputs()[0].(*batchingMemoryOutput)
+	memory := router.GetOutputs()[1].(*MemoryOutput)
+
+	const total = 500
+	want := make([]string, total)
+	for i := range want {
+		want[i] = fmt.Sprintf("event-%03d", i)
+		if err := router.Send(context.Background(), &types.LogEvent{Message: want[i]}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := router.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	for name, got := range map[string][]string{"batched": batched.Messages(), "memory": memory.Messages()} {
+		if len(got) != total {
+			t.Errorf("%s: expected %d events, got %d", name, total, len(got))
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: event %d = %q, want %q", name, i, got[i], want[i])
+				break
+			}
+		}
+	}
+}
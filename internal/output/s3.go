@@ -3,23 +3,19 @@ package output
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
-	"sync"
-	"sync/atomic"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
-	logtypes "github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
 // S3Config contains S3-specific configuration
 type S3Config struct {
-	BaseConfig `yaml:",inline"`
+	BaseConfig   `yaml:",inline"`
+	ObjectConfig `yaml:",inline"`
 
 	// Bucket is the S3 bucket name
 	Bucket string `yaml:"bucket"`
@@ -27,12 +23,6 @@ type S3Config struct {
 	// Region is the AWS region
 	Region string `yaml:"region"`
 
-	// Prefix is the key prefix for objects
-	Prefix string `yaml:"prefix,omitempty"`
-
-	// KeyTemplate is the template for object keys (supports time patterns)
-	KeyTemplate string `yaml:"key_template,omitempty"`
-
 	// StorageClass is the S3 storage class (STANDARD, GLACIER, etc.)
 	StorageClass string `yaml:"storage_class,omitempty"`
 
@@ -42,9 +32,6 @@ type S3Config struct {
 	// ACL is the canned ACL (private, public-read, etc.)
 	ACL string `yaml:"acl,omitempty"`
 
-	// UploadConcurrency is the number of concurrent uploads
-	UploadConcurrency int `yaml:"upload_concurrency,omitempty"`
-
 	// AccessKeyID for authentication (optional, uses default credentials if not set)
 	AccessKeyID string `yaml:"access_key_id,omitempty"`
 
@@ -59,34 +46,40 @@ type S3Config struct {
 
 	// UsePathStyle forces path-style addressing
 	UsePathStyle bool `yaml:"use_path_style,omitempty"`
+}
 
-	// ContentType for uploaded objects
-	ContentType string `yaml:"content_type,omitempty"`
+func init() {
+	Register("s3", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultS3Config()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewS3Output(config)
+	})
 }
 
 // DefaultS3Config returns default S3 configuration
 func DefaultS3Config() S3Config {
 	return S3Config{
-		BaseConfig:        DefaultBaseConfig(),
-		Region:            "us-east-1",
-		Prefix:            "logs/",
-		KeyTemplate:       "{{.Year}}/{{.Month}}/{{.Day}}/{{.Hour}}/{{.Timestamp}}.json",
-		StorageClass:      "STANDARD",
-		ACL:               "private",
-		UploadConcurrency: 5,
-		ContentType:       "application/json",
+		BaseConfig:   DefaultBaseConfig(),
+		ObjectConfig: DefaultObjectConfig(),
+		Region:       "us-east-1",
+		StorageClass: "STANDARD",
+		ACL:          "private",
 	}
 }
 
+// s3API is the subset of the S3 client used by S3Output
+type s3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
 // S3Output sends events to S3
 type S3Output struct {
-	config     S3Config
-	client     *s3.Client
-	batcher    *Batcher
-	metrics    *OutputMetrics
-	compressor Compressor
-	mu         sync.RWMutex
-	closed     atomic.Bool
+	*objectWriter
+	config S3Config
+	client s3API
 }
 
 // NewS3Output creates a new S3 output
@@ -120,161 +113,16 @@ func NewS3Output(s3Config S3Config) (*S3Output, error) {
 
 	client := s3.NewFromConfig(cfg, opts...)
 
-	// Get compressor
-	compressor, err := GetCompressor(s3Config.Compression)
-	if err != nil {
-		return nil, err
-	}
-
 	output := &S3Output{
-		config:     s3Config,
-		client:     client,
-		metrics:    &OutputMetrics{},
-		compressor: compressor,
-	}
-
-	// Create batcher
-	if s3Config.BatchSize > 1 {
-		output.batcher = NewBatcher(BatcherConfig{
-			MaxBatchSize:  s3Config.BatchSize,
-			MaxBatchBytes: 100 * 1024 * 1024, // 100MB
-			FlushInterval: s3Config.FlushInterval,
-		}, output.sendBatchInternal)
-	}
-
-	return output, nil
-}
-
-// Send sends a single event to S3
-func (s *S3Output) Send(ctx context.Context, event *types.LogEvent) error {
-	if s.closed.Load() {
-		return fmt.Errorf("s3 output is closed")
-	}
-
-	// Use batcher if configured
-	if s.batcher != nil {
-		return s.batcher.Add(ctx, event)
-	}
-
-	return s.sendSingle(ctx, event)
-}
-
-// SendBatch sends a batch of events to S3
-func (s *S3Output) SendBatch(ctx context.Context, events []*types.LogEvent) error {
-	if s.closed.Load() {
-		return fmt.Errorf("s3 output is closed")
-	}
-
-	return s.sendBatchInternal(ctx, events)
-}
-
-// sendSingle sends a single event as a separate S3 object
-func (s *S3Output) sendSingle(ctx context.Context, event *types.LogEvent) error {
-	key := s.generateKey(event.Timestamp)
-
-	// Serialize event
-	data, err := json.Marshal(event)
-	if err != nil {
-		atomic.AddInt64(&s.metrics.EventsFailed, 1)
-		s.metrics.LastError = err.Error()
-		s.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
-
-	// Compress if needed
-	data, err = s.compressor.Compress(data)
-	if err != nil {
-		atomic.AddInt64(&s.metrics.EventsFailed, 1)
-		s.metrics.LastError = err.Error()
-		s.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to compress data: %w", err)
-	}
-
-	// Upload to S3
-	startTime := time.Now()
-	err = s.uploadObject(ctx, key, data)
-	latency := time.Since(startTime)
-
-	if err != nil {
-		atomic.AddInt64(&s.metrics.EventsFailed, 1)
-		s.metrics.LastError = err.Error()
-		s.metrics.LastErrorTime = time.Now()
-		return err
-	}
-
-	// Update metrics
-	atomic.AddInt64(&s.metrics.EventsSent, 1)
-	atomic.AddInt64(&s.metrics.BytesSent, int64(len(data)))
-	s.metrics.LastSendTime = time.Now()
-
-	// Update average latency
-	s.mu.Lock()
-	s.metrics.AvgLatency = (s.metrics.AvgLatency + latency) / 2
-	s.mu.Unlock()
-
-	return nil
-}
-
-// sendBatchInternal sends a batch of events as a single S3 object
-func (s *S3Output) sendBatchInternal(ctx context.Context, events []*types.LogEvent) error {
-	if len(events) == 0 {
-		return nil
+		config: s3Config,
+		client: client,
 	}
-
-	startTime := time.Now()
-
-	// Use first event's timestamp for key generation
-	key := s.generateKey(events[0].Timestamp)
-
-	// Serialize events as NDJSON (newline-delimited JSON)
-	var buf bytes.Buffer
-	for _, event := range events {
-		data, err := json.Marshal(event)
-		if err != nil {
-			atomic.AddInt64(&s.metrics.EventsFailed, 1)
-			continue
-		}
-		buf.Write(data)
-		buf.WriteByte('\n')
-	}
-
-	data := buf.Bytes()
-
-	// Compress if needed
-	compressed, err := s.compressor.Compress(data)
+	output.objectWriter, err = newObjectWriter("s3", s3Config.BaseConfig, s3Config.ObjectConfig, output.uploadObject)
 	if err != nil {
-		atomic.AddInt64(&s.metrics.EventsFailed, int64(len(events)))
-		s.metrics.LastError = err.Error()
-		s.metrics.LastErrorTime = time.Now()
-		return fmt.Errorf("failed to compress data: %w", err)
-	}
-
-	// Upload to S3
-	err = s.uploadObject(ctx, key, compressed)
-	latency := time.Since(startTime)
-
-	if err != nil {
-		atomic.AddInt64(&s.metrics.EventsFailed, int64(len(events)))
-		s.metrics.LastError = err.Error()
-		s.metrics.LastErrorTime = time.Now()
-		return err
-	}
-
-	// Update metrics
-	atomic.AddInt64(&s.metrics.EventsSent, int64(len(events)))
-	atomic.AddInt64(&s.metrics.BytesSent, int64(len(compressed)))
-	atomic.AddInt64(&s.metrics.BatchesSent, 1)
-	s.metrics.LastSendTime = time.Now()
-
-	// Update average batch size and latency
-	s.mu.Lock()
-	if s.metrics.BatchesSent > 0 {
-		s.metrics.AvgBatchSize = float64(s.metrics.EventsSent) / float64(s.metrics.BatchesSent)
+		return nil, err
 	}
-	s.metrics.AvgLatency = (s.metrics.AvgLatency + latency) / 2
-	s.mu.Unlock()
 
-	return nil
+	return output, nil
 }
 
 // uploadObject uploads data to S3
@@ -283,7 +131,7 @@ func (s *S3Output) uploadObject(ctx context.Context, key string, data []byte) er
 		Bucket:      aws.String(s.config.Bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(data),
-		ContentType: aws.String(s.config.ContentType),
+		ContentType: aws.String(s.object.ContentType),
 	}
 
 	// Set storage class
@@ -301,93 +149,37 @@ func (s *S3Output) uploadObject(ctx context.Context, key string, data []byte) er
 		input.ServerSideEncryption = s3types.ServerSideEncryption(s.config.ServerSideEncryption)
 	}
 
-	// Add compression extension if compressed
-	if s.config.Compression != CompressionNone {
-		input.ContentEncoding = aws.String(string(s.config.Compression))
+	// Add compression encoding if compressed
+	if encoding := s.compressor.ContentEncoding(); encoding != "" {
+		input.ContentEncoding = aws.String(encoding)
 	}
 
 	_, err := s.client.PutObject(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to upload to S3: %w", err)
+		return classifyS3Error(fmt.Errorf("failed to upload to S3: %w", err))
 	}
 
 	return nil
 }
 
-// generateKey generates an S3 key from a template and timestamp
-func (s *S3Output) generateKey(timestamp time.Time) string {
-	if timestamp.IsZero() {
-		timestamp = time.Now()
+// classifyS3Error wraps an upload error with the class of the response
+// status, or ErrTransient when no response was received
+func classifyS3Error(err error) error {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() >= 400 {
+		return statusError(respErr.HTTPStatusCode(), err)
 	}
-
-	key := s.config.KeyTemplate
-	if key == "" {
-		key = "{{.Timestamp}}.json"
-	}
-
-	// Replace template variables
-	replacements := map[string]string{
-		"{{.Year}}":      fmt.Sprintf("%04d", timestamp.Year()),
-		"{{.Month}}":     fmt.Sprintf("%02d", timestamp.Month()),
-		"{{.Day}}":       fmt.Sprintf("%02d", timestamp.Day()),
-		"{{.Hour}}":      fmt.Sprintf("%02d", timestamp.Hour()),
-		"{{.Minute}}":    fmt.Sprintf("%02d", timestamp.Minute()),
-		"{{.Second}}":    fmt.Sprintf("%02d", timestamp.Second()),
-		"{{.Timestamp}}": fmt.Sprintf("%d", timestamp.Unix()),
-		"{{.UnixNano}}":  fmt.Sprintf("%d", timestamp.UnixNano()),
-	}
-
-	for placeholder, value := range replacements {
-		key = strings.ReplaceAll(key, placeholder, value)
-	}
-
-	// Add prefix
-	if s.config.Prefix != "" {
-		key = s.config.Prefix + key
-	}
-
-	// Add compression extension
-	if s.config.Compression == CompressionGzip {
-		key += ".gz"
-	} else if s.config.Compression == CompressionSnappy {
-		key += ".snappy"
-	} else if s.config.Compression == CompressionLZ4 {
-		key += ".lz4"
-	}
-
-	return key
+	return classify(ErrTransient, err)
 }
 
-// Close closes the S3 output
-func (s *S3Output) Close() error {
-	if !s.closed.CompareAndSwap(false, true) {
-		return nil // Already closed
-	}
-
-	// Stop batcher first
-	if s.batcher != nil {
-		if err := s.batcher.Stop(); err != nil {
-			return err
-		}
+// HealthCheck verifies the S3 bucket is reachable
+func (s *S3Output) HealthCheck(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.config.Bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reach S3 bucket %s: %w", s.config.Bucket, err)
 	}
 
 	return nil
 }
-
-// Name returns the output name
-func (s *S3Output) Name() string {
-	if s.config.Name != "" {
-		return s.config.Name
-	}
-	return "s3"
-}
-
-// Metrics returns the current metrics
-func (s *S3Output) Metrics() *OutputMetrics {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Return a copy
-	metricsCopy := *s.metrics
-	return &metricsCopy
-}
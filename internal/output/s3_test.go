@@ -0,0 +1,326 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordingS3Client keeps the key and body of each upload. Failed uploads
+// keep the body without reading it; successful ones copy it, as the output
+// reuses the buffer once the upload returns.
+type recordingS3Client struct {
+	s3API
+	mu     sync.Mutex
+	keys   []string
+	bodies []io.Reader
+	err    error
+}
+
+func (r *recordingS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys = append(r.keys, *params.Key)
+	if r.err != nil {
+		r.bodies = append(r.bodies, params.Body)
+		return nil, r.err
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.bodies = append(r.bodies, bytes.NewReader(body))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func newTestS3Output(client s3API) *S3Output {
+	config := DefaultS3Config()
+	config.Bucket = "logs"
+	out := &S3Output{config: config, client: client}
+	out.objectWriter = newTestObjectWriter("s3", config.ObjectConfig, out.uploadObject)
+	return out
+}
+
+// newTestObjectWriter returns an unbatched writer of uncompressed JSON
+func newTestObjectWriter(outputType string, object ObjectConfig, upload objectUploader) *objectWriter {
+	return &objectWriter{
+		outputType: outputType,
+		object:     object,
+		upload:     upload,
+		metrics:    &OutputMetrics{},
+		compressor: &NoneCompressor{},
+		serializer: &JSONSerializer{},
+	}
+}
+
+// messageBatch returns events whose messages are prefix-0, prefix-1, ...
+func messageBatch(prefix string, n int) []*types.LogEvent {
+	events := make([]*types.LogEvent, n)
+	for i := range events {
+		events[i] = &types.LogEvent{Message: fmt.Sprintf("%s-%d", prefix, i)}
+	}
+	return events
+}
+
+func TestS3Output_SendBatch(t *testing.T) {
+	client := &recordingS3Client{}
+	out := newTestS3Output(client)
+
+	if err := out.sendBatchInternal(context.Background(), messageBatch("first", 3)); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	body, err := io.ReadAll(client.bodies[0])
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"message":"first-2"`) {
+		t.Errorf("expected 3 NDJSON lines, got %q", body)
+	}
+}
+
+func TestS3Output_FailedUploadKeepsBuffer(t *testing.T) {
+	client := &recordingS3Client{err: errors.New("connection reset")}
+	out := newTestS3Output(client)
+
+	if err := out.sendBatchInternal(context.Background(), messageBatch("failed", 2)); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+
+	// A later batch must not reuse the buffer the failed upload may still read
+	client.err = nil
+	for i := 0; i < 3; i++ {
+		if err := out.sendBatchInternal(context.Background(), messageBatch("next", 50)); err != nil {
+			t.Fatalf("sendBatchInternal() error = %v", err)
+		}
+	}
+
+	body, err := io.ReadAll(client.bodies[0])
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if !strings.Contains(string(body), `"message":"failed-1"`) || strings.Contains(string(body), "next-") {
+		t.Errorf("failed upload body was overwritten: %q", body)
+	}
+}
+
+func TestS3Output_KeyFields(t *testing.T) {
+	out := newTestS3Output(nil)
+	out.object.KeyTemplate = "{{.Fields.service}}/{{.Year}}/{{.Fields.tenant}}/{{.Timestamp}}.json"
+
+	ts := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"interpolated", map[string]string{"service": "api", "tenant": "acme"}, "logs/api/2024/acme/1710496800.json"},
+		{"unsafe characters", map[string]string{"service": "../etc/passwd", "tenant": "a b?c&d"}, "logs/.._etc_passwd/2024/a_b_c_d/1710496800.json"},
+		{"dot segments", map[string]string{"service": "..", "tenant": ""}, "logs/unknown/2024/unknown/1710496800.json"},
+		{"unicode", map[string]string{"service": "café", "tenant": "x{{.Year}}"}, "logs/caf_/2024/x__.Year__/1710496800.json"},
+		{"missing", map[string]string{"service": "api"}, "logs/api/2024/unknown/1710496800.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := out.generateKey(&types.LogEvent{Timestamp: ts, Fields: tt.fields}); got != tt.want {
+				t.Errorf("generateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if fields := keyFields(out.object.KeyTemplate + "{{.Fields.service}}"); len(fields) != 2 || fields[0] != "service" || fields[1] != "tenant" {
+		t.Errorf("keyFields() = %v", fields)
+	}
+}
+
+func TestValidateKeyTemplate(t *testing.T) {
+	valid := []string{
+		"",
+		DefaultS3Config().KeyTemplate,
+		"{{.Fields.service}}/{{.Fields.k8s.namespace}}/{{.UnixNano}}.json",
+	}
+	for _, template := range valid {
+		if err := validateKeyTemplate(template); err != nil {
+			t.Errorf("validateKeyTemplate(%q) error = %v", template, err)
+		}
+	}
+
+	invalid := []string{
+		"{{.Service}}/{{.Timestamp}}.json",
+		"{{.Fields.}}/{{.Timestamp}}.json",
+		"{{.Fields.a b}}.json",
+		"{{.Year}}/{{.Timestamp",
+	}
+	for _, template := range invalid {
+		if err := validateKeyTemplate(template); err == nil {
+			t.Errorf("expected an error for %q", template)
+		}
+	}
+}
+
+func TestS3Output_SendBatchPartitions(t *testing.T) {
+	client := &recordingS3Client{}
+	out := newTestS3Output(client)
+	out.object.KeyTemplate = "{{.Fields.service}}/{{.Timestamp}}.json"
+
+	ts := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	events := messageBatch("event", 5)
+	for i, service := range []string{"api", "web", "api", "web/v2", "api"} {
+		events[i].Timestamp = ts.Add(time.Duration(i) * time.Second)
+		events[i].Fields = map[string]string{"service": service}
+	}
+
+	if err := out.sendBatchInternal(context.Background(), events); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	assertObjects(t, client, map[string][]string{
+		"logs/api/1710496800.json":    {"event-0", "event-2", "event-4"},
+		"logs/web/1710496801.json":    {"event-1"},
+		"logs/web_v2/1710496803.json": {"event-3"},
+	})
+	if m := out.Metrics(); m.EventsSent != 5 || m.BatchesSent != 1 {
+		t.Errorf("expected 5 events in 1 batch, got %d in %d", m.EventsSent, m.BatchesSent)
+	}
+
+	// The first event must carry every referenced field
+	client.keys = nil
+	events[0].Fields = nil
+	err := out.sendBatchInternal(context.Background(), events)
+	if !errors.Is(err, ErrSerialization) {
+		t.Errorf("expected a serialization error for a missing key field, got %v", err)
+	}
+	if len(client.keys) != 0 {
+		t.Errorf("expected nothing uploaded, got %v", client.keys)
+	}
+}
+
+func TestS3Output_SplitByKey(t *testing.T) {
+	client := &recordingS3Client{}
+	out := newTestS3Output(client)
+	out.object.KeyTemplate = "{{.Fields.service}}/{{.Year}}/{{.Month}}/{{.Day}}/{{.Hour}}/{{.Timestamp}}.json"
+	out.object.SplitByKey = true
+	out.object.UploadConcurrency = 2
+
+	// Two services across two hours, interleaved
+	ts := time.Date(2024, 3, 15, 10, 59, 0, 0, time.UTC)
+	events := messageBatch("event", 8)
+	for i, event := range events {
+		event.Timestamp = ts.Add(time.Duration(i) * 20 * time.Second)
+		event.Fields = map[string]string{"service": []string{"api", "web"}[i%2]}
+	}
+
+	if err := out.sendBatchInternal(context.Background(), events); err != nil {
+		t.Fatalf("sendBatchInternal() error = %v", err)
+	}
+
+	// Each object is named by its first event
+	assertObjects(t, client, map[string][]string{
+		"logs/api/2024/03/15/10/1710500340.json": {"event-0", "event-2"},
+		"logs/web/2024/03/15/10/1710500360.json": {"event-1"},
+		"logs/web/2024/03/15/11/1710500400.json": {"event-3", "event-5", "event-7"},
+		"logs/api/2024/03/15/11/1710500420.json": {"event-4", "event-6"},
+	})
+
+	m := out.Metrics()
+	if m.EventsSent != 8 || m.BatchesSent != 1 || m.AvgBatchSize != 8 {
+		t.Errorf("expected the uploads aggregated into one batch of 8, got %d events in %d batches", m.EventsSent, m.BatchesSent)
+	}
+	var size int64
+	for _, body := range client.bodies {
+		size += body.(*bytes.Reader).Size()
+	}
+	if m.BytesSent != size {
+		t.Errorf("expected %d bytes sent, got %d", size, m.BytesSent)
+	}
+
+	// A failed upload fails the batch, counting only its own events
+	client.keys, client.bodies = nil, nil
+	client.err = errors.New("connection reset")
+	if err := out.sendBatchInternal(context.Background(), events); err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if m := out.Metrics(); m.EventsFailed != 8 || m.BatchesSent != 1 {
+		t.Errorf("expected 8 failed events and no new batch, got %d and %d", m.EventsFailed, m.BatchesSent)
+	}
+}
+
+// assertObjects checks that the client uploaded exactly the objects in
+// want, each holding the events with the given messages in order
+func assertObjects(t *testing.T, client *recordingS3Client, want map[string][]string) {
+	t.Helper()
+
+	if len(client.keys) != len(want) {
+		t.Fatalf("expected %d objects, got keys %v", len(want), client.keys)
+	}
+	for i, key := range client.keys {
+		messages, ok := want[key]
+		if !ok {
+			t.Errorf("unexpected object %s", key)
+			continue
+		}
+		body, err := io.ReadAll(client.bodies[i])
+		if err != nil {
+			t.Fatalf("failed to read body: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+		if len(lines) != len(messages) {
+			t.Errorf("object %s has %d events, want %d: %q", key, len(lines), len(messages), body)
+			continue
+		}
+		for j, msg := range messages {
+			if !strings.Contains(lines[j], `"message":"`+msg+`"`) {
+				t.Errorf("object %s line %d = %s, want %s", key, j, lines[j], msg)
+			}
+		}
+	}
+}
+
+// discardS3Client drains each upload and succeeds
+type discardS3Client struct {
+	s3API
+}
+
+func (discardS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	io.Copy(io.Discard, params.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func BenchmarkS3Output_SendBatch(b *testing.B) {
+	for _, pooled := range []bool{false, true} {
+		name := "unpooled"
+		if pooled {
+			name = "pooled"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			pool.SetEnabled(pooled)
+			defer pool.SetEnabled(true)
+
+			out := newTestS3Output(discardS3Client{})
+			events := testBatch(500)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := out.sendBatchInternal(context.Background(), events); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// SchemaName selects the field names events are encoded with
+type SchemaName string
+
+const (
+	SchemaECS  SchemaName = "ecs"
+	SchemaGELF SchemaName = "gelf"
+)
+
+// Canonical names of the event fields, as in the JSON encoding of a
+// LogEvent
+var canonicalFields = []string{"timestamp", "message", "level", "source", "fields", "raw"}
+
+// schemaFields are the names each schema gives the canonical fields. An
+// empty name for fields spreads them over the top level of the document.
+var schemaFields = map[SchemaName]map[string]string{
+	SchemaECS: {
+		"timestamp": "@timestamp",
+		"message":   "message",
+		"level":     "log.level",
+		"source":    "log.file.path",
+		"fields":    "labels",
+		"raw":       "event.original",
+	},
+	SchemaGELF: {
+		"timestamp": "timestamp",
+		"message":   "short_message",
+		"level":     "level",
+		"source":    "host",
+		"fields":    "",
+		"raw":       "full_message",
+	},
+}
+
+// gelfVersion is the GELF version documents declare
+const gelfVersion = "1.1"
+
+// gelfLevels are the syslog severities of the normalized levels
+var gelfLevels = map[string]int{
+	"debug": 7,
+	"info":  6,
+	"warn":  4,
+	"error": 3,
+	"fatal": 2,
+}
+
+// SchemaConfig renames the event fields an output encodes to a target
+// schema
+type SchemaConfig struct {
+	// Name is the target schema: ecs or gelf. The canonical names are kept
+	// when empty.
+	Name SchemaName `yaml:"name,omitempty"`
+
+	// Rename maps canonical field names (timestamp, message, level, source,
+	// fields, raw) to output names, overriding the schema's
+	Rename map[string]string `yaml:"rename,omitempty"`
+}
+
+// Enabled returns whether events are encoded with other than the
+// canonical names
+func (c SchemaConfig) Enabled() bool {
+	return c.Name != "" || len(c.Rename) > 0
+}
+
+// SchemaMapper builds the documents of events under a target schema.
+//
+// ECS documents carry the event fields as labels and the raw line as
+// event.original. GELF documents declare version 1.1, carry the timestamp
+// in Unix seconds and the level as a syslog severity (informational for
+// unknown levels), and spread the event fields as additional fields
+// prefixed with an underscore.
+type SchemaMapper struct {
+	names map[string]string
+	gelf  bool
+}
+
+// NewSchemaMapper creates a mapper for a schema configuration
+func NewSchemaMapper(config SchemaConfig) (*SchemaMapper, error) {
+	names := make(map[string]string, len(canonicalFields))
+	for _, field := range canonicalFields {
+		names[field] = field
+	}
+
+	if config.Name != "" {
+		fields, ok := schemaFields[config.Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported schema: %s", config.Name)
+		}
+		for field, name := range fields {
+			names[field] = name
+		}
+	}
+
+	for field, name := range config.Rename {
+		if _, ok := names[field]; !ok {
+			return nil, fmt.Errorf("unknown schema field %q: must be one of %v", field, canonicalFields)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("schema field %q renamed to an empty name", field)
+		}
+		names[field] = name
+	}
+
+	return &SchemaMapper{names: names, gelf: config.Name == SchemaGELF}, nil
+}
+
+// newOptionalSchemaMapper returns the mapper of a schema configuration, or
+// nil when events keep their canonical names
+func newOptionalSchemaMapper(config SchemaConfig) (*SchemaMapper, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+	return NewSchemaMapper(config)
+}
+
+// Document returns the event keyed by the schema's field names. Empty
+// levels, fields and raw lines are left out, as in the JSON encoding.
+func (m *SchemaMapper) Document(event *types.LogEvent) map[string]interface{} {
+	doc := make(map[string]interface{}, len(canonicalFields)+len(event.Fields))
+	if m.gelf {
+		doc["version"] = gelfVersion
+		doc[m.names["timestamp"]] = float64(event.Timestamp.UnixMicro()) / 1e6
+	} else {
+		doc[m.names["timestamp"]] = event.Timestamp.Format(time.RFC3339Nano)
+	}
+	doc[m.names["message"]] = event.Message
+	doc[m.names["source"]] = event.Source
+
+	if event.Level != "" {
+		if m.gelf {
+			level, ok := gelfLevels[types.NormalizeLevel(event.Level)]
+			if !ok {
+				level = gelfLevels["info"]
+			}
+			doc[m.names["level"]] = level
+		} else {
+			doc[m.names["level"]] = event.Level
+		}
+	}
+
+	if len(event.Fields) > 0 {
+		if name := m.names["fields"]; name != "" {
+			doc[name] = event.Fields
+		} else {
+			for key, value := range event.Fields {
+				doc["_"+key] = value
+			}
+		}
+	}
+
+	if event.Raw != "" {
+		doc[m.names["raw"]] = event.Raw
+	}
+
+	return doc
+}
+
+// SchemaSerializer encodes events under a target schema as JSON or
+// MessagePack
+type SchemaSerializer struct {
+	mapper *SchemaMapper
+	format SerializationFormat
+}
+
+// NewSchemaSerializer creates a schema serializer. The avro format has its
+// own record schema and cannot be combined with one.
+func NewSchemaSerializer(config SchemaConfig, format SerializationFormat) (*SchemaSerializer, error) {
+	switch format {
+	case "", SerializationJSON:
+		format = SerializationJSON
+	case SerializationMsgpack:
+	case SerializationAvro:
+		return nil, fmt.Errorf("a schema cannot be used with avro serialization; set avro_schema instead")
+	default:
+		return nil, fmt.Errorf("unsupported serialization format: %s", format)
+	}
+
+	mapper, err := NewSchemaMapper(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaSerializer{mapper: mapper, format: format}, nil
+}
+
+func (s *SchemaSerializer) Serialize(event *types.LogEvent) ([]byte, error) {
+	doc := s.mapper.Document(event)
+	if s.format == SerializationJSON {
+		return json.Marshal(doc)
+	}
+
+	var buf bytes.Buffer
+	if err := msgpack.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, fmt.Errorf("msgpack encode failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *SchemaSerializer) ContentType() string {
+	if s.format == SerializationJSON {
+		return "application/json"
+	}
+	return "application/msgpack"
+}
+
+// NewSerializer returns the serializer of an output: its serialization
+// format, under its schema when one is configured
+func NewSerializer(config BaseConfig) (Serializer, error) {
+	if config.Schema.Enabled() {
+		return NewSchemaSerializer(config.Schema, config.Serialization.Format)
+	}
+	return GetSerializer(config.Serialization)
+}
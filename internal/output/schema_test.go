@@ -0,0 +1,159 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// serializeDocument encodes the test event with a schema and decodes it
+func serializeDocument(t *testing.T, config SchemaConfig) map[string]interface{} {
+	t.Helper()
+
+	serializer, err := NewSerializer(BaseConfig{Schema: config})
+	if err != nil {
+		t.Fatalf("NewSerializer() error = %v", err)
+	}
+	data, err := serializer.Serialize(testSerializerEvent())
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return doc
+}
+
+func TestSchemaSerializer_ECS(t *testing.T) {
+	doc := serializeDocument(t, SchemaConfig{Name: SchemaECS})
+
+	want := map[string]interface{}{
+		"@timestamp":     "2024-03-15T10:30:00.123456Z",
+		"log.level":      "info",
+		"message":        "user logged in",
+		"log.file.path":  "/var/log/app.log",
+		"event.original": `{"msg":"user logged in"}`,
+	}
+	for key, value := range want {
+		if doc[key] != value {
+			t.Errorf("%s = %v, want %v", key, doc[key], value)
+		}
+	}
+
+	labels, ok := doc["labels"].(map[string]interface{})
+	if !ok || labels["user"] != "alice" {
+		t.Errorf("expected the fields as labels, got %v", doc["labels"])
+	}
+	for _, canonical := range []string{"timestamp", "level", "source", "fields", "raw"} {
+		if _, ok := doc[canonical]; ok {
+			t.Errorf("unexpected canonical field %s in %v", canonical, doc)
+		}
+	}
+}
+
+func TestSchemaSerializer_GELF(t *testing.T) {
+	doc := serializeDocument(t, SchemaConfig{Name: SchemaGELF})
+
+	want := map[string]interface{}{
+		"version":       "1.1",
+		"short_message": "user logged in",
+		"host":          "/var/log/app.log",
+		"full_message":  `{"msg":"user logged in"}`,
+		"timestamp":     1710498600.123456,
+		"level":         float64(6),
+		"_user":         "alice",
+		"_status":       "200",
+	}
+	for key, value := range want {
+		if doc[key] != value {
+			t.Errorf("%s = %v, want %v", key, doc[key], value)
+		}
+	}
+	if len(doc) != len(want) {
+		t.Errorf("unexpected document %v", doc)
+	}
+}
+
+func TestSchemaSerializer_CustomRenameOverrides(t *testing.T) {
+	doc := serializeDocument(t, SchemaConfig{
+		Name:   SchemaECS,
+		Rename: map[string]string{"message": "msg", "level": "severity"},
+	})
+
+	if doc["msg"] != "user logged in" || doc["severity"] != "info" {
+		t.Errorf("expected the renames to override the schema, got %v", doc)
+	}
+	if _, ok := doc["log.level"]; ok {
+		t.Errorf("unexpected ecs level field in %v", doc)
+	}
+	if doc["@timestamp"] == nil {
+		t.Errorf("expected the other ecs names to be kept, got %v", doc)
+	}
+
+	// Without a schema, renames apply to the canonical names
+	doc = serializeDocument(t, SchemaConfig{Rename: map[string]string{"timestamp": "ts"}})
+	if doc["ts"] == nil || doc["message"] != "user logged in" || doc["timestamp"] != nil {
+		t.Errorf("unexpected document %v", doc)
+	}
+}
+
+func TestSchemaSerializer_Msgpack(t *testing.T) {
+	serializer, err := NewSerializer(BaseConfig{
+		Serialization: SerializationConfig{Format: SerializationMsgpack},
+		Schema:        SchemaConfig{Name: SchemaECS},
+	})
+	if err != nil {
+		t.Fatalf("NewSerializer() error = %v", err)
+	}
+	if serializer.ContentType() != "application/msgpack" || recordSeparator(serializer) != nil {
+		t.Errorf("unexpected msgpack framing")
+	}
+
+	data, err := serializer.Serialize(testSerializerEvent())
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	var doc map[string]interface{}
+	if err := msgpack.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		t.Fatalf("msgpack decode failed: %v", err)
+	}
+	if doc["log.level"] != "info" {
+		t.Errorf("unexpected document %v", doc)
+	}
+}
+
+func TestNewSerializer_SchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BaseConfig
+	}{
+		{"unknown schema", BaseConfig{Schema: SchemaConfig{Name: "otel"}}},
+		{"unknown field", BaseConfig{Schema: SchemaConfig{Rename: map[string]string{"host": "hostname"}}}},
+		{"empty name", BaseConfig{Schema: SchemaConfig{Rename: map[string]string{"message": ""}}}},
+		{"avro", BaseConfig{
+			Serialization: SerializationConfig{Format: SerializationAvro},
+			Schema:        SchemaConfig{Name: SchemaECS},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSerializer(tt.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	// Without a schema the format's own serializer is used
+	serializer, err := NewSerializer(BaseConfig{})
+	if err != nil {
+		t.Fatalf("NewSerializer() error = %v", err)
+	}
+	if _, ok := serializer.(*JSONSerializer); !ok {
+		t.Errorf("expected the JSON serializer, got %T", serializer)
+	}
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient implements sarama.SCRAMClient on top of the XDG SCRAM library
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	hashGenerator scram.HashGeneratorFcn
+}
+
+// newSCRAMSHA256Client creates a SCRAM client for SCRAM-SHA-256
+func newSCRAMSHA256Client() sarama.SCRAMClient {
+	return &scramClient{hashGenerator: sha256.New}
+}
+
+// newSCRAMSHA512Client creates a SCRAM client for SCRAM-SHA-512
+func newSCRAMSHA512Client() sarama.SCRAMClient {
+	return &scramClient{hashGenerator: sha512.New}
+}
+
+// Begin starts a SCRAM conversation for the given credentials
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.Client = client
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
+
+// Step answers a server challenge
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+// Done reports whether the conversation has completed
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
@@ -0,0 +1,176 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// SerializationFormat defines how events are encoded for an output
+type SerializationFormat string
+
+const (
+	SerializationJSON    SerializationFormat = "json"
+	SerializationMsgpack SerializationFormat = "msgpack"
+	SerializationAvro    SerializationFormat = "avro"
+)
+
+// DefaultAvroSchema is the Avro schema used when none is configured. It
+// mirrors the JSON encoding of a LogEvent.
+const DefaultAvroSchema = `{
+  "type": "record",
+  "name": "LogEvent",
+  "namespace": "logaggregator",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "message", "type": "string"},
+    {"name": "level", "type": "string", "default": ""},
+    {"name": "source", "type": "string", "default": ""},
+    {"name": "fields", "type": {"type": "map", "values": "string"}, "default": {}},
+    {"name": "raw", "type": "string", "default": ""}
+  ]
+}`
+
+// SerializationConfig selects the encoding of events sent by an output
+type SerializationConfig struct {
+	// Format is the encoding: json (default), msgpack or avro
+	Format SerializationFormat `yaml:"format,omitempty"`
+
+	// AvroSchema is the Avro record schema (JSON) for the avro format;
+	// DefaultAvroSchema is used when empty. Schema fields other than the
+	// event's own are filled from event fields of the same name.
+	AvroSchema string `yaml:"avro_schema,omitempty"`
+}
+
+// Serializer encodes events for an output
+type Serializer interface {
+	// Serialize encodes a single event
+	Serialize(event *types.LogEvent) ([]byte, error)
+
+	// ContentType returns the MIME type of the encoded events
+	ContentType() string
+}
+
+// GetSerializer returns a serializer for the configured format
+func GetSerializer(config SerializationConfig) (Serializer, error) {
+	switch config.Format {
+	case "", SerializationJSON:
+		return &JSONSerializer{}, nil
+	case SerializationMsgpack:
+		return &MsgpackSerializer{}, nil
+	case SerializationAvro:
+		return NewAvroSerializer(config.AvroSchema)
+	default:
+		return nil, fmt.Errorf("unsupported serialization format: %s", config.Format)
+	}
+}
+
+// recordSeparator returns the bytes written between events serialized into
+// a single object. JSON events are newline-delimited; msgpack and Avro
+// records are self-delimiting.
+func recordSeparator(serializer Serializer) []byte {
+	switch s := serializer.(type) {
+	case *JSONSerializer:
+		return []byte{'\n'}
+	case *SchemaSerializer:
+		if s.format == SerializationJSON {
+			return []byte{'\n'}
+		}
+	}
+	return nil
+}
+
+// JSONSerializer encodes events as JSON
+type JSONSerializer struct{}
+
+func (s *JSONSerializer) Serialize(event *types.LogEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (s *JSONSerializer) ContentType() string {
+	return "application/json"
+}
+
+// MsgpackSerializer encodes events as MessagePack maps keyed like the JSON
+// encoding
+type MsgpackSerializer struct{}
+
+func (s *MsgpackSerializer) Serialize(event *types.LogEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(event); err != nil {
+		return nil, fmt.Errorf("msgpack encode failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *MsgpackSerializer) ContentType() string {
+	return "application/msgpack"
+}
+
+// AvroSerializer encodes events as Avro binary records
+type AvroSerializer struct {
+	schema *avro.RecordSchema
+}
+
+// NewAvroSerializer creates an Avro serializer for a record schema, or for
+// DefaultAvroSchema when schema is empty
+func NewAvroSerializer(schema string) (*AvroSerializer, error) {
+	if schema == "" {
+		schema = DefaultAvroSchema
+	}
+
+	parsed, err := avro.Parse(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	record, ok := parsed.(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("avro schema must be a record, got %s", parsed.Type())
+	}
+
+	return &AvroSerializer{schema: record}, nil
+}
+
+func (s *AvroSerializer) Serialize(event *types.LogEvent) ([]byte, error) {
+	record := map[string]interface{}{
+		"timestamp": event.Timestamp,
+		"message":   event.Message,
+		"level":     event.Level,
+		"source":    event.Source,
+		"fields":    event.Fields,
+		"raw":       event.Raw,
+	}
+	if event.Fields == nil {
+		record["fields"] = map[string]string{}
+	}
+	for _, field := range s.schema.Fields() {
+		if _, ok := record[field.Name()]; ok {
+			continue
+		}
+		if value, ok := event.Fields[field.Name()]; ok {
+			record[field.Name()] = value
+		}
+	}
+
+	data, err := avro.Marshal(s.schema, record)
+	if err != nil {
+		return nil, fmt.Errorf("avro encode failed: %w", err)
+	}
+	return data, nil
+}
+
+func (s *AvroSerializer) ContentType() string {
+	return "application/avro"
+}
+
+// Schema returns the record schema events are encoded with
+func (s *AvroSerializer) Schema() *avro.RecordSchema {
+	return s.schema
+}
@@ -0,0 +1,192 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func testSerializerEvent() *types.LogEvent {
+	return &types.LogEvent{
+		Timestamp: time.Date(2024, 3, 15, 10, 30, 0, 123456000, time.UTC),
+		Message:   "user logged in",
+		Level:     "info",
+		Source:    "/var/log/app.log",
+		Fields:    map[string]string{"user": "alice", "status": "200"},
+		Raw:       `{"msg":"user logged in"}`,
+	}
+}
+
+// assertSameEvent compares the serialized parts of two events
+func assertSameEvent(t *testing.T, got, want *types.LogEvent) {
+	t.Helper()
+
+	if !got.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+	}
+	if got.Message != want.Message || got.Level != want.Level || got.Source != want.Source || got.Raw != want.Raw {
+		t.Errorf("decoded event = %+v, want %+v", got, want)
+	}
+	if len(got.Fields) != len(want.Fields) {
+		t.Fatalf("fields = %v, want %v", got.Fields, want.Fields)
+	}
+	for key, value := range want.Fields {
+		if got.Fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, got.Fields[key], value)
+		}
+	}
+}
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	event := testSerializerEvent()
+
+	tests := []struct {
+		format      SerializationFormat
+		contentType string
+		decode      func(data []byte, event *types.LogEvent) error
+	}{
+		{
+			format:      SerializationJSON,
+			contentType: "application/json",
+			decode: func(data []byte, event *types.LogEvent) error {
+				return json.Unmarshal(data, event)
+			},
+		},
+		{
+			format:      SerializationMsgpack,
+			contentType: "application/msgpack",
+			decode: func(data []byte, event *types.LogEvent) error {
+				decoder := msgpack.NewDecoder(bytes.NewReader(data))
+				decoder.SetCustomStructTag("json")
+				return decoder.Decode(event)
+			},
+		},
+		{
+			format:      SerializationAvro,
+			contentType: "application/avro",
+			decode: func(data []byte, event *types.LogEvent) error {
+				var record struct {
+					Timestamp time.Time         `avro:"timestamp"`
+					Message   string            `avro:"message"`
+					Level     string            `avro:"level"`
+					Source    string            `avro:"source"`
+					Fields    map[string]string `avro:"fields"`
+					Raw       string            `avro:"raw"`
+				}
+				if err := avro.Unmarshal(avro.MustParse(DefaultAvroSchema), data, &record); err != nil {
+					return err
+				}
+				*event = types.LogEvent{
+					Timestamp: record.Timestamp,
+					Message:   record.Message,
+					Level:     record.Level,
+					Source:    record.Source,
+					Fields:    record.Fields,
+					Raw:       record.Raw,
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			serializer, err := GetSerializer(SerializationConfig{Format: tt.format})
+			if err != nil {
+				t.Fatalf("GetSerializer() error = %v", err)
+			}
+			if got := serializer.ContentType(); got != tt.contentType {
+				t.Errorf("ContentType() = %q, want %q", got, tt.contentType)
+			}
+
+			data, err := serializer.Serialize(event)
+			if err != nil {
+				t.Fatalf("Serialize() error = %v", err)
+			}
+
+			var decoded types.LogEvent
+			if err := tt.decode(data, &decoded); err != nil {
+				t.Fatalf("failed to decode %s: %v", tt.format, err)
+			}
+			assertSameEvent(t, &decoded, event)
+		})
+	}
+}
+
+func TestAvroSerializer_CustomSchema(t *testing.T) {
+	schema := `{
+  "type": "record",
+  "name": "AccessLog",
+  "fields": [
+    {"name": "message", "type": "string"},
+    {"name": "user", "type": "string"},
+    {"name": "region", "type": ["null", "string"], "default": null}
+  ]
+}`
+
+	serializer, err := NewAvroSerializer(schema)
+	if err != nil {
+		t.Fatalf("NewAvroSerializer() error = %v", err)
+	}
+
+	data, err := serializer.Serialize(testSerializerEvent())
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	var record struct {
+		Message string  `avro:"message"`
+		User    string  `avro:"user"`
+		Region  *string `avro:"region"`
+	}
+	if err := avro.Unmarshal(serializer.Schema(), data, &record); err != nil {
+		t.Fatalf("failed to decode avro: %v", err)
+	}
+	if record.Message != "user logged in" || record.User != "alice" || record.Region != nil {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	// A required schema field the event doesn't have cannot be encoded
+	if _, err := serializer.Serialize(&types.LogEvent{Message: "no user"}); err == nil {
+		t.Error("expected error for an event missing a required field")
+	}
+}
+
+func TestGetSerializer_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SerializationConfig
+	}{
+		{"unknown format", SerializationConfig{Format: "xml"}},
+		{"invalid avro schema", SerializationConfig{Format: SerializationAvro, AvroSchema: `{"type": "record"}`}},
+		{"non-record avro schema", SerializationConfig{Format: SerializationAvro, AvroSchema: `"string"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GetSerializer(tt.config); err == nil {
+				t.Error("expected GetSerializer() to fail")
+			}
+		})
+	}
+}
+
+func TestDecodeConfig_Serialization(t *testing.T) {
+	config := DefaultKafkaConfig()
+	settings := map[string]interface{}{
+		"topic":         "logs",
+		"serialization": map[string]interface{}{"format": "msgpack"},
+	}
+	if err := DecodeConfig(settings, &config); err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+	if config.Serialization.Format != SerializationMsgpack {
+		t.Errorf("expected msgpack serialization, got %q", config.Serialization.Format)
+	}
+}
@@ -0,0 +1,236 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// StdoutConfig contains stdout output configuration
+type StdoutConfig struct {
+	BaseConfig `yaml:",inline"`
+
+	// Writer receives the encoded events (stdout by default)
+	Writer io.Writer `yaml:"-"`
+}
+
+func init() {
+	Register("stdout", func(cfg map[string]interface{}) (Output, error) {
+		config := DefaultStdoutConfig()
+		if err := DecodeConfig(cfg, &config); err != nil {
+			return nil, err
+		}
+		return NewStdoutOutput(config)
+	})
+}
+
+// DefaultStdoutConfig returns default stdout output configuration. Batches
+// are flushed quickly so lines still show up promptly when events are few.
+func DefaultStdoutConfig() StdoutConfig {
+	base := DefaultBaseConfig()
+	base.FlushInterval = 100 * time.Millisecond
+
+	return StdoutConfig{BaseConfig: base}
+}
+
+// StdoutOutput writes events to stdout, one JSON document per line by
+// default. With a batch size above one, events are buffered and each batch
+// is written at once. The file output writes to its file through one.
+type StdoutOutput struct {
+	kind       string // stdout, or file for the file output
+	config     StdoutConfig
+	writer     io.Writer
+	serializer Serializer
+	separator  []byte
+	batcher    *Batcher
+	metrics    *OutputMetrics
+	latency    LatencyHistogram
+	mu         sync.Mutex
+	closed     atomic.Bool
+
+	instrumentation
+	flushErrorHook
+}
+
+// NewStdoutOutput creates a new stdout output
+func NewStdoutOutput(config StdoutConfig) (*StdoutOutput, error) {
+	if config.Writer == nil {
+		config.Writer = os.Stdout
+	}
+	return newWriterOutput("stdout", config)
+}
+
+// newWriterOutput creates an output of the given kind writing to
+// config.Writer
+func newWriterOutput(kind string, config StdoutConfig) (*StdoutOutput, error) {
+	serializer, err := NewSerializer(config.BaseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &StdoutOutput{
+		kind:       kind,
+		config:     config,
+		writer:     config.Writer,
+		serializer: serializer,
+		separator:  recordSeparator(serializer),
+		metrics:    &OutputMetrics{},
+	}
+
+	if config.BatchSize > 1 {
+		output.batcher = NewBatcher(BatcherConfig{
+			MaxBatchSize:  config.BatchSize,
+			FlushInterval: config.FlushInterval,
+			OnFlush: func(trigger FlushTrigger) {
+				output.observeFlush(output.Name(), kind, trigger)
+			},
+			Adaptive: config.AdaptiveBatch,
+			Ordered:  config.Ordered,
+			Metrics:  output.Metrics,
+			OnResize: func(size int) {
+				output.observeBatchSize(output.Name(), kind, size)
+			},
+			OnFlushError: output.flushFailed,
+		}, output.write)
+	}
+
+	return output, nil
+}
+
+// Send writes an event, or adds it to the current batch
+func (s *StdoutOutput) Send(ctx context.Context, event *types.LogEvent) error {
+	if s.closed.Load() {
+		return fmt.Errorf("%s output is closed", s.kind)
+	}
+
+	if s.batcher != nil {
+		return s.batcher.Add(ctx, event)
+	}
+	return s.write(ctx, []*types.LogEvent{event})
+}
+
+// SendBatch writes a batch of events at once
+func (s *StdoutOutput) SendBatch(ctx context.Context, events []*types.LogEvent) error {
+	if s.closed.Load() {
+		return fmt.Errorf("%s output is closed", s.kind)
+	}
+
+	return s.write(ctx, events)
+}
+
+// write encodes events and writes them in a single write
+func (s *StdoutOutput) write(_ context.Context, events []*types.LogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	var encoded int
+	for _, event := range events {
+		data, err := s.serializer.Serialize(event)
+		if err != nil {
+			s.recordFailure(1, err)
+			continue
+		}
+		buf.Write(data)
+		buf.Write(s.separator)
+		encoded++
+	}
+	if encoded == 0 {
+		return classifyf(ErrSerialization, "failed to encode any of %d events", len(events))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	startTime := time.Now()
+	n, err := s.writer.Write(buf.Bytes())
+	latency := time.Since(startTime)
+
+	if err != nil {
+		s.metrics.EventsFailed += int64(encoded)
+		s.metrics.LastError = err.Error()
+		s.metrics.LastErrorTime = time.Now()
+		return classifyf(ErrPermanent, "failed to write to %s: %w", s.kind, err)
+	}
+
+	s.metrics.EventsSent += int64(encoded)
+	s.metrics.BytesSent += int64(n)
+	s.metrics.BatchesSent++
+	s.metrics.AvgBatchSize = float64(s.metrics.EventsSent) / float64(s.metrics.BatchesSent)
+	s.metrics.LastSendTime = time.Now()
+	s.latency.Record(latency)
+	s.observeBatch(s.Name(), s.kind, encoded, int64(n), latency)
+	return nil
+}
+
+// recordFailure counts failed events and records the error
+func (s *StdoutOutput) recordFailure(events int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.EventsFailed += int64(events)
+	s.metrics.LastError = err.Error()
+	s.metrics.LastErrorTime = time.Now()
+}
+
+// Flush writes any events buffered in the batcher
+func (s *StdoutOutput) Flush(ctx context.Context) error {
+	if s.batcher == nil {
+		return nil
+	}
+	return s.batcher.Flush(ctx)
+}
+
+// SetBatchConfig updates the batcher's size and flush interval
+func (s *StdoutOutput) SetBatchConfig(batchSize int, flushInterval time.Duration) bool {
+	if s.batcher == nil {
+		return false
+	}
+	s.batcher.SetLimits(batchSize, 0, flushInterval)
+	return true
+}
+
+// Close writes the buffered events and closes the output. The writer is
+// left open.
+func (s *StdoutOutput) Close() error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil // Already closed
+	}
+
+	if s.batcher != nil {
+		return s.batcher.Stop()
+	}
+	return nil
+}
+
+// HealthCheck always succeeds
+func (s *StdoutOutput) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Name returns the output name
+func (s *StdoutOutput) Name() string {
+	if s.config.Name != "" {
+		return s.config.Name
+	}
+	return s.kind
+}
+
+// Metrics returns the current metrics
+func (s *StdoutOutput) Metrics() *OutputMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Return a copy
+	metricsCopy := *s.metrics
+	s.latency.fill(&metricsCopy)
+	return &metricsCopy
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// captureStdout redirects os.Stdout while fn runs and returns what was
+// written to it
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	read := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		read <- data
+	}()
+
+	fn()
+	w.Close()
+	return string(<-read)
+}
+
+func TestStdoutOutput_Batched(t *testing.T) {
+	collector := metrics.NewCollector()
+
+	output := captureStdout(t, func() {
+		config := DefaultStdoutConfig()
+		config.BatchSize = 3
+		config.FlushInterval = time.Hour
+		out, err := NewStdoutOutput(config)
+		if err != nil {
+			t.Fatalf("NewStdoutOutput() error = %v", err)
+		}
+		out.SetCollector(collector)
+
+		for i := 0; i < 4; i++ {
+			event := &types.LogEvent{Message: fmt.Sprintf("event-%d", i), Source: "test"}
+			if err := out.Send(context.Background(), event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+		}
+		// The fourth event is written when the output is closed
+		if err := out.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if metrics := out.Metrics(); metrics.EventsSent != 4 || metrics.BatchesSent != 2 {
+			t.Errorf("expected 4 events in 2 batches, got %d in %d", metrics.EventsSent, metrics.BatchesSent)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %q", len(lines), output)
+	}
+	for i, line := range lines {
+		var event types.LogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		if event.Message != fmt.Sprintf("event-%d", i) || event.Source != "test" {
+			t.Errorf("line %d = %+v", i, event)
+		}
+	}
+
+	batchSize := findMetric(t, collector, "logaggregator_output_batch_size", "stdout", "stdout").GetHistogram()
+	if batchSize.GetSampleCount() != 2 || batchSize.GetSampleSum() != 4 {
+		t.Errorf("batch size histogram count = %d, sum = %v, want 2 and 4", batchSize.GetSampleCount(), batchSize.GetSampleSum())
+	}
+	bytesSent := findMetric(t, collector, "logaggregator_output_bytes_sent_total", "stdout", "stdout").GetCounter()
+	if bytesSent.GetValue() != float64(len(output)) {
+		t.Errorf("bytes sent = %v, want %d", bytesSent.GetValue(), len(output))
+	}
+}
+
+func TestStdoutOutput_Unbatched(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := New("stdout", map[string]interface{}{"batch_size": 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	out.(*StdoutOutput).writer = &buf
+
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var event types.LogEvent
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil || event.Message != "hello" || !strings.HasSuffix(buf.String(), "}\n") {
+		t.Errorf("expected the event written at once as a JSON line, got %q", buf.String())
+	}
+
+	out.Close()
+	if err := out.Send(context.Background(), &types.LogEvent{Message: "late"}); err == nil {
+		t.Error("expected an error sending to a closed output")
+	}
+}
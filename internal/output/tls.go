@@ -0,0 +1,55 @@
+package output
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/security"
+)
+
+// TLSClientConfig contains the TLS settings for outputs that connect to a
+// remote cluster. Certificates and keys are paths to PEM files.
+type TLSClientConfig struct {
+	// TLSCACert is the CA bundle used to verify the cluster's certificate
+	TLSCACert string `yaml:"tls_ca_cert,omitempty"`
+
+	// TLSClientCert is the client certificate for mutual TLS
+	TLSClientCert string `yaml:"tls_client_cert,omitempty"`
+
+	// TLSClientKey is the private key of the client certificate
+	TLSClientKey string `yaml:"tls_client_key,omitempty"`
+
+	// TLSInsecureSkipVerify disables certificate verification, e.g. for
+	// self-signed clusters
+	TLSInsecureSkipVerify bool `yaml:"tls_insecure_skip_verify,omitempty"`
+}
+
+// configured reports whether any TLS setting is set
+func (c TLSClientConfig) configured() bool {
+	return c.TLSCACert != "" || c.TLSClientCert != "" || c.TLSClientKey != "" || c.TLSInsecureSkipVerify
+}
+
+// buildTLSConfig returns the client TLS configuration, or nil if TLS is
+// neither enabled nor configured
+func (c TLSClientConfig) buildTLSConfig(enabled bool) (*tls.Config, error) {
+	if !enabled && !c.configured() {
+		return nil, nil
+	}
+
+	if (c.TLSClientCert == "") != (c.TLSClientKey == "") {
+		return nil, fmt.Errorf("tls_client_cert and tls_client_key must be set together")
+	}
+
+	tlsConfig, err := security.LoadTLSConfig(&security.TLSConfig{
+		Enabled:            true,
+		CertFile:           c.TLSClientCert,
+		KeyFile:            c.TLSClientKey,
+		CAFile:             c.TLSCACert,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,197 @@
+package output
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key to dir
+// and returns their paths
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "logaggregator-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSClientConfig_Build(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		config    TLSClientConfig
+		wantNil   bool
+		wantErr   bool
+		wantCA    bool
+		wantCerts int
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "enabled with system roots", enabled: true},
+		{name: "ca implies tls", config: TLSClientConfig{TLSCACert: certFile}, wantCA: true},
+		{
+			name:      "mutual tls",
+			config:    TLSClientConfig{TLSCACert: certFile, TLSClientCert: certFile, TLSClientKey: keyFile},
+			wantCA:    true,
+			wantCerts: 1,
+		},
+		{name: "cert without key", config: TLSClientConfig{TLSClientCert: certFile}, wantErr: true},
+		{name: "missing ca file", config: TLSClientConfig{TLSCACert: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+		{name: "invalid key pair", config: TLSClientConfig{TLSClientCert: certFile, TLSClientKey: certFile}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.config.buildTLSConfig(tt.enabled)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (tlsConfig == nil) != tt.wantNil {
+				t.Fatalf("buildTLSConfig() = %v, wantNil %v", tlsConfig, tt.wantNil)
+			}
+			if tlsConfig == nil {
+				return
+			}
+			if (tlsConfig.RootCAs != nil) != tt.wantCA {
+				t.Errorf("RootCAs set = %v, want %v", tlsConfig.RootCAs != nil, tt.wantCA)
+			}
+			if len(tlsConfig.Certificates) != tt.wantCerts {
+				t.Errorf("expected %d client certificates, got %d", tt.wantCerts, len(tlsConfig.Certificates))
+			}
+		})
+	}
+}
+
+func TestTLSClientConfig_InsecureSkipVerify(t *testing.T) {
+	config := TLSClientConfig{TLSInsecureSkipVerify: true}
+
+	tlsConfig, err := config.buildTLSConfig(false)
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+	if tlsConfig == nil || !tlsConfig.InsecureSkipVerify {
+		t.Errorf("expected InsecureSkipVerify to be set, got %+v", tlsConfig)
+	}
+}
+
+func TestNewSaramaConfig_TLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+
+	config := DefaultKafkaConfig()
+	config.Brokers = []string{"localhost:9093"}
+	config.Topic = "logs"
+	config.TLSCACert = certFile
+	config.TLSClientCert = certFile
+	config.TLSClientKey = keyFile
+
+	saramaConfig, err := newSaramaConfig(config)
+	if err != nil {
+		t.Fatalf("newSaramaConfig() error = %v", err)
+	}
+	if !saramaConfig.Net.TLS.Enable || saramaConfig.Net.TLS.Config == nil {
+		t.Fatal("expected TLS to be enabled with a client configuration")
+	}
+	if len(saramaConfig.Net.TLS.Config.Certificates) != 1 {
+		t.Errorf("expected the client certificate to be loaded")
+	}
+
+	config.TLSClientKey = ""
+	if _, err := newSaramaConfig(config); err == nil {
+		t.Error("expected error for a client certificate without a key")
+	}
+}
+
+func TestNewElasticsearchClientConfig_TLS(t *testing.T) {
+	certFile, _ := writeTestCertificate(t, t.TempDir())
+
+	config := DefaultElasticsearchConfig()
+	config.Addresses = []string{"https://localhost:9200"}
+
+	esConfig, err := newElasticsearchClientConfig(config)
+	if err != nil {
+		t.Fatalf("newElasticsearchClientConfig() error = %v", err)
+	}
+	if esConfig.Transport != nil {
+		t.Error("expected the default transport without TLS settings")
+	}
+
+	config.TLSCACert = certFile
+	esConfig, err = newElasticsearchClientConfig(config)
+	if err != nil {
+		t.Fatalf("newElasticsearchClientConfig() error = %v", err)
+	}
+	transport, ok := esConfig.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport, got %T", esConfig.Transport)
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs == nil {
+		t.Error("expected the transport to trust the configured CA")
+	}
+}
+
+func TestDecodeConfig_TLS(t *testing.T) {
+	var config KafkaConfig
+	err := DecodeConfig(map[string]interface{}{
+		"tls_ca_cert":              "/etc/ssl/ca.pem",
+		"tls_client_cert":          "/etc/ssl/client.pem",
+		"tls_client_key":           "/etc/ssl/client-key.pem",
+		"tls_insecure_skip_verify": true,
+	}, &config)
+	if err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+
+	want := TLSClientConfig{
+		TLSCACert:             "/etc/ssl/ca.pem",
+		TLSClientCert:         "/etc/ssl/client.pem",
+		TLSClientKey:          "/etc/ssl/client-key.pem",
+		TLSInsecureSkipVerify: true,
+	}
+	if config.TLSClientConfig != want {
+		t.Errorf("TLSClientConfig = %+v, want %+v", config.TLSClientConfig, want)
+	}
+}
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Multiline match modes
+const (
+	MultilineMatchAfter  = "after"
+	MultilineMatchBefore = "before"
+)
+
+// MultilineAssembler joins physical lines into logical events, such as a
+// stack trace following the line that raised it. Lines are buffered per
+// event source so interleaved files do not mix.
+//
+// A line "matches" when it matches Pattern (inverted by Negate). With Match
+// "after" (the default), a matching line starts a new event and the lines
+// after it are appended. With Match "before", a matching line ends the
+// current event, so the lines before it are joined with it. A buffered
+// event is also flushed once it reaches MaxLines or sits idle for Timeout.
+type MultilineAssembler struct {
+	pattern  *regexp.Regexp
+	negate   bool
+	match    string
+	maxLines int
+	timeout  time.Duration
+	pending  map[string]*pendingEvent
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// pendingEvent is a logical event being assembled for one source
+type pendingEvent struct {
+	first      *types.LogEvent
+	lines      []string
+	lastUpdate time.Time
+}
+
+// NewMultilineAssembler creates an assembler from a multiline configuration
+func NewMultilineAssembler(cfg *MultilineConfig) (*MultilineAssembler, error) {
+	if cfg == nil || cfg.Pattern == "" {
+		return nil, fmt.Errorf("multiline pattern is required")
+	}
+
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile multiline pattern: %w", err)
+	}
+
+	match := cfg.Match
+	if match == "" {
+		match = MultilineMatchAfter
+	}
+	if match != MultilineMatchAfter && match != MultilineMatchBefore {
+		return nil, fmt.Errorf("invalid multiline match %q: must be %q or %q", cfg.Match, MultilineMatchAfter, MultilineMatchBefore)
+	}
+
+	// Parse timeout
+	timeout := 5 * time.Second
+	if cfg.Timeout != "" {
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multiline timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("multiline timeout must be positive: %s", cfg.Timeout)
+		}
+	}
+
+	// Set max lines default
+	maxLines := cfg.MaxLines
+	if maxLines == 0 {
+		maxLines = 500
+	}
+
+	return &MultilineAssembler{
+		pattern:  pattern,
+		negate:   cfg.Negate,
+		match:    match,
+		maxLines: maxLines,
+		timeout:  timeout,
+		pending:  make(map[string]*pendingEvent),
+		now:      time.Now,
+	}, nil
+}
+
+// Add feeds one line event and returns any logical events it completed
+func (a *MultilineAssembler) Add(event *types.LogEvent) []*types.LogEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	line := strings.TrimRight(event.Message, "\r\n")
+	matches := a.pattern.MatchString(line) != a.negate
+	p := a.pending[event.Source]
+
+	var completed []*types.LogEvent
+
+	if a.match == MultilineMatchAfter && matches && p != nil {
+		// A new event starts; the buffered one is complete
+		completed = append(completed, a.flushLocked(event.Source))
+		p = nil
+	}
+
+	if p == nil {
+		p = &pendingEvent{first: event}
+		a.pending[event.Source] = p
+	}
+	p.lines = append(p.lines, line)
+	p.lastUpdate = a.now()
+
+	if (a.match == MultilineMatchBefore && matches) || len(p.lines) >= a.maxLines {
+		completed = append(completed, a.flushLocked(event.Source))
+	}
+
+	return completed
+}
+
+// FlushExpired returns buffered events idle for longer than the timeout
+func (a *MultilineAssembler) FlushExpired() []*types.LogEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := a.now().Add(-a.timeout)
+
+	var expired []*types.LogEvent
+	for source, p := range a.pending {
+		if !p.lastUpdate.After(cutoff) {
+			expired = append(expired, a.flushLocked(source))
+		}
+	}
+	return expired
+}
+
+// Flush returns all buffered events regardless of age
+func (a *MultilineAssembler) Flush() []*types.LogEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var events []*types.LogEvent
+	for source := range a.pending {
+		events = append(events, a.flushLocked(source))
+	}
+	return events
+}
+
+// Run assembles events from in until it is closed or ctx is done, flushing
+// idle events on timeout. The returned channel is closed when Run stops.
+func (a *MultilineAssembler) Run(ctx context.Context, in <-chan *types.LogEvent) <-chan *types.LogEvent {
+	out := make(chan *types.LogEvent, cap(in))
+
+	go func() {
+		defer close(out)
+
+		interval := a.timeout / 2
+		if interval > time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		emit := func(events []*types.LogEvent) bool {
+			for _, event := range events {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		for {
+			select {
+			case event, ok := <-in:
+				if !ok {
+					emit(a.Flush())
+					return
+				}
+				if !emit(a.Add(event)) {
+					return
+				}
+			case <-ticker.C:
+				if !emit(a.FlushExpired()) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// flushLocked joins the pending lines of a source into one event
+func (a *MultilineAssembler) flushLocked(source string) *types.LogEvent {
+	p := a.pending[source]
+	delete(a.pending, source)
+
+	event := *p.first
+	event.Message = strings.Join(p.lines, "\n")
+	event.Raw = event.Message
+	return &event
+}
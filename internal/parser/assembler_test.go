@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func lineEvent(source, line string) *types.LogEvent {
+	return &types.LogEvent{
+		Timestamp: time.Now(),
+		Message:   line + "\n",
+		Source:    source,
+		Fields:    make(map[string]string),
+	}
+}
+
+func assembleAll(t *testing.T, cfg *MultilineConfig, lines []string) []string {
+	t.Helper()
+
+	a, err := NewMultilineAssembler(cfg)
+	if err != nil {
+		t.Fatalf("NewMultilineAssembler() error = %v", err)
+	}
+
+	var got []string
+	for _, line := range lines {
+		for _, event := range a.Add(lineEvent("app.log", line)) {
+			got = append(got, event.Message)
+		}
+	}
+	for _, event := range a.Flush() {
+		got = append(got, event.Message)
+	}
+	return got
+}
+
+func TestMultilineAssembler_Assemble(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *MultilineConfig
+		lines  []string
+		want   []string
+	}{
+		{
+			name:   "java stack trace",
+			config: &MultilineConfig{Pattern: `^\d{4}-\d{2}-\d{2}`},
+			lines: []string{
+				"2024-01-15 10:30:00 ERROR Request failed",
+				"java.lang.NullPointerException: value is null",
+				"\tat com.example.Service.handle(Service.java:42)",
+				"\tat com.example.Server.run(Server.java:17)",
+				"Caused by: java.io.IOException: closed",
+				"\t... 2 more",
+				"2024-01-15 10:30:01 INFO Recovered",
+			},
+			want: []string{
+				"2024-01-15 10:30:00 ERROR Request failed\n" +
+					"java.lang.NullPointerException: value is null\n" +
+					"\tat com.example.Service.handle(Service.java:42)\n" +
+					"\tat com.example.Server.run(Server.java:17)\n" +
+					"Caused by: java.io.IOException: closed\n" +
+					"\t... 2 more",
+				"2024-01-15 10:30:01 INFO Recovered",
+			},
+		},
+		{
+			name:   "negate treats non-matching lines as event starts",
+			config: &MultilineConfig{Pattern: `^\s`, Negate: true},
+			lines:  []string{"first", "  continued", "second", "third", "\tcontinued"},
+			want:   []string{"first\n  continued", "second", "third\n\tcontinued"},
+		},
+		{
+			name:   "before ends the event on a matching line",
+			config: &MultilineConfig{Pattern: `;$`, Match: MultilineMatchBefore},
+			lines:  []string{"SELECT *", "FROM users;", "DELETE", "FROM t", "WHERE x;"},
+			want:   []string{"SELECT *\nFROM users;", "DELETE\nFROM t\nWHERE x;"},
+		},
+		{
+			name:   "max lines splits long events",
+			config: &MultilineConfig{Pattern: `^START`, MaxLines: 2},
+			lines:  []string{"START a", "b", "c", "START d"},
+			want:   []string{"START a\nb", "c", "START d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assembleAll(t, tt.config, tt.lines)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events %q, want %d %q", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMultilineAssembler_SeparateSources(t *testing.T) {
+	a, err := NewMultilineAssembler(&MultilineConfig{Pattern: `^\S`})
+	if err != nil {
+		t.Fatalf("NewMultilineAssembler() error = %v", err)
+	}
+
+	a.Add(lineEvent("a.log", "a1"))
+	a.Add(lineEvent("b.log", "b1"))
+	a.Add(lineEvent("a.log", "  a2"))
+	a.Add(lineEvent("b.log", "  b2"))
+
+	got := make(map[string]string)
+	for _, event := range a.Flush() {
+		got[event.Source] = event.Message
+	}
+
+	if got["a.log"] != "a1\n  a2" {
+		t.Errorf("a.log event = %q, want %q", got["a.log"], "a1\n  a2")
+	}
+	if got["b.log"] != "b1\n  b2" {
+		t.Errorf("b.log event = %q, want %q", got["b.log"], "b1\n  b2")
+	}
+}
+
+func TestMultilineAssembler_FlushExpired(t *testing.T) {
+	a, err := NewMultilineAssembler(&MultilineConfig{Pattern: `^\S`, Timeout: "1s"})
+	if err != nil {
+		t.Fatalf("NewMultilineAssembler() error = %v", err)
+	}
+
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	a.Add(lineEvent("app.log", "panic: boom"))
+	a.Add(lineEvent("app.log", "  goroutine 1"))
+
+	if events := a.FlushExpired(); len(events) != 0 {
+		t.Fatalf("FlushExpired() before timeout returned %d events", len(events))
+	}
+
+	now = now.Add(time.Second)
+	events := a.FlushExpired()
+	if len(events) != 1 {
+		t.Fatalf("FlushExpired() after timeout returned %d events, want 1", len(events))
+	}
+	if events[0].Message != "panic: boom\n  goroutine 1" {
+		t.Errorf("Message = %q", events[0].Message)
+	}
+}
+
+func TestMultilineAssembler_RunTimeout(t *testing.T) {
+	a, err := NewMultilineAssembler(&MultilineConfig{Pattern: `^\S`, Timeout: "50ms"})
+	if err != nil {
+		t.Fatalf("NewMultilineAssembler() error = %v", err)
+	}
+
+	in := make(chan *types.LogEvent, 10)
+	out := a.Run(context.Background(), in)
+
+	in <- lineEvent("app.log", "Exception in thread main")
+	in <- lineEvent("app.log", "\tat Main.main(Main.java:3)")
+
+	// The event must be flushed by the timeout without a following line
+	select {
+	case event := <-out:
+		if event.Message != "Exception in thread main\n\tat Main.main(Main.java:3)" {
+			t.Errorf("Message = %q", event.Message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for flushed event")
+	}
+
+	in <- lineEvent("app.log", "last line")
+	close(in)
+
+	event, ok := <-out
+	if !ok || event.Message != "last line" {
+		t.Errorf("expected pending event flushed on close, got %v", event)
+	}
+	if _, ok := <-out; ok {
+		t.Error("expected output channel to be closed")
+	}
+}
+
+func TestNewMultilineAssembler_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *MultilineConfig
+	}{
+		{name: "nil config", config: nil},
+		{name: "empty pattern", config: &MultilineConfig{}},
+		{name: "invalid pattern", config: &MultilineConfig{Pattern: "[invalid"}},
+		{name: "invalid match", config: &MultilineConfig{Pattern: "^x", Match: "middle"}},
+		{name: "invalid timeout", config: &MultilineConfig{Pattern: "^x", Timeout: "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMultilineAssembler(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Line formats the auto parser detects
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatPlain  = "plain"
+)
+
+// maxAutoSources bounds the per-source format cache; it is cleared when
+// full
+const maxAutoSources = 10000
+
+// AutoStats are the counters of an auto parser
+type AutoStats struct {
+	JSON      int64 // lines parsed as JSON
+	Logfmt    int64 // lines parsed as logfmt
+	Plain     int64 // lines kept as plain messages
+	CacheHits int64 // lines parsed with their source's cached format
+}
+
+// AutoParser parses streams of mixed formats. Each line is sniffed as JSON
+// first, then logfmt, and kept as a plain message when it is neither. The
+// format of each source's last line is cached and tried first, so a
+// homogeneous source is not sniffed line by line; a line the cached format
+// does not fit is sniffed again. JSON and logfmt lines take their
+// timestamp, level and message from the same fields as the JSON parser.
+type AutoParser struct {
+	json *JSONParser
+
+	mu      sync.RWMutex
+	formats map[string]string // source -> format of its last line
+
+	counts    map[string]*atomic.Int64
+	cacheHits atomic.Int64
+}
+
+// NewAutoParser creates a new auto-detecting parser
+func NewAutoParser(cfg *ParserConfig) (*AutoParser, error) {
+	jsonParser, err := NewJSONParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AutoParser{
+		json:    jsonParser,
+		formats: make(map[string]string),
+		counts: map[string]*atomic.Int64{
+			FormatJSON:   new(atomic.Int64),
+			FormatLogfmt: new(atomic.Int64),
+			FormatPlain:  new(atomic.Int64),
+		},
+	}, nil
+}
+
+// Parse detects the format of a line and parses it
+func (p *AutoParser) Parse(line string, source string) (*types.LogEvent, error) {
+	start := time.Now()
+	event, reason, err := p.parse(line, source)
+	observeParse(ParserTypeAuto, start, reason, err)
+	return event, err
+}
+
+// parse detects the format of a line and parses it, returning the reason a
+// structured line failed to parse cleanly if any
+func (p *AutoParser) parse(line string, source string) (*types.LogEvent, string, error) {
+	if line == "" {
+		return nil, "", fmt.Errorf("empty log line")
+	}
+
+	p.mu.RLock()
+	cached := p.formats[source]
+	p.mu.RUnlock()
+
+	if cached != "" {
+		if event, reason, ok := p.parseAs(cached, line, source); ok {
+			p.cacheHits.Add(1)
+			p.record(cached)
+			return event, reason, nil
+		}
+	}
+
+	for _, format := range []string{FormatJSON, FormatLogfmt} {
+		if format == cached {
+			continue
+		}
+		if event, reason, ok := p.parseAs(format, line, source); ok {
+			p.remember(source, format)
+			p.record(format)
+			return event, reason, nil
+		}
+	}
+
+	p.remember(source, FormatPlain)
+	p.record(FormatPlain)
+	return p.plain(line, source), "", nil
+}
+
+// parseAs parses a line in one format, reporting whether it fits and, for
+// structured formats, why it failed to parse cleanly
+func (p *AutoParser) parseAs(format, line, source string) (*types.LogEvent, string, bool) {
+	switch format {
+	case FormatJSON:
+		trimmed := strings.TrimSpace(line)
+		// Documents nested too deep are not decoded but kept as plain lines
+		if !strings.HasPrefix(trimmed, "{") || p.json.tooDeep(trimmed) {
+			return nil, "", false
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &data); err != nil {
+			return nil, "", false
+		}
+		event, reason := p.json.event(data, line, source)
+		return event, reason, true
+	case FormatLogfmt:
+		pairs, ok := parseLogfmt(line)
+		if !ok {
+			return nil, "", false
+		}
+		data := make(map[string]interface{}, len(pairs))
+		for key, value := range pairs {
+			data[key] = value
+		}
+		event, reason := p.json.event(data, line, source)
+		return event, reason, true
+	case FormatPlain:
+		if p.looksStructured(line) {
+			return nil, "", false
+		}
+		return p.plain(line, source), "", true
+	default:
+		return nil, "", false
+	}
+}
+
+// plain keeps a line as the message of an event
+func (p *AutoParser) plain(line, source string) *types.LogEvent {
+	event := &types.LogEvent{
+		Timestamp: time.Now(),
+		Message:   line,
+		Source:    source,
+		Fields:    make(map[string]string, len(p.json.customFields)),
+	}
+	for key, value := range p.json.customFields {
+		event.Fields[key] = value
+	}
+	return event
+}
+
+// looksStructured reports whether a line is JSON or logfmt, so a source
+// cached as plain does not swallow structured lines
+func (p *AutoParser) looksStructured(line string) bool {
+	if strings.HasPrefix(strings.TrimSpace(line), "{") && json.Valid([]byte(line)) {
+		return true
+	}
+	_, ok := parseLogfmt(line)
+	return ok
+}
+
+// remember caches the format of a source's line
+func (p *AutoParser) remember(source, format string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.formats[source]; !ok && len(p.formats) >= maxAutoSources {
+		p.formats = make(map[string]string)
+	}
+	p.formats[source] = format
+}
+
+// record counts a line resolved to a format
+func (p *AutoParser) record(format string) {
+	p.counts[format].Add(1)
+	metrics.GetGlobalCollector().ParserAutoDetected.WithLabelValues(format).Inc()
+}
+
+// Stats returns the parser's counters
+func (p *AutoParser) Stats() AutoStats {
+	return AutoStats{
+		JSON:      p.counts[FormatJSON].Load(),
+		Logfmt:    p.counts[FormatLogfmt].Load(),
+		Plain:     p.counts[FormatPlain].Load(),
+		CacheHits: p.cacheHits.Load(),
+	}
+}
+
+// Name returns the parser name
+func (p *AutoParser) Name() string {
+	return "auto"
+}
+
+// parseLogfmt parses a logfmt line of space-separated key=value pairs,
+// where values may be double-quoted with Go escapes. The line fits only if
+// every token is a pair.
+func parseLogfmt(line string) (map[string]string, bool) {
+	pairs := make(map[string]string)
+	rest := strings.TrimSpace(line)
+
+	for rest != "" {
+		eq := strings.IndexAny(rest, "= \t\"")
+		if eq <= 0 || rest[eq] != '=' {
+			return nil, false
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, false
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, false
+			}
+			rest = rest[len(quoted):]
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				return nil, false
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			if strings.ContainsAny(value, `="`) {
+				return nil, false
+			}
+			rest = rest[end:]
+		}
+
+		pairs[key] = value
+		rest = strings.TrimLeft(rest, " \t")
+	}
+
+	return pairs, len(pairs) > 0
+}
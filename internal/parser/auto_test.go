@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestAutoParser(t *testing.T, cfg *ParserConfig) *AutoParser {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = &ParserConfig{Type: ParserTypeAuto}
+	}
+	p, err := NewAutoParser(cfg)
+	if err != nil {
+		t.Fatalf("NewAutoParser() error = %v", err)
+	}
+	return p
+}
+
+func TestAutoParser_MixedStream(t *testing.T) {
+	p := newTestAutoParser(t, &ParserConfig{
+		Type:         ParserTypeAuto,
+		TimeField:    "ts",
+		CustomFields: map[string]string{"env": "test"},
+	})
+
+	tests := []struct {
+		line    string
+		message string
+		level   string
+		fields  map[string]string
+	}{
+		{
+			line:    `{"ts":"2024-01-15T10:30:00Z","level":"error","msg":"db down","db":"orders"}`,
+			message: "db down",
+			level:   "error",
+			fields:  map[string]string{"db": "orders", "env": "test"},
+		},
+		{
+			line:    `ts=2024-01-15T10:30:01Z level=warn msg="slow query" duration=1.5s`,
+			message: "slow query",
+			level:   "warn",
+			fields:  map[string]string{"duration": "1.5s", "env": "test"},
+		},
+		{
+			line:    "panic: runtime error: index out of range",
+			message: "panic: runtime error: index out of range",
+			fields:  map[string]string{"env": "test"},
+		},
+		{
+			line:    `{"message":"recovered","attempt":2}`,
+			message: "recovered",
+			fields:  map[string]string{"attempt": "2", "env": "test"},
+		},
+		{
+			line:    "{not json at all",
+			message: "{not json at all",
+			fields:  map[string]string{"env": "test"},
+		},
+	}
+
+	for _, tt := range tests {
+		event, err := p.Parse(tt.line, "mixed.log")
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.line, err)
+		}
+		if event.Message != tt.message || event.Level != tt.level {
+			t.Errorf("Parse(%q) = message %q level %q, want %q %q", tt.line, event.Message, event.Level, tt.message, tt.level)
+		}
+		if len(event.Fields) != len(tt.fields) {
+			t.Errorf("Parse(%q) fields = %v, want %v", tt.line, event.Fields, tt.fields)
+		}
+		for key, value := range tt.fields {
+			if event.Fields[key] != value {
+				t.Errorf("Parse(%q) field %s = %q, want %q", tt.line, key, event.Fields[key], value)
+			}
+		}
+	}
+
+	if stats := p.Stats(); stats.JSON != 2 || stats.Logfmt != 1 || stats.Plain != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	event, _ := p.Parse(`{"ts":"2024-01-15T10:30:00Z","msg":"timed"}`, "mixed.log")
+	if !event.Timestamp.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected the JSON timestamp, got %v", event.Timestamp)
+	}
+}
+
+func TestAutoParser_CachesFormatPerSource(t *testing.T) {
+	p := newTestAutoParser(t, nil)
+
+	// Each source's first line is sniffed; later lines of the same format
+	// hit the cache
+	for i := 0; i < 3; i++ {
+		p.Parse(`level=info msg=ready`, "logfmt.log")
+		p.Parse(`{"msg":"ready"}`, "json.log")
+		p.Parse(`plain text line`, "plain.log")
+	}
+	if stats := p.Stats(); stats.CacheHits != 6 || stats.JSON != 3 || stats.Logfmt != 3 || stats.Plain != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// A line the cached format does not fit is sniffed again and recached
+	if event, _ := p.Parse(`{"msg":"switched"}`, "plain.log"); event.Message != "switched" {
+		t.Errorf("expected a JSON line from a plain source to be parsed, got %q", event.Message)
+	}
+	if event, _ := p.Parse(`{"msg":"again"}`, "plain.log"); event.Message != "again" {
+		t.Errorf("unexpected message %q", event.Message)
+	}
+	if event, _ := p.Parse(`back to plain`, "plain.log"); event.Message != "back to plain" {
+		t.Errorf("unexpected message %q", event.Message)
+	}
+	if stats := p.Stats(); stats.CacheHits != 7 || stats.JSON != 5 || stats.Plain != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAutoParser_EmptyLine(t *testing.T) {
+	p := newTestAutoParser(t, nil)
+	if _, err := p.Parse("", "source"); err == nil {
+		t.Error("expected an error for an empty line")
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]string
+	}{
+		{`a=1 b=two`, map[string]string{"a": "1", "b": "two"}},
+		{`msg="hello \"world\"" empty=`, map[string]string{"msg": `hello "world"`, "empty": ""}},
+		{`  spaced=yes	tab=ok  `, map[string]string{"spaced": "yes", "tab": "ok"}},
+		{`plain words here`, nil},
+		{`key=value trailing`, nil},
+		{`=value`, nil},
+		{`msg="unterminated`, nil},
+		{`msg="x"y`, nil},
+		{`a=b=c`, nil},
+		{``, nil},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseLogfmt(tt.line)
+		if ok != (tt.want != nil) {
+			t.Errorf("parseLogfmt(%q) ok = %v, want %v", tt.line, ok, tt.want != nil)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseLogfmt(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+		for key, value := range tt.want {
+			if got[key] != value {
+				t.Errorf("parseLogfmt(%q)[%s] = %q, want %q", tt.line, key, got[key], value)
+			}
+		}
+	}
+}
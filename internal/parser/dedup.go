@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Dedup transformer defaults
+const (
+	DefaultDedupTTL        = 5 * time.Minute
+	DefaultDedupMaxEntries = 100000
+)
+
+// dedupKey is the content hash of an event
+type dedupKey [16]byte
+
+// dedupEntry is an event hash remembered until it expires
+type dedupEntry struct {
+	key     dedupKey
+	expires time.Time
+}
+
+// DedupStats are the counters of a dedup transformer
+type DedupStats struct {
+	Duplicates int64 // events dropped as duplicates
+	Evictions  int64 // hashes forgotten early to stay within max entries
+	Entries    int   // hashes currently remembered
+}
+
+// DedupTransformer drops events whose content hash was seen within the TTL
+// window, with ErrDropEvent. The hash covers the configured fields, or the
+// whole normalized event (timestamp, level, source, message and fields) when
+// none are set. Hashes are kept in an LRU bounded by max entries, so an
+// event may pass again before the TTL expires under heavy load. It is safe
+// for concurrent use by several inputs.
+type DedupTransformer struct {
+	fields     []string
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[dedupKey]*list.Element
+	lru     *list.List // most recently seen first
+
+	duplicates atomic.Int64
+	evictions  atomic.Int64
+}
+
+// NewDedupTransformer creates a new dedup transformer
+func NewDedupTransformer(cfg *TransformConfig) (*DedupTransformer, error) {
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultDedupTTL
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("dedup ttl must be positive: %v", cfg.TTL)
+	}
+
+	maxEntries := cfg.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultDedupMaxEntries
+	}
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("dedup max_entries must be positive: %d", cfg.MaxEntries)
+	}
+
+	return &DedupTransformer{
+		fields:     cfg.Fields,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[dedupKey]*list.Element),
+		lru:        list.New(),
+	}, nil
+}
+
+// Transform drops the event if an identical one was seen within the TTL
+func (t *DedupTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	key := t.hash(event)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.expire(now)
+
+	if elem, ok := t.entries[key]; ok {
+		if now.Before(elem.Value.(*dedupEntry).expires) {
+			t.lru.MoveToFront(elem)
+			t.duplicates.Add(1)
+			metrics.GetGlobalCollector().DedupEventsDropped.Inc()
+			return nil, ErrDropEvent
+		}
+		t.remove(elem)
+	}
+
+	t.entries[key] = t.lru.PushFront(&dedupEntry{key: key, expires: now.Add(t.ttl)})
+	for t.lru.Len() > t.maxEntries {
+		t.remove(t.lru.Back())
+		t.evictions.Add(1)
+	}
+
+	return event, nil
+}
+
+// expire forgets the least recently seen hashes that have expired
+func (t *DedupTransformer) expire(now time.Time) {
+	for elem := t.lru.Back(); elem != nil; elem = t.lru.Back() {
+		if now.Before(elem.Value.(*dedupEntry).expires) {
+			return
+		}
+		t.remove(elem)
+	}
+}
+
+// remove forgets a hash
+func (t *DedupTransformer) remove(elem *list.Element) {
+	t.lru.Remove(elem)
+	delete(t.entries, elem.Value.(*dedupEntry).key)
+}
+
+// hash computes the content hash of the configured fields, or of the whole
+// normalized event. Values are length-prefixed so adjacent values cannot
+// run into each other.
+func (t *DedupTransformer) hash(event *types.LogEvent) dedupKey {
+	h := fnv.New128a()
+	write := func(value string) {
+		h.Write([]byte(strconv.Itoa(len(value))))
+		h.Write([]byte{':'})
+		h.Write([]byte(value))
+	}
+
+	if len(t.fields) > 0 {
+		for _, field := range t.fields {
+			value, ok := event.Fields[field]
+			if !ok {
+				value = eventAttribute(event, field)
+			}
+			write(field)
+			write(value)
+		}
+	} else {
+		event.Normalize()
+		write(event.Timestamp.Format(time.RFC3339Nano))
+		write(event.Level)
+		write(event.Source)
+		write(event.Message)
+
+		keys := make([]string, 0, len(event.Fields))
+		for key := range event.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			write(key)
+			write(event.Fields[key])
+		}
+	}
+
+	var key dedupKey
+	h.Sum(key[:0])
+	return key
+}
+
+// eventAttribute returns the event attribute a dedup field names when the
+// event has no such field
+func eventAttribute(event *types.LogEvent, name string) string {
+	switch name {
+	case "message":
+		return event.Message
+	case "level":
+		return event.Level
+	case "source":
+		return event.Source
+	case "timestamp":
+		return event.Timestamp.UTC().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+// Stats returns the transformer's counters
+func (t *DedupTransformer) Stats() DedupStats {
+	t.mu.Lock()
+	entries := t.lru.Len()
+	t.mu.Unlock()
+
+	return DedupStats{
+		Duplicates: t.duplicates.Load(),
+		Evictions:  t.evictions.Load(),
+		Entries:    entries,
+	}
+}
+
+// Name returns the transformer name
+func (t *DedupTransformer) Name() string {
+	return "dedup"
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// newTestDedup creates a dedup transformer on a clock the test advances
+func newTestDedup(t *testing.T, cfg *TransformConfig) (*DedupTransformer, *time.Time) {
+	t.Helper()
+
+	dedup, err := NewDedupTransformer(cfg)
+	if err != nil {
+		t.Fatalf("NewDedupTransformer() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dedup.now = func() time.Time { return now }
+	return dedup, &now
+}
+
+func dedupEvent(message string) *types.LogEvent {
+	return &types.LogEvent{
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Message:   message,
+		Fields:    map[string]string{"request_id": "abc"},
+	}
+}
+
+func TestDedupTransformer_DropsWithinTTL(t *testing.T) {
+	dedup, now := newTestDedup(t, &TransformConfig{Type: "dedup", TTL: time.Minute})
+
+	if _, err := dedup.Transform(dedupEvent("first")); err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if _, err := dedup.Transform(dedupEvent("first")); !errors.Is(err, ErrDropEvent) {
+		t.Errorf("expected the duplicate to be dropped, got %v", err)
+	}
+	if _, err := dedup.Transform(dedupEvent("second")); err != nil {
+		t.Errorf("expected a different event to pass, got %v", err)
+	}
+
+	if stats := dedup.Stats(); stats.Duplicates != 1 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDedupTransformer_PassesAfterTTL(t *testing.T) {
+	dedup, now := newTestDedup(t, &TransformConfig{Type: "dedup", TTL: time.Minute})
+
+	if _, err := dedup.Transform(dedupEvent("repeated")); err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := dedup.Transform(dedupEvent("repeated")); err != nil {
+		t.Errorf("expected the event to pass again after the TTL, got %v", err)
+	}
+
+	// The window restarts from the event that passed
+	*now = now.Add(30 * time.Second)
+	if _, err := dedup.Transform(dedupEvent("repeated")); !errors.Is(err, ErrDropEvent) {
+		t.Errorf("expected the duplicate to be dropped, got %v", err)
+	}
+	if stats := dedup.Stats(); stats.Duplicates != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDedupTransformer_Fields(t *testing.T) {
+	dedup, _ := newTestDedup(t, &TransformConfig{Type: "dedup", Fields: []string{"request_id", "message"}})
+
+	first := dedupEvent("same")
+	retried := dedupEvent("same")
+	retried.Timestamp = retried.Timestamp.Add(time.Second)
+	other := dedupEvent("same")
+	other.Fields["request_id"] = "def"
+
+	if _, err := dedup.Transform(first); err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if _, err := dedup.Transform(retried); !errors.Is(err, ErrDropEvent) {
+		t.Errorf("expected an event with the same fields to be dropped, got %v", err)
+	}
+	if _, err := dedup.Transform(other); err != nil {
+		t.Errorf("expected an event with another request_id to pass, got %v", err)
+	}
+}
+
+func TestDedupTransformer_MaxEntries(t *testing.T) {
+	dedup, _ := newTestDedup(t, &TransformConfig{Type: "dedup", MaxEntries: 2})
+
+	for _, message := range []string{"a", "b", "c"} {
+		if _, err := dedup.Transform(dedupEvent(message)); err != nil {
+			t.Fatalf("Transform(%s) error = %v", message, err)
+		}
+	}
+
+	// The least recently seen hash was evicted
+	if _, err := dedup.Transform(dedupEvent("a")); err != nil {
+		t.Errorf("expected the evicted event to pass, got %v", err)
+	}
+	if _, err := dedup.Transform(dedupEvent("c")); !errors.Is(err, ErrDropEvent) {
+		t.Errorf("expected a remembered event to be dropped, got %v", err)
+	}
+	if stats := dedup.Stats(); stats.Evictions != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDedupTransformer_Concurrent(t *testing.T) {
+	dedup, _ := newTestDedup(t, &TransformConfig{Type: "dedup"})
+
+	const inputs, events = 8, 100
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		passed int
+	)
+	for i := 0; i < inputs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < events; j++ {
+				if _, err := dedup.Transform(dedupEvent(fmt.Sprintf("event-%d", j))); err == nil {
+					mu.Lock()
+					passed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if passed != events {
+		t.Errorf("expected each event to pass once, got %d", passed)
+	}
+	if stats := dedup.Stats(); stats.Duplicates != (inputs-1)*events {
+		t.Errorf("expected %d duplicates, got %d", (inputs-1)*events, stats.Duplicates)
+	}
+}
+
+func TestDedupTransformer_InvalidConfig(t *testing.T) {
+	if _, err := NewDedupTransformer(&TransformConfig{TTL: -time.Second}); err == nil {
+		t.Error("expected an error for a negative ttl")
+	}
+	if _, err := NewDedupTransformer(&TransformConfig{MaxEntries: -1}); err == nil {
+		t.Error("expected an error for negative max_entries")
+	}
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Drop condition operators
+const (
+	OperatorEq       = "eq"
+	OperatorNe       = "ne"
+	OperatorGt       = "gt"
+	OperatorLt       = "lt"
+	OperatorContains = "contains"
+	OperatorMatches  = "matches"
+)
+
+// DropIfTransformer drops events matching a condition with ErrDropEvent.
+// The field is looked up in the event fields, falling back to the message,
+// level and source attributes for those names. Events missing the field
+// never match; gt and lt compare numerically and never match non-numbers.
+type DropIfTransformer struct {
+	field    string
+	operator string
+	value    string
+	number   float64
+	pattern  *regexp.Regexp
+}
+
+// NewDropIfTransformer creates a new drop_if transformer
+func NewDropIfTransformer(cfg *TransformConfig) (*DropIfTransformer, error) {
+	if cfg.Field == "" {
+		return nil, fmt.Errorf("drop_if transformer requires a field")
+	}
+
+	t := &DropIfTransformer{
+		field:    cfg.Field,
+		operator: cfg.Operator,
+		value:    cfg.Value,
+	}
+
+	switch cfg.Operator {
+	case OperatorEq, OperatorNe, OperatorContains:
+	case OperatorGt, OperatorLt:
+		number, err := strconv.ParseFloat(cfg.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("drop_if %s requires a numeric value: %w", cfg.Operator, err)
+		}
+		t.number = number
+	case OperatorMatches:
+		pattern, err := regexp.Compile(cfg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid drop_if pattern: %w", err)
+		}
+		t.pattern = pattern
+	default:
+		return nil, fmt.Errorf("unknown drop_if operator: %s", cfg.Operator)
+	}
+
+	return t, nil
+}
+
+// Transform drops the event when the condition matches
+func (t *DropIfTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	if t.matches(event) {
+		return nil, ErrDropEvent
+	}
+	return event, nil
+}
+
+// matches evaluates the condition against the event
+func (t *DropIfTransformer) matches(event *types.LogEvent) bool {
+	value, ok := t.lookup(event)
+	if !ok {
+		return false
+	}
+
+	switch t.operator {
+	case OperatorEq:
+		return value == t.value
+	case OperatorNe:
+		return value != t.value
+	case OperatorContains:
+		return strings.Contains(value, t.value)
+	case OperatorMatches:
+		return t.pattern.MatchString(value)
+	case OperatorGt, OperatorLt:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		if t.operator == OperatorGt {
+			return number > t.number
+		}
+		return number < t.number
+	}
+	return false
+}
+
+// lookup returns the value of the condition field
+func (t *DropIfTransformer) lookup(event *types.LogEvent) (string, bool) {
+	if value, ok := event.Fields[t.field]; ok {
+		return value, true
+	}
+
+	switch t.field {
+	case "message":
+		return event.Message, true
+	case "level":
+		return event.Level, true
+	case "source":
+		return event.Source, true
+	}
+	return "", false
+}
+
+// Name returns the transformer name
+func (t *DropIfTransformer) Name() string {
+	return "drop_if"
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestDropIfTransformer(t *testing.T) {
+	event := func() *types.LogEvent {
+		return &types.LogEvent{
+			Message: "GET /healthz 200",
+			Level:   "info",
+			Source:  "nginx",
+			Fields: map[string]string{
+				"path":        "/healthz",
+				"status":      "200",
+				"duration_ms": "12.5",
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   *TransformConfig
+		wantDrop bool
+	}{
+		{name: "eq match", config: &TransformConfig{Field: "path", Operator: "eq", Value: "/healthz"}, wantDrop: true},
+		{name: "eq no match", config: &TransformConfig{Field: "path", Operator: "eq", Value: "/api"}, wantDrop: false},
+		{name: "ne match", config: &TransformConfig{Field: "status", Operator: "ne", Value: "500"}, wantDrop: true},
+		{name: "ne no match", config: &TransformConfig{Field: "status", Operator: "ne", Value: "200"}, wantDrop: false},
+		{name: "gt match", config: &TransformConfig{Field: "duration_ms", Operator: "gt", Value: "10"}, wantDrop: true},
+		{name: "gt no match", config: &TransformConfig{Field: "duration_ms", Operator: "gt", Value: "100"}, wantDrop: false},
+		{name: "lt match", config: &TransformConfig{Field: "status", Operator: "lt", Value: "300"}, wantDrop: true},
+		{name: "lt non numeric field", config: &TransformConfig{Field: "path", Operator: "lt", Value: "300"}, wantDrop: false},
+		{name: "contains match", config: &TransformConfig{Field: "message", Operator: "contains", Value: "healthz"}, wantDrop: true},
+		{name: "contains no match", config: &TransformConfig{Field: "message", Operator: "contains", Value: "error"}, wantDrop: false},
+		{name: "matches match", config: &TransformConfig{Field: "source", Operator: "matches", Value: "^ngin"}, wantDrop: true},
+		{name: "matches no match", config: &TransformConfig{Field: "level", Operator: "matches", Value: "^(warn|error)$"}, wantDrop: false},
+		{name: "missing field", config: &TransformConfig{Field: "user", Operator: "ne", Value: "alice"}, wantDrop: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Type = "drop_if"
+			transformer, err := NewTransformer(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create transformer: %v", err)
+			}
+
+			result, err := transformer.Transform(event())
+			if tt.wantDrop {
+				if !errors.Is(err, ErrDropEvent) {
+					t.Errorf("expected ErrDropEvent, got %v", err)
+				}
+				return
+			}
+			if err != nil || result == nil {
+				t.Errorf("expected event to be kept, got %v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestDropIfTransformer_Pipeline(t *testing.T) {
+	pipeline, err := NewTransformPipeline([]TransformConfig{
+		{Type: "add", Add: map[string]string{"env": "prod"}},
+		{Type: "drop_if", Field: "path", Operator: "eq", Value: "/healthz"},
+		{Type: "rename", Rename: map[string]string{"env": "environment"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	result, err := pipeline.Transform(&types.LogEvent{Fields: map[string]string{"path": "/healthz"}})
+	if !errors.Is(err, ErrDropEvent) {
+		t.Fatalf("expected ErrDropEvent, got %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected no event after drop, got %v", result)
+	}
+
+	result, err = pipeline.Transform(&types.LogEvent{Fields: map[string]string{"path": "/api"}})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if result.Fields["environment"] != "prod" {
+		t.Errorf("expected kept event to pass through the remaining transformers")
+	}
+}
+
+func TestNewDropIfTransformer_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *TransformConfig
+	}{
+		{name: "missing field", config: &TransformConfig{Operator: "eq", Value: "x"}},
+		{name: "unknown operator", config: &TransformConfig{Field: "a", Operator: "like", Value: "x"}},
+		{name: "non numeric gt", config: &TransformConfig{Field: "a", Operator: "gt", Value: "x"}},
+		{name: "invalid pattern", config: &TransformConfig{Field: "a", Operator: "matches", Value: "[invalid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDropIfTransformer(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Fields set by the enrich transformer when enabled
+const (
+	EnrichHostnameField = "hostname"
+	EnrichPIDField      = "pid"
+)
+
+// EnrichTransformer adds static fields, and optionally the host name and
+// process ID of the aggregator, to events. Unlike the add transformer it
+// never overwrites a field the event already has, so fields set by inputs,
+// parsers or earlier transforms win.
+type EnrichTransformer struct {
+	fields map[string]string
+}
+
+// NewEnrichTransformer creates a new enrich transformer
+func NewEnrichTransformer(cfg *TransformConfig) (*EnrichTransformer, error) {
+	fields := make(map[string]string, len(cfg.Add)+2)
+	for key, value := range cfg.Add {
+		fields[key] = value
+	}
+
+	if cfg.Hostname {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve hostname: %w", err)
+		}
+		fields[EnrichHostnameField] = hostname
+	}
+	if cfg.PID {
+		fields[EnrichPIDField] = strconv.Itoa(os.Getpid())
+	}
+
+	return &EnrichTransformer{fields: fields}, nil
+}
+
+// Transform adds the enrichment fields the event does not have
+func (t *EnrichTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	if len(t.fields) == 0 {
+		return event, nil
+	}
+
+	if event.Fields == nil {
+		event.Fields = make(map[string]string, len(t.fields))
+	}
+
+	for key, value := range t.fields {
+		if _, exists := event.Fields[key]; !exists {
+			event.Fields[key] = value
+		}
+	}
+
+	return event, nil
+}
+
+// Name returns the transformer name
+func (t *EnrichTransformer) Name() string {
+	return "enrich"
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestEnrichTransformer(t *testing.T) {
+	transformer, err := NewTransformer(&TransformConfig{
+		Type:     "enrich",
+		Add:      map[string]string{"env": "production", "region": "us-east-1"},
+		Hostname: true,
+		PID:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewTransformer() error = %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	want := map[string]string{
+		"env":               "production",
+		"region":            "us-east-1",
+		EnrichHostnameField: hostname,
+		EnrichPIDField:      strconv.Itoa(os.Getpid()),
+	}
+
+	event, err := transformer.Transform(&types.LogEvent{Message: "no fields"})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	for key, value := range want {
+		if event.Fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, event.Fields[key], value)
+		}
+	}
+}
+
+func TestEnrichTransformer_KeepsExistingFields(t *testing.T) {
+	transformer, err := NewEnrichTransformer(&TransformConfig{
+		Add:      map[string]string{"env": "production", "service": "aggregator"},
+		Hostname: true,
+	})
+	if err != nil {
+		t.Fatalf("NewEnrichTransformer() error = %v", err)
+	}
+
+	event, _ := transformer.Transform(&types.LogEvent{Fields: map[string]string{
+		"env":      "staging",
+		"hostname": "pod-1",
+	}})
+
+	if event.Fields["env"] != "staging" || event.Fields["hostname"] != "pod-1" {
+		t.Errorf("expected existing fields to be kept, got %v", event.Fields)
+	}
+	if event.Fields["service"] != "aggregator" {
+		t.Errorf("expected missing fields to be added, got %v", event.Fields)
+	}
+}
+
+func TestEnrichTransformer_ComposesWithTransforms(t *testing.T) {
+	pipeline, err := NewTransformPipeline([]TransformConfig{
+		{Type: "enrich", Add: map[string]string{"env": "production", "team": "platform"}},
+		{Type: "add", Add: map[string]string{"team": "payments"}},
+		{Type: "drop_if", Field: "env", Operator: "eq", Value: "staging"},
+	})
+	if err != nil {
+		t.Fatalf("NewTransformPipeline() error = %v", err)
+	}
+
+	// Later transforms see and may override the enrichment
+	event, err := pipeline.Transform(&types.LogEvent{Message: "kept"})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if event.Fields["env"] != "production" || event.Fields["team"] != "payments" {
+		t.Errorf("unexpected fields %v", event.Fields)
+	}
+
+	if _, err := pipeline.Transform(&types.LogEvent{Fields: map[string]string{"env": "staging"}}); err != ErrDropEvent {
+		t.Errorf("expected the staging event to be dropped, got %v", err)
+	}
+}
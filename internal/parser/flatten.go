@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Flatten transformer defaults
+const (
+	DefaultFlattenSeparator = "."
+	DefaultFlattenMaxDepth  = 10
+)
+
+// FlattenTransformer expands fields holding JSON objects or arrays into
+// dotted keys, e.g. {"error":{"code":500}} becomes "error.code". Array
+// elements are keyed by index. Values nested deeper than the max depth are
+// kept as JSON. Existing fields win over flattened keys that collide.
+type FlattenTransformer struct {
+	separator    string
+	maxDepth     int
+	keepOriginal bool
+}
+
+// NewFlattenTransformer creates a new flatten transformer
+func NewFlattenTransformer(cfg *TransformConfig) (*FlattenTransformer, error) {
+	separator := cfg.Separator
+	if separator == "" {
+		separator = DefaultFlattenSeparator
+	}
+
+	maxDepth := cfg.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultFlattenMaxDepth
+	}
+	if maxDepth < 0 {
+		return nil, fmt.Errorf("flatten max_depth must be positive: %d", cfg.MaxDepth)
+	}
+
+	return &FlattenTransformer{
+		separator:    separator,
+		maxDepth:     maxDepth,
+		keepOriginal: cfg.KeepOriginal,
+	}, nil
+}
+
+// Transform flattens nested fields of the event
+func (t *FlattenTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	if event.Fields == nil {
+		return event, nil
+	}
+
+	// Process keys in order so collisions resolve deterministically
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	flattened := make(map[string]string)
+	for _, key := range keys {
+		nested, ok := decodeNested(event.Fields[key])
+		if !ok {
+			continue
+		}
+
+		t.flatten(flattened, key, nested, 0)
+		if !t.keepOriginal {
+			delete(event.Fields, key)
+		}
+	}
+
+	for key, value := range flattened {
+		if _, exists := event.Fields[key]; !exists {
+			event.Fields[key] = value
+		}
+	}
+
+	return event, nil
+}
+
+// flatten writes value under prefix, descending into objects and arrays
+func (t *FlattenTransformer) flatten(out map[string]string, prefix string, value interface{}, depth int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 || depth >= t.maxDepth {
+			setFlattened(out, prefix, encodeNested(v))
+			return
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			t.flatten(out, prefix+t.separator+key, v[key], depth+1)
+		}
+	case []interface{}:
+		if len(v) == 0 || depth >= t.maxDepth {
+			setFlattened(out, prefix, encodeNested(v))
+			return
+		}
+
+		for i, elem := range v {
+			t.flatten(out, prefix+t.separator+strconv.Itoa(i), elem, depth+1)
+		}
+	case nil:
+		setFlattened(out, prefix, "")
+	default:
+		setFlattened(out, prefix, fmt.Sprintf("%v", v))
+	}
+}
+
+// setFlattened stores a flattened value unless an earlier key claimed it
+func setFlattened(out map[string]string, key, value string) {
+	if _, exists := out[key]; !exists {
+		out[key] = value
+	}
+}
+
+// decodeNested decodes a field value holding a JSON object or array
+func decodeNested(value string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+
+	var nested interface{}
+	if err := dec.Decode(&nested); err != nil || dec.More() {
+		return nil, false
+	}
+	return nested, true
+}
+
+// encodeNested renders a value that is not flattened any further
+func encodeNested(value interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Name returns the transformer name
+func (t *FlattenTransformer) Name() string {
+	return "flatten"
+}
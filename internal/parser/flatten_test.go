@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestFlattenTransformer(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *TransformConfig
+		fields     map[string]string
+		wantFields map[string]string
+	}{
+		{
+			name:   "nested objects",
+			config: &TransformConfig{Type: "flatten"},
+			fields: map[string]string{
+				"error":    `{"code":500,"details":"Internal server error"}`,
+				"metadata": `{"request_id":"test-123","user":{"id":"user-456"}}`,
+				"service":  "e2e-test",
+			},
+			wantFields: map[string]string{
+				"error.code":          "500",
+				"error.details":       "Internal server error",
+				"metadata.request_id": "test-123",
+				"metadata.user.id":    "user-456",
+				"service":             "e2e-test",
+			},
+		},
+		{
+			name:   "arrays",
+			config: &TransformConfig{Type: "flatten"},
+			fields: map[string]string{
+				"tags":  `["a","b"]`,
+				"hosts": `[{"name":"web-1"},{"name":"web-2"}]`,
+				"empty": `[]`,
+			},
+			wantFields: map[string]string{
+				"tags.0":       "a",
+				"tags.1":       "b",
+				"hosts.0.name": "web-1",
+				"hosts.1.name": "web-2",
+				"empty":        "[]",
+			},
+		},
+		{
+			name:   "depth limit",
+			config: &TransformConfig{Type: "flatten", MaxDepth: 1},
+			fields: map[string]string{
+				"a": `{"b":{"c":{"d":1}},"e":2}`,
+			},
+			wantFields: map[string]string{
+				"a.b": `{"c":{"d":1}}`,
+				"a.e": "2",
+			},
+		},
+		{
+			name:   "custom separator and keep original",
+			config: &TransformConfig{Type: "flatten", Separator: "_", KeepOriginal: true},
+			fields: map[string]string{
+				"error": `{"code":500}`,
+			},
+			wantFields: map[string]string{
+				"error":      `{"code":500}`,
+				"error_code": "500",
+			},
+		},
+		{
+			name:   "existing fields win collisions",
+			config: &TransformConfig{Type: "flatten"},
+			fields: map[string]string{
+				"error":      `{"code":500}`,
+				"error.code": "explicit",
+			},
+			wantFields: map[string]string{
+				"error.code": "explicit",
+			},
+		},
+		{
+			name:   "first flattened key wins collisions",
+			config: &TransformConfig{Type: "flatten"},
+			fields: map[string]string{
+				"a":   `{"b.c":1}`,
+				"a.b": `{"c":2}`,
+			},
+			wantFields: map[string]string{
+				"a.b.c": "1",
+			},
+		},
+		{
+			name:   "non json fields untouched",
+			config: &TransformConfig{Type: "flatten"},
+			fields: map[string]string{
+				"message": "{not json",
+				"count":   "3",
+			},
+			wantFields: map[string]string{
+				"message": "{not json",
+				"count":   "3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := NewTransformer(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create transformer: %v", err)
+			}
+
+			result, err := transformer.Transform(&types.LogEvent{Fields: tt.fields})
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+
+			if len(result.Fields) != len(tt.wantFields) {
+				t.Errorf("Fields = %v, want %v", result.Fields, tt.wantFields)
+			}
+
+			for key, wantValue := range tt.wantFields {
+				if gotValue, ok := result.Fields[key]; !ok {
+					t.Errorf("Field %s not found", key)
+				} else if gotValue != wantValue {
+					t.Errorf("Field %s = %v, want %v", key, gotValue, wantValue)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFlattenTransformer_InvalidDepth(t *testing.T) {
+	if _, err := NewFlattenTransformer(&TransformConfig{Type: "flatten", MaxDepth: -1}); err == nil {
+		t.Error("expected error for negative max_depth")
+	}
+}
@@ -18,6 +18,7 @@ type GrokParser struct {
 	levelField   string
 	messageField string
 	customFields map[string]string
+	detector     *timestampDetector
 }
 
 // Common Grok patterns (subset of popular patterns)
@@ -104,7 +105,7 @@ func NewGrokParser(cfg *ParserConfig) (*GrokParser, error) {
 		return nil, fmt.Errorf("failed to compile expanded pattern: %w", err)
 	}
 
-	return &GrokParser{
+	p := &GrokParser{
 		pattern:      regex,
 		patternName:  patternName,
 		timeFormat:   cfg.TimeFormat,
@@ -112,7 +113,12 @@ func NewGrokParser(cfg *ParserConfig) (*GrokParser, error) {
 		levelField:   cfg.LevelField,
 		messageField: cfg.MessageField,
 		customFields: cfg.CustomFields,
-	}, nil
+	}
+	if cfg.TimeFormat == TimeFormatAuto {
+		p.detector = newTimestampDetector()
+	}
+
+	return p, nil
 }
 
 // expandGrokPattern expands grok pattern syntax to regex
@@ -152,8 +158,17 @@ func expandGrokPattern(pattern string) (string, error) {
 
 // Parse parses a log line using grok pattern
 func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error) {
+	start := time.Now()
+	event, reason, err := p.parse(line, source)
+	observeParse(ParserTypeGrok, start, reason, err)
+	return event, err
+}
+
+// parse parses a line, returning the reason it failed to parse cleanly if
+// any
+func (p *GrokParser) parse(line string, source string) (*types.LogEvent, string, error) {
 	if line == "" {
-		return nil, fmt.Errorf("empty log line")
+		return nil, "", fmt.Errorf("empty log line")
 	}
 
 	match := p.pattern.FindStringSubmatch(line)
@@ -164,9 +179,11 @@ func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error)
 			Message:   line,
 			Source:    source,
 			Fields:    make(map[string]string),
-		}, nil
+		}, ReasonMalformed, nil
 	}
 
+	var reason string
+
 	// Extract named groups
 	fields := make(map[string]string)
 	for i, name := range p.pattern.SubexpNames() {
@@ -191,7 +208,9 @@ func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error)
 		var ts time.Time
 		var err error
 
-		if p.timeFormat != "" {
+		if p.detector != nil {
+			ts, err = p.detector.Detect(source, tsStr)
+		} else if p.timeFormat != "" {
 			ts, err = time.Parse(p.timeFormat, tsStr)
 		} else {
 			ts, err = ParseTimestamp(tsStr)
@@ -200,8 +219,12 @@ func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error)
 		if err == nil {
 			event.Timestamp = ts
 			delete(fields, timeField)
+		} else if p.detector != nil {
+			fields[TimestampErrorField] = err.Error()
+			reason = ReasonTimeParse
 		} else {
 			event.Timestamp = time.Now()
+			reason = ReasonTimeParse
 		}
 	} else {
 		event.Timestamp = time.Now()
@@ -229,6 +252,10 @@ func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error)
 		delete(fields, messageField)
 	} else {
 		event.Message = line
+		// Patterns without a message are only missing one when it was asked for
+		if p.messageField != "" && reason == "" {
+			reason = ReasonMissingField
+		}
 	}
 
 	// Add custom fields
@@ -236,7 +263,7 @@ func (p *GrokParser) Parse(line string, source string) (*types.LogEvent, error)
 		fields[key] = value
 	}
 
-	return event, nil
+	return event, reason, nil
 }
 
 // Name returns the parser name
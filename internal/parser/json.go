@@ -2,12 +2,18 @@ package parser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
+// ErrNestingTooDeep is returned for a JSON document nested deeper than the
+// parser's depth limit
+var ErrNestingTooDeep = errors.New("JSON nesting exceeds the depth limit")
+
 // JSONParser parses JSON-formatted log lines
 type JSONParser struct {
 	timeField    string
@@ -15,23 +21,54 @@ type JSONParser struct {
 	levelField   string
 	messageField string
 	customFields map[string]string
+	detector     *timestampDetector
+	allowed      map[string]bool // fields kept in strict mode, nil keeps all
+	depthLimit   int             // 0 for unlimited
 }
 
 // NewJSONParser creates a new JSON parser
 func NewJSONParser(cfg *ParserConfig) (*JSONParser, error) {
-	return &JSONParser{
+	p := &JSONParser{
 		timeField:    cfg.TimeField,
 		timeFormat:   cfg.TimeFormat,
 		levelField:   cfg.LevelField,
 		messageField: cfg.MessageField,
 		customFields: cfg.CustomFields,
-	}, nil
+		depthLimit:   cfg.NestedDepthLimit,
+	}
+	if cfg.TimeFormat == TimeFormatAuto {
+		p.detector = newTimestampDetector()
+	}
+	if cfg.NestedDepthLimit < 0 {
+		return nil, fmt.Errorf("nested_depth_limit must not be negative, got %d", cfg.NestedDepthLimit)
+	}
+	if cfg.StrictFields {
+		p.allowed = make(map[string]bool, len(cfg.AllowedFields))
+		for _, field := range cfg.AllowedFields {
+			p.allowed[field] = true
+		}
+	}
+
+	return p, nil
 }
 
 // Parse parses a JSON log line
 func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error) {
+	start := time.Now()
+	event, reason, err := p.parse(line, source)
+	observeParse(ParserTypeJSON, start, reason, err)
+	return event, err
+}
+
+// parse parses a JSON log line, returning the reason it failed to parse
+// cleanly if any
+func (p *JSONParser) parse(line string, source string) (*types.LogEvent, string, error) {
 	if line == "" {
-		return nil, fmt.Errorf("empty log line")
+		return nil, "", fmt.Errorf("empty log line")
+	}
+
+	if p.tooDeep(line) {
+		return nil, ReasonMalformed, fmt.Errorf("%w of %d", ErrNestingTooDeep, p.depthLimit)
 	}
 
 	var data map[string]interface{}
@@ -42,9 +79,58 @@ func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error)
 			Message:   line,
 			Source:    source,
 			Fields:    make(map[string]string),
-		}, nil
+		}, ReasonMalformed, nil
+	}
+
+	event, reason := p.event(data, line, source)
+	return event, reason, nil
+}
+
+// tooDeep reports whether a line nests objects or arrays deeper than the
+// depth limit. It is checked before decoding, so a pathological document
+// is rejected without allocating its values.
+func (p *JSONParser) tooDeep(line string) bool {
+	if p.depthLimit == 0 {
+		return false
 	}
 
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > p.depthLimit {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
+
+// event builds the event of a decoded line: the timestamp, level and
+// message are taken from their fields and the rest become event fields,
+// only the allowed ones in strict mode. It
+// also returns why the line failed to parse cleanly, if it did: an
+// unparseable timestamp or no message field.
+func (p *JSONParser) event(data map[string]interface{}, line, source string) (*types.LogEvent, string) {
+	var reason string
 	event := &types.LogEvent{
 		Source: source,
 		Fields: make(map[string]string),
@@ -52,8 +138,26 @@ func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error)
 
 	// Extract timestamp
 	timestamp := time.Now()
-	if p.timeField != "" {
+	if p.timeField != "" && p.detector != nil {
 		if tsVal, ok := data[p.timeField]; ok {
+			tsStr := fmt.Sprintf("%v", tsVal)
+			if num, ok := tsVal.(float64); ok {
+				// Epoch timestamps decode as JSON numbers
+				tsStr = strconv.FormatFloat(num, 'f', -1, 64)
+			}
+
+			var err error
+			timestamp, err = p.detector.Detect(source, tsStr)
+			if err == nil {
+				delete(data, p.timeField)
+			} else {
+				event.Fields[TimestampErrorField] = err.Error()
+				reason = ReasonTimeParse
+			}
+		}
+	} else if p.timeField != "" {
+		if tsVal, ok := data[p.timeField]; ok {
+			reason = ReasonTimeParse
 			if tsStr, ok := tsVal.(string); ok {
 				var err error
 				if p.timeFormat != "" {
@@ -62,6 +166,7 @@ func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error)
 					timestamp, err = ParseTimestamp(tsStr)
 				}
 				if err == nil {
+					reason = ""
 					delete(data, p.timeField)
 				}
 			}
@@ -116,10 +221,16 @@ func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error)
 	// If still no message, use the entire line
 	if event.Message == "" {
 		event.Message = line
+		if reason == "" {
+			reason = ReasonMissingField
+		}
 	}
 
 	// Convert remaining fields to strings
 	for key, value := range data {
+		if p.allowed != nil && !p.allowed[key] {
+			continue
+		}
 		event.Fields[key] = fmt.Sprintf("%v", value)
 	}
 
@@ -128,7 +239,7 @@ func (p *JSONParser) Parse(line string, source string) (*types.LogEvent, error)
 		event.Fields[key] = value
 	}
 
-	return event, nil
+	return event, reason
 }
 
 // Name returns the parser name
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -160,3 +162,122 @@ func TestJSONParser_Name(t *testing.T) {
 		t.Errorf("Name() = %v, want %v", parser.Name(), "json")
 	}
 }
+
+func TestJSONParser_StrictFields(t *testing.T) {
+	line := `{"level":"info","message":"hello","user":"admin","request_id":"r-1","trace":{"id":"t-1"}}`
+
+	tests := []struct {
+		name       string
+		config     *ParserConfig
+		wantFields map[string]string
+	}{
+		{
+			name:   "keep unknown fields",
+			config: &ParserConfig{Type: ParserTypeJSON},
+			wantFields: map[string]string{
+				"user":       "admin",
+				"request_id": "r-1",
+				"trace":      "map[id:t-1]",
+			},
+		},
+		{
+			name: "drop unknown fields",
+			config: &ParserConfig{
+				Type:          ParserTypeJSON,
+				StrictFields:  true,
+				AllowedFields: []string{"user", "missing"},
+				CustomFields:  map[string]string{"env": "prod"},
+			},
+			wantFields: map[string]string{"user": "admin", "env": "prod"},
+		},
+		{
+			name:       "strict without allowed fields",
+			config:     &ParserConfig{Type: ParserTypeJSON, StrictFields: true},
+			wantFields: map[string]string{},
+		},
+		{
+			name:   "allowed fields ignored without strict mode",
+			config: &ParserConfig{Type: ParserTypeJSON, AllowedFields: []string{"user"}},
+			wantFields: map[string]string{
+				"user":       "admin",
+				"request_id": "r-1",
+				"trace":      "map[id:t-1]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewJSONParser(tt.config)
+			if err != nil {
+				t.Fatalf("NewJSONParser() error = %v", err)
+			}
+
+			event, err := parser.Parse(line, "test")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if event.Message != "hello" || event.Level != "info" {
+				t.Errorf("expected the message and level extracted, got %q and %q", event.Message, event.Level)
+			}
+			if len(event.Fields) != len(tt.wantFields) {
+				t.Errorf("Fields = %v, want %v", event.Fields, tt.wantFields)
+			}
+			for key, want := range tt.wantFields {
+				if got, ok := event.Fields[key]; !ok || got != want {
+					t.Errorf("Field %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONParser_NestedDepthLimit(t *testing.T) {
+	parser, err := NewJSONParser(&ParserConfig{Type: ParserTypeJSON, NestedDepthLimit: 3})
+	if err != nil {
+		t.Fatalf("NewJSONParser() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"flat", `{"message":"hello"}`, false},
+		{"at the limit", `{"message":"hello","a":{"b":[1,2]}}`, false},
+		{"brackets in strings", `{"message":"{{[[{{","a":{"b":"]}\"{{"}}`, false},
+		{"object too deep", `{"message":"hello","a":{"b":{"c":{}}}}`, true},
+		{"array too deep", `{"message":"hello","a":[[[1]]]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parser.Parse(tt.input, "test")
+			if tt.wantErr {
+				if !errors.Is(err, ErrNestingTooDeep) {
+					t.Errorf("expected ErrNestingTooDeep, got %v", err)
+				}
+				if event != nil {
+					t.Errorf("expected no event, got %+v", event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if event.Message == tt.input {
+				t.Errorf("expected the line decoded, got it kept as the message")
+			}
+		})
+	}
+
+	// A pathologically nested document is rejected before it is decoded
+	deep := `{"a":` + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + `}`
+	if _, err := parser.Parse(deep, "test"); !errors.Is(err, ErrNestingTooDeep) {
+		t.Errorf("expected ErrNestingTooDeep, got %v", err)
+	}
+
+	if _, err := NewJSONParser(&ParserConfig{Type: ParserTypeJSON, NestedDepthLimit: -1}); err == nil {
+		t.Error("expected an error for a negative depth limit")
+	}
+}
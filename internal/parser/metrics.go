@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+)
+
+// Reasons a line failed to parse, the reason label of the parser's failed
+// events metric
+const (
+	// ReasonMalformed means the line is not in the parser's format
+	ReasonMalformed = "malformed"
+
+	// ReasonTimeParse means the timestamp field could not be parsed
+	ReasonTimeParse = "time_parse"
+
+	// ReasonMissingField means the message field is missing
+	ReasonMissingField = "missing_field"
+)
+
+// observeParse records the duration of a parse and counts the line as
+// processed or, with a reason, failed. Parsers still emit an event for most
+// failures, keeping the raw line as the message or the current time as the
+// timestamp; a line that produced no event at all is malformed.
+func observeParse(parserType ParserType, start time.Time, reason string, err error) {
+	collector := metrics.GetGlobalCollector()
+	collector.ParserDuration.WithLabelValues(string(parserType)).Observe(time.Since(start).Seconds())
+
+	if err != nil && reason == "" {
+		reason = ReasonMalformed
+	}
+	if reason != "" {
+		collector.ParserEventsFailed.WithLabelValues(string(parserType), reason).Inc()
+		return
+	}
+	collector.ParserEventsProcessed.WithLabelValues(string(parserType)).Inc()
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+)
+
+// parseCount returns the number of parses the duration histogram observed
+func parseCount(t *testing.T, parserType ParserType) uint64 {
+	t.Helper()
+
+	m := &dto.Metric{}
+	observer := metrics.GetGlobalCollector().ParserDuration.WithLabelValues(string(parserType))
+	if err := observer.(prometheus.Metric).Write(m); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestParserMetrics_FailureReasons(t *testing.T) {
+	parser, err := NewJSONParser(&ParserConfig{
+		Type:         ParserTypeJSON,
+		TimeField:    "timestamp",
+		TimeFormat:   "2006-01-02T15:04:05Z",
+		MessageField: "message",
+	})
+	if err != nil {
+		t.Fatalf("NewJSONParser() error = %v", err)
+	}
+
+	collector := metrics.GetGlobalCollector()
+	failed := func(reason string) float64 {
+		return testutil.ToFloat64(collector.ParserEventsFailed.WithLabelValues(string(ParserTypeJSON), reason))
+	}
+	processed := func() float64 {
+		return testutil.ToFloat64(collector.ParserEventsProcessed.WithLabelValues(string(ParserTypeJSON)))
+	}
+
+	tests := []struct {
+		name       string
+		line       string
+		wantReason string // empty when the line parses cleanly
+	}{
+		{
+			name:       "malformed JSON",
+			line:       `{"timestamp":"2024-01-15T10:30:00Z","message":`,
+			wantReason: ReasonMalformed,
+		},
+		{
+			name:       "bad timestamp",
+			line:       `{"timestamp":"not-a-time","message":"hello"}`,
+			wantReason: ReasonTimeParse,
+		},
+		{
+			name:       "missing message field",
+			line:       `{"timestamp":"2024-01-15T10:30:00Z","body":"hello"}`,
+			wantReason: ReasonMissingField,
+		},
+		{
+			name: "valid line",
+			line: `{"timestamp":"2024-01-15T10:30:00Z","message":"hello"}`,
+		},
+	}
+
+	reasons := []string{ReasonMalformed, ReasonTimeParse, ReasonMissingField}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := make(map[string]float64)
+			for _, reason := range reasons {
+				before[reason] = failed(reason)
+			}
+			processedBefore := processed()
+			countBefore := parseCount(t, ParserTypeJSON)
+
+			if _, err := parser.Parse(tt.line, "test"); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			for _, reason := range reasons {
+				want := before[reason]
+				if reason == tt.wantReason {
+					want++
+				}
+				if got := failed(reason); got != want {
+					t.Errorf("failed{reason=%q} = %v, want %v", reason, got, want)
+				}
+			}
+			wantProcessed := processedBefore
+			if tt.wantReason == "" {
+				wantProcessed++
+			}
+			if got := processed(); got != wantProcessed {
+				t.Errorf("processed = %v, want %v", got, wantProcessed)
+			}
+			if got := parseCount(t, ParserTypeJSON); got != countBefore+1 {
+				t.Errorf("expected one parse observed, got %d", got-countBefore)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ const (
 	ParserTypeJSON      ParserType = "json"
 	ParserTypeGrok      ParserType = "grok"
 	ParserTypeMultiline ParserType = "multiline"
+	ParserTypeAuto      ParserType = "auto" // JSON, logfmt or plain, detected per line
 )
 
 // ParserConfig holds parser configuration
@@ -31,17 +32,22 @@ type ParserConfig struct {
 	Type         ParserType        `yaml:"type"`
 	Pattern      string            `yaml:"pattern,omitempty"`       // For regex/grok parsers
 	GrokPattern  string            `yaml:"grok_pattern,omitempty"`  // Named grok pattern
-	TimeFormat   string            `yaml:"time_format,omitempty"`   // Time parsing format
+	TimeFormat   string            `yaml:"time_format,omitempty"`   // Time parsing format, or "auto" to detect
 	TimeField    string            `yaml:"time_field,omitempty"`    // Field containing timestamp
 	LevelField   string            `yaml:"level_field,omitempty"`   // Field containing log level
 	MessageField string            `yaml:"message_field,omitempty"` // Field containing message
 	Multiline    *MultilineConfig  `yaml:"multiline,omitempty"`     // Multiline configuration
 	CustomFields map[string]string `yaml:"custom_fields,omitempty"` // Custom fields to add
+
+	// JSON field handling
+	StrictFields     bool     `yaml:"strict_fields,omitempty"`      // Drop fields not in AllowedFields
+	AllowedFields    []string `yaml:"allowed_fields,omitempty"`     // Fields kept in strict mode
+	NestedDepthLimit int      `yaml:"nested_depth_limit,omitempty"` // Maximum nesting depth, 0 for unlimited
 }
 
 // MultilineConfig holds configuration for multi-line log handling
 type MultilineConfig struct {
-	Pattern string `yaml:"pattern"`        // Regex pattern to match continuation lines
+	Pattern string `yaml:"pattern"`        // Regex pattern matching the boundary line of an event
 	Negate  bool   `yaml:"negate"`         // Whether to negate the pattern match
 	Match   string `yaml:"match"`          // "after" or "before" - where to append
 	MaxLines int   `yaml:"max_lines"`      // Maximum lines to buffer
@@ -63,6 +69,8 @@ func New(cfg *ParserConfig) (Parser, error) {
 		return NewGrokParser(cfg)
 	case ParserTypeMultiline:
 		return NewMultilineParser(cfg)
+	case ParserTypeAuto:
+		return NewAutoParser(cfg)
 	default:
 		return nil, fmt.Errorf("unknown parser type: %s", cfg.Type)
 	}
@@ -112,18 +120,5 @@ func DefaultTimeFormats() []string {
 
 // NormalizeLogLevel normalizes log level strings to standard values
 func NormalizeLogLevel(level string) string {
-	switch level {
-	case "DEBUG", "debug", "TRACE", "trace":
-		return "debug"
-	case "INFO", "info", "information", "INFORMATION":
-		return "info"
-	case "WARN", "warn", "WARNING", "warning":
-		return "warn"
-	case "ERROR", "error", "ERR", "err":
-		return "error"
-	case "FATAL", "fatal", "CRITICAL", "critical", "PANIC", "panic":
-		return "fatal"
-	default:
-		return level
-	}
+	return types.NormalizeLevel(level)
 }
@@ -34,6 +34,13 @@ func TestNew(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "create auto parser",
+			config: &ParserConfig{
+				Type: ParserTypeAuto,
+			},
+			wantErr: false,
+		},
 		{
 			name: "nil config",
 			config: nil,
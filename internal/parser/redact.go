@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// DefaultRedactMask replaces redacted values when no mask is configured
+const DefaultRedactMask = "****"
+
+// builtinRedactPatterns are redaction patterns selectable by name
+var builtinRedactPatterns = map[string]string{
+	"credit_card": `\b(?:\d[ -]?){12,18}\d\b`,
+	"email":       `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"ssn":         `\b\d{3}-\d{2}-\d{4}\b`,
+}
+
+// RedactTransformer masks sensitive values in fields and messages. Listed
+// fields, and any nested fields below them (e.g. "user" covers
+// "user.email"), are replaced entirely; patterns replace only the matching
+// parts of field values and, optionally, of the message.
+type RedactTransformer struct {
+	fields        []string
+	patterns      []*regexp.Regexp
+	mask          string
+	hash          bool
+	redactMessage bool
+}
+
+// NewRedactTransformer creates a new redaction transformer
+func NewRedactTransformer(cfg *TransformConfig) (*RedactTransformer, error) {
+	if len(cfg.Fields) == 0 && len(cfg.Patterns) == 0 {
+		return nil, fmt.Errorf("redact transformer requires fields or patterns")
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(cfg.Patterns))
+	for _, pattern := range cfg.Patterns {
+		if builtin, ok := builtinRedactPatterns[pattern]; ok {
+			pattern = builtin
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern: %w", err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	mask := cfg.Mask
+	if mask == "" {
+		mask = DefaultRedactMask
+	}
+
+	return &RedactTransformer{
+		fields:        cfg.Fields,
+		patterns:      patterns,
+		mask:          mask,
+		hash:          cfg.Hash,
+		redactMessage: cfg.RedactMessage,
+	}, nil
+}
+
+// Transform redacts sensitive values from the event
+func (t *RedactTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	for key, value := range event.Fields {
+		if t.isRedactedField(key) {
+			event.Fields[key] = t.replace(value)
+			continue
+		}
+		event.Fields[key] = t.redactPatterns(value)
+	}
+
+	if t.redactMessage {
+		event.Message = t.redactPatterns(event.Message)
+	}
+
+	return event, nil
+}
+
+// isRedactedField reports whether key is a listed field or nested below one
+func (t *RedactTransformer) isRedactedField(key string) bool {
+	for _, field := range t.fields {
+		if key == field || strings.HasPrefix(key, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactPatterns replaces every pattern match in value
+func (t *RedactTransformer) redactPatterns(value string) string {
+	for _, pattern := range t.patterns {
+		value = pattern.ReplaceAllStringFunc(value, t.replace)
+	}
+	return value
+}
+
+// replace returns the mask or hash for a sensitive value
+func (t *RedactTransformer) replace(value string) string {
+	if t.hash {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	return t.mask
+}
+
+// Name returns the transformer name
+func (t *RedactTransformer) Name() string {
+	return "redact"
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestRedactTransformer(t *testing.T) {
+	secretHash := sha256.Sum256([]byte("hunter2"))
+
+	tests := []struct {
+		name        string
+		config      *TransformConfig
+		event       *types.LogEvent
+		wantFields  map[string]string
+		wantMessage string
+	}{
+		{
+			name: "named fields",
+			config: &TransformConfig{
+				Type:   "redact",
+				Fields: []string{"password"},
+			},
+			event: &types.LogEvent{
+				Message: "login",
+				Fields: map[string]string{
+					"user":     "alice",
+					"password": "hunter2",
+				},
+			},
+			wantFields: map[string]string{
+				"user":     "alice",
+				"password": "****",
+			},
+			wantMessage: "login",
+		},
+		{
+			name: "nested fields",
+			config: &TransformConfig{
+				Type:   "redact",
+				Fields: []string{"user"},
+				Mask:   "[REDACTED]",
+			},
+			event: &types.LogEvent{
+				Fields: map[string]string{
+					"user.email":   "alice@example.com",
+					"user.address": "1 Main St",
+					"username":     "alice",
+				},
+			},
+			wantFields: map[string]string{
+				"user.email":   "[REDACTED]",
+				"user.address": "[REDACTED]",
+				"username":     "alice",
+			},
+		},
+		{
+			name: "builtin patterns in fields and message",
+			config: &TransformConfig{
+				Type:          "redact",
+				Patterns:      []string{"email", "credit_card", "ssn"},
+				RedactMessage: true,
+			},
+			event: &types.LogEvent{
+				Message: "charged 4111 1111 1111 1111 for bob@example.com",
+				Fields: map[string]string{
+					"payload": `{"contact":{"email":"bob@example.com","ssn":"123-45-6789"}}`,
+					"status":  "ok",
+				},
+			},
+			wantFields: map[string]string{
+				"payload": `{"contact":{"email":"****","ssn":"****"}}`,
+				"status":  "ok",
+			},
+			wantMessage: "charged **** for ****",
+		},
+		{
+			name: "message untouched unless enabled",
+			config: &TransformConfig{
+				Type:     "redact",
+				Patterns: []string{"email"},
+			},
+			event: &types.LogEvent{
+				Message: "mail bob@example.com",
+				Fields:  map[string]string{"to": "bob@example.com"},
+			},
+			wantFields:  map[string]string{"to": "****"},
+			wantMessage: "mail bob@example.com",
+		},
+		{
+			name: "custom pattern with hash",
+			config: &TransformConfig{
+				Type:     "redact",
+				Patterns: []string{`hunter\d`},
+				Hash:     true,
+			},
+			event: &types.LogEvent{
+				Fields: map[string]string{"note": "pw=hunter2"},
+			},
+			wantFields: map[string]string{
+				"note": "pw=" + hex.EncodeToString(secretHash[:]),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := NewTransformer(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create transformer: %v", err)
+			}
+
+			result, err := transformer.Transform(tt.event)
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+
+			if len(result.Fields) != len(tt.wantFields) {
+				t.Errorf("Fields count = %d, want %d", len(result.Fields), len(tt.wantFields))
+			}
+
+			for key, wantValue := range tt.wantFields {
+				if gotValue := result.Fields[key]; gotValue != wantValue {
+					t.Errorf("Field %s = %v, want %v", key, gotValue, wantValue)
+				}
+			}
+
+			if result.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", result.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestNewRedactTransformer_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *TransformConfig
+	}{
+		{name: "no fields or patterns", config: &TransformConfig{Type: "redact"}},
+		{name: "invalid pattern", config: &TransformConfig{Type: "redact", Patterns: []string{"[invalid"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRedactTransformer(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
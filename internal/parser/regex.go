@@ -16,6 +16,7 @@ type RegexParser struct {
 	levelField   string
 	messageField string
 	customFields map[string]string
+	detector     *timestampDetector
 }
 
 // NewRegexParser creates a new regex parser
@@ -29,20 +30,34 @@ func NewRegexParser(cfg *ParserConfig) (*RegexParser, error) {
 		return nil, fmt.Errorf("failed to compile regex pattern: %w", err)
 	}
 
-	return &RegexParser{
+	p := &RegexParser{
 		pattern:      pattern,
 		timeFormat:   cfg.TimeFormat,
 		timeField:    cfg.TimeField,
 		levelField:   cfg.LevelField,
 		messageField: cfg.MessageField,
 		customFields: cfg.CustomFields,
-	}, nil
+	}
+	if cfg.TimeFormat == TimeFormatAuto {
+		p.detector = newTimestampDetector()
+	}
+
+	return p, nil
 }
 
 // Parse parses a log line using regex pattern matching
 func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error) {
+	start := time.Now()
+	event, reason, err := p.parse(line, source)
+	observeParse(ParserTypeRegex, start, reason, err)
+	return event, err
+}
+
+// parse parses a line, returning the reason it failed to parse cleanly if
+// any
+func (p *RegexParser) parse(line string, source string) (*types.LogEvent, string, error) {
 	if line == "" {
-		return nil, fmt.Errorf("empty log line")
+		return nil, "", fmt.Errorf("empty log line")
 	}
 
 	match := p.pattern.FindStringSubmatch(line)
@@ -53,9 +68,11 @@ func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error)
 			Message:   line,
 			Source:    source,
 			Fields:    make(map[string]string),
-		}, nil
+		}, ReasonMalformed, nil
 	}
 
+	var reason string
+
 	// Extract named groups
 	fields := make(map[string]string)
 	for i, name := range p.pattern.SubexpNames() {
@@ -76,7 +93,9 @@ func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error)
 			var ts time.Time
 			var err error
 
-			if p.timeFormat != "" {
+			if p.detector != nil {
+				ts, err = p.detector.Detect(source, tsStr)
+			} else if p.timeFormat != "" {
 				ts, err = time.Parse(p.timeFormat, tsStr)
 			} else {
 				ts, err = ParseTimestamp(tsStr)
@@ -85,8 +104,12 @@ func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error)
 			if err == nil {
 				event.Timestamp = ts
 				delete(fields, p.timeField) // Remove from fields to avoid duplication
+			} else if p.detector != nil {
+				fields[TimestampErrorField] = err.Error()
+				reason = ReasonTimeParse
 			} else {
 				event.Timestamp = time.Now()
+				reason = ReasonTimeParse
 			}
 		} else {
 			event.Timestamp = time.Now()
@@ -108,6 +131,8 @@ func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error)
 		if msg, ok := fields[p.messageField]; ok {
 			event.Message = msg
 			delete(fields, p.messageField) // Remove from fields to avoid duplication
+		} else if reason == "" {
+			reason = ReasonMissingField
 		}
 	} else {
 		event.Message = line
@@ -118,7 +143,7 @@ func (p *RegexParser) Parse(line string, source string) (*types.LogEvent, error)
 		fields[key] = value
 	}
 
-	return event, nil
+	return event, reason, nil
 }
 
 // Name returns the parser name
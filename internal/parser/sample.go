@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// SampleTransformer keeps a fraction of events and drops the rest with
+// ErrDropEvent. When a key field is set, events sharing a key value are
+// kept or dropped together. Level overrides replace the rate for matching
+// levels, e.g. {"error": 1} always keeps errors.
+type SampleTransformer struct {
+	rate           float64
+	keyField       string
+	levelOverrides map[string]float64
+	random         func() float64
+}
+
+// NewSampleTransformer creates a new sampling transformer
+func NewSampleTransformer(cfg *TransformConfig) (*SampleTransformer, error) {
+	if cfg.Rate != 0 && cfg.KeepOneIn != 0 {
+		return nil, fmt.Errorf("sample transformer accepts rate or keep_one_in, not both")
+	}
+
+	rate := cfg.Rate
+	if cfg.KeepOneIn != 0 {
+		if cfg.KeepOneIn < 0 {
+			return nil, fmt.Errorf("sample keep_one_in must be positive: %d", cfg.KeepOneIn)
+		}
+		rate = 1 / float64(cfg.KeepOneIn)
+	}
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("sample rate must be in (0, 1]: %v", rate)
+	}
+
+	overrides := make(map[string]float64, len(cfg.LevelOverrides))
+	for level, levelRate := range cfg.LevelOverrides {
+		if levelRate < 0 || levelRate > 1 {
+			return nil, fmt.Errorf("sample rate for level %s must be in [0, 1]: %v", level, levelRate)
+		}
+		overrides[NormalizeLogLevel(level)] = levelRate
+	}
+
+	return &SampleTransformer{
+		rate:           rate,
+		keyField:       cfg.KeyField,
+		levelOverrides: overrides,
+		random:         rand.Float64,
+	}, nil
+}
+
+// Transform keeps or drops the event according to the sampling rate
+func (t *SampleTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	rate := t.rate
+	if levelRate, ok := t.levelOverrides[NormalizeLogLevel(event.Level)]; ok {
+		rate = levelRate
+	}
+
+	if t.position(event) < rate {
+		return event, nil
+	}
+	return nil, ErrDropEvent
+}
+
+// position maps the event to [0, 1), by hashing its key when one is set
+func (t *SampleTransformer) position(event *types.LogEvent) float64 {
+	if t.keyField != "" {
+		if key, ok := event.Fields[t.keyField]; ok && key != "" {
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			return float64(h.Sum64()>>11) / float64(uint64(1)<<53)
+		}
+	}
+	return t.random()
+}
+
+// Name returns the transformer name
+func (t *SampleTransformer) Name() string {
+	return "sample"
+}
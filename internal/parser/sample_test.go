@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// retention returns the fraction of n events the transformer keeps
+func retention(t *testing.T, transformer Transformer, n int, event func(i int) *types.LogEvent) float64 {
+	t.Helper()
+
+	kept := 0
+	for i := 0; i < n; i++ {
+		result, err := transformer.Transform(event(i))
+		switch {
+		case err == nil:
+			if result == nil {
+				t.Fatal("kept event must not be nil")
+			}
+			kept++
+		case !errors.Is(err, ErrDropEvent):
+			t.Fatalf("Transform() error = %v", err)
+		}
+	}
+	return float64(kept) / float64(n)
+}
+
+func TestSampleTransformer_Rates(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *TransformConfig
+		want   float64
+	}{
+		{name: "rate", config: &TransformConfig{Type: "sample", Rate: 0.25}, want: 0.25},
+		{name: "keep one in", config: &TransformConfig{Type: "sample", KeepOneIn: 10}, want: 0.1},
+		{name: "keyed rate", config: &TransformConfig{Type: "sample", Rate: 0.5, KeyField: "trace_id"}, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := NewTransformer(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create transformer: %v", err)
+			}
+
+			got := retention(t, transformer, 20000, func(i int) *types.LogEvent {
+				return &types.LogEvent{
+					Level:  "debug",
+					Fields: map[string]string{"trace_id": fmt.Sprintf("trace-%d", i)},
+				}
+			})
+			if math.Abs(got-tt.want) > 0.03 {
+				t.Errorf("retention = %.3f, want about %.3f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleTransformer_ConsistentKeying(t *testing.T) {
+	transformer, err := NewSampleTransformer(&TransformConfig{Type: "sample", Rate: 0.5, KeyField: "trace_id"})
+	if err != nil {
+		t.Fatalf("Failed to create transformer: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		traceID := fmt.Sprintf("trace-%d", i)
+
+		_, first := transformer.Transform(&types.LogEvent{Fields: map[string]string{"trace_id": traceID}})
+		for j := 0; j < 5; j++ {
+			_, err := transformer.Transform(&types.LogEvent{
+				Message: fmt.Sprintf("span %d", j),
+				Fields:  map[string]string{"trace_id": traceID},
+			})
+			if (err == nil) != (first == nil) {
+				t.Fatalf("events for %s were not sampled consistently", traceID)
+			}
+		}
+	}
+}
+
+func TestSampleTransformer_LevelOverrides(t *testing.T) {
+	transformer, err := NewSampleTransformer(&TransformConfig{
+		Type:           "sample",
+		KeepOneIn:      100,
+		LevelOverrides: map[string]float64{"ERROR": 1, "debug": 0},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create transformer: %v", err)
+	}
+
+	if got := retention(t, transformer, 1000, func(int) *types.LogEvent {
+		return &types.LogEvent{Level: "error"}
+	}); got != 1 {
+		t.Errorf("error retention = %.3f, want 1", got)
+	}
+
+	if got := retention(t, transformer, 1000, func(int) *types.LogEvent {
+		return &types.LogEvent{Level: "debug"}
+	}); got != 0 {
+		t.Errorf("debug retention = %.3f, want 0", got)
+	}
+}
+
+func TestSampleTransformer_Pipeline(t *testing.T) {
+	pipeline, err := NewTransformPipeline([]TransformConfig{
+		{Type: "sample", Rate: 1, LevelOverrides: map[string]float64{"debug": 0}},
+		{Type: "add", Add: map[string]string{"env": "prod"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	if _, err := pipeline.Transform(&types.LogEvent{Level: "debug"}); !errors.Is(err, ErrDropEvent) {
+		t.Errorf("expected ErrDropEvent, got %v", err)
+	}
+
+	event, err := pipeline.Transform(&types.LogEvent{Level: "info"})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if event.Fields["env"] != "prod" {
+		t.Errorf("expected later transformers to run on kept events")
+	}
+}
+
+func TestNewSampleTransformer_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *TransformConfig
+	}{
+		{name: "no rate", config: &TransformConfig{Type: "sample"}},
+		{name: "rate above one", config: &TransformConfig{Type: "sample", Rate: 1.5}},
+		{name: "negative keep one in", config: &TransformConfig{Type: "sample", KeepOneIn: -2}},
+		{name: "rate and keep one in", config: &TransformConfig{Type: "sample", Rate: 0.5, KeepOneIn: 2}},
+		{name: "invalid level override", config: &TransformConfig{Type: "sample", Rate: 0.5, LevelOverrides: map[string]float64{"error": 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSampleTransformer(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
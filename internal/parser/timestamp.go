@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormatAuto enables timestamp layout auto-detection
+const TimeFormatAuto = "auto"
+
+// TimestampErrorField is set on events whose timestamp could not be detected
+const TimestampErrorField = "timestamp_error"
+
+// timestampLayout is a named timestamp format tried during auto-detection
+type timestampLayout struct {
+	name  string
+	parse func(value string) (time.Time, error)
+}
+
+// autoTimestampLayouts lists the detectable layouts in priority order
+var autoTimestampLayouts = []timestampLayout{
+	{name: "rfc3339", parse: layoutParser(time.RFC3339)},
+	{name: "rfc3339nano", parse: layoutParser(time.RFC3339Nano)},
+	{name: "iso8601_space_zone", parse: layoutParser("2006-01-02 15:04:05.999999999Z07:00")},
+	{name: "iso8601_space", parse: layoutParser("2006-01-02 15:04:05.999999999")},
+	{name: "apache_clf", parse: layoutParser("02/Jan/2006:15:04:05 -0700")},
+	{name: "syslog_bsd", parse: parseSyslogBSD},
+	{name: "unix", parse: parseUnixSeconds},
+	{name: "unix_ms", parse: parseUnixMillis},
+}
+
+// layoutParser returns a parse function for a Go time layout
+func layoutParser(layout string) func(string) (time.Time, error) {
+	return func(value string) (time.Time, error) {
+		return time.Parse(layout, value)
+	}
+}
+
+// parseSyslogBSD parses RFC 3164 timestamps, which carry no year. The
+// current year is assumed, or the previous one if that lands in the future.
+func parseSyslogBSD(value string) (time.Time, error) {
+	ts, err := time.Parse(time.Stamp, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	ts = ts.AddDate(now.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts, nil
+}
+
+// parseUnixSeconds parses epoch seconds with an optional fraction
+func parseUnixSeconds(value string) (time.Time, error) {
+	whole, frac, _ := strings.Cut(value, ".")
+	if len(whole) == 0 || len(whole) > 10 {
+		return time.Time{}, fmt.Errorf("not epoch seconds: %s", value)
+	}
+
+	secs, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var nanos int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		nanos, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Unix(secs, nanos).UTC(), nil
+}
+
+// parseUnixMillis parses epoch milliseconds
+func parseUnixMillis(value string) (time.Time, error) {
+	if len(value) < 11 || len(value) > 13 {
+		return time.Time{}, fmt.Errorf("not epoch milliseconds: %s", value)
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(millis).UTC(), nil
+}
+
+// timestampDetector detects timestamp layouts, remembering the layout that
+// last matched for each source so later lines try it first
+type timestampDetector struct {
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// newTimestampDetector creates a timestamp detector with an empty cache
+func newTimestampDetector() *timestampDetector {
+	return &timestampDetector{
+		cache: make(map[string]int),
+	}
+}
+
+// Detect parses value with the cached layout for source, falling back to
+// every known layout in priority order
+func (d *timestampDetector) Detect(source, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	d.mu.Lock()
+	cached, ok := d.cache[source]
+	d.mu.Unlock()
+
+	if ok {
+		if ts, err := autoTimestampLayouts[cached].parse(value); err == nil {
+			return ts, nil
+		}
+	}
+
+	for i, layout := range autoTimestampLayouts {
+		if ok && i == cached {
+			continue
+		}
+		if ts, err := layout.parse(value); err == nil {
+			d.mu.Lock()
+			d.cache[source] = i
+			d.mu.Unlock()
+			return ts, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized timestamp format: %s", value)
+}
+
+// layout returns the name of the cached layout for source
+func (d *timestampDetector) layout(source string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i, ok := d.cache[source]
+	if !ok {
+		return "", false
+	}
+	return autoTimestampLayouts[i].name, true
+}
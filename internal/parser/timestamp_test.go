@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampDetector_Layouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantLayout string
+		want       time.Time
+	}{
+		{
+			name:       "rfc3339",
+			input:      "2024-01-15T10:30:00Z",
+			wantLayout: "rfc3339",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "rfc3339 nano",
+			input:      "2024-01-15T10:30:00.123456789+02:00",
+			wantLayout: "rfc3339",
+			want:       time.Date(2024, 1, 15, 8, 30, 0, 123456789, time.UTC),
+		},
+		{
+			name:       "iso8601 with space and zone",
+			input:      "2024-01-15 10:30:00.5Z",
+			wantLayout: "iso8601_space_zone",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 500000000, time.UTC),
+		},
+		{
+			name:       "iso8601 with space",
+			input:      "2024-01-15 10:30:00",
+			wantLayout: "iso8601_space",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "apache clf",
+			input:      "15/Jan/2024:10:30:00 +0000",
+			wantLayout: "apache_clf",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "unix seconds",
+			input:      "1705314600",
+			wantLayout: "unix",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "unix seconds with fraction",
+			input:      "1705314600.25",
+			wantLayout: "unix",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 250000000, time.UTC),
+		},
+		{
+			name:       "unix millis",
+			input:      "1705314600123",
+			wantLayout: "unix_ms",
+			want:       time.Date(2024, 1, 15, 10, 30, 0, 123000000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTimestampDetector()
+
+			got, err := d.Detect("app.log", tt.input)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+			if layout, _ := d.layout("app.log"); layout != tt.wantLayout {
+				t.Errorf("cached layout = %q, want %q", layout, tt.wantLayout)
+			}
+		})
+	}
+}
+
+func TestTimestampDetector_SyslogBSD(t *testing.T) {
+	d := newTimestampDetector()
+
+	got, err := d.Detect("syslog", "Jan  5 10:30:00")
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if got.Month() != time.January || got.Day() != 5 || got.Hour() != 10 {
+		t.Errorf("Detect() = %v", got)
+	}
+	if got.Year() < time.Now().Year()-1 {
+		t.Errorf("expected current or previous year, got %d", got.Year())
+	}
+	if layout, _ := d.layout("syslog"); layout != "syslog_bsd" {
+		t.Errorf("cached layout = %q, want syslog_bsd", layout)
+	}
+}
+
+func TestTimestampDetector_Caching(t *testing.T) {
+	d := newTimestampDetector()
+
+	if _, err := d.Detect("a.log", "15/Jan/2024:10:30:00 +0000"); err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if _, err := d.Detect("b.log", "1705314600"); err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+
+	// Each source keeps its own layout
+	if layout, _ := d.layout("a.log"); layout != "apache_clf" {
+		t.Errorf("a.log layout = %q, want apache_clf", layout)
+	}
+	if layout, _ := d.layout("b.log"); layout != "unix" {
+		t.Errorf("b.log layout = %q, want unix", layout)
+	}
+
+	// A source switching format falls back and re-caches
+	if _, err := d.Detect("a.log", "2024-01-15T10:30:00Z"); err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if layout, _ := d.layout("a.log"); layout != "rfc3339" {
+		t.Errorf("a.log layout after switch = %q, want rfc3339", layout)
+	}
+
+	// Failures leave the cache untouched
+	if _, err := d.Detect("a.log", "not a time"); err == nil {
+		t.Error("expected error for unrecognized timestamp")
+	}
+	if layout, _ := d.layout("a.log"); layout != "rfc3339" {
+		t.Errorf("a.log layout after failure = %q, want rfc3339", layout)
+	}
+}
+
+func TestParsers_AutoTimeFormat(t *testing.T) {
+	want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		config *ParserConfig
+		input  string
+	}{
+		{
+			name: "regex",
+			config: &ParserConfig{
+				Type:       ParserTypeRegex,
+				Pattern:    `^(?P<timestamp>\S+ \S+) (?P<message>.*)$`,
+				TimeField:  "timestamp",
+				TimeFormat: TimeFormatAuto,
+			},
+			input: "2024-01-15 10:30:00Z started",
+		},
+		{
+			name: "json epoch number",
+			config: &ParserConfig{
+				Type:       ParserTypeJSON,
+				TimeField:  "ts",
+				TimeFormat: TimeFormatAuto,
+			},
+			input: `{"ts": 1705314600, "msg": "started"}`,
+		},
+		{
+			name: "grok",
+			config: &ParserConfig{
+				Type:       ParserTypeGrok,
+				Pattern:    `%{NOTSPACE:timestamp} %{GREEDYDATA:message}`,
+				TimeFormat: TimeFormatAuto,
+			},
+			input: "1705314600000 started",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			event, err := p.Parse(tt.input, "app.log")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !event.Timestamp.Equal(want) {
+				t.Errorf("Timestamp = %v, want %v", event.Timestamp, want)
+			}
+			if _, ok := event.Fields[TimestampErrorField]; ok {
+				t.Errorf("unexpected %s field", TimestampErrorField)
+			}
+		})
+	}
+}
+
+func TestParsers_AutoTimeFormatFailure(t *testing.T) {
+	p, err := New(&ParserConfig{
+		Type:       ParserTypeRegex,
+		Pattern:    `^(?P<timestamp>\S+) (?P<message>.*)$`,
+		TimeField:  "timestamp",
+		TimeFormat: TimeFormatAuto,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	event, err := p.Parse("yesterday started", "app.log")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !event.Timestamp.IsZero() {
+		t.Errorf("Timestamp = %v, want zero", event.Timestamp)
+	}
+	if event.Fields[TimestampErrorField] == "" {
+		t.Errorf("expected %s field to be set", TimestampErrorField)
+	}
+	if event.Fields["timestamp"] != "yesterday" {
+		t.Errorf("expected original timestamp field to be kept, got %q", event.Fields["timestamp"])
+	}
+}
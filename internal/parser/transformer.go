@@ -1,14 +1,20 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
+// ErrDropEvent is returned by transformers that drop an event; the event
+// must not be emitted
+var ErrDropEvent = errors.New("drop event")
+
 // Transformer applies transformations to log events
 type Transformer interface {
 	Transform(event *types.LogEvent) (*types.LogEvent, error)
@@ -17,16 +23,33 @@ type Transformer interface {
 
 // TransformConfig holds transformation configuration
 type TransformConfig struct {
-	Type         string            `yaml:"type"`
-	Fields       []string          `yaml:"fields,omitempty"`        // Fields to operate on
-	IncludeFields []string         `yaml:"include_fields,omitempty"` // Fields to keep
-	ExcludeFields []string         `yaml:"exclude_fields,omitempty"` // Fields to remove
-	Rename       map[string]string `yaml:"rename,omitempty"`        // Field renaming map
-	Add          map[string]string `yaml:"add,omitempty"`           // Fields to add
-	Patterns     []string          `yaml:"patterns,omitempty"`      // KV extraction patterns
-	FieldSplit   string            `yaml:"field_split,omitempty"`   // Field separator for KV
-	ValueSplit   string            `yaml:"value_split,omitempty"`   // Value separator for KV
-	Prefix       string            `yaml:"prefix,omitempty"`        // Prefix for extracted fields
+	Type           string             `yaml:"type"`
+	Fields         []string           `yaml:"fields,omitempty"`          // Fields to operate on
+	IncludeFields  []string           `yaml:"include_fields,omitempty"`  // Fields to keep
+	ExcludeFields  []string           `yaml:"exclude_fields,omitempty"`  // Fields to remove
+	Rename         map[string]string  `yaml:"rename,omitempty"`          // Field renaming map
+	Add            map[string]string  `yaml:"add,omitempty"`             // Fields to add
+	Patterns       []string           `yaml:"patterns,omitempty"`        // KV extraction patterns
+	FieldSplit     string             `yaml:"field_split,omitempty"`     // Field separator for KV
+	ValueSplit     string             `yaml:"value_split,omitempty"`     // Value separator for KV
+	Prefix         string             `yaml:"prefix,omitempty"`          // Prefix for extracted fields
+	Mask           string             `yaml:"mask,omitempty"`            // Replacement for redacted values
+	Hash           bool               `yaml:"hash,omitempty"`            // Replace redacted values with a hash
+	RedactMessage  bool               `yaml:"redact_message,omitempty"`  // Also redact the message body
+	Separator      string             `yaml:"separator,omitempty"`       // Key separator for flattening
+	MaxDepth       int                `yaml:"max_depth,omitempty"`       // Maximum depth to flatten
+	KeepOriginal   bool               `yaml:"keep_original,omitempty"`   // Keep nested fields after flattening
+	Rate           float64            `yaml:"rate,omitempty"`            // Fraction of events to keep when sampling
+	KeepOneIn      int                `yaml:"keep_one_in,omitempty"`     // Keep 1 in N events when sampling
+	KeyField       string             `yaml:"key_field,omitempty"`       // Field to sample consistently by
+	LevelOverrides map[string]float64 `yaml:"level_overrides,omitempty"` // Per-level sampling rates
+	Field          string             `yaml:"field,omitempty"`           // Field tested by drop_if
+	Operator       string             `yaml:"operator,omitempty"`        // drop_if operator: eq, ne, gt, lt, contains, matches
+	Value          string             `yaml:"value,omitempty"`           // Value compared by drop_if
+	Hostname       bool               `yaml:"hostname,omitempty"`        // Add the host name when enriching
+	PID            bool               `yaml:"pid,omitempty"`             // Add the process ID when enriching
+	TTL            time.Duration      `yaml:"ttl,omitempty"`             // Window in which dedup drops repeated events
+	MaxEntries     int                `yaml:"max_entries,omitempty"`     // Maximum event hashes remembered by dedup
 }
 
 // TransformPipeline is a series of transformers
@@ -51,11 +74,28 @@ func NewTransformPipeline(configs []TransformConfig) (*TransformPipeline, error)
 	}, nil
 }
 
-// Transform applies all transformers in the pipeline
+// Append adds a transformer to the end of the pipeline. It must not be
+// called while the pipeline is transforming events.
+func (p *TransformPipeline) Append(transformer Transformer) {
+	p.transformers = append(p.transformers, transformer)
+}
+
+// Len returns the number of transformers in the pipeline
+func (p *TransformPipeline) Len() int {
+	return len(p.transformers)
+}
+
+// Transform applies all transformers in the pipeline. A transformer drops
+// the event by returning ErrDropEvent or a nil event; the pipeline then stops
+// and returns a nil event with ErrDropEvent so the caller does not emit it.
+// Any other error also stops the pipeline and is returned with the event.
 func (p *TransformPipeline) Transform(event *types.LogEvent) (*types.LogEvent, error) {
 	var err error
 	for _, transformer := range p.transformers {
 		event, err = transformer.Transform(event)
+		if errors.Is(err, ErrDropEvent) || (err == nil && event == nil) {
+			return nil, ErrDropEvent
+		}
 		if err != nil {
 			return event, err
 		}
@@ -76,6 +116,18 @@ func NewTransformer(cfg *TransformConfig) (Transformer, error) {
 		return NewKVExtractor(cfg)
 	case "convert":
 		return NewTypeConverter(cfg)
+	case "redact":
+		return NewRedactTransformer(cfg)
+	case "flatten":
+		return NewFlattenTransformer(cfg)
+	case "sample":
+		return NewSampleTransformer(cfg)
+	case "drop_if":
+		return NewDropIfTransformer(cfg)
+	case "enrich":
+		return NewEnrichTransformer(cfg)
+	case "dedup":
+		return NewDedupTransformer(cfg)
 	default:
 		return nil, fmt.Errorf("unknown transformer type: %s", cfg.Type)
 	}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -319,6 +321,77 @@ func TestTransformPipeline(t *testing.T) {
 	}
 }
 
+// stageTransformer records calls and returns a fixed result
+type stageTransformer struct {
+	calls int
+	drop  bool
+	err   error
+}
+
+func (s *stageTransformer) Transform(event *types.LogEvent) (*types.LogEvent, error) {
+	s.calls++
+	if s.drop {
+		return nil, s.err
+	}
+	return event, s.err
+}
+
+func (s *stageTransformer) Name() string {
+	return "stage"
+}
+
+func TestTransformPipeline_Drop(t *testing.T) {
+	tests := []struct {
+		name    string
+		middle  *stageTransformer
+		wantErr error
+	}{
+		{name: "sentinel error", middle: &stageTransformer{drop: true, err: ErrDropEvent}, wantErr: ErrDropEvent},
+		{name: "wrapped sentinel error", middle: &stageTransformer{drop: true, err: fmt.Errorf("health check: %w", ErrDropEvent)}, wantErr: ErrDropEvent},
+		{name: "nil event", middle: &stageTransformer{drop: true}, wantErr: ErrDropEvent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &stageTransformer{}
+			last := &stageTransformer{}
+			pipeline := &TransformPipeline{transformers: []Transformer{first, tt.middle, last}}
+
+			result, err := pipeline.Transform(&types.LogEvent{Message: "GET /healthz"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Transform() error = %v, want %v", err, tt.wantErr)
+			}
+			if result != nil {
+				t.Errorf("expected nil event after drop, got %v", result)
+			}
+			if first.calls != 1 || tt.middle.calls != 1 {
+				t.Errorf("expected stages before the drop to run once, got %d and %d", first.calls, tt.middle.calls)
+			}
+			if last.calls != 0 {
+				t.Errorf("expected stages after the drop to be skipped, got %d calls", last.calls)
+			}
+		})
+	}
+}
+
+func TestTransformPipeline_ErrorStops(t *testing.T) {
+	failure := errors.New("boom")
+	last := &stageTransformer{}
+	pipeline := &TransformPipeline{transformers: []Transformer{&stageTransformer{err: failure}, last}}
+
+	event := &types.LogEvent{Message: "test"}
+	result, err := pipeline.Transform(event)
+	if !errors.Is(err, failure) {
+		t.Errorf("Transform() error = %v, want %v", err, failure)
+	}
+	if result != event {
+		t.Error("expected the event to be returned with a non-drop error")
+	}
+	if last.calls != 0 {
+		t.Errorf("expected stages after the error to be skipped, got %d calls", last.calls)
+	}
+}
+
 func TestNewTransformer_UnknownType(t *testing.T) {
 	config := &TransformConfig{
 		Type: "unknown",
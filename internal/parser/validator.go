@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ErrInvalidJSON is returned when a payload checked against a schema is not
+// valid JSON
+var ErrInvalidJSON = errors.New("payload is not valid JSON")
+
+// SchemaValidator checks JSON events against a JSON Schema
+type SchemaValidator struct {
+	path   string
+	schema *jsonschema.Schema
+}
+
+// NewSchemaValidator compiles the JSON Schema in the file at path
+func NewSchemaValidator(path string) (*SchemaValidator, error) {
+	if path == "" {
+		return nil, fmt.Errorf("schema validator requires a schema path")
+	}
+
+	schema, err := jsonschema.NewCompiler().Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %s: %w", path, err)
+	}
+
+	return &SchemaValidator{path: path, schema: schema}, nil
+}
+
+// Validate checks a decoded JSON value, such as the result of json.Unmarshal
+// into an interface{} or map[string]interface{}
+func (v *SchemaValidator) Validate(data interface{}) error {
+	if err := v.schema.Validate(data); err != nil {
+		return fmt.Errorf("schema validation failed: %s", validationMessage(err))
+	}
+	return nil
+}
+
+// ValidateJSON decodes and checks a raw JSON payload
+func (v *SchemaValidator) ValidateJSON(payload []byte) error {
+	data, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return ErrInvalidJSON
+	}
+	return v.Validate(data)
+}
+
+// Path returns the schema file path
+func (v *SchemaValidator) Path() string {
+	return v.path
+}
+
+// validationMessage joins the causes of a validation error on one line,
+// dropping the header naming the schema file
+func validationMessage(err error) string {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err.Error()
+	}
+
+	lines := strings.Split(strings.TrimSpace(validationErr.Error()), "\n")
+	if len(lines) > 1 {
+		lines = lines[1:]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testEventSchema = `{
+  "type": "object",
+  "required": ["message", "level"],
+  "properties": {
+    "message": {"type": "string"},
+    "level": {"type": "string", "enum": ["debug", "info", "warn", "error"]},
+    "status": {"type": "integer"}
+  }
+}`
+
+// writeTestSchema writes testEventSchema to a temporary file
+func writeTestSchema(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "event.schema.json")
+	if err := os.WriteFile(path, []byte(testEventSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	return path
+}
+
+func TestSchemaValidator_ValidateJSON(t *testing.T) {
+	validator, err := NewSchemaValidator(writeTestSchema(t))
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{
+			name:    "conforming",
+			payload: `{"message": "user logged in", "level": "info", "status": 200}`,
+		},
+		{
+			name:    "missing required field",
+			payload: `{"message": "user logged in"}`,
+			wantErr: "level",
+		},
+		{
+			name:    "type mismatch",
+			payload: `{"message": "user logged in", "level": "info", "status": "ok"}`,
+			wantErr: "status",
+		},
+		{
+			name:    "invalid JSON",
+			payload: `user logged in`,
+			wantErr: ErrInvalidJSON.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateJSON([]byte(tt.payload))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateJSON() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateJSON() error = %v, want error mentioning %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "\n") {
+				t.Errorf("expected a single-line error, got %q", err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidator_Validate(t *testing.T) {
+	validator, err := NewSchemaValidator(writeTestSchema(t))
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+
+	if err := validator.ValidateJSON([]byte("not json")); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("expected ErrInvalidJSON, got %v", err)
+	}
+
+	// Values decoded by encoding/json hold numbers as float64
+	if err := validator.Validate(map[string]interface{}{"message": "m", "level": "warn", "status": float64(503)}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := validator.Validate(map[string]interface{}{"message": "m", "level": "trace"}); err == nil {
+		t.Error("expected an error for a level outside the enum")
+	}
+}
+
+func TestNewSchemaValidator_Errors(t *testing.T) {
+	if _, err := NewSchemaValidator(""); err == nil {
+		t.Error("expected error for an empty schema path")
+	}
+	if _, err := NewSchemaValidator(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for a missing schema file")
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte(`{"type": 5}`), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	if _, err := NewSchemaValidator(path); err == nil {
+		t.Error("expected error for an invalid schema")
+	}
+}
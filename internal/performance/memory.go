@@ -0,0 +1,70 @@
+package performance
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+)
+
+// defaultMemoryCheckInterval is how often the heap is checked when the
+// memory pressure configuration does not set an interval
+const defaultMemoryCheckInterval = 5 * time.Second
+
+// MemoryWatchdog checks the heap in use against a threshold and calls its
+// pressure handler for every check over it
+type MemoryWatchdog struct {
+	threshold  uint64
+	interval   time.Duration
+	onPressure func(ctx context.Context, heapAlloc uint64)
+	heapAlloc  func() uint64
+}
+
+// NewMemoryWatchdog creates a watchdog calling onPressure with the heap in
+// use while it exceeds the configured threshold
+func NewMemoryWatchdog(cfg config.MemoryPressureConfig, onPressure func(ctx context.Context, heapAlloc uint64)) *MemoryWatchdog {
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultMemoryCheckInterval
+	}
+	return &MemoryWatchdog{
+		threshold:  cfg.HeapThreshold,
+		interval:   interval,
+		onPressure: onPressure,
+		heapAlloc:  readHeapAlloc,
+	}
+}
+
+// Check compares the heap in use with the threshold, calling the pressure
+// handler and reporting true when it is over
+func (w *MemoryWatchdog) Check(ctx context.Context) bool {
+	heapAlloc := w.heapAlloc()
+	if heapAlloc <= w.threshold {
+		return false
+	}
+	w.onPressure(ctx, heapAlloc)
+	return true
+}
+
+// Run checks the heap every interval until ctx is done
+func (w *MemoryWatchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.Check(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readHeapAlloc returns the bytes of allocated heap objects
+func readHeapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
@@ -0,0 +1,54 @@
+package performance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+)
+
+func TestMemoryWatchdog_Check(t *testing.T) {
+	var pressures []uint64
+	watchdog := NewMemoryWatchdog(config.MemoryPressureConfig{Enabled: true, HeapThreshold: 1000}, func(_ context.Context, heapAlloc uint64) {
+		pressures = append(pressures, heapAlloc)
+	})
+
+	if watchdog.interval != defaultMemoryCheckInterval {
+		t.Errorf("Expected the default check interval, got %v", watchdog.interval)
+	}
+
+	for _, heapAlloc := range []uint64{500, 1000, 1500} {
+		watchdog.heapAlloc = func() uint64 { return heapAlloc }
+		if over := watchdog.Check(context.Background()); over != (heapAlloc > 1000) {
+			t.Errorf("Check() with %d bytes = %v", heapAlloc, over)
+		}
+	}
+
+	if len(pressures) != 1 || pressures[0] != 1500 {
+		t.Errorf("Expected one pressure call with 1500 bytes, got %v", pressures)
+	}
+}
+
+func TestMemoryWatchdog_Run(t *testing.T) {
+	pressure := make(chan uint64, 1)
+	watchdog := NewMemoryWatchdog(config.MemoryPressureConfig{Enabled: true, HeapThreshold: 1, CheckInterval: 10 * time.Millisecond}, func(_ context.Context, heapAlloc uint64) {
+		select {
+		case pressure <- heapAlloc:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+
+	select {
+	case heapAlloc := <-pressure:
+		if heapAlloc <= 1 {
+			t.Errorf("Expected the heap in use over the threshold, got %d", heapAlloc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the watchdog to report pressure")
+	}
+}
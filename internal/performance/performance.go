@@ -0,0 +1,64 @@
+package performance
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
+)
+
+// Settings holds the tuning values in effect after Apply
+type Settings struct {
+	GOMAXPROCS         int
+	GCPercent          int
+	EnablePooling      bool
+	ChannelBufferSize  int
+	MaxConcurrentReads int
+}
+
+// Apply applies the performance configuration to the runtime and the event
+// pool. Zero or invalid values leave the runtime defaults in place.
+func Apply(cfg config.PerformanceConfig) Settings {
+	if cfg.GOMAXPROCS > 0 {
+		runtime.GOMAXPROCS(cfg.GOMAXPROCS)
+	}
+
+	gcPercent := currentGCPercent()
+	if cfg.GCPercent > 0 {
+		debug.SetGCPercent(cfg.GCPercent)
+		gcPercent = cfg.GCPercent
+	}
+
+	pool.SetEnabled(cfg.EnablePooling)
+
+	settings := Settings{
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		GCPercent:     gcPercent,
+		EnablePooling: cfg.EnablePooling,
+	}
+	if cfg.ChannelBufferSize > 0 {
+		settings.ChannelBufferSize = cfg.ChannelBufferSize
+	}
+	if cfg.MaxConcurrentReads > 0 {
+		settings.MaxConcurrentReads = cfg.MaxConcurrentReads
+	}
+
+	return settings
+}
+
+// BufferSize returns the configured buffer size of an input, falling back to
+// the global channel buffer size when the input does not set one
+func (s Settings) BufferSize(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return s.ChannelBufferSize
+}
+
+// currentGCPercent returns the GC percent without changing it
+func currentGCPercent() int {
+	percent := debug.SetGCPercent(100)
+	debug.SetGCPercent(percent)
+	return percent
+}
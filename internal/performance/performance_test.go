@@ -0,0 +1,100 @@
+package performance
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/pool"
+)
+
+// restoreRuntime resets the runtime settings changed by Apply
+func restoreRuntime(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	gcPercent := debug.SetGCPercent(100)
+	debug.SetGCPercent(gcPercent)
+
+	t.Cleanup(func() {
+		runtime.GOMAXPROCS(procs)
+		debug.SetGCPercent(gcPercent)
+		pool.SetEnabled(true)
+	})
+}
+
+func TestApply(t *testing.T) {
+	restoreRuntime(t)
+
+	settings := Apply(config.PerformanceConfig{
+		EnablePooling:      true,
+		GOMAXPROCS:         2,
+		GCPercent:          250,
+		ChannelBufferSize:  4096,
+		MaxConcurrentReads: 8,
+	})
+
+	if got := runtime.GOMAXPROCS(0); got != 2 {
+		t.Errorf("Expected GOMAXPROCS 2, got %d", got)
+	}
+	if got := currentGCPercent(); got != 250 {
+		t.Errorf("Expected GC percent 250, got %d", got)
+	}
+	if !pool.Enabled() {
+		t.Error("Expected pooling to be enabled")
+	}
+
+	want := Settings{
+		GOMAXPROCS:         2,
+		GCPercent:          250,
+		EnablePooling:      true,
+		ChannelBufferSize:  4096,
+		MaxConcurrentReads: 8,
+	}
+	if settings != want {
+		t.Errorf("Apply() = %+v, want %+v", settings, want)
+	}
+}
+
+func TestApply_InvalidValuesKeepDefaults(t *testing.T) {
+	restoreRuntime(t)
+
+	procs := runtime.GOMAXPROCS(0)
+	gcPercent := currentGCPercent()
+
+	settings := Apply(config.PerformanceConfig{
+		GOMAXPROCS:         -1,
+		GCPercent:          -5,
+		ChannelBufferSize:  -10,
+		MaxConcurrentReads: 0,
+	})
+
+	if got := runtime.GOMAXPROCS(0); got != procs {
+		t.Errorf("Expected GOMAXPROCS to stay %d, got %d", procs, got)
+	}
+	if got := currentGCPercent(); got != gcPercent {
+		t.Errorf("Expected GC percent to stay %d, got %d", gcPercent, got)
+	}
+	if settings.GOMAXPROCS != procs || settings.GCPercent != gcPercent {
+		t.Errorf("Expected effective defaults, got %+v", settings)
+	}
+	if settings.ChannelBufferSize != 0 || settings.MaxConcurrentReads != 0 {
+		t.Errorf("Expected invalid sizes to be ignored, got %+v", settings)
+	}
+	if pool.Enabled() {
+		t.Error("Expected pooling to be disabled")
+	}
+}
+
+func TestSettings_BufferSize(t *testing.T) {
+	settings := Settings{ChannelBufferSize: 2048}
+
+	if got := settings.BufferSize(100); got != 100 {
+		t.Errorf("Expected input buffer size to win, got %d", got)
+	}
+	if got := settings.BufferSize(0); got != 2048 {
+		t.Errorf("Expected global buffer size, got %d", got)
+	}
+	if got := (Settings{}).BufferSize(0); got != 0 {
+		t.Errorf("Expected zero to keep the input default, got %d", got)
+	}
+}
@@ -3,6 +3,8 @@ package pool
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
@@ -11,20 +13,39 @@ import (
 var EventPool = sync.Pool{
 	New: func() interface{} {
 		return &types.LogEvent{
-			Fields: make(map[string]interface{}, 8), // Pre-allocate some capacity
+			Fields: make(map[string]string, 8), // Pre-allocate some capacity
 		}
 	},
 }
 
-// GetEvent retrieves a LogEvent from the pool
+// poolingDisabled turns GetEvent and PutEvent into plain allocations
+var poolingDisabled atomic.Bool
+
+// SetEnabled enables or disables event pooling globally
+func SetEnabled(enabled bool) {
+	poolingDisabled.Store(!enabled)
+}
+
+// Enabled reports whether event pooling is enabled
+func Enabled() bool {
+	return !poolingDisabled.Load()
+}
+
+// GetEvent retrieves a LogEvent from the pool, or allocates a new one when
+// pooling is disabled
 func GetEvent() *types.LogEvent {
+	if poolingDisabled.Load() {
+		return &types.LogEvent{Fields: make(map[string]string, 8)}
+	}
+
 	event := EventPool.Get().(*types.LogEvent)
 	// Reset the event
-	event.Timestamp = ""
+	event.Timestamp = time.Time{}
 	event.Level = ""
 	event.Message = ""
 	event.Source = ""
 	event.Raw = ""
+	event.Context = nil
 	// Clear map but keep allocated memory
 	for k := range event.Fields {
 		delete(event.Fields, k)
@@ -34,7 +55,7 @@ func GetEvent() *types.LogEvent {
 
 // PutEvent returns a LogEvent to the pool
 func PutEvent(event *types.LogEvent) {
-	if event != nil {
+	if event != nil && !poolingDisabled.Load() {
 		EventPool.Put(event)
 	}
 }
@@ -64,6 +85,38 @@ func PutByteBuffer(buf *bytes.Buffer) {
 	}
 }
 
+// maxBatchBufferSize is the largest batch buffer kept for reuse
+const maxBatchBufferSize = 16 * 1024 * 1024
+
+// BatchBufferPool is a pool of buffers for building output request bodies,
+// which outgrow the byte buffers kept by ByteBufferPool
+var BatchBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBatchBuffer retrieves a batch buffer from the pool, or allocates a new
+// one when pooling is disabled
+func GetBatchBuffer() *bytes.Buffer {
+	if poolingDisabled.Load() {
+		return new(bytes.Buffer)
+	}
+
+	buf := BatchBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBatchBuffer returns a batch buffer to the pool. The caller must be
+// done with the buffer's bytes, including readers of them.
+func PutBatchBuffer(buf *bytes.Buffer) {
+	if buf != nil && buf.Cap() <= maxBatchBufferSize && !poolingDisabled.Load() {
+		buf.Reset()
+		BatchBufferPool.Put(buf)
+	}
+}
+
 // StringBuilderPool is a pool of strings.Builder for efficient string concatenation
 type StringBuilderPool struct {
 	pool sync.Pool
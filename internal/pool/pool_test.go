@@ -2,6 +2,7 @@ package pool
 
 import (
 	"testing"
+	"time"
 )
 
 func TestEventPool(t *testing.T) {
@@ -12,8 +13,8 @@ func TestEventPool(t *testing.T) {
 	}
 
 	// Verify event is clean
-	if event.Timestamp != "" {
-		t.Errorf("Expected empty timestamp, got %s", event.Timestamp)
+	if !event.Timestamp.IsZero() {
+		t.Errorf("Expected zero timestamp, got %s", event.Timestamp)
 	}
 
 	if event.Level != "" {
@@ -29,7 +30,7 @@ func TestEventPool(t *testing.T) {
 	}
 
 	// Set some values
-	event.Timestamp = "2024-01-01T10:00:00Z"
+	event.Timestamp = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
 	event.Level = "info"
 	event.Message = "Test message"
 	event.Fields["key"] = "value"
@@ -44,8 +45,8 @@ func TestEventPool(t *testing.T) {
 	}
 
 	// Verify it's clean (could be the same object)
-	if event2.Timestamp != "" {
-		t.Errorf("Expected empty timestamp, got %s", event2.Timestamp)
+	if !event2.Timestamp.IsZero() {
+		t.Errorf("Expected zero timestamp, got %s", event2.Timestamp)
 	}
 
 	if len(event2.Fields) != 0 {
@@ -53,6 +54,26 @@ func TestEventPool(t *testing.T) {
 	}
 }
 
+func TestEventPoolDisabled(t *testing.T) {
+	SetEnabled(false)
+	defer SetEnabled(true)
+
+	if Enabled() {
+		t.Fatal("Expected pooling to be disabled")
+	}
+
+	event := GetEvent()
+	if event == nil || event.Fields == nil {
+		t.Fatal("Expected a new event with a fields map")
+	}
+	event.Message = "not pooled"
+	PutEvent(event)
+
+	if got := GetEvent(); got == event {
+		t.Error("Expected a fresh event while pooling is disabled")
+	}
+}
+
 func TestByteBufferPool(t *testing.T) {
 	// Get buffer from pool
 	buf := GetByteBuffer()
@@ -88,6 +109,27 @@ func TestByteBufferPool(t *testing.T) {
 	}
 }
 
+func TestBatchBufferPool(t *testing.T) {
+	buf := GetBatchBuffer()
+	buf.Write(make([]byte, 128*1024))
+
+	// Batch buffers larger than the byte buffer limit are still reused
+	PutBatchBuffer(buf)
+
+	buf2 := GetBatchBuffer()
+	if buf2.Len() != 0 {
+		t.Errorf("Expected empty buffer, got %d bytes", buf2.Len())
+	}
+	PutBatchBuffer(buf2)
+
+	SetEnabled(false)
+	defer SetEnabled(true)
+
+	if buf := GetBatchBuffer(); buf.Cap() != 0 {
+		t.Errorf("Expected a new buffer with pooling disabled, got capacity %d", buf.Cap())
+	}
+}
+
 func TestStringBuilderPool(t *testing.T) {
 	pool := NewStringBuilderPool()
 	if pool == nil {
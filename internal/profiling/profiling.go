@@ -3,6 +3,7 @@ package profiling
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -11,18 +12,20 @@ import (
 	"sync"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
 )
 
 // Config holds profiling configuration
 type Config struct {
-	Enabled        bool   `yaml:"enabled"`
-	Address        string `yaml:"address"`         // HTTP server address for pprof
-	CPUProfilePath string `yaml:"cpu_profile"`     // Path for CPU profile output
-	MemProfilePath string `yaml:"mem_profile"`     // Path for memory profile output
-	BlockProfile   bool   `yaml:"block_profile"`   // Enable blocking profiling
-	MutexProfile   bool   `yaml:"mutex_profile"`   // Enable mutex profiling
-	GoroutineThreshold int `yaml:"goroutine_threshold"` // Warn if goroutines exceed this
+	Enabled            bool          `yaml:"enabled"`
+	Address            string        `yaml:"address"`             // HTTP server address for pprof
+	CPUProfilePath     string        `yaml:"cpu_profile"`         // Path for CPU profile output
+	MemProfilePath     string        `yaml:"mem_profile"`         // Path for memory profile output
+	BlockProfile       bool          `yaml:"block_profile"`       // Enable blocking profiling
+	MutexProfile       bool          `yaml:"mutex_profile"`       // Enable mutex profiling
+	GoroutineThreshold int           `yaml:"goroutine_threshold"` // Warn if goroutines exceed this
+	CheckInterval      time.Duration `yaml:"check_interval"`      // How often the goroutine count is checked
 }
 
 // Profiler manages performance profiling
@@ -31,7 +34,8 @@ type Profiler struct {
 	logger *logging.Logger
 	server *http.Server
 
-	cpuFile *os.File
+	cpuFile  *os.File
+	listener net.Listener
 
 	mu     sync.Mutex
 	ctx    context.Context
@@ -41,7 +45,7 @@ type Profiler struct {
 // New creates a new profiler
 func New(config Config, logger *logging.Logger) (*Profiler, error) {
 	if logger == nil {
-		logger = logging.GetGlobal()
+		logger = logging.Global()
 	}
 
 	if config.Address == "" {
@@ -52,6 +56,10 @@ func New(config Config, logger *logging.Logger) (*Profiler, error) {
 		config.GoroutineThreshold = 10000
 	}
 
+	if config.CheckInterval == 0 {
+		config.CheckInterval = 30 * time.Second
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	p := &Profiler{
@@ -64,6 +72,29 @@ func New(config Config, logger *logging.Logger) (*Profiler, error) {
 	return p, nil
 }
 
+// Start creates a profiler from the profiling configuration and starts it
+func Start(cfg config.ProfilingConfig, logger *logging.Logger) (*Profiler, error) {
+	p, err := New(Config{
+		Enabled:            cfg.Enabled,
+		Address:            cfg.Address,
+		CPUProfilePath:     cfg.CPUProfilePath,
+		MemProfilePath:     cfg.MemProfilePath,
+		BlockProfile:       cfg.BlockProfile,
+		MutexProfile:       cfg.MutexProfile,
+		GoroutineThreshold: cfg.GoroutineThreshold,
+		CheckInterval:      cfg.GoroutineCheckInterval,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.Start(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
 // Start begins profiling
 func (p *Profiler) Start() error {
 	if !p.config.Enabled {
@@ -108,14 +139,22 @@ func (p *Profiler) Start() error {
 		mux.HandleFunc("/debug/stats", p.statsHandler)
 		mux.HandleFunc("/debug/gc", p.gcHandler)
 
+		// Listen before returning so address errors are reported to the caller
+		listener, err := net.Listen("tcp", p.config.Address)
+		if err != nil {
+			p.stopCPUProfile()
+			return fmt.Errorf("failed to listen on %s: %w", p.config.Address, err)
+		}
+		p.listener = listener
+
 		p.server = &http.Server{
 			Addr:    p.config.Address,
 			Handler: mux,
 		}
 
 		go func() {
-			p.logger.Info().Str("address", p.config.Address).Msg("Starting profiling HTTP server")
-			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			p.logger.Info().Str("address", listener.Addr().String()).Msg("Starting profiling HTTP server")
+			if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 				p.logger.Error().Err(err).Msg("Profiling server error")
 			}
 		}()
@@ -142,11 +181,18 @@ func (p *Profiler) Stop() error {
 
 	// Stop CPU profiling
 	if p.cpuFile != nil {
-		runtimepprof.StopCPUProfile()
-		p.cpuFile.Close()
+		p.stopCPUProfile()
 		p.logger.Info().Str("path", p.config.CPUProfilePath).Msg("CPU profile saved")
 	}
 
+	// Restore the default profiling rates
+	if p.config.BlockProfile {
+		runtime.SetBlockProfileRate(0)
+	}
+	if p.config.MutexProfile {
+		runtime.SetMutexProfileFraction(0)
+	}
+
 	// Write memory profile if configured
 	if p.config.MemProfilePath != "" {
 		if err := p.writeMemProfile(); err != nil {
@@ -184,6 +230,28 @@ func (p *Profiler) startCPUProfile() error {
 	return nil
 }
 
+// stopCPUProfile stops CPU profiling, if running, and closes the profile file
+func (p *Profiler) stopCPUProfile() {
+	if p.cpuFile == nil {
+		return
+	}
+	runtimepprof.StopCPUProfile()
+	p.cpuFile.Close()
+	p.cpuFile = nil
+}
+
+// Addr returns the address the profiling server is listening on, or an empty
+// string if it is not running
+func (p *Profiler) Addr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener == nil {
+		return ""
+	}
+	return p.listener.Addr().String()
+}
+
 // writeMemProfile writes memory profile to file
 func (p *Profiler) writeMemProfile() error {
 	f, err := os.Create(p.config.MemProfilePath)
@@ -204,7 +272,7 @@ func (p *Profiler) writeMemProfile() error {
 
 // monitorGoroutines monitors goroutine count
 func (p *Profiler) monitorGoroutines() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(p.config.CheckInterval)
 	defer ticker.Stop()
 
 	for {
@@ -1,11 +1,18 @@
 package profiling
 
 import (
+	"bytes"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
 )
 
@@ -138,11 +145,8 @@ func TestBlockAndMutexProfiling(t *testing.T) {
 		t.Fatalf("Failed to start profiler: %v", err)
 	}
 
-	// Verify block profiling is enabled
-	if runtime.SetBlockProfileRate(0) != 1 {
-		t.Error("Block profiling not enabled")
-	}
-	runtime.SetBlockProfileRate(1) // Reset
+	// Block profile rate has no getter; reset it after the profiler enables it
+	defer runtime.SetBlockProfileRate(0)
 
 	// Verify mutex profiling is enabled
 	if runtime.SetMutexProfileFraction(0) != 1 {
@@ -244,3 +248,102 @@ func TestGCHandler(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
 }
+
+func TestStartFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.ProfilingConfig{
+		Enabled:        true,
+		Address:        "127.0.0.1:0",
+		CPUProfilePath: filepath.Join(dir, "cpu.pprof"),
+		MemProfilePath: filepath.Join(dir, "mem.pprof"),
+	}
+
+	p, err := Start(cfg, logging.New(logging.Config{Level: "error"}))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/goroutine"} {
+		resp, err := http.Get("http://" + p.Addr() + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected status 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	for _, path := range []string{cfg.CPUProfilePath, cfg.MemProfilePath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("expected profile %s to be written: %v", path, err)
+		} else if info.Size() == 0 {
+			t.Errorf("expected profile %s to be non-empty", path)
+		}
+	}
+}
+
+func TestStartAddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	_, err = Start(config.ProfilingConfig{
+		Enabled: true,
+		Address: listener.Addr().String(),
+	}, logging.New(logging.Config{Level: "error"}))
+	if err == nil {
+		t.Fatal("expected error when the address is already in use")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes by the logger
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestGoroutineWatchdog(t *testing.T) {
+	var out syncBuffer
+	p, err := New(Config{
+		Enabled:            true,
+		Address:            "127.0.0.1:0",
+		GoroutineThreshold: 1,
+		CheckInterval:      10 * time.Millisecond,
+	}, logging.New(logging.Config{Level: "warn", Format: "json", Output: &out}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer p.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "High goroutine count detected") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected goroutine warning, got logs: %s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 var (
@@ -35,14 +38,27 @@ func (s State) String() string {
 	}
 }
 
+// MarshalText encodes the state as its name
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // CircuitBreakerConfig holds configuration for the circuit breaker
 type CircuitBreakerConfig struct {
+	Name              string
 	MaxRequests       uint32
+	HalfOpenMaxProbes uint32 // Trial requests allowed while half-open; defaults to MaxRequests
 	Interval          time.Duration
 	Timeout           time.Duration
 	ReadyToTrip       func(counts Counts) bool
 	OnStateChange     func(from State, to State)
+	// OnStateChangeDetailed receives the breaker name and the counts
+	// accumulated in the generation that ended with the transition
+	OnStateChangeDetailed func(name string, from State, to State, counts Counts)
 	IsSuccessful      func(err error) bool
+	// Clock is the time source of the interval and timeout; defaults to
+	// the wall clock
+	Clock clock.Clock
 }
 
 // Counts holds the circuit breaker statistics
@@ -67,10 +83,18 @@ type CircuitBreaker struct {
 
 // NewCircuitBreaker creates a new circuit breaker
 func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.Name == "" {
+		config.Name = "default"
+	}
+
 	if config.MaxRequests == 0 {
 		config.MaxRequests = 1
 	}
 
+	if config.HalfOpenMaxProbes == 0 {
+		config.HalfOpenMaxProbes = config.MaxRequests
+	}
+
 	if config.Interval == 0 {
 		config.Interval = 60 * time.Second
 	}
@@ -87,10 +111,14 @@ func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
 		config.IsSuccessful = defaultIsSuccessful
 	}
 
+	if config.Clock == nil {
+		config.Clock = clock.Real
+	}
+
 	cb := &CircuitBreaker{
 		config: config,
 		state:  StateClosed,
-		expiry: time.Now().Add(config.Interval),
+		expiry: config.Clock.Now().Add(config.Interval),
 	}
 
 	return cb
@@ -126,7 +154,7 @@ func (cb *CircuitBreaker) State() State {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 
-	now := time.Now()
+	now := cb.config.Clock.Now()
 	state, _ := cb.currentState(now)
 	return state
 }
@@ -144,12 +172,12 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	now := time.Now()
+	now := cb.config.Clock.Now()
 	state, generation := cb.currentState(now)
 
 	if state == StateOpen {
 		return generation, ErrCircuitOpen
-	} else if state == StateHalfOpen && cb.counts.Requests >= cb.config.MaxRequests {
+	} else if state == StateHalfOpen && cb.counts.Requests >= cb.config.HalfOpenMaxProbes {
 		return generation, ErrTooManyRequests
 	}
 
@@ -162,7 +190,7 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	now := time.Now()
+	now := cb.config.Clock.Now()
 	state, generation := cb.currentState(now)
 
 	if generation != before {
@@ -188,7 +216,7 @@ func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 		cb.counts.ConsecutiveSuccesses++
 		cb.counts.ConsecutiveFailures = 0
 
-		if cb.counts.ConsecutiveSuccesses >= cb.config.MaxRequests {
+		if cb.counts.ConsecutiveSuccesses >= cb.config.HalfOpenMaxProbes {
 			cb.setState(StateClosed, now)
 		}
 	}
@@ -232,13 +260,22 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 	}
 
 	prev := cb.state
+	counts := cb.counts
 	cb.state = state
 
 	cb.toNewGeneration(now)
 
+	collector := metrics.GetGlobalCollector()
+	collector.CircuitBreakerState.WithLabelValues(cb.config.Name).Set(float64(state))
+	collector.CircuitBreakerConsecutive.WithLabelValues(cb.config.Name).Set(float64(counts.ConsecutiveFailures))
+
 	if cb.config.OnStateChange != nil {
 		cb.config.OnStateChange(prev, state)
 	}
+
+	if cb.config.OnStateChangeDetailed != nil {
+		cb.config.OnStateChangeDetailed(cb.config.Name, prev, state, counts)
+	}
 }
 
 // toNewGeneration starts a new generation
@@ -266,7 +303,7 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	cb.toNewGeneration(time.Now())
+	cb.toNewGeneration(cb.config.Clock.Now())
 	cb.state = StateClosed
 }
 
@@ -282,13 +319,13 @@ func defaultIsSuccessful(err error) bool {
 
 // Metrics returns circuit breaker statistics
 type Metrics struct {
-	State                State
-	Requests             uint32
-	TotalSuccesses       uint32
-	TotalFailures        uint32
-	ConsecutiveSuccesses uint32
-	ConsecutiveFailures  uint32
-	ErrorRate            float64
+	State                State   `json:"state"`
+	Requests             uint32  `json:"requests"`
+	TotalSuccesses       uint32  `json:"total_successes"`
+	TotalFailures        uint32  `json:"total_failures"`
+	ConsecutiveSuccesses uint32  `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32  `json:"consecutive_failures"`
+	ErrorRate            float64 `json:"error_rate"`
 }
 
 // Metrics returns current metrics
@@ -384,11 +421,21 @@ func (mcb *MultiCircuitBreaker) GetOrCreate(key string, config CircuitBreakerCon
 		return cb
 	}
 
+	config.Name = key
 	cb = NewCircuitBreaker(config)
 	mcb.breakers[key] = cb
 	return cb
 }
 
+// Add registers an existing circuit breaker under the given key, replacing
+// any breaker already registered there
+func (mcb *MultiCircuitBreaker) Add(key string, cb *CircuitBreaker) {
+	mcb.mu.Lock()
+	defer mcb.mu.Unlock()
+
+	mcb.breakers[key] = cb
+}
+
 // Execute executes a function with the circuit breaker for the given key
 func (mcb *MultiCircuitBreaker) Execute(ctx context.Context, key string, config CircuitBreakerConfig, fn func() error) error {
 	cb := mcb.GetOrCreate(key, config)
@@ -441,18 +488,19 @@ type rateLimitedCircuitBreaker struct {
 
 // NewRateLimitedCircuitBreaker creates a circuit breaker with rate limiting
 func NewRateLimitedCircuitBreaker(config CircuitBreakerConfig, maxRate uint32, interval time.Duration) *rateLimitedCircuitBreaker {
+	cb := NewCircuitBreaker(config)
 	return &rateLimitedCircuitBreaker{
-		cb:        NewCircuitBreaker(config),
+		cb:        cb,
 		maxRate:   maxRate,
 		interval:  interval,
-		lastReset: time.Now(),
+		lastReset: cb.config.Clock.Now(),
 	}
 }
 
 // Execute executes with rate limiting and circuit breaker
 func (rlcb *rateLimitedCircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 	rlcb.mu.Lock()
-	now := time.Now()
+	now := rlcb.cb.config.Clock.Now()
 	if now.Sub(rlcb.lastReset) >= rlcb.interval {
 		rlcb.count = 0
 		rlcb.lastReset = now
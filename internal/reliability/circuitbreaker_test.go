@@ -5,6 +5,11 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 func TestCircuitBreaker_ClosedState(t *testing.T) {
@@ -69,6 +74,7 @@ func TestCircuitBreaker_OpenState(t *testing.T) {
 }
 
 func TestCircuitBreaker_HalfOpenState(t *testing.T) {
+	fake := clock.NewFake(time.Now())
 	cb := NewCircuitBreaker(CircuitBreakerConfig{
 		MaxRequests: 2,
 		Interval:    time.Second,
@@ -76,6 +82,7 @@ func TestCircuitBreaker_HalfOpenState(t *testing.T) {
 		ReadyToTrip: func(counts Counts) bool {
 			return counts.ConsecutiveFailures >= 2
 		},
+		Clock: fake,
 	})
 
 	// Open the circuit
@@ -89,21 +96,29 @@ func TestCircuitBreaker_HalfOpenState(t *testing.T) {
 		t.Fatalf("state = %v, want %v", cb.State(), StateOpen)
 	}
 
-	// Wait for timeout
-	time.Sleep(150 * time.Millisecond)
+	// Stays open until the timeout has passed
+	fake.Advance(100 * time.Millisecond)
+	if cb.State() != StateOpen {
+		t.Fatalf("state = %v, want %v before the timeout", cb.State(), StateOpen)
+	}
 
-	// Should transition to half-open
-	// Execute to trigger state check
+	fake.Advance(time.Millisecond)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("state = %v, want %v", cb.State(), StateHalfOpen)
+	}
+
+	// One success is not enough to close with MaxRequests 2
 	_ = cb.Execute(context.Background(), func() error {
 		return nil
 	})
 
-	if cb.State() != StateHalfOpen && cb.State() != StateClosed {
-		t.Errorf("state = %v, want half-open or closed", cb.State())
+	if cb.State() != StateHalfOpen {
+		t.Errorf("state = %v, want %v", cb.State(), StateHalfOpen)
 	}
 }
 
 func TestCircuitBreaker_HalfOpenToClosed(t *testing.T) {
+	fake := clock.NewFake(time.Now())
 	cb := NewCircuitBreaker(CircuitBreakerConfig{
 		MaxRequests: 2,
 		Interval:    time.Second,
@@ -111,6 +126,7 @@ func TestCircuitBreaker_HalfOpenToClosed(t *testing.T) {
 		ReadyToTrip: func(counts Counts) bool {
 			return counts.ConsecutiveFailures >= 2
 		},
+		Clock: fake,
 	})
 
 	// Open the circuit
@@ -121,7 +137,7 @@ func TestCircuitBreaker_HalfOpenToClosed(t *testing.T) {
 	}
 
 	// Wait for timeout to enter half-open
-	time.Sleep(100 * time.Millisecond)
+	fake.Advance(100 * time.Millisecond)
 
 	// Succeed enough times to close
 	for i := 0; i < 2; i++ {
@@ -139,7 +155,61 @@ func TestCircuitBreaker_HalfOpenToClosed(t *testing.T) {
 	}
 }
 
+func TestCircuitBreaker_HalfOpenMaxProbes(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	tscb := NewTwoStepCircuitBreaker(CircuitBreakerConfig{
+		MaxRequests:       5,
+		HalfOpenMaxProbes: 2,
+		Interval:          time.Second,
+		Timeout:           50 * time.Millisecond,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 2
+		},
+		Clock: fake,
+	})
+
+	// Open the circuit
+	for i := 0; i < 2; i++ {
+		done, err := tscb.Allow()
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		done(false)
+	}
+
+	// Wait for timeout to enter half-open
+	fake.Advance(100 * time.Millisecond)
+
+	// Hold the probes in flight so they count against the limit
+	var probes []func(success bool)
+	for i := 0; i < 2; i++ {
+		done, err := tscb.Allow()
+		if err != nil {
+			t.Fatalf("probe %d: Allow() error = %v", i, err)
+		}
+		probes = append(probes, done)
+	}
+
+	// Further half-open requests exceed the probe limit even though
+	// MaxRequests would allow them
+	for i := 0; i < 3; i++ {
+		if _, err := tscb.Allow(); err != ErrTooManyRequests {
+			t.Errorf("expected ErrTooManyRequests, got %v", err)
+		}
+	}
+
+	for _, done := range probes {
+		done(true)
+	}
+
+	// Successful probes close the circuit
+	if tscb.State() != StateClosed {
+		t.Errorf("state = %v, want %v", tscb.State(), StateClosed)
+	}
+}
+
 func TestCircuitBreaker_HalfOpenToOpen(t *testing.T) {
+	fake := clock.NewFake(time.Now())
 	cb := NewCircuitBreaker(CircuitBreakerConfig{
 		MaxRequests: 3,
 		Interval:    time.Second,
@@ -147,6 +217,7 @@ func TestCircuitBreaker_HalfOpenToOpen(t *testing.T) {
 		ReadyToTrip: func(counts Counts) bool {
 			return counts.ConsecutiveFailures >= 2
 		},
+		Clock: fake,
 	})
 
 	// Open the circuit
@@ -157,7 +228,7 @@ func TestCircuitBreaker_HalfOpenToOpen(t *testing.T) {
 	}
 
 	// Wait for timeout
-	time.Sleep(100 * time.Millisecond)
+	fake.Advance(100 * time.Millisecond)
 
 	// Fail again to reopen
 	_ = cb.Execute(context.Background(), func() error {
@@ -270,6 +341,74 @@ func TestCircuitBreaker_OnStateChange(t *testing.T) {
 	}
 }
 
+func TestCircuitBreaker_OnStateChangeDetailed(t *testing.T) {
+	type transition struct {
+		name     string
+		from, to State
+		counts   Counts
+	}
+	var transitions []transition
+
+	mcb := NewMultiCircuitBreaker()
+	config := CircuitBreakerConfig{
+		Interval: time.Second,
+		Timeout:  50 * time.Millisecond,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 3
+		},
+		OnStateChangeDetailed: func(name string, from State, to State, counts Counts) {
+			transitions = append(transitions, transition{name, from, to, counts})
+		},
+	}
+
+	// One success followed by three failures trips the breaker
+	_ = mcb.Execute(context.Background(), "elasticsearch", config, func() error {
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		_ = mcb.Execute(context.Background(), "elasticsearch", config, func() error {
+			return errors.New("error")
+		})
+	}
+
+	if len(transitions) != 1 {
+		t.Fatalf("got %d transitions, want 1", len(transitions))
+	}
+
+	tr := transitions[0]
+	if tr.name != "elasticsearch" {
+		t.Errorf("name = %q, want %q", tr.name, "elasticsearch")
+	}
+	if tr.from != StateClosed || tr.to != StateOpen {
+		t.Errorf("transition = %v -> %v, want %v -> %v", tr.from, tr.to, StateClosed, StateOpen)
+	}
+	if tr.counts.Requests != 4 {
+		t.Errorf("counts.Requests = %d, want 4", tr.counts.Requests)
+	}
+	if tr.counts.TotalSuccesses != 1 {
+		t.Errorf("counts.TotalSuccesses = %d, want 1", tr.counts.TotalSuccesses)
+	}
+	if tr.counts.TotalFailures != 3 {
+		t.Errorf("counts.TotalFailures = %d, want 3", tr.counts.TotalFailures)
+	}
+	if tr.counts.ConsecutiveFailures != 3 {
+		t.Errorf("counts.ConsecutiveFailures = %d, want 3", tr.counts.ConsecutiveFailures)
+	}
+
+	// The breaker's counts are reset for the new generation
+	if counts := mcb.GetOrCreate("elasticsearch", config).Counts(); counts.Requests != 0 {
+		t.Errorf("counts after transition = %+v, want zero", counts)
+	}
+
+	collector := metrics.GetGlobalCollector()
+	if got := testutil.ToFloat64(collector.CircuitBreakerState.WithLabelValues("elasticsearch")); got != float64(StateOpen) {
+		t.Errorf("state gauge = %v, want %v", got, float64(StateOpen))
+	}
+	if got := testutil.ToFloat64(collector.CircuitBreakerConsecutive.WithLabelValues("elasticsearch")); got != 3 {
+		t.Errorf("consecutive_failures gauge = %v, want 3", got)
+	}
+}
+
 func TestTwoStepCircuitBreaker_Allow(t *testing.T) {
 	tscb := NewTwoStepCircuitBreaker(CircuitBreakerConfig{
 		MaxRequests: 3,
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"time"
 )
 
@@ -122,12 +123,130 @@ func RetryWithBackoff(ctx context.Context, maxRetries int, backoffFunc func(atte
 
 // isRetryable determines if an error should trigger a retry
 func isRetryable(err error) bool {
-	// Add logic to determine if error is retryable
-	// For now, retry all errors except context errors
+	// Retry all errors except context errors and permanent failures
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return false
 	}
-	return true
+	return !IsPermanent(err)
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error so that retry helpers return it immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// Retrier retries operations using full-jitter exponential backoff
+type Retrier struct {
+	config RetryConfig
+
+	// sleep and random are replaceable for deterministic tests
+	sleep  func(ctx context.Context, d time.Duration) error
+	random func() float64
+}
+
+// NewRetrier creates a new Retrier, applying the same defaults as Retry
+func NewRetrier(config RetryConfig) *Retrier {
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3 // Default
+	}
+
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = 100 * time.Millisecond
+	}
+
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+
+	if config.Multiplier == 0 {
+		config.Multiplier = 2.0
+	}
+
+	return &Retrier{
+		config: config,
+		sleep:  sleepContext,
+		random: rand.Float64,
+	}
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the retry
+// budget is exhausted, or ctx is done
+func (r *Retrier) Do(ctx context.Context, fn func() error) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+
+		if !isRetryable(err) {
+			return err
+		}
+
+		if attempt == r.config.MaxRetries {
+			break
+		}
+
+		if err := r.sleep(ctx, r.Backoff(attempt)); err != nil {
+			return fmt.Errorf("%w: %v", ErrRetryAborted, err)
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrMaxRetriesExceeded, lastErr)
+}
+
+// Backoff returns the delay before the retry following the given attempt
+// (0-based). With jitter enabled the delay is drawn uniformly from
+// [0, cap), where cap grows exponentially up to MaxBackoff.
+func (r *Retrier) Backoff(attempt int) time.Duration {
+	ceiling := ExponentialBackoff(attempt, r.config.InitialBackoff, r.config.Multiplier, r.config.MaxBackoff)
+	if !r.config.Jitter {
+		return ceiling
+	}
+	return time.Duration(r.random() * float64(ceiling))
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // addJitter adds randomness to backoff duration
@@ -204,3 +204,128 @@ func BenchmarkRetry(b *testing.B) {
 		_ = Retry(context.Background(), config, fn)
 	}
 }
+
+func TestRetrier_BackoffSchedule(t *testing.T) {
+	r := NewRetrier(RetryConfig{
+		MaxRetries:     5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     1 * time.Second,
+		Multiplier:     2.0,
+	})
+
+	var delays []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	attempts := 0
+	err := r.Do(context.Background(), func() error {
+		attempts++
+		return errors.New("temporary error")
+	})
+
+	if !errors.Is(err, ErrMaxRetriesExceeded) {
+		t.Errorf("expected ErrMaxRetriesExceeded, got %v", err)
+	}
+
+	if attempts != 6 {
+		t.Errorf("attempts = %d, want 6", attempts)
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1 * time.Second, // Capped at MaxBackoff
+	}
+
+	if len(delays) != len(expected) {
+		t.Fatalf("got %d delays, want %d", len(delays), len(expected))
+	}
+
+	for i, want := range expected {
+		if delays[i] != want {
+			t.Errorf("delay[%d] = %v, want %v", i, delays[i], want)
+		}
+	}
+}
+
+func TestRetrier_FullJitter(t *testing.T) {
+	r := NewRetrier(RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     1 * time.Second,
+		Multiplier:     2.0,
+		Jitter:         true,
+	})
+	r.random = func() float64 { return 0.5 }
+
+	expected := []time.Duration{
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+	}
+
+	for attempt, want := range expected {
+		if got := r.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetrier_PermanentError(t *testing.T) {
+	r := NewRetrier(RetryConfig{MaxRetries: 5})
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Error("permanent errors must not trigger a backoff")
+		return nil
+	}
+
+	baseErr := errors.New("bad request")
+	attempts := 0
+	err := r.Do(context.Background(), func() error {
+		attempts++
+		return Permanent(baseErr)
+	})
+
+	if err != baseErr {
+		t.Errorf("Do() error = %v, want %v", err, baseErr)
+	}
+
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+
+	if !IsPermanent(Permanent(baseErr)) {
+		t.Error("IsPermanent should detect wrapped errors")
+	}
+
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) should be nil")
+	}
+}
+
+func TestRetrier_ContextCanceled(t *testing.T) {
+	r := NewRetrier(RetryConfig{
+		MaxRetries:     5,
+		InitialBackoff: 1 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := r.Do(ctx, func() error {
+		attempts++
+		return errors.New("error")
+	})
+
+	if !errors.Is(err, ErrRetryAborted) {
+		t.Errorf("expected ErrRetryAborted, got %v", err)
+	}
+
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/therealutkarshpriyadarshi/log/internal/health"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 // Server provides HTTP endpoints for metrics and health checks
@@ -29,6 +30,9 @@ type Config struct {
 	MetricsRegistry   *prometheus.Registry
 	HealthChecker     *health.Checker
 	Logger            *logging.Logger
+
+	// DebugHandler, when set, serves /debug/state on the health address
+	DebugHandler http.Handler
 }
 
 // New creates a new server
@@ -51,6 +55,7 @@ func New(cfg Config) *Server {
 				EnableOpenMetrics: true,
 			},
 		))
+		mux.Handle(metricsPath+metrics.JSONPathSuffix, metrics.JSONHandler(cfg.MetricsRegistry))
 
 		s.metricsServer = &http.Server{
 			Addr:         cfg.MetricsAddress,
@@ -76,6 +81,9 @@ func New(cfg Config) *Server {
 		mux.HandleFunc(livenessPath, cfg.HealthChecker.LivenessHandler())
 		mux.HandleFunc(readinessPath, cfg.HealthChecker.ReadinessHandler())
 		mux.HandleFunc("/health", cfg.HealthChecker.HTTPHandler())
+		if cfg.DebugHandler != nil {
+			mux.Handle("/debug/state", cfg.DebugHandler)
+		}
 
 		s.healthServer = &http.Server{
 			Addr:         cfg.HealthAddress,
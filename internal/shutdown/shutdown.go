@@ -16,6 +16,8 @@ import (
 type Manager struct {
 	logger         *logging.Logger
 	timeout        time.Duration
+	stageTimeout   time.Duration
+	stages         []stage
 	shutdownFuncs  []ShutdownFunc
 	mu             sync.Mutex
 	shutdownCh     chan struct{}
@@ -26,10 +28,23 @@ type Manager struct {
 // ShutdownFunc is a function that performs cleanup during shutdown
 type ShutdownFunc func(context.Context) error
 
+// StageFunc performs one step of an ordered shutdown and returns the number
+// of events it flushed
+type StageFunc func(context.Context) (int, error)
+
+// stage is a named step of the ordered shutdown
+type stage struct {
+	name string
+	fn   StageFunc
+}
+
 // Config holds shutdown manager configuration
 type Config struct {
 	Timeout time.Duration
 	Logger  *logging.Logger
+
+	// StageTimeout bounds each ordered stage; defaults to Timeout
+	StageTimeout time.Duration
 }
 
 // New creates a new shutdown manager
@@ -37,10 +52,14 @@ func New(cfg Config) *Manager {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30 * time.Second
 	}
+	if cfg.StageTimeout == 0 {
+		cfg.StageTimeout = cfg.Timeout
+	}
 
 	return &Manager{
 		logger:       cfg.Logger,
 		timeout:      cfg.Timeout,
+		stageTimeout: cfg.StageTimeout,
 		shutdownCh:   make(chan struct{}),
 		gracefulDone: make(chan struct{}),
 	}
@@ -55,6 +74,17 @@ func (m *Manager) RegisterFunc(name string, fn ShutdownFunc) {
 	m.shutdownFuncs = append(m.shutdownFuncs, fn)
 }
 
+// RegisterStage registers an ordered shutdown stage. Stages run one after
+// another in registration order, before the shutdown functions, so each can
+// rely on the previous stages having completed.
+func (m *Manager) RegisterStage(name string, fn StageFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logger.Info().Str("stage", name).Msg("Registered shutdown stage")
+	m.stages = append(m.stages, stage{name: name, fn: fn})
+}
+
 // WaitForSignal blocks until a shutdown signal is received
 func (m *Manager) WaitForSignal(signals ...os.Signal) {
 	if len(signals) == 0 {
@@ -87,12 +117,15 @@ func (m *Manager) Shutdown() {
 func (m *Manager) performShutdown() {
 	m.logger.Info().
 		Dur("timeout", m.timeout).
+		Int("stages", len(m.stages)).
 		Int("functions", len(m.shutdownFuncs)).
 		Msg("Starting graceful shutdown")
 
 	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
 	defer cancel()
 
+	m.runStages(ctx)
+
 	var wg sync.WaitGroup
 	errors := make(chan error, len(m.shutdownFuncs))
 
@@ -153,6 +186,53 @@ func (m *Manager) performShutdown() {
 	close(m.gracefulDone)
 }
 
+// runStages executes the ordered stages, each bounded by the stage timeout.
+// A stage that fails or times out is logged and the next stage still runs.
+func (m *Manager) runStages(ctx context.Context) {
+	for _, st := range m.stages {
+		if ctx.Err() != nil {
+			m.logger.Warn().Str("stage", st.name).Msg("Shutdown timed out, skipping stage")
+			continue
+		}
+
+		start := time.Now()
+		stageCtx, cancel := context.WithTimeout(ctx, m.stageTimeout)
+
+		type result struct {
+			flushed int
+			err     error
+		}
+		resultCh := make(chan result, 1)
+		go func(fn StageFunc) {
+			flushed, err := fn(stageCtx)
+			resultCh <- result{flushed: flushed, err: err}
+		}(st.fn)
+
+		select {
+		case res := <-resultCh:
+			if res.err != nil {
+				m.logger.Error().
+					Err(res.err).
+					Str("stage", st.name).
+					Int("events_flushed", res.flushed).
+					Msg("Shutdown stage failed")
+			} else {
+				m.logger.Info().
+					Str("stage", st.name).
+					Int("events_flushed", res.flushed).
+					Dur("duration", time.Since(start)).
+					Msg("Shutdown stage completed")
+			}
+		case <-stageCtx.Done():
+			m.logger.Warn().
+				Str("stage", st.name).
+				Dur("timeout", m.stageTimeout).
+				Msg("Shutdown stage timed out")
+		}
+		cancel()
+	}
+}
+
 // Done returns a channel that is closed when shutdown is complete
 func (m *Manager) Done() <-chan struct{} {
 	return m.gracefulDone
@@ -232,3 +232,72 @@ func TestRegisterComponent(t *testing.T) {
 		t.Errorf("Expected 1 shutdown function, got %d", len(manager.shutdownFuncs))
 	}
 }
+
+func TestShutdown_StagesRunInOrder(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "info", Format: "json"})
+	manager := New(Config{
+		Logger:  logger,
+		Timeout: 5 * time.Second,
+	})
+
+	var callOrder []string
+	for _, name := range []string{"inputs", "buffer", "outputs"} {
+		stageName := name
+		manager.RegisterStage(stageName, func(ctx context.Context) (int, error) {
+			time.Sleep(10 * time.Millisecond)
+			callOrder = append(callOrder, stageName)
+			return 1, nil
+		})
+	}
+	manager.RegisterFunc("cleanup", func(ctx context.Context) error {
+		callOrder = append(callOrder, "cleanup")
+		return nil
+	})
+
+	manager.Shutdown()
+	<-manager.Done()
+
+	expected := []string{"inputs", "buffer", "outputs", "cleanup"}
+	if len(callOrder) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, callOrder)
+	}
+	for i, name := range expected {
+		if callOrder[i] != name {
+			t.Errorf("Expected %v, got %v", expected, callOrder)
+			break
+		}
+	}
+}
+
+func TestShutdown_StageTimeout(t *testing.T) {
+	logger := logging.New(logging.Config{Level: "info", Format: "json"})
+	manager := New(Config{
+		Logger:       logger,
+		Timeout:      5 * time.Second,
+		StageTimeout: 50 * time.Millisecond,
+	})
+
+	manager.RegisterStage("stuck", func(ctx context.Context) (int, error) {
+		time.Sleep(time.Second)
+		return 0, nil
+	})
+
+	next := make(chan struct{})
+	manager.RegisterStage("next", func(ctx context.Context) (int, error) {
+		close(next)
+		return 0, nil
+	})
+
+	start := time.Now()
+	manager.Shutdown()
+	<-manager.Done()
+
+	select {
+	case <-next:
+	default:
+		t.Error("Expected the stage after a timed out stage to run")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown took too long: %v", elapsed)
+	}
+}
@@ -0,0 +1,110 @@
+package tailer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// SetExcludePatterns skips files matching any of the glob patterns. A
+// pattern is matched against both the full path and the file name.
+func (t *Tailer) SetExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	t.excludes = patterns
+	return nil
+}
+
+// expandPaths resolves the configured paths and glob patterns to files.
+// Explicit paths are kept even if they do not exist yet.
+func (t *Tailer) expandPaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+
+	for _, pattern := range t.paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.logger.Warn().Err(err).Str("pattern", pattern).Msg("Invalid path pattern")
+			continue
+		}
+		if len(matches) == 0 && !hasMeta(pattern) {
+			matches = []string{pattern}
+		}
+
+		for _, path := range matches {
+			if seen[path] || t.excluded(path) {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// watchDirs returns the directories holding the configured paths
+func (t *Tailer) watchDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+
+	for _, pattern := range t.paths {
+		matches, err := filepath.Glob(filepath.Dir(pattern))
+		if err != nil {
+			continue
+		}
+
+		for _, dir := range matches {
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	sort.Strings(dirs)
+	return dirs
+}
+
+// matches reports whether path matches a configured path and is not excluded
+func (t *Tailer) matches(path string) bool {
+	if t.excluded(path) {
+		return false
+	}
+
+	for _, pattern := range t.paths {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether path matches an exclude pattern
+func (t *Tailer) excluded(path string) bool {
+	for _, pattern := range t.excludes {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMeta reports whether path contains glob metacharacters
+func hasMeta(path string) bool {
+	for _, c := range path {
+		switch c {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package tailer
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -12,30 +13,45 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/therealutkarshpriyadarshi/log/internal/checkpoint"
+	"github.com/therealutkarshpriyadarshi/log/internal/input"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
-// Tailer tails log files and handles rotation
+// Tailer tails log files and handles rotation. Paths may be glob patterns;
+// their directories are watched so matching files created later are tailed
+// and deleted files are dropped.
 type Tailer struct {
 	paths          []string
+	excludes       []string
 	checkpointMgr  *checkpoint.Manager
 	logger         *logging.Logger
+	cutLogger      *logging.Logger // Rate limited, for lines over the maximum
 	watcher        *fsnotify.Watcher
 	files          map[string]*tailedFile
 	mu             sync.RWMutex
 	eventCh        chan *types.LogEvent
+	readSem        chan struct{} // Limits concurrent file reads when set
+	limit          input.SizeLimit
+	maxLineBytes   int // Lines are cut beyond this size when set
+	collector      *metrics.Collector
 	ctx            context.Context
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
 }
 
 type tailedFile struct {
-	path   string
-	file   *os.File
-	reader *bufio.Reader
-	offset int64
-	inode  uint64
+	path    string
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	inode   uint64
+	partial string // Bytes kept of an unterminated last line
+	pending int64  // Bytes read of the unterminated line, kept or not
+	cut     bool   // The unterminated line was cut
+	resetCh chan struct{}
 }
 
 // New creates a new Tailer instance
@@ -51,26 +67,73 @@ func New(paths []string, checkpointMgr *checkpoint.Manager, logger *logging.Logg
 		paths:         paths,
 		checkpointMgr: checkpointMgr,
 		logger:        logger.WithComponent("tailer"),
+		cutLogger:     logger.WithComponent("tailer").RateLimited(),
 		watcher:       watcher,
 		files:         make(map[string]*tailedFile),
 		eventCh:       make(chan *types.LogEvent, 1000),
+		collector:     metrics.GetGlobalCollector(),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
 
+	checkpointMgr.OnReset(t.resetFile)
+
 	return t, nil
 }
 
+// SetSizeLimit sets the limit lines are truncated or dropped over. Lines
+// are cut to it as they are read, unless SetMaxLineBytes sets another size.
+// It must be called before Start.
+func (t *Tailer) SetSizeLimit(limit input.SizeLimit) {
+	t.limit = limit
+	if t.maxLineBytes == 0 {
+		t.maxLineBytes = limit.MaxBytes
+	}
+}
+
+// SetMaxLineBytes sets the size lines are cut to as they are read, so an
+// enormous line is never held in memory whole. Cut lines are truncated or
+// dropped per the size limit's policy. It must be called before Start; zero
+// keeps the size limit, if any.
+func (t *Tailer) SetMaxLineBytes(n int) {
+	if n > 0 {
+		t.maxLineBytes = n
+	}
+}
+
+// SetBufferSize sets the capacity of the events channel. It must be called
+// before Start and Events; a size of zero keeps the default.
+func (t *Tailer) SetBufferSize(size int) {
+	if size > 0 {
+		t.eventCh = make(chan *types.LogEvent, size)
+	}
+}
+
+// SetMaxConcurrentReads limits how many files are read at the same time. It
+// must be called before Start; zero leaves reads unlimited.
+func (t *Tailer) SetMaxConcurrentReads(n int) {
+	if n > 0 {
+		t.readSem = make(chan struct{}, n)
+	}
+}
+
 // Start starts tailing files
 func (t *Tailer) Start() error {
 	// Open all files
-	for _, path := range t.paths {
-		if err := t.openFile(path); err != nil {
+	for _, path := range t.expandPaths() {
+		if err := t.openFile(path, false); err != nil {
 			t.logger.Error().Err(err).Str("path", path).Msg("Failed to open file")
 			// Continue with other files
 		}
 	}
 
+	// Watch directories for files created later
+	for _, dir := range t.watchDirs() {
+		if err := t.watcher.Add(dir); err != nil {
+			t.logger.Warn().Err(err).Str("dir", dir).Msg("Failed to add directory to watcher")
+		}
+	}
+
 	// Start watching for file events
 	t.wg.Add(1)
 	go t.watchLoop()
@@ -102,8 +165,10 @@ func (t *Tailer) Events() <-chan *types.LogEvent {
 	return t.eventCh
 }
 
-// openFile opens a file and starts tailing from the last checkpoint
-func (t *Tailer) openFile(path string) error {
+// openFile opens a file and starts tailing from the last checkpoint. Without
+// a checkpoint it starts from the end, or from the beginning if fromStart is
+// set, as for files discovered after startup.
+func (t *Tailer) openFile(path string, fromStart bool) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
@@ -122,7 +187,15 @@ func (t *Tailer) openFile(path string) error {
 	var offset int64
 	if pos, ok := t.checkpointMgr.GetPosition(path); ok && pos.Inode == inode {
 		offset = pos.Offset
-		t.logger.Info().Str("path", path).Int64("offset", offset).Msg("Resuming from checkpoint")
+		if stat.Size() < offset {
+			// Truncated since the checkpoint was taken
+			offset = 0
+			t.logger.Info().Str("path", path).Msg("File truncated since checkpoint, starting from beginning")
+		} else {
+			t.logger.Info().Str("path", path).Int64("offset", offset).Msg("Resuming from checkpoint")
+		}
+	} else if fromStart {
+		t.logger.Info().Str("path", path).Msg("Starting from beginning of new file")
 	} else {
 		// Start from end of file for new files
 		offset, err = file.Seek(0, io.SeekEnd)
@@ -140,21 +213,26 @@ func (t *Tailer) openFile(path string) error {
 	}
 
 	tf := &tailedFile{
-		path:   path,
-		file:   file,
-		reader: bufio.NewReader(file),
-		offset: offset,
-		inode:  inode,
+		path:    path,
+		file:    file,
+		reader:  bufio.NewReader(file),
+		offset:  offset,
+		inode:   inode,
+		resetCh: make(chan struct{}, 1),
 	}
 
 	t.mu.Lock()
+	if _, ok := t.files[path]; ok {
+		// Already tailed, e.g. created while startup discovery ran
+		t.mu.Unlock()
+		file.Close()
+		return nil
+	}
 	t.files[path] = tf
 	t.mu.Unlock()
 
-	// Add to watcher
-	if err := t.watcher.Add(path); err != nil {
-		t.logger.Warn().Err(err).Str("path", path).Msg("Failed to add file to watcher")
-	}
+	// Record the starting position so every tailed file has a checkpoint
+	t.checkpointMgr.UpdatePosition(path, offset, inode)
 
 	// Start reading from this file
 	t.wg.Add(1)
@@ -163,23 +241,6 @@ func (t *Tailer) openFile(path string) error {
 	return nil
 }
 
-// reopenFile handles file rotation by reopening the file
-func (t *Tailer) reopenFile(path string) error {
-	t.mu.Lock()
-	tf, ok := t.files[path]
-	t.mu.Unlock()
-
-	if ok && tf.file != nil {
-		t.checkpointMgr.UpdatePosition(path, tf.offset, tf.inode)
-		tf.file.Close()
-	}
-
-	// Wait a bit for the new file to be created
-	time.Sleep(100 * time.Millisecond)
-
-	return t.openFile(path)
-}
-
 // readLoop reads lines from a file
 func (t *Tailer) readLoop(tf *tailedFile) {
 	defer t.wg.Done()
@@ -188,12 +249,38 @@ func (t *Tailer) readLoop(tf *tailedFile) {
 		select {
 		case <-t.ctx.Done():
 			return
+		case <-tf.resetCh:
+			if err := t.rewind(tf); err != nil {
+				t.logger.Error().Err(err).Str("path", tf.path).Msg("Failed to reset file position")
+				return
+			}
 		default:
 		}
 
-		line, err := tf.reader.ReadString('\n')
+		if !t.acquireRead() {
+			return
+		}
+		chunk, err := tf.reader.ReadSlice('\n')
+		t.releaseRead()
+
+		// Keep the line read so far until the rest arrives
+		tf.keep(chunk, t.maxLineBytes)
+		if err == bufio.ErrBufferFull {
+			continue // The line goes on past the read buffer
+		}
 		if err != nil {
 			if err == io.EOF {
+				// At the end of the file, detect rotation or truncation
+				deleted, err := t.checkRotation(tf)
+				if err != nil {
+					t.logger.Error().Err(err).Str("path", tf.path).Msg("Failed to follow rotated file")
+					return
+				}
+				if deleted {
+					t.dropFile(tf)
+					return
+				}
+
 				// Wait for more data
 				time.Sleep(100 * time.Millisecond)
 				continue
@@ -202,20 +289,12 @@ func (t *Tailer) readLoop(tf *tailedFile) {
 			return
 		}
 
-		// Update offset
-		tf.offset += int64(len(line))
+		line, size, cut := tf.takeLine()
 
-		// Create log event
-		event := &types.LogEvent{
-			Timestamp: time.Now(),
-			Message:   line,
-			Source:    tf.path,
-		}
+		// Update offset
+		tf.offset += size
 
-		// Send event
-		select {
-		case t.eventCh <- event:
-		case <-t.ctx.Done():
+		if !t.emit(tf.path, line, cut) {
 			return
 		}
 
@@ -226,6 +305,205 @@ func (t *Tailer) readLoop(tf *tailedFile) {
 	}
 }
 
+// acquireRead waits for a read slot, reporting false if the tailer stopped
+func (t *Tailer) acquireRead() bool {
+	if t.readSem == nil {
+		return true
+	}
+	select {
+	case t.readSem <- struct{}{}:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// releaseRead returns a slot taken by acquireRead
+func (t *Tailer) releaseRead() {
+	if t.readSem != nil {
+		<-t.readSem
+	}
+}
+
+// keep adds bytes read of the current line, keeping at most limit of them
+// besides the newline
+func (tf *tailedFile) keep(chunk []byte, limit int) {
+	tf.pending += int64(len(chunk))
+
+	line, newline := bytes.CutSuffix(chunk, []byte("\n"))
+	if room := limit - len(tf.partial); limit > 0 && len(line) > room {
+		line = line[:room]
+		tf.cut = true
+	}
+	tf.partial += string(line)
+	if newline {
+		tf.partial += "\n"
+	}
+}
+
+// takeLine returns the current line, its size in the file and whether it
+// was cut, and starts the next line
+func (tf *tailedFile) takeLine() (string, int64, bool) {
+	line, size, cut := tf.partial, tf.pending, tf.cut
+	tf.resetLine()
+	return line, size, cut
+}
+
+// resetLine discards the current line
+func (tf *tailedFile) resetLine() {
+	tf.partial = ""
+	tf.pending = 0
+	tf.cut = false
+}
+
+// emit sends a line read from path as a log event, truncated or dropped if
+// it is over the size limit
+func (t *Tailer) emit(path, line string, cut bool) bool {
+	event := &types.LogEvent{
+		Timestamp: time.Now(),
+		Message:   line,
+		Source:    path,
+	}
+	if cut {
+		t.cutLogger.Warn().Str("path", path).Int("max_bytes", t.maxLineBytes).Msg("Line over the maximum size cut")
+	}
+
+	kept, truncated := t.limit.Apply(event, cut)
+	if !kept {
+		t.collector.InputEventsDropped.WithLabelValues("file", "file", input.DropReasonOversize).Inc()
+		return true
+	}
+	if truncated {
+		t.collector.InputEventsTruncated.WithLabelValues("file", "file").Inc()
+	}
+
+	span := tracing.StartEvent(event, path, "file")
+	defer span.End()
+
+	select {
+	case t.eventCh <- event:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// checkRotation is called at the end of a tailed file. If the path now
+// refers to a different inode, the file was rotated and, since the old file
+// has been read to the end, the new file is opened from the beginning. If
+// the file shrank below the current offset, it was truncated and is read
+// again from the beginning. It reports true if the file was deleted and
+// should no longer be tailed.
+func (t *Tailer) checkRotation(tf *tailedFile) (bool, error) {
+	stat, err := os.Stat(tf.path)
+	if err != nil {
+		// A renamed file may be recreated at the path; only a file that no
+		// longer has any links is gone for good
+		if os.IsNotExist(err) {
+			if fi, err := tf.file.Stat(); err == nil && getNlink(fi) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if inode := getInode(stat); inode != tf.inode {
+		file, err := os.Open(tf.path)
+		if err != nil {
+			return false, fmt.Errorf("failed to open rotated file: %w", err)
+		}
+
+		// Flush an unterminated last line of the old file
+		if tf.pending > 0 {
+			line, _, cut := tf.takeLine()
+			if !t.emit(tf.path, line, cut) {
+				file.Close()
+				return false, nil
+			}
+		}
+
+		tf.file.Close()
+		tf.file = file
+		tf.reader.Reset(file)
+		tf.offset = 0
+		tf.inode = inode
+		t.checkpointMgr.UpdatePosition(tf.path, tf.offset, tf.inode)
+
+		t.logger.Info().Str("path", tf.path).Uint64("inode", inode).Msg("File rotated, following new file")
+		return false, nil
+	}
+
+	if stat.Size() < tf.offset+tf.pending {
+		if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("failed to seek truncated file: %w", err)
+		}
+
+		tf.reader.Reset(tf.file)
+		tf.offset = 0
+		tf.resetLine()
+		t.checkpointMgr.UpdatePosition(tf.path, tf.offset, tf.inode)
+
+		t.logger.Info().Str("path", tf.path).Msg("File truncated, reading from beginning")
+	}
+
+	return false, nil
+}
+
+// dropFile stops tailing a deleted file and forgets its checkpoint
+func (t *Tailer) dropFile(tf *tailedFile) {
+	if tf.pending > 0 {
+		line, _, cut := tf.takeLine()
+		t.emit(tf.path, line, cut)
+	}
+
+	t.mu.Lock()
+	delete(t.files, tf.path)
+	t.mu.Unlock()
+
+	tf.file.Close()
+	t.checkpointMgr.DeletePosition(tf.path)
+
+	t.logger.Info().Str("path", tf.path).Msg("File deleted, stopped tailing")
+}
+
+// resetFile asks the reader of a tailed file to apply a checkpoint reset
+func (t *Tailer) resetFile(path string) {
+	t.mu.RLock()
+	tf, ok := t.files[path]
+	t.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	select {
+	case tf.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+// rewind moves a tailed file to the start, or to the end if the checkpoint
+// manager resets to the end, and records the new position
+func (t *Tailer) rewind(tf *tailedFile) error {
+	whence := io.SeekStart
+	if t.checkpointMgr.ResetsToEnd() {
+		whence = io.SeekEnd
+	}
+
+	offset, err := tf.file.Seek(0, whence)
+	if err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	tf.reader.Reset(tf.file)
+	tf.offset = offset
+	tf.resetLine()
+	t.checkpointMgr.UpdatePosition(tf.path, tf.offset, tf.inode)
+	t.logger.Info().Str("path", tf.path).Int64("offset", offset).Msg("Checkpoint reset")
+
+	return nil
+}
+
 // watchLoop watches for file events
 func (t *Tailer) watchLoop() {
 	defer t.wg.Done()
@@ -262,21 +540,41 @@ func (t *Tailer) handleEvent(event fsnotify.Event) {
 
 	case event.Op&fsnotify.Remove == fsnotify.Remove,
 		event.Op&fsnotify.Rename == fsnotify.Rename:
-		// File was removed or renamed (rotation)
+		// File was removed or renamed (rotation); readLoop drains the old
+		// file and switches to the new one once it appears
 		t.logger.Info().Str("path", path).Msg("File rotation detected")
-		if err := t.reopenFile(path); err != nil {
-			t.logger.Error().Err(err).Str("path", path).Msg("Failed to reopen file")
-		}
 
 	case event.Op&fsnotify.Create == fsnotify.Create:
 		// New file created
+		t.mu.RLock()
+		_, tailed := t.files[path]
+		t.mu.RUnlock()
+		if tailed {
+			return
+		}
+
+		if !t.matches(path) {
+			return
+		}
+		if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+			return
+		}
+
 		t.logger.Info().Str("path", path).Msg("File created")
-		if err := t.openFile(path); err != nil {
+		if err := t.openFile(path, true); err != nil {
 			t.logger.Error().Err(err).Str("path", path).Msg("Failed to open file")
 		}
 	}
 }
 
+// getNlink extracts the hard link count from FileInfo
+func getNlink(fi os.FileInfo) uint64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 1
+}
+
 // getInode extracts inode from FileInfo
 func getInode(fi os.FileInfo) uint64 {
 	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
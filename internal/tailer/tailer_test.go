@@ -4,11 +4,16 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"github.com/therealutkarshpriyadarshi/log/internal/checkpoint"
+	"github.com/therealutkarshpriyadarshi/log/internal/input"
 	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 )
 
 func TestTailerBasic(t *testing.T) {
@@ -207,3 +212,405 @@ func TestTailerCheckpoint(t *testing.T) {
 
 	t.Logf("Checkpoint saved with offset: %d", pos.Offset)
 }
+
+func TestTailerReset(t *testing.T) {
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "test.log")
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	if err := os.WriteFile(logFile, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	// New files are tailed from the end, so nothing is read yet
+	tailer, err := New([]string{logFile}, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	if err := ckptMgr.Reset(logFile); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+
+	var lines []string
+	timeout := time.After(5 * time.Second)
+	for len(lines) < 2 {
+		select {
+		case event := <-tailer.Events():
+			lines = append(lines, event.Message)
+		case <-timeout:
+			t.Fatalf("Expected existing lines to be re-read after reset, got %q", lines)
+		}
+	}
+
+	if lines[0] != "line1\n" || lines[1] != "line2\n" {
+		t.Errorf("Re-read lines = %q", lines)
+	}
+}
+
+// expectLines reads events until the given lines were received in order
+func expectLines(t *testing.T, tailer *Tailer, want ...string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for _, line := range want {
+		select {
+		case event := <-tailer.Events():
+			if event.Message != line {
+				t.Fatalf("Expected line %q, got %q", line, event.Message)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for line %q", line)
+		}
+	}
+}
+
+// appendLine appends data to a file
+func appendLine(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("Failed to write to log file: %v", err)
+	}
+}
+
+func TestTailerRenameAndRecreate(t *testing.T) {
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "test.log")
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	if err := os.WriteFile(logFile, []byte("initial\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	tailer, err := New([]string{logFile}, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	appendLine(t, logFile, "before rotation\n")
+	expectLines(t, tailer, "before rotation\n")
+
+	// Rotate, with a late write to the old file before the new one exists
+	rotatedFile := logFile + ".1"
+	if err := os.Rename(logFile, rotatedFile); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	appendLine(t, rotatedFile, "late write\n")
+	time.Sleep(300 * time.Millisecond)
+
+	if err := os.WriteFile(logFile, []byte("after rotation\n"), 0644); err != nil {
+		t.Fatalf("Failed to write new log file: %v", err)
+	}
+
+	expectLines(t, tailer, "late write\n", "after rotation\n")
+
+	appendLine(t, logFile, "new line\n")
+	expectLines(t, tailer, "new line\n")
+
+	stat, err := os.Stat(logFile)
+	if err != nil {
+		t.Fatalf("Failed to stat log file: %v", err)
+	}
+	if pos, ok := ckptMgr.GetPosition(logFile); !ok || pos.Inode != getInode(stat) {
+		t.Errorf("Expected checkpoint to record the new inode, got %+v", pos)
+	}
+}
+
+func TestTailerTruncation(t *testing.T) {
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "test.log")
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	tailer, err := New([]string{logFile}, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	appendLine(t, logFile, "first line\nsecond line\n")
+	expectLines(t, tailer, "first line\n", "second line\n")
+
+	if err := os.Truncate(logFile, 0); err != nil {
+		t.Fatalf("Failed to truncate log file: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	appendLine(t, logFile, "fresh\n")
+	expectLines(t, tailer, "fresh\n")
+}
+
+func TestTailerPartialLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "test.log")
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	tailer, err := New([]string{logFile}, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	appendLine(t, logFile, "half")
+	time.Sleep(300 * time.Millisecond)
+	appendLine(t, logFile, " line\n")
+
+	expectLines(t, tailer, "half line\n")
+}
+
+func TestTailerOversizedLines(t *testing.T) {
+	for _, policy := range []string{input.OversizeTruncate, input.OversizeDrop} {
+		t.Run(policy, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			logFile := filepath.Join(tmpDir, "test.log")
+
+			ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+			if err != nil {
+				t.Fatalf("Failed to create checkpoint manager: %v", err)
+			}
+			defer ckptMgr.Stop()
+
+			logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+			if err := os.WriteFile(logFile, nil, 0644); err != nil {
+				t.Fatalf("Failed to write log file: %v", err)
+			}
+
+			collector := metrics.NewCollector()
+			tailer, err := New([]string{logFile}, ckptMgr, logger)
+			if err != nil {
+				t.Fatalf("Failed to create tailer: %v", err)
+			}
+			tailer.collector = collector
+			tailer.SetSizeLimit(input.SizeLimit{MaxBytes: 16, Policy: policy})
+			if err := tailer.Start(); err != nil {
+				t.Fatalf("Failed to start tailer: %v", err)
+			}
+			defer tailer.Stop()
+
+			// The long line spans several reads of the file
+			long := strings.Repeat("x", 10000)
+			appendLine(t, logFile, "short\n"+long+"\nnext\n")
+
+			want := []string{"short\n", "next\n"}
+			if policy == input.OversizeTruncate {
+				want = []string{"short\n", long[:16], "next\n"}
+			}
+			expectLines(t, tailer, want...)
+
+			dropped := testutil.ToFloat64(collector.InputEventsDropped.WithLabelValues("file", "file", input.DropReasonOversize))
+			if policy == input.OversizeDrop && dropped != 1 {
+				t.Errorf("expected 1 oversize drop, got %v", dropped)
+			}
+
+			// Reading goes on after the cut line
+			appendLine(t, logFile, "after\n")
+			expectLines(t, tailer, "after\n")
+		})
+	}
+}
+
+func TestTailerGlobDiscovery(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	existing := filepath.Join(tmpDir, "existing.log")
+	if err := os.WriteFile(existing, nil, 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	tailer, err := New([]string{filepath.Join(tmpDir, "*.log")}, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	if err := tailer.SetExcludePatterns([]string{"debug-*.log"}); err != nil {
+		t.Fatalf("SetExcludePatterns() error = %v", err)
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	appendLine(t, existing, "from existing\n")
+	expectLines(t, tailer, "from existing\n")
+
+	// Excluded and non-matching files are ignored
+	if err := os.WriteFile(filepath.Join(tmpDir, "debug-1.log"), []byte("excluded\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("not matching\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// A matching file created after startup is tailed from its beginning
+	created := filepath.Join(tmpDir, "created.log")
+	if err := os.WriteFile(created, []byte("from created\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	select {
+	case event := <-tailer.Events():
+		if event.Message != "from created\n" || event.Source != created {
+			t.Fatalf("Expected line from %s, got %q from %s", created, event.Message, event.Source)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the created file to be tailed")
+	}
+
+	if _, ok := ckptMgr.GetPosition(created); !ok {
+		t.Error("Expected a checkpoint keyed by the created file's path")
+	}
+
+	// Deleted files are dropped
+	if err := os.Remove(created); err != nil {
+		t.Fatalf("Failed to remove log file: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tailer.mu.RLock()
+		_, tailed := tailer.files[created]
+		tailer.mu.RUnlock()
+		if !tailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the deleted file to be dropped")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, ok := ckptMgr.GetPosition(created); ok {
+		t.Error("Expected the deleted file's checkpoint to be removed")
+	}
+}
+
+func TestTailerSetExcludePatterns_Invalid(t *testing.T) {
+	ckptMgr, err := checkpoint.NewManager(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+
+	tailer, err := New(nil, ckptMgr, logging.New(logging.Config{Level: "info", Format: "json"}))
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+
+	if err := tailer.SetExcludePatterns([]string{"[invalid"}); err == nil {
+		t.Error("Expected error for invalid exclude pattern")
+	}
+}
+
+func TestTailerBufferAndConcurrentReads(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	ckptMgr, err := checkpoint.NewManager(filepath.Join(tmpDir, "checkpoints"), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint manager: %v", err)
+	}
+	defer ckptMgr.Stop()
+
+	logger := logging.New(logging.Config{Level: "debug", Format: "json"})
+
+	var paths []string
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to write log file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	tailer, err := New(paths, ckptMgr, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tailer: %v", err)
+	}
+	tailer.SetBufferSize(5)
+	tailer.SetMaxConcurrentReads(1)
+	if cap(tailer.Events()) != 5 {
+		t.Errorf("Expected events buffer of 5, got %d", cap(tailer.Events()))
+	}
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	// Every file is still read with a single read slot
+	for _, path := range paths {
+		appendLine(t, path, filepath.Base(path)+"\n")
+	}
+
+	received := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(received) < len(paths) {
+		select {
+		case event := <-tailer.Events():
+			received[event.Message] = true
+		case <-timeout:
+			t.Fatalf("Timed out waiting for lines, got %v", received)
+		}
+	}
+}
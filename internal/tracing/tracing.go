@@ -6,12 +6,16 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
 const (
@@ -33,7 +37,8 @@ type Provider struct {
 	tracer trace.Tracer
 }
 
-// NewProvider creates a new tracing provider
+// NewProvider creates a new tracing provider, exporting spans over OTLP
+// when an endpoint is set and to stdout when EnableStdout is set
 func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
 	if !cfg.Enabled {
 		// Return a no-op provider
@@ -42,43 +47,58 @@ func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
 		}, nil
 	}
 
-	// Create resource
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			attribute.String("service.name", serviceName),
-			attribute.String("service.version", serviceVersion),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
+	var exporters []sdktrace.SpanExporter
 
 	// Create OTLP exporter
-	var exporter *otlptrace.Exporter
 	if cfg.Endpoint != "" {
 		client := otlptracegrpc.NewClient(
 			otlptracegrpc.WithEndpoint(cfg.Endpoint),
 			otlptracegrpc.WithInsecure(), // Use TLS in production
 		)
-		exporter, err = otlptrace.New(ctx, client)
+		exporter, err := otlptrace.New(ctx, client)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
 		}
+		exporters = append(exporters, exporter)
+	}
+
+	// Create stdout exporter
+	if cfg.EnableStdout {
+		exporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		exporters = append(exporters, exporter)
 	}
 
-	// Configure sampler
-	sampler := sdktrace.AlwaysSample()
-	if cfg.SampleRate > 0 && cfg.SampleRate < 1 {
-		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRate)
+	return NewProviderWithExporters(ctx, cfg, exporters...)
+}
+
+// NewProviderWithExporters creates a tracing provider that batches spans to
+// the given exporters and installs it as the global tracer provider
+func NewProviderWithExporters(ctx context.Context, cfg Config, exporters ...sdktrace.SpanExporter) (*Provider, error) {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("invalid sample rate %v: must be between 0 and 1", cfg.SampleRate)
+	}
+
+	// Create resource
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", serviceVersion),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
 	// Create tracer provider
 	opts := []sdktrace.TracerProviderOption{
-		sdktrace.WithSampler(sampler),
+		sdktrace.WithSampler(newSampler(cfg.SampleRate)),
 		sdktrace.WithResource(res),
 	}
 
-	if exporter != nil {
+	for _, exporter := range exporters {
 		opts = append(opts, sdktrace.WithBatcher(exporter))
 	}
 
@@ -99,6 +119,17 @@ func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
 	}, nil
 }
 
+// newSampler samples root spans at the given rate, treating 0 as unset, and
+// lets child spans follow their parent so an event's trace is kept or
+// dropped as a whole
+func newSampler(rate float64) sdktrace.Sampler {
+	root := sdktrace.AlwaysSample()
+	if rate > 0 && rate < 1 {
+		root = sdktrace.TraceIDRatioBased(rate)
+	}
+	return sdktrace.ParentBased(root)
+}
+
 // Tracer returns the tracer
 func (p *Provider) Tracer() trace.Tracer {
 	return p.tracer
@@ -112,6 +143,14 @@ func (p *Provider) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// ForceFlush exports all spans that have not been exported yet
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p.tp != nil {
+		return p.tp.ForceFlush(ctx)
+	}
+	return nil
+}
+
 // StartSpan starts a new span
 func (p *Provider) StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
 	return p.tracer.Start(ctx, name, opts...)
@@ -135,6 +174,45 @@ func RecordError(ctx context.Context, err error) {
 	span.RecordError(err)
 }
 
+// EndSpan records err on the span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Tracer returns the global tracer used to instrument the pipeline
+func Tracer() trace.Tracer {
+	return otel.Tracer(serviceName)
+}
+
+// EventContext returns the trace context carried by the event
+func EventContext(event *types.LogEvent) context.Context {
+	if event == nil || event.Context == nil {
+		return context.Background()
+	}
+	return event.Context
+}
+
+// WithEventSpan returns ctx parented to the event's trace unless ctx already
+// carries a span of its own
+func WithEventSpan(ctx context.Context, event *types.LogEvent) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(EventContext(event)))
+}
+
+// StartEvent starts the root span for an event entering an input and stores
+// its context on the event so later stages become children of it
+func StartEvent(event *types.LogEvent, inputName, inputType string) trace.Span {
+	ctx, span := TraceInput(EventContext(event), Tracer(), inputName, inputType)
+	event.Context = ctx
+	return span
+}
+
 // Helper functions for common operations
 
 // TraceInput creates a span for input operations
@@ -156,6 +234,15 @@ func TraceParser(ctx context.Context, tracer trace.Tracer, parserType string) (c
 	)
 }
 
+// TraceTransform creates a span for transform pipeline operations
+func TraceTransform(ctx context.Context, tracer trace.Tracer, transformCount int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "transform.apply",
+		trace.WithAttributes(
+			attribute.Int("transform.count", transformCount),
+		),
+	)
+}
+
 // TraceOutput creates a span for output operations
 func TraceOutput(ctx context.Context, tracer trace.Tracer, outputName, outputType string, eventCount int) (context.Context, trace.Span) {
 	return tracer.Start(ctx, "output.send",
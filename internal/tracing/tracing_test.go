@@ -0,0 +1,177 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/input"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/tracing"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// recordingOutput accepts every event it is sent
+type recordingOutput struct {
+	events []*types.LogEvent
+}
+
+func (r *recordingOutput) Send(_ context.Context, event *types.LogEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingOutput) SendBatch(_ context.Context, events []*types.LogEvent) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *recordingOutput) Close() error                          { return nil }
+func (r *recordingOutput) Name() string                          { return "recorder" }
+func (r *recordingOutput) Metrics() *output.OutputMetrics        { return &output.OutputMetrics{} }
+func (r *recordingOutput) HealthCheck(ctx context.Context) error { return nil }
+
+func init() {
+	output.Register("recorder", func(map[string]interface{}) (output.Output, error) {
+		return &recordingOutput{}, nil
+	})
+}
+
+func newTestProvider(t *testing.T, sampleRate float64) (*tracing.Provider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := tracing.NewProviderWithExporters(context.Background(), tracing.Config{
+		Enabled:    true,
+		SampleRate: sampleRate,
+	}, exporter)
+	if err != nil {
+		t.Fatalf("NewProviderWithExporters() error = %v", err)
+	}
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return provider, exporter
+}
+
+// runPipeline sends one event through an input, the ring buffer, the parse
+// and transform stages and the output router
+func runPipeline(t *testing.T) {
+	t.Helper()
+
+	inp := input.NewBaseInput("app", "http", 1)
+	if !inp.SendEvent(&types.LogEvent{Message: "hello", Source: "test"}) {
+		t.Fatal("SendEvent() failed")
+	}
+	event := <-inp.Events()
+
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 4})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	defer rb.Close()
+	if err := rb.Enqueue(context.Background(), event); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	event, err = rb.Dequeue(context.Background())
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+
+	_, span := tracing.TraceParser(tracing.EventContext(event), tracing.Tracer(), "json")
+	span.End()
+	_, span = tracing.TraceTransform(tracing.EventContext(event), tracing.Tracer(), 2)
+	span.End()
+
+	router, err := output.NewRouter(output.RouterConfig{
+		Outputs: []output.OutputConfig{{Type: "recorder"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	defer router.Close()
+	if err := router.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+}
+
+func TestPipelineSpanHierarchy(t *testing.T) {
+	provider, exporter := newTestProvider(t, 1)
+
+	runPipeline(t)
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	root, ok := byName["input.receive"]
+	if !ok {
+		t.Fatalf("expected an input.receive span, got %d spans", len(spans))
+	}
+	if root.Parent.IsValid() {
+		t.Error("expected input.receive to be the root span")
+	}
+
+	for _, name := range []string{"buffer.enqueue", "parser.parse", "transform.apply", "output.send"} {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("expected a %s span", name)
+			continue
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("expected %s to be a child of input.receive", name)
+		}
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("expected %s to share the event's trace", name)
+		}
+	}
+
+	attrs := make(map[string]string)
+	for _, span := range []string{"input.receive", "parser.parse", "output.send"} {
+		for _, kv := range byName[span].Attributes {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+	expected := map[string]string{
+		"input.name":  "app",
+		"input.type":  "http",
+		"parser.type": "json",
+		"output.name": "recorder",
+		"output.type": "recorder",
+		"event.count": "1",
+	}
+	for key, want := range expected {
+		if got := attrs[key]; got != want {
+			t.Errorf("attribute %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	provider, exporter := newTestProvider(t, 1e-12)
+
+	for i := 0; i < 20; i++ {
+		runPipeline(t)
+	}
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("expected unsampled traces to record no spans, got %d", len(spans))
+	}
+}
+
+func TestNewProvider_InvalidSampleRate(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1.5} {
+		if _, err := tracing.NewProvider(context.Background(), tracing.Config{Enabled: true, SampleRate: rate}); err == nil {
+			t.Errorf("expected error for sample rate %v", rate)
+		}
+	}
+}
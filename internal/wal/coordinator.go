@@ -0,0 +1,274 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// Sink receives the events a Coordinator delivers; *output.Router
+// implements it
+type Sink interface {
+	// Send delivers an event, possibly into a batch sent later
+	Send(ctx context.Context, event *types.LogEvent) error
+
+	// Flush sends any batched events
+	Flush(ctx context.Context) error
+}
+
+// CoordinatorConfig holds configuration for a Coordinator
+type CoordinatorConfig struct {
+	// CommitInterval is how often delivered events are committed (one
+	// second by default)
+	CommitInterval time.Duration
+
+	// CommitEvery also commits once this many events have been delivered
+	// since the last commit (1000 by default)
+	CommitEvery int
+
+	// ReplayBatch is how many entries are read at a time when replaying (500
+	// by default)
+	ReplayBatch int
+}
+
+// Coordinator hands events from the buffer to a sink through the WAL. Every
+// event is written to the WAL before it is buffered, and the WAL's commit
+// offset only moves past an event once the sink has sent it and been
+// flushed, so batched events are committed only once they have left the
+// process. On startup, Replay sends the entries that were never committed.
+//
+// This gives at-least-once delivery: an event is lost only if it was never
+// synced to the WAL, while the events delivered since the last commit are
+// sent again after a crash. Outputs should therefore deduplicate, e.g. with
+// idempotent document IDs or the dedup transform on the receiving side.
+// Events the buffer rejects or drops under backpressure are not replayed,
+// and an event the sink fails to send counts as delivered once the sink has
+// given up on it; the router hands such events to its dead letter queue.
+type Coordinator struct {
+	wal    *WAL
+	buffer *buffer.RingBuffer
+	sink   Sink
+	config CoordinatorConfig
+	logger *logging.Logger
+
+	// replayEnd is the offset of the first entry written after the WAL was
+	// opened
+	replayEnd uint64
+
+	commitMu  sync.Mutex
+	delivered atomic.Uint64 // offset after the last delivered event
+	held      atomic.Uint64 // first event interrupted before it was sent
+	pending   atomic.Int64  // events delivered since the last commit
+}
+
+// offsetKey is the event context key of an event's WAL offset
+type offsetKey struct{}
+
+// NewCoordinator creates a coordinator delivering the events of rb to sink
+// through w. The entries already in w are the ones Replay sends.
+func NewCoordinator(w *WAL, rb *buffer.RingBuffer, sink Sink, config CoordinatorConfig, logger *logging.Logger) *Coordinator {
+	if config.CommitInterval <= 0 {
+		config.CommitInterval = time.Second
+	}
+	if config.CommitEvery <= 0 {
+		config.CommitEvery = 1000
+	}
+	if config.ReplayBatch <= 0 {
+		config.ReplayBatch = 500
+	}
+
+	c := &Coordinator{
+		wal:       w,
+		buffer:    rb,
+		sink:      sink,
+		config:    config,
+		logger:    logger,
+		replayEnd: w.NextOffset(),
+	}
+	c.delivered.Store(w.Committed())
+	c.held.Store(math.MaxUint64)
+	return c
+}
+
+// Replay sends the entries written before the coordinator was created that
+// were never committed, then commits them. It must run before buffered
+// events are delivered.
+func (c *Coordinator) Replay(ctx context.Context) (int, error) {
+	end := c.replayEnd
+	next := c.wal.Committed()
+
+	replayed := 0
+	for next < end {
+		entries, err := c.wal.Read(next, c.config.ReplayBatch)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read WAL: %w", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, entry := range entries {
+			if entry.Offset >= end {
+				next = end
+				break
+			}
+			if err := c.send(ctx, entry.Event, entry.Offset); err != nil {
+				return replayed, err
+			}
+			replayed++
+			next = entry.Offset + 1
+		}
+	}
+
+	return replayed, c.Commit(ctx)
+}
+
+// Enqueue writes an event to the WAL and then to the buffer. Events are
+// buffered in WAL order, so every event before a delivered one has been
+// delivered too; with group commit, concurrent Enqueue calls still share
+// the WAL's fsyncs.
+func (c *Coordinator) Enqueue(ctx context.Context, event *types.LogEvent) error {
+	var enqueueErr error
+	_, err := c.wal.WriteThen(event, func(offset uint64) {
+		parent := event.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		event.Context = context.WithValue(parent, offsetKey{}, offset)
+
+		enqueueErr = c.buffer.Enqueue(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write event to WAL: %w", err)
+	}
+	return enqueueErr
+}
+
+// Deliver sends a buffered event to the sink and marks it delivered,
+// committing once CommitEvery events are pending
+func (c *Coordinator) Deliver(ctx context.Context, event *types.LogEvent) error {
+	var offset uint64
+	var ok bool
+	if event.Context != nil {
+		offset, ok = event.Context.Value(offsetKey{}).(uint64)
+	}
+	if !ok {
+		return c.sink.Send(ctx, event)
+	}
+
+	if err := c.send(ctx, event, offset); err != nil {
+		return err
+	}
+	if c.pending.Load() >= int64(c.config.CommitEvery) {
+		return c.Commit(ctx)
+	}
+	return nil
+}
+
+// send sends an event and marks its offset delivered. Send failures are
+// final, as the sink has already retried them, unless ctx was cancelled:
+// the commit offset then stays at the event so it is replayed.
+func (c *Coordinator) send(ctx context.Context, event *types.LogEvent, offset uint64) error {
+	err := c.sink.Send(ctx, event)
+	if err != nil && ctx.Err() != nil {
+		for held := c.held.Load(); offset < held; held = c.held.Load() {
+			if c.held.CompareAndSwap(held, offset) {
+				break
+			}
+		}
+		return err
+	}
+
+	c.delivered.Store(offset + 1)
+	c.pending.Add(1)
+	return err
+}
+
+// Commit flushes the sink and advances the WAL's commit offset past the
+// events delivered before the flush
+func (c *Coordinator) Commit(ctx context.Context) error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	delivered := min(c.delivered.Load(), c.held.Load())
+	pending := c.pending.Load()
+	if delivered <= c.wal.Committed() {
+		return nil
+	}
+
+	if err := c.sink.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush before commit: %w", err)
+	}
+	if err := c.wal.Commit(delivered); err != nil {
+		return fmt.Errorf("failed to commit WAL offset: %w", err)
+	}
+	c.pending.Add(-pending)
+	return nil
+}
+
+// Run delivers buffered events until ctx is done, committing every
+// CommitInterval. An event being sent when ctx is done is sent in full.
+func (c *Coordinator) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.commitLoop(ctx)
+	}()
+	defer wg.Wait()
+
+	for {
+		event, err := c.buffer.Dequeue(ctx)
+		if err != nil {
+			return
+		}
+		if err := c.Deliver(context.Background(), event); err != nil {
+			c.logger.Warn().Err(err).Msg("Failed to send event")
+		}
+	}
+}
+
+// commitLoop commits delivered events every CommitInterval until ctx is done
+func (c *Coordinator) commitLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.CommitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Commit(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Error().Err(err).Msg("Failed to commit WAL offset")
+			}
+		}
+	}
+}
+
+// Drain delivers the events left in the buffer once Run has returned,
+// returning how many were delivered. They are committed by the next Commit.
+func (c *Coordinator) Drain(ctx context.Context) (int, error) {
+	drained := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return drained, fmt.Errorf("%d events left in buffer: %w", c.buffer.Size(), err)
+		}
+		event, ok := c.buffer.TryDequeue()
+		if !ok {
+			break
+		}
+		if err := c.Deliver(ctx, event); err != nil {
+			c.logger.Warn().Err(err).Msg("Failed to send event")
+		}
+		drained++
+	}
+
+	return drained, nil
+}
@@ -0,0 +1,321 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/buffer"
+	"github.com/therealutkarshpriyadarshi/log/internal/logging"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// batchingSink holds sent events until it is flushed. After crashAfter
+// sends it hangs, like a process that died mid-send, and fails everything
+// once released; its unflushed events are lost with it.
+type batchingSink struct {
+	mu         sync.Mutex
+	pending    []string
+	flushed    []string
+	sends      int
+	crashAfter int // 0 never crashes
+	dead       bool
+	crashed    chan struct{}
+	release    chan struct{}
+}
+
+func newBatchingSink(crashAfter int) *batchingSink {
+	return &batchingSink{
+		crashAfter: crashAfter,
+		crashed:    make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+}
+
+func (s *batchingSink) Send(ctx context.Context, event *types.LogEvent) error {
+	s.mu.Lock()
+	if s.dead || (s.crashAfter > 0 && s.sends == s.crashAfter) {
+		if !s.dead {
+			s.dead = true
+			close(s.crashed)
+		}
+		s.mu.Unlock()
+		<-s.release
+		return fmt.Errorf("process crashed")
+	}
+	s.sends++
+	s.pending = append(s.pending, event.Message)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *batchingSink) Flush(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dead {
+		return fmt.Errorf("process crashed")
+	}
+	s.flushed = append(s.flushed, s.pending...)
+	s.pending = nil
+	return nil
+}
+
+func (s *batchingSink) delivered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.flushed...)
+}
+
+func newTestCoordinator(t *testing.T, w *WAL, sink Sink) (*Coordinator, *buffer.RingBuffer) {
+	t.Helper()
+
+	rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 1024})
+	if err != nil {
+		t.Fatalf("NewRingBuffer() error = %v", err)
+	}
+	logger := logging.New(logging.Config{Level: "error", Format: "json"})
+	return NewCoordinator(w, rb, sink, CoordinatorConfig{CommitEvery: 10, CommitInterval: time.Hour}, logger), rb
+}
+
+func TestCoordinator_CrashReplay(t *testing.T) {
+	dir := t.TempDir()
+	config := WALConfig{Dir: dir, SegmentSize: 2048}
+
+	w1, err := NewWAL(config)
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+
+	const total = 100
+	sink1 := newBatchingSink(45)
+	c1, _ := newTestCoordinator(t, w1, sink1)
+
+	for i := 0; i < total; i++ {
+		if err := c1.Enqueue(context.Background(), &types.LogEvent{Message: fmt.Sprintf("event-%d", i)}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := w1.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c1.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		close(sink1.release)
+		<-done
+	})
+
+	select {
+	case <-sink1.crashed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the crash")
+	}
+	// The process dies: nothing more is committed and the sink's unflushed
+	// events are lost
+	cancel()
+	committed := w1.Committed()
+	delivered := sink1.delivered()
+	w1.Close()
+
+	if committed == 0 || committed > uint64(len(delivered)) {
+		t.Fatalf("committed offset %d, but only %d events were delivered", committed, len(delivered))
+	}
+	for i := uint64(0); i < committed; i++ {
+		if want := fmt.Sprintf("event-%d", i); delivered[i] != want {
+			t.Fatalf("committed event %d was not delivered: got %s", i, delivered[i])
+		}
+	}
+
+	// Restart from the same WAL: only the uncommitted entries are replayed
+	w2, err := NewWAL(config)
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w2.Close()
+	if w2.Committed() != committed || w2.NextOffset() != total {
+		t.Fatalf("expected commit %d and next offset %d after restart, got %d and %d", committed, total, w2.Committed(), w2.NextOffset())
+	}
+
+	sink2 := newBatchingSink(0)
+	c2, _ := newTestCoordinator(t, w2, sink2)
+
+	// An event written after the restart is not replayed
+	if err := c2.Enqueue(context.Background(), &types.LogEvent{Message: "after-restart"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	replayed, err := c2.Replay(context.Background())
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if replayed != total-int(committed) {
+		t.Errorf("expected %d replayed events, got %d", total-int(committed), replayed)
+	}
+	if w2.Committed() != total {
+		t.Errorf("expected the replayed events to be committed, commit offset %d", w2.Committed())
+	}
+
+	// Every event was delivered at least once; the duplicates are the ones
+	// delivered but not committed before the crash
+	seen := make(map[string]int)
+	for _, msg := range append(delivered, sink2.delivered()...) {
+		seen[msg]++
+	}
+	for i := 0; i < total; i++ {
+		msg := fmt.Sprintf("event-%d", i)
+		if seen[msg] == 0 {
+			t.Errorf("%s was lost", msg)
+		}
+		if seen[msg] > 1 && uint64(i) < committed {
+			t.Errorf("committed %s was delivered again", msg)
+		}
+	}
+	if seen["after-restart"] != 0 {
+		t.Error("expected the event written after the restart to be left to the buffer")
+	}
+}
+
+func TestCoordinator_DrainAndCommit(t *testing.T) {
+	w, err := NewWAL(WALConfig{Dir: t.TempDir(), SegmentSize: 512})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w.Close()
+
+	sink := newBatchingSink(0)
+	c, rb := newTestCoordinator(t, w, sink)
+
+	for i := 0; i < 25; i++ {
+		if err := c.Enqueue(context.Background(), &types.LogEvent{Message: fmt.Sprintf("event-%d", i)}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if rb.Size() != 25 {
+		t.Fatalf("expected 25 buffered events, got %d", rb.Size())
+	}
+
+	drained, err := c.Drain(context.Background())
+	if err != nil || drained != 25 {
+		t.Fatalf("Drain() = %d, %v", drained, err)
+	}
+	// Every CommitEvery deliveries are committed along the way
+	if got := w.Committed(); got != 20 {
+		t.Errorf("expected 20 events committed while draining, got %d", got)
+	}
+
+	if err := c.Commit(context.Background()); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if got := w.Committed(); got != 25 || len(sink.delivered()) != 25 {
+		t.Errorf("expected all 25 events flushed and committed, got commit %d and %d delivered", got, len(sink.delivered()))
+	}
+	if metrics := w.Metrics(); metrics.SegmentsCurrent != 1 || metrics.CommittedOffset != 25 {
+		t.Errorf("expected the committed segments to be truncated, got %+v", metrics)
+	}
+}
+
+func TestCoordinator_ConcurrentEnqueueOrder(t *testing.T) {
+	for _, groupCommit := range []bool{false, true} {
+		t.Run(fmt.Sprintf("group_commit=%v", groupCommit), func(t *testing.T) {
+			w, err := NewWAL(WALConfig{Dir: t.TempDir(), SegmentSize: 4096, GroupCommit: groupCommit})
+			if err != nil {
+				t.Fatalf("NewWAL() error = %v", err)
+			}
+			defer w.Close()
+
+			c, rb := newTestCoordinator(t, w, newBatchingSink(0))
+
+			const producers, perProducer = 16, 50
+			var wg sync.WaitGroup
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for i := 0; i < perProducer; i++ {
+						event := &types.LogEvent{Message: fmt.Sprintf("producer-%d-%d", p, i)}
+						if err := c.Enqueue(context.Background(), event); err != nil {
+							t.Errorf("Enqueue() error = %v", err)
+							return
+						}
+					}
+				}(p)
+			}
+			wg.Wait()
+
+			// The buffer holds the events in WAL order
+			for want := uint64(0); want < producers*perProducer; want++ {
+				event, ok := rb.TryDequeue()
+				if !ok {
+					t.Fatalf("expected %d buffered events, got %d", producers*perProducer, want)
+				}
+				if offset := event.Context.Value(offsetKey{}).(uint64); offset != want {
+					t.Fatalf("buffered event %d has WAL offset %d", want, offset)
+				}
+			}
+		})
+	}
+}
+
+// discardSink sends events nowhere
+type discardSink struct{}
+
+func (discardSink) Send(context.Context, *types.LogEvent) error { return nil }
+func (discardSink) Flush(context.Context) error                 { return nil }
+
+func BenchmarkCoordinator_Enqueue(b *testing.B) {
+	for _, groupCommit := range []bool{false, true} {
+		name := "locked"
+		if groupCommit {
+			name = "group_commit"
+		}
+		b.Run(name, func(b *testing.B) {
+			w, err := NewWAL(WALConfig{
+				Dir:          b.TempDir(),
+				SegmentSize:  64 * 1024 * 1024,
+				SyncInterval: 10 * time.Second,
+				GroupCommit:  groupCommit,
+			})
+			if err != nil {
+				b.Fatalf("NewWAL() error = %v", err)
+			}
+			defer w.Close()
+
+			rb, err := buffer.NewRingBuffer(buffer.RingBufferConfig{Size: 8192})
+			if err != nil {
+				b.Fatalf("NewRingBuffer() error = %v", err)
+			}
+			logger := logging.New(logging.Config{Level: "error", Format: "json"})
+			c := NewCoordinator(w, rb, discardSink{}, CoordinatorConfig{}, logger)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.Run(ctx)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					event := &types.LogEvent{Message: "benchmark message", Source: "bench"}
+					if err := c.Enqueue(context.Background(), event); err != nil {
+						b.Errorf("Enqueue() error = %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
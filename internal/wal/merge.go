@@ -0,0 +1,176 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// merge rewrites the entries of the sealed segments at or above the commit
+// offset into as few segments as SegmentSize allows and removes the
+// originals; the caller holds w.mu. The merged segments take the IDs of the
+// first originals, so they stay ordered before the current segment. It does
+// nothing when merging would neither drop committed entries nor reduce the
+// number of segments. A crash part way through may leave entries
+// duplicated, never lost.
+func (w *WAL) merge() error {
+	sealed := make([]*segment, 0, len(w.segments))
+	var size int64
+	for _, seg := range w.segments {
+		if seg != w.currentSegment {
+			sealed = append(sealed, seg)
+			size += seg.size
+		}
+	}
+	if len(sealed) == 0 {
+		return nil
+	}
+
+	needed := int((size + w.config.SegmentSize - 1) / w.config.SegmentSize)
+	if needed >= len(sealed) {
+		first, err := sealed[0].readEntries(0, 1)
+		if err != nil {
+			return fmt.Errorf("failed to read segment %d: %w", sealed[0].id, err)
+		}
+		if len(first) == 0 || first[0].Offset >= w.committed {
+			return nil
+		}
+	}
+
+	tmps, err := w.writeMerged(sealed)
+	if err != nil {
+		return err
+	}
+	if len(tmps) > len(sealed) {
+		removeAll(tmps)
+		return fmt.Errorf("merging %d segments produced %d", len(sealed), len(tmps))
+	}
+
+	// Replace the first originals with the merged segments and remove the
+	// rest. Renaming before removing keeps every entry on disk throughout.
+	for _, seg := range sealed {
+		if err := seg.close(); err != nil {
+			removeAll(tmps)
+			return w.reopen(sealed, fmt.Errorf("failed to close segment %d: %w", seg.id, err))
+		}
+	}
+	for i, tmp := range tmps {
+		if err := os.Rename(tmp, sealed[i].path); err != nil {
+			removeAll(tmps[i:])
+			return w.reopen(sealed, fmt.Errorf("failed to replace segment %d: %w", sealed[i].id, err))
+		}
+	}
+	for _, seg := range sealed[len(tmps):] {
+		if err := os.Remove(seg.path); err != nil {
+			return w.reopen(sealed, fmt.Errorf("failed to remove segment %d: %w", seg.id, err))
+		}
+	}
+
+	w.compactions++
+	return w.reopen(sealed[:len(tmps)], nil)
+}
+
+// writeMerged writes the uncommitted entries of segments to temporary files
+// of up to SegmentSize bytes each, rolling at the same point Write does
+func (w *WAL) writeMerged(segments []*segment) ([]string, error) {
+	var (
+		tmps   []string
+		file   *os.File
+		writer *bufio.Writer
+		size   int64
+	)
+	// finish flushes, syncs and closes the current temporary file
+	finish := func() error {
+		if file == nil {
+			return nil
+		}
+		err := writer.Flush()
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		file = nil
+		return err
+	}
+	fail := func(err error) ([]string, error) {
+		if file != nil {
+			file.Close()
+		}
+		removeAll(tmps)
+		return nil, err
+	}
+
+	for _, seg := range segments {
+		entries, err := seg.readAllEntries()
+		if err != nil {
+			return fail(fmt.Errorf("failed to read segment %d: %w", seg.id, err))
+		}
+
+		for _, entry := range entries {
+			if entry.Offset < w.committed {
+				continue
+			}
+
+			if file == nil || size >= w.config.SegmentSize {
+				if err := finish(); err != nil {
+					return fail(fmt.Errorf("failed to write merged segment: %w", err))
+				}
+				file, err = os.CreateTemp(w.config.Dir, mergePrefix+"*"+mergeSuffix)
+				if err != nil {
+					return fail(fmt.Errorf("failed to create merged segment: %w", err))
+				}
+				tmps = append(tmps, file.Name())
+				writer = bufio.NewWriter(file)
+				size = 0
+			}
+
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return fail(fmt.Errorf("failed to marshal entry: %w", err))
+			}
+			data = append(data, '\n')
+			if _, err := writer.Write(data); err != nil {
+				return fail(fmt.Errorf("failed to write merged segment: %w", err))
+			}
+			size += int64(len(data))
+		}
+	}
+
+	if err := finish(); err != nil {
+		return fail(fmt.Errorf("failed to write merged segment: %w", err))
+	}
+	return tmps, nil
+}
+
+// reopen replaces the sealed segments with the given ones reopened, keeping
+// those whose files still exist, and returns err; the caller holds w.mu
+func (w *WAL) reopen(segments []*segment, err error) error {
+	reopened := make([]*segment, 0, len(segments)+1)
+	for _, old := range segments {
+		if _, statErr := os.Stat(old.path); statErr != nil {
+			continue
+		}
+		seg, openErr := newSegment(old.id, old.path, w.config.SegmentSize, true)
+		if openErr != nil {
+			if err == nil {
+				err = openErr
+			}
+			continue
+		}
+		seg.mmapReads = w.config.MmapReads
+		reopened = append(reopened, seg)
+	}
+
+	w.segments = append(reopened, w.currentSegment)
+	return err
+}
+
+// removeAll removes files, ignoring errors
+func removeAll(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
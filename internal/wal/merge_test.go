@@ -0,0 +1,203 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// openMergeWAL reopens the WAL in dir for merging into segments of 4 KB
+func openMergeWAL(t *testing.T, dir string) *WAL {
+	t.Helper()
+
+	w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 4096, MaxSegments: 1000, CompactionPolicy: CompactMerge})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	return w
+}
+
+// segmentFiles returns the names of the segment files in dir
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// assertOffsets checks that the WAL holds exactly the entries from..to-1
+func assertOffsets(t *testing.T, w *WAL, from, to uint64) {
+	t.Helper()
+
+	entries, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(entries) != int(to-from) {
+		t.Fatalf("expected entries %d to %d, got %d entries", from, to-1, len(entries))
+	}
+	for i, entry := range entries {
+		want := from + uint64(i)
+		if entry.Offset != want || entry.Event.Message != fmt.Sprintf("message %d", want) {
+			t.Fatalf("entry %d = %d %q, want offset %d", i, entry.Offset, entry.Event.Message, want)
+		}
+	}
+}
+
+func TestWAL_MergeCompaction(t *testing.T) {
+	const total = 60
+
+	t.Run("keeps uncommitted entries and drops committed ones", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSegments(t, dir, total, 200) // two entries per segment
+
+		w := openMergeWAL(t, dir)
+		defer w.Close()
+
+		// The segment holding offsets 24 and 25 is partly committed
+		if err := w.Commit(25); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+		assertOffsets(t, w, 24, total)
+		before := len(segmentFiles(t, dir))
+
+		if err := w.Compact(); err != nil {
+			t.Fatalf("Compact() error = %v", err)
+		}
+
+		after := len(segmentFiles(t, dir))
+		if after >= before || after != len(w.segments) {
+			t.Errorf("expected fewer segments than %d, got %d on disk and %d open", before, after, len(w.segments))
+		}
+		if w.Metrics().Compactions != 1 {
+			t.Errorf("expected 1 compaction, got %d", w.Metrics().Compactions)
+		}
+		assertOffsets(t, w, 25, total)
+
+		// New entries follow the merged ones and survive a restart
+		if _, err := w.Write(&types.LogEvent{Message: fmt.Sprintf("message %d", total)}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		reopened := openMergeWAL(t, dir)
+		defer reopened.Close()
+		if reopened.NextOffset() != total+1 || reopened.Committed() != 25 {
+			t.Errorf("expected next offset %d and commit 25, got %d and %d", total+1, reopened.NextOffset(), reopened.Committed())
+		}
+		assertOffsets(t, reopened, 25, total+1)
+	})
+
+	t.Run("keeps everything without a commit", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSegments(t, dir, total, 200)
+
+		w := openMergeWAL(t, dir)
+		defer w.Close()
+		if err := w.Compact(); err != nil {
+			t.Fatalf("Compact() error = %v", err)
+		}
+		assertOffsets(t, w, 0, total)
+
+		// Merged segments are full, so merging again does nothing
+		if err := w.Compact(); err != nil {
+			t.Fatalf("Compact() error = %v", err)
+		}
+		if w.Metrics().Compactions != 1 {
+			t.Errorf("expected the second merge skipped, got %d compactions", w.Metrics().Compactions)
+		}
+	})
+
+	t.Run("removes leftovers of an interrupted merge", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSegments(t, dir, 4, 200)
+		leftover := filepath.Join(dir, mergePrefix+"123"+mergeSuffix)
+		if err := os.WriteFile(leftover, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		w := openMergeWAL(t, dir)
+		defer w.Close()
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("expected the leftover merge file removed, got %v", err)
+		}
+		assertOffsets(t, w, 0, 4)
+	})
+}
+
+func TestWAL_MergeTrigger(t *testing.T) {
+	w, err := NewWAL(WALConfig{Dir: t.TempDir(), SegmentSize: 4096, MaxSegments: 2, CompactionPolicy: CompactMerge})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w.Close()
+
+	// runs waits for the background compaction, if any, and returns how
+	// many were started
+	runs := func() (uint64, uint64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			w.mu.RLock()
+			compacting, started, sealed := w.compacting, w.compactionRuns, w.lastSegmentID
+			w.mu.RUnlock()
+			if !compacting {
+				return started, sealed
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("background compaction did not finish")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	write := func(message string) {
+		t.Helper()
+		if _, err := w.Write(&types.LogEvent{Message: message}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	// Fill sealed segments past MaxSegments with uncommitted entries, which
+	// a merge cannot drop
+	large := strings.Repeat("x", 1500)
+	for {
+		w.mu.RLock()
+		over := len(w.segments) > w.config.MaxSegments
+		w.mu.RUnlock()
+		if over {
+			break
+		}
+		write(large)
+	}
+	started, sealed := runs()
+	if started != 1 {
+		t.Fatalf("expected 1 compaction once over MaxSegments, got %d", started)
+	}
+
+	// More writes to the same segments do not start another
+	for i := 0; i < 5; i++ {
+		write("small")
+	}
+	if got, nowSealed := runs(); got != started || nowSealed != sealed {
+		t.Errorf("expected no compaction without a commit or sealed segment, got %d started and segment %d", got, nowSealed)
+	}
+
+	// A commit lets the next write start one
+	if err := w.Commit(1); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	write("small")
+	if got, _ := runs(); got != started+1 {
+		t.Errorf("expected a compaction after the commit, got %d started", got-started)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package wal
+
+import (
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+// mmapFile always fails, so segments are read through buffered IO
+func mmapFile(file *os.File, size int64) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+// munmap does nothing, as nothing is ever mapped
+func munmap(data []byte) error {
+	return nil
+}
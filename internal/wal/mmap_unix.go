@@ -0,0 +1,18 @@
+//go:build unix
+
+package wal
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of a file read-only
+func mmapFile(file *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps memory returned by mmapFile
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
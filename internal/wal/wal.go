@@ -2,18 +2,22 @@ package wal
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -28,6 +32,14 @@ const (
 	defaultMaxSegments = 100
 	segmentPrefix      = "wal-"
 	segmentSuffix      = ".log"
+	commitFile         = "commit"
+	mergePrefix        = "merge-"
+	mergeSuffix        = ".tmp"
+
+	// groupCommitQueue is how many writes may wait for the writer goroutine
+	groupCommitQueue = 1024
+	// maxGroupCommit is the most entries written and synced as one group
+	maxGroupCommit = 256
 )
 
 // WALConfig holds configuration for the Write-Ahead Log
@@ -37,6 +49,29 @@ type WALConfig struct {
 	MaxSegments      int
 	SyncInterval     time.Duration
 	CompactionPolicy CompactionPolicy
+
+	// SegmentMaxAge seals the current segment and starts a new one once it
+	// is older than this, even if it is not full, so it can be compacted
+	// and read as sealed. It is checked every SyncInterval; 0 disables it.
+	SegmentMaxAge time.Duration
+
+	// GroupCommit funnels writes through a single writer goroutine, which
+	// writes the entries queued at the time as one group and syncs them
+	// with a single fsync before acknowledging them. Write then returns
+	// once its entry is durable, and concurrent producers do not contend
+	// on the WAL lock. Without it, each Write appends under the lock and
+	// entries are synced every SyncInterval.
+	GroupCommit bool
+
+	// MmapReads reads sealed segments through a read-only memory mapping
+	// instead of buffered IO, which speeds up replaying large WALs. The
+	// active segment is always read through buffered IO, as are all
+	// segments where mmap is unavailable.
+	MmapReads bool
+
+	// Clock drives the sync loop and segment ages; defaults to the wall
+	// clock
+	Clock clock.Clock
 }
 
 // CompactionPolicy defines when to compact WAL segments
@@ -46,6 +81,11 @@ const (
 	CompactOnSize  CompactionPolicy = "size"  // Compact when segments exceed count
 	CompactOnTime  CompactionPolicy = "time"  // Compact segments older than duration
 	CompactManual  CompactionPolicy = "manual" // Only compact on explicit call
+
+	// CompactMerge merges the sealed segments when they exceed count,
+	// dropping committed entries and keeping every uncommitted one, so the
+	// WAL may stay above MaxSegments
+	CompactMerge CompactionPolicy = "merge"
 )
 
 // WAL is a Write-Ahead Log for durable event storage
@@ -57,10 +97,28 @@ type WAL struct {
 	segments        []*segment
 	lastSegmentID   uint64
 	writePos        uint64
+	committed       uint64 // entries below this offset are delivered
 
 	closeCh         chan struct{}
 	closed          bool
 
+	// Group commit: writes queue for the writer goroutine, which closes
+	// writerDone once writes is closed and drained
+	writes       chan *writeRequest
+	writerDone   chan struct{}
+	submitMu     sync.RWMutex
+	submitClosed bool
+
+	// orderMu keeps WriteThen callbacks in offset order without group
+	// commit
+	orderMu sync.Mutex
+
+	// Background compaction: compacting is set while one runs, and
+	// compactedAt is the WAL's state when the last one started
+	compacting     bool
+	compactedAt    compactionState
+	compactionRuns uint64 // background compactions started
+
 	// Metrics
 	bytesWritten    uint64
 	entriesWritten  uint64
@@ -77,7 +135,38 @@ type segment struct {
 	size     int64
 	maxSize  int64
 	readOnly bool
+	created  time.Time
 	mu       sync.Mutex
+
+	// last caches the offset of the last entry of a read-only segment
+	last      uint64
+	lastKnown bool
+
+	// mapped holds the contents of a read-only segment once mapped, when
+	// mmapReads is set; mmapFailed falls back to buffered IO for good
+	mmapReads  bool
+	mapped     []byte
+	mmapFailed bool
+}
+
+// compactionState is what a merge depends on: the sealed segments and the
+// commit offset
+type compactionState struct {
+	lastSegmentID uint64
+	committed     uint64
+}
+
+// writeRequest is a write queued for the group commit writer
+type writeRequest struct {
+	event  *types.LogEvent
+	then   func(offset uint64) // called once the entry is synced, if set
+	result chan writeResult
+}
+
+// writeResult is the outcome of a queued write
+type writeResult struct {
+	offset uint64
+	err    error
 }
 
 // WALEntry represents a single entry in the WAL
@@ -105,6 +194,10 @@ func NewWAL(config WALConfig) (*WAL, error) {
 		config.SyncInterval = 1 * time.Second
 	}
 
+	if config.Clock == nil {
+		config.Clock = clock.Real
+	}
+
 	if config.CompactionPolicy == "" {
 		config.CompactionPolicy = CompactOnSize
 	}
@@ -134,17 +227,135 @@ func NewWAL(config WALConfig) (*WAL, error) {
 		w.currentSegment = w.segments[len(w.segments)-1]
 	}
 
+	// Continue the offsets after the last recovered entry
+	if err := w.recoverOffsets(); err != nil {
+		w.closeSegments()
+		return nil, err
+	}
+
 	// Start background sync
 	go w.syncLoop()
 
+	if config.GroupCommit {
+		w.writes = make(chan *writeRequest, groupCommitQueue)
+		w.writerDone = make(chan struct{})
+		go w.writeLoop()
+	}
+
 	return w, nil
 }
 
 // Write writes an event to the WAL
 func (w *WAL) Write(event *types.LogEvent) (uint64, error) {
+	if w.writes != nil {
+		return w.submit(event, nil)
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	return w.append(event)
+}
+
+// WriteThen writes an event like Write, then calls then with its offset
+// before returning. The calls are made in offset order, so callers can
+// hand entries on in the order they were written. With group commit the
+// writer goroutine makes them once the entry's group is synced, and
+// concurrent producers still share one fsync per group; without it they
+// are made under a lock taken around the write. then is not called when
+// the write fails.
+func (w *WAL) WriteThen(event *types.LogEvent, then func(offset uint64)) (uint64, error) {
+	if w.writes != nil {
+		return w.submit(event, then)
+	}
+
+	w.orderMu.Lock()
+	defer w.orderMu.Unlock()
+
+	offset, err := w.Write(event)
+	if err != nil {
+		return 0, err
+	}
+	then(offset)
+	return offset, nil
+}
+
+// submit queues a write for the group commit writer and waits until its
+// group is synced and then, if set, has been called
+func (w *WAL) submit(event *types.LogEvent, then func(offset uint64)) (uint64, error) {
+	req := &writeRequest{event: event, then: then, result: make(chan writeResult, 1)}
+
+	w.submitMu.RLock()
+	if w.submitClosed {
+		w.submitMu.RUnlock()
+		return 0, ErrWALClosed
+	}
+	w.writes <- req
+	w.submitMu.RUnlock()
+
+	result := <-req.result
+	return result.offset, result.err
+}
+
+// writeLoop writes the queued requests in groups until writes is closed
+func (w *WAL) writeLoop() {
+	defer close(w.writerDone)
+
+	group := make([]*writeRequest, 0, maxGroupCommit)
+	for req := range w.writes {
+		group = append(group[:0], req)
+
+		// Let producers that were just acknowledged queue their next
+		// writes, then take whatever is queued without waiting
+		runtime.Gosched()
+	collect:
+		for len(group) < maxGroupCommit {
+			select {
+			case req, ok := <-w.writes:
+				if !ok {
+					break collect
+				}
+				group = append(group, req)
+			default:
+				break collect
+			}
+		}
+
+		w.commitGroup(group)
+	}
+}
+
+// commitGroup writes a group of entries, syncs them with one fsync and
+// acknowledges each request with its offset, calling its then first. The
+// calls are made outside the WAL lock, in offset order.
+func (w *WAL) commitGroup(group []*writeRequest) {
+	results := make([]writeResult, len(group))
+
+	w.mu.Lock()
+	for i, req := range group {
+		results[i].offset, results[i].err = w.append(req.event)
+	}
+	// Segments rotated within the group were synced when they were sealed
+	var syncErr error
+	if !w.closed {
+		syncErr = w.currentSegment.sync()
+	}
+	w.mu.Unlock()
+
+	for i, req := range group {
+		if results[i].err == nil && syncErr != nil {
+			results[i].err = fmt.Errorf("failed to sync WAL: %w", syncErr)
+		}
+		if results[i].err == nil && req.then != nil {
+			req.then(results[i].offset)
+		}
+		req.result <- results[i]
+	}
+}
+
+// append writes an entry to the current segment, rolling to a new segment
+// when it is full; the caller holds w.mu
+func (w *WAL) append(event *types.LogEvent) (uint64, error) {
 	if w.closed {
 		return 0, ErrWALClosed
 	}
@@ -161,22 +372,26 @@ func (w *WAL) Write(event *types.LogEvent) (uint64, error) {
 
 	entry := WALEntry{
 		Offset:    offset,
-		Timestamp: time.Now(),
+		Timestamp: w.config.Clock.Now(),
 		Event:     event,
 	}
 
 	// Write entry to segment
-	if err := w.currentSegment.writeEntry(&entry); err != nil {
+	start := time.Now()
+	n, err := w.currentSegment.writeEntry(&entry)
+	if err != nil {
 		return 0, fmt.Errorf("failed to write entry: %w", err)
 	}
 
-	w.bytesWritten += uint64(w.currentSegment.size)
+	w.bytesWritten += uint64(n)
 	w.entriesWritten++
 
+	collector := metrics.GetGlobalCollector()
+	collector.WALWriteBytes.WithLabelValues(w.config.Dir).Add(float64(n))
+	collector.WALWriteDuration.WithLabelValues(w.config.Dir).Observe(time.Since(start).Seconds())
+
 	// Check if compaction is needed
-	if w.config.CompactionPolicy == CompactOnSize && len(w.segments) > w.config.MaxSegments {
-		go w.Compact()
-	}
+	w.triggerCompaction()
 
 	return offset, nil
 }
@@ -248,7 +463,10 @@ func (w *WAL) Sync() error {
 	return nil
 }
 
-// Compact removes old segments and consolidates data
+// Compact removes old segments and consolidates data. With the merge
+// policy the sealed segments are merged instead, keeping uncommitted
+// entries; otherwise the oldest segments beyond MaxSegments are deleted,
+// whether or not their entries were committed.
 func (w *WAL) Compact() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -257,6 +475,10 @@ func (w *WAL) Compact() error {
 		return ErrWALClosed
 	}
 
+	if w.config.CompactionPolicy == CompactMerge {
+		return w.merge()
+	}
+
 	// Keep only the most recent segments
 	if len(w.segments) <= w.config.MaxSegments {
 		return nil
@@ -282,7 +504,8 @@ func (w *WAL) Compact() error {
 	return nil
 }
 
-// Truncate removes all entries before the given offset
+// Truncate removes the segments whose entries are all before the given
+// offset. The current segment is kept.
 func (w *WAL) Truncate(offset uint64) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -291,39 +514,94 @@ func (w *WAL) Truncate(offset uint64) error {
 		return ErrWALClosed
 	}
 
-	// Find segments that can be removed
-	var toRemove []*segment
-	for i, seg := range w.segments {
-		// Read first entry to check offset
-		entries, err := seg.readEntries(0, 1)
-		if err != nil || len(entries) == 0 {
-			continue
+	return w.truncate(offset)
+}
+
+// truncate removes the segments before offset; the caller holds w.mu
+func (w *WAL) truncate(offset uint64) error {
+	removed := 0
+	for _, seg := range w.segments {
+		if seg == w.currentSegment {
+			break
 		}
 
-		if entries[0].Offset < offset {
-			toRemove = append(toRemove, seg)
-		} else {
-			// Keep remaining segments
-			w.segments = w.segments[i:]
+		last, ok, err := seg.lastOffset()
+		if err != nil {
+			return fmt.Errorf("failed to read segment %d: %w", seg.id, err)
+		}
+		if ok && last >= offset {
 			break
 		}
-	}
 
-	// Remove old segments
-	for _, seg := range toRemove {
 		if err := seg.close(); err != nil {
 			return fmt.Errorf("failed to close segment: %w", err)
 		}
 		if err := os.Remove(seg.path); err != nil {
 			return fmt.Errorf("failed to remove segment: %w", err)
 		}
+		removed++
 	}
 
+	w.segments = w.segments[removed:]
 	return nil
 }
 
+// Commit records that the entries before offset have been delivered, so
+// they are not replayed after a restart, and truncates the segments holding
+// only committed entries. The commit offset is persisted before Commit
+// returns; committing an offset at or below the current one does nothing.
+func (w *WAL) Commit(offset uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+	if offset <= w.committed {
+		return nil
+	}
+
+	path := filepath.Join(w.config.Dir, commitFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(offset, 10)), 0644); err != nil {
+		return fmt.Errorf("failed to write commit offset: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write commit offset: %w", err)
+	}
+	w.committed = offset
+
+	return w.truncate(offset)
+}
+
+// Committed returns the commit offset: the entries before it have been
+// delivered
+func (w *WAL) Committed() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.committed
+}
+
+// NextOffset returns the offset of the next entry written
+func (w *WAL) NextOffset() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.writePos
+}
+
 // Close closes the WAL and all open segments
 func (w *WAL) Close() error {
+	// Stop taking writes and let the writer finish the queued ones
+	if w.writes != nil {
+		w.submitMu.Lock()
+		if !w.submitClosed {
+			w.submitClosed = true
+			close(w.writes)
+		}
+		w.submitMu.Unlock()
+		<-w.writerDone
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -334,13 +612,16 @@ func (w *WAL) Close() error {
 	w.closed = true
 	close(w.closeCh)
 
-	// Close all segments
+	return w.closeSegments()
+}
+
+// closeSegments closes all segments
+func (w *WAL) closeSegments() error {
 	for _, seg := range w.segments {
 		if err := seg.close(); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -355,6 +636,7 @@ func (w *WAL) Metrics() WALMetrics {
 		SegmentsCreated: w.segmentsCreated,
 		SegmentsCurrent: uint64(len(w.segments)),
 		Compactions:     w.compactions,
+		CommittedOffset: w.committed,
 	}
 }
 
@@ -370,6 +652,8 @@ func (w *WAL) createSegment() error {
 	if err != nil {
 		return err
 	}
+	seg.mmapReads = w.config.MmapReads
+	seg.created = w.config.Clock.Now()
 
 	// Sync previous segment before switching
 	if w.currentSegment != nil {
@@ -401,6 +685,10 @@ func (w *WAL) loadSegments() error {
 		if strings.HasPrefix(entry.Name(), segmentPrefix) && strings.HasSuffix(entry.Name(), segmentSuffix) {
 			segmentFiles = append(segmentFiles, entry.Name())
 		}
+		// Merged segments left behind by an interrupted compaction
+		if strings.HasPrefix(entry.Name(), mergePrefix) && strings.HasSuffix(entry.Name(), mergeSuffix) {
+			os.Remove(filepath.Join(w.config.Dir, entry.Name()))
+		}
 	}
 
 	// Sort by segment ID
@@ -417,12 +705,15 @@ func (w *WAL) loadSegments() error {
 
 		path := filepath.Join(w.config.Dir, filename)
 
-		// Open existing segment as read-only except for the last one
-		readOnly := true
+		// Open existing segments as read-only except for the last one,
+		// which new entries are appended to
+		readOnly := filename != segmentFiles[len(segmentFiles)-1]
 		seg, err := newSegment(id, path, w.config.SegmentSize, readOnly)
 		if err != nil {
 			return err
 		}
+		seg.mmapReads = w.config.MmapReads
+		seg.created = w.config.Clock.Now()
 
 		w.segments = append(w.segments, seg)
 		if id > w.lastSegmentID {
@@ -430,23 +721,108 @@ func (w *WAL) loadSegments() error {
 		}
 	}
 
-	// Last segment should be writable
-	if len(w.segments) > 0 {
-		lastSeg := w.segments[len(w.segments)-1]
-		lastSeg.readOnly = false
+	return nil
+}
+
+// recoverOffsets restores the write position after the last entry on disk
+// and the persisted commit offset
+func (w *WAL) recoverOffsets() error {
+	for i := len(w.segments) - 1; i >= 0; i-- {
+		last, ok, err := w.segments[i].lastOffset()
+		if err != nil {
+			return fmt.Errorf("failed to read segment %d: %w", w.segments[i].id, err)
+		}
+		if ok {
+			w.writePos = last + 1
+			break
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(w.config.Dir, commitFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read commit offset: %w", err)
+	}
+	committed, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid commit offset %q: %w", data, err)
+	}
+	w.committed = committed
+	return nil
+}
+
+// compactsOnCount reports whether writes trigger a compaction once the
+// segments exceed MaxSegments
+func (w *WAL) compactsOnCount() bool {
+	return w.config.CompactionPolicy == CompactOnSize || w.config.CompactionPolicy == CompactMerge
+}
+
+// triggerCompaction starts a background compaction once the segments
+// exceed MaxSegments; the caller holds w.mu. Only one runs at a time. As a
+// merge may leave the WAL above MaxSegments, the merge policy only starts
+// another once a segment was sealed or the commit offset moved since the
+// last one started.
+func (w *WAL) triggerCompaction() {
+	if !w.compactsOnCount() || len(w.segments) <= w.config.MaxSegments || w.compacting {
+		return
+	}
+
+	state := compactionState{lastSegmentID: w.lastSegmentID, committed: w.committed}
+	if w.config.CompactionPolicy == CompactMerge && w.compactionRuns > 0 && state == w.compactedAt {
+		return
+	}
+	w.compacting = true
+	w.compactedAt = state
+	w.compactionRuns++
+
+	go func() {
+		w.Compact()
+
+		w.mu.Lock()
+		w.compacting = false
+		w.mu.Unlock()
+	}()
+}
+
+// rollExpired seals the current segment and starts a new one once the
+// current one holds entries and is older than SegmentMaxAge
+func (w *WAL) rollExpired() error {
+	if w.config.SegmentMaxAge <= 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+
+	current := w.currentSegment
+	if current.size == 0 || w.config.Clock.Since(current.created) < w.config.SegmentMaxAge {
+		return nil
+	}
+	if err := w.createSegment(); err != nil {
+		return fmt.Errorf("failed to create new segment: %w", err)
 	}
 
+	w.triggerCompaction()
 	return nil
 }
 
 // syncLoop periodically syncs the WAL to disk
 func (w *WAL) syncLoop() {
-	ticker := time.NewTicker(w.config.SyncInterval)
+	ticker := w.config.Clock.NewTicker(w.config.SyncInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
+			if err := w.rollExpired(); err != nil {
+				// Log error but continue
+			}
 			if err := w.Sync(); err != nil {
 				// Log error but continue
 			}
@@ -494,29 +870,30 @@ func newSegment(id uint64, path string, maxSize int64, readOnly bool) (*segment,
 	return seg, nil
 }
 
-// writeEntry writes an entry to the segment
-func (s *segment) writeEntry(entry *WALEntry) error {
+// writeEntry writes an entry to the segment, returning the number of bytes
+// written
+func (s *segment) writeEntry(entry *WALEntry) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.readOnly {
-		return errors.New("cannot write to read-only segment")
+		return 0, errors.New("cannot write to read-only segment")
 	}
 
 	data, err := json.Marshal(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal entry: %w", err)
+		return 0, fmt.Errorf("failed to marshal entry: %w", err)
 	}
 
-	// Write length prefix
+	// Write the entry as a line
 	line := fmt.Sprintf("%s\n", string(data))
 	n, err := s.writer.WriteString(line)
+	s.size += int64(n)
 	if err != nil {
-		return fmt.Errorf("failed to write entry: %w", err)
+		return n, fmt.Errorf("failed to write entry: %w", err)
 	}
 
-	s.size += int64(n)
-	return nil
+	return n, nil
 }
 
 // readEntries reads entries from the segment
@@ -524,26 +901,24 @@ func (s *segment) readEntries(startOffset uint64, limit int) ([]*WALEntry, error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Seek to beginning
-	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
-
 	entries := make([]*WALEntry, 0, limit)
-	scanner := bufio.NewScanner(s.file)
+	err := s.scan(func(record []byte) bool {
+		if len(entries) >= limit {
+			return false
+		}
 
-	for scanner.Scan() && len(entries) < limit {
 		var entry WALEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
+		if err := json.Unmarshal(record, &entry); err != nil {
+			return true
 		}
 
 		if entry.Offset >= startOffset {
 			entries = append(entries, &entry)
 		}
-	}
+		return true
+	})
 
-	return entries, scanner.Err()
+	return entries, err
 }
 
 // readAllEntries reads all entries from the segment
@@ -551,23 +926,98 @@ func (s *segment) readAllEntries() ([]*WALEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var entries []*WALEntry
+	err := s.scan(func(record []byte) bool {
+		var entry WALEntry
+		if err := json.Unmarshal(record, &entry); err == nil {
+			entries = append(entries, &entry)
+		}
+		return true
+	})
+
+	return entries, err
+}
+
+// scan calls fn with each record of the segment until fn returns false
+// (must be called with s.mu held). A mapped segment is iterated in memory
+// without a syscall per read; others are read through a buffered scanner.
+func (s *segment) scan(fn func(record []byte) bool) error {
+	if data, ok := s.mapping(); ok {
+		for len(data) > 0 {
+			record := data
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				record, data = data[:i], data[i+1:]
+			} else {
+				data = nil
+			}
+			if !fn(record) {
+				return nil
+			}
+		}
+		return nil
+	}
+
 	// Seek to beginning
 	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
-		return nil, err
+		return err
 	}
 
-	var entries []*WALEntry
 	scanner := bufio.NewScanner(s.file)
-
 	for scanner.Scan() {
-		var entry WALEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
+		if !fn(scanner.Bytes()) {
+			return nil
 		}
-		entries = append(entries, &entry)
+	}
+	return scanner.Err()
+}
+
+// mapping returns the contents of a read-only segment, mapping it on first
+// use (must be called with s.mu held). It reports false when the segment
+// is read through buffered IO instead: mmap reads are disabled, the
+// segment is active or empty, or mapping it failed.
+func (s *segment) mapping() ([]byte, bool) {
+	if !s.mmapReads || !s.readOnly || s.mmapFailed {
+		return nil, false
+	}
+	if s.mapped != nil {
+		return s.mapped, true
 	}
 
-	return entries, scanner.Err()
+	stat, err := s.file.Stat()
+	if err != nil || stat.Size() == 0 {
+		return nil, false
+	}
+	data, err := mmapFile(s.file, stat.Size())
+	if err != nil {
+		s.mmapFailed = true
+		return nil, false
+	}
+	s.mapped = data
+	return data, true
+}
+
+// lastOffset returns the offset of the segment's last entry, reporting
+// false when it has none. It is cached once the segment is read-only.
+func (s *segment) lastOffset() (uint64, bool, error) {
+	s.mu.Lock()
+	if s.lastKnown {
+		defer s.mu.Unlock()
+		return s.last, true, nil
+	}
+	s.mu.Unlock()
+
+	entries, err := s.readAllEntries()
+	if err != nil || len(entries) == 0 {
+		return 0, false, err
+	}
+	last := entries[len(entries)-1].Offset
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		s.last, s.lastKnown = last, true
+	}
+	return last, true, nil
 }
 
 // sync flushes buffered writes to disk
@@ -597,14 +1047,22 @@ func (s *segment) close() error {
 		}
 	}
 
+	if s.mapped != nil {
+		if err := munmap(s.mapped); err != nil {
+			return err
+		}
+		s.mapped = nil
+	}
+
 	return s.file.Close()
 }
 
 // WALMetrics holds WAL statistics
 type WALMetrics struct {
-	BytesWritten    uint64
-	EntriesWritten  uint64
-	SegmentsCreated uint64
-	SegmentsCurrent uint64
-	Compactions     uint64
+	BytesWritten    uint64 `json:"bytes_written"`
+	EntriesWritten  uint64 `json:"entries_written"`
+	SegmentsCreated uint64 `json:"segments_created"`
+	SegmentsCurrent uint64 `json:"segments_current"`
+	Compactions     uint64 `json:"compactions"`
+	CommittedOffset uint64 `json:"committed_offset"`
 }
@@ -1,11 +1,19 @@
 package wal
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/clock"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -266,6 +274,68 @@ func TestWAL_Truncate(t *testing.T) {
 	}
 }
 
+func TestWAL_SegmentMaxAge(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	w, err := NewWAL(WALConfig{
+		Dir:           t.TempDir(),
+		SyncInterval:  10 * time.Millisecond,
+		SegmentMaxAge: 50 * time.Millisecond,
+		Clock:         fake,
+	})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write(&types.LogEvent{Message: "only event"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// The sync loop has started its ticker
+	fake.BlockUntil(1)
+
+	fake.Advance(40 * time.Millisecond)
+	if err := w.rollExpired(); err != nil {
+		t.Fatalf("rollExpired() error = %v", err)
+	}
+	if created := w.Metrics().SegmentsCreated; created != 1 {
+		t.Fatalf("expected the segment kept before the max age, got %d segments", created)
+	}
+
+	// The segment is rolled once it is old enough, without further writes
+	fake.Advance(10 * time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for w.Metrics().SegmentsCreated < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a new segment after the max age elapsed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w.mu.RLock()
+	sealed := w.segments[0].readOnly
+	w.mu.RUnlock()
+	if !sealed {
+		t.Error("expected the old segment sealed")
+	}
+
+	entries, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Event.Message != "only event" {
+		t.Errorf("expected the event in the sealed segment, got %d entries", len(entries))
+	}
+
+	// An empty segment is not rolled, however old
+	fake.Advance(time.Hour)
+	if err := w.rollExpired(); err != nil {
+		t.Fatalf("rollExpired() error = %v", err)
+	}
+	if created := w.Metrics().SegmentsCreated; created != 2 {
+		t.Errorf("expected no more segments while idle, got %d", created)
+	}
+}
+
 func TestWAL_Metrics(t *testing.T) {
 	dir := t.TempDir()
 
@@ -306,6 +376,50 @@ func TestWAL_Metrics(t *testing.T) {
 	}
 }
 
+func TestWAL_BytesWritten(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 1024})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w.Close()
+
+	collector := metrics.GetGlobalCollector()
+	counterBefore := testutil.ToFloat64(collector.WALWriteBytes.WithLabelValues(dir))
+
+	// Entries differ in size only by their message
+	const n = 40
+	for i := 0; i < n; i++ {
+		event := &types.LogEvent{Message: strings.Repeat("x", i), Source: "test"}
+		if _, err := w.Write(event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// Every byte written is in a segment file
+	var onDisk uint64
+	for _, seg := range w.segments {
+		info, err := os.Stat(seg.path)
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		onDisk += uint64(info.Size())
+	}
+	if len(w.segments) < 2 {
+		t.Fatalf("expected several segments, got %d", len(w.segments))
+	}
+
+	if got := w.Metrics().BytesWritten; got != onDisk {
+		t.Errorf("BytesWritten = %d, want the %d bytes of the entries", got, onDisk)
+	}
+	if got := testutil.ToFloat64(collector.WALWriteBytes.WithLabelValues(dir)) - counterBefore; got != float64(onDisk) {
+		t.Errorf("write bytes counter increased by %v, want %d", got, onDisk)
+	}
+}
+
 func TestWAL_Close(t *testing.T) {
 	dir := t.TempDir()
 
@@ -415,9 +529,13 @@ func TestSegment_WriteAndRead(t *testing.T) {
 		},
 	}
 
-	if err := seg.writeEntry(entry); err != nil {
+	n, err := seg.writeEntry(entry)
+	if err != nil {
 		t.Fatalf("writeEntry() error = %v", err)
 	}
+	if int64(n) != seg.size {
+		t.Errorf("writeEntry() = %d bytes, segment size %d", n, seg.size)
+	}
 
 	if err := seg.sync(); err != nil {
 		t.Fatalf("sync() error = %v", err)
@@ -438,6 +556,96 @@ func TestSegment_WriteAndRead(t *testing.T) {
 	}
 }
 
+func TestWAL_MmapReads(t *testing.T) {
+	dir := t.TempDir()
+	writeSegments(t, dir, 200, 1024)
+
+	buffered, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 1024})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer buffered.Close()
+	want, err := buffered.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 1024, MmapReads: true})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	defer w.Close()
+	if len(w.segments) < 3 {
+		t.Fatalf("expected several segments, got %d", len(w.segments))
+	}
+
+	got, err := w.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(got) != 200 || len(got) != len(want) {
+		t.Fatalf("expected 200 entries, got %d mapped and %d buffered", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Offset != uint64(i) || got[i].Event.Message != want[i].Event.Message {
+			t.Fatalf("entry %d = %d %q, want %d %q", i, got[i].Offset, got[i].Event.Message, i, want[i].Event.Message)
+		}
+	}
+
+	// Only the sealed segments are mapped
+	for _, seg := range w.segments {
+		if mapped := seg.mapped != nil; mapped != seg.readOnly && seg.size > 0 {
+			t.Errorf("segment %d mapped = %v, read-only = %v", seg.id, mapped, seg.readOnly)
+		}
+	}
+	if w.currentSegment.mapped != nil {
+		t.Error("expected the active segment read through buffered IO")
+	}
+
+	// Reads starting mid-WAL stop at the limit
+	entries, err := w.Read(50, 10)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(entries) != 10 || entries[0].Offset != 50 || entries[9].Offset != 59 {
+		t.Errorf("expected entries 50 to 59, got %d entries", len(entries))
+	}
+
+	// Entries written after reopening are read from the active segment
+	if _, err := w.Write(&types.LogEvent{Message: "late"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	entries, err = w.Read(200, 10)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Event.Message != "late" {
+		t.Errorf("expected the late entry, got %d entries", len(entries))
+	}
+}
+
+// writeSegments writes n entries to a new WAL in dir, over segments of
+// segmentSize bytes, and closes it
+func writeSegments(tb testing.TB, dir string, n int, segmentSize int64) {
+	tb.Helper()
+
+	w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: segmentSize, MaxSegments: n})
+	if err != nil {
+		tb.Fatalf("NewWAL() error = %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := w.Write(&types.LogEvent{Message: fmt.Sprintf("message %d", i), Source: "bench"}); err != nil {
+			tb.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatalf("Close() error = %v", err)
+	}
+}
+
 func BenchmarkWAL_Write(b *testing.B) {
 	dir := b.TempDir()
 
@@ -461,6 +669,122 @@ func BenchmarkWAL_Write(b *testing.B) {
 	}
 }
 
+func TestWAL_GroupCommit(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 4096, GroupCommit: true})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+
+	const producers, perProducer = 16, 50
+	var wg sync.WaitGroup
+	offsets := make(chan uint64, producers*perProducer)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				offset, err := w.Write(&types.LogEvent{Message: fmt.Sprintf("%d-%d", p, i)})
+				if err != nil {
+					t.Errorf("Write() error = %v", err)
+					return
+				}
+				offsets <- offset
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(offsets)
+
+	// Every write got its own offset, with no gaps
+	seen := make(map[uint64]bool)
+	for offset := range offsets {
+		if seen[offset] {
+			t.Fatalf("offset %d assigned twice", offset)
+		}
+		seen[offset] = true
+	}
+	const total = producers * perProducer
+	for offset := uint64(0); offset < total; offset++ {
+		if !seen[offset] {
+			t.Fatalf("offset %d not assigned", offset)
+		}
+	}
+
+	// Acknowledged entries are on disk without an explicit sync
+	reader, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 4096})
+	if err != nil {
+		t.Fatalf("NewWAL() error = %v", err)
+	}
+	entries, err := reader.ReadAll()
+	reader.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(entries) != total {
+		t.Errorf("expected %d entries on disk, got %d", total, len(entries))
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := w.Write(&types.LogEvent{Message: "late"}); !errors.Is(err, ErrWALClosed) {
+		t.Errorf("expected ErrWALClosed after Close, got %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrWALClosed) {
+		t.Errorf("expected ErrWALClosed closing twice, got %v", err)
+	}
+}
+
+func BenchmarkWAL_ConcurrentWrite(b *testing.B) {
+	event := &types.LogEvent{
+		Message: "benchmark message",
+		Source:  "bench",
+	}
+
+	// locked appends under the WAL lock and syncs every SyncInterval;
+	// locked_sync syncs after each write for the durability group commit
+	// gives; group_commit syncs each group of queued writes once
+	modes := []struct {
+		name        string
+		groupCommit bool
+		syncEach    bool
+	}{
+		{"locked", false, false},
+		{"locked_sync", false, true},
+		{"group_commit", true, false},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			wal, err := NewWAL(WALConfig{
+				Dir:          b.TempDir(),
+				SegmentSize:  64 * 1024 * 1024,
+				SyncInterval: 10 * time.Second,
+				GroupCommit:  mode.groupCommit,
+			})
+			if err != nil {
+				b.Fatalf("NewWAL() error = %v", err)
+			}
+			defer wal.Close()
+
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := wal.Write(event); err != nil {
+						b.Errorf("Write() error = %v", err)
+						return
+					}
+					if mode.syncEach {
+						_ = wal.Sync()
+					}
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkWAL_Read(b *testing.B) {
 	dir := b.TempDir()
 
@@ -487,3 +811,29 @@ func BenchmarkWAL_Read(b *testing.B) {
 		_, _ = wal.Read(0, 100)
 	}
 }
+
+func BenchmarkWAL_ReadAll(b *testing.B) {
+	dir := b.TempDir()
+	writeSegments(b, dir, 100000, 1024*1024)
+
+	for _, mmap := range []bool{false, true} {
+		name := "buffered"
+		if mmap {
+			name = "mmap"
+		}
+		b.Run(name, func(b *testing.B) {
+			w, err := NewWAL(WALConfig{Dir: dir, SegmentSize: 1024 * 1024, MmapReads: mmap})
+			if err != nil {
+				b.Fatalf("NewWAL() error = %v", err)
+			}
+			defer w.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := w.ReadAll(); err != nil {
+					b.Fatalf("ReadAll() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package wiring converts the configuration into the settings of the
+// parser and output packages, for the binaries that assemble a pipeline
+package wiring
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/internal/parser"
+)
+
+// RouterConfig returns the router configuration for stdout, console,
+// kafka, elasticsearch, s3, gcs, http, loki, gelf and multi outputs
+func RouterConfig(cfg config.OutputConfig) (*output.RouterConfig, error) {
+	routerCfg := output.DefaultRouterConfig()
+
+	switch cfg.Type {
+	case "stdout", "console", "kafka", "elasticsearch", "s3", "gcs", "http", "loki", "gelf":
+		oc, err := outputConfig(cfg.Type, cfg.Type, cfg.Kafka, cfg.Elasticsearch, cfg.S3, cfg.GCS, cfg.HTTP, cfg.Loki, cfg.Console, cfg.Stdout, cfg.GELF)
+		if err != nil {
+			return nil, err
+		}
+		routerCfg.Outputs = append(routerCfg.Outputs, oc)
+	case "file":
+		routerCfg.Outputs = append(routerCfg.Outputs, fileOutputConfig("file", cfg.Path))
+	case "multi":
+		if cfg.Multi == nil || len(cfg.Multi.Outputs) == 0 {
+			return nil, fmt.Errorf("multi output has no outputs configured")
+		}
+		for _, def := range cfg.Multi.Outputs {
+			if def.Type == "file" {
+				routerCfg.Outputs = append(routerCfg.Outputs, fileOutputConfig(def.Name, def.Path))
+				continue
+			}
+			oc, err := outputConfig(def.Type, def.Name, def.Kafka, def.Elasticsearch, def.S3, def.GCS, def.HTTP, def.Loki, def.Console, def.Stdout, def.GELF)
+			if err != nil {
+				return nil, err
+			}
+			routerCfg.Outputs = append(routerCfg.Outputs, oc)
+		}
+		if cfg.Multi.FailureStrategy != "" {
+			routerCfg.FailureStrategy = cfg.Multi.FailureStrategy
+		}
+		routerCfg.Parallel = cfg.Multi.Parallel
+		routerCfg.MaxRetries = cfg.Multi.MaxRetries
+		routerCfg.RetryBackoff = cfg.Multi.RetryBackoff
+	default:
+		return nil, fmt.Errorf("unsupported output type: %s", cfg.Type)
+	}
+
+	return &routerCfg, nil
+}
+
+// fileOutputConfig returns the settings of a file output, whose only
+// setting is its path
+func fileOutputConfig(name, path string) output.OutputConfig {
+	return output.OutputConfig{Type: "file", Name: name, Config: map[string]interface{}{"path": path}}
+}
+
+// outputConfig converts the typed configuration of an output into the
+// settings map the output registry decodes
+func outputConfig(outputType, name string, kafka *config.KafkaOutputConfig, es *config.ElasticsearchOutputConfig, s3 *config.S3OutputConfig, gcs *config.GCSOutputConfig, httpCfg *config.HTTPOutputConfig, loki *config.LokiOutputConfig, console *config.ConsoleOutputConfig, stdout *config.StdoutOutputConfig, gelf *config.GELFOutputConfig) (output.OutputConfig, error) {
+	var typed interface{}
+	switch outputType {
+	case "kafka":
+		typed = kafka
+	case "elasticsearch":
+		typed = es
+	case "s3":
+		typed = s3
+	case "gcs":
+		typed = gcs
+	case "http":
+		typed = httpCfg
+	case "loki":
+		typed = loki
+	case "console":
+		typed = console
+	case "stdout":
+		typed = stdout
+	case "gelf":
+		typed = gelf
+	default:
+		return output.OutputConfig{}, fmt.Errorf("unsupported output type: %s", outputType)
+	}
+
+	settings := make(map[string]interface{})
+	data, err := yaml.Marshal(typed)
+	if err != nil {
+		return output.OutputConfig{}, fmt.Errorf("failed to encode %s output config: %w", outputType, err)
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return output.OutputConfig{}, fmt.Errorf("failed to decode %s output config: %w", outputType, err)
+	}
+
+	return output.OutputConfig{Type: outputType, Name: name, Config: settings}, nil
+}
+
+// enrichTransform converts the enrichment configuration to an enrich
+// transform, or returns nil when it adds no fields
+func enrichTransform(cfg *config.EnrichmentConfig) *parser.TransformConfig {
+	if cfg == nil || (len(cfg.Fields) == 0 && !cfg.Hostname && !cfg.PID) {
+		return nil
+	}
+
+	return &parser.TransformConfig{
+		Type:     "enrich",
+		Add:      cfg.Fields,
+		Hostname: cfg.Hostname,
+		PID:      cfg.PID,
+	}
+}
+
+// PipelineConfigs returns an input's transform configurations preceded by
+// the enrichment, if any
+func PipelineConfigs(enrichment *config.EnrichmentConfig, transforms []config.TransformConfig) []parser.TransformConfig {
+	configs := transformConfigs(transforms)
+	if enrich := enrichTransform(enrichment); enrich != nil {
+		configs = append([]parser.TransformConfig{*enrich}, configs...)
+	}
+	return configs
+}
+
+// transformConfigs converts an input's transform configurations
+func transformConfigs(transforms []config.TransformConfig) []parser.TransformConfig {
+	configs := make([]parser.TransformConfig, len(transforms))
+	for i, tc := range transforms {
+		configs[i] = parser.TransformConfig{
+			Type:           tc.Type,
+			Fields:         tc.Fields,
+			IncludeFields:  tc.IncludeFields,
+			ExcludeFields:  tc.ExcludeFields,
+			Rename:         tc.Rename,
+			Add:            tc.Add,
+			Patterns:       tc.Patterns,
+			FieldSplit:     tc.FieldSplit,
+			ValueSplit:     tc.ValueSplit,
+			Prefix:         tc.Prefix,
+			Mask:           tc.Mask,
+			Hash:           tc.Hash,
+			RedactMessage:  tc.RedactMessage,
+			Separator:      tc.Separator,
+			MaxDepth:       tc.MaxDepth,
+			KeepOriginal:   tc.KeepOriginal,
+			Rate:           tc.Rate,
+			KeepOneIn:      tc.KeepOneIn,
+			KeyField:       tc.KeyField,
+			LevelOverrides: tc.LevelOverrides,
+			Field:          tc.Field,
+			Operator:       tc.Operator,
+			Value:          tc.Value,
+			Hostname:       tc.Hostname,
+			PID:            tc.PID,
+			TTL:            tc.TTL,
+			MaxEntries:     tc.MaxEntries,
+		}
+	}
+	return configs
+}
@@ -0,0 +1,145 @@
+package wiring
+
+import (
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/config"
+)
+
+func TestRouterConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.OutputConfig
+		wantErr     bool
+		wantOutputs []string
+	}{
+		{
+			name:        "stdout",
+			cfg:         config.OutputConfig{Type: "stdout", Stdout: &config.StdoutOutputConfig{BatchSize: 10}},
+			wantOutputs: []string{"stdout"},
+		},
+		{name: "file", cfg: config.OutputConfig{Type: "file", Path: "/var/log/events.ndjson"}, wantOutputs: []string{"file"}},
+		{name: "unknown", cfg: config.OutputConfig{Type: "carrier-pigeon"}, wantErr: true},
+		{
+			name: "kafka",
+			cfg: config.OutputConfig{Type: "kafka", Kafka: &config.KafkaOutputConfig{
+				Brokers: []string{"localhost:9092"},
+				Topic:   "logs",
+			}},
+			wantOutputs: []string{"kafka"},
+		},
+		{name: "console", cfg: config.OutputConfig{Type: "console"}, wantOutputs: []string{"console"}},
+		{
+			name:        "http",
+			cfg:         config.OutputConfig{Type: "http", HTTP: &config.HTTPOutputConfig{URL: "http://localhost:3100"}},
+			wantOutputs: []string{"http"},
+		},
+		{
+			name:        "loki",
+			cfg:         config.OutputConfig{Type: "loki", Loki: &config.LokiOutputConfig{URL: "http://localhost:3100"}},
+			wantOutputs: []string{"loki"},
+		},
+		{
+			name:        "gcs",
+			cfg:         config.OutputConfig{Type: "gcs", GCS: &config.GCSOutputConfig{Bucket: "logs", Endpoint: "http://localhost:4443"}},
+			wantOutputs: []string{"gcs"},
+		},
+		{
+			name:        "gelf",
+			cfg:         config.OutputConfig{Type: "gelf", GELF: &config.GELFOutputConfig{Address: "graylog:12201", Transport: "tcp"}},
+			wantOutputs: []string{"gelf"},
+		},
+		{
+			name: "multi",
+			cfg: config.OutputConfig{Type: "multi", Multi: &config.MultiOutputConfig{
+				Outputs: []config.OutputDefinition{
+					{Name: "primary", Type: "kafka", Kafka: &config.KafkaOutputConfig{Topic: "logs"}},
+					{Name: "archive", Type: "s3", S3: &config.S3OutputConfig{Bucket: "logs"}},
+					{Name: "local", Type: "file", Path: "/var/log/events.ndjson"},
+				},
+				FailureStrategy: "stop",
+			}},
+			wantOutputs: []string{"kafka", "s3", "file"},
+		},
+		{name: "empty multi", cfg: config.OutputConfig{Type: "multi"}, wantErr: true},
+		{
+			name: "unknown multi output",
+			cfg: config.OutputConfig{Type: "multi", Multi: &config.MultiOutputConfig{
+				Outputs: []config.OutputDefinition{{Name: "x", Type: "carrier-pigeon"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routerCfg, err := RouterConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RouterConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(routerCfg.Outputs) != len(tt.wantOutputs) {
+				t.Fatalf("expected %d outputs, got %d", len(tt.wantOutputs), len(routerCfg.Outputs))
+			}
+			for i, outputType := range tt.wantOutputs {
+				if routerCfg.Outputs[i].Type != outputType {
+					t.Errorf("output %d type = %s, want %s", i, routerCfg.Outputs[i].Type, outputType)
+				}
+			}
+		})
+	}
+
+	// Typed settings are passed to the output registry by their YAML keys
+	routerCfg, err := RouterConfig(tests[3].cfg)
+	if err != nil {
+		t.Fatalf("RouterConfig() error = %v", err)
+	}
+	if topic := routerCfg.Outputs[0].Config["topic"]; topic != "logs" {
+		t.Errorf("expected topic setting logs, got %v", topic)
+	}
+	routerCfg, err = RouterConfig(tests[1].cfg)
+	if err != nil {
+		t.Fatalf("RouterConfig() error = %v", err)
+	}
+	if path := routerCfg.Outputs[0].Config["path"]; path != "/var/log/events.ndjson" {
+		t.Errorf("expected path setting /var/log/events.ndjson, got %v", path)
+	}
+
+	// Settings shared by the batching outputs and TLS settings keep their keys
+	kafka := &config.KafkaOutputConfig{Topic: "logs"}
+	kafka.Schema = &config.SchemaConfig{Name: "ecs"}
+	kafka.MaxBytesPerSec = 1 << 20
+	kafka.MaxConcurrentBatches = 4
+	kafka.AdaptiveBatch = &config.AdaptiveBatchConfig{Enabled: true}
+	kafka.TLSCACert = "/etc/ssl/ca.pem"
+	routerCfg, err = RouterConfig(config.OutputConfig{Type: "kafka", Kafka: kafka})
+	if err != nil {
+		t.Fatalf("RouterConfig() error = %v", err)
+	}
+	if schema, _ := routerCfg.Outputs[0].Config["schema"].(map[string]interface{}); schema["name"] != "ecs" {
+		t.Errorf("expected schema setting ecs, got %v", routerCfg.Outputs[0].Config["schema"])
+	}
+	if adaptive, _ := routerCfg.Outputs[0].Config["adaptive_batch"].(map[string]interface{}); adaptive["enabled"] != true {
+		t.Errorf("expected adaptive batching enabled, got %v", routerCfg.Outputs[0].Config["adaptive_batch"])
+	}
+	if ca := routerCfg.Outputs[0].Config["tls_ca_cert"]; ca != "/etc/ssl/ca.pem" {
+		t.Errorf("expected tls_ca_cert /etc/ssl/ca.pem, got %v", ca)
+	}
+	if batches := routerCfg.Outputs[0].Config["max_concurrent_batches"]; batches != 4 {
+		t.Errorf("expected max_concurrent_batches 4, got %v", batches)
+	}
+	if limit := routerCfg.Outputs[0].Config["max_bytes_per_sec"]; limit != 1<<20 {
+		t.Errorf("expected max_bytes_per_sec %d, got %v", 1<<20, limit)
+	}
+
+	// Rate limits are passed with the output's settings
+	routerCfg, err = RouterConfig(config.OutputConfig{Type: "console", Console: &config.ConsoleOutputConfig{RateLimitConfig: config.RateLimitConfig{MaxEventsPerSec: 100}}})
+	if err != nil {
+		t.Fatalf("RouterConfig() error = %v", err)
+	}
+	if limit := routerCfg.Outputs[0].Config["max_events_per_sec"]; limit != 100 {
+		t.Errorf("expected max_events_per_sec 100, got %v", limit)
+	}
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// AutoScaleConfig configures the autoscaling of a worker pool
+type AutoScaleConfig struct {
+	Enabled bool
+
+	// MinWorkers and MaxWorkers bound the number of workers (1 and four
+	// times NumWorkers by default)
+	MinWorkers int
+	MaxWorkers int
+
+	// TargetQueueDepth is the queue depth the pool scales up to stay under
+	// (a tenth of the queue size by default)
+	TargetQueueDepth int
+
+	// Interval is how often the queue depth and job latency are sampled
+	// (one second by default)
+	Interval time.Duration
+
+	// Cooldown is the least time between two scale events (30 seconds by
+	// default)
+	Cooldown time.Duration
+}
+
+// setDefaults fills in the unset settings for a pool's configuration
+func (c *AutoScaleConfig) setDefaults(pool PoolConfig) error {
+	if c.MinWorkers <= 0 {
+		c.MinWorkers = 1
+	}
+	if c.MaxWorkers <= 0 {
+		c.MaxWorkers = max(pool.NumWorkers*4, c.MinWorkers)
+	}
+	if c.MinWorkers > c.MaxWorkers {
+		return fmt.Errorf("autoscale min_workers %d is above max_workers %d", c.MinWorkers, c.MaxWorkers)
+	}
+	if c.TargetQueueDepth <= 0 {
+		c.TargetQueueDepth = max(pool.QueueSize/10, 1)
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Cooldown < 0 {
+		return fmt.Errorf("autoscale cooldown must not be negative: %s", c.Cooldown)
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	return nil
+}
+
+// scaleSample is what the autoscaler observes of the pool in an interval
+type scaleSample struct {
+	workers    int
+	depth      int           // jobs queued
+	active     int           // workers running a job
+	completed  uint64        // jobs completed in the interval
+	avgLatency time.Duration // mean time to complete those jobs
+}
+
+// desiredWorkers returns the number of workers for a sample. Above the
+// target depth, the pool adds the workers needed to drain the excess within
+// an interval at the observed latency, or doubles when no job completed.
+// Below half the target it keeps the workers the completed jobs kept busy,
+// and never fewer than are running a job.
+func (c AutoScaleConfig) desiredWorkers(s scaleSample) int {
+	desired := s.workers
+
+	switch {
+	case s.depth > c.TargetQueueDepth:
+		add := s.workers
+		if s.avgLatency > 0 {
+			excess := float64(s.depth - c.TargetQueueDepth)
+			add = int(math.Ceil(excess * float64(s.avgLatency) / float64(c.Interval)))
+		}
+		desired = s.workers + max(add, 1)
+	case s.depth <= c.TargetQueueDepth/2:
+		busy := float64(s.completed) * float64(s.avgLatency) / float64(c.Interval)
+		desired = min(s.workers, max(int(math.Ceil(busy)), s.active))
+	}
+
+	return clamp(desired, c.MinWorkers, c.MaxWorkers)
+}
+
+// autoscale samples the pool every interval and scales it to the desired
+// number of workers, at most once per cooldown, until the pool stops
+func (p *WorkerPool) autoscale() {
+	defer p.wg.Done()
+
+	cfg := p.config.AutoScale
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var lastScale time.Time
+	lastProcessed := atomic.LoadUint64(&p.jobsProcessed)
+	lastLatency := atomic.LoadUint64(&p.jobLatency)
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case now := <-ticker.C:
+			processed := atomic.LoadUint64(&p.jobsProcessed)
+			latency := atomic.LoadUint64(&p.jobLatency)
+
+			sample := scaleSample{
+				workers:   p.size(),
+				depth:     len(p.jobQueue),
+				active:    int(atomic.LoadUint64(&p.workersActive)),
+				completed: processed - lastProcessed,
+			}
+			if sample.completed > 0 {
+				sample.avgLatency = time.Duration((latency - lastLatency) / sample.completed)
+			}
+			lastProcessed, lastLatency = processed, latency
+
+			if now.Sub(lastScale) < cfg.Cooldown {
+				continue
+			}
+			if desired := cfg.desiredWorkers(sample); desired != sample.workers {
+				if err := p.Scale(desired); err != nil {
+					return
+				}
+				lastScale = now
+			}
+		}
+	}
+}
+
+// clamp bounds n to [lo, hi]
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+func TestWorkerPool_AutoScale(t *testing.T) {
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	const cooldown = 100 * time.Millisecond
+	pool, err := NewWorkerPool(PoolConfig{
+		Name:       "autoscale-test",
+		NumWorkers: 1,
+		QueueSize:  1000,
+		AutoScale: AutoScaleConfig{
+			Enabled:          true,
+			MinWorkers:       1,
+			MaxWorkers:       8,
+			TargetQueueDepth: 10,
+			Interval:         10 * time.Millisecond,
+			Cooldown:         cooldown,
+		},
+	}, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+	pool.Start()
+
+	gauge := metrics.GetGlobalCollector().WorkerPoolSize.WithLabelValues("autoscale-test")
+
+	// Flood the pool
+	for i := 0; i < 500; i++ {
+		if err := pool.SubmitAsync(&types.LogEvent{}); err != nil {
+			t.Fatalf("SubmitAsync() error = %v", err)
+		}
+	}
+
+	var scaledUp time.Time
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pool.Metrics().NumWorkers > 1 {
+			scaledUp = time.Now()
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if scaledUp.IsZero() {
+		t.Fatalf("expected the pool to scale up, queue depth %d", pool.Metrics().QueueSize)
+	}
+	if got := testutil.ToFloat64(gauge); got <= 1 {
+		t.Errorf("expected the workers gauge to follow the scale up, got %v", got)
+	}
+
+	// Once drained, the pool scales back down after the cooldown
+	var scaledDown time.Time
+	deadline = time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if m := pool.Metrics(); m.NumWorkers == 1 && m.QueueSize == 0 {
+			scaledDown = time.Now()
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if scaledDown.IsZero() {
+		t.Fatalf("expected the pool to scale back down, %d workers", pool.Metrics().NumWorkers)
+	}
+	if elapsed := scaledDown.Sub(scaledUp); elapsed < cooldown-10*time.Millisecond {
+		t.Errorf("scaled down %s after scaling up, within the %s cooldown", elapsed, cooldown)
+	}
+	if got := testutil.ToFloat64(gauge); got != 1 {
+		t.Errorf("expected the workers gauge to be 1 after scaling down, got %v", got)
+	}
+
+	if m := pool.Metrics(); m.JobsProcessed != 500 || m.JobsFailed != 0 {
+		t.Errorf("expected every job to be processed, got %d processed and %d failed", m.JobsProcessed, m.JobsFailed)
+	}
+}
+
+func TestAutoScaleConfig_DesiredWorkers(t *testing.T) {
+	cfg := AutoScaleConfig{MinWorkers: 2, MaxWorkers: 10, TargetQueueDepth: 100, Interval: time.Second}
+
+	tests := []struct {
+		name   string
+		sample scaleSample
+		want   int
+	}{
+		{"drain the excess", scaleSample{workers: 2, depth: 300, avgLatency: 10 * time.Millisecond}, 4},
+		{"capped at max", scaleSample{workers: 8, depth: 1000, avgLatency: 100 * time.Millisecond}, 10},
+		{"no completed jobs doubles", scaleSample{workers: 3, depth: 200}, 6},
+		{"within the target", scaleSample{workers: 5, depth: 80, completed: 10, avgLatency: time.Millisecond}, 5},
+		{"busy workers kept", scaleSample{workers: 6, depth: 10, completed: 300, avgLatency: 10 * time.Millisecond}, 3},
+		{"running jobs kept", scaleSample{workers: 6, depth: 0, active: 4}, 4},
+		{"idle down to min", scaleSample{workers: 6, depth: 0}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.desiredWorkers(tt.sample); got != tt.want {
+				t.Errorf("desiredWorkers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWorkerPool_AutoScaleDefaults(t *testing.T) {
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error { return nil }
+
+	pool, err := NewWorkerPool(PoolConfig{
+		NumWorkers: 2,
+		QueueSize:  500,
+		AutoScale:  AutoScaleConfig{Enabled: true, MinWorkers: 3},
+	}, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+
+	as := pool.config.AutoScale
+	if as.MaxWorkers != 8 || as.TargetQueueDepth != 50 || as.Interval != time.Second || as.Cooldown != 30*time.Second {
+		t.Errorf("unexpected defaults %+v", as)
+	}
+	if len(pool.workers) != 3 {
+		t.Errorf("expected the workers raised to the minimum, got %d", len(pool.workers))
+	}
+
+	if _, err := NewWorkerPool(PoolConfig{
+		AutoScale: AutoScaleConfig{Enabled: true, MinWorkers: 5, MaxWorkers: 2},
+	}, jobFunc); err == nil {
+		t.Error("expected an error for min_workers above max_workers")
+	}
+}
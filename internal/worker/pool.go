@@ -7,6 +7,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/log/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/log/internal/reliability"
 	"github.com/therealutkarshpriyadarshi/log/pkg/types"
 )
 
@@ -14,25 +16,45 @@ var (
 	ErrPoolClosed   = errors.New("worker pool is closed")
 	ErrNoWorkers    = errors.New("no workers available")
 	ErrJobTimeout   = errors.New("job execution timeout")
+	ErrInvalidScale = errors.New("number of workers must be positive")
 )
 
 // JobFunc is a function that processes a log event
 type JobFunc func(ctx context.Context, event *types.LogEvent) error
 
+// ExhaustedFunc is called with an event whose job failed after all retries
+type ExhaustedFunc func(event *types.LogEvent, err error)
+
 // PoolConfig holds configuration for the worker pool
 type PoolConfig struct {
+	Name         string
 	NumWorkers   int
 	QueueSize    int
 	JobTimeout   time.Duration
 	EnableStealing bool
+
+	// Retry configuration
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	OnExhausted     ExhaustedFunc
+
+	// AutoScale scales the workers with the queue depth once the pool is
+	// started
+	AutoScale AutoScaleConfig
 }
 
 // WorkerPool is a pool of workers that process log events
 type WorkerPool struct {
 	config   PoolConfig
-	workers  []*worker
+	jobFunc  JobFunc
 	jobQueue chan *job
 
+	scaleMu sync.Mutex   // serializes scaling and stopping
+	mu      sync.RWMutex // guards workers and started
+	workers []*worker
+	started bool
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -41,7 +63,10 @@ type WorkerPool struct {
 	jobsProcessed uint64
 	jobsFailed    uint64
 	jobsTimeout   uint64
+	jobsRetried   uint64
+	jobsCancelled uint64
 	workersActive uint64
+	jobLatency    uint64 // total nanoseconds spent processing jobs
 }
 
 // worker represents a single worker in the pool
@@ -52,6 +77,8 @@ type worker struct {
 	jobFunc    JobFunc
 	ctx        context.Context
 	cancel     context.CancelFunc
+	quit       chan struct{} // closed when the pool scales down
+	done       chan struct{} // closed when the worker exits
 
 	// Metrics
 	jobsProcessed uint64
@@ -62,6 +89,7 @@ type worker struct {
 
 // job represents a unit of work
 type job struct {
+	ctx       context.Context
 	event     *types.LogEvent
 	resultCh  chan error
 	createdAt time.Time
@@ -82,10 +110,34 @@ func NewWorkerPool(config PoolConfig, jobFunc JobFunc) (*WorkerPool, error) {
 		config.JobTimeout = 30 * time.Second // Default
 	}
 
+	if config.Name == "" {
+		config.Name = "default"
+	}
+
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 100 * time.Millisecond // Default
+	}
+
+	if config.MaxRetryBackoff == 0 {
+		config.MaxRetryBackoff = 5 * time.Second // Default
+	}
+
+	if config.AutoScale.Enabled {
+		if err := config.AutoScale.setDefaults(config); err != nil {
+			return nil, err
+		}
+		config.NumWorkers = clamp(config.NumWorkers, config.AutoScale.MinWorkers, config.AutoScale.MaxWorkers)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pool := &WorkerPool{
 		config:   config,
+		jobFunc:  jobFunc,
 		workers:  make([]*worker, config.NumWorkers),
 		jobQueue: make(chan *job, config.QueueSize),
 		ctx:      ctx,
@@ -100,12 +152,23 @@ func NewWorkerPool(config PoolConfig, jobFunc JobFunc) (*WorkerPool, error) {
 	return pool, nil
 }
 
-// Start starts all workers in the pool
+// Start starts all workers in the pool, and the autoscaler if enabled
 func (p *WorkerPool) Start() {
+	p.mu.Lock()
 	for _, w := range p.workers {
 		p.wg.Add(1)
 		go w.run()
 	}
+	p.started = true
+	size := len(p.workers)
+	p.mu.Unlock()
+
+	metrics.GetGlobalCollector().WorkerPoolSize.WithLabelValues(p.config.Name).Set(float64(size))
+
+	if p.config.AutoScale.Enabled {
+		p.wg.Add(1)
+		go p.autoscale()
+	}
 }
 
 // Submit submits a job to the worker pool
@@ -117,6 +180,7 @@ func (p *WorkerPool) Submit(ctx context.Context, event *types.LogEvent) error {
 	}
 
 	j := &job{
+		ctx:       ctx,
 		event:     event,
 		resultCh:  make(chan error, 1),
 		createdAt: time.Now(),
@@ -138,21 +202,38 @@ func (p *WorkerPool) Submit(ctx context.Context, event *types.LogEvent) error {
 		return err
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(j.timeout):
+	case <-time.After(p.resultTimeout()):
 		atomic.AddUint64(&p.jobsTimeout, 1)
 		return ErrJobTimeout
 	}
 }
 
+// resultTimeout returns how long Submit waits for a job, covering every
+// retry attempt and the backoff between them
+func (p *WorkerPool) resultTimeout() time.Duration {
+	attempts := time.Duration(p.config.MaxRetries + 1)
+	return p.config.JobTimeout*attempts + p.config.MaxRetryBackoff*time.Duration(p.config.MaxRetries)
+}
+
 // SubmitAsync submits a job without waiting for the result
 func (p *WorkerPool) SubmitAsync(event *types.LogEvent) error {
+	return p.SubmitAsyncCtx(context.Background(), event)
+}
+
+// SubmitAsyncCtx submits a job without waiting for the result. If ctx is
+// done by the time a worker dequeues the job, the job is skipped and
+// counted as cancelled.
+func (p *WorkerPool) SubmitAsyncCtx(ctx context.Context, event *types.LogEvent) error {
 	select {
 	case <-p.ctx.Done():
 		return ErrPoolClosed
+	case <-ctx.Done():
+		return ctx.Err()
 	default:
 	}
 
 	j := &job{
+		ctx:       ctx,
 		event:     event,
 		resultCh:  make(chan error, 1),
 		createdAt: time.Now(),
@@ -173,8 +254,10 @@ func (p *WorkerPool) SubmitAsync(event *types.LogEvent) error {
 func (p *WorkerPool) Stop() error {
 	p.cancel()
 
-	// Close job queue
+	// Close job queue once no scaling is in progress
+	p.scaleMu.Lock()
 	close(p.jobQueue)
+	p.scaleMu.Unlock()
 
 	// Wait for all workers to finish
 	p.wg.Wait()
@@ -182,54 +265,93 @@ func (p *WorkerPool) Stop() error {
 	return nil
 }
 
-// Scale adjusts the number of workers
+// Scale adjusts the number of workers. Scaling down drains the removed
+// workers: each finishes the job it is running and stops taking new ones,
+// and Scale returns once they have exited, so no job is interrupted or lost.
 func (p *WorkerPool) Scale(numWorkers int) error {
 	if numWorkers <= 0 {
-		return errors.New("number of workers must be positive")
+		return ErrInvalidScale
 	}
 
+	p.scaleMu.Lock()
+	defer p.scaleMu.Unlock()
+
 	select {
 	case <-p.ctx.Done():
 		return ErrPoolClosed
 	default:
 	}
 
-	currentWorkers := len(p.workers)
+	if numWorkers > p.size() {
+		p.scaleUp(numWorkers)
+	} else {
+		p.scaleDown(numWorkers)
+	}
+
+	metrics.GetGlobalCollector().WorkerPoolSize.WithLabelValues(p.config.Name).Set(float64(numWorkers))
+	return nil
+}
 
-	if numWorkers > currentWorkers {
-		// Add more workers
-		for i := currentWorkers; i < numWorkers; i++ {
-			w := newWorker(i, p, p.workers[0].jobFunc)
-			p.workers = append(p.workers, w)
+// scaleUp adds workers, starting them if the pool is started
+func (p *WorkerPool) scaleUp(numWorkers int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := len(p.workers); i < numWorkers; i++ {
+		w := newWorker(i, p, p.jobFunc)
+		p.workers = append(p.workers, w)
+		if p.started {
 			p.wg.Add(1)
 			go w.run()
 		}
-	} else if numWorkers < currentWorkers {
-		// Remove workers
-		toRemove := currentWorkers - numWorkers
-		for i := 0; i < toRemove; i++ {
-			lastIdx := len(p.workers) - 1
-			p.workers[lastIdx].stop()
-			p.workers = p.workers[:lastIdx]
+	}
+}
+
+// scaleDown signals the workers above numWorkers to stop, waits for them to
+// finish their current job and removes them
+func (p *WorkerPool) scaleDown(numWorkers int) {
+	p.mu.RLock()
+	removed := p.workers[min(numWorkers, len(p.workers)):]
+	started := p.started
+	p.mu.RUnlock()
+
+	for _, w := range removed {
+		w.stop()
+	}
+	if started {
+		for _, w := range removed {
+			<-w.done
 		}
 	}
 
-	p.config.NumWorkers = numWorkers
-	return nil
+	p.mu.Lock()
+	p.workers = p.workers[:min(numWorkers, len(p.workers))]
+	p.mu.Unlock()
+}
+
+// size returns the current number of workers
+func (p *WorkerPool) size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.workers)
 }
 
 // Metrics returns worker pool statistics
 func (p *WorkerPool) Metrics() PoolMetrics {
+	p.mu.RLock()
 	workerMetrics := make([]WorkerMetrics, len(p.workers))
 	for i, w := range p.workers {
 		workerMetrics[i] = w.metrics()
 	}
+	p.mu.RUnlock()
 
 	return PoolMetrics{
-		NumWorkers:     len(p.workers),
+		NumWorkers:     len(workerMetrics),
 		JobsProcessed:  atomic.LoadUint64(&p.jobsProcessed),
 		JobsFailed:     atomic.LoadUint64(&p.jobsFailed),
 		JobsTimeout:    atomic.LoadUint64(&p.jobsTimeout),
+		JobsRetried:    atomic.LoadUint64(&p.jobsRetried),
+		JobsCancelled:  atomic.LoadUint64(&p.jobsCancelled),
 		WorkersActive:  atomic.LoadUint64(&p.workersActive),
 		QueueSize:      len(p.jobQueue),
 		QueueCapacity:  cap(p.jobQueue),
@@ -248,17 +370,30 @@ func newWorker(id int, pool *WorkerPool, jobFunc JobFunc) *worker {
 		jobFunc:  jobFunc,
 		ctx:      ctx,
 		cancel:   cancel,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 }
 
 // run is the main worker loop
 func (w *worker) run() {
 	defer w.pool.wg.Done()
+	defer close(w.done)
+	defer w.cancel()
 
 	for {
+		// A draining worker takes no new job
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+
 		select {
 		case <-w.ctx.Done():
 			return
+		case <-w.quit:
+			return
 		case j, ok := <-w.jobQueue:
 			if !ok {
 				return
@@ -278,19 +413,36 @@ func (w *worker) processJob(j *job) {
 	w.lastActive = time.Now()
 	w.mu.Unlock()
 
-	// Create timeout context
-	ctx, cancel := context.WithTimeout(w.ctx, j.timeout)
-	defer cancel()
+	// Skip jobs whose submitter has already given up on them
+	if j.ctx != nil && j.ctx.Err() != nil {
+		atomic.AddUint64(&w.pool.jobsCancelled, 1)
+		select {
+		case j.resultCh <- j.ctx.Err():
+		default:
+		}
+		return
+	}
 
-	// Execute job
-	err := w.jobFunc(ctx, j.event)
+	// Execute job, retrying failures up to the configured limit
+	start := time.Now()
+	exhausted, err := w.execute(j)
+	atomic.AddUint64(&w.pool.jobLatency, uint64(time.Since(start)))
 
 	atomic.AddUint64(&w.jobsProcessed, 1)
 	atomic.AddUint64(&w.pool.jobsProcessed, 1)
 
-	if err != nil {
+	switch {
+	case err == nil:
+	case !exhausted:
+		// Stopped by the pool or the submitter before its retries ran out
+		atomic.AddUint64(&w.pool.jobsCancelled, 1)
+	default:
 		atomic.AddUint64(&w.jobsFailed, 1)
 		atomic.AddUint64(&w.pool.jobsFailed, 1)
+
+		if w.pool.config.OnExhausted != nil {
+			w.pool.config.OnExhausted(j.event, err)
+		}
 	}
 
 	// Send result
@@ -300,9 +452,57 @@ func (w *worker) processJob(j *job) {
 	}
 }
 
-// stop stops the worker
+// execute runs the job function with exponential backoff between retries,
+// reporting whether a failure used up every retry rather than being cut
+// short by the pool stopping or the submitter's context ending
+func (w *worker) execute(j *job) (bool, error) {
+	cfg := w.pool.config
+
+	var err error
+	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := reliability.ExponentialBackoff(attempt-1, cfg.RetryBackoff, 2.0, cfg.MaxRetryBackoff)
+
+			select {
+			case <-w.ctx.Done():
+				return false, err
+			case <-j.done():
+				return false, err
+			case <-time.After(backoff):
+			}
+
+			atomic.AddUint64(&w.pool.jobsRetried, 1)
+			metrics.GetGlobalCollector().WorkerPoolRetries.WithLabelValues(cfg.Name).Inc()
+		}
+
+		err = w.runOnce(j)
+		if err == nil || w.ctx.Err() != nil || (j.ctx != nil && j.ctx.Err() != nil) {
+			return false, err
+		}
+	}
+
+	return true, err
+}
+
+// done returns the submitter's done channel, or nil if the job has no context
+func (j *job) done() <-chan struct{} {
+	if j.ctx == nil {
+		return nil
+	}
+	return j.ctx.Done()
+}
+
+// runOnce executes a single attempt of the job under its timeout
+func (w *worker) runOnce(j *job) error {
+	ctx, cancel := context.WithTimeout(w.ctx, j.timeout)
+	defer cancel()
+
+	return w.jobFunc(ctx, j.event)
+}
+
+// stop signals the worker to exit once its current job, if any, is done
 func (w *worker) stop() {
-	w.cancel()
+	close(w.quit)
 }
 
 // metrics returns worker metrics
@@ -347,6 +547,8 @@ type PoolMetrics struct {
 	JobsProcessed  uint64
 	JobsFailed     uint64
 	JobsTimeout    uint64
+	JobsRetried    uint64
+	JobsCancelled  uint64
 	WorkersActive  uint64
 	QueueSize      int
 	QueueCapacity  int
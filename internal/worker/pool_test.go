@@ -136,6 +136,66 @@ func TestWorkerPool_SubmitAsync(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_SubmitAsyncCtxCancelled(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var processed uint64
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		if event.Message == "blocker" {
+			started <- struct{}{}
+			<-release
+		}
+		atomic.AddUint64(&processed, 1)
+		return nil
+	}
+
+	config := PoolConfig{
+		NumWorkers: 1,
+		QueueSize:  10,
+	}
+
+	pool, err := NewWorkerPool(config, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+
+	pool.Start()
+
+	// Occupy the only worker so the next job stays queued
+	if err := pool.SubmitAsync(&types.LogEvent{Message: "blocker"}); err != nil {
+		t.Fatalf("SubmitAsync() error = %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := pool.SubmitAsyncCtx(ctx, &types.LogEvent{Message: "stale"}); err != nil {
+		t.Fatalf("SubmitAsyncCtx() error = %v", err)
+	}
+
+	// Cancel before the worker reaches the queued job
+	cancel()
+	close(release)
+
+	deadline := time.Now().Add(1 * time.Second)
+	for pool.Metrics().JobsCancelled == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := pool.Metrics()
+	if metrics.JobsCancelled != 1 {
+		t.Errorf("expected 1 cancelled job, got %d", metrics.JobsCancelled)
+	}
+	if got := atomic.LoadUint64(&processed); got != 1 {
+		t.Errorf("expected only the blocker to be processed, got %d", got)
+	}
+
+	// An already-cancelled context is rejected at submission
+	if err := pool.SubmitAsyncCtx(ctx, &types.LogEvent{Message: "late"}); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 func TestWorkerPool_JobError(t *testing.T) {
 	expectedErr := errors.New("job error")
 	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
@@ -168,6 +228,166 @@ func TestWorkerPool_JobError(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_RetryThenSucceed(t *testing.T) {
+	var attempts uint64
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		if atomic.AddUint64(&attempts, 1) <= 2 {
+			return errors.New("transient error")
+		}
+		return nil
+	}
+
+	var exhausted uint64
+	config := PoolConfig{
+		NumWorkers:   1,
+		QueueSize:    10,
+		MaxRetries:   3,
+		RetryBackoff: 1 * time.Millisecond,
+		OnExhausted: func(event *types.LogEvent, err error) {
+			atomic.AddUint64(&exhausted, 1)
+		},
+	}
+
+	pool, err := NewWorkerPool(config, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+
+	pool.Start()
+
+	event := &types.LogEvent{Message: "test"}
+	if err := pool.Submit(context.Background(), event); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if got := atomic.LoadUint64(&attempts); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	metrics := pool.Metrics()
+	if metrics.JobsRetried != 2 {
+		t.Errorf("expected 2 retries, got %d", metrics.JobsRetried)
+	}
+	if metrics.JobsFailed != 0 {
+		t.Errorf("expected 0 failed jobs, got %d", metrics.JobsFailed)
+	}
+	if atomic.LoadUint64(&exhausted) != 0 {
+		t.Errorf("OnExhausted should not be called for a job that succeeded")
+	}
+}
+
+func TestWorkerPool_RetryExhausted(t *testing.T) {
+	expectedErr := errors.New("permanent error")
+	var attempts uint64
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		atomic.AddUint64(&attempts, 1)
+		return expectedErr
+	}
+
+	exhaustedCh := make(chan error, 1)
+	var exhaustedEvent *types.LogEvent
+	config := PoolConfig{
+		NumWorkers:   1,
+		QueueSize:    10,
+		MaxRetries:   2,
+		RetryBackoff: 1 * time.Millisecond,
+		OnExhausted: func(event *types.LogEvent, err error) {
+			exhaustedEvent = event
+			exhaustedCh <- err
+		},
+	}
+
+	pool, err := NewWorkerPool(config, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+
+	pool.Start()
+
+	event := &types.LogEvent{Message: "test"}
+	if err := pool.Submit(context.Background(), event); err != expectedErr {
+		t.Errorf("expected %v, got %v", expectedErr, err)
+	}
+
+	select {
+	case err := <-exhaustedCh:
+		if err != expectedErr {
+			t.Errorf("OnExhausted error = %v, want %v", err, expectedErr)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("OnExhausted was not called")
+	}
+
+	if exhaustedEvent != event {
+		t.Errorf("OnExhausted received a different event")
+	}
+
+	if got := atomic.LoadUint64(&attempts); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	metrics := pool.Metrics()
+	if metrics.JobsRetried != 2 {
+		t.Errorf("expected 2 retries, got %d", metrics.JobsRetried)
+	}
+	if metrics.JobsFailed != 1 {
+		t.Errorf("expected 1 failed job, got %d", metrics.JobsFailed)
+	}
+}
+
+func TestWorkerPool_RetryCancelled(t *testing.T) {
+	attempted := make(chan struct{}, 10)
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		attempted <- struct{}{}
+		return errors.New("transient error")
+	}
+
+	var exhausted uint64
+	config := PoolConfig{
+		NumWorkers:   1,
+		QueueSize:    10,
+		MaxRetries:   5,
+		RetryBackoff: time.Hour,
+		OnExhausted: func(event *types.LogEvent, err error) {
+			atomic.AddUint64(&exhausted, 1)
+		},
+	}
+
+	pool, err := NewWorkerPool(config, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+
+	pool.Start()
+
+	// Cancel the submitter's context while the job waits to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := pool.SubmitAsyncCtx(ctx, &types.LogEvent{Message: "test"}); err != nil {
+		t.Fatalf("SubmitAsyncCtx() error = %v", err)
+	}
+	<-attempted
+	cancel()
+
+	deadline := time.Now().Add(1 * time.Second)
+	for pool.Metrics().JobsCancelled == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := pool.Metrics()
+	if metrics.JobsCancelled != 1 {
+		t.Errorf("expected 1 cancelled job, got %d", metrics.JobsCancelled)
+	}
+	if metrics.JobsFailed != 0 {
+		t.Errorf("expected 0 failed jobs, got %d", metrics.JobsFailed)
+	}
+	if atomic.LoadUint64(&exhausted) != 0 {
+		t.Errorf("OnExhausted should not be called for a job cancelled before its retries ran out")
+	}
+}
+
 func TestWorkerPool_Timeout(t *testing.T) {
 	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
 		// Simulate long-running job
@@ -296,6 +516,69 @@ func TestWorkerPool_Scale(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_ScaleDownDrains(t *testing.T) {
+	var completed, interrupted uint64
+	started := make(chan struct{}, 16)
+	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
+		started <- struct{}{}
+		select {
+		case <-time.After(100 * time.Millisecond):
+			atomic.AddUint64(&completed, 1)
+			return nil
+		case <-ctx.Done():
+			atomic.AddUint64(&interrupted, 1)
+			return ctx.Err()
+		}
+	}
+
+	pool, err := NewWorkerPool(PoolConfig{NumWorkers: 4, QueueSize: 16}, jobFunc)
+	if err != nil {
+		t.Fatalf("NewWorkerPool() error = %v", err)
+	}
+	defer pool.Stop()
+	pool.Start()
+
+	const total = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, total)
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pool.Submit(context.Background(), &types.LogEvent{Message: "long"})
+		}()
+	}
+
+	// Scale down while every worker is running a job
+	for i := 0; i < 4; i++ {
+		<-started
+	}
+	if err := pool.Scale(1); err != nil {
+		t.Fatalf("Scale() error = %v", err)
+	}
+	if n := pool.Metrics().NumWorkers; n != 1 {
+		t.Errorf("expected 1 worker once the removed ones drained, got %d", n)
+	}
+	if n := atomic.LoadUint64(&completed); n < 4 {
+		t.Errorf("expected Scale to wait for the in-flight jobs, %d completed", n)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Submit() error = %v", err)
+		}
+	}
+	if completed != total || interrupted != 0 {
+		t.Errorf("expected %d jobs completed and none interrupted, got %d and %d", total, completed, interrupted)
+	}
+
+	if err := pool.Scale(0); !errors.Is(err, ErrInvalidScale) {
+		t.Errorf("expected ErrInvalidScale scaling to 0, got %v", err)
+	}
+}
+
 func TestWorkerPool_Stop(t *testing.T) {
 	jobFunc := func(ctx context.Context, event *types.LogEvent) error {
 		return nil
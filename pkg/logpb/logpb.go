@@ -0,0 +1,205 @@
+// Package logpb contains the protobuf messages and gRPC service for log
+// ingestion described in ingest.proto. The messages are plain structs with
+// protobuf struct tags, which the protobuf runtime encodes without generated
+// descriptors, so the package builds without protoc.
+package logpb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LogEvent is a single log entry
+type LogEvent struct {
+	TimestampUnixNano int64             `protobuf:"varint,1,opt,name=timestamp_unix_nano,json=timestampUnixNano,proto3" json:"timestamp_unix_nano,omitempty"`
+	Message           string            `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Level             string            `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	Source            string            `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	Fields            map[string]string `protobuf:"bytes,5,rep,name=fields,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3" json:"fields,omitempty"`
+	Raw               string            `protobuf:"bytes,6,opt,name=raw,proto3" json:"raw,omitempty"`
+}
+
+// Reset clears the message
+func (m *LogEvent) Reset() { *m = LogEvent{} }
+
+// String returns a debug representation of the message
+func (m *LogEvent) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks LogEvent as a protobuf message
+func (*LogEvent) ProtoMessage() {}
+
+// LogBatch is a group of events sent in one stream message
+type LogBatch struct {
+	BatchId uint64      `protobuf:"varint,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Events  []*LogEvent `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+}
+
+// Reset clears the message
+func (m *LogBatch) Reset() { *m = LogBatch{} }
+
+// String returns a debug representation of the message
+func (m *LogBatch) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks LogBatch as a protobuf message
+func (*LogBatch) ProtoMessage() {}
+
+// Ack acknowledges a LogBatch
+type Ack struct {
+	BatchId  uint64 `protobuf:"varint,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Accepted uint32 `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Error    string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+// Reset clears the message
+func (m *Ack) Reset() { *m = Ack{} }
+
+// String returns a debug representation of the message
+func (m *Ack) String() string { return fmt.Sprintf("%+v", *m) }
+
+// ProtoMessage marks Ack as a protobuf message
+func (*Ack) ProtoMessage() {}
+
+// FromEvent converts a log event to its protobuf form
+func FromEvent(event *types.LogEvent) *LogEvent {
+	m := &LogEvent{
+		Message: event.Message,
+		Level:   event.Level,
+		Source:  event.Source,
+		Fields:  event.Fields,
+		Raw:     event.Raw,
+	}
+	if !event.Timestamp.IsZero() {
+		m.TimestampUnixNano = event.Timestamp.UnixNano()
+	}
+	return m
+}
+
+// ToEvent converts a protobuf log event, using now when it has no timestamp
+func (m *LogEvent) ToEvent(now time.Time) *types.LogEvent {
+	event := &types.LogEvent{
+		Timestamp: now,
+		Message:   m.Message,
+		Level:     m.Level,
+		Source:    m.Source,
+		Fields:    make(map[string]string, len(m.Fields)),
+		Raw:       m.Raw,
+	}
+	if m.TimestampUnixNano != 0 {
+		event.Timestamp = time.Unix(0, m.TimestampUnixNano)
+	}
+	for k, v := range m.Fields {
+		event.Fields[k] = v
+	}
+	return event
+}
+
+// LogIngestServer is the server API for the LogIngest service
+type LogIngestServer interface {
+	Stream(LogIngest_StreamServer) error
+}
+
+// LogIngest_StreamServer is the server side of a LogIngest.Stream call
+type LogIngest_StreamServer interface {
+	Send(*Ack) error
+	Recv() (*LogBatch, error)
+	grpc.ServerStream
+}
+
+// UnimplementedLogIngestServer can be embedded for forward compatibility
+type UnimplementedLogIngestServer struct{}
+
+// Stream returns codes.Unimplemented
+func (UnimplementedLogIngestServer) Stream(LogIngest_StreamServer) error {
+	return status.Error(codes.Unimplemented, "method Stream not implemented")
+}
+
+// RegisterLogIngestServer registers the LogIngest service with a gRPC server
+func RegisterLogIngestServer(s grpc.ServiceRegistrar, srv LogIngestServer) {
+	s.RegisterService(&LogIngest_ServiceDesc, srv)
+}
+
+// LogIngest_ServiceDesc is the grpc.ServiceDesc for the LogIngest service
+var LogIngest_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "logaggregator.v1.LogIngest",
+	HandlerType: (*LogIngestServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       logIngestStreamHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingest.proto",
+}
+
+func logIngestStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LogIngestServer).Stream(&logIngestStreamServer{stream})
+}
+
+type logIngestStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *logIngestStreamServer) Send(m *Ack) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *logIngestStreamServer) Recv() (*LogBatch, error) {
+	m := new(LogBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LogIngestClient is the client API for the LogIngest service
+type LogIngestClient interface {
+	Stream(ctx context.Context, opts ...grpc.CallOption) (LogIngest_StreamClient, error)
+}
+
+// LogIngest_StreamClient is the client side of a LogIngest.Stream call
+type LogIngest_StreamClient interface {
+	Send(*LogBatch) error
+	Recv() (*Ack, error)
+	grpc.ClientStream
+}
+
+type logIngestClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewLogIngestClient creates a LogIngest client on a connection
+func NewLogIngestClient(cc grpc.ClientConnInterface) LogIngestClient {
+	return &logIngestClient{cc}
+}
+
+func (c *logIngestClient) Stream(ctx context.Context, opts ...grpc.CallOption) (LogIngest_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LogIngest_ServiceDesc.Streams[0], "/logaggregator.v1.LogIngest/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &logIngestStreamClient{stream}, nil
+}
+
+type logIngestStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *logIngestStreamClient) Send(m *LogBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *logIngestStreamClient) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// logEventJSON has the fields of LogEvent without its JSON methods
+type logEventJSON LogEvent
+
+// MarshalJSON encodes the event with its timestamp in RFC3339Nano
+func (e LogEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		logEventJSON
+		Timestamp string `json:"timestamp"`
+	}{
+		logEventJSON: logEventJSON(e),
+		Timestamp:    e.Timestamp.Format(time.RFC3339Nano),
+	})
+}
+
+// UnmarshalJSON decodes an event whose timestamp is an RFC3339 string or a
+// Unix epoch in seconds, milliseconds, microseconds or nanoseconds, given as
+// a number or a numeric string. A missing or null timestamp is left zero.
+func (e *LogEvent) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*logEventJSON
+		Timestamp json.RawMessage `json:"timestamp"`
+	}{
+		logEventJSON: (*logEventJSON)(e),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	timestamp, err := parseJSONTimestamp(aux.Timestamp)
+	if err != nil {
+		return err
+	}
+	e.Timestamp = timestamp
+	return nil
+}
+
+// parseJSONTimestamp parses a raw JSON timestamp value
+func parseJSONTimestamp(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return time.Time{}, nil
+	}
+
+	value := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %s: %w", raw, err)
+		}
+		if value == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, nil
+		}
+	}
+
+	t, err := parseEpoch(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s: expected RFC3339 or Unix epoch", raw)
+	}
+	return t, nil
+}
+
+// parseEpoch parses a Unix epoch, inferring its unit from its magnitude
+func parseEpoch(value string) (time.Time, error) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		abs := n
+		if abs < 0 {
+			abs = -abs
+		}
+		switch {
+		case abs < 1e11:
+			return time.Unix(n, 0).UTC(), nil
+		case abs < 1e14:
+			return time.UnixMilli(n).UTC(), nil
+		case abs < 1e17:
+			return time.UnixMicro(n).UTC(), nil
+		default:
+			return time.Unix(0, n).UTC(), nil
+		}
+	}
+
+	// Fractional epochs are in seconds
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, fmt.Errorf("invalid epoch %q", value)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+}
@@ -1,6 +1,16 @@
 package types
 
-import "time"
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Defaults applied by Normalize to events without a level or source
+const (
+	DefaultLevel  = "info"
+	DefaultSource = "unknown"
+)
 
 // LogEvent represents a parsed log entry
 type LogEvent struct {
@@ -10,6 +20,48 @@ type LogEvent struct {
 	Source    string            `json:"source"`
 	Fields    map[string]string `json:"fields,omitempty"`
 	Raw       string            `json:"raw,omitempty"` // Original raw log line
+
+	// Context carries the event's trace context between pipeline stages
+	Context context.Context `json:"-"`
+}
+
+// Normalize canonicalizes an event before output: the timestamp is set to
+// UTC (or to now when it is missing), level aliases are mapped to their
+// standard names, and an empty level or source is given its default.
+func (e *LogEvent) Normalize() {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	e.Timestamp = e.Timestamp.UTC()
+
+	e.Level = NormalizeLevel(e.Level)
+	if e.Level == "" {
+		e.Level = DefaultLevel
+	}
+
+	if e.Source == "" {
+		e.Source = DefaultSource
+	}
+}
+
+// NormalizeLevel maps a log level to one of debug, info, warn, error or
+// fatal regardless of case. Unknown levels are returned lowercased.
+func NormalizeLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "debug", "trace":
+		return "debug"
+	case "info", "information", "notice":
+		return "info"
+	case "warn", "warning":
+		return "warn"
+	case "error", "err":
+		return "error"
+	case "fatal", "critical", "crit", "panic", "emerg", "alert":
+		return "fatal"
+	default:
+		return level
+	}
 }
 
 // FilePosition tracks the current position in a file
@@ -0,0 +1,172 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"WARNING", "warn"},
+		{"Warning", "warn"},
+		{"warn", "warn"},
+		{"ERR", "error"},
+		{"Error", "error"},
+		{"TRACE", "debug"},
+		{"Information", "info"},
+		{"notice", "info"},
+		{"CRIT", "fatal"},
+		{"panic", "fatal"},
+		{" INFO ", "info"},
+		{"Custom", "custom"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizeLevel(tt.input); got != tt.want {
+				t.Errorf("NormalizeLevel(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogEvent_NormalizeTimestamp(t *testing.T) {
+	zone := time.FixedZone("UTC+5:30", 5*3600+30*60)
+	local := time.Date(2024, 3, 1, 17, 30, 0, 123456789, zone)
+
+	event := &LogEvent{Timestamp: local, Level: "info", Source: "app"}
+	event.Normalize()
+
+	if event.Timestamp.Location() != time.UTC {
+		t.Errorf("expected a UTC timestamp, got %v", event.Timestamp.Location())
+	}
+	if !event.Timestamp.Equal(local) {
+		t.Errorf("expected the instant to be kept, got %v", event.Timestamp)
+	}
+	if got := event.Timestamp.Format(time.RFC3339Nano); got != "2024-03-01T12:00:00.123456789Z" {
+		t.Errorf("unexpected RFC3339Nano timestamp %s", got)
+	}
+}
+
+func TestLogEvent_NormalizeDefaults(t *testing.T) {
+	before := time.Now()
+	event := &LogEvent{Message: "hello"}
+	event.Normalize()
+
+	if event.Level != DefaultLevel {
+		t.Errorf("expected level %q, got %q", DefaultLevel, event.Level)
+	}
+	if event.Source != DefaultSource {
+		t.Errorf("expected source %q, got %q", DefaultSource, event.Source)
+	}
+	if event.Timestamp.Before(before) || event.Timestamp.Location() != time.UTC {
+		t.Errorf("expected a missing timestamp to be set to now in UTC, got %v", event.Timestamp)
+	}
+
+	// Set values are kept
+	event = &LogEvent{Level: "WARNING", Source: "/var/log/app.log"}
+	event.Normalize()
+	if event.Level != "warn" || event.Source != "/var/log/app.log" {
+		t.Errorf("expected level warn and the original source, got %q and %q", event.Level, event.Source)
+	}
+}
+
+func TestLogEvent_JSONRoundTrip(t *testing.T) {
+	zone := time.FixedZone("UTC-7", -7*3600)
+	timestamps := []time.Time{
+		time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		time.Date(2024, 3, 1, 5, 0, 0, 0, zone),
+		{},
+	}
+
+	for _, ts := range timestamps {
+		event := LogEvent{Timestamp: ts, Message: "hello", Level: "info", Source: "app", Fields: map[string]string{"k": "v"}}
+		data, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if fields["timestamp"] != ts.Format(time.RFC3339Nano) {
+			t.Errorf("expected an RFC3339Nano timestamp %s, got %v", ts.Format(time.RFC3339Nano), fields["timestamp"])
+		}
+
+		var decoded LogEvent
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("failed to unmarshal %s: %v", data, err)
+		}
+		if !decoded.Timestamp.Equal(ts) {
+			t.Errorf("expected timestamp %v, got %v", ts, decoded.Timestamp)
+		}
+		if decoded.Message != "hello" || decoded.Source != "app" || decoded.Fields["k"] != "v" {
+			t.Errorf("expected the other fields to round-trip, got %+v", decoded)
+		}
+	}
+
+	// Pointers marshal the same way
+	data, err := json.Marshal(&LogEvent{Timestamp: timestamps[0]})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"timestamp":"2024-03-01T12:00:00.123456789Z"`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestLogEvent_UnmarshalTimestamp(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		want      time.Time
+	}{
+		{"rfc3339", `"2024-03-01T12:00:00Z"`, want},
+		{"rfc3339 offset", `"2024-03-01T14:00:00+02:00"`, want},
+		{"rfc3339 nano", `"2024-03-01T12:00:00.5Z"`, want.Add(500 * time.Millisecond)},
+		{"epoch seconds", `1709294400`, want},
+		{"epoch fractional seconds", `1709294400.25`, want.Add(250 * time.Millisecond)},
+		{"epoch milliseconds", `1709294400000`, want},
+		{"epoch microseconds", `1709294400000000`, want},
+		{"epoch nanoseconds", `1709294400000000001`, want.Add(time.Nanosecond)},
+		{"epoch string", `"1709294400"`, want},
+		{"null", `null`, time.Time{}},
+		{"empty string", `""`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event LogEvent
+			if err := json.Unmarshal([]byte(`{"message":"m","timestamp":`+tt.timestamp+`}`), &event); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if !event.Timestamp.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, event.Timestamp)
+			}
+			if event.Message != "m" {
+				t.Errorf("expected message m, got %q", event.Message)
+			}
+		})
+	}
+
+	// A missing timestamp is left zero
+	var event LogEvent
+	if err := json.Unmarshal([]byte(`{"message":"m"}`), &event); err != nil || !event.Timestamp.IsZero() {
+		t.Errorf("expected a zero timestamp, got %v (%v)", event.Timestamp, err)
+	}
+
+	for _, invalid := range []string{`"yesterday"`, `true`, `{}`} {
+		if err := json.Unmarshal([]byte(`{"timestamp":`+invalid+`}`), &event); err == nil {
+			t.Errorf("expected timestamp %s to be rejected", invalid)
+		}
+	}
+}
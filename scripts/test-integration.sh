@@ -7,7 +7,7 @@ set -e
 echo "Starting integration test infrastructure..."
 
 # Start required services
-docker-compose -f docker-compose.test.yml up -d zookeeper kafka elasticsearch minio redis prometheus jaeger
+docker-compose -f docker-compose.test.yml up -d zookeeper kafka elasticsearch minio fake-gcs redis prometheus jaeger
 
 echo "Waiting for services to be ready..."
 
@@ -35,6 +35,14 @@ until curl -s http://localhost:9000/minio/health/live &> /dev/null; do
 done
 echo " ✓"
 
+# Wait for the GCS emulator
+echo -n "Waiting for fake-gcs-server"
+until curl -s http://localhost:4443/storage/v1/b &> /dev/null; do
+    echo -n "."
+    sleep 2
+done
+echo " ✓"
+
 # Wait for Redis
 echo -n "Waiting for Redis"
 until docker exec test-redis redis-cli ping &> /dev/null; do
@@ -54,6 +62,7 @@ export S3_ENDPOINT="http://localhost:9000"
 export S3_ACCESS_KEY="minioadmin"
 export S3_SECRET_KEY="minioadmin"
 export S3_BUCKET="test-logs"
+export GCS_EMULATOR_ENDPOINT="http://localhost:4443"
 
 # Run integration tests
 echo "Running integration tests..."
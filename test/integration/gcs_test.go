@@ -0,0 +1,123 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// TestGCSOutputIntegration uploads a batch through the GCS output to
+// fake-gcs-server and reads the object back
+func TestGCSOutputIntegration(t *testing.T) {
+	endpoint := getEnvOrDefault("GCS_EMULATOR_ENDPOINT", "")
+	if endpoint == "" {
+		t.Skip("GCS_EMULATOR_ENDPOINT not set, skipping GCS test")
+	}
+	bucket := fmt.Sprintf("test-logs-%d", time.Now().UnixNano())
+
+	// Create the bucket
+	waitForService(t, "fake-gcs-server", func() error {
+		body := strings.NewReader(fmt.Sprintf(`{"name":%q}`, bucket))
+		resp, err := http.Post(endpoint+"/storage/v1/b?project=test", "application/json", body)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("creating bucket returned %d", resp.StatusCode)
+		}
+		return nil
+	}, 60*time.Second)
+
+	config := output.DefaultGCSConfig()
+	config.Name = "gcs"
+	config.Bucket = bucket
+	config.Endpoint = endpoint
+	config.Prefix = "integration/"
+	config.KeyTemplate = "{{.Fields.service}}/{{.Timestamp}}.json"
+	config.StorageClass = "NEARLINE"
+	config.BatchSize = 10
+
+	out, err := output.NewGCSOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create GCS output: %v", err)
+	}
+	defer out.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := out.HealthCheck(ctx); err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+
+	ts := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	events := make([]*types.LogEvent, 3)
+	for i := range events {
+		events[i] = &types.LogEvent{
+			Timestamp: ts,
+			Message:   fmt.Sprintf("GCS integration test message %d", i),
+			Level:     "info",
+			Source:    "integration-test",
+			Fields:    map[string]string{"service": "api"},
+		}
+	}
+	if err := out.SendBatch(ctx, events); err != nil {
+		t.Fatalf("Failed to send batch: %v", err)
+	}
+
+	// Read the object back
+	name := "integration/api/1710496800.json"
+	objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", endpoint, bucket, url.PathEscape(name))
+	resp, err := http.Get(objectURL + "?alt=media")
+	if err != nil {
+		t.Fatalf("Failed to download object: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Downloading %s returned %d: %s", name, resp.StatusCode, body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != len(events) {
+		t.Fatalf("Expected %d events in the object, got %d: %s", len(events), len(lines), body)
+	}
+	for i, line := range lines {
+		var event types.LogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Line %d is not JSON: %v", i, err)
+		}
+		if event.Message != events[i].Message {
+			t.Errorf("Line %d message = %q, want %q", i, event.Message, events[i].Message)
+		}
+	}
+
+	// The metadata carries the storage class
+	resp, err = http.Get(objectURL)
+	if err != nil {
+		t.Fatalf("Failed to get object metadata: %v", err)
+	}
+	defer resp.Body.Close()
+	var metadata struct {
+		StorageClass string `json:"storageClass"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode object metadata: %v", err)
+	}
+	if metadata.StorageClass != "NEARLINE" {
+		t.Errorf("Storage class = %q, want NEARLINE", metadata.StorageClass)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// TestKafkaOrderedByKeyIntegration sends keyed events concurrently through
+// a Kafka output with OrderedByKey and checks that each key's events are
+// stored at increasing offsets in the order they were sent
+func TestKafkaOrderedByKeyIntegration(t *testing.T) {
+	brokers := strings.Split(getEnvOrDefault("KAFKA_BROKERS", "localhost:29092"), ",")
+	topic := fmt.Sprintf("test-ordered-%d", time.Now().UnixNano())
+
+	const keys, perKey = 4, 50
+
+	config := output.DefaultKafkaConfig()
+	config.Name = "kafka-ordered"
+	config.Brokers = brokers
+	config.Topic = topic
+	config.BatchSize = 1
+	config.PartitionKey = "key"
+	config.IdempotentWrites = true
+	config.OrderedByKey = true
+
+	out, err := output.NewKafkaOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create Kafka output: %v", err)
+	}
+	defer out.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for k := 0; k < keys; k++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			for seq := 0; seq < perKey; seq++ {
+				event := &types.LogEvent{
+					Timestamp: time.Now(),
+					Message:   "ordered message",
+					Fields:    map[string]string{"key": key, "seq": strconv.Itoa(seq)},
+				}
+				if err := out.Send(ctx, event); err != nil {
+					t.Errorf("Failed to send %s/%d: %v", key, seq, err)
+					return
+				}
+			}
+		}(fmt.Sprintf("key-%d", k))
+	}
+	wg.Wait()
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.V2_8_0_0
+	consumer, err := sarama.NewConsumer(brokers, saramaConfig)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := consumer.Partitions(topic)
+	if err != nil {
+		t.Fatalf("Failed to list partitions: %v", err)
+	}
+
+	// Per key, the sequence numbers in offset order
+	received := make(map[string][]int)
+	total := 0
+	for _, partition := range partitions {
+		pc, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			t.Fatalf("Failed to consume partition %d: %v", partition, err)
+		}
+
+		lastOffset := int64(-1)
+	consume:
+		for pc.HighWaterMarkOffset() == 0 || lastOffset < pc.HighWaterMarkOffset()-1 {
+			select {
+			case msg := <-pc.Messages():
+				if msg.Offset <= lastOffset {
+					t.Errorf("Partition %d: offset %d after %d", partition, msg.Offset, lastOffset)
+				}
+				lastOffset = msg.Offset
+
+				var event types.LogEvent
+				if err := json.Unmarshal(msg.Value, &event); err != nil {
+					t.Fatalf("Failed to decode message: %v", err)
+				}
+				if string(msg.Key) != event.Fields["key"] {
+					t.Errorf("Message key %q does not match field %q", msg.Key, event.Fields["key"])
+				}
+				seq, _ := strconv.Atoi(event.Fields["seq"])
+				received[event.Fields["key"]] = append(received[event.Fields["key"]], seq)
+				total++
+			case <-time.After(10 * time.Second):
+				break consume
+			}
+		}
+		pc.Close()
+	}
+
+	if total != keys*perKey {
+		t.Fatalf("Expected %d messages, got %d", keys*perKey, total)
+	}
+	for key, seqs := range received {
+		for i, seq := range seqs {
+			if seq != i {
+				t.Fatalf("Key %s: message %d has sequence %d, messages were reordered", key, i, seq)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/log/internal/output"
+	"github.com/therealutkarshpriyadarshi/log/pkg/types"
+)
+
+// TestKafkaSCRAMIntegration sends an event through the Kafka output to a
+// broker that requires SASL/SCRAM authentication
+func TestKafkaSCRAMIntegration(t *testing.T) {
+	brokers := getEnvOrDefault("KAFKA_SASL_BROKERS", "")
+	if brokers == "" {
+		t.Skip("KAFKA_SASL_BROKERS not set, skipping SASL/SCRAM test")
+	}
+
+	for _, mechanism := range strings.Split(getEnvOrDefault("KAFKA_SASL_MECHANISMS", "SCRAM-SHA-256,SCRAM-SHA-512"), ",") {
+		t.Run(mechanism, func(t *testing.T) {
+			config := output.DefaultKafkaConfig()
+			config.Name = "kafka-scram"
+			config.Brokers = strings.Split(brokers, ",")
+			config.Topic = "test-scram-" + strings.ToLower(strings.ReplaceAll(mechanism, "-", ""))
+			config.BatchSize = 1
+			config.SASLEnabled = true
+			config.SASLMechanism = mechanism
+			config.SASLUsername = getEnvOrDefault("KAFKA_SASL_USERNAME", "admin")
+			config.SASLPassword = getEnvOrDefault("KAFKA_SASL_PASSWORD", "admin-secret")
+
+			out, err := output.NewKafkaOutput(config)
+			if err != nil {
+				t.Fatalf("Failed to create Kafka output with %s: %v", mechanism, err)
+			}
+			defer out.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := out.HealthCheck(ctx); err != nil {
+				t.Fatalf("Health check failed: %v", err)
+			}
+
+			event := &types.LogEvent{
+				Timestamp: time.Now(),
+				Message:   "SCRAM integration test message",
+				Level:     "info",
+				Source:    "integration-test",
+			}
+			if err := out.Send(ctx, event); err != nil {
+				t.Fatalf("Failed to send event: %v", err)
+			}
+		})
+	}
+}